@@ -0,0 +1,77 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// diskStore stores each blob in a file of its directory.
+type diskStore struct {
+	directory string
+}
+
+func newDiskStore(directory string) (*diskStore, error) {
+	if err := os.MkdirAll(directory, 0o750); err != nil {
+		return nil, fmt.Errorf("could not create blob directory: %+v", err)
+	}
+	return &diskStore{directory: directory}, nil
+}
+
+func (s *diskStore) Put(_ context.Context, key string, value []byte) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	// written to a temporary file first, so a partially written blob is never read
+	tmp, err := os.CreateTemp(s.directory, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("could not create blob file: %+v", err)
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write blob file: %+v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write blob file: %+v", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(s.directory, key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write blob file: %+v", err)
+	}
+	return nil
+}
+
+func (s *diskStore) Get(_ context.Context, key string) ([]byte, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+	value, err := os.ReadFile(filepath.Join(s.directory, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read blob file: %+v", err)
+	}
+	return value, nil
+}
@@ -0,0 +1,110 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/teletrace/teletrace/blobstore/sigv4"
+)
+
+// s3Timeout bounds each request to S3
+const s3Timeout = 30 * time.Second
+
+// s3Store stores each blob in an object of its bucket, signing requests with AWS Signature Version 4.
+type s3Store struct {
+	cfg    Config
+	client *http.Client
+	signer *sigv4.Signer
+}
+
+func newS3Store(cfg Config) (*s3Store, error) {
+	signer, err := sigv4.NewSigner(context.Background(), cfg.S3Region, sigv4.ServiceS3)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Store{
+		cfg:    cfg,
+		client: &http.Client{Timeout: s3Timeout},
+		signer: signer,
+	}, nil
+}
+
+// objectURL returns the path-style URL of a custom endpoint, or the virtual-hosted-style URL of AWS.
+func (s *s3Store) objectURL(key string) string {
+	if s.cfg.S3Endpoint != "" {
+		return fmt.Sprintf("%s/%s/%s%s", strings.TrimSuffix(s.cfg.S3Endpoint, "/"), s.cfg.S3Bucket, s.cfg.S3Prefix, key)
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s%s", s.cfg.S3Bucket, s.cfg.S3Region, s.cfg.S3Prefix, key)
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, value []byte) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	res, err := s.do(ctx, http.MethodPut, key, value)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("could not put blob, s3 responded with %d: %s", res.StatusCode, body)
+	}
+	return nil
+}
+
+func (s *s3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+	res, err := s.do(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(res.Body)
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("could not get blob, s3 responded with %d: %s", res.StatusCode, body)
+	}
+}
+
+func (s *s3Store) do(ctx context.Context, method string, key string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("could not create s3 request: %+v", err)
+	}
+	if err := s.signer.Sign(req); err != nil {
+		return nil, err
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 request failed: %+v", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,112 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package sigv4 signs HTTP requests to AWS services with AWS Signature Version 4.
+package sigv4
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+const (
+	Algorithm = "AWS4-HMAC-SHA256"
+
+	// ServiceS3 is the signing name of S3, whose paths are escaped once rather than twice
+	ServiceS3 = "s3"
+	// ServiceOpenSearchServerless is the signing name of OpenSearch Serverless collections
+	ServiceOpenSearchServerless = "aoss"
+	// ServiceOpenSearch is the signing name of OpenSearch Service domains
+	ServiceOpenSearch = "es"
+)
+
+// payloadHashServices require the payload hash header
+var payloadHashServices = map[string]bool{ServiceS3: true, ServiceOpenSearchServerless: true}
+
+// Signer signs requests to a service of a region.
+type Signer struct {
+	Region      string
+	Service     string
+	Credentials aws.CredentialsProvider
+	Now         func() time.Time
+
+	signer *v4.Signer
+}
+
+// NewSigner returns a signer of requests to service in region with the credentials of the default AWS credential
+// chain: the environment variables, the shared config and credentials files, web identity tokens such as IRSA, and the
+// ECS container and EC2 instance profile credentials. Credentials are cached, and refreshed before they expire.
+func NewSigner(ctx context.Context, region string, service string) (*Signer, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("could not load aws config for sigv4 authentication: %w", err)
+	}
+	return newSigner(region, service, cfg.Credentials), nil
+}
+
+func newSigner(region string, service string, credentials aws.CredentialsProvider) *Signer {
+	return &Signer{
+		Region:      region,
+		Service:     service,
+		Credentials: credentials,
+		Now:         time.Now,
+		signer: v4.NewSigner(func(o *v4.SignerOptions) {
+			// S3 expects the path to be escaped once, and other services expect each path segment to be escaped twice
+			o.DisableURIPathEscaping = service == ServiceS3
+		}),
+	}
+}
+
+// Sign adds the sigv4 authorization headers to req, buffering its body for hashing.
+func (s *Signer) Sign(req *http.Request) error {
+	if s.Credentials == nil {
+		return fmt.Errorf("no aws credentials for sigv4 authentication")
+	}
+	creds, err := s.Credentials.Retrieve(req.Context())
+	if err != nil {
+		return fmt.Errorf("could not retrieve aws credentials for sigv4 authentication: %w", err)
+	}
+
+	body := []byte{}
+	if req.Body != nil {
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return fmt.Errorf("could not read request body for signing: %+v", err)
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	hash := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(hash[:])
+	if payloadHashServices[s.Service] {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	return s.signer.SignHTTP(req.Context(), creds, req, payloadHash, s.Service, s.Region, s.Now().UTC())
+}
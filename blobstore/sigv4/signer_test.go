@@ -0,0 +1,117 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sigv4
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func newTestSigner(service string, sessionToken string) *Signer {
+	signer := newSigner("us-east-1", service, aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+		return aws.Credentials{
+			AccessKeyID:     "AKIDEXAMPLE",
+			SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+			SessionToken:    sessionToken,
+		}, nil
+	}))
+	signer.Now = func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) }
+	return signer
+}
+
+// Expected signatures are taken from the AWS sigv4 test suite
+func TestSignerTestSuite(t *testing.T) {
+	tests := map[string]struct {
+		url               string
+		expectedSignature string
+	}{
+		"get-vanilla": {
+			url:               "https://example.amazonaws.com/",
+			expectedSignature: "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		"get-vanilla-query-order-key-case": {
+			url:               "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			expectedSignature: "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, test.url, nil)
+
+			if err := newTestSigner("service", "").Sign(req); err != nil {
+				t.Fatal(err)
+			}
+
+			if date := req.Header.Get("X-Amz-Date"); date != "20150830T123600Z" {
+				t.Errorf("unexpected date %q", date)
+			}
+			expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, Signature=" + test.expectedSignature
+			if auth := req.Header.Get("Authorization"); auth != expected {
+				t.Errorf("expected authorization %q, got %q", expected, auth)
+			}
+		})
+	}
+}
+
+func TestSessionToken(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+
+	if err := newTestSigner(ServiceOpenSearch, "session").Sign(req); err != nil {
+		t.Fatal(err)
+	}
+
+	if token := req.Header.Get("X-Amz-Security-Token"); token != "session" {
+		t.Errorf("expected the session token header, got %q", token)
+	}
+	if auth := req.Header.Get("Authorization"); !strings.Contains(auth, "x-amz-security-token") {
+		t.Errorf("expected the session token to be signed, got %q", auth)
+	}
+}
+
+func TestPayloadHashHeader(t *testing.T) {
+	for service, expected := range map[string]bool{ServiceS3: true, ServiceOpenSearchServerless: true, ServiceOpenSearch: false} {
+		req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+
+		if err := newTestSigner(service, "").Sign(req); err != nil {
+			t.Fatal(err)
+		}
+
+		if hasHash := req.Header.Get("X-Amz-Content-Sha256") != ""; hasHash != expected {
+			t.Errorf("expected payload hash header of %s to be %v", service, expected)
+		}
+	}
+}
+
+func TestCredentialsError(t *testing.T) {
+	errNoCredentials := errors.New("no EC2 IMDS role found")
+	signer := newSigner("us-east-1", ServiceS3, aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+		return aws.Credentials{}, errNoCredentials
+	}))
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+
+	if err := signer.Sign(req); !errors.Is(err, errNoCredentials) {
+		t.Errorf("expected the credentials error, got %v", err)
+	}
+}
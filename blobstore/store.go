@@ -0,0 +1,114 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blobstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+const (
+	TypeDisk = "disk"
+	TypeS3   = "s3"
+)
+
+// ReferenceAttributePrefix prefixes the span attribute referencing the full value of a truncated attribute,
+// e.g. teletrace.sidecar.db.statement holds the key of the blob storing the full db.statement value.
+const ReferenceAttributePrefix = "teletrace.sidecar."
+
+// ErrNotFound is returned when getting a blob which doesn't exist.
+var ErrNotFound = errors.New("blob not found")
+
+// Store persists blobs by key.
+type Store interface {
+	Put(ctx context.Context, key string, value []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// Config defines which store blobs are persisted to.
+type Config struct {
+	// Type is either disk or s3, blobs aren't stored if empty
+	Type string `mapstructure:"type"`
+	// Directory holds the blobs of a disk store
+	Directory string `mapstructure:"directory"`
+	// S3Bucket, S3Region and S3Prefix locate the blobs of an s3 store
+	S3Bucket string `mapstructure:"s3_bucket"`
+	S3Region string `mapstructure:"s3_region"`
+	S3Prefix string `mapstructure:"s3_prefix"`
+	// S3Endpoint overrides the AWS endpoint, for S3 compatible stores, e.g. http://minio:9000
+	S3Endpoint string `mapstructure:"s3_endpoint"`
+}
+
+// Enabled returns whether a store is configured.
+func (cfg *Config) Enabled() bool {
+	return cfg.Type != ""
+}
+
+// Validate validates the blob store configuration.
+func (cfg *Config) Validate() error {
+	switch cfg.Type {
+	case "":
+		return nil
+	case TypeDisk:
+		if cfg.Directory == "" {
+			return fmt.Errorf("a directory is required for a %s blob store", TypeDisk)
+		}
+	case TypeS3:
+		if cfg.S3Bucket == "" || cfg.S3Region == "" {
+			return fmt.Errorf("a bucket and a region are required for an %s blob store", TypeS3)
+		}
+	default:
+		return fmt.Errorf("invalid blob store type %q, expected %s or %s", cfg.Type, TypeDisk, TypeS3)
+	}
+	return nil
+}
+
+// NewStore creates the configured store.
+func NewStore(cfg Config) (Store, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	switch cfg.Type {
+	case TypeDisk:
+		return newDiskStore(cfg.Directory)
+	case TypeS3:
+		return newS3Store(cfg)
+	}
+	return nil, fmt.Errorf("no blob store is configured")
+}
+
+// ContentKey returns the key of a content-addressed blob, so storing the same value twice is idempotent.
+func ContentKey(value []byte) string {
+	hash := sha256.Sum256(value)
+	return hex.EncodeToString(hash[:])
+}
+
+// ValidateKey rejects keys which could escape the store, as keys may come from API requests.
+func ValidateKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("blob key must not be empty")
+	}
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+	value := []byte(strings.Repeat("SELECT * FROM orders; ", 100))
+	key := ContentKey(value)
+
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := store.Put(ctx, key, value); err != nil {
+		t.Fatalf("put failed: %v", err)
+	}
+	// content-addressed blobs may be put again
+	if err := store.Put(ctx, key, value); err != nil {
+		t.Fatalf("second put failed: %v", err)
+	}
+	got, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if !bytes.Equal(value, got) {
+		t.Fatalf("expected %q, got %q", value, got)
+	}
+
+	if err := store.Put(ctx, "../escape", value); err == nil {
+		t.Fatalf("expected an invalid key error")
+	}
+}
+
+func TestDiskStore(t *testing.T) {
+	store, err := NewStore(Config{Type: TypeDisk, Directory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("could not create store: %v", err)
+	}
+	testStore(t, store)
+}
+
+// fakeS3 stores the objects put to it by path, rejecting unsigned requests.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20230101/us-east-1/s3/aws4_request, ") ||
+		!strings.Contains(auth, "host;x-amz-content-sha256;x-amz-date, ") ||
+		r.Header.Get("X-Amz-Date") != "20230101T000000Z" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		if hash := sha256.Sum256(body); r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(hash[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.objects[r.URL.Path] = body
+	case http.MethodGet:
+		object, ok := s.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(object)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	s3 := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(s3)
+	defer server.Close()
+
+	store, err := newS3Store(Config{Type: TypeS3, S3Bucket: "spans", S3Region: "us-east-1", S3Prefix: "sidecar/", S3Endpoint: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.signer.Credentials = credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", "")
+	store.signer.Now = func() time.Time { return time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC) }
+	testStore(t, store)
+
+	for path := range s3.objects {
+		if !strings.HasPrefix(path, "/spans/sidecar/") {
+			t.Fatalf("unexpected object path %s", path)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := []Config{{}, {Type: TypeDisk, Directory: "/var/lib/teletrace"}, {Type: TypeS3, S3Bucket: "spans", S3Region: "us-east-1"}}
+	for _, cfg := range valid {
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("expected %+v to be valid, got %v", cfg, err)
+		}
+	}
+	invalid := []Config{{Type: TypeDisk}, {Type: TypeS3, S3Bucket: "spans"}, {Type: "gcs"}}
+	for _, cfg := range invalid {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", cfg)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrOpen is returned without calling the backend while the circuit breaker is open.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the state of a circuit breaker.
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// Config defines when the circuit breaker opens and for how long.
+type Config struct {
+	// FailureThreshold is the number of consecutive failures that opens the circuit breaker.
+	FailureThreshold int
+	// OpenDuration is how long calls fast-fail before a single trial call is let through.
+	OpenDuration time.Duration
+}
+
+// Breaker tracks consecutive failures of a backend.
+// While open, calls fail immediately with ErrOpen. After OpenDuration a single trial call is let
+// through (half-open), its success closes the breaker and its failure opens it again.
+type Breaker struct {
+	// Now returns the current time, it can be replaced in tests.
+	Now func() time.Time
+
+	logger *zap.Logger
+	cfg    Config
+	ignore func(err error) bool
+
+	mu            sync.Mutex
+	state         State
+	failures      int
+	openedAt      time.Time
+	trialInFlight bool
+}
+
+// New creates a closed Breaker. Errors for which ignore returns true, e.g. invalid requests, tell nothing
+// about the health of the backend and are not counted as failures, ignore may be nil.
+func New(logger *zap.Logger, cfg Config, ignore func(err error) bool) *Breaker {
+	if ignore == nil {
+		ignore = func(error) bool { return false }
+	}
+	return &Breaker{Now: time.Now, logger: logger, cfg: cfg, ignore: ignore}
+}
+
+// Do calls fn unless the breaker is open, and records its outcome.
+// Calls failing once ctx is done are not counted as failures, as they were canceled by their caller.
+func (b *Breaker) Do(ctx context.Context, fn func() error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := fn()
+	b.record(ctx, err)
+	return err
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.Now().Sub(b.openedAt) < b.cfg.OpenDuration {
+			return ErrOpen
+		}
+		b.setState(StateHalfOpen)
+		b.trialInFlight = true
+	case StateHalfOpen:
+		if b.trialInFlight {
+			return ErrOpen
+		}
+		b.trialInFlight = true
+	}
+	return nil
+}
+
+func (b *Breaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ignored := err != nil && (ctx.Err() != nil || b.ignore(err))
+
+	if b.state == StateHalfOpen {
+		b.trialInFlight = false
+		switch {
+		case ignored:
+		case err != nil:
+			b.open()
+		default:
+			b.failures = 0
+			b.setState(StateClosed)
+		}
+		return
+	}
+
+	switch {
+	case ignored:
+	case err != nil:
+		b.failures++
+		if b.state == StateClosed && b.failures >= b.cfg.FailureThreshold {
+			b.open()
+		}
+	default:
+		b.failures = 0
+	}
+}
+
+func (b *Breaker) open() {
+	b.openedAt = b.Now()
+	b.setState(StateOpen)
+}
+
+func (b *Breaker) setState(s State) {
+	if b.state == s {
+		return
+	}
+	b.logger.Warn("Storage circuit breaker state changed",
+		zap.Stringer("from", b.state), zap.Stringer("to", s), zap.Int("consecutive_failures", b.failures))
+	b.state = s
+}
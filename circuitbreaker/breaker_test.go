@@ -0,0 +1,130 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	errBackend = errors.New("connection refused")
+	errInvalid = errors.New("invalid request")
+)
+
+func newTestBreaker() (*Breaker, *time.Time) {
+	b := New(zap.NewNop(), Config{FailureThreshold: 3, OpenDuration: 30 * time.Second},
+		func(err error) bool { return errors.Is(err, errInvalid) })
+	now := time.Now()
+	b.Now = func() time.Time { return now }
+	return b, &now
+}
+
+func fail(err error) func() error {
+	return func() error { return err }
+}
+
+func TestOpensAfterConsecutiveFailures(t *testing.T) {
+	b, _ := newTestBreaker()
+
+	calls := 0
+	call := func() error {
+		calls++
+		return errBackend
+	}
+	for i := 0; i < 3; i++ {
+		if err := b.Do(context.Background(), call); !errors.Is(err, errBackend) {
+			t.Fatalf("expected the backend error, got %v", err)
+		}
+	}
+	if err := b.Do(context.Background(), call); !errors.Is(err, ErrOpen) {
+		t.Fatalf("expected ErrOpen, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestSuccessResetsFailures(t *testing.T) {
+	b, _ := newTestBreaker()
+
+	for i := 0; i < 5; i++ {
+		_ = b.Do(context.Background(), fail(errBackend))
+		_ = b.Do(context.Background(), fail(errBackend))
+		if err := b.Do(context.Background(), fail(nil)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if b.State() != StateClosed {
+		t.Errorf("expected closed, got %v", b.State())
+	}
+}
+
+func TestRecoversAfterOpenDuration(t *testing.T) {
+	b, now := newTestBreaker()
+	for i := 0; i < 3; i++ {
+		_ = b.Do(context.Background(), fail(errBackend))
+	}
+
+	// a failed trial call opens the breaker again
+	*now = now.Add(30 * time.Second)
+	if err := b.Do(context.Background(), fail(errBackend)); !errors.Is(err, errBackend) {
+		t.Fatalf("expected the trial call to reach the backend, got %v", err)
+	}
+	if err := b.Do(context.Background(), fail(nil)); !errors.Is(err, ErrOpen) {
+		t.Fatalf("expected ErrOpen, got %v", err)
+	}
+
+	// a successful trial call closes it
+	*now = now.Add(30 * time.Second)
+	if err := b.Do(context.Background(), fail(nil)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.State() != StateClosed {
+		t.Errorf("expected closed, got %v", b.State())
+	}
+}
+
+func TestHalfOpenAllowsSingleTrialCall(t *testing.T) {
+	b, now := newTestBreaker()
+	b.open()
+
+	*now = now.Add(30 * time.Second)
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected a trial call, got %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("expected ErrOpen during the trial call, got %v", err)
+	}
+}
+
+func TestCanceledAndIgnoredCallsAreNotFailures(t *testing.T) {
+	b, _ := newTestBreaker()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for i := 0; i < 5; i++ {
+		_ = b.Do(ctx, fail(context.Canceled))
+		_ = b.Do(context.Background(), fail(errInvalid))
+	}
+	if b.State() != StateClosed {
+		t.Errorf("expected closed, got %v", b.State())
+	}
+}
@@ -18,18 +18,30 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
+	// embeds the timezone database, which the images lack, for the timezones of aggregation buckets
+	_ "time/tzdata"
 
 	"github.com/teletrace/teletrace/pkg/api"
 	"github.com/teletrace/teletrace/pkg/config"
 	"github.com/teletrace/teletrace/pkg/logs"
+	"github.com/teletrace/teletrace/pkg/metadatastore"
 	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/cutover"
+	"github.com/teletrace/teletrace/pkg/spanreader/federated"
+	"github.com/teletrace/teletrace/pkg/storageplugin"
 
+	sqlmetadatastore "github.com/teletrace/teletrace/plugin/metadatastore/sql"
+	badger "github.com/teletrace/teletrace/plugin/spanreader/badger"
+	cassandra "github.com/teletrace/teletrace/plugin/spanreader/cassandra"
 	spanreaderes "github.com/teletrace/teletrace/plugin/spanreader/es"
+	parquet "github.com/teletrace/teletrace/plugin/spanreader/parquet"
 	sqlite "github.com/teletrace/teletrace/plugin/spanreader/sqlite"
 
 	"github.com/teletrace/teletrace/teletrace-otelcol/pkg/collector"
@@ -56,7 +68,28 @@ func main() {
 			log.Fatalf("Failed to initialize SpanReader plugin %v", err)
 		}
 	}
+	if err := sr.Initialize(); err != nil {
+		logger.Fatal("Failed to initialize the schema of the spans storage", zap.Error(err))
+	}
 	api := api.NewAPI(logger, cfg, &sr)
+	store, err := initializeMetadataStore(cfg, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize metadata store", zap.Error(err))
+	}
+	api.SetMetadataStore(store)
+	onChange := api.Reload
+	if migrating, ok := sr.(*cutover.SpanReader); ok {
+		onChange = func(cfg config.Config) {
+			api.Reload(cfg)
+			if migrating.CutOver() != cfg.StorageMigrationCutover {
+				migrating.SetCutover(cfg.StorageMigrationCutover)
+				logger.Info("Switched storage migration reads", zap.Bool("cutover", cfg.StorageMigrationCutover))
+			}
+		}
+	}
+	if err := config.Watch(logger, cfg, onChange); err != nil {
+		logger.Fatal("Failed to watch config file", zap.Error(err))
+	}
 
 	collector, err := collector.NewCollector()
 	if err != nil {
@@ -76,16 +109,113 @@ func main() {
 }
 
 func initializeSpanReader(cfg config.Config, logger *zap.Logger) (spanreader.SpanReader, error) {
+	if cfg.StorageMigrationTargetPlugin != "" {
+		return initializeMigratingSpanReader(cfg, logger)
+	}
+	if cfg.FederationConfigFile != "" {
+		return initializeFederatedSpanReader(cfg, logger)
+	}
 	switch cfg.SpansStoragePlugin {
 	case "sqlite":
 		return sqlite.NewSqliteSpanReader(context.Background(), logger, sqlite.NewSqliteConfig(cfg))
+	case "cassandra":
+		return cassandra.NewCassandraSpanReader(context.Background(), logger, cassandra.NewCassandraConfig(cfg))
+	case "badger":
+		return badger.NewBadgerSpanReader(context.Background(), logger, badger.NewBadgerConfig(cfg))
+	case "parquet":
+		return parquet.NewParquetSpanReader(context.Background(), logger, parquet.NewParquetConfig(cfg))
 	case "elasticsearch":
 		return spanreaderes.NewSpanReader(context.Background(), logger, spanreaderes.NewElasticConfig(cfg), spanreaderes.NewElasticMetaConfig(cfg))
+	case "grpc":
+		// The plugin exits along with Teletrace once the stdin pipe to it is closed, so the client isn't closed
+		client, err := storageplugin.NewClient(logger, storageplugin.Config{
+			Path:         cfg.GRPCPluginPath,
+			StartTimeout: time.Duration(cfg.GRPCPluginStartTimeoutSeconds) * time.Second,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return client.SpanReader()
 	default:
 		return nil, fmt.Errorf("Invalid spans storage plugin %s", cfg.SpansStoragePlugin)
 	}
 }
 
+// initializeFederatedSpanReader returns a span reader searching the backends of the federation config as one,
+// each configured by the global config with its settings overridden.
+func initializeFederatedSpanReader(cfg config.Config, logger *zap.Logger) (spanreader.SpanReader, error) {
+	federationCfg, err := federated.LoadConfig(cfg.FederationConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	var backends []federated.Backend
+	for _, backendCfg := range federationCfg.Backends {
+		c, err := cfg.WithSettings(backendCfg.Settings)
+		if err != nil {
+			return nil, fmt.Errorf("backend %s: %w", backendCfg.Name, err)
+		}
+		c.SpansStoragePlugin = backendCfg.Plugin
+		c.FederationConfigFile = ""
+		sr, err := initializeSpanReader(c, logger.With(zap.String("backend", backendCfg.Name)))
+		if err != nil {
+			return nil, fmt.Errorf("backend %s: %w", backendCfg.Name, err)
+		}
+		backends = append(backends, federated.Backend{Name: backendCfg.Name, Reader: sr})
+	}
+	return federated.NewSpanReader(backends)
+}
+
+// initializeMigratingSpanReader returns a span reader switching from the spans storage plugin to the migration target
+// plugin on the cutover, the target being configured by the global config with the target settings overridden.
+func initializeMigratingSpanReader(cfg config.Config, logger *zap.Logger) (spanreader.SpanReader, error) {
+	sourceCfg := cfg
+	sourceCfg.StorageMigrationTargetPlugin = ""
+	source, err := initializeSpanReader(sourceCfg, logger.With(zap.String("migration", "source")))
+	if err != nil {
+		return nil, fmt.Errorf("migration source: %w", err)
+	}
+
+	settings := map[string]any{}
+	if cfg.StorageMigrationTargetSettings != "" {
+		if err := json.Unmarshal([]byte(cfg.StorageMigrationTargetSettings), &settings); err != nil {
+			return nil, fmt.Errorf("invalid migration target settings: %w", err)
+		}
+	}
+	targetCfg, err := cfg.WithSettings(settings)
+	if err != nil {
+		return nil, fmt.Errorf("migration target: %w", err)
+	}
+	targetCfg.SpansStoragePlugin = cfg.StorageMigrationTargetPlugin
+	targetCfg.StorageMigrationTargetPlugin = ""
+	targetCfg.FederationConfigFile = ""
+	target, err := initializeSpanReader(targetCfg, logger.With(zap.String("migration", "target")))
+	if err != nil {
+		return nil, fmt.Errorf("migration target: %w", err)
+	}
+	return cutover.NewSpanReader(source, target, cfg.StorageMigrationCutover), nil
+}
+
+// initializeMetadataStore returns the Postgres metadata store if configured, or else the store of the spans storage plugin.
+// External gRPC storage plugins and the cassandra, badger and parquet plugins don't serve a metadata store, so the sqlite
+// one is used along with them.
+func initializeMetadataStore(cfg config.Config, logger *zap.Logger) (metadatastore.MetadataStore, error) {
+	var store metadatastore.MetadataStore
+	var err error
+	switch {
+	case cfg.MetadataPostgresDSN != "":
+		store, err = sqlmetadatastore.NewSqlMetadataStore(context.Background(), logger, sqlmetadatastore.NewPostgresConfig(cfg))
+	case cfg.SpansStoragePlugin == "sqlite", cfg.SpansStoragePlugin == "grpc", cfg.SpansStoragePlugin == "cassandra",
+		cfg.SpansStoragePlugin == "badger", cfg.SpansStoragePlugin == "parquet":
+		store, err = sqlmetadatastore.NewSqlMetadataStore(context.Background(), logger, sqlmetadatastore.NewSqliteConfig(cfg))
+	default:
+		store, err = spanreaderes.NewMetadataStore(context.Background(), logger, spanreaderes.NewElasticMetadataStoreConfig(cfg))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return store, store.Initialize()
+}
+
 func startAPI(logger *zap.Logger, api *api.API) {
 	if err := api.Start(); err != nil {
 		logger.Fatal("API stopped with an error", zap.Error(err))
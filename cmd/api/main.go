@@ -19,10 +19,14 @@ package main
 import (
 	"context"
 	"log"
+	// embeds the timezone database, which the images lack, for the timezones of aggregation buckets
+	_ "time/tzdata"
 
 	"github.com/teletrace/teletrace/pkg/api"
 	"github.com/teletrace/teletrace/pkg/config"
 	"github.com/teletrace/teletrace/pkg/logs"
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	sqlmetadatastore "github.com/teletrace/teletrace/plugin/metadatastore/sql"
 	spanreaderes "github.com/teletrace/teletrace/plugin/spanreader/es"
 
 	"go.uber.org/zap"
@@ -44,8 +48,34 @@ func main() {
 	if err != nil {
 		logger.Fatal("Failed to create Span Reader for Elasticsearch", zap.Error(err))
 	}
+	if err := sr.Initialize(); err != nil {
+		logger.Fatal("Failed to initialize Span Reader for Elasticsearch", zap.Error(err))
+	}
 	api := api.NewAPI(logger, cfg, &sr)
+	store, err := initializeMetadataStore(cfg, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize metadata store", zap.Error(err))
+	}
+	api.SetMetadataStore(store)
+	if err := config.Watch(logger, cfg, api.Reload); err != nil {
+		logger.Fatal("Failed to watch config file", zap.Error(err))
+	}
 	if err := api.Start(); err != nil {
 		logger.Fatal("API server crashed", zap.Error(err))
 	}
 }
+
+// initializeMetadataStore returns the Postgres metadata store if configured, or else the Elasticsearch metadata store.
+func initializeMetadataStore(cfg config.Config, logger *zap.Logger) (metadatastore.MetadataStore, error) {
+	var store metadatastore.MetadataStore
+	var err error
+	if cfg.MetadataPostgresDSN != "" {
+		store, err = sqlmetadatastore.NewSqlMetadataStore(context.Background(), logger, sqlmetadatastore.NewPostgresConfig(cfg))
+	} else {
+		store, err = spanreaderes.NewMetadataStore(context.Background(), logger, spanreaderes.NewElasticMetadataStoreConfig(cfg))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return store, store.Initialize()
+}
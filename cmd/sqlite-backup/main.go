@@ -0,0 +1,76 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/teletrace/teletrace/pkg/config"
+	sqlite "github.com/teletrace/teletrace/plugin/spanreader/sqlite"
+)
+
+const usage = `Usage: sqlite-backup [-db <path>] <command> <backup path>
+
+Commands:
+  backup   copies the database and its shards to a new backup, while Teletrace keeps running
+  restore  replaces the content of the database and its shards with a backup
+
+Options:
+`
+
+func main() {
+	dbPath := flag.String("db", "", "path of the spans database, defaults to the SQLITE_PATH config option")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.NewConfig()
+	if err != nil {
+		log.Fatalf("Failed to initialize config: %v", err)
+	}
+	// the shards of the spans are read from the SQLITE_SHARD_BY and SQLITE_SHARDS config options
+	sqliteCfg := sqlite.NewSqliteConfig(cfg)
+	if *dbPath != "" {
+		sqliteCfg.Path = *dbPath
+	}
+
+	command, backupPath := flag.Arg(0), flag.Arg(1)
+	switch command {
+	case "backup":
+		if err := sqlite.BackupShards(context.Background(), sqliteCfg, backupPath); err != nil {
+			log.Fatalf("Failed to back up: %v", err)
+		}
+		log.Printf("Backed up %s to %s", sqliteCfg.Path, backupPath)
+	case "restore":
+		if err := sqlite.RestoreShards(context.Background(), sqliteCfg, backupPath); err != nil {
+			log.Fatalf("Failed to restore: %v", err)
+		}
+		log.Printf("Restored %s from %s", sqliteCfg.Path, backupPath)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,24 @@
+module github.com/teletrace/teletrace/kvstore
+
+go 1.19
+
+require (
+	github.com/dgraph-io/badger v1.6.2
+	github.com/teletrace/teletrace/model v0.0.0-00010101000000-000000000000
+	go.uber.org/zap v1.23.0
+)
+
+require (
+	github.com/AndreasBriese/bbloom v0.0.0-20190825152654-46b345b51c96 // indirect
+	github.com/cespare/xxhash v1.1.0 // indirect
+	github.com/dgraph-io/ristretto v0.0.2 // indirect
+	github.com/dustin/go-humanize v1.0.0 // indirect
+	github.com/golang/protobuf v1.3.1 // indirect
+	github.com/pkg/errors v0.8.1 // indirect
+	go.uber.org/atomic v1.7.0 // indirect
+	go.uber.org/multierr v1.6.0 // indirect
+	golang.org/x/net v0.0.0-20190620200207-3b0461eec859 // indirect
+	golang.org/x/sys v0.0.0-20190626221950-04f50cda93cb // indirect
+)
+
+replace github.com/teletrace/teletrace/model => ../model
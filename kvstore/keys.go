@@ -0,0 +1,114 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kvstore
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+// The keys of the store start with a byte of their kind:
+//
+//	span:      's' | start time | trace ID | 0 | span ID                               -> the span JSON
+//	trace:     'r' | trace ID | 0 | start time | span ID                               -> empty
+//	tag index: 'i' | tag key | 0 | tag value | 0 | start time | trace ID | 0 | span ID -> empty
+//	tags:      'k' | tag key                                                           -> the type of the tag
+//
+// Start times are 8 byte big-endian nanoseconds, so the spans and the index keys of a tag value sort by start time.
+// The span keys are the only ones with a value, the trace and tag index keys reference them.
+const (
+	spanPrefix     = 's'
+	tracePrefix    = 'r'
+	tagIndexPrefix = 'i'
+	tagsPrefix     = 'k'
+)
+
+// timeLength is the length of the start time in keys
+const timeLength = 8
+
+// spanRef is the key of a span without its kind, start time | trace ID | 0 | span ID, which suffixes its tag index
+// keys.
+func spanRef(startTime uint64, traceId string, spanId string) []byte {
+	ref := make([]byte, 0, timeLength+len(traceId)+1+len(spanId))
+	ref = binary.BigEndian.AppendUint64(ref, startTime)
+	ref = append(ref, traceId...)
+	ref = append(ref, 0)
+	return append(ref, spanId...)
+}
+
+func spanKey(ref []byte) []byte {
+	return append([]byte{spanPrefix}, ref...)
+}
+
+func traceKeyPrefix(traceId string) []byte {
+	prefix := make([]byte, 0, 2+len(traceId))
+	prefix = append(prefix, tracePrefix)
+	prefix = append(prefix, traceId...)
+	return append(prefix, 0)
+}
+
+func traceKey(traceId string, startTime uint64, spanId string) []byte {
+	key := traceKeyPrefix(traceId)
+	key = binary.BigEndian.AppendUint64(key, startTime)
+	return append(key, spanId...)
+}
+
+// traceKeyRef returns the span reference of a trace key of traceId.
+func traceKeyRef(traceId string, key []byte) ([]byte, bool) {
+	suffix := key[len(traceId)+2:]
+	if len(suffix) < timeLength {
+		return nil, false
+	}
+	return spanRef(binary.BigEndian.Uint64(suffix), traceId, string(suffix[timeLength:])), true
+}
+
+func tagIndexKeyPrefix(key string, value string) []byte {
+	prefix := make([]byte, 0, 3+len(key)+len(value))
+	prefix = append(prefix, tagIndexPrefix)
+	prefix = append(prefix, key...)
+	prefix = append(prefix, 0)
+	prefix = append(prefix, value...)
+	return append(prefix, 0)
+}
+
+func tagIndexKey(key string, value string, ref []byte) []byte {
+	return append(tagIndexKeyPrefix(key, value), ref...)
+}
+
+func tagsKey(key string) []byte {
+	return append([]byte{tagsPrefix}, key...)
+}
+
+// refTime returns the start time of a span reference.
+func refTime(ref []byte) (uint64, bool) {
+	if len(ref) < timeLength {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(ref), true
+}
+
+// seekKey returns the key seeking the last key of prefix with a start time up to end, for reverse iterations.
+func seekKey(prefix []byte, end uint64) []byte {
+	key := append(append([]byte{}, prefix...), 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64(key[len(prefix):], end)
+	return append(key, 0xff)
+}
+
+// Indexable returns whether a tag key or value can be part of a key, as the parts of keys are separated by 0.
+func Indexable(s string) bool {
+	return !strings.ContainsRune(s, 0)
+}
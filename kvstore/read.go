@@ -0,0 +1,237 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kvstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+
+	"github.com/dgraph-io/badger"
+)
+
+// Scan returns the spans starting in [start, end], up to limit spans starting the latest, and whether there are more.
+func (s *Store) Scan(ctx context.Context, start uint64, end uint64, limit int) ([]*internalspan.InternalSpan, bool, error) {
+	var spans []*internalspan.InternalSpan
+	var truncated bool
+	err := s.db.View(func(txn *badger.Txn) error {
+		refs, more, err := scanRefs(ctx, txn, []byte{spanPrefix}, start, end, limit)
+		if err != nil {
+			return err
+		}
+		truncated = more
+		spans, err = readSpans(ctx, txn, refs)
+		return err
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to scan spans: %w", err)
+	}
+	return spans, truncated, nil
+}
+
+// ScanTag returns the spans starting in [start, end] with one of the values of an indexed tag, up to limit spans
+// starting the latest, and whether there are more.
+func (s *Store) ScanTag(
+	ctx context.Context, key string, values []string, start uint64, end uint64, limit int,
+) ([]*internalspan.InternalSpan, bool, error) {
+	var spans []*internalspan.InternalSpan
+	var truncated bool
+	err := s.db.View(func(txn *badger.Txn) error {
+		seen := make(map[string]bool)
+		var refs [][]byte
+		for _, value := range values {
+			valueRefs, more, err := scanRefs(ctx, txn, tagIndexKeyPrefix(key, value), start, end, limit)
+			if err != nil {
+				return err
+			}
+			truncated = truncated || more
+			for _, ref := range valueRefs {
+				if !seen[string(ref)] {
+					seen[string(ref)] = true
+					refs = append(refs, ref)
+				}
+			}
+		}
+		// references sort by start time, the latest of the values are kept
+		if len(values) > 1 {
+			sort.Slice(refs, func(i, j int) bool { return bytes.Compare(refs[i], refs[j]) > 0 })
+		}
+		if limit > 0 && len(refs) > limit {
+			refs, truncated = refs[:limit], true
+		}
+		var err error
+		spans, err = readSpans(ctx, txn, refs)
+		return err
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to scan tag index: %w", err)
+	}
+	return spans, truncated, nil
+}
+
+// Traces returns the spans of the traces, up to limit spans, and whether there are more.
+func (s *Store) Traces(ctx context.Context, traceIds []string, limit int) ([]*internalspan.InternalSpan, bool, error) {
+	var spans []*internalspan.InternalSpan
+	var truncated bool
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		var refs [][]byte
+		for _, traceId := range traceIds {
+			prefix := traceKeyPrefix(traceId)
+			for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if limit > 0 && len(refs) == limit {
+					truncated = true
+					break
+				}
+				if ref, ok := traceKeyRef(traceId, it.Item().Key()); ok {
+					refs = append(refs, ref)
+				}
+			}
+		}
+		var err error
+		spans, err = readSpans(ctx, txn, refs)
+		return err
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read traces: %w", err)
+	}
+	return spans, truncated, nil
+}
+
+// Tags returns the attribute tags of the written spans, with their types.
+func (s *Store) Tags(ctx context.Context) (map[string]string, error) {
+	tags := make(map[string]string)
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		prefix := []byte{tagsPrefix}
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			item := it.Item()
+			tagType, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			tags[string(item.Key()[1:])] = string(tagType)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tags: %w", err)
+	}
+	return tags, nil
+}
+
+// Ping reads a key of the store, failing if the database is closed.
+func (s *Store) Ping() error {
+	return s.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get([]byte{spanPrefix})
+		if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+		return nil
+	})
+}
+
+// scanRefs returns the span references of the keys of prefix, which are suffixed by the references, starting in
+// [start, end], up to limit references starting the latest, and whether there are more.
+func scanRefs(ctx context.Context, txn *badger.Txn, prefix []byte, start uint64, end uint64, limit int) ([][]byte, bool, error) {
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	opts.Reverse = true
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	var refs [][]byte
+	for it.Seek(seekKey(prefix, end)); it.ValidForPrefix(prefix); it.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+		ref := it.Item().KeyCopy(nil)[len(prefix):]
+		startTime, ok := refTime(ref)
+		if !ok {
+			continue
+		}
+		if startTime < start {
+			break
+		}
+		if limit > 0 && len(refs) == limit {
+			return refs, true, nil
+		}
+		refs = append(refs, ref)
+	}
+	return refs, false, nil
+}
+
+// readSpans reads the spans of the references. Spans which expired since their references were read are skipped.
+func readSpans(ctx context.Context, txn *badger.Txn, refs [][]byte) ([]*internalspan.InternalSpan, error) {
+	spans := make([]*internalspan.InternalSpan, 0, len(refs))
+	for _, ref := range refs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		item, err := txn.Get(spanKey(ref))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var span *internalspan.InternalSpan
+		err = item.Value(func(data []byte) error {
+			span, err = decodeSpan(data)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode span: %w", err)
+		}
+		spans = append(spans, span)
+	}
+	return spans, nil
+}
+
+// decodeSpan decodes a span value. Numeric attribute values are decoded as json.Number, keeping integers exact.
+func decodeSpan(data []byte) (*internalspan.InternalSpan, error) {
+	var stored storedSpan
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&stored); err != nil {
+		return nil, err
+	}
+	span := stored.InternalSpan
+	if span == nil {
+		span = &internalspan.InternalSpan{}
+	}
+	if span.Span != nil {
+		span.Span.Events, span.Span.Links = stored.Events, stored.Links
+	}
+	return span, nil
+}
@@ -0,0 +1,139 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kvstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger"
+	"go.uber.org/zap"
+)
+
+const (
+	// gcInterval is the interval between value log garbage collections, which reclaim the space of expired spans
+	gcInterval = 5 * time.Minute
+	// gcDiscardRatio is the ratio of stale data a value log file needs for the garbage collection to rewrite it
+	gcDiscardRatio = 0.5
+)
+
+// Store is a Badger database of spans and their index keys, written by the badger exporter and read by the badger
+// span reader.
+type Store struct {
+	db        *badger.DB
+	directory string
+	// refs is the number of opens of the store which weren't closed yet
+	refs int
+	// tags caches the attribute tags written to the store, so they're written once
+	tags   sync.Map
+	stopGC chan struct{}
+	gcDone chan struct{}
+}
+
+var (
+	storesMu sync.Mutex
+	stores   = make(map[string]*Store)
+)
+
+// Open opens the store of directory. A Badger database is locked by the process which opens it, so the exporter and
+// the span reader of a process share the store, which is closed once both closed it.
+func Open(logger *zap.Logger, directory string) (*Store, error) {
+	directory, err := filepath.Abs(directory)
+	if err != nil {
+		return nil, fmt.Errorf("invalid badger directory: %+v", err)
+	}
+
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	if s, ok := stores[directory]; ok {
+		s.refs++
+		return s, nil
+	}
+
+	if err := os.MkdirAll(directory, 0o750); err != nil {
+		return nil, fmt.Errorf("could not create badger directory: %+v", err)
+	}
+	db, err := badger.Open(badger.DefaultOptions(directory).WithLogger(badgerLogger{logger.Sugar()}))
+	if err != nil {
+		return nil, fmt.Errorf("could not open badger database: %+v", err)
+	}
+	s := &Store{
+		db:        db,
+		directory: directory,
+		refs:      1,
+		stopGC:    make(chan struct{}),
+		gcDone:    make(chan struct{}),
+	}
+	go s.collectGarbage(logger)
+	stores[directory] = s
+	return s, nil
+}
+
+// Close closes the store once it was closed as many times as it was opened.
+func (s *Store) Close() error {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	s.refs--
+	if s.refs > 0 {
+		return nil
+	}
+	delete(stores, s.directory)
+	close(s.stopGC)
+	<-s.gcDone
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("could not close badger database: %+v", err)
+	}
+	return nil
+}
+
+// collectGarbage runs the value log garbage collection every gcInterval until the store is closed.
+func (s *Store) collectGarbage(logger *zap.Logger) {
+	defer close(s.gcDone)
+	ticker := time.NewTicker(gcInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopGC:
+			return
+		case <-ticker.C:
+		}
+		// each run rewrites a single value log file, so it runs until no file is rewritten
+		for {
+			err := s.db.RunValueLogGC(gcDiscardRatio)
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, badger.ErrNoRewrite) {
+				logger.Warn("Badger value log garbage collection failed", zap.Error(err))
+			}
+			break
+		}
+	}
+}
+
+// badgerLogger logs the messages of Badger with zap.
+type badgerLogger struct {
+	*zap.SugaredLogger
+}
+
+func (l badgerLogger) Warningf(template string, args ...interface{}) {
+	l.Warnf(template, args...)
+}
@@ -0,0 +1,191 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kvstore
+
+import (
+	"context"
+	"testing"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+
+	"go.uber.org/zap"
+)
+
+func testSpan(traceId string, spanId string, startTime uint64, service string) *internalspan.InternalSpan {
+	return &internalspan.InternalSpan{
+		Resource: &internalspan.Resource{Attributes: internalspan.Attributes{"service.name": service}},
+		Scope:    &internalspan.InstrumentationScope{Name: "scope"},
+		Span: &internalspan.Span{
+			TraceId:           traceId,
+			SpanId:            spanId,
+			Name:              "GET /",
+			StartTimeUnixNano: startTime,
+			EndTimeUnixNano:   startTime + 10,
+			Attributes:        internalspan.Attributes{"http.status_code": int64(200), "tags": []any{"a", "b"}},
+			Events:            []*internalspan.SpanEvent{{Name: "retry"}},
+		},
+	}
+}
+
+func spanIds(spans []*internalspan.InternalSpan) []string {
+	ids := make([]string, 0, len(spans))
+	for _, span := range spans {
+		ids = append(ids, span.Span.SpanId)
+	}
+	return ids
+}
+
+func assertSpanIds(t *testing.T, expected []string, spans []*internalspan.InternalSpan) {
+	t.Helper()
+	ids := spanIds(spans)
+	if len(ids) != len(expected) {
+		t.Fatalf("expected spans %v, got %v", expected, ids)
+	}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Fatalf("expected spans %v, got %v", expected, ids)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	directory := t.TempDir()
+	store, err := Open(zap.NewNop(), directory)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	defer store.Close()
+
+	// the store of a directory is shared by the process
+	shared, err := Open(zap.NewNop(), directory)
+	if err != nil {
+		t.Fatalf("second open failed: %v", err)
+	}
+	if shared != store {
+		t.Fatalf("expected the store to be shared")
+	}
+	if err := shared.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	err = store.WriteSpans([]*internalspan.InternalSpan{
+		testSpan("t1", "s1", 100, "cart"),
+		testSpan("t1", "s2", 200, "checkout"),
+		testSpan("t2", "s3", 300, "cart"),
+		testSpan("t3", "s4", 400, "checkout"),
+	}, 0)
+	if err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	spans, truncated, err := store.Scan(ctx, 150, 400, 0)
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	assertSpanIds(t, []string{"s4", "s3", "s2"}, spans)
+	if truncated {
+		t.Fatalf("expected the scan not to be truncated")
+	}
+
+	spans, truncated, err = store.Scan(ctx, 0, 1000, 2)
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	assertSpanIds(t, []string{"s4", "s3"}, spans)
+	if !truncated {
+		t.Fatalf("expected the scan to be truncated")
+	}
+
+	spans, _, err = store.ScanTag(ctx, "resource.attributes.service.name", []string{"cart"}, 0, 1000, 0)
+	if err != nil {
+		t.Fatalf("tag scan failed: %v", err)
+	}
+	assertSpanIds(t, []string{"s3", "s1"}, spans)
+
+	spans, truncated, err = store.ScanTag(ctx, "resource.attributes.service.name", []string{"cart", "checkout"}, 0, 350, 2)
+	if err != nil {
+		t.Fatalf("tag scan failed: %v", err)
+	}
+	assertSpanIds(t, []string{"s3", "s2"}, spans)
+	if !truncated {
+		t.Fatalf("expected the tag scan to be truncated")
+	}
+
+	// numbers and array elements are indexed
+	for key, value := range map[string]string{"span.attributes.http.status_code": "200", "span.attributes.tags": "b", "span.events.name": "retry"} {
+		spans, _, err = store.ScanTag(ctx, key, []string{value}, 0, 1000, 0)
+		if err != nil {
+			t.Fatalf("tag scan failed: %v", err)
+		}
+		if len(spans) != 4 {
+			t.Fatalf("expected 4 spans with %s = %s, got %d", key, value, len(spans))
+		}
+	}
+
+	spans, _, err = store.Traces(ctx, []string{"t1", "t3"}, 0)
+	if err != nil {
+		t.Fatalf("trace read failed: %v", err)
+	}
+	assertSpanIds(t, []string{"s1", "s2", "s4"}, spans)
+	if len(spans[0].Span.Events) != 1 || spans[0].Span.Events[0].Name != "retry" {
+		t.Fatalf("expected the span events to be read")
+	}
+
+	tags, err := store.Tags(ctx)
+	if err != nil {
+		t.Fatalf("tags read failed: %v", err)
+	}
+	expectedTags := map[string]string{
+		"resource.attributes.service.name": "Str",
+		"span.attributes.http.status_code": "Int",
+		"span.attributes.tags":             "Slice",
+	}
+	if len(tags) != len(expectedTags) {
+		t.Fatalf("expected tags %v, got %v", expectedTags, tags)
+	}
+	for key, tagType := range expectedTags {
+		if tags[key] != tagType {
+			t.Fatalf("expected tags %v, got %v", expectedTags, tags)
+		}
+	}
+}
+
+func TestWriteSpansReplacesSpan(t *testing.T) {
+	store, err := Open(zap.NewNop(), t.TempDir())
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	defer store.Close()
+
+	span := testSpan("t1", "s1", 100, "cart")
+	if err := store.WriteSpans([]*internalspan.InternalSpan{span}, 0); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	span.Span.Name = "POST /"
+	if err := store.WriteSpans([]*internalspan.InternalSpan{span}, 0); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	spans, _, err := store.Traces(context.Background(), []string{"t1"}, 0)
+	if err != nil {
+		t.Fatalf("trace read failed: %v", err)
+	}
+	if len(spans) != 1 || spans[0].Span.Name != "POST /" {
+		t.Fatalf("expected the span to be replaced, got %d spans", len(spans))
+	}
+}
@@ -0,0 +1,211 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kvstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+
+	"github.com/dgraph-io/badger"
+)
+
+// MaxIndexedValueLength bounds the tag values written to the tag index, longer values aren't indexed
+const MaxIndexedValueLength = 256
+
+// storedSpan keeps the events and links of the span alongside it, as they aren't part of the span JSON.
+type storedSpan struct {
+	*internalspan.InternalSpan
+	Events []*internalspan.SpanEvent `json:"events"`
+	Links  []*internalspan.SpanLink  `json:"links"`
+}
+
+// WriteSpans writes the spans with their trace and tag index keys, expiring after ttl unless it's 0, and adds their
+// new attribute tags to the tags keys. Writes are upserts, so a span written again replaces the written span.
+func (s *Store) WriteSpans(spans []*internalspan.InternalSpan, ttl time.Duration) error {
+	batch := s.db.NewWriteBatch()
+	defer batch.Cancel()
+	set := func(key []byte, value []byte, expires bool) error {
+		entry := badger.NewEntry(key, value)
+		if expires && ttl > 0 {
+			entry = entry.WithTTL(ttl)
+		}
+		if err := batch.SetEntry(entry); err != nil {
+			return fmt.Errorf("failed to write spans: %w", err)
+		}
+		return nil
+	}
+
+	tags := make(map[string]string)
+	for _, span := range spans {
+		data, err := json.Marshal(storedSpan{InternalSpan: span, Events: span.Span.Events, Links: span.Span.Links})
+		if err != nil {
+			return fmt.Errorf("cannot encode span with id %v: %w", span.Span.SpanId, err)
+		}
+		traceId, spanId, startTime := span.Span.TraceId, span.Span.SpanId, span.Span.StartTimeUnixNano
+		ref := spanRef(startTime, traceId, spanId)
+		if err := set(spanKey(ref), data, true); err != nil {
+			return err
+		}
+		if err := set(traceKey(traceId, startTime, spanId), nil, true); err != nil {
+			return err
+		}
+		for key, values := range indexedTags(span) {
+			for value := range values {
+				if err := set(tagIndexKey(key, value, ref), nil, true); err != nil {
+					return err
+				}
+			}
+		}
+		for key, tagType := range attributeTags(span) {
+			if _, ok := s.tags.Load(key); !ok {
+				tags[key] = tagType
+			}
+		}
+	}
+	// the tags are kept once the spans expire
+	for key, tagType := range tags {
+		if err := set(tagsKey(key), []byte(tagType), false); err != nil {
+			return err
+		}
+	}
+	if err := batch.Flush(); err != nil {
+		return fmt.Errorf("failed to write spans: %w", err)
+	}
+	for key := range tags {
+		s.tags.Store(key, true)
+	}
+	return nil
+}
+
+// indexedTags returns the values of the tags of span written to the tag index: the name, kind and status code of
+// the span, the names of its scope and events, and its scalar resource and span attributes, including the
+// scalar elements of their array attributes.
+func indexedTags(span *internalspan.InternalSpan) map[string]map[string]bool {
+	tags := make(map[string]map[string]bool)
+	add := func(key string, value any) {
+		v, ok := IndexValue(value)
+		if !ok || len(v) > MaxIndexedValueLength || !Indexable(key) || !Indexable(v) {
+			return
+		}
+		if tags[key] == nil {
+			tags[key] = make(map[string]bool)
+		}
+		tags[key][v] = true
+	}
+	addAttributes := func(prefix string, attributes internalspan.Attributes) {
+		for key, value := range attributes {
+			if elements, ok := value.([]any); ok {
+				for _, element := range elements {
+					add(prefix+key, element)
+				}
+				continue
+			}
+			add(prefix+key, value)
+		}
+	}
+
+	add("span.name", span.Span.Name)
+	add("span.kind", span.Span.Kind)
+	if span.Span.Status != nil {
+		add("span.status.code", span.Span.Status.Code)
+	}
+	if span.Scope != nil {
+		add("scope.name", span.Scope.Name)
+	}
+	for _, event := range span.Span.Events {
+		if event != nil {
+			add("span.events.name", event.Name)
+		}
+	}
+	if span.Resource != nil {
+		addAttributes("resource.attributes.", span.Resource.Attributes)
+	}
+	addAttributes("span.attributes.", span.Span.Attributes)
+	return tags
+}
+
+// IndexValue returns the tag index value of a scalar value, numbers being written in their shortest decimal form.
+func IndexValue(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case int:
+		return strconv.Itoa(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return strconv.FormatFloat(f, 'f', -1, 64), true
+		}
+		return "", false
+	default:
+		return "", false
+	}
+}
+
+// attributeTags returns the tags of the attributes of span, with the OTLP type names of their values.
+func attributeTags(span *internalspan.InternalSpan) map[string]string {
+	tags := make(map[string]string)
+	add := func(prefix string, attributes internalspan.Attributes) {
+		for key, value := range attributes {
+			tags[prefix+key] = attributeType(value)
+		}
+	}
+	if span.Resource != nil {
+		add("resource.attributes.", span.Resource.Attributes)
+	}
+	if span.Scope != nil {
+		add("scope.attributes.", span.Scope.Attributes)
+	}
+	add("span.attributes.", span.Span.Attributes)
+	for _, event := range span.Span.Events {
+		if event != nil {
+			add("span.events.attributes.", event.Attributes)
+		}
+	}
+	for _, link := range span.Span.Links {
+		if link != nil {
+			add("span.links.attributes.", link.Attributes)
+		}
+	}
+	return tags
+}
+
+func attributeType(value any) string {
+	switch value.(type) {
+	case bool:
+		return "Bool"
+	case int, int64:
+		return "Int"
+	case float64:
+		return "Double"
+	case []any:
+		return "Slice"
+	case map[string]any:
+		return "Map"
+	default:
+		return "Str"
+	}
+}
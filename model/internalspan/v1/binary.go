@@ -0,0 +1,179 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internalspanv1
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Encodings of binary attribute values. Binary values are stored as strings in one of the encodings, which is kept
+// apart from the value in the BinaryAttributes next to the attributes, so binary values can be told apart from
+// string values in responses.
+const (
+	BinaryEncodingBase64 = "base64"
+	BinaryEncodingHex    = "hex"
+)
+
+const base64Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// ValidateBinaryEncoding returns an error if encoding isn't a supported binary encoding.
+func ValidateBinaryEncoding(encoding string) error {
+	switch encoding {
+	case BinaryEncodingBase64, BinaryEncodingHex:
+		return nil
+	default:
+		return fmt.Errorf("unsupported binary encoding %q, expected %q or %q", encoding, BinaryEncodingBase64, BinaryEncodingHex)
+	}
+}
+
+// binaryEncoding returns the supported encoding used for encoding, unsupported encodings fall back to base64.
+func binaryEncoding(encoding string) string {
+	if encoding == BinaryEncodingHex {
+		return BinaryEncodingHex
+	}
+	return BinaryEncodingBase64
+}
+
+// EncodeBinaryValue encodes a binary attribute value as a string with the given encoding.
+// Unsupported encodings fall back to base64.
+func EncodeBinaryValue(b []byte, encoding string) string {
+	if binaryEncoding(encoding) == BinaryEncodingHex {
+		return hex.EncodeToString(b)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// DecodeBinaryValue decodes a value encoded by EncodeBinaryValue with the given encoding.
+func DecodeBinaryValue(s string, encoding string) ([]byte, error) {
+	switch encoding {
+	case BinaryEncodingBase64:
+		return base64.StdEncoding.DecodeString(s)
+	case BinaryEncodingHex:
+		return hex.DecodeString(s)
+	default:
+		return nil, ValidateBinaryEncoding(encoding)
+	}
+}
+
+// BinaryValuePrefixes returns string prefixes matching the values encoded with the given encoding which start with
+// the given bytes. A value starts with the bytes if it starts with any of the returned prefixes.
+func BinaryValuePrefixes(prefix []byte, encoding string) []string {
+	if binaryEncoding(encoding) == BinaryEncodingHex {
+		return []string{hex.EncodeToString(prefix)}
+	}
+
+	// every 3 bytes are encoded as 4 base64 characters, the characters encoding
+	// the remaining 1-2 bytes are only partly determined by them
+	full := len(prefix) / 3 * 3
+	base := base64.StdEncoding.EncodeToString(prefix[:full])
+	var prefixes []string
+	switch rest := prefix[full:]; len(rest) {
+	case 0:
+		prefixes = append(prefixes, base)
+	case 1:
+		first := string(base64Alphabet[rest[0]>>2])
+		for i := 0; i < 16; i++ {
+			prefixes = append(prefixes, base+first+string(base64Alphabet[(rest[0]&0x3)<<4|byte(i)]))
+		}
+	case 2:
+		first := string(base64Alphabet[rest[0]>>2]) + string(base64Alphabet[(rest[0]&0x3)<<4|rest[1]>>4])
+		for i := 0; i < 4; i++ {
+			prefixes = append(prefixes, base+first+string(base64Alphabet[(rest[1]&0xf)<<2|byte(i)]))
+		}
+	}
+	return prefixes
+}
+
+// NormalizeAttributes prepares attribute values for storage in place: binary values are encoded with the given
+// encoding, and invalid UTF-8 sequences in strings are replaced, in nested values as well. It returns the encodings
+// of the binary attributes it encoded, binary values nested in array and kvlist attributes aren't included, as
+// they're not told apart from their string siblings.
+func NormalizeAttributes(attributes Attributes, encoding string) BinaryAttributes {
+	var binary BinaryAttributes
+	for k, v := range attributes {
+		if b, ok := v.([]byte); ok {
+			if binary == nil {
+				binary = BinaryAttributes{}
+			}
+			attributes[k] = EncodeBinaryValue(b, encoding)
+			binary[k] = binaryEncoding(encoding)
+			continue
+		}
+		attributes[k] = normalizeAttributeValue(v, encoding)
+	}
+	return binary
+}
+
+func normalizeAttributeValue(v any, encoding string) any {
+	switch value := v.(type) {
+	case []byte:
+		return EncodeBinaryValue(value, encoding)
+	case string:
+		if !utf8.ValidString(value) {
+			return strings.ToValidUTF8(value, string(utf8.RuneError))
+		}
+		return value
+	case map[string]any:
+		for k, nested := range value {
+			value[k] = normalizeAttributeValue(nested, encoding)
+		}
+		return value
+	case []any:
+		for i := range value {
+			value[i] = normalizeAttributeValue(value[i], encoding)
+		}
+		return value
+	default:
+		return v
+	}
+}
+
+// withBinaryAttributes adds the encodings of the binary attributes normalized to binary. The resource and scope of
+// spans are shared by the spans of a batch, and their attributes are only encoded when the first span is normalized.
+func withBinaryAttributes(binary BinaryAttributes, normalized BinaryAttributes) BinaryAttributes {
+	if binary == nil {
+		return normalized
+	}
+	for k, encoding := range normalized {
+		binary[k] = encoding
+	}
+	return binary
+}
+
+// NormalizeAttributes normalizes the attributes of the span, its resource, scope, events and links, and records the
+// encodings of their binary attributes in their BinaryAttributes.
+func (s *InternalSpan) NormalizeAttributes(encoding string) {
+	if s.Resource != nil {
+		s.Resource.BinaryAttributes = withBinaryAttributes(s.Resource.BinaryAttributes, NormalizeAttributes(s.Resource.Attributes, encoding))
+	}
+	if s.Scope != nil {
+		s.Scope.BinaryAttributes = withBinaryAttributes(s.Scope.BinaryAttributes, NormalizeAttributes(s.Scope.Attributes, encoding))
+	}
+	if s.Span != nil {
+		s.Span.BinaryAttributes = withBinaryAttributes(s.Span.BinaryAttributes, NormalizeAttributes(s.Span.Attributes, encoding))
+		for _, e := range s.Span.Events {
+			e.BinaryAttributes = withBinaryAttributes(e.BinaryAttributes, NormalizeAttributes(e.Attributes, encoding))
+		}
+		for _, l := range s.Span.Links {
+			l.BinaryAttributes = withBinaryAttributes(l.BinaryAttributes, NormalizeAttributes(l.Attributes, encoding))
+		}
+	}
+}
@@ -0,0 +1,113 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internalspanv1
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEncodeBinaryValue(t *testing.T) {
+	b := []byte{0x00, 0x01, 0xfe, 0xff}
+	expected := map[string]string{BinaryEncodingBase64: "AAH+/w==", BinaryEncodingHex: "0001feff"}
+	for encoding, value := range expected {
+		encoded := EncodeBinaryValue(b, encoding)
+		if encoded != value {
+			t.Errorf("expected %v to be encoded with %s as %q, got %q", b, encoding, value, encoded)
+		}
+		decoded, err := DecodeBinaryValue(encoded, encoding)
+		if err != nil || !bytes.Equal(decoded, b) {
+			t.Errorf("expected %q to decode to %v, got %v (%v)", encoded, b, decoded, err)
+		}
+	}
+
+	if _, err := DecodeBinaryValue("AAH+/w==", "utf8"); err == nil {
+		t.Error("expected an unsupported encoding to fail decoding")
+	}
+}
+
+func TestBinaryValuePrefixes(t *testing.T) {
+	values := [][]byte{{}, {0x00}, {0xff}, {0x12, 0x34}, {0x12, 0x34, 0x56}, {0x12, 0x34, 0x56, 0x78, 0x9a}, {0x12, 0x35}}
+	for _, prefix := range values {
+		for _, encoding := range []string{BinaryEncodingBase64, BinaryEncodingHex} {
+			prefixes := BinaryValuePrefixes(prefix, encoding)
+			for _, value := range values {
+				expected := bytes.HasPrefix(value, prefix)
+				encoded := EncodeBinaryValue(value, encoding)
+				matched := false
+				for _, p := range prefixes {
+					matched = matched || strings.HasPrefix(encoded, p)
+				}
+				if matched != expected {
+					t.Errorf("prefix %x matching %q: expected %v, got %v", prefix, encoded, expected, matched)
+				}
+			}
+		}
+	}
+}
+
+func TestNormalizeAttributes(t *testing.T) {
+	attributes := Attributes{
+		"binary":  []byte{0x01, 0x02},
+		"invalid": "a\xffb",
+		"valid":   "0102",
+		"nested":  map[string]any{"binary": []byte{0x01}},
+		"slice":   []any{[]byte{0x02}, 1},
+	}
+
+	binary := NormalizeAttributes(attributes, BinaryEncodingHex)
+
+	expected := map[string]any{
+		"binary":  "0102",
+		"invalid": "a�b",
+		"valid":   "0102",
+	}
+	for k, v := range expected {
+		if attributes[k] != v {
+			t.Errorf("expected %q to be %q, got %q", k, v, attributes[k])
+		}
+	}
+	if nested := attributes["nested"].(map[string]any)["binary"]; nested != "01" {
+		t.Errorf("expected nested binary value to be encoded, got %v", nested)
+	}
+	if slice := attributes["slice"].([]any); slice[0] != "02" || slice[1] != 1 {
+		t.Errorf("expected binary slice values to be encoded, got %v", slice)
+	}
+	if len(binary) != 1 || binary["binary"] != BinaryEncodingHex {
+		t.Errorf("expected only the binary attribute to be typed as hex, got %v", binary)
+	}
+}
+
+func TestNormalizeSpanAttributes(t *testing.T) {
+	resource := &Resource{Attributes: Attributes{"id": []byte{0x01}}}
+	first := &InternalSpan{Resource: resource, Span: &Span{Attributes: Attributes{"payload": []byte{0x02}}}}
+	second := &InternalSpan{Resource: resource, Span: &Span{Attributes: Attributes{"payload": "text"}}}
+
+	first.NormalizeAttributes(BinaryEncodingBase64)
+	second.NormalizeAttributes(BinaryEncodingBase64)
+
+	if resource.BinaryAttributes["id"] != BinaryEncodingBase64 {
+		t.Errorf("expected the shared resource attribute to stay typed, got %v", resource.BinaryAttributes)
+	}
+	if first.Span.BinaryAttributes["payload"] != BinaryEncodingBase64 {
+		t.Errorf("expected the binary span attribute to be typed, got %v", first.Span.BinaryAttributes)
+	}
+	if second.Span.BinaryAttributes != nil {
+		t.Errorf("expected the string span attribute not to be typed, got %v", second.Span.BinaryAttributes)
+	}
+}
@@ -0,0 +1,105 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package internalspanv1
+
+import (
+	"math"
+	"strings"
+)
+
+// The OTLP enum name prefixes of span kinds and status codes, e.g. SPAN_KIND_SERVER and STATUS_CODE_ERROR
+const (
+	spanKindEnumPrefix   = "SPAN_KIND_"
+	statusCodeEnumPrefix = "STATUS_CODE_"
+)
+
+// spanKinds and statusCodes are the stored names of the span kinds and status codes, indexed by their OTLP enum numbers.
+var (
+	spanKinds   = []string{"Unspecified", "Internal", "Server", "Client", "Producer", "Consumer"}
+	statusCodes = []string{"Unset", "Ok", "Error"}
+)
+
+// NormalizeSpanKind returns the stored name of a span kind (e.g. "Server") given by its stored name,
+// its OTLP enum name (e.g. "SPAN_KIND_SERVER") or its OTLP enum number (e.g. 2).
+// ok is false if v isn't a span kind.
+func NormalizeSpanKind(v any) (kind string, ok bool) {
+	return normalizeEnum(v, spanKindEnumPrefix, spanKinds)
+}
+
+// NormalizeStatusCode returns the stored name of a status code (e.g. "Error") given by its stored name,
+// its OTLP enum name (e.g. "STATUS_CODE_ERROR") or its OTLP enum number (e.g. 2).
+// ok is false if v isn't a status code.
+func NormalizeStatusCode(v any) (code string, ok bool) {
+	return normalizeEnum(v, statusCodeEnumPrefix, statusCodes)
+}
+
+// SpanKindEnumName returns the OTLP enum name of a stored span kind, e.g. "SPAN_KIND_SERVER" of "Server".
+func SpanKindEnumName(kind string) string {
+	return spanKindEnumPrefix + strings.ToUpper(kind)
+}
+
+// StatusCodeEnumName returns the OTLP enum name of a stored status code, e.g. "STATUS_CODE_ERROR" of "Error".
+func StatusCodeEnumName(code string) string {
+	return statusCodeEnumPrefix + strings.ToUpper(code)
+}
+
+// WithEnumNames returns a copy of span with its kind and status code named as their OTLP enums,
+// e.g. SPAN_KIND_SERVER and STATUS_CODE_ERROR. span isn't modified.
+func WithEnumNames(span *InternalSpan) *InternalSpan {
+	if span == nil || span.Span == nil {
+		return span
+	}
+	named := *span
+	inner := *span.Span
+	if inner.Kind != "" {
+		inner.Kind = SpanKindEnumName(inner.Kind)
+	}
+	if inner.Status != nil {
+		status := *inner.Status
+		if status.Code != "" {
+			status.Code = StatusCodeEnumName(status.Code)
+		}
+		inner.Status = &status
+	}
+	named.Span = &inner
+	return &named
+}
+
+func normalizeEnum(v any, prefix string, names []string) (string, bool) {
+	var number float64
+	switch value := v.(type) {
+	case string:
+		name := strings.TrimPrefix(strings.ToUpper(value), prefix)
+		for _, n := range names {
+			if strings.ToUpper(n) == name {
+				return n, true
+			}
+		}
+		return "", false
+	case float64:
+		number = value
+	case int:
+		number = float64(value)
+	case int64:
+		number = float64(value)
+	default:
+		return "", false
+	}
+	if number != math.Trunc(number) || number < 0 || int(number) >= len(names) {
+		return "", false
+	}
+	return names[int(number)], true
+}
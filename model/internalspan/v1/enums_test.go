@@ -0,0 +1,64 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package internalspanv1
+
+import "testing"
+
+func TestNormalizeSpanKind(t *testing.T) {
+	for _, v := range []any{"Server", "server", "SPAN_KIND_SERVER", "span_kind_server", 2.0, 2, int64(2)} {
+		if kind, ok := NormalizeSpanKind(v); !ok || kind != "Server" {
+			t.Errorf("expected %#v to normalize to Server, got %q", v, kind)
+		}
+	}
+	for _, v := range []any{"SPAN_KIND_FOO", "STATUS_CODE_OK", 6.0, -1, 1.5, true, nil} {
+		if kind, ok := NormalizeSpanKind(v); ok {
+			t.Errorf("expected %#v not to be a span kind, got %q", v, kind)
+		}
+	}
+}
+
+func TestNormalizeStatusCode(t *testing.T) {
+	for _, v := range []any{"Error", "STATUS_CODE_ERROR", "ERROR", 2.0} {
+		if code, ok := NormalizeStatusCode(v); !ok || code != "Error" {
+			t.Errorf("expected %#v to normalize to Error, got %q", v, code)
+		}
+	}
+	if code, ok := NormalizeStatusCode("SPAN_KIND_SERVER"); ok {
+		t.Errorf("expected SPAN_KIND_SERVER not to be a status code, got %q", code)
+	}
+}
+
+func TestEnumNames(t *testing.T) {
+	if name := SpanKindEnumName("Server"); name != "SPAN_KIND_SERVER" {
+		t.Errorf("expected SPAN_KIND_SERVER, got %q", name)
+	}
+	if name := StatusCodeEnumName("Ok"); name != "STATUS_CODE_OK" {
+		t.Errorf("expected STATUS_CODE_OK, got %q", name)
+	}
+}
+
+func TestWithEnumNames(t *testing.T) {
+	span := &InternalSpan{Span: &Span{Kind: "Client", Status: &SpanStatus{Code: "Error"}}}
+
+	named := WithEnumNames(span)
+
+	if named.Span.Kind != "SPAN_KIND_CLIENT" || named.Span.Status.Code != "STATUS_CODE_ERROR" {
+		t.Errorf("expected enum names, got %q and %q", named.Span.Kind, named.Span.Status.Code)
+	}
+	if span.Span.Kind != "Client" || span.Span.Status.Code != "Error" {
+		t.Errorf("expected the span not to be modified, got %q and %q", span.Span.Kind, span.Span.Status.Code)
+	}
+}
@@ -18,19 +18,25 @@ package internalspanv1
 
 type Attributes map[string]any
 
+// BinaryAttributes maps the keys of the binary attributes of a span, resource, scope, event or link to the encoding
+// of their values, which are stored as encoded strings (see EncodeBinaryValue).
+type BinaryAttributes map[string]string
+
 type SpanEvent struct {
-	TimeUnixNano           uint64     `json:"timeUnixNano"`
-	Name                   string     `json:"name"`
-	Attributes             Attributes `json:"attributes"`
-	DroppedAttributesCount uint32     `json:"droppedAttributesCount"`
+	TimeUnixNano           uint64           `json:"timeUnixNano"`
+	Name                   string           `json:"name"`
+	Attributes             Attributes       `json:"attributes"`
+	BinaryAttributes       BinaryAttributes `json:"binaryAttributes,omitempty"`
+	DroppedAttributesCount uint32           `json:"droppedAttributesCount"`
 }
 
 type SpanLink struct {
-	TraceId                string     `json:"traceId"`
-	SpanId                 string     `json:"spanId"`
-	TraceState             string     `json:"traceState"`
-	Attributes             Attributes `json:"attributes"`
-	DroppedAttributesCount uint32     `json:"droppedAttributesCount"`
+	TraceId                string           `json:"traceId"`
+	SpanId                 string           `json:"spanId"`
+	TraceState             string           `json:"traceState"`
+	Attributes             Attributes       `json:"attributes"`
+	BinaryAttributes       BinaryAttributes `json:"binaryAttributes,omitempty"`
+	DroppedAttributesCount uint32           `json:"droppedAttributesCount"`
 }
 
 type SpanStatus struct {
@@ -39,37 +45,42 @@ type SpanStatus struct {
 }
 
 type Resource struct {
-	Attributes             Attributes `json:"attributes"`
-	DroppedAttributesCount uint32     `json:"droppedAttributesCount"`
+	Attributes             Attributes       `json:"attributes"`
+	BinaryAttributes       BinaryAttributes `json:"binaryAttributes,omitempty"`
+	DroppedAttributesCount uint32           `json:"droppedAttributesCount"`
 }
 
 type InstrumentationScope struct {
-	Name                   string     `json:"name"`
-	Version                string     `json:"version"`
-	Attributes             Attributes `json:"attributes"`
-	DroppedAttributesCount uint32     `json:"droppedAttributesCount"`
+	Name                   string           `json:"name"`
+	Version                string           `json:"version"`
+	Attributes             Attributes       `json:"attributes"`
+	BinaryAttributes       BinaryAttributes `json:"binaryAttributes,omitempty"`
+	DroppedAttributesCount uint32           `json:"droppedAttributesCount"`
 }
 
 type Span struct {
-	TraceId                string       `json:"traceId"`
-	SpanId                 string       `json:"spanId"`
-	TraceState             string       `json:"traceState"`
-	ParentSpanId           string       `json:"parentSpanId"`
-	Name                   string       `json:"name"`
-	Kind                   string       `json:"kind"`
-	StartTimeUnixNano      uint64       `json:"startTimeUnixNano"`
-	EndTimeUnixNano        uint64       `json:"endTimeUnixNano"`
-	Attributes             Attributes   `json:"attributes"`
-	DroppedAttributesCount uint32       `json:"droppedAttributesCount"`
-	Events                 []*SpanEvent `json:"-"`
-	DroppedEventsCount     uint32       `json:"droppedEventsCount"`
-	Links                  []*SpanLink  `json:"-"`
-	DroppedLinksCount      uint32       `json:"droppedLinksCount"`
-	Status                 *SpanStatus  `json:"status"`
+	TraceId                string           `json:"traceId"`
+	SpanId                 string           `json:"spanId"`
+	TraceState             string           `json:"traceState"`
+	ParentSpanId           string           `json:"parentSpanId"`
+	Name                   string           `json:"name"`
+	Kind                   string           `json:"kind"`
+	StartTimeUnixNano      uint64           `json:"startTimeUnixNano"`
+	EndTimeUnixNano        uint64           `json:"endTimeUnixNano"`
+	Attributes             Attributes       `json:"attributes"`
+	BinaryAttributes       BinaryAttributes `json:"binaryAttributes,omitempty"`
+	DroppedAttributesCount uint32           `json:"droppedAttributesCount"`
+	Events                 []*SpanEvent     `json:"-"`
+	DroppedEventsCount     uint32           `json:"droppedEventsCount"`
+	Links                  []*SpanLink      `json:"-"`
+	DroppedLinksCount      uint32           `json:"droppedLinksCount"`
+	Status                 *SpanStatus      `json:"status"`
 }
 
 type ExternalFields struct {
 	DurationNano uint64 `json:"durationNano"`
+	// ChildCount is the number of direct children of the span, i.e. its fan-out
+	ChildCount uint64 `json:"childCount"`
 }
 
 type InternalSpan struct {
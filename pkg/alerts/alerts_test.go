@@ -0,0 +1,231 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	"github.com/teletrace/teletrace/pkg/metadatastore/memory"
+	"github.com/teletrace/teletrace/pkg/model"
+	alertsv1 "github.com/teletrace/teletrace/pkg/model/alerts/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/acl"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// statusSpanReader counts the spans of each status code, failing if err is set.
+type statusSpanReader struct {
+	spanreader.SpanReader
+	counts   map[string]int
+	err      error
+	requests []tagsquery.TagValuesRequest
+	roles    []string
+}
+
+func (sr *statusSpanReader) GetTagsValues(ctx context.Context, r tagsquery.TagValuesRequest, tags []string) (map[string]*tagsquery.TagValuesResponse, error) {
+	sr.requests = append(sr.requests, r)
+	role, _ := acl.RoleFromContext(ctx)
+	sr.roles = append(sr.roles, role)
+	if sr.err != nil {
+		return nil, sr.err
+	}
+	values := &tagsquery.TagValuesResponse{}
+	for code, count := range sr.counts {
+		values.Values = append(values.Values, tagsquery.TagValueInfo{Value: code, Count: count})
+	}
+	return map[string]*tagsquery.TagValuesResponse{statusCodeTag: values}, nil
+}
+
+func (sr *statusSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	res := &spansquery.SearchResponse{}
+	for _, traceId := range []string{"trace-1", "trace-1", "trace-2"} {
+		res.Spans = append(res.Spans, &internalspan.InternalSpan{Span: &internalspan.Span{TraceId: traceId}})
+	}
+	return res, nil
+}
+
+func TestEvaluator(t *testing.T) {
+	ctx := context.Background()
+	rules := NewStore(memory.NewMetadataStore())
+	slow, err := rules.Create(ctx, alertsv1.CreateRuleRequest{
+		Name: "slow checkout",
+		SearchFilters: []model.SearchFilter{{KeyValueFilter: &model.KeyValueFilter{
+			Key: "span.duration", Operator: spansquery.OPERATOR_GT, Value: float64(2e9),
+		}}},
+		Condition:     alertsv1.CONDITION_SPAN_COUNT,
+		Threshold:     0,
+		WindowSeconds: 300,
+	}, "")
+	assert.NoError(t, err)
+	errorRate, err := rules.Create(ctx, alertsv1.CreateRuleRequest{
+		Name:          "checkout errors",
+		Condition:     alertsv1.CONDITION_ERROR_RATE,
+		Threshold:     0.05,
+		WindowSeconds: 300,
+	}, "payments")
+	assert.NoError(t, err)
+
+	sr := &statusSpanReader{counts: map[string]int{"Ok": 18, "Error": 2}}
+	now := time.Unix(1700000000, 0)
+	evaluator := NewEvaluator(zap.NewNop(), sr, rules, time.Minute)
+	evaluator.now = func() time.Time { return now }
+	assert.Equal(t, alertsv1.STATE_PENDING, evaluator.State(slow.Id).State)
+
+	assert.NoError(t, evaluator.EvaluateAll(ctx))
+	assert.Equal(t, uint64(now.Add(-5*time.Minute).UnixNano()), sr.requests[0].Timeframe.StartTime)
+	assert.Equal(t, uint64(now.UnixNano()), sr.requests[0].Timeframe.EndTime)
+	assert.Len(t, sr.requests[0].SearchFilters, 1)
+	assert.Equal(t, []string{"", "payments"}, sr.roles)
+
+	state := evaluator.State(slow.Id)
+	assert.Equal(t, alertsv1.STATE_FIRING, state.State)
+	assert.Equal(t, float64(20), state.Value)
+	assert.Equal(t, uint64(now.UnixNano()), state.FiringSinceUnixNano)
+	state = evaluator.State(errorRate.Id)
+	assert.Equal(t, alertsv1.STATE_FIRING, state.State)
+	assert.Equal(t, 0.1, state.Value)
+
+	// a failed evaluation keeps the rule firing since its first firing evaluation
+	sr.err = errors.New("backend unavailable")
+	firstFiring := now
+	now = now.Add(time.Minute)
+	assert.NoError(t, evaluator.EvaluateAll(ctx))
+	state = evaluator.State(slow.Id)
+	assert.Equal(t, alertsv1.STATE_ERROR, state.State)
+	assert.Equal(t, "backend unavailable", state.Error)
+	assert.Equal(t, uint64(firstFiring.UnixNano()), state.FiringSinceUnixNano)
+
+	sr.err = nil
+	sr.counts = map[string]int{"Ok": 100}
+	assert.NoError(t, evaluator.EvaluateAll(ctx))
+	assert.Equal(t, uint64(firstFiring.UnixNano()), evaluator.State(slow.Id).FiringSinceUnixNano)
+	state = evaluator.State(errorRate.Id)
+	assert.Equal(t, alertsv1.STATE_OK, state.State)
+	assert.Zero(t, state.FiringSinceUnixNano)
+
+	assert.NoError(t, rules.Delete(ctx, slow.Id))
+	assert.ErrorIs(t, rules.Delete(ctx, slow.Id), metadatastore.ErrNotFound)
+	assert.NoError(t, evaluator.EvaluateAll(ctx))
+	assert.Equal(t, alertsv1.STATE_PENDING, evaluator.State(slow.Id).State)
+}
+
+func TestCreateRuleRequestValidation(t *testing.T) {
+	valid := alertsv1.CreateRuleRequest{Name: "errors", Condition: alertsv1.CONDITION_ERROR_RATE, Threshold: 0.05, WindowSeconds: 300}
+	assert.NoError(t, valid.Validate())
+
+	for name, modify := range map[string]func(r *alertsv1.CreateRuleRequest){
+		"no name":           func(r *alertsv1.CreateRuleRequest) { r.Name = "" },
+		"unknown condition": func(r *alertsv1.CreateRuleRequest) { r.Condition = "latency" },
+		"rate above 1":      func(r *alertsv1.CreateRuleRequest) { r.Threshold = 5 },
+		"no window":         func(r *alertsv1.CreateRuleRequest) { r.WindowSeconds = 0 },
+		"window too long":   func(r *alertsv1.CreateRuleRequest) { r.WindowSeconds = alertsv1.MaxWindowSeconds + 1 },
+	} {
+		r := valid
+		modify(&r)
+		assert.Error(t, r.Validate(), name)
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []map[string]any
+	failures := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/invalid" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var decoded map[string]any
+		assert.NoError(t, json.Unmarshal(body, &decoded))
+		bodies = append(bodies, decoded)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	rules := NewStore(memory.NewMetadataStore())
+	rule, err := rules.Create(ctx, alertsv1.CreateRuleRequest{
+		Name:          "checkout errors",
+		Condition:     alertsv1.CONDITION_ERROR_RATE,
+		Threshold:     0.05,
+		WindowSeconds: 300,
+		Webhooks: []alertsv1.Webhook{
+			{Url: server.URL + "/slack", Format: alertsv1.WEBHOOK_FORMAT_SLACK},
+			{Url: server.URL + "/invalid"},
+		},
+	}, "")
+	assert.NoError(t, err)
+
+	notifier := NewNotifier(zap.NewNop(), NotifierConfig{UIBaseURL: "https://teletrace.example.com/", MaxAttempts: 3, Timeout: time.Second})
+	notifier.retryBackoff = time.Millisecond
+	sr := &statusSpanReader{counts: map[string]int{"Ok": 1, "Error": 1}}
+	evaluator := NewEvaluator(zap.NewNop(), sr, rules, time.Minute)
+	evaluator.SetNotifier(notifier)
+
+	assert.NoError(t, evaluator.EvaluateAll(ctx))
+	notifier.Wait()
+	// still firing, not notified again
+	assert.NoError(t, evaluator.EvaluateAll(ctx))
+	notifier.Wait()
+
+	deliveries := notifier.Deliveries(rule.Id)
+	assert.Len(t, deliveries, 2)
+	for _, delivery := range deliveries {
+		assert.Equal(t, alertsv1.EVENT_FIRING, delivery.Event)
+		if delivery.Url == server.URL+"/slack" {
+			assert.Equal(t, alertsv1.DELIVERY_DELIVERED, delivery.Status)
+			assert.Equal(t, 2, delivery.Attempts)
+		} else {
+			assert.Equal(t, alertsv1.DELIVERY_FAILED, delivery.Status)
+			assert.Equal(t, 1, delivery.Attempts)
+			assert.Contains(t, delivery.Error, "400")
+		}
+	}
+	assert.Len(t, bodies, 1)
+	text := bodies[0]["text"].(string)
+	assert.Contains(t, text, "*checkout errors* is firing")
+	assert.Contains(t, text, "<https://teletrace.example.com/trace/trace-1|Example trace 1>")
+	assert.Contains(t, text, "<https://teletrace.example.com/trace/trace-2|Example trace 2>")
+
+	rule.Webhooks = rule.Webhooks[:1]
+	rule.Webhooks[0].Format = alertsv1.WEBHOOK_FORMAT_JSON
+	notifier.Notify(*rule, alertsv1.Notification{RuleId: rule.Id, Event: alertsv1.EVENT_RESOLVED, Value: 0.01})
+	notifier.Wait()
+	assert.Len(t, bodies, 2)
+	assert.Equal(t, alertsv1.EVENT_RESOLVED, bodies[1]["event"])
+	assert.Len(t, notifier.Deliveries(rule.Id), 3)
+}
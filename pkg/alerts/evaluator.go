@@ -0,0 +1,266 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	alertsv1 "github.com/teletrace/teletrace/pkg/model/alerts/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/acl"
+
+	"go.uber.org/zap"
+)
+
+const (
+	statusCodeTag   = "span.status.code"
+	errorStatusCode = "Error"
+	// statusCodeValuesLimit covers all the span status codes: Unset, Ok and Error
+	statusCodeValuesLimit = 10
+	// exampleSpansLimit is the number of matching spans whose traces are linked from firing notifications
+	exampleSpansLimit = 5
+)
+
+// Evaluator periodically evaluates the alert rules against the span reader, and keeps their state in memory.
+type Evaluator struct {
+	logger     *zap.Logger
+	spanReader spanreader.SpanReader
+	rules      *Store
+	interval   time.Duration
+	notifier   *Notifier
+	now        func() time.Time
+
+	mu     sync.RWMutex
+	states map[string]alertsv1.RuleState
+}
+
+func NewEvaluator(logger *zap.Logger, sr spanreader.SpanReader, rules *Store, interval time.Duration) *Evaluator {
+	return &Evaluator{
+		logger:     logger,
+		spanReader: sr,
+		rules:      rules,
+		interval:   interval,
+		now:        time.Now,
+		states:     make(map[string]alertsv1.RuleState),
+	}
+}
+
+// SetNotifier sets the notifier of the rules starting to fire and resolving.
+func (e *Evaluator) SetNotifier(notifier *Notifier) {
+	e.notifier = notifier
+}
+
+// Run evaluates the rules every interval, until ctx is done.
+func (e *Evaluator) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		if err := e.EvaluateAll(ctx); err != nil {
+			e.logger.Warn("Failed to evaluate alert rules", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// EvaluateAll evaluates every rule once. A failed rule evaluation is recorded in the rule state,
+// only failing to list the rules is returned.
+func (e *Evaluator) EvaluateAll(ctx context.Context) error {
+	rules, err := e.rules.List(ctx)
+	if err != nil {
+		return fmt.Errorf("could not list alert rules: %w", err)
+	}
+
+	states := make(map[string]alertsv1.RuleState, len(rules))
+	for _, rule := range rules {
+		states[rule.Id] = e.evaluateRule(ctx, rule)
+	}
+
+	// states of deleted rules are dropped
+	e.mu.Lock()
+	previous := e.states
+	e.states = states
+	e.mu.Unlock()
+	if e.notifier != nil {
+		for ruleId := range previous {
+			if _, ok := states[ruleId]; !ok {
+				e.notifier.Forget(ruleId)
+			}
+		}
+	}
+	return nil
+}
+
+// State returns the state of a rule as of its last evaluation.
+func (e *Evaluator) State(ruleId string) alertsv1.RuleState {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if state, ok := e.states[ruleId]; ok {
+		return state
+	}
+	return alertsv1.RuleState{State: alertsv1.STATE_PENDING}
+}
+
+func (e *Evaluator) evaluateRule(ctx context.Context, rule alertsv1.Rule) alertsv1.RuleState {
+	if rule.Role != "" {
+		ctx = acl.WithRole(ctx, rule.Role)
+	}
+	now := e.now()
+	previous := e.State(rule.Id)
+	state := alertsv1.RuleState{EvaluatedAtUnixNano: uint64(now.UnixNano())}
+
+	value, err := e.evaluate(ctx, rule, now)
+	if err != nil {
+		e.logger.Warn("Failed to evaluate alert rule", zap.String("rule", rule.Id), zap.Error(err))
+		state.State = alertsv1.STATE_ERROR
+		state.Error = err.Error()
+		// a rule keeps firing through failed evaluations, so a flaky backend doesn't resolve it
+		state.FiringSinceUnixNano = previous.FiringSinceUnixNano
+		return state
+	}
+
+	state.Value = value
+	if value <= rule.Threshold {
+		state.State = alertsv1.STATE_OK
+		if previous.FiringSinceUnixNano != 0 {
+			e.logger.Info("Alert rule resolved",
+				zap.String("rule", rule.Id), zap.String("name", rule.Name), zap.Float64("value", value))
+			// the resolved notification tells since when the rule fired
+			resolved := state
+			resolved.FiringSinceUnixNano = previous.FiringSinceUnixNano
+			e.notify(ctx, rule, alertsv1.EVENT_RESOLVED, resolved)
+		}
+		return state
+	}
+
+	state.State = alertsv1.STATE_FIRING
+	state.FiringSinceUnixNano = previous.FiringSinceUnixNano
+	if state.FiringSinceUnixNano == 0 {
+		state.FiringSinceUnixNano = state.EvaluatedAtUnixNano
+		e.logger.Info("Alert rule started firing",
+			zap.String("rule", rule.Id), zap.String("name", rule.Name), zap.Float64("value", value))
+		e.notify(ctx, rule, alertsv1.EVENT_FIRING, state)
+	}
+	return state
+}
+
+// evaluate returns the value of the rule condition over its window ending at now.
+func (e *Evaluator) evaluate(ctx context.Context, rule alertsv1.Rule, now time.Time) (float64, error) {
+	// the spans are counted by their status codes, which covers both conditions with a single aggregation
+	res, err := e.spanReader.GetTagsValues(ctx, tagsquery.TagValuesRequest{
+		Timeframe: &model.Timeframe{
+			StartTime: uint64(now.Add(-time.Duration(rule.WindowSeconds) * time.Second).UnixNano()),
+			EndTime:   uint64(now.UnixNano()),
+		},
+		SearchFilters: spanreader.CopyFilters(rule.SearchFilters),
+		Limit:         statusCodeValuesLimit,
+	}, []string{statusCodeTag})
+	if err != nil {
+		return 0, err
+	}
+
+	total, errorCount := 0, 0
+	if values := res[statusCodeTag]; values != nil {
+		for _, value := range values.Values {
+			total += value.Count
+			if fmt.Sprint(value.Value) == errorStatusCode {
+				errorCount += value.Count
+			}
+		}
+	}
+
+	switch rule.Condition {
+	case alertsv1.CONDITION_SPAN_COUNT:
+		return float64(total), nil
+	case alertsv1.CONDITION_ERROR_RATE:
+		if total == 0 {
+			return 0, nil
+		}
+		return float64(errorCount) / float64(total), nil
+	default:
+		return 0, fmt.Errorf("unknown condition %q", rule.Condition)
+	}
+}
+
+// notify sends a notification of a rule event, with links to example traces of a firing rule.
+func (e *Evaluator) notify(ctx context.Context, rule alertsv1.Rule, event string, state alertsv1.RuleState) {
+	if e.notifier == nil || len(rule.Webhooks) == 0 {
+		return
+	}
+	notification := alertsv1.Notification{
+		RuleId:              rule.Id,
+		RuleName:            rule.Name,
+		Event:               event,
+		Condition:           rule.Condition,
+		Threshold:           rule.Threshold,
+		Value:               state.Value,
+		EvaluatedAtUnixNano: state.EvaluatedAtUnixNano,
+		FiringSinceUnixNano: state.FiringSinceUnixNano,
+	}
+	if event == alertsv1.EVENT_FIRING {
+		traceIds, err := e.exampleTraces(ctx, rule, time.Unix(0, int64(state.EvaluatedAtUnixNano)))
+		if err != nil {
+			// the notification is still useful without its trace links
+			e.logger.Warn("Failed to get example traces of alert rule", zap.String("rule", rule.Id), zap.Error(err))
+		}
+		notification.TraceLinks = e.notifier.TraceLinks(traceIds)
+	}
+	e.notifier.Notify(rule, notification)
+}
+
+// exampleTraces returns the IDs of the traces of the most recent spans matching a rule,
+// limited to the spans with an error status for error rate rules.
+func (e *Evaluator) exampleTraces(ctx context.Context, rule alertsv1.Rule, now time.Time) ([]string, error) {
+	filters := spanreader.CopyFilters(rule.SearchFilters)
+	if rule.Condition == alertsv1.CONDITION_ERROR_RATE {
+		filters = append(filters, model.SearchFilter{KeyValueFilter: &model.KeyValueFilter{
+			Key:      statusCodeTag,
+			Operator: spansquery.OPERATOR_EQUALS,
+			Value:    errorStatusCode,
+		}})
+	}
+	res, err := e.spanReader.Search(ctx, spansquery.SearchRequest{
+		Timeframe: model.Timeframe{
+			StartTime: uint64(now.Add(-time.Duration(rule.WindowSeconds) * time.Second).UnixNano()),
+			EndTime:   uint64(now.UnixNano()),
+		},
+		SearchFilters: filters,
+		Sort:          []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: false}},
+		Limit:         exampleSpansLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var traceIds []string
+	seen := make(map[string]bool)
+	for _, span := range res.Spans {
+		if traceId := span.Span.TraceId; !seen[traceId] {
+			seen[traceId] = true
+			traceIds = append(traceIds, traceId)
+		}
+	}
+	return traceIds, nil
+}
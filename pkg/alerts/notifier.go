@@ -0,0 +1,215 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	alertsv1 "github.com/teletrace/teletrace/pkg/model/alerts/v1"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// maxDeliveries is the number of recent deliveries tracked per rule
+	maxDeliveries = 20
+	// retryBackoff is the delay before the first retry of a failed delivery, doubled on every retry
+	retryBackoff = time.Second
+)
+
+// NotifierConfig configures the delivery of notifications to webhooks.
+type NotifierConfig struct {
+	// UIBaseURL is the URL of the Teletrace UI the trace links point to
+	UIBaseURL   string
+	MaxAttempts int
+	// Timeout bounds every delivery attempt
+	Timeout time.Duration
+}
+
+// Notifier posts the notifications of rules to their webhooks in the background, retrying failed deliveries,
+// and tracks the recent deliveries of every rule in memory.
+type Notifier struct {
+	logger       *zap.Logger
+	client       *http.Client
+	cfg          NotifierConfig
+	retryBackoff time.Duration
+	now          func() time.Time
+	wg           sync.WaitGroup
+
+	mu         sync.Mutex
+	deliveries map[string][]*alertsv1.Delivery
+}
+
+func NewNotifier(logger *zap.Logger, cfg NotifierConfig) *Notifier {
+	return &Notifier{
+		logger:       logger,
+		client:       &http.Client{Timeout: cfg.Timeout},
+		cfg:          cfg,
+		retryBackoff: retryBackoff,
+		now:          time.Now,
+		deliveries:   make(map[string][]*alertsv1.Delivery),
+	}
+}
+
+// Notify delivers a notification to every webhook of the rule in the background.
+func (n *Notifier) Notify(rule alertsv1.Rule, notification alertsv1.Notification) {
+	for _, webhook := range rule.Webhooks {
+		delivery := n.track(rule.Id, webhook.Url, notification.Event)
+		n.wg.Add(1)
+		go func(webhook alertsv1.Webhook) {
+			defer n.wg.Done()
+			n.deliver(webhook, notification, delivery)
+		}(webhook)
+	}
+}
+
+// TraceLinks returns the links to traces in the Teletrace UI.
+func (n *Notifier) TraceLinks(traceIds []string) []string {
+	links := make([]string, 0, len(traceIds))
+	for _, traceId := range traceIds {
+		links = append(links, strings.TrimSuffix(n.cfg.UIBaseURL, "/")+"/trace/"+traceId)
+	}
+	return links
+}
+
+// Deliveries returns the recent deliveries of the notifications of a rule, the most recent first.
+func (n *Notifier) Deliveries(ruleId string) []alertsv1.Delivery {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	deliveries := make([]alertsv1.Delivery, 0, len(n.deliveries[ruleId]))
+	for _, delivery := range n.deliveries[ruleId] {
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries
+}
+
+// Forget drops the deliveries of a deleted rule.
+func (n *Notifier) Forget(ruleId string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.deliveries, ruleId)
+}
+
+// Wait blocks until the pending deliveries complete.
+func (n *Notifier) Wait() {
+	n.wg.Wait()
+}
+
+func (n *Notifier) track(ruleId string, url string, event string) *alertsv1.Delivery {
+	delivery := &alertsv1.Delivery{
+		Url:               url,
+		Event:             event,
+		Status:            alertsv1.DELIVERY_PENDING,
+		UpdatedAtUnixNano: uint64(n.now().UnixNano()),
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	deliveries := append([]*alertsv1.Delivery{delivery}, n.deliveries[ruleId]...)
+	if len(deliveries) > maxDeliveries {
+		deliveries = deliveries[:maxDeliveries]
+	}
+	n.deliveries[ruleId] = deliveries
+	return delivery
+}
+
+func (n *Notifier) update(delivery *alertsv1.Delivery, status string, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delivery.Status = status
+	delivery.Attempts++
+	delivery.Error = ""
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	delivery.UpdatedAtUnixNano = uint64(n.now().UnixNano())
+}
+
+// deliver posts the notification to the webhook until it succeeds, fails permanently or runs out of attempts.
+func (n *Notifier) deliver(webhook alertsv1.Webhook, notification alertsv1.Notification, delivery *alertsv1.Delivery) {
+	body, err := payload(webhook, notification)
+	if err != nil {
+		n.update(delivery, alertsv1.DELIVERY_FAILED, err)
+		return
+	}
+
+	backoff := n.retryBackoff
+	for attempt := 1; ; attempt++ {
+		retryable, err := n.post(webhook.Url, body)
+		if err == nil {
+			n.update(delivery, alertsv1.DELIVERY_DELIVERED, nil)
+			return
+		}
+		if !retryable || attempt >= n.cfg.MaxAttempts {
+			n.logger.Warn("Failed to deliver alert notification",
+				zap.String("rule", notification.RuleId), zap.Int("attempts", attempt), zap.Error(err))
+			n.update(delivery, alertsv1.DELIVERY_FAILED, err)
+			return
+		}
+		n.update(delivery, alertsv1.DELIVERY_PENDING, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post returns whether a failed post may succeed when retried.
+func (n *Notifier) post(url string, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), n.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	// other client errors won't be fixed by retrying the same payload
+	retryable := res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusRequestTimeout
+	return retryable, err
+}
+
+// payload returns the body posted to a webhook.
+func payload(webhook alertsv1.Webhook, notification alertsv1.Notification) ([]byte, error) {
+	if webhook.Format != alertsv1.WEBHOOK_FORMAT_SLACK {
+		return json.Marshal(notification)
+	}
+
+	var text strings.Builder
+	switch notification.Event {
+	case alertsv1.EVENT_FIRING:
+		fmt.Fprintf(&text, ":rotating_light: *%s* is firing", notification.RuleName)
+	default:
+		fmt.Fprintf(&text, ":white_check_mark: *%s* resolved", notification.RuleName)
+	}
+	fmt.Fprintf(&text, "\n%s is %g, threshold %g", notification.Condition, notification.Value, notification.Threshold)
+	for i, link := range notification.TraceLinks {
+		fmt.Fprintf(&text, "\n<%s|Example trace %d>", link, i+1)
+	}
+	return json.Marshal(map[string]string{"text": text.String()})
+}
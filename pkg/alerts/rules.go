@@ -0,0 +1,107 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package alerts
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	alertsv1 "github.com/teletrace/teletrace/pkg/model/alerts/v1"
+)
+
+// Namespace is the metadata store namespace holding the alert rules, keyed by their ids.
+const Namespace = "alert-rules"
+
+// Store persists the alert rules in a metadata store.
+type Store struct {
+	store metadatastore.MetadataStore
+	now   func() time.Time
+}
+
+func NewStore(store metadatastore.MetadataStore) *Store {
+	return &Store{store: store, now: time.Now}
+}
+
+// Create persists a new rule, evaluated on behalf of role if the access control is enabled, and returns it.
+func (s *Store) Create(ctx context.Context, r alertsv1.CreateRuleRequest, role string) (*alertsv1.Rule, error) {
+	id, err := newId()
+	if err != nil {
+		return nil, err
+	}
+	rule := alertsv1.Rule{
+		Id:                id,
+		Name:              r.Name,
+		SearchFilters:     r.SearchFilters,
+		Condition:         r.Condition,
+		Threshold:         r.Threshold,
+		WindowSeconds:     r.WindowSeconds,
+		Webhooks:          r.Webhooks,
+		Role:              role,
+		CreatedAtUnixNano: uint64(s.now().UnixNano()),
+	}
+	if err := metadatastore.PutJSON(ctx, s.store, Namespace, id, rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// Get returns a rule, or metadatastore.ErrNotFound.
+func (s *Store) Get(ctx context.Context, id string) (*alertsv1.Rule, error) {
+	var rule alertsv1.Rule
+	if err := metadatastore.GetJSON(ctx, s.store, Namespace, id, &rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// List returns all the rules, the oldest first.
+func (s *Store) List(ctx context.Context) ([]alertsv1.Rule, error) {
+	records, err := s.store.List(ctx, Namespace)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]alertsv1.Rule, 0, len(records))
+	for _, record := range records {
+		var rule alertsv1.Rule
+		if err := json.Unmarshal(record.Value, &rule); err != nil {
+			return nil, fmt.Errorf("could not decode alert rule %s: %w", record.Key, err)
+		}
+		rules = append(rules, rule)
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].CreatedAtUnixNano < rules[j].CreatedAtUnixNano
+	})
+	return rules, nil
+}
+
+// Delete removes a rule, or returns metadatastore.ErrNotFound.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	return s.store.Delete(ctx, Namespace, id)
+}
+
+func newId() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("could not generate alert rule id: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,101 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package annotations
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	annotationsv1 "github.com/teletrace/teletrace/pkg/model/annotations/v1"
+)
+
+// namespacePrefix prefixes the metadata store namespace of the annotations of each trace,
+// so the annotations of a trace are listed without reading those of other traces.
+const namespacePrefix = "annotations:"
+
+// Store persists the annotations of traces in a metadata store.
+type Store struct {
+	store metadatastore.MetadataStore
+	now   func() time.Time
+}
+
+func NewStore(store metadatastore.MetadataStore) *Store {
+	return &Store{store: store, now: time.Now}
+}
+
+// Add attaches a new annotation to a trace, and returns it.
+func (s *Store) Add(ctx context.Context, traceId string, r annotationsv1.AddAnnotationRequest) (*annotationsv1.Annotation, error) {
+	id, err := newId()
+	if err != nil {
+		return nil, err
+	}
+	annotation := annotationsv1.Annotation{
+		Id:                id,
+		TraceId:           traceId,
+		SpanId:            r.SpanId,
+		Author:            r.Author,
+		Text:              r.Text,
+		CreatedAtUnixNano: uint64(s.now().UnixNano()),
+	}
+	value, err := json.Marshal(annotation)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode annotation: %w", err)
+	}
+	record := metadatastore.Record{Namespace: namespacePrefix + traceId, Key: id, Value: value}
+	if err := s.store.Create(ctx, record); err != nil {
+		return nil, err
+	}
+	return &annotation, nil
+}
+
+// List returns the annotations of a trace, the oldest first.
+func (s *Store) List(ctx context.Context, traceId string) ([]annotationsv1.Annotation, error) {
+	records, err := s.store.List(ctx, namespacePrefix+traceId)
+	if err != nil {
+		return nil, err
+	}
+	annotations := make([]annotationsv1.Annotation, 0, len(records))
+	for _, record := range records {
+		var annotation annotationsv1.Annotation
+		if err := json.Unmarshal(record.Value, &annotation); err != nil {
+			return nil, fmt.Errorf("could not decode annotation %s: %w", record.Key, err)
+		}
+		annotations = append(annotations, annotation)
+	}
+	sort.SliceStable(annotations, func(i, j int) bool {
+		return annotations[i].CreatedAtUnixNano < annotations[j].CreatedAtUnixNano
+	})
+	return annotations, nil
+}
+
+// Delete removes an annotation of a trace, or returns metadatastore.ErrNotFound.
+func (s *Store) Delete(ctx context.Context, traceId string, id string) error {
+	return s.store.Delete(ctx, namespacePrefix+traceId, id)
+}
+
+func newId() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("could not generate annotation id: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
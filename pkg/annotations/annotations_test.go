@@ -0,0 +1,60 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package annotations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	"github.com/teletrace/teletrace/pkg/metadatastore/memory"
+	annotationsv1 "github.com/teletrace/teletrace/pkg/model/annotations/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotations(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(memory.NewMetadataStore())
+	now := time.Unix(1700000000, 0)
+	store.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	first, err := store.Add(ctx, "trace", annotationsv1.AddAnnotationRequest{Author: "alice", Text: "slow query"})
+	assert.NoError(t, err)
+	second, err := store.Add(ctx, "trace", annotationsv1.AddAnnotationRequest{SpanId: "span", Author: "bob", Text: "retry storm"})
+	assert.NoError(t, err)
+	_, err = store.Add(ctx, "other-trace", annotationsv1.AddAnnotationRequest{Author: "alice", Text: "unrelated"})
+	assert.NoError(t, err)
+
+	annotations, err := store.List(ctx, "trace")
+	assert.NoError(t, err)
+	assert.Equal(t, []annotationsv1.Annotation{*first, *second}, annotations)
+	assert.Equal(t, "span", annotations[1].SpanId)
+
+	assert.NoError(t, store.Delete(ctx, "trace", first.Id))
+	assert.ErrorIs(t, store.Delete(ctx, "trace", first.Id), metadatastore.ErrNotFound)
+	annotations, err = store.List(ctx, "trace")
+	assert.NoError(t, err)
+	assert.Equal(t, []annotationsv1.Annotation{*second}, annotations)
+
+	annotations, err = store.List(ctx, "no-annotations")
+	assert.NoError(t, err)
+	assert.Empty(t, annotations)
+}
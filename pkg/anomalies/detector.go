@@ -0,0 +1,311 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package anomalies
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	anomaliesv1 "github.com/teletrace/teletrace/pkg/model/anomalies/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/acl"
+
+	"go.uber.org/zap"
+)
+
+const (
+	serviceTag   = "resource.attributes.service.name"
+	operationTag = "span.name"
+	durationTag  = "externalFields.durationNano"
+)
+
+// statistics are the compared latency statistics of every operation.
+var statistics = []tagsquery.TagStatistic{tagsquery.P99, tagsquery.AVG}
+
+// Config configures the detection of latency regressions.
+type Config struct {
+	// Interval is the time between detections
+	Interval time.Duration
+	// Window is the duration of the recent spans compared to the baseline
+	Window time.Duration
+	// BaselineOffset is how long the baseline window precedes the compared window, e.g. a week
+	BaselineOffset time.Duration
+	// MinSpans is the minimum number of spans of an operation in both windows for its latency to be compared
+	MinSpans int
+	// RegressionRatio is the ratio of a latency statistic to its baseline flagged as a regression
+	RegressionRatio float64
+	// MaxOperations bounds the operations compared by a single detection
+	MaxOperations int
+	// Roles are the access control roles detected separately, each seeing only its spans. Empty if the access
+	// control is disabled.
+	Roles []string
+}
+
+// Detector periodically baselines the latency of every operation, i.e. the spans of a service with the same name,
+// and finds the operations whose latency regressed, keeping the findings of the last detection in memory.
+type Detector struct {
+	logger     *zap.Logger
+	spanReader spanreader.SpanReader
+	cfg        Config
+	now        func() time.Time
+
+	mu      sync.RWMutex
+	results map[string]anomaliesv1.GetFindingsResponse
+}
+
+func NewDetector(logger *zap.Logger, sr spanreader.SpanReader, cfg Config) *Detector {
+	return &Detector{
+		logger:     logger,
+		spanReader: sr,
+		cfg:        cfg,
+		now:        time.Now,
+		results:    make(map[string]anomaliesv1.GetFindingsResponse),
+	}
+}
+
+// Run detects regressions every interval, until ctx is done.
+func (d *Detector) Run(ctx context.Context) {
+	roles := d.cfg.Roles
+	if len(roles) == 0 {
+		roles = []string{""}
+	}
+
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		for _, role := range roles {
+			if _, err := d.Detect(ctx, role); err != nil {
+				d.logger.Warn("Failed to detect latency anomalies", zap.String("role", role), zap.Error(err))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Findings returns the findings of the last detection on behalf of role.
+func (d *Detector) Findings(role string) anomaliesv1.GetFindingsResponse {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if res, ok := d.results[role]; ok {
+		return res
+	}
+	return anomaliesv1.GetFindingsResponse{Findings: []anomaliesv1.Finding{}}
+}
+
+// Detect compares the latency of the operations within the window ending now to their latency within
+// the baseline window, on behalf of role if the access control is enabled, and keeps the findings.
+func (d *Detector) Detect(ctx context.Context, role string) (*anomaliesv1.GetFindingsResponse, error) {
+	if role != "" {
+		ctx = acl.WithRole(ctx, role)
+	}
+	now := d.now()
+	window := model.Timeframe{
+		StartTime: uint64(now.Add(-d.cfg.Window).UnixNano()),
+		EndTime:   uint64(now.UnixNano()),
+	}
+	baseline := model.Timeframe{
+		StartTime: uint64(now.Add(-d.cfg.Window - d.cfg.BaselineOffset).UnixNano()),
+		EndTime:   uint64(now.Add(-d.cfg.BaselineOffset).UnixNano()),
+	}
+
+	operations, err := d.operations(ctx, window)
+	if err != nil {
+		return nil, fmt.Errorf("could not list operations: %w", err)
+	}
+
+	res := anomaliesv1.GetFindingsResponse{
+		Findings:              []anomaliesv1.Finding{},
+		WindowStartUnixNano:   window.StartTime,
+		WindowEndUnixNano:     window.EndTime,
+		BaselineStartUnixNano: baseline.StartTime,
+		BaselineEndUnixNano:   baseline.EndTime,
+	}
+	baselineSpans := make(map[string]map[string]int)
+	for _, op := range operations {
+		if _, ok := baselineSpans[op.service]; !ok {
+			counts, err := d.operationCounts(ctx, baseline, op.service)
+			if err != nil {
+				return nil, fmt.Errorf("could not count baseline spans of service %s: %w", op.service, err)
+			}
+			baselineSpans[op.service] = counts
+		}
+		if baselineSpans[op.service][op.name] < d.cfg.MinSpans {
+			// too few spans to baseline, e.g. a new operation
+			continue
+		}
+
+		findings, err := d.compare(ctx, op, baselineSpans[op.service][op.name], window, baseline)
+		if err != nil {
+			return nil, fmt.Errorf("could not compare latency of %s %s: %w", op.service, op.name, err)
+		}
+		res.Findings = append(res.Findings, findings...)
+		res.AnalyzedOperations++
+	}
+	sort.SliceStable(res.Findings, func(i, j int) bool {
+		a, b := res.Findings[i], res.Findings[j]
+		if a.Ratio != b.Ratio {
+			return a.Ratio > b.Ratio
+		}
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		return a.Operation < b.Operation
+	})
+	res.DetectedAtUnixNano = uint64(d.now().UnixNano())
+
+	d.mu.Lock()
+	d.results[role] = res
+	d.mu.Unlock()
+	return &res, nil
+}
+
+type operation struct {
+	service string
+	name    string
+	spans   int
+}
+
+// operations returns the operations with enough spans within the timeframe, the busiest services first.
+func (d *Detector) operations(ctx context.Context, timeframe model.Timeframe) ([]operation, error) {
+	services, err := d.spanReader.GetTagsValues(ctx, tagsquery.TagValuesRequest{
+		Timeframe: &timeframe,
+		Limit:     d.cfg.MaxOperations,
+	}, []string{serviceTag})
+	if err != nil {
+		return nil, err
+	}
+
+	var operations []operation
+	for _, service := range tagValues(services, serviceTag) {
+		if service.Count < d.cfg.MinSpans {
+			continue
+		}
+		serviceName := fmt.Sprint(service.Value)
+		counts, err := d.operationCounts(ctx, timeframe, serviceName)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(counts))
+		for name := range counts {
+			names = append(names, name)
+		}
+		// the busiest operations first, as the number of compared operations is bounded
+		sort.Slice(names, func(i, j int) bool {
+			if counts[names[i]] != counts[names[j]] {
+				return counts[names[i]] > counts[names[j]]
+			}
+			return names[i] < names[j]
+		})
+		for _, name := range names {
+			if counts[name] < d.cfg.MinSpans {
+				continue
+			}
+			if len(operations) == d.cfg.MaxOperations {
+				return operations, nil
+			}
+			operations = append(operations, operation{service: serviceName, name: name, spans: counts[name]})
+		}
+	}
+	return operations, nil
+}
+
+// operationCounts returns the number of spans of each operation of a service within the timeframe.
+func (d *Detector) operationCounts(ctx context.Context, timeframe model.Timeframe, service string) (map[string]int, error) {
+	res, err := d.spanReader.GetTagsValues(ctx, tagsquery.TagValuesRequest{
+		Timeframe:     &timeframe,
+		SearchFilters: []model.SearchFilter{equals(serviceTag, service)},
+		Limit:         d.cfg.MaxOperations,
+	}, []string{operationTag})
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, value := range tagValues(res, operationTag) {
+		counts[fmt.Sprint(value.Value)] += value.Count
+	}
+	return counts, nil
+}
+
+// compare returns the latency statistics of an operation which regressed compared to the baseline window.
+func (d *Detector) compare(ctx context.Context, op operation, baselineSpans int, window model.Timeframe, baseline model.Timeframe) ([]anomaliesv1.Finding, error) {
+	current, err := d.latency(ctx, op, window)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := d.latency(ctx, op, baseline)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []anomaliesv1.Finding
+	for _, statistic := range statistics {
+		if previous[statistic] <= 0 {
+			continue
+		}
+		ratio := current[statistic] / previous[statistic]
+		if ratio < d.cfg.RegressionRatio {
+			continue
+		}
+		findings = append(findings, anomaliesv1.Finding{
+			Service:       op.service,
+			Operation:     op.name,
+			Statistic:     string(statistic),
+			BaselineNano:  previous[statistic],
+			CurrentNano:   current[statistic],
+			Ratio:         ratio,
+			Spans:         op.spans,
+			BaselineSpans: baselineSpans,
+		})
+	}
+	return findings, nil
+}
+
+func (d *Detector) latency(ctx context.Context, op operation, timeframe model.Timeframe) (map[tagsquery.TagStatistic]float64, error) {
+	res, err := d.spanReader.GetTagsStatistics(ctx, tagsquery.TagStatisticsRequest{
+		Timeframe:         &timeframe,
+		SearchFilters:     []model.SearchFilter{equals(serviceTag, op.service), equals(operationTag, op.name)},
+		DesiredStatistics: statistics,
+	}, durationTag)
+	if err != nil {
+		return nil, err
+	}
+	return res.Statistics, nil
+}
+
+func tagValues(res map[string]*tagsquery.TagValuesResponse, tag string) []tagsquery.TagValueInfo {
+	if values := res[tag]; values != nil {
+		return values.Values
+	}
+	return nil
+}
+
+func equals(key string, value string) model.SearchFilter {
+	return model.SearchFilter{KeyValueFilter: &model.KeyValueFilter{
+		Key:      model.FilterKey(key),
+		Operator: spansquery.OPERATOR_EQUALS,
+		Value:    value,
+	}}
+}
@@ -0,0 +1,133 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package anomalies
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type operationStats struct {
+	spans int
+	p99   float64
+	avg   float64
+}
+
+// windowSpanReader serves the spans of each service operation within the window and within the baseline window,
+// which ends before the window starts.
+type windowSpanReader struct {
+	spanreader.SpanReader
+	windowStart uint64
+	window      map[string]map[string]operationStats
+	baseline    map[string]map[string]operationStats
+}
+
+func (sr *windowSpanReader) operations(timeframe uint64) map[string]map[string]operationStats {
+	if timeframe < sr.windowStart {
+		return sr.baseline
+	}
+	return sr.window
+}
+
+func (sr *windowSpanReader) GetTagsValues(ctx context.Context, r tagsquery.TagValuesRequest, tags []string) (map[string]*tagsquery.TagValuesResponse, error) {
+	services := sr.operations(r.Timeframe.StartTime)
+	values := &tagsquery.TagValuesResponse{}
+	if tags[0] == serviceTag {
+		for service, operations := range services {
+			count := 0
+			for _, stats := range operations {
+				count += stats.spans
+			}
+			values.Values = append(values.Values, tagsquery.TagValueInfo{Value: service, Count: count})
+		}
+	} else {
+		for name, stats := range services[fmt.Sprint(r.SearchFilters[0].KeyValueFilter.Value)] {
+			values.Values = append(values.Values, tagsquery.TagValueInfo{Value: name, Count: stats.spans})
+		}
+	}
+	return map[string]*tagsquery.TagValuesResponse{tags[0]: values}, nil
+}
+
+func (sr *windowSpanReader) GetTagsStatistics(ctx context.Context, r tagsquery.TagStatisticsRequest, tag string) (*tagsquery.TagStatisticsResponse, error) {
+	service := fmt.Sprint(r.SearchFilters[0].KeyValueFilter.Value)
+	name := fmt.Sprint(r.SearchFilters[1].KeyValueFilter.Value)
+	stats := sr.operations(r.Timeframe.StartTime)[service][name]
+	return &tagsquery.TagStatisticsResponse{Statistics: map[tagsquery.TagStatistic]float64{
+		tagsquery.P99: stats.p99,
+		tagsquery.AVG: stats.avg,
+	}}, nil
+}
+
+func TestDetect(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	sr := &windowSpanReader{
+		windowStart: uint64(now.Add(-time.Hour).UnixNano()),
+		window: map[string]map[string]operationStats{
+			"checkout": {
+				"POST /checkout": {spans: 500, p99: 900e6, avg: 100e6},
+				"GET /cart":      {spans: 500, p99: 50e6, avg: 10e6},
+				"GET /new":       {spans: 500, p99: 500e6, avg: 100e6},
+				"GET /rare":      {spans: 5, p99: 900e6, avg: 900e6},
+			},
+			"payments": {
+				"charge": {spans: 200, p99: 3e9, avg: 1e9},
+			},
+		},
+		baseline: map[string]map[string]operationStats{
+			"checkout": {
+				"POST /checkout": {spans: 400, p99: 300e6, avg: 90e6},
+				"GET /cart":      {spans: 400, p99: 40e6, avg: 10e6},
+				"GET /rare":      {spans: 5, p99: 100e6, avg: 100e6},
+			},
+			"payments": {
+				"charge": {spans: 200, p99: 1e9, avg: 400e6},
+			},
+		},
+	}
+	detector := NewDetector(zap.NewNop(), sr, Config{
+		Window:          time.Hour,
+		BaselineOffset:  7 * 24 * time.Hour,
+		MinSpans:        100,
+		RegressionRatio: 2,
+		MaxOperations:   100,
+	})
+	detector.now = func() time.Time { return now }
+	assert.Empty(t, detector.Findings("").Findings)
+
+	res, err := detector.Detect(context.Background(), "")
+	assert.NoError(t, err)
+	// GET /new has no baseline, and GET /rare too few spans
+	assert.Equal(t, 3, res.AnalyzedOperations)
+	assert.Equal(t, uint64(now.Add(-7*24*time.Hour-time.Hour).UnixNano()), res.BaselineStartUnixNano)
+
+	var found []string
+	for _, finding := range res.Findings {
+		found = append(found, fmt.Sprintf("%s %s %s %.1f", finding.Service, finding.Operation, finding.Statistic, finding.Ratio))
+	}
+	assert.Equal(t, []string{"checkout POST /checkout p99 3.0", "payments charge p99 3.0", "payments charge avg 2.5"}, found)
+	assert.Equal(t, 500, res.Findings[0].Spans)
+	assert.Equal(t, 400, res.Findings[0].BaselineSpans)
+	assert.Equal(t, *res, detector.Findings(""))
+	assert.Empty(t, detector.Findings("other role").Findings)
+}
@@ -0,0 +1,63 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/teletrace/teletrace/pkg/spanreader/acl"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// registerAccessControl restricts the span reader by the configured ACL policy, if any.
+func (api *API) registerAccessControl() {
+	if api.config.ACLPolicyFile == "" {
+		return
+	}
+	policy, err := acl.LoadPolicy(api.config.ACLPolicyFile)
+	if err != nil {
+		api.logger.Fatal("Failed to load ACL policy", zap.Error(err))
+	}
+	sr := acl.NewSpanReader(*api.spanReader, policy)
+	api.spanReader = &sr
+	api.aclPolicy = &policy
+}
+
+// accessControlMiddleware attaches the role of the request to its context.
+// The role header is expected to be set by a trusted authenticating proxy.
+func (api *API) accessControlMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetHeader(api.config.ACLRoleHeader)
+		if !api.aclPolicy.HasRole(role) {
+			respondWithError(http.StatusForbidden, fmt.Errorf("%w for role %q", acl.ErrAccessDenied, role), c)
+			c.Abort()
+			return
+		}
+		c.Request = c.Request.WithContext(acl.WithRole(c.Request.Context(), role))
+		c.Next()
+	}
+}
+
+// requestRole returns the access control role of the request, which alert rules, SLOs and anomalies are scoped to.
+// It's empty if the access control is disabled.
+func requestRole(c *gin.Context) string {
+	role, _ := acl.RoleFromContext(c.Request.Context())
+	return role
+}
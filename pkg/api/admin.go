@@ -0,0 +1,75 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+
+	"github.com/teletrace/teletrace/pkg/logs"
+
+	"go.uber.org/zap"
+)
+
+const (
+	pprofPath    = "/debug/pprof/"
+	logLevelPath = "/log/level"
+)
+
+// handleAdmin serves handler on the admin port, which is only listened on when an admin endpoint is enabled.
+func (api *API) handleAdmin(pattern string, handler http.Handler) {
+	if api.adminMux == nil {
+		api.adminMux = http.NewServeMux()
+	}
+	api.adminMux.Handle(pattern, handler)
+}
+
+// registerProfiling serves the net/http/pprof profiles on the admin port, so CPU and memory profiles can be captured
+// from a misbehaving instance, e.g. go tool pprof http://localhost:8081/debug/pprof/heap
+func (api *API) registerProfiling() {
+	if !api.config.AdminPprofEnabled {
+		return
+	}
+	// the index serves the named profiles, e.g. /debug/pprof/heap
+	api.handleAdmin(pprofPath, http.HandlerFunc(pprof.Index))
+	api.handleAdmin(pprofPath+"cmdline", http.HandlerFunc(pprof.Cmdline))
+	api.handleAdmin(pprofPath+"profile", http.HandlerFunc(pprof.Profile))
+	api.handleAdmin(pprofPath+"symbol", http.HandlerFunc(pprof.Symbol))
+	api.handleAdmin(pprofPath+"trace", http.HandlerFunc(pprof.Trace))
+}
+
+// registerLogLevel serves the level of the process logger on the admin port, so debug logging can be turned on
+// temporarily while reproducing an issue, e.g. curl -X PUT -d '{"level":"debug"}' http://localhost:8081/log/level
+func (api *API) registerLogLevel() {
+	if !api.config.AdminLogLevelEnabled {
+		return
+	}
+	api.handleAdmin(logLevelPath, logs.LevelHandler())
+}
+
+// startAdmin listens on the admin port of the admin host in the background if any admin endpoint is enabled.
+func (api *API) startAdmin() {
+	if api.adminMux == nil {
+		return
+	}
+	go func() {
+		if err := http.ListenAndServe(net.JoinHostPort(api.config.AdminHost, strconv.Itoa(api.config.AdminPort)), api.adminMux); err != nil {
+			api.logger.Fatal("Admin server stopped with an error", zap.Error(err))
+		}
+	}()
+}
@@ -0,0 +1,111 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errTooManyQueries = errors.New("too many concurrent queries, retry later")
+
+// admission limits the number of concurrently served query requests, so heavy clients can't starve the storage backend.
+// Requests beyond the limit wait in a bounded queue, and are rejected when it's full or they wait too long.
+type admission struct {
+	slots        chan struct{}
+	queue        chan struct{}
+	queueTimeout time.Duration
+}
+
+func newAdmission(maxConcurrent int, queueSize int, queueTimeout time.Duration) *admission {
+	return &admission{
+		slots:        make(chan struct{}, maxConcurrent),
+		queue:        make(chan struct{}, queueSize),
+		queueTimeout: queueTimeout,
+	}
+}
+
+// acquire takes a slot, waiting in the queue if there is none. It returns false if the request is rejected,
+// in which case release mustn't be called.
+func (a *admission) acquire(ctx context.Context) bool {
+	select {
+	case a.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	select {
+	case a.queue <- struct{}{}:
+		defer func() { <-a.queue }()
+	default:
+		return false
+	}
+
+	timer := time.NewTimer(a.queueTimeout)
+	defer timer.Stop()
+	select {
+	case a.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (a *admission) release() {
+	<-a.slots
+}
+
+// retryAfterSeconds is the Retry-After of rejected requests, the time a queued request waits for a slot.
+func (a *admission) retryAfterSeconds() int {
+	seconds := int(a.queueTimeout.Seconds())
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// registerAdmissionControl limits the concurrent query requests, if enabled.
+func (api *API) registerAdmissionControl() {
+	if api.config.APIMaxConcurrentQueries <= 0 {
+		return
+	}
+	api.admission = newAdmission(
+		api.config.APIMaxConcurrentQueries,
+		api.config.APIQueryQueueSize,
+		time.Duration(api.config.APIQueryQueueTimeoutSeconds)*time.Second,
+	)
+}
+
+// admissionMiddleware responds with 429 and a Retry-After header to query requests rejected by the admission control.
+func (api *API) admissionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !api.admission.acquire(c) {
+			c.Header("Retry-After", strconv.Itoa(api.admission.retryAfterSeconds()))
+			respondWithError(http.StatusTooManyRequests, errTooManyQueries, c)
+			c.Abort()
+			return
+		}
+		defer api.admission.release()
+		c.Next()
+	}
+}
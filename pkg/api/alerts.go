@@ -0,0 +1,127 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	alertsv1 "github.com/teletrace/teletrace/pkg/model/alerts/v1"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errAlertsDisabled = errors.New("alerting is disabled")
+
+// requireAlerts responds with an error if the alert rules aren't evaluated.
+func (api *API) requireAlerts(c *gin.Context) bool {
+	if !api.requireMetadataStore(c) {
+		return false
+	}
+	if api.alertEvaluator == nil {
+		respondWithError(http.StatusNotImplemented, errAlertsDisabled, c)
+		return false
+	}
+	return true
+}
+
+// alertRuleStatus returns a rule with its state and recent notifications.
+func (api *API) alertRuleStatus(rule alertsv1.Rule) alertsv1.RuleStatus {
+	return alertsv1.RuleStatus{
+		Rule:       rule,
+		State:      api.alertEvaluator.State(rule.Id),
+		Deliveries: api.alertNotifier.Deliveries(rule.Id),
+	}
+}
+
+func (api *API) getAlertRules(c *gin.Context) {
+	if !api.requireAlerts(c) {
+		return
+	}
+	rules, err := api.alertRules.List(c)
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+	role := requestRole(c)
+	res := alertsv1.GetRulesResponse{Rules: []alertsv1.RuleStatus{}}
+	for _, rule := range rules {
+		if rule.Role == role {
+			res.Rules = append(res.Rules, api.alertRuleStatus(rule))
+		}
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (api *API) createAlertRule(c *gin.Context) {
+	if !api.requireAlerts(c) {
+		return
+	}
+	var req alertsv1.CreateRuleRequest
+	if isValidationError := api.validateRequestBody(&req, c); isValidationError {
+		return
+	}
+	rule, err := api.alertRules.Create(c, req, requestRole(c))
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+	c.JSON(http.StatusCreated, api.alertRuleStatus(*rule))
+}
+
+func (api *API) getAlertRule(c *gin.Context) {
+	if !api.requireAlerts(c) {
+		return
+	}
+	rule, ok := api.findAlertRule(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, api.alertRuleStatus(*rule))
+}
+
+func (api *API) deleteAlertRule(c *gin.Context) {
+	if !api.requireAlerts(c) {
+		return
+	}
+	rule, ok := api.findAlertRule(c)
+	if !ok {
+		return
+	}
+	if err := api.alertRules.Delete(c, rule.Id); err != nil && !errors.Is(err, metadatastore.ErrNotFound) {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// findAlertRule returns the rule of the request path, or responds with 404 if it doesn't exist
+// or was created by another access control role.
+func (api *API) findAlertRule(c *gin.Context) (*alertsv1.Rule, bool) {
+	id := c.Param("id")
+	rule, err := api.alertRules.Get(c, id)
+	if errors.Is(err, metadatastore.ErrNotFound) || (err == nil && rule.Role != requestRole(c)) {
+		respondWithError(http.StatusNotFound, fmt.Errorf("alert rule %s not found", id), c)
+		return nil, false
+	}
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return nil, false
+	}
+	return rule, true
+}
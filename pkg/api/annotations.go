@@ -0,0 +1,89 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	annotationsv1 "github.com/teletrace/teletrace/pkg/model/annotations/v1"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (api *API) getAnnotations(c *gin.Context) {
+	if !api.requireMetadataStore(c) || !api.requireVisibleTrace(c) {
+		return
+	}
+	annotations, err := api.annotations.List(c, c.Param("id"))
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+	c.JSON(http.StatusOK, annotationsv1.GetAnnotationsResponse{Annotations: annotations})
+}
+
+func (api *API) addAnnotation(c *gin.Context) {
+	if !api.requireMetadataStore(c) || !api.requireVisibleTrace(c) {
+		return
+	}
+	var req annotationsv1.AddAnnotationRequest
+	if isValidationError := api.validateRequestBody(&req, c); isValidationError {
+		return
+	}
+	annotation, err := api.annotations.Add(c, c.Param("id"), req)
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+	c.JSON(http.StatusCreated, annotation)
+}
+
+func (api *API) deleteAnnotation(c *gin.Context) {
+	if !api.requireMetadataStore(c) || !api.requireVisibleTrace(c) {
+		return
+	}
+	err := api.annotations.Delete(c, c.Param("id"), c.Param("annotationId"))
+	if errors.Is(err, metadatastore.ErrNotFound) {
+		respondWithError(http.StatusNotFound, err, c)
+		return
+	}
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// requireVisibleTrace responds with 404 unless the trace has spans the role of the request can see,
+// since the annotations are stored apart from the spans the access control applies to.
+func (api *API) requireVisibleTrace(c *gin.Context) bool {
+	traceId := c.Param("id")
+	sr := traceSearchRequest(traceId)
+	sr.Limit = 1
+	res, err := (*api.spanReader).Search(c, sr)
+	if err != nil {
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return false
+	}
+	if len(res.Spans) == 0 {
+		respondWithError(http.StatusNotFound, fmt.Errorf("trace %s not found", traceId), c)
+		return false
+	}
+	return true
+}
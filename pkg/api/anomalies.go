@@ -0,0 +1,62 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/anomalies"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+var errAnomaliesDisabled = errors.New("latency anomaly detection is disabled")
+
+// registerAnomalyDetector creates the latency anomaly detector on top of the (possibly restricted) span reader,
+// detecting the anomalies of each access control role separately.
+func (api *API) registerAnomalyDetector() {
+	if !api.config.AnomaliesEnabled {
+		return
+	}
+	cfg := anomalies.Config{
+		Interval:        time.Duration(api.config.AnomaliesIntervalSeconds) * time.Second,
+		Window:          time.Duration(api.config.AnomaliesWindowMinutes) * time.Minute,
+		BaselineOffset:  time.Duration(api.config.AnomaliesBaselineOffsetHours) * time.Hour,
+		MinSpans:        api.config.AnomaliesMinSpans,
+		RegressionRatio: api.config.AnomaliesRegressionRatio,
+		MaxOperations:   api.config.AnomaliesMaxOperations,
+	}
+	if cfg.Interval <= 0 || cfg.Window <= 0 {
+		api.logger.Fatal("Anomaly detection interval and window must be positive",
+			zap.Duration("interval", cfg.Interval), zap.Duration("window", cfg.Window))
+	}
+	if api.aclPolicy != nil {
+		cfg.Roles = api.aclPolicy.RoleNames()
+	}
+	api.anomalyDetector = anomalies.NewDetector(api.logger, *api.spanReader, cfg)
+}
+
+// getAnomalies responds with the latency regressions found by the last detection, within the spans of the request role.
+func (api *API) getAnomalies(c *gin.Context) {
+	if api.anomalyDetector == nil {
+		respondWithError(http.StatusNotImplemented, errAnomaliesDisabled, c)
+		return
+	}
+	c.JSON(http.StatusOK, api.anomalyDetector.Findings(requestRole(c)))
+}
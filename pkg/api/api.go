@@ -17,20 +17,42 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/teletrace/teletrace/blobstore"
+	"github.com/teletrace/teletrace/pkg/alerts"
+	"github.com/teletrace/teletrace/pkg/annotations"
+	"github.com/teletrace/teletrace/pkg/anomalies"
+	"github.com/teletrace/teletrace/pkg/archive"
+	"github.com/teletrace/teletrace/pkg/audit"
+	"github.com/teletrace/teletrace/pkg/cache"
 	"github.com/teletrace/teletrace/pkg/config"
+	"github.com/teletrace/teletrace/pkg/errorgroups"
+	"github.com/teletrace/teletrace/pkg/flamegraph"
+	"github.com/teletrace/teletrace/pkg/incompletetraces"
+	"github.com/teletrace/teletrace/pkg/ingestionlag"
+	"github.com/teletrace/teletrace/pkg/loglinks"
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	"github.com/teletrace/teletrace/pkg/metriccorrelation"
 	"github.com/teletrace/teletrace/pkg/model"
+	"github.com/teletrace/teletrace/pkg/nplusone"
+	"github.com/teletrace/teletrace/pkg/queryhistory"
+	"github.com/teletrace/teletrace/pkg/slos"
+	"github.com/teletrace/teletrace/pkg/snapshots"
 	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/acl"
+	"github.com/teletrace/teletrace/pkg/tracesummaries"
+	"github.com/teletrace/teletrace/ratelimit"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/static"
 	ginzap "github.com/gin-contrib/zap"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -41,10 +63,38 @@ var staticFilesPath = filepath.Join("web", "build")
 // API holds the config used for running the API as well as
 // the endpoint handlers and resources used by them (e.g. logger).
 type API struct {
-	logger     *zap.Logger
-	config     config.Config
-	router     *gin.Engine
-	spanReader *spanreader.SpanReader
+	logger      *zap.Logger
+	config      config.Config
+	router      *gin.Engine
+	spanReader  *spanreader.SpanReader
+	cache       *cache.StaleWhileRevalidateCache
+	aclPolicy   *acl.Policy
+	admission   *admission
+	rateLimiter *ratelimit.Limiter
+
+	nPlusOneDetector         *nplusone.Detector
+	anomalyDetector          *anomalies.Detector
+	errorAnalyzer            *errorgroups.Analyzer
+	metricCorrelator         *metriccorrelation.Correlator
+	ingestionLagAnalyzer     *ingestionlag.Analyzer
+	flamegraphAggregator     *flamegraph.Aggregator
+	traceSummarizer          *tracesummaries.Summarizer
+	incompleteTracesDetector *incompletetraces.Detector
+	sidecarStore             blobstore.Store
+	archiver                 *archive.Archiver
+	metadataStore            metadatastore.MetadataStore
+	annotations              *annotations.Store
+	snapshots                *snapshots.Store
+	queryHistory             *queryhistory.History
+	auditLog                 *audit.Log
+	alertRules               *alerts.Store
+	alertEvaluator           *alerts.Evaluator
+	alertNotifier            *alerts.Notifier
+	slos                     *slos.Store
+	sloCalculator            *slos.Calculator
+	logLinks                 []loglinks.Template
+	tracer                   trace.Tracer
+	adminMux                 *http.ServeMux
 }
 
 // NewAPI creates and returns a new API instance.
@@ -56,6 +106,35 @@ func NewAPI(logger *zap.Logger, config config.Config, sr *spanreader.SpanReader)
 		router:     router,
 		spanReader: sr,
 	}
+	// access control wraps the circuit breaker, so denied requests aren't counted as storage failures,
+	// and the search and tag values caches, so the filters of each role are part of their cache keys.
+	// Percentile filters wrap access control, so their thresholds are computed of the spans of each role
+	api.registerMetrics()
+	api.registerTracing()
+	api.registerReadDeduplication()
+	api.registerServiceAliases()
+	api.registerLogLinks()
+	api.registerCircuitBreaker()
+	api.registerSearchCache()
+	api.registerTagValuesCache()
+	api.registerAccessControl()
+	api.registerPercentileFilters()
+	api.registerCache()
+	api.registerRateLimit()
+	api.registerAdmissionControl()
+	api.registerNPlusOneDetector()
+	api.registerIncompleteTracesDetector()
+	api.registerErrorAnalyzer()
+	api.registerMetricCorrelator()
+	api.registerIngestionLagAnalyzer()
+	api.registerFlamegraphAggregator()
+	api.registerTraceSummarizer()
+	api.registerAnomalyDetector()
+	api.registerSidecarStore()
+	api.registerArchiver()
+	api.registerProfiling()
+	api.registerLogLevel()
+	api.registerAuditLog()
 	api.registerMiddlewares()
 	api.registerRoutes()
 	return api
@@ -64,6 +143,8 @@ func NewAPI(logger *zap.Logger, config config.Config, sr *spanreader.SpanReader)
 func newRouter(logger *zap.Logger, config config.Config) *gin.Engine {
 	setGinMode(config)
 	router := gin.New()
+	// makes values and cancellation of the request context available through the gin context
+	router.ContextWithFallback = true
 	return router
 }
 
@@ -85,11 +166,25 @@ func (api *API) registerMiddlewares() {
 	// zap recovery logger middleware
 	api.router.Use(ginzap.RecoveryWithZap(api.logger, false))
 
+	if api.tracer != nil {
+		api.router.Use(api.tracingMiddleware())
+	}
+
+	if api.config.APIMetricsEnabled {
+		api.router.Use(api.metricsMiddleware())
+	}
+
+	if api.config.APISecurityHeadersEnabled {
+		api.router.Use(api.securityHeadersMiddleware())
+	}
+
 	// static files middleware, for serving frontend files
 	api.registerStaticFilesMiddleware()
 
-	// CORS policy config middleware
-	api.router.Use(cors.Default())
+	// CORS policy config middleware, cross-origin requests are denied by browsers without allowed origins
+	if origins := splitList(api.config.APICORSAllowedOrigins); len(origins) > 0 {
+		api.router.Use(api.corsMiddleware(origins))
+	}
 }
 
 func (api *API) registerStaticFilesMiddleware() {
@@ -105,20 +200,97 @@ func (api *API) registerStaticFilesMiddleware() {
 	})
 }
 
+func (api *API) registerCache() {
+	responseCache, err := newResponseCache(api.logger, api.config)
+	if err != nil {
+		api.logger.Fatal("Failed to initialize API response cache", zap.Error(err))
+	}
+	api.cache = responseCache
+}
+
 func (api *API) registerRoutes() {
+	api.router.GET(healthPath, api.getHealth)
+	api.router.GET(readinessPath, api.getReadiness)
 	v1 := api.router.Group(apiPrefix)
+	if api.config.APICompressionEnabled {
+		v1.Use(api.compressionMiddleware())
+	}
 	v1.GET("/ping", api.getPing)
+	if api.config.AuditLogEnabled {
+		// applies to the routes registered below, including the requests denied by the access control
+		v1.Use(api.auditMiddleware())
+	}
+	if api.aclPolicy != nil {
+		// applies to the routes registered below
+		v1.Use(api.accessControlMiddleware())
+	}
 	v1.GET("/system-info", api.getSystemInfo)
-	v1.POST("/search", api.search)
-	v1.GET("/trace/:id", api.getTraceById)
+	v1.GET("/settings", api.listSettings)
+	v1.GET("/settings/:key", api.getSetting)
+	v1.PUT("/settings/:key", api.setSetting)
+	v1.GET("/trace/:id/annotations", api.getAnnotations)
+	v1.POST("/trace/:id/annotations", api.addAnnotation)
+	v1.DELETE("/trace/:id/annotations/:annotationId", api.deleteAnnotation)
+	v1.GET("/snapshots/:token", api.getSnapshot)
+	v1.DELETE("/snapshots/:token", api.deleteSnapshot)
+	v1.GET("/query-history", api.getQueryHistory)
+	v1.GET("/alerts/rules", api.getAlertRules)
+	v1.POST("/alerts/rules", api.createAlertRule)
+	v1.GET("/alerts/rules/:id", api.getAlertRule)
+	v1.DELETE("/alerts/rules/:id", api.deleteAlertRule)
+	v1.GET("/anomalies", api.getAnomalies)
+	v1.GET("/slos", api.getSLOs)
+	v1.POST("/slos", api.createSLO)
+	v1.DELETE("/slos/:id", api.deleteSLO)
+	v1.GET("/trace/:id/spans/:spanId/attributes/:key", api.getSpanAttributeValue)
 	v1.GET("/tags", api.getAvailableTags)
-	v1.POST("/tags/:tag", api.tagsValues)
-	v1.POST("/tags/:tag/statistics", api.tagsStatistics)
+	v1.POST("/search/validate", api.validateSearch)
+
+	// the expensive query routes, limited by the rate limit and the admission control
+	queries := v1.Group("")
+	queries.Use(api.rateLimitMiddleware())
+	if api.admission != nil {
+		queries.Use(api.admissionMiddleware())
+	}
+	queries.POST("/search", api.search)
+	queries.POST("/search/export", api.exportSearch)
+	queries.POST("/events/search", api.searchEvents)
+	queries.POST("/query-history/:id/run", api.rerunQuery)
+	queries.POST("/traces/search", api.searchTraces)
+	queries.POST("/traces/summaries", api.summarizeTraces)
+	queries.POST("/archive", api.archiveTraces)
+	queries.POST("/archive/restore", api.restoreTraces)
+	queries.GET("/trace/:id", api.getTraceById)
+	queries.GET("/trace/:id/tree", api.getTraceTree)
+	queries.POST("/trace/:id/search", api.searchTrace)
+	queries.POST("/trace/:id/snapshots", api.createSnapshot)
+	queries.GET("/trace/:id/spans/:spanId/links", api.getSpanLinks)
+	queries.GET("/slos/:id", api.getSLO)
+	queries.GET("/slos/:id/burn-rates", api.getSLOBurnRates)
+	queries.POST("/tags/statistics", api.tagsStatisticsBatch)
+	queries.POST("/tags/:tag", api.tagsValues)
+	queries.POST("/tags/:tag/statistics", api.tagsStatistics)
+	queries.POST("/analysis/n-plus-one", api.detectNPlusOne)
+	queries.POST("/analysis/incomplete-traces", api.detectIncompleteTraces)
+	queries.POST("/analysis/errors", api.analyzeErrors)
+	queries.POST("/analysis/ingestion-lag", api.analyzeIngestionLag)
+	queries.POST("/analysis/flamegraph", api.aggregateFlamegraph)
+	queries.POST("/correlate/metric", api.correlateMetric)
 }
 
 // Start runs the configured API instance.
 // Blocks the goroutine indefinitely unless an error happens.
 func (api *API) Start() error {
+	if api.config.APIWarmUpEnabled {
+		go api.warmUp(context.Background())
+	}
+	if api.alertEvaluator != nil {
+		go api.alertEvaluator.Run(context.Background())
+	}
+	if api.anomalyDetector != nil {
+		go api.anomalyDetector.Run(context.Background())
+	}
+	api.startAdmin()
 	return api.router.Run(fmt.Sprintf(":%d", api.config.APIPort))
 }
 
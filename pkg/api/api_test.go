@@ -18,8 +18,12 @@ package api
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -28,16 +32,41 @@ import (
 	"testing"
 	"time"
 
+	"github.com/teletrace/teletrace/pkg/arrowexport"
 	"github.com/teletrace/teletrace/pkg/config"
+	"github.com/teletrace/teletrace/pkg/metadatastore/memory"
 	"github.com/teletrace/teletrace/pkg/model"
+	alerts "github.com/teletrace/teletrace/pkg/model/alerts/v1"
+	annotationsv1 "github.com/teletrace/teletrace/pkg/model/annotations/v1"
+	archivemodel "github.com/teletrace/teletrace/pkg/model/archive/v1"
+	auditv1 "github.com/teletrace/teletrace/pkg/model/audit/v1"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	queryhistory "github.com/teletrace/teletrace/pkg/model/queryhistory/v1"
+	slos "github.com/teletrace/teletrace/pkg/model/slos/v1"
+	snapshots "github.com/teletrace/teletrace/pkg/model/snapshots/v1"
+	spanlinks "github.com/teletrace/teletrace/pkg/model/spanlinks/v1"
 	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
 	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	tracetree "github.com/teletrace/teletrace/pkg/model/tracetree/v1"
+	"github.com/teletrace/teletrace/pkg/settings"
+	pkgspanreader "github.com/teletrace/teletrace/pkg/spanreader"
 	spanreader "github.com/teletrace/teletrace/pkg/spanreader/mock"
 
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
 	spanformatutiltests "github.com/teletrace/teletrace/model/internalspan/v1/util"
 
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/ipc"
 	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats/view"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest/observer"
 )
@@ -128,6 +157,111 @@ func TestSearchRoute(t *testing.T) {
 	assert.Equal(t, expectedSpanId, resBody.Spans[0].Span.SpanId)
 }
 
+func TestSearchAggregations(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{}, &srMock)
+	expectedSpan := spanformatutiltests.GenInternalSpan(nil, nil, nil)
+
+	for body, expectedStatus := range map[string]int{
+		`{"timeframe": {"start": "now-1h"}, "aggregations": {"metrics": [{"function": "count"}], "groupBy": "span.name"}}`:                        http.StatusOK,
+		`{"timeframe": {"start": "now-1h"}, "aggregations": {"metrics": [{"function": "median", "field": "span.duration"}]}}`:                     http.StatusBadRequest,
+		`{"timeframe": {"start": "now-1h"}, "aggregations": {"metrics": [{"function": "avg"}]}}`:                                                  http.StatusBadRequest,
+		`{"timeframe": {"start": "now-1h"}, "aggregations": {"metrics": [{"function": "count"}], "interval": "week"}}`:                            http.StatusBadRequest,
+		`{"timeframe": {"start": "now-1h"}, "aggregations": {"metrics": [{"function": "count"}], "interval": "day", "timezone": "Mars/Olympus"}}`: http.StatusBadRequest,
+		`{"timeframe": {"start": "now-1h"}, "aggregations": {"metrics": [{"function": "count"}], "timezone": "Europe/Paris"}}`:                    http.StatusBadRequest,
+	} {
+		req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader([]byte(body)))
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, expectedStatus, resRecorder.Code, body)
+		if expectedStatus != http.StatusOK {
+			continue
+		}
+		var resBody spansquery.SearchResponse
+		assert.NoError(t, json.NewDecoder(resRecorder.Body).Decode(&resBody))
+		assert.Empty(t, resBody.Spans)
+		assert.Equal(t, []spansquery.AggregationGroup{
+			{Value: expectedSpan.Span.Name, Count: 1, Metrics: map[string]float64{"count": 1}},
+		}, resBody.Aggregations.Groups)
+	}
+}
+
+func TestSearchFields(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{}, &srMock)
+	expectedSpan := spanformatutiltests.GenInternalSpan(nil, nil, nil)
+
+	for body, expectedStatus := range map[string]int{
+		`{"timeframe": {"start": "now-1h"}, "fields": ["span.attributes"]}`: http.StatusOK,
+		`{"timeframe": {"start": "now-1h"}, "fields": ["span.logs"]}`:       http.StatusBadRequest,
+	} {
+		req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader([]byte(body)))
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, expectedStatus, resRecorder.Code, body)
+		if expectedStatus != http.StatusOK {
+			continue
+		}
+		var resBody spansquery.SearchResponse
+		assert.NoError(t, json.NewDecoder(resRecorder.Body).Decode(&resBody))
+		assert.Len(t, resBody.Spans, 1)
+		assert.Equal(t, expectedSpan.Span.SpanId, resBody.Spans[0].Span.SpanId)
+		assert.Empty(t, resBody.Spans[0].Resource.Attributes)
+		assert.Nil(t, resBody.Spans[0].Scope)
+	}
+}
+
+func TestSearchEventsRoute(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{Debug: false}, &srMock)
+
+	for body, expectedStatus := range map[string]int{
+		`{"timeframe": {"start": "now-1h"}, "name": "exception", "attributes": {"exception.type": "TimeoutError"}}`: http.StatusOK,
+		`{"timeframe": {"start": "now-1h"}, "attributes": {"exception.type": {"name": "TimeoutError"}}}`:            http.StatusBadRequest,
+		`{"timeframe": {"start": "now-1h"}, "limit": 100000}`:                                                       http.StatusBadRequest,
+	} {
+		req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/events/search"), bytes.NewReader([]byte(body)))
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, expectedStatus, resRecorder.Code, body)
+		if expectedStatus != http.StatusOK {
+			continue
+		}
+		var resBody eventsquery.SearchResponse
+		assert.NoError(t, json.NewDecoder(resRecorder.Body).Decode(&resBody))
+		assert.Len(t, resBody.Events, 1)
+		assert.Equal(t, spanformatutiltests.GenInternalSpan(nil, nil, nil).Span.SpanId, resBody.Events[0].SpanId)
+	}
+}
+
+func TestSearchTracesRoute(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{}, &srMock)
+	expectedSpan := spanformatutiltests.GenInternalSpan(nil, nil, nil).Span
+
+	for body, expectedStatus := range map[string]int{
+		`{"timeframe": {"start": "now-1h"}, "filters": [{"keyValueFilter": {"key": "span.name", "operator": "equals", "value": "GET"}}]}`: http.StatusOK,
+		`{"timeframe": {"start": "now-1h"}, "limit": 1000}`: http.StatusBadRequest,
+	} {
+		req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/traces/search"), bytes.NewReader([]byte(body)))
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, expectedStatus, resRecorder.Code, body)
+		if expectedStatus != http.StatusOK {
+			continue
+		}
+		var resBody spansquery.SearchTracesResponse
+		assert.NoError(t, json.NewDecoder(resRecorder.Body).Decode(&resBody))
+		assert.Len(t, resBody.Traces, 1)
+		assert.Equal(t, expectedSpan.TraceId, resBody.Traces[0].TraceId)
+		assert.Len(t, resBody.Traces[0].Spans, 1)
+	}
+}
+
 func TestGetTraceById(t *testing.T) {
 	fakeLogger, _ := getLoggerObserver()
 	cfg := config.Config{Debug: false}
@@ -150,6 +284,671 @@ func TestGetTraceById(t *testing.T) {
 	assert.Equal(t, expectedTraceId, resBody.Spans[0].Span.TraceId)
 }
 
+func TestGetTraceTree(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{}, &srMock)
+	expectedSpan := spanformatutiltests.GenInternalSpan(nil, nil, nil).Span
+
+	req, _ := http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/trace", expectedSpan.TraceId, "tree"), nil)
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+
+	var resBody tracetree.GetTraceTreeResponse
+	assert.NoError(t, json.NewDecoder(resRecorder.Body).Decode(&resBody))
+	assert.Equal(t, 1, resBody.SpanCount)
+	assert.Len(t, resBody.Roots, 1)
+	assert.Equal(t, expectedSpan.SpanId, resBody.Roots[0].Span.Span.SpanId)
+}
+
+func TestSearchTrace(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{}, &srMock)
+	expectedSpan := spanformatutiltests.GenInternalSpan(nil, nil, nil).Span
+
+	body := `{"filters": [{"keyValueFilter": {"key": "span.name", "operator": "contains", "value": "GET"}}]}`
+	req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/trace", expectedSpan.TraceId, "search"), bytes.NewReader([]byte(body)))
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+
+	var resBody spansquery.SearchTraceResponse
+	assert.NoError(t, json.NewDecoder(resRecorder.Body).Decode(&resBody))
+	assert.Equal(t, []string{expectedSpan.SpanId}, resBody.SpanIds)
+	assert.False(t, resBody.Truncated)
+}
+
+func TestMetricsRoute(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	cfg := config.Config{Debug: false, APIMetricsEnabled: true}
+	srMock, _ := spanreader.NewSpanReaderMock()
+
+	api := NewAPI(fakeLogger, cfg, &srMock)
+
+	req, _ := http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/tags"), nil)
+	api.router.ServeHTTP(httptest.NewRecorder(), req)
+	// measurements are recorded asynchronously, retrieving a view waits for the recorded ones
+	_, err := view.RetrieveData(requestLatency.Name())
+	assert.NoError(t, err)
+
+	req, _ = http.NewRequest(http.MethodGet, metricsPath, nil)
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusNotFound, resRecorder.Code, "metrics must not be served on the API port")
+
+	resRecorder = httptest.NewRecorder()
+	api.adminMux.ServeHTTP(resRecorder, req)
+
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	body := resRecorder.Body.String()
+	assert.Contains(t, body, `teletrace_api_request_latency_count{method="GET",route="/v1/tags",status_code="200"}`)
+	assert.Contains(t, body, `teletrace_storage_query_latency_count{operation="get_available_tags",result="success"}`)
+	assert.Contains(t, body, "go_goroutines")
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{Debug: false}, &srMock)
+	recorder := tracetest.NewSpanRecorder()
+	api.tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer(tracerName)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	router := gin.New()
+	router.Use(api.tracingMiddleware())
+	router.GET(path.Join(apiPrefix, "/tags"), api.getAvailableTags)
+
+	req, _ := http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/tags"), nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resRecorder := httptest.NewRecorder()
+	router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "GET /v1/tags", spans[0].Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String(), "continues the caller trace")
+	assert.Contains(t, spans[0].Attributes(), semconv.HTTPStatusCodeKey.Int(http.StatusOK))
+}
+
+func TestProfilingRoutes(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+
+	api := NewAPI(fakeLogger, config.Config{Debug: false}, &srMock)
+	assert.Nil(t, api.adminMux, "the admin port isn't listened on without admin endpoints")
+
+	api = NewAPI(fakeLogger, config.Config{Debug: false, AdminPprofEnabled: true}, &srMock)
+	req, _ := http.NewRequest(http.MethodGet, pprofPath+"heap?debug=1", nil)
+	resRecorder := httptest.NewRecorder()
+	api.adminMux.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	assert.Contains(t, resRecorder.Body.String(), "heap profile")
+
+	// profiles aren't served on the public API port
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.NotContains(t, resRecorder.Body.String(), "heap profile")
+}
+
+type unreachableSpanReader struct {
+	pkgspanreader.SpanReader
+}
+
+func (sr unreachableSpanReader) Ping(ctx context.Context) error {
+	return errors.New("connection refused")
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	var unreachable pkgspanreader.SpanReader = unreachableSpanReader{SpanReader: srMock}
+
+	for sr, readyCode := range map[*pkgspanreader.SpanReader]int{
+		&srMock:      http.StatusOK,
+		&unreachable: http.StatusServiceUnavailable,
+	} {
+		api := NewAPI(fakeLogger, config.Config{Debug: false}, sr)
+
+		req, _ := http.NewRequest(http.MethodGet, healthPath, nil)
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, http.StatusOK, resRecorder.Code, "liveness doesn't depend on the storage backend")
+
+		req, _ = http.NewRequest(http.MethodGet, readinessPath, nil)
+		resRecorder = httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, readyCode, resRecorder.Code)
+	}
+}
+
+// blockingSpanReader searches until the search is timed out or canceled.
+type blockingSpanReader struct {
+	pkgspanreader.SpanReader
+	timeout time.Duration
+}
+
+func (sr blockingSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	ctx, cancel := pkgspanreader.WithQueryTimeout(ctx, sr.timeout)
+	defer cancel()
+	<-ctx.Done()
+	return nil, pkgspanreader.QueryContextError(ctx, ctx.Err())
+}
+
+func TestSearchRouteTimeout(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	var slow pkgspanreader.SpanReader = blockingSpanReader{SpanReader: srMock, timeout: time.Millisecond}
+	api := NewAPI(fakeLogger, config.Config{Debug: false}, &slow)
+
+	jsonBody := []byte(fmt.Sprintf("{\"timeframe\": { \"startTime\": 0, \"endTime\": %v }}", time.Now().UnixNano()))
+	req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader(jsonBody))
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+
+	assert.Equal(t, http.StatusGatewayTimeout, resRecorder.Code)
+	assert.Contains(t, resRecorder.Body.String(), "query timed out")
+}
+
+// failingSpanReader fails searches with err.
+type failingSpanReader struct {
+	pkgspanreader.SpanReader
+	err error
+}
+
+func (sr failingSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	return nil, sr.err
+}
+
+func TestSearchErrorCodes(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+
+	for cause, status := range map[error]int{
+		pkgspanreader.ErrNotFound:           http.StatusNotFound,
+		pkgspanreader.ErrInvalidQuery:       http.StatusBadRequest,
+		pkgspanreader.ErrBackendUnavailable: http.StatusServiceUnavailable,
+		errors.New("disk is full"):          http.StatusInternalServerError,
+	} {
+		var failing pkgspanreader.SpanReader = failingSpanReader{SpanReader: srMock, err: fmt.Errorf("%w: search failed", cause)}
+		api := NewAPI(fakeLogger, config.Config{}, &failing)
+
+		req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader([]byte(`{"timeframe": {"startTime": 0}}`)))
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, status, resRecorder.Code, cause.Error())
+
+		var resBody errorResponse
+		assert.Nil(t, json.NewDecoder(resRecorder.Body).Decode(&resBody))
+		assert.Equal(t, cause.Error()+": search failed", resBody.ErrorMessage)
+		if status != http.StatusInternalServerError {
+			assert.Equal(t, pkgspanreader.Code(cause), resBody.ErrorCode)
+		} else {
+			assert.Empty(t, resBody.ErrorCode)
+		}
+	}
+}
+
+// partialSpanReader returns a search response with a dropped span.
+type partialSpanReader struct {
+	pkgspanreader.SpanReader
+}
+
+func (sr partialSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	res := &spansquery.SearchResponse{Metadata: &spansquery.Metadata{}, Spans: []*internalspan.InternalSpan{}}
+	res.AddDroppedSpan("failed to read span")
+	return res, nil
+}
+
+func TestSearchPartialResult(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	var sr pkgspanreader.SpanReader = partialSpanReader{SpanReader: srMock}
+	api := NewAPI(fakeLogger, config.Config{}, &sr)
+
+	req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader([]byte(`{"timeframe": {"startTime": 0}}`)))
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+
+	var resBody spansquery.SearchResponse
+	assert.Nil(t, json.NewDecoder(resRecorder.Body).Decode(&resBody))
+	assert.Equal(t, &spansquery.PartialResult{DroppedSpans: 1, Reasons: []string{"failed to read span"}}, resBody.Partial)
+
+	req, _ = http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader([]byte(`{"timeframe": {"startTime": 0}}`)))
+	req.Header.Set("Accept", protobufContentType)
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	assert.Equal(t, "1", resRecorder.Header().Get(droppedSpansHeader))
+}
+
+// limitSpanReader records the limits of the requests it's given.
+type limitSpanReader struct {
+	pkgspanreader.SpanReader
+	limits []int
+}
+
+func (sr *limitSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	sr.limits = append(sr.limits, r.Limit)
+	return &spansquery.SearchResponse{}, nil
+}
+
+func (sr *limitSpanReader) GetAvailableTags(
+	ctx context.Context, r tagsquery.GetAvailableTagsRequest,
+) (*tagsquery.GetAvailableTagsResponse, error) {
+	sr.limits = append(sr.limits, r.Limit)
+	return &tagsquery.GetAvailableTagsResponse{}, nil
+}
+
+func TestResultLimits(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	limits := &limitSpanReader{SpanReader: srMock}
+	var sr pkgspanreader.SpanReader = limits
+	cfg := config.Config{APISearchDefaultLimit: 200, APISearchMaxLimit: 1000, APITagsDefaultLimit: 100, APITagsMaxLimit: 500}
+	api := NewAPI(fakeLogger, cfg, &sr)
+
+	for _, body := range []string{`{"limit": 0}`, `{"limit": 20}`, `{"limit": 5000}`} {
+		req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader([]byte(body)))
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, http.StatusOK, resRecorder.Code)
+	}
+	for _, query := range []string{"", "?limit=1000"} {
+		req, _ := http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/tags")+query, nil)
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, http.StatusOK, resRecorder.Code)
+	}
+	assert.Equal(t, []int{200, 20, 1000, 100, 500}, limits.limits)
+
+	req, _ := http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/tags")+"?limit=-1", nil)
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusBadRequest, resRecorder.Code)
+}
+
+// searchRecorderSpanReader records the search requests it's given.
+type searchRecorderSpanReader struct {
+	pkgspanreader.SpanReader
+	requests []spansquery.SearchRequest
+}
+
+func (sr *searchRecorderSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	sr.requests = append(sr.requests, r)
+	return &spansquery.SearchResponse{}, nil
+}
+
+func TestSearchTimeExpressions(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	recorder := &searchRecorderSpanReader{SpanReader: srMock}
+	var sr pkgspanreader.SpanReader = recorder
+	api := NewAPI(fakeLogger, config.Config{Debug: false}, &sr)
+
+	before := time.Now()
+	jsonBody := []byte(`{"timeframe": {"start": "now-15m", "end": "now"}}`)
+	req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader(jsonBody))
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	after := time.Now()
+
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	assert.Len(t, recorder.requests, 1)
+	timeframe := recorder.requests[0].Timeframe
+	assert.Equal(t, uint64(15*time.Minute), timeframe.EndTime-timeframe.StartTime)
+	assert.GreaterOrEqual(t, timeframe.EndTime, uint64(before.UnixNano()))
+	assert.LessOrEqual(t, timeframe.EndTime, uint64(after.UnixNano()))
+
+	jsonBody = []byte(`{"timeframe": {"start": "2023-01-02T15:00:00Z", "end": "2023-01-02T17:30:00.5+02:00"}}`)
+	req, _ = http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader(jsonBody))
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	assert.Len(t, recorder.requests, 2)
+	timeframe = recorder.requests[1].Timeframe
+	assert.Equal(t, uint64(time.Date(2023, 1, 2, 15, 0, 0, 0, time.UTC).UnixNano()), timeframe.StartTime)
+	assert.Equal(t, uint64(30*time.Minute+500*time.Millisecond), timeframe.EndTime-timeframe.StartTime)
+
+	for _, body := range []string{
+		`{"timeframe": {"start": "now-15y"}}`,
+		`{"timeframe": {"start": "2023-01-02 15:00:00"}}`,
+		`{"timeframe": {"start": "1969-12-31T00:00:00Z"}}`,
+		`{"timeframe": {"start": "now-15m", "startTimeUnixNanoSec": 1}}`,
+		`{"timeframe": {"start": "now", "end": "now-1h"}}`,
+	} {
+		req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader([]byte(body)))
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, http.StatusBadRequest, resRecorder.Code, body)
+	}
+}
+
+func TestRangeFilterValues(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{Debug: false}, &srMock)
+
+	for value, expectedStatus := range map[string]int{`500`: http.StatusOK, `500.5`: http.StatusOK, `"500"`: http.StatusBadRequest} {
+		body := fmt.Sprintf(`{"timeframe": {"start": "now-1h"}, "filters": [{"keyValueFilter": {"key": "span.attributes.http.status_code", "operator": "gte", "value": %s}}]}`, value)
+		req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader([]byte(body)))
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, expectedStatus, resRecorder.Code, value)
+	}
+}
+
+func TestSearchRouteClientDisconnect(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	var blocking pkgspanreader.SpanReader = blockingSpanReader{SpanReader: srMock}
+	api := NewAPI(fakeLogger, config.Config{Debug: false}, &blocking)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	jsonBody := []byte(fmt.Sprintf("{\"timeframe\": { \"startTime\": 0, \"endTime\": %v }}", time.Now().UnixNano()))
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader(jsonBody))
+	resRecorder := httptest.NewRecorder()
+	time.AfterFunc(10*time.Millisecond, cancel)
+	// returns only once the search observes the disconnect
+	api.router.ServeHTTP(resRecorder, req)
+
+	assert.Equal(t, statusClientClosedRequest, resRecorder.Code)
+	assert.Contains(t, resRecorder.Body.String(), "query canceled")
+}
+
+func TestAdmissionQueue(t *testing.T) {
+	a := newAdmission(1, 1, 50*time.Millisecond)
+	assert.True(t, a.acquire(context.Background()))
+
+	// queued until the queue timeout
+	assert.False(t, a.acquire(context.Background()))
+
+	// queued until a slot is released
+	time.AfterFunc(10*time.Millisecond, a.release)
+	assert.True(t, a.acquire(context.Background()))
+
+	// the queue is full
+	queued := make(chan bool)
+	go func() { queued <- a.acquire(context.Background()) }()
+	assert.Eventually(t, func() bool { return len(a.queue) == 1 }, time.Second, time.Millisecond)
+	assert.False(t, a.acquire(context.Background()))
+	assert.False(t, <-queued)
+}
+
+func TestAdmissionControl(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	cfg := config.Config{Debug: false, APIMaxConcurrentQueries: 1, APIQueryQueueTimeoutSeconds: 5}
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, cfg, &srMock)
+	jsonBody := []byte(fmt.Sprintf("{\"timeframe\": { \"startTime\": 0, \"endTime\": %v }}", time.Now().UnixNano()))
+
+	// a heavy query in flight
+	assert.True(t, api.admission.acquire(context.Background()))
+
+	req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader(jsonBody))
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusTooManyRequests, resRecorder.Code)
+	assert.Equal(t, "5", resRecorder.Header().Get("Retry-After"))
+
+	// cheap routes aren't limited
+	req, _ = http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/ping"), nil)
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+
+	api.admission.release()
+	req, _ = http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader(jsonBody))
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+}
+
+func TestRateLimit(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	cfg := config.Config{
+		Debug:                         false,
+		APIRateLimitRequestsPerSecond: 0.5,
+		APIRateLimitKeyHeader:         "X-API-Key",
+		APIRateLimitOverrides:         "premium=100",
+	}
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, cfg, &srMock)
+	jsonBody := []byte(fmt.Sprintf("{\"timeframe\": { \"startTime\": 0, \"endTime\": %v }}", time.Now().UnixNano()))
+	search := func(apiKey string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader(jsonBody))
+		req.Header.Set("X-API-Key", apiKey)
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		return resRecorder
+	}
+
+	assert.Equal(t, http.StatusOK, search("tenant-a").Code)
+	resRecorder := search("tenant-a")
+	assert.Equal(t, http.StatusTooManyRequests, resRecorder.Code)
+	assert.Equal(t, "2", resRecorder.Header().Get("Retry-After"))
+
+	// keys are limited separately, and overrides apply to their keys
+	assert.Equal(t, http.StatusOK, search("tenant-b").Code)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, search("premium").Code)
+	}
+}
+
+func TestReloadRateLimit(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{Debug: false, APIRateLimitKeyHeader: "X-API-Key"}, &srMock)
+	jsonBody := []byte(fmt.Sprintf("{\"timeframe\": { \"startTime\": 0, \"endTime\": %v }}", time.Now().UnixNano()))
+	search := func() int {
+		req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader(jsonBody))
+		req.Header.Set("X-API-Key", "tenant-a")
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		return resRecorder.Code
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, search(), "no limit is set")
+	}
+	api.Reload(config.Config{APIRateLimitRequestsPerSecond: 0.5})
+	assert.Equal(t, http.StatusOK, search())
+	assert.Equal(t, http.StatusTooManyRequests, search(), "the reloaded limit applies")
+
+	// invalid overrides keep the current limits
+	api.Reload(config.Config{APIRateLimitOverrides: "tenant-a"})
+	assert.Equal(t, http.StatusTooManyRequests, search())
+}
+
+func TestCORSAndSecurityHeaders(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	cfg := config.Config{
+		Debug:                     false,
+		APICORSAllowedOrigins:     "https://ui.example.com, https://*.teletrace.io",
+		APICORSAllowedMethods:     "GET,POST",
+		APICORSAllowedHeaders:     "Content-Type,X-API-Key",
+		APICORSAllowCredentials:   true,
+		APICORSMaxAgeSeconds:      600,
+		APISecurityHeadersEnabled: true,
+		APIFrameOptions:           "DENY",
+		APIHSTSMaxAgeSeconds:      31536000,
+	}
+	api := NewAPI(fakeLogger, cfg, &srMock)
+
+	req, _ := http.NewRequest(http.MethodOptions, path.Join(apiPrefix, "/search"), nil)
+	req.Header.Set("Origin", "https://ui.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "x-api-key")
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusNoContent, resRecorder.Code)
+	assert.Equal(t, "https://ui.example.com", resRecorder.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", resRecorder.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "600", resRecorder.Header().Get("Access-Control-Max-Age"))
+
+	req, _ = http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/ping"), nil)
+	req.Header.Set("Origin", "https://app.teletrace.io")
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	assert.Equal(t, "https://app.teletrace.io", resRecorder.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "nosniff", resRecorder.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", resRecorder.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "max-age=31536000", resRecorder.Header().Get("Strict-Transport-Security"))
+	assert.Empty(t, resRecorder.Header().Get("Content-Security-Policy"))
+
+	req, _ = http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/ping"), nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusForbidden, resRecorder.Code)
+	assert.Empty(t, resRecorder.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	for acceptEncoding, expected := range map[string]string{
+		"":                        "",
+		"identity":                "",
+		"gzip, deflate, br":       encodingGzip,
+		"gzip, zstd":              encodingZstd,
+		"zstd;q=0, gzip;q=0.5":    encodingGzip,
+		"GZIP":                    encodingGzip,
+		"*":                       encodingGzip,
+		"br;q=1.0, zstd;q=0.8, *": encodingZstd,
+	} {
+		assert.Equal(t, expected, negotiateEncoding(acceptEncoding), acceptEncoding)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	cfg := config.Config{Debug: false, APICompressionEnabled: true, APICompressionMinSizeBytes: 256}
+	api := NewAPI(fakeLogger, cfg, &srMock)
+	jsonBody := []byte(fmt.Sprintf("{\"timeframe\": { \"startTime\": 0, \"endTime\": %v }}", time.Now().UnixNano()))
+
+	req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader(jsonBody))
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	assert.Greater(t, resRecorder.Body.Len(), 256)
+	assert.Empty(t, resRecorder.Header().Get("Content-Encoding"), "the client doesn't accept compressed responses")
+
+	for _, encoding := range []string{encodingGzip, encodingZstd} {
+		req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader(jsonBody))
+		req.Header.Set("Accept-Encoding", encoding)
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, http.StatusOK, resRecorder.Code)
+		assert.Equal(t, encoding, resRecorder.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", resRecorder.Header().Get("Vary"))
+
+		var decoder io.Reader
+		if encoding == encodingGzip {
+			decoder, _ = gzip.NewReader(resRecorder.Body)
+		} else {
+			decoder, _ = zstd.NewReader(resRecorder.Body)
+		}
+		var res spansquery.SearchResponse
+		assert.NoError(t, json.NewDecoder(decoder).Decode(&res))
+		assert.Len(t, res.Spans, 1)
+	}
+
+	// small responses aren't compressed
+	req, _ = http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/ping"), nil)
+	req.Header.Set("Accept-Encoding", encodingGzip)
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Empty(t, resRecorder.Header().Get("Content-Encoding"))
+	assert.Equal(t, "pong", resRecorder.Body.String())
+}
+
+func TestProtobufResponses(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{Debug: false}, &srMock)
+	jsonBody := []byte(fmt.Sprintf("{\"timeframe\": { \"startTime\": 0, \"endTime\": %v }}", time.Now().UnixNano()))
+
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader(jsonBody)),
+		httptest.NewRequest(http.MethodGet, path.Join(apiPrefix, "/trace/1234567887654321"), nil),
+	}
+	for _, req := range requests {
+		req.Header.Set("Accept", "application/x-protobuf")
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, http.StatusOK, resRecorder.Code)
+		assert.Equal(t, protobufContentType, resRecorder.Header().Get("Content-Type"))
+
+		traces, err := (&ptrace.ProtoUnmarshaler{}).UnmarshalTraces(resRecorder.Body.Bytes())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, traces.SpanCount())
+		span := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+		assert.Equal(t, "span_name", span.Name())
+		assert.Equal(t, ptrace.SpanKindInternal, span.Kind())
+		assert.Equal(t, ptrace.StatusCodeOk, span.Status().Code())
+		assert.Equal(t, "00000000000000001234567887654321", span.TraceID().String())
+	}
+
+	// JSON is still preferred when the client accepts both
+	req := httptest.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader(jsonBody))
+	req.Header.Set("Accept", "application/json, application/x-protobuf")
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Contains(t, resRecorder.Header().Get("Content-Type"), "application/json")
+}
+
+func TestExportSearch(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{Debug: false, APIExportMaxSpans: 10}, &srMock)
+	jsonBody := []byte(fmt.Sprintf("{\"timeframe\": { \"startTime\": 0, \"endTime\": %v }}", time.Now().UnixNano()))
+
+	req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search/export"), bytes.NewReader(jsonBody))
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	assert.Equal(t, arrowexport.ContentType, resRecorder.Header().Get("Content-Type"))
+
+	r, err := ipc.NewReader(resRecorder.Body)
+	assert.NoError(t, err)
+	defer r.Release()
+	var rows int64
+	for r.Next() {
+		rows += r.Record().NumRows()
+		assert.Equal(t, "span_name", r.Record().Column(3).(*array.String).Value(0))
+	}
+	assert.NoError(t, r.Err())
+	assert.Equal(t, int64(1), rows)
+}
+
+func TestValidateSearch(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{Debug: false}, &srMock)
+
+	jsonBody := []byte(`{"filters": [
+		{"keyValueFilter": {"key": "custom-tag", "operator": "equals", "value": "a"}},
+		{"keyValueFilter": {"key": "unknown-tag", "operator": "equals", "value": "a"}}
+	]}`)
+	req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search/validate"), bytes.NewReader(jsonBody))
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+
+	var resBody spansquery.ValidateSearchResponse
+	assert.NoError(t, json.NewDecoder(resRecorder.Body).Decode(&resBody))
+	assert.False(t, resBody.Valid)
+	assert.Len(t, resBody.Errors, 1)
+	assert.Equal(t, 1, *resBody.Errors[0].FilterIndex)
+	assert.Equal(t, "key", resBody.Errors[0].Field)
+}
+
 func TestGetAvailableTags(t *testing.T) {
 	fakeLogger, _ := getLoggerObserver()
 	cfg := config.Config{Debug: false}
@@ -172,12 +971,89 @@ func TestGetAvailableTags(t *testing.T) {
 	assert.Equal(t, mockTagName, resBody.Tags[0].Name)
 }
 
-func TestTagsValues(t *testing.T) {
+func TestAccessControl(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	policyPath := filepath.Join(t.TempDir(), "policy.yaml")
+	policy := "roles:\n  payments:\n    filters:\n      - key: resource.attributes.service.name\n        operator: equals\n        value: checkout\n"
+	assert.NoError(t, os.WriteFile(policyPath, []byte(policy), 0o600))
+	cfg := config.Config{Debug: false, ACLPolicyFile: policyPath, ACLRoleHeader: "X-Teletrace-Role"}
+	srMock, _ := spanreader.NewSpanReaderMock()
+
+	api := NewAPI(fakeLogger, cfg, &srMock)
+
+	for role, expectedCode := range map[string]int{
+		"":         http.StatusForbidden,
+		"unknown":  http.StatusForbidden,
+		"payments": http.StatusOK,
+	} {
+		req, _ := http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/tags"), nil)
+		req.Header.Set("X-Teletrace-Role", role)
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, expectedCode, resRecorder.Code, "role %q", role)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/ping"), nil)
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+}
+
+func TestTagsValues(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	cfg := config.Config{Debug: false}
+	expectedTag := "span.attributes.custom-tag"
+	jsonBody := []byte(fmt.Sprintf("{\"timeframe\": { \"startTime\": 0, \"endTime\": %v }}", time.Now().UnixNano()))
+	req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, fmt.Sprintf("/tags/%v", expectedTag)), bytes.NewReader(jsonBody))
+	resRecorder := httptest.NewRecorder()
+	srMock, _ := spanreader.NewSpanReaderMock()
+
+	api := NewAPI(fakeLogger, cfg, &srMock)
+
+	api.router.ServeHTTP(resRecorder, req)
+
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+
+	var resBody *tagsquery.TagValuesResponse
+	err := json.NewDecoder(resRecorder.Body).Decode(&resBody)
+	assert.Nil(t, err)
+	assert.NotNil(t, resBody)
+	assert.NotEmpty(t, resBody.Values)
+
+	expectedValue := "custom-value"
+	expectedValueCount := 3
+	assert.Equal(t, expectedValue, resBody.Values[0].Value)
+	assert.Equal(t, expectedValueCount, resBody.Values[0].Count)
+}
+
+func TestTagsValuesOfEnumTag(t *testing.T) {
+	for enumNames, expectedValue := range map[bool]string{false: "Server", true: "SPAN_KIND_SERVER"} {
+		fakeLogger, _ := getLoggerObserver()
+		cfg := config.Config{Debug: false, APIOTLPEnumNames: enumNames}
+		jsonBody := []byte(fmt.Sprintf("{\"timeframe\": { \"startTime\": 0, \"endTime\": %v }}", time.Now().UnixNano()))
+		req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/tags/span.kind"), bytes.NewReader(jsonBody))
+		resRecorder := httptest.NewRecorder()
+		srMock, _ := spanreader.NewSpanReaderMock()
+
+		api := NewAPI(fakeLogger, cfg, &srMock)
+
+		api.router.ServeHTTP(resRecorder, req)
+
+		assert.Equal(t, http.StatusOK, resRecorder.Code)
+
+		var resBody *tagsquery.TagValuesResponse
+		err := json.NewDecoder(resRecorder.Body).Decode(&resBody)
+		assert.Nil(t, err)
+		assert.Len(t, resBody.Values, 1)
+		assert.Equal(t, expectedValue, resBody.Values[0].Value)
+	}
+}
+
+func TestSearchRouteWithOTLPEnumNames(t *testing.T) {
 	fakeLogger, _ := getLoggerObserver()
-	cfg := config.Config{Debug: false}
-	expectedTag := "span.attributes.custom-tag"
+	cfg := config.Config{Debug: false, APIOTLPEnumNames: true}
 	jsonBody := []byte(fmt.Sprintf("{\"timeframe\": { \"startTime\": 0, \"endTime\": %v }}", time.Now().UnixNano()))
-	req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, fmt.Sprintf("/tags/%v", expectedTag)), bytes.NewReader(jsonBody))
+	req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader(jsonBody))
 	resRecorder := httptest.NewRecorder()
 	srMock, _ := spanreader.NewSpanReaderMock()
 
@@ -187,16 +1063,12 @@ func TestTagsValues(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, resRecorder.Code)
 
-	var resBody *tagsquery.TagValuesResponse
+	var resBody *spansquery.SearchResponse
 	err := json.NewDecoder(resRecorder.Body).Decode(&resBody)
 	assert.Nil(t, err)
-	assert.NotNil(t, resBody)
-	assert.NotEmpty(t, resBody.Values)
-
-	expectedValue := "custom-value"
-	expectedValueCount := 3
-	assert.Equal(t, expectedValue, resBody.Values[0].Value)
-	assert.Equal(t, expectedValueCount, resBody.Values[0].Count)
+	assert.NotEmpty(t, resBody.Spans)
+	assert.Equal(t, "SPAN_KIND_INTERNAL", resBody.Spans[0].Span.Kind)
+	assert.Equal(t, "STATUS_CODE_OK", resBody.Spans[0].Span.Status.Code)
 }
 
 func TestSearchRouteWithMalformedRequestBody(t *testing.T) {
@@ -219,6 +1091,21 @@ func TestSearchRouteWithMalformedRequestBody(t *testing.T) {
 	assert.NotNil(t, resBody.ErrorMessage)
 }
 
+func TestSearchRouteWithInvalidSampleSize(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	cfg := config.Config{Debug: false}
+	body := []byte("{\"timeframe\": {\"startTime\": 0, \"endTime\": 1}, \"sample\": {\"size\": 0}}")
+	req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader(body))
+	resRecorder := httptest.NewRecorder()
+	srMock, _ := spanreader.NewSpanReaderMock()
+
+	api := NewAPI(fakeLogger, cfg, &srMock)
+
+	api.router.ServeHTTP(resRecorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, resRecorder.Code)
+}
+
 func TestTagsValuesWithMalformedRequestBody(t *testing.T) {
 	fakeLogger, _ := getLoggerObserver()
 	cfg := config.Config{Debug: false}
@@ -273,6 +1160,611 @@ func TestTagsStatistics(t *testing.T) {
 	assert.Equal(t, expectedP99, resBody.Statistics[tagsquery.P99])
 }
 
+func TestTagsStatisticsBatch(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{}, &srMock)
+
+	for body, status := range map[string]int{
+		`{"desiredStatistics": ["min", "max"], "tags": ["someNumber", "otherNumber"]}`: http.StatusOK,
+		`{"desiredStatistics": ["min", "max"], "tags": []}`:                            http.StatusBadRequest,
+		`{"desiredStatistics": ["min", "max"]}`:                                        http.StatusBadRequest,
+	} {
+		req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/tags/statistics"), bytes.NewReader([]byte(body)))
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, status, resRecorder.Code, body)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/tags/statistics"),
+		bytes.NewReader([]byte(`{"desiredStatistics": ["min", "max"], "tags": ["someNumber", "otherNumber"]}`)))
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+
+	var resBody tagsquery.TagsStatisticsBatchResponse
+	assert.Nil(t, json.NewDecoder(resRecorder.Body).Decode(&resBody))
+	assert.Len(t, resBody.Tags, 2)
+	assert.Equal(t, 10.0, resBody.Tags["otherNumber"].Statistics[tagsquery.MAX])
+}
+
+func TestTagsStatisticsStaleWhileRevalidateCache(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	cfg := config.Config{
+		Debug:                       false,
+		APICacheMode:                cacheModeStaleWhileRevalidate,
+		APICacheTTLSeconds:          30,
+		APICacheMaxStalenessSeconds: 300,
+	}
+	jsonBody := []byte("{\"desiredStatistics\": [\"min\"], \"timeframe\": { \"startTime\": 0, \"endTime\": 0 }}")
+	srMock, _ := spanreader.NewSpanReaderMock()
+
+	api := NewAPI(fakeLogger, cfg, &srMock)
+
+	for _, expectedStatus := range []string{"MISS", "HIT"} {
+		req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/tags/someNumber/statistics"), bytes.NewReader(jsonBody))
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+
+		assert.Equal(t, http.StatusOK, resRecorder.Code)
+		assert.Equal(t, expectedStatus, resRecorder.Header().Get(cacheStatusHeader))
+
+		var resBody *tagsquery.TagStatisticsResponse
+		err := json.NewDecoder(resRecorder.Body).Decode(&resBody)
+		assert.Nil(t, err)
+		assert.NotEmpty(t, resBody.Statistics)
+	}
+}
+
+func TestWarmUpPrimesAvailableTagsCache(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	cfg := config.Config{
+		Debug:                     false,
+		APICacheMode:              cacheModeStaleWhileRevalidate,
+		APICacheTTLSeconds:        30,
+		APIWarmUpTags:             "span.attributes.custom-tag",
+		APIWarmUpTimeframeMinutes: 60,
+	}
+	srMock, _ := spanreader.NewSpanReaderMock()
+
+	api := NewAPI(fakeLogger, cfg, &srMock)
+	api.warmUp(context.Background())
+
+	req, _ := http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/tags"), nil)
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	assert.Equal(t, "HIT", resRecorder.Header().Get(cacheStatusHeader))
+}
+
+func TestWarmUpPrimesPopularTagValuesCache(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	cfg := config.Config{
+		Debug:                     false,
+		APICacheMode:              cacheModeStaleWhileRevalidate,
+		APICacheTTLSeconds:        30,
+		APIWarmUpTimeframeMinutes: 60,
+		APIWarmUpPopularTags:      1,
+		QueryHistorySize:          10,
+	}
+	srMock, _ := spanreader.NewSpanReaderMock()
+
+	api := NewAPI(fakeLogger, cfg, &srMock)
+	api.SetMetadataStore(memory.NewMetadataStore())
+	for i, tag := range []string{"span.name", "span.name", "span.attributes.custom-tag"} {
+		err := api.queryHistory.Record(context.Background(), "alice", spansquery.SearchRequest{
+			SearchFilters: []model.SearchFilter{{
+				KeyValueFilter: &model.KeyValueFilter{Key: model.FilterKey(tag), Operator: spansquery.OPERATOR_EQUALS, Value: i},
+			}},
+		})
+		assert.NoError(t, err)
+	}
+	api.warmUp(context.Background())
+
+	for tag, expectedStatus := range map[string]string{"span.name": "HIT", "span.attributes.custom-tag": "MISS"} {
+		req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/tags", tag), bytes.NewReader([]byte(`{"filters":[]}`)))
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+
+		assert.Equal(t, http.StatusOK, resRecorder.Code, tag)
+		assert.Equal(t, expectedStatus, resRecorder.Header().Get(cacheStatusHeader), tag)
+	}
+}
+
+func TestSettings(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{Debug: false}, &srMock)
+
+	req, _ := http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/settings"), nil)
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusNotImplemented, resRecorder.Code)
+
+	api.SetMetadataStore(memory.NewMetadataStore())
+	for body, expectedStatus := range map[string]int{`{"mode": "dark"}`: http.StatusNoContent, `{"mode"`: http.StatusBadRequest} {
+		req, _ := http.NewRequest(http.MethodPut, path.Join(apiPrefix, "/settings/ui.theme"), bytes.NewReader([]byte(body)))
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, expectedStatus, resRecorder.Code, body)
+	}
+
+	req, _ = http.NewRequest(http.MethodPut, path.Join(apiPrefix, "/settings/installation.system-id"), bytes.NewReader([]byte(`"id"`)))
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusForbidden, resRecorder.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/settings/ui.theme"), nil)
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	assert.JSONEq(t, `{"mode": "dark"}`, resRecorder.Body.String())
+
+	req, _ = http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/settings/ui.missing"), nil)
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusNotFound, resRecorder.Code)
+}
+
+func TestAuditLog(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	cfg := config.Config{
+		Debug:                false,
+		AuditLogEnabled:      true,
+		APIUserHeader:        "X-Teletrace-User",
+		AuditLogAPIKeyHeader: "X-API-Key",
+	}
+	api := NewAPI(fakeLogger, cfg, &srMock)
+	api.SetMetadataStore(memory.NewMetadataStore())
+
+	jsonBody := []byte(`{"timeframe": {"start": "now-1h"}, "filters": [
+		{"keyValueFilter": {"key": "span.name", "operator": "equals", "value": "GET /users"}}]}`)
+	req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader(jsonBody))
+	req.Header.Set("X-Teletrace-User", "alice")
+	req.Header.Set("X-API-Key", "secret")
+	api.router.ServeHTTP(httptest.NewRecorder(), req)
+
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(apiPrefix, "/snapshots/token"), nil)
+	req.Header.Set("X-Teletrace-User", "bob")
+	api.router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// reads of non-span data aren't audited
+	req, _ = http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/settings"), nil)
+	api.router.ServeHTTP(httptest.NewRecorder(), req)
+
+	req, _ = http.NewRequest(http.MethodGet, auditLogPath+"?user=alice", nil)
+	resRecorder := httptest.NewRecorder()
+	api.adminMux.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	var res auditv1.GetAuditLogResponse
+	assert.NoError(t, json.Unmarshal(resRecorder.Body.Bytes(), &res))
+	assert.Len(t, res.Entries, 1)
+	entry := res.Entries[0]
+	assert.Equal(t, auditv1.ActionQuery, entry.Action)
+	assert.Equal(t, path.Join(apiPrefix, "/search"), entry.Route)
+	assert.Equal(t, http.StatusOK, entry.StatusCode)
+	assert.Equal(t, "timeframe now-1h..now, span.name equals GET /users", entry.Summary)
+	assert.NotNil(t, entry.ResultCount)
+	assert.NotEmpty(t, entry.APIKeyFingerprint)
+	assert.NotContains(t, resRecorder.Body.String(), "secret")
+
+	req, _ = http.NewRequest(http.MethodGet, auditLogPath, nil)
+	resRecorder = httptest.NewRecorder()
+	api.adminMux.ServeHTTP(resRecorder, req)
+	assert.NoError(t, json.Unmarshal(resRecorder.Body.Bytes(), &res))
+	assert.Len(t, res.Entries, 2)
+	assert.Equal(t, auditv1.ActionDelete, res.Entries[0].Action)
+	assert.Equal(t, "bob", res.Entries[0].User)
+	assert.Equal(t, "token=token", res.Entries[0].Summary)
+
+	req, _ = http.NewRequest(http.MethodGet, auditLogPath+"?limit=x", nil)
+	resRecorder = httptest.NewRecorder()
+	api.adminMux.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusBadRequest, resRecorder.Code)
+}
+
+func TestSystemInfoFromSettings(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{Debug: false}, &srMock)
+	store := memory.NewMetadataStore()
+	api.SetMetadataStore(store)
+	assert.NoError(t, settings.SystemId.Set(context.Background(), store, "installation-id"))
+
+	req, _ := http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/system-info"), nil)
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	assert.JSONEq(t, `{"systemId": "installation-id"}`, resRecorder.Body.String())
+}
+
+func TestTraceAnnotations(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{Debug: false}, &srMock)
+	api.SetMetadataStore(memory.NewMetadataStore())
+
+	for body, expectedStatus := range map[string]int{
+		`{"author": "alice", "text": "db pool exhausted", "spanId": "span"}`: http.StatusCreated,
+		`{"author": "alice"}`: http.StatusBadRequest,
+	} {
+		req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/trace/trace/annotations"), bytes.NewReader([]byte(body)))
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, expectedStatus, resRecorder.Code, body)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/trace/trace"), nil)
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	var traceRes spansquery.GetTraceResponse
+	assert.NoError(t, json.Unmarshal(resRecorder.Body.Bytes(), &traceRes))
+	assert.Len(t, traceRes.Annotations, 1)
+	annotation := traceRes.Annotations[0]
+	assert.Equal(t, "trace", annotation.TraceId)
+	assert.Equal(t, "span", annotation.SpanId)
+	assert.Equal(t, "db pool exhausted", annotation.Text)
+
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(apiPrefix, "/trace/trace/annotations", annotation.Id), nil)
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusNoContent, resRecorder.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/trace/trace/annotations"), nil)
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	assert.JSONEq(t, `{"annotations": []}`, resRecorder.Body.String())
+}
+
+// serviceSpanReader responds with the span of a trace of the checkout service, or of the billing service,
+// unless the request filters out the service of the trace.
+type serviceSpanReader struct {
+	pkgspanreader.SpanReader
+}
+
+func (sr serviceSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	service := "billing"
+	for _, f := range r.SearchFilters {
+		if f.KeyValueFilter != nil && f.KeyValueFilter.Key == "span.traceId" && f.KeyValueFilter.Value == "checkout-trace" {
+			service = "checkout"
+		}
+	}
+	for _, f := range r.SearchFilters {
+		if f.KeyValueFilter != nil && f.KeyValueFilter.Key == "resource.attributes.service.name" && f.KeyValueFilter.Value != service {
+			return &spansquery.SearchResponse{}, nil
+		}
+	}
+	span := spanformatutiltests.GenInternalSpan(nil, map[string]any{"service.name": service}, nil)
+	return &spansquery.SearchResponse{Spans: []*internalspan.InternalSpan{span}}, nil
+}
+
+func TestTraceAnnotationsAccessControl(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	policyPath := filepath.Join(t.TempDir(), "policy.yaml")
+	policy := "roles:\n  payments:\n    filters:\n      - key: resource.attributes.service.name\n        operator: equals\n        value: checkout\n"
+	assert.NoError(t, os.WriteFile(policyPath, []byte(policy), 0o600))
+	cfg := config.Config{Debug: false, ACLPolicyFile: policyPath, ACLRoleHeader: "X-Teletrace-Role"}
+	srMock, _ := spanreader.NewSpanReaderMock()
+	var sr pkgspanreader.SpanReader = serviceSpanReader{SpanReader: srMock}
+	api := NewAPI(fakeLogger, cfg, &sr)
+	api.SetMetadataStore(memory.NewMetadataStore())
+	hidden, err := api.annotations.Add(context.Background(), "billing-trace", annotationsv1.AddAnnotationRequest{Author: "bob", Text: "card declined"})
+	assert.NoError(t, err)
+
+	serve := func(method string, url string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path.Join(apiPrefix, url), bytes.NewReader([]byte(body)))
+		req.Header.Set("X-Teletrace-Role", "payments")
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		return resRecorder
+	}
+	addBody := `{"author": "alice", "text": "db pool exhausted"}`
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/trace/billing-trace/annotations", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/trace/billing-trace/annotations", addBody).Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, "/trace/billing-trace/annotations/"+hidden.Id, "").Code)
+	annotations, err := api.annotations.List(context.Background(), "billing-trace")
+	assert.NoError(t, err)
+	assert.Len(t, annotations, 1)
+
+	resRecorder := serve(http.MethodPost, "/trace/checkout-trace/annotations", addBody)
+	assert.Equal(t, http.StatusCreated, resRecorder.Code)
+	var added annotationsv1.Annotation
+	assert.NoError(t, json.Unmarshal(resRecorder.Body.Bytes(), &added))
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/trace/checkout-trace/annotations", "").Code)
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodDelete, "/trace/checkout-trace/annotations/"+added.Id, "").Code)
+}
+
+func TestTraceSnapshots(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{Debug: false}, &srMock)
+	api.SetMetadataStore(memory.NewMetadataStore())
+
+	req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/trace/trace/snapshots"), bytes.NewReader([]byte(`{"expiresInSeconds": 3600}`)))
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusCreated, resRecorder.Code)
+	var created snapshots.CreateSnapshotResponse
+	assert.NoError(t, json.Unmarshal(resRecorder.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.Token)
+	assert.Greater(t, created.ExpiresAtUnixNano, uint64(time.Now().UnixNano()))
+
+	req, _ = http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/snapshots", created.Token), nil)
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	var snapshot snapshots.Snapshot
+	assert.NoError(t, json.Unmarshal(resRecorder.Body.Bytes(), &snapshot))
+	assert.Equal(t, "trace", snapshot.TraceId)
+	assert.Len(t, snapshot.Spans, 1)
+
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(apiPrefix, "/snapshots", created.Token), nil)
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusNoContent, resRecorder.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/snapshots", created.Token), nil)
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusNotFound, resRecorder.Code)
+}
+
+func TestQueryHistory(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{Debug: false, APIUserHeader: "X-User", QueryHistorySize: 10}, &srMock)
+	api.SetMetadataStore(memory.NewMetadataStore())
+
+	for _, user := range []string{"alice", ""} {
+		req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/search"), bytes.NewReader([]byte(`{"timeframe": {"start": "now-15m"}}`)))
+		req.Header.Set("X-User", user)
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, http.StatusOK, resRecorder.Code)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/query-history"), nil)
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusUnauthorized, resRecorder.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/query-history"), nil)
+	req.Header.Set("X-User", "alice")
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	var history queryhistory.GetQueryHistoryResponse
+	assert.NoError(t, json.Unmarshal(resRecorder.Body.Bytes(), &history))
+	assert.Len(t, history.Entries, 1)
+	entry := history.Entries[0]
+	assert.Equal(t, "now-15m", entry.Request.Timeframe.Start)
+	assert.Zero(t, entry.Request.Timeframe.StartTime)
+
+	req, _ = http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/query-history", entry.Id, "run"), nil)
+	req.Header.Set("X-User", "alice")
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+
+	req, _ = http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/query-history", entry.Id, "run"), nil)
+	req.Header.Set("X-User", "bob")
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusNotFound, resRecorder.Code)
+}
+
+func TestAlertRules(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{Debug: false}, &srMock)
+	api.SetMetadataStore(memory.NewMetadataStore())
+
+	req, _ := http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/alerts/rules"), nil)
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusNotImplemented, resRecorder.Code)
+
+	api = NewAPI(fakeLogger, config.Config{Debug: false, AlertsEnabled: true, AlertsEvaluationIntervalSeconds: 60}, &srMock)
+	api.SetMetadataStore(memory.NewMetadataStore())
+	for body, expectedStatus := range map[string]int{
+		`{"name": "errors", "condition": "error_rate", "threshold": 0.05, "windowSeconds": 300}`: http.StatusCreated,
+		`{"name": "errors", "condition": "error_rate", "threshold": 5, "windowSeconds": 300}`:    http.StatusBadRequest,
+	} {
+		req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/alerts/rules"), bytes.NewReader([]byte(body)))
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, expectedStatus, resRecorder.Code, body)
+	}
+	assert.NoError(t, api.alertEvaluator.EvaluateAll(context.Background()))
+
+	req, _ = http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/alerts/rules"), nil)
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	var rules alerts.GetRulesResponse
+	assert.NoError(t, json.Unmarshal(resRecorder.Body.Bytes(), &rules))
+	assert.Len(t, rules.Rules, 1)
+	rule := rules.Rules[0]
+	assert.Equal(t, "errors", rule.Name)
+	assert.NotEqual(t, alerts.STATE_PENDING, rule.State.State)
+
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(apiPrefix, "/alerts/rules", rule.Id), nil)
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusNoContent, resRecorder.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/alerts/rules", rule.Id), nil)
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusNotFound, resRecorder.Code)
+}
+
+func TestSLOs(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{Debug: false}, &srMock)
+	api.SetMetadataStore(memory.NewMetadataStore())
+
+	for body, expectedStatus := range map[string]int{
+		`{"name": "checkout", "service": "checkout", "type": "availability", "objective": 0.999, "windowDays": 30}`: http.StatusCreated,
+		`{"name": "checkout", "service": "checkout", "type": "latency", "objective": 0.999, "windowDays": 30}`:      http.StatusBadRequest,
+	} {
+		req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/slos"), bytes.NewReader([]byte(body)))
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, expectedStatus, resRecorder.Code, body)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/slos"), nil)
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	var list slos.GetSLOsResponse
+	assert.NoError(t, json.Unmarshal(resRecorder.Body.Bytes(), &list))
+	assert.Len(t, list.SLOs, 1)
+	id := list.SLOs[0].Id
+
+	req, _ = http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/slos", id), nil)
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	var slo slos.GetSLOResponse
+	assert.NoError(t, json.Unmarshal(resRecorder.Body.Bytes(), &slo))
+	assert.Equal(t, "checkout", slo.Service)
+	assert.Len(t, slo.BurnRates, 4)
+
+	for query, expectedStatus := range map[string]int{"?window=5m&window=1h": http.StatusOK, "?window=soon": http.StatusBadRequest} {
+		req, _ = http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/slos", id, "burn-rates")+query, nil)
+		resRecorder = httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, expectedStatus, resRecorder.Code, query)
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(apiPrefix, "/slos", id), nil)
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusNoContent, resRecorder.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/slos", id), nil)
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusNotFound, resRecorder.Code)
+}
+
+func TestAnomalies(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	for enabled, expectedStatus := range map[bool]int{false: http.StatusNotImplemented, true: http.StatusOK} {
+		cfg := config.Config{AnomaliesEnabled: enabled, AnomaliesIntervalSeconds: 900, AnomaliesWindowMinutes: 60}
+		api := NewAPI(fakeLogger, cfg, &srMock)
+		req, _ := http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/anomalies"), nil)
+		resRecorder := httptest.NewRecorder()
+		api.router.ServeHTTP(resRecorder, req)
+		assert.Equal(t, expectedStatus, resRecorder.Code)
+	}
+}
+
+type linkedSpanReader struct {
+	pkgspanreader.SpanReader
+}
+
+func (sr linkedSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	span := func(traceId string, spanId string, links ...*internalspan.SpanLink) *internalspan.InternalSpan {
+		return &internalspan.InternalSpan{Span: &internalspan.Span{TraceId: traceId, SpanId: spanId, Links: links}}
+	}
+	var spans []*internalspan.InternalSpan
+	switch r.SearchFilters[0].KeyValueFilter.Key {
+	case "span.links.traceId": // spans linking to the consumer span
+		spans = append(spans, span("t2", "retry", &internalspan.SpanLink{TraceId: "t1", SpanId: "consumer"}))
+	case "span.spanId": // spans linked by the consumer span
+		spans = append(spans, span("t0", "producer"))
+	default:
+		spans = append(spans, span("t1", "consumer",
+			&internalspan.SpanLink{TraceId: "t0", SpanId: "producer", Attributes: internalspan.Attributes{"messaging.operation": "publish"}},
+			&internalspan.SpanLink{TraceId: "t9", SpanId: "expired"},
+			&internalspan.SpanLink{},
+		))
+	}
+	return &spansquery.SearchResponse{Spans: spans}, nil
+}
+
+func TestSpanLinks(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	var sr pkgspanreader.SpanReader = linkedSpanReader{SpanReader: srMock}
+	api := NewAPI(fakeLogger, config.Config{}, &sr)
+
+	req, _ := http.NewRequest(http.MethodGet, path.Join(apiPrefix, "/trace/t1/spans/consumer/links"), nil)
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+
+	var res spanlinks.GetSpanLinksResponse
+	assert.NoError(t, json.NewDecoder(resRecorder.Body).Decode(&res))
+	assert.Len(t, res.Outgoing, 2)
+	assert.Equal(t, "producer", res.Outgoing[0].Span.Span.SpanId)
+	assert.Equal(t, "publish", res.Outgoing[0].Attributes["messaging.operation"])
+	assert.Nil(t, res.Outgoing[1].Span)
+	assert.Len(t, res.Incoming, 1)
+	assert.Equal(t, "retry", res.Incoming[0].SpanId)
+	assert.False(t, res.Truncated)
+}
+
+func TestArchive(t *testing.T) {
+	restored := 0
+	restoreServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		restored++
+	}))
+	defer restoreServer.Close()
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{
+		ArchiveStoreType:       "disk",
+		ArchiveDirectory:       t.TempDir(),
+		ArchiveMaxTraces:       10,
+		ArchiveRestoreEndpoint: restoreServer.URL,
+	}, &srMock)
+
+	body, _ := json.Marshal(archivemodel.ArchiveRequest{TraceIds: []string{"abc"}})
+	req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/archive"), bytes.NewReader(body))
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	var res archivemodel.ArchiveResponse
+	assert.NoError(t, json.NewDecoder(resRecorder.Body).Decode(&res))
+	assert.Equal(t, []string{"abc"}, res.TraceIds)
+
+	body, _ = json.Marshal(archivemodel.RestoreRequest{TraceIds: []string{"def"}})
+	req, _ = http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/archive/restore"), bytes.NewReader(body))
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusNotFound, resRecorder.Code)
+
+	body, _ = json.Marshal(archivemodel.RestoreRequest{TraceIds: []string{"abc"}})
+	req, _ = http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/archive/restore"), bytes.NewReader(body))
+	resRecorder = httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	assert.Equal(t, 1, restored)
+}
+
+func TestArchiveNotConfigured(t *testing.T) {
+	fakeLogger, _ := getLoggerObserver()
+	srMock, _ := spanreader.NewSpanReaderMock()
+	api := NewAPI(fakeLogger, config.Config{}, &srMock)
+
+	body, _ := json.Marshal(archivemodel.ArchiveRequest{TraceIds: []string{"abc"}})
+	req, _ := http.NewRequest(http.MethodPost, path.Join(apiPrefix, "/archive"), bytes.NewReader(body))
+	resRecorder := httptest.NewRecorder()
+	api.router.ServeHTTP(resRecorder, req)
+	assert.Equal(t, http.StatusNotImplemented, resRecorder.Code)
+}
+
 func TestRootStaticRoute(t *testing.T) {
 	runStaticFilesRouteTest(t, "/")
 }
@@ -0,0 +1,112 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/teletrace/teletrace/blobstore"
+	"github.com/teletrace/teletrace/pkg/archive"
+	"github.com/teletrace/teletrace/pkg/audit"
+	archivemodel "github.com/teletrace/teletrace/pkg/model/archive/v1"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// registerArchiver creates the archiver on top of the (possibly restricted) span reader, if an archive store is configured.
+func (api *API) registerArchiver() {
+	cfg := blobstore.Config{
+		Type:       api.config.ArchiveStoreType,
+		Directory:  api.config.ArchiveDirectory,
+		S3Bucket:   api.config.ArchiveS3Bucket,
+		S3Region:   api.config.ArchiveS3Region,
+		S3Prefix:   api.config.ArchiveS3Prefix,
+		S3Endpoint: api.config.ArchiveS3Endpoint,
+	}
+	if !cfg.Enabled() {
+		return
+	}
+	store, err := blobstore.NewStore(cfg)
+	if err != nil {
+		api.logger.Fatal("Failed to create archive store", zap.Error(err))
+	}
+	api.archiver = archive.NewArchiver(api.logger, *api.spanReader, store, archive.Config{
+		MaxTraces:       api.config.ArchiveMaxTraces,
+		RestoreEndpoint: api.config.ArchiveRestoreEndpoint,
+	})
+}
+
+func (api *API) archiveTraces(c *gin.Context) {
+	if api.archiver == nil {
+		respondWithError(http.StatusNotImplemented, fmt.Errorf("no archive store is configured"), c)
+		return
+	}
+	var req archivemodel.ArchiveRequest
+	isValidationError := api.validateRequestBody(&req, c)
+	if isValidationError {
+		return
+	}
+	handleTimeframe(&req.Timeframe)
+
+	res, err := api.archiver.Archive(c, req)
+	if err != nil {
+		respondWithError(archiveErrorStatusCode(err), err, c)
+		return
+	}
+	setAuditDetails(c, archiveSummary(req), len(res.TraceIds))
+	c.JSON(http.StatusOK, res)
+}
+
+func (api *API) restoreTraces(c *gin.Context) {
+	if api.archiver == nil {
+		respondWithError(http.StatusNotImplemented, fmt.Errorf("no archive store is configured"), c)
+		return
+	}
+	var req archivemodel.RestoreRequest
+	isValidationError := api.validateRequestBody(&req, c)
+	if isValidationError {
+		return
+	}
+
+	res, err := api.archiver.Restore(c, req)
+	if err != nil {
+		respondWithError(archiveErrorStatusCode(err), err, c)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// archiveSummary describes an archive request by its trace IDs, or its timeframe and filters.
+func archiveSummary(req archivemodel.ArchiveRequest) string {
+	if len(req.TraceIds) > 0 {
+		return "traceIds=" + strings.Join(req.TraceIds, ",")
+	}
+	return audit.Summarize(&req.Timeframe, req.SearchFilters)
+}
+
+func archiveErrorStatusCode(err error) int {
+	if errors.Is(err, archive.ErrNotArchived) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, archive.ErrTooManyTraces) {
+		return http.StatusBadRequest
+	}
+	return spanReaderErrorStatusCode(err)
+}
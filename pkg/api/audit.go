@@ -0,0 +1,186 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/teletrace/teletrace/pkg/audit"
+	auditv1 "github.com/teletrace/teletrace/pkg/model/audit/v1"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	auditLogPath = "/audit"
+
+	// context keys of the details set by handlers of audited routes
+	auditSummaryKey     = "auditSummary"
+	auditResultCountKey = "auditResultCount"
+)
+
+var errNoAuditLog = errors.New("audit log is disabled or no metadata store is configured")
+
+// auditedQueries are the audited routes which read spans, by method and route. Exports are listed separately,
+// and every DELETE route is audited as a deletion.
+var (
+	auditedQueries = map[string]bool{
+		"POST " + apiPrefix + "/search":                                 true,
+		"POST " + apiPrefix + "/events/search":                          true,
+		"POST " + apiPrefix + "/query-history/:id/run":                  true,
+		"POST " + apiPrefix + "/traces/summaries":                       true,
+		"GET " + apiPrefix + "/trace/:id":                               true,
+		"GET " + apiPrefix + "/trace/:id/tree":                          true,
+		"POST " + apiPrefix + "/trace/:id/search":                       true,
+		"GET " + apiPrefix + "/trace/:id/spans/:spanId/links":           true,
+		"GET " + apiPrefix + "/trace/:id/spans/:spanId/attributes/:key": true,
+		"POST " + apiPrefix + "/tags/:tag":                              true,
+		"POST " + apiPrefix + "/tags/:tag/statistics":                   true,
+		"POST " + apiPrefix + "/analysis/n-plus-one":                    true,
+		"POST " + apiPrefix + "/analysis/incomplete-traces":             true,
+		"POST " + apiPrefix + "/analysis/errors":                        true,
+		"POST " + apiPrefix + "/analysis/ingestion-lag":                 true,
+		"POST " + apiPrefix + "/analysis/flamegraph":                    true,
+	}
+	auditedExports = map[string]bool{
+		"POST " + apiPrefix + "/archive":             true,
+		"POST " + apiPrefix + "/search/export":       true,
+		"POST " + apiPrefix + "/trace/:id/snapshots": true,
+	}
+)
+
+// registerAuditLog serves the audit log on the admin port, if enabled, so it can't be read or tampered with
+// through the public API. Entries are recorded once a metadata store is set.
+func (api *API) registerAuditLog() {
+	if !api.config.AuditLogEnabled {
+		return
+	}
+	api.handleAdmin(auditLogPath, http.HandlerFunc(api.serveAuditLog))
+}
+
+// auditAction returns the audit action of the handled request, or an empty string if it isn't audited.
+func auditAction(c *gin.Context) string {
+	if c.Request.Method == http.MethodDelete {
+		return auditv1.ActionDelete
+	}
+	route := c.Request.Method + " " + c.FullPath()
+	if auditedQueries[route] {
+		return auditv1.ActionQuery
+	}
+	if auditedExports[route] {
+		return auditv1.ActionExport
+	}
+	return ""
+}
+
+// setAuditDetails describes the request handled by an audited route and the number of results it returned.
+// Requests of routes which don't set them are described by their route parameters.
+func setAuditDetails(c *gin.Context, summary string, resultCount int) {
+	c.Set(auditSummaryKey, summary)
+	c.Set(auditResultCountKey, resultCount)
+}
+
+// auditMiddleware records the audited requests once handled, including the rejected ones.
+func (api *API) auditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		action := auditAction(c)
+		if api.auditLog == nil || action == "" {
+			return
+		}
+		entry := auditv1.Entry{
+			User:       c.GetHeader(api.config.APIUserHeader),
+			ClientIP:   c.ClientIP(),
+			Action:     action,
+			Method:     c.Request.Method,
+			Route:      c.FullPath(),
+			Path:       c.Request.URL.Path,
+			StatusCode: c.Writer.Status(),
+			Summary:    c.GetString(auditSummaryKey),
+		}
+		if apiKey := c.GetHeader(api.config.AuditLogAPIKeyHeader); apiKey != "" {
+			entry.APIKeyFingerprint = audit.Fingerprint(apiKey)
+		}
+		if entry.Summary == "" {
+			entry.Summary = summarizeParams(c.Params)
+		}
+		if count, ok := c.Get(auditResultCountKey); ok {
+			resultCount := count.(int)
+			entry.ResultCount = &resultCount
+		}
+		if err := api.auditLog.Record(c, entry); err != nil {
+			// the request was already answered, the entry is lost but the failure is logged with its details
+			api.logger.Error("Failed to record audit entry",
+				zap.String("user", entry.User), zap.String("action", entry.Action),
+				zap.String("path", entry.Path), zap.Error(err))
+		}
+	}
+}
+
+// summarizeParams describes a request by its route parameters, e.g. "id=0af7651916cd43dd8448eb211c80319c".
+func summarizeParams(params gin.Params) string {
+	parts := make([]string, 0, len(params))
+	for _, param := range params {
+		parts = append(parts, param.Key+"="+param.Value)
+	}
+	return strings.Join(parts, " ")
+}
+
+// serveAuditLog responds with the audit entries matching the user, action, from, to (unix nanoseconds) and limit
+// query parameters, the most recent first.
+func (api *API) serveAuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if api.auditLog == nil {
+		http.Error(w, errNoAuditLog.Error(), http.StatusNotImplemented)
+		return
+	}
+
+	params := r.URL.Query()
+	q := audit.Query{User: params.Get("user"), Action: params.Get("action")}
+	var err error
+	for name, target := range map[string]*uint64{"from": &q.FromUnixNano, "to": &q.ToUnixNano} {
+		if value := params.Get(name); value != "" {
+			if *target, err = strconv.ParseUint(value, 10, 64); err != nil {
+				http.Error(w, fmt.Sprintf("invalid %s: %s", name, value), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if value := params.Get("limit"); value != "" {
+		if q.Limit, err = strconv.Atoi(value); err != nil || q.Limit < 0 {
+			http.Error(w, fmt.Sprintf("invalid limit: %s", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := api.auditLog.List(r.Context(), q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(auditv1.GetAuditLogResponse{Entries: entries})
+}
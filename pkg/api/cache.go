@@ -0,0 +1,106 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/cache"
+	"github.com/teletrace/teletrace/pkg/config"
+	"github.com/teletrace/teletrace/pkg/spanreader/acl"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	cacheModeNone                 = "none"
+	cacheModeStaleWhileRevalidate = "stale-while-revalidate"
+
+	// cacheStatusHeader indicates whether a cached response was fresh (HIT), stale (STALE) or loaded (MISS)
+	cacheStatusHeader = "X-Teletrace-Cache"
+)
+
+func newResponseCache(logger *zap.Logger, cfg config.Config) (*cache.StaleWhileRevalidateCache, error) {
+	switch cfg.APICacheMode {
+	case "", cacheModeNone:
+		return nil, nil
+	case cacheModeStaleWhileRevalidate:
+		return cache.NewStaleWhileRevalidateCache(
+			logger,
+			time.Duration(cfg.APICacheTTLSeconds)*time.Second,
+			time.Duration(cfg.APICacheMaxStalenessSeconds)*time.Second,
+			cfg.APICacheMaxEntries,
+		)
+	default:
+		return nil, fmt.Errorf("invalid api cache mode %s", cfg.APICacheMode)
+	}
+}
+
+// respondCached responds with the value returned by load, served through the response cache when enabled.
+// The cache key is built from the request path and the given request body.
+func (api *API) respondCached(c *gin.Context, req interface{}, load cache.Loader) {
+	if api.cache == nil {
+		res, err := load(c)
+		if err != nil {
+			respondWithError(spanReaderErrorStatusCode(err), err, c)
+			return
+		}
+		c.JSON(http.StatusOK, res)
+		return
+	}
+
+	key, err := cacheKey(c.Request.URL.Path, req)
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+	if role, ok := acl.RoleFromContext(c); ok {
+		// responses are restricted per role, and so are their cache entries and refreshes
+		key = role + ":" + key
+		load = withRole(load, role)
+	}
+
+	res, err := api.cache.Get(c, key, load)
+	if err != nil {
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return
+	}
+
+	c.Header(cacheStatusHeader, string(res.Status))
+	c.Header("Age", strconv.Itoa(int(res.Age.Seconds())))
+	c.JSON(http.StatusOK, res.Value)
+}
+
+func cacheKey(path string, req interface{}) (string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("could not build cache key: %w", err)
+	}
+	return path + ":" + string(body), nil
+}
+
+func withRole(load cache.Loader, role string) cache.Loader {
+	return func(ctx context.Context) (interface{}, error) {
+		return load(acl.WithRole(ctx, role))
+	}
+}
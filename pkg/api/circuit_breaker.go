@@ -0,0 +1,35 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/spanreader/circuitbreaker"
+)
+
+// registerCircuitBreaker fast-fails span reader calls while the storage backend keeps failing, if enabled.
+func (api *API) registerCircuitBreaker() {
+	if !api.config.StorageCircuitBreakerEnabled {
+		return
+	}
+	sr := circuitbreaker.NewSpanReader(api.logger, *api.spanReader, circuitbreaker.Config{
+		FailureThreshold: api.config.StorageCircuitBreakerFailureThreshold,
+		OpenDuration:     time.Duration(api.config.StorageCircuitBreakerOpenSeconds) * time.Second,
+	})
+	api.spanReader = &sr
+}
@@ -0,0 +1,178 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	encodingZstd = "zstd"
+	encodingGzip = "gzip"
+)
+
+// encoder compresses a response, and can be reset to compress another one.
+type encoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+	Flush() error
+}
+
+// encoders pool the encoders of each encoding, which are costly to allocate for every response.
+var encoders = map[string]*sync.Pool{
+	encodingZstd: {New: func() any {
+		// the options are valid, so the encoder is always created
+		e, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return e
+	}},
+	encodingGzip: {New: func() any {
+		return gzip.NewWriter(nil)
+	}},
+}
+
+// negotiateEncoding returns the preferred encoding accepted by an Accept-Encoding header, zstd over gzip,
+// or an empty string if neither is accepted.
+func negotiateEncoding(acceptEncoding string) string {
+	accepted := map[string]bool{}
+	for _, item := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			if weight, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64); err == nil && weight == 0 {
+				continue
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	for _, encoding := range []string{encodingZstd, encodingGzip} {
+		if accepted[encoding] {
+			return encoding
+		}
+	}
+	if accepted["*"] {
+		return encodingGzip
+	}
+	return ""
+}
+
+// compressionMiddleware compresses the responses of the clients accepting zstd or gzip, unless they're smaller than
+// API_COMPRESSION_MIN_SIZE_BYTES, which aren't worth the overhead.
+func (api *API) compressionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+		w := &compressWriter{ResponseWriter: c.Writer, encoding: encoding, minSize: api.config.APICompressionMinSizeBytes}
+		c.Writer = w
+		defer w.close()
+		c.Next()
+	}
+}
+
+// compressWriter buffers the start of a response until it reaches the minimum size, and then either compresses it
+// or, if the response ended before, writes it as is.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	minSize  int
+
+	buf     []byte
+	decided bool
+	encoder encoder
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < w.minSize {
+			return len(p), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends the buffered response, compressed, as the handler expects it to be streamed.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(len(w.buf) > 0)
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide writes the buffered response, compressed if compress is set and the handler didn't encode it already.
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	header := w.Header()
+	if compress && header.Get("Content-Encoding") == "" && bodyAllowed(w.Status()) {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		w.encoder = encoders[w.encoding].Get().(encoder)
+		w.encoder.Reset(w.ResponseWriter)
+	}
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.encoder != nil {
+		_, err := w.encoder.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// close ends the response, writing the small responses as is and returning the encoder to its pool.
+func (w *compressWriter) close() {
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	if w.encoder == nil {
+		return
+	}
+	_ = w.encoder.Close()
+	w.encoder.Reset(nil)
+	encoders[w.encoding].Put(w.encoder)
+	w.encoder = nil
+}
+
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified && status >= http.StatusOK
+}
@@ -0,0 +1,73 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// corsMiddleware answers the CORS preflight requests and sets the CORS headers of requests from the allowed origins,
+// so the UI can be hosted on a different domain than the API. A single "*" origin allows any origin.
+func (api *API) corsMiddleware(origins []string) gin.HandlerFunc {
+	cfg := cors.Config{
+		AllowMethods:     splitList(api.config.APICORSAllowedMethods),
+		AllowHeaders:     splitList(api.config.APICORSAllowedHeaders),
+		AllowCredentials: api.config.APICORSAllowCredentials,
+		MaxAge:           time.Duration(api.config.APICORSMaxAgeSeconds) * time.Second,
+		AllowWildcard:    true,
+	}
+	if len(origins) == 1 && origins[0] == "*" {
+		cfg.AllowAllOrigins = true
+	} else {
+		cfg.AllowOrigins = origins
+	}
+	if cfg.AllowAllOrigins && cfg.AllowCredentials {
+		// browsers reject credentialed responses allowing any origin
+		api.logger.Fatal("CORS credentials require explicit allowed origins")
+	}
+	if err := cfg.Validate(); err != nil {
+		api.logger.Fatal("Invalid CORS config", zap.Error(err))
+	}
+	return cors.New(cfg)
+}
+
+// securityHeadersMiddleware sets the standard security headers on every response, including the UI files.
+func (api *API) securityHeadersMiddleware() gin.HandlerFunc {
+	headers := map[string]string{
+		"X-Content-Type-Options": "nosniff",
+		"Referrer-Policy":        "strict-origin-when-cross-origin",
+	}
+	if api.config.APIFrameOptions != "" {
+		headers["X-Frame-Options"] = api.config.APIFrameOptions
+	}
+	if api.config.APIContentSecurityPolicy != "" {
+		headers["Content-Security-Policy"] = api.config.APIContentSecurityPolicy
+	}
+	if api.config.APIHSTSMaxAgeSeconds > 0 {
+		headers["Strict-Transport-Security"] = "max-age=" + strconv.Itoa(api.config.APIHSTSMaxAgeSeconds)
+	}
+	return func(c *gin.Context) {
+		for name, value := range headers {
+			c.Header(name, value)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,34 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"github.com/teletrace/teletrace/pkg/spanreader/dedup"
+
+	"go.uber.org/zap"
+)
+
+// registerReadDeduplication merges the copies of spans written more than once in search results, if enabled.
+func (api *API) registerReadDeduplication() {
+	if api.config.ReadDedupMode == "" {
+		return
+	}
+	sr, err := dedup.NewSpanReader(*api.spanReader, api.config.ReadDedupMode)
+	if err != nil {
+		api.logger.Fatal("Failed to create span deduplication", zap.Error(err))
+	}
+	api.spanReader = &sr
+}
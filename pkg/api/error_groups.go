@@ -0,0 +1,48 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"net/http"
+
+	"github.com/teletrace/teletrace/pkg/errorgroups"
+	errorgroupsmodel "github.com/teletrace/teletrace/pkg/model/errorgroups/v1"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerErrorAnalyzer creates the error analyzer on top of the (possibly restricted) span reader.
+func (api *API) registerErrorAnalyzer() {
+	api.errorAnalyzer = errorgroups.NewAnalyzer(api.logger, *api.spanReader, errorgroups.Config{
+		MaxSpans: api.config.ErrorGroupsMaxSpans,
+	})
+}
+
+func (api *API) analyzeErrors(c *gin.Context) {
+	var req errorgroupsmodel.AnalyzeRequest
+	isValidationError := api.validateRequestBody(&req, c)
+	if isValidationError {
+		return
+	}
+	handleTimeframe(&req.Timeframe)
+
+	res, err := api.errorAnalyzer.Analyze(c, req)
+	if err != nil {
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
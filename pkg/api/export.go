@@ -0,0 +1,81 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"net/http"
+
+	"github.com/teletrace/teletrace/pkg/arrowexport"
+	"github.com/teletrace/teletrace/pkg/audit"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// exportSearch streams the spans matching a search as an Arrow IPC stream, a record batch per page of results,
+// so they can be loaded into dataframe libraries for offline analysis. Exports stop at the configured maximum spans.
+func (api *API) exportSearch(c *gin.Context) {
+	var req spansquery.SearchRequest
+	isValidationError := api.validateRequestBody(&req, c)
+	if isValidationError {
+		return
+	}
+	handleTimeframe(&req.Timeframe)
+	req.Limit = api.searchLimit(req.Limit)
+	maxSpans := api.config.APIExportMaxSpans
+
+	var w *arrowexport.Writer
+	exported := 0
+	for {
+		res, err := (*api.spanReader).Search(c, req)
+		if err != nil {
+			if w == nil {
+				respondWithError(spanReaderErrorStatusCode(err), err, c)
+				return
+			}
+			// the response status was already sent, the client fails reading the unterminated stream
+			api.logger.Error("Failed to export search results", zap.Error(err))
+			return
+		}
+		spans := api.withEnumNames(req.Project(res.Spans))
+		if maxSpans > 0 && exported+len(spans) > maxSpans {
+			spans = spans[:maxSpans-exported]
+		}
+		if w == nil {
+			c.Header("Content-Disposition", `attachment; filename="spans.arrow"`)
+			c.Header("Content-Type", arrowexport.ContentType)
+			c.Status(http.StatusOK)
+			w = arrowexport.NewWriter(c.Writer)
+		}
+		if err := w.Write(spans); err != nil {
+			api.logger.Error("Failed to export search results", zap.Error(err))
+			return
+		}
+		c.Writer.Flush()
+		exported += len(spans)
+
+		if exported == maxSpans || len(res.Spans) == 0 || res.Metadata == nil || res.Metadata.NextToken == "" {
+			break
+		}
+		req.Metadata = &spansquery.Metadata{NextToken: res.Metadata.NextToken}
+	}
+	if err := w.Close(); err != nil {
+		api.logger.Error("Failed to export search results", zap.Error(err))
+		return
+	}
+	setAuditDetails(c, audit.Summarize(&req.Timeframe, req.SearchFilters), exported)
+}
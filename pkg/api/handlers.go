@@ -17,17 +17,50 @@
 package api
 
 import (
+	"context"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/teletrace/teletrace/pkg/audit"
+	"github.com/teletrace/teletrace/pkg/clockskew"
+	"github.com/teletrace/teletrace/pkg/loglinks"
 	"github.com/teletrace/teletrace/pkg/model"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
 	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
 	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
 	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/settings"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
+// maxTraceSearchSpans is the number of matching span IDs returned by a search within a trace
+const maxTraceSearchSpans = 10000
+
+// enumTagValueNames maps the tags holding OTLP enums to the functions naming their stored values as the enums,
+// so their values are returned as e.g. SPAN_KIND_SERVER rather than Server if APIOTLPEnumNames is set
+var enumTagValueNames = map[string]func(string) string{
+	"span.kind":        internalspan.SpanKindEnumName,
+	"span.status.code": internalspan.StatusCodeEnumName,
+}
+
+// withEnumNames returns spans with their kind and status code named as OTLP enums if APIOTLPEnumNames is set,
+// matching their tag values. spans aren't modified, as they may be cached.
+func (api *API) withEnumNames(spans []*internalspan.InternalSpan) []*internalspan.InternalSpan {
+	if !api.config.APIOTLPEnumNames {
+		return spans
+	}
+	named := make([]*internalspan.InternalSpan, len(spans))
+	for i, span := range spans {
+		named[i] = internalspan.WithEnumNames(span)
+	}
+	return named
+}
+
 func (api *API) getPing(c *gin.Context) {
 	c.String(http.StatusOK, "pong")
 }
@@ -38,19 +71,104 @@ func (api *API) search(c *gin.Context) {
 	if isValidationError {
 		return
 	}
+	if api.runSearch(c, req) {
+		api.recordQuery(c, req)
+	}
+}
+
+// runSearch resolves the timeframe and limit of a search request and responds with its results.
+// Returns whether the search succeeded.
+func (api *API) runSearch(c *gin.Context, req spansquery.SearchRequest) bool {
 	handleTimeframe(&req.Timeframe)
+	if req.Aggregations != nil {
+		return api.runAggregatedSearch(c, req)
+	}
+	req.Limit = api.searchLimit(req.Limit)
 
 	res, err := (*api.spanReader).Search(c, req)
 	if err != nil {
-		respondWithError(http.StatusInternalServerError, err, c)
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return false
+	}
+	// storage plugins which can't skip reading field groups return them, so they are dropped from the response
+	res.Spans = req.Project(res.Spans)
+	setAuditDetails(c, audit.Summarize(&req.Timeframe, req.SearchFilters), len(res.Spans))
+	if res.Partial != nil {
+		api.logger.Warn("Search results are partial",
+			zap.Int("droppedSpans", res.Partial.DroppedSpans), zap.Strings("reasons", res.Partial.Reasons))
+	}
+	if acceptsProtobuf(c) {
+		if res.Partial != nil {
+			c.Header(droppedSpansHeader, strconv.Itoa(res.Partial.DroppedSpans))
+		}
+		respondWithOTLP(c, res.Spans, res.Metadata)
+		return true
+	}
+	res.Spans = api.withEnumNames(res.Spans)
+	c.JSON(http.StatusOK, res)
+	return true
+}
+
+// runAggregatedSearch responds with the metrics of the spans matching a search with aggregations.
+// Returns whether the search succeeded.
+func (api *API) runAggregatedSearch(c *gin.Context, req spansquery.SearchRequest) bool {
+	res, err := (*api.spanReader).Aggregate(c, req)
+	if err != nil {
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return false
+	}
+	setAuditDetails(c, audit.Summarize(&req.Timeframe, req.SearchFilters), len(res.Aggregations.Groups))
+	c.JSON(http.StatusOK, res)
+	return true
+}
+
+func (api *API) searchEvents(c *gin.Context) {
+	var req eventsquery.SearchRequest
+	isValidationError := api.validateRequestBody(&req, c)
+	if isValidationError {
+		return
+	}
+	handleTimeframe(&req.Timeframe)
+
+	res, err := (*api.spanReader).SearchEvents(c, req)
+	if err != nil {
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
 		return
 	}
+	setAuditDetails(c, audit.Summarize(&req.Timeframe, req.SearchFilters), len(res.Events))
 	c.JSON(http.StatusOK, res)
 }
 
-func (api *API) getTraceById(c *gin.Context) {
-	traceId := c.Param("id")
-	sr := &spansquery.SearchRequest{
+// searchTraces responds with the traces having any span matching the request filters, each with all of its spans.
+func (api *API) searchTraces(c *gin.Context) {
+	var req spansquery.SearchTracesRequest
+	isValidationError := api.validateRequestBody(&req, c)
+	if isValidationError {
+		return
+	}
+	handleTimeframe(&req.Timeframe)
+
+	res, err := (*api.spanReader).SearchTraces(c, req)
+	if err != nil {
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return
+	}
+	setAuditDetails(c, audit.Summarize(&req.Timeframe, req.SearchFilters), len(res.Traces))
+	if api.config.APIOTLPEnumNames {
+		traces := make([]*spansquery.TraceResult, len(res.Traces))
+		for i, trace := range res.Traces {
+			named := *trace
+			named.Spans = api.withEnumNames(trace.Spans)
+			traces[i] = &named
+		}
+		res.Traces = traces
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// traceSearchRequest returns the search request of all the spans of a trace.
+func traceSearchRequest(traceId string) spansquery.SearchRequest {
+	return spansquery.SearchRequest{
 		Timeframe: model.Timeframe{
 			StartTime: 0,
 			EndTime:   uint64(time.Now().UnixNano()),
@@ -65,24 +183,105 @@ func (api *API) getTraceById(c *gin.Context) {
 			},
 		},
 	}
+}
 
-	res, err := (*api.spanReader).Search(c, *sr)
+// spanSearchRequest returns the search request of a span of a trace.
+func spanSearchRequest(traceId string, spanId string) spansquery.SearchRequest {
+	r := traceSearchRequest(traceId)
+	r.SearchFilters = append(r.SearchFilters, model.SearchFilter{
+		KeyValueFilter: &model.KeyValueFilter{
+			Key:      "span.spanId",
+			Operator: spansquery.OPERATOR_EQUALS,
+			Value:    spanId,
+		},
+	})
+	r.Metadata = &spansquery.Metadata{}
+	return r
+}
+
+func (api *API) getTraceById(c *gin.Context) {
+	traceId := c.Param("id")
+	res, err := (*api.spanReader).Search(c, traceSearchRequest(traceId))
 	if err != nil {
-		respondWithError(http.StatusInternalServerError, err, c)
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return
+	}
+
+	setAuditDetails(c, "id="+traceId, len(res.Spans))
+	if acceptsProtobuf(c) {
+		// clock skew adjustments, annotations and log links have no OTLP equivalent
+		respondWithOTLP(c, res.Spans, nil)
+		return
+	}
+	traceRes := spansquery.GetTraceResponse{SearchResponse: *res}
+	// rendered before the clock skew adjustment, as the logs are timed by the same clocks as the spans
+	traceRes.LogLinks = loglinks.Render(api.logLinks, res.Spans)
+	if api.config.APIClockSkewAdjustmentEnabled {
+		traceRes.ClockSkewAdjustments = clockskew.Adjust(res.Spans)
+	}
+	if api.annotations != nil {
+		annotations, err := api.annotations.List(c, traceId)
+		if err != nil {
+			// the trace is still useful without its annotations
+			api.logger.Warn("Failed to get trace annotations", zap.String("traceId", traceId), zap.Error(err))
+		}
+		traceRes.Annotations = annotations
+	}
+	traceRes.Spans = api.withEnumNames(traceRes.Spans)
+	c.JSON(http.StatusOK, traceRes)
+}
+
+// searchTrace responds with the IDs of the spans of a trace matching the request filters,
+// so huge traces can be searched without being fully loaded by the client.
+func (api *API) searchTrace(c *gin.Context) {
+	var req spansquery.SearchTraceRequest
+	isValidationError := api.validateRequestBody(&req, c)
+	if isValidationError {
 		return
 	}
 
+	sr := traceSearchRequest(c.Param("id"))
+	sr.SearchFilters = append(sr.SearchFilters, req.SearchFilters...)
+	sr.Sort = []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: true}}
+	res := spansquery.SearchTraceResponse{SpanIds: make([]string, 0)}
+	var token spansquery.ContinuationToken
+	for {
+		sr.Metadata = &spansquery.Metadata{NextToken: token}
+		page, err := (*api.spanReader).Search(c, sr)
+		if err != nil {
+			respondWithError(spanReaderErrorStatusCode(err), err, c)
+			return
+		}
+		for _, span := range page.Spans {
+			if len(res.SpanIds) == maxTraceSearchSpans {
+				res.Truncated = true
+				break
+			}
+			res.SpanIds = append(res.SpanIds, span.Span.SpanId)
+		}
+		if res.Truncated || len(page.Spans) == 0 || page.Metadata == nil || page.Metadata.NextToken == "" {
+			break
+		}
+		token = page.Metadata.NextToken
+	}
+	summary := "id=" + c.Param("id")
+	if filters := audit.Summarize(nil, req.SearchFilters); filters != "" {
+		summary += ", " + filters
+	}
+	setAuditDetails(c, summary, len(res.SpanIds))
 	c.JSON(http.StatusOK, res)
 }
 
 func (api *API) getAvailableTags(c *gin.Context) {
-	res, err := (*api.spanReader).GetAvailableTags(c, tagsquery.GetAvailableTagsRequest{})
+	limit, err := queryLimit(c)
 	if err != nil {
-		respondWithError(http.StatusInternalServerError, err, c)
+		respondWithError(http.StatusBadRequest, err, c)
 		return
 	}
-
-	c.JSON(http.StatusOK, res)
+	req := tagsquery.GetAvailableTagsRequest{Limit: api.tagsLimit(limit)}
+	api.respondCached(c, req, func(ctx context.Context) (interface{}, error) {
+		return (*api.spanReader).GetAvailableTags(ctx, req)
+	})
 }
 
 func (api *API) tagsValues(c *gin.Context) {
@@ -91,22 +290,40 @@ func (api *API) tagsValues(c *gin.Context) {
 	if isValidationError {
 		return
 	}
-	handleTimeframe(req.Timeframe)
 
 	tag := c.Param("tag")
+	req.Limit = api.tagValuesLimit(req.Limit)
+
+	api.respondCached(c, req, func(ctx context.Context) (interface{}, error) {
+		return api.loadTagValues(ctx, req, tag)
+	})
+}
 
-	res, err := (*api.spanReader).GetTagsValues(c, req, []string{tag})
+// loadTagValues returns the values of tag as responded by GET /tags/:tag, with enum values named if configured.
+func (api *API) loadTagValues(ctx context.Context, req tagsquery.TagValuesRequest, tag string) (*tagsquery.TagValuesResponse, error) {
+	req.Timeframe = resolveTimeframe(req.Timeframe)
+	res, err := (*api.spanReader).GetTagsValues(ctx, req, []string{tag})
 	if err != nil {
-		respondWithError(http.StatusInternalServerError, err, c)
-		return
+		return nil, err
 	}
 
 	tagValues := res[tag]
 	if tagValues == nil {
 		tagValues = &tagsquery.TagValuesResponse{}
 	}
-
-	c.JSON(http.StatusOK, tagValues)
+	if enumName, ok := enumTagValueNames[tag]; ok && api.config.APIOTLPEnumNames {
+		// the values may be cached by the storage plugin, so they are copied rather than named in place
+		named := *tagValues
+		named.Values = make([]tagsquery.TagValueInfo, len(tagValues.Values))
+		for i, v := range tagValues.Values {
+			if s, ok := v.Value.(string); ok {
+				v.Value = enumName(s)
+			}
+			named.Values[i] = v
+		}
+		tagValues = &named
+	}
+	return tagValues, nil
 }
 
 func (api *API) tagsStatistics(c *gin.Context) {
@@ -115,31 +332,75 @@ func (api *API) tagsStatistics(c *gin.Context) {
 	if isValidationError {
 		return
 	}
-	handleTimeframe(req.Timeframe)
 
 	tag := c.Param("tag")
 
-	res, err := (*api.spanReader).GetTagsStatistics(c, req, tag)
-	if err != nil {
-		respondWithError(http.StatusInternalServerError, err, c)
+	api.respondCached(c, req, func(ctx context.Context) (interface{}, error) {
+		r := req
+		r.Timeframe = resolveTimeframe(req.Timeframe)
+		return (*api.spanReader).GetTagsStatistics(ctx, r, tag)
+	})
+}
+
+// tagsStatisticsBatch responds with the statistics of several tags of the spans matching the same filters,
+// computed in a single query of the storage where the plugin supports it.
+func (api *API) tagsStatisticsBatch(c *gin.Context) {
+	var req tagsquery.TagsStatisticsBatchRequest
+	isValidationError := api.validateRequestBody(&req, c)
+	if isValidationError {
 		return
 	}
 
-	c.JSON(http.StatusOK, res)
+	api.respondCached(c, req, func(ctx context.Context) (interface{}, error) {
+		r := req.TagStatisticsRequest
+		r.Timeframe = resolveTimeframe(req.Timeframe)
+		res, err := (*api.spanReader).GetTagsStatisticsBatch(ctx, r, req.Tags)
+		if err != nil {
+			return nil, err
+		}
+		return &tagsquery.TagsStatisticsBatchResponse{Tags: res}, nil
+	})
 }
 
 func handleTimeframe(t *model.Timeframe) {
 	if t != nil {
+		now := time.Now()
+		// time expressions were validated with the request, so they resolve
+		_ = t.Resolve(now)
 		if t.EndTime == 0 {
-			t.EndTime = uint64(time.Now().UnixNano())
+			t.EndTime = uint64(now.UnixNano())
 		}
 	}
 }
 
+// resolveTimeframe returns a copy of t with its time expressions and an open end time resolved to now,
+// so cached requests for "until now" are re-evaluated on every load.
+func resolveTimeframe(t *model.Timeframe) *model.Timeframe {
+	if t == nil {
+		return nil
+	}
+	timeframe := *t
+	handleTimeframe(&timeframe)
+	return &timeframe
+}
+
 func (api *API) getSystemInfo(c *gin.Context) {
+	if api.metadataStore != nil {
+		systemId, err := settings.SystemId.Get(c, api.metadataStore)
+		if err != nil {
+			respondWithError(http.StatusInternalServerError, err, c)
+			return
+		}
+		if systemId != "" {
+			c.JSON(http.StatusOK, metadata.GetSystemInfoResponse{SystemId: systemId})
+			return
+		}
+	}
+
+	// installations whose system id predates the settings store keep it in the span storage
 	res, err := (*api.spanReader).GetSystemId(c, metadata.GetSystemIdRequest{})
 	if err != nil {
-		respondWithError(http.StatusInternalServerError, err, c)
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
 		return
 	}
 
@@ -0,0 +1,58 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	healthPath    = "/healthz"
+	readinessPath = "/readyz"
+
+	// readinessTimeout bounds the storage backend ping, so a hanging backend fails the probe instead of blocking it
+	readinessTimeout = 5 * time.Second
+
+	statusOk          = "ok"
+	statusUnavailable = "unavailable"
+)
+
+type healthResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// getHealth reports that the API is alive, regardless of the storage backend.
+func (api *API) getHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, healthResponse{Status: statusOk})
+}
+
+// getReadiness pings the storage backend, so instances with a broken backend stop receiving traffic.
+func (api *API) getReadiness(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, readinessTimeout)
+	defer cancel()
+	if err := (*api.spanReader).Ping(ctx); err != nil {
+		api.logger.Warn("Storage backend is not ready", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, healthResponse{Status: statusUnavailable, Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, healthResponse{Status: statusOk})
+}
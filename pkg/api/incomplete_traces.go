@@ -0,0 +1,52 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/incompletetraces"
+	incompletetracesmodel "github.com/teletrace/teletrace/pkg/model/incompletetraces/v1"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerIncompleteTracesDetector creates the incomplete traces detector on top of the (possibly restricted) span reader.
+func (api *API) registerIncompleteTracesDetector() {
+	api.incompleteTracesDetector = incompletetraces.NewDetector(api.logger, *api.spanReader, incompletetraces.Config{
+		MaxSpans:    api.config.IncompleteTracesMaxSpans,
+		MaxTraces:   api.config.IncompleteTracesMaxTraces,
+		GracePeriod: time.Duration(api.config.IncompleteTracesGracePeriodSeconds) * time.Second,
+	})
+}
+
+func (api *API) detectIncompleteTraces(c *gin.Context) {
+	var req incompletetracesmodel.DetectRequest
+	isValidationError := api.validateRequestBody(&req, c)
+	if isValidationError {
+		return
+	}
+	handleTimeframe(&req.Timeframe)
+
+	res, err := api.incompleteTracesDetector.Detect(c, req)
+	if err != nil {
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
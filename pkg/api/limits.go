@@ -0,0 +1,62 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resultLimit returns the number of results requested from the span reader: the requested limit, or defaultLimit
+// when the request has none, capped at maxLimit. Limits aren't trusted from clients, as the storage plugins apply
+// them inside the generated queries.
+func resultLimit(requested int, defaultLimit int, maxLimit int) int {
+	limit := requested
+	if limit == 0 {
+		limit = defaultLimit
+	}
+	if maxLimit > 0 && limit > maxLimit {
+		limit = maxLimit
+	}
+	return limit
+}
+
+// queryLimit returns the limit query parameter of requests without a body, 0 when missing.
+func queryLimit(c *gin.Context) (int, error) {
+	value, ok := c.GetQuery("limit")
+	if !ok {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("invalid limit %q, expected a non-negative integer", value)
+	}
+	return limit, nil
+}
+
+func (api *API) searchLimit(requested int) int {
+	return resultLimit(requested, api.config.APISearchDefaultLimit, api.config.APISearchMaxLimit)
+}
+
+func (api *API) tagsLimit(requested int) int {
+	return resultLimit(requested, api.config.APITagsDefaultLimit, api.config.APITagsMaxLimit)
+}
+
+func (api *API) tagValuesLimit(requested int) int {
+	return resultLimit(requested, api.config.APITagValuesDefaultLimit, api.config.APITagValuesMaxLimit)
+}
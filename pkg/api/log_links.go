@@ -0,0 +1,35 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"github.com/teletrace/teletrace/pkg/loglinks"
+
+	"go.uber.org/zap"
+)
+
+// registerLogLinks parses the templates of the links from the spans of a trace to their logs, if configured.
+func (api *API) registerLogLinks() {
+	if api.config.APILogLinkTemplates == "" {
+		return
+	}
+	templates, err := loglinks.ParseTemplates(api.config.APILogLinkTemplates)
+	if err != nil {
+		api.logger.Fatal("Failed to parse log link templates", zap.Error(err))
+	}
+	api.logLinks = templates
+}
@@ -0,0 +1,52 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/metriccorrelation"
+	metriccorrelationmodel "github.com/teletrace/teletrace/pkg/model/metriccorrelation/v1"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerMetricCorrelator creates the metric to trace correlator on top of the (possibly restricted) span reader.
+func (api *API) registerMetricCorrelator() {
+	api.metricCorrelator = metriccorrelation.NewCorrelator(api.logger, *api.spanReader, metriccorrelation.Config{
+		PrometheusURL: api.config.MetricCorrelationPrometheusURL,
+		Timeout:       time.Duration(api.config.MetricCorrelationTimeoutSeconds) * time.Second,
+		MaxSpans:      api.config.MetricCorrelationMaxSpans,
+	})
+}
+
+func (api *API) correlateMetric(c *gin.Context) {
+	var req metriccorrelationmodel.CorrelateRequest
+	isValidationError := api.validateRequestBody(&req, c)
+	if isValidationError {
+		return
+	}
+	handleTimeframe(&req.Timeframe)
+
+	res, err := api.metricCorrelator.Correlate(c, req)
+	if err != nil {
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,126 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/spanreader/instrumented"
+
+	ocprometheus "contrib.go.opencensus.io/exporter/prometheus"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+const metricsPath = "/metrics"
+
+var (
+	routeKey      = tag.MustNewKey("route")
+	methodKey     = tag.MustNewKey("method")
+	statusCodeKey = tag.MustNewKey("status_code")
+	tenantKey     = tag.MustNewKey("tenant")
+
+	requestLatency = stats.Float64(
+		"teletrace_api_request_latency",
+		"Latency of API requests",
+		stats.UnitMilliseconds,
+	)
+	rateLimitedRequests = stats.Int64(
+		"teletrace_api_rate_limited_requests",
+		"Number of API requests rejected by the rate limit",
+		stats.UnitDimensionless,
+	)
+
+	registerViewsOnce sync.Once
+	errRegisterViews  error
+)
+
+// registerMetrics records the latency of API requests and storage queries, and exposes them on /metrics of the admin port
+// together with the other OpenCensus metrics of the process, e.g. the exporter metrics when running all-in-one.
+// They aren't served on the API port, which would expose them publicly without authentication.
+func (api *API) registerMetrics() {
+	if !api.config.APIMetricsEnabled {
+		return
+	}
+	registerViewsOnce.Do(func() {
+		errRegisterViews = view.Register(
+			&view.View{
+				Name:        requestLatency.Name(),
+				Description: requestLatency.Description(),
+				Measure:     requestLatency,
+				TagKeys:     []tag.Key{routeKey, methodKey, statusCodeKey},
+				Aggregation: view.Distribution(5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000),
+			},
+			&view.View{
+				Name:        rateLimitedRequests.Name(),
+				Description: rateLimitedRequests.Description(),
+				Measure:     rateLimitedRequests,
+				TagKeys:     []tag.Key{tenantKey},
+				Aggregation: view.Count(),
+			},
+		)
+	})
+	if errRegisterViews != nil {
+		api.logger.Fatal("Failed to register API metrics", zap.Error(errRegisterViews))
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	exporter, err := ocprometheus.NewExporter(ocprometheus.Options{
+		Registry: registry,
+		OnError:  func(err error) { api.logger.Warn("Failed to export metrics", zap.Error(err)) },
+	})
+	if err != nil {
+		api.logger.Fatal("Failed to create metrics exporter", zap.Error(err))
+	}
+	api.handleAdmin(metricsPath, exporter)
+
+	sr, err := instrumented.NewSpanReader(*api.spanReader)
+	if err != nil {
+		api.logger.Fatal("Failed to instrument span reader", zap.Error(err))
+	}
+	api.spanReader = &sr
+}
+
+// metricsMiddleware records the latency of API requests by route.
+func (api *API) metricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if !strings.HasPrefix(route, apiPrefix) {
+			// static files and unknown routes
+			return
+		}
+		_ = stats.RecordWithTags(c.Request.Context(),
+			[]tag.Mutator{
+				tag.Upsert(routeKey, route),
+				tag.Upsert(methodKey, c.Request.Method),
+				tag.Upsert(statusCodeKey, strconv.Itoa(c.Writer.Status())),
+			},
+			requestLatency.M(float64(time.Since(start))/float64(time.Millisecond)),
+		)
+	}
+}
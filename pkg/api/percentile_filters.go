@@ -0,0 +1,29 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/spanreader/percentile"
+)
+
+// registerPercentileFilters resolves percentile filters into range filters on the thresholds computed from recent spans.
+func (api *API) registerPercentileFilters() {
+	sr := percentile.NewSpanReader(*api.spanReader, time.Duration(api.config.APIPercentileThresholdTTLSeconds)*time.Second)
+	api.spanReader = &sr
+}
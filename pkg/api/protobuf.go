@@ -0,0 +1,60 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/modeltranslator"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+const (
+	// protobufContentType is the content type of responses encoded as an OTLP TracesData protobuf message
+	protobufContentType = "application/x-protobuf"
+	// nextTokenHeader holds the continuation token of protobuf search responses, which have no metadata field
+	nextTokenHeader = "X-Teletrace-Next-Token"
+	// droppedSpansHeader holds the number of matching spans dropped from protobuf search responses
+	droppedSpansHeader = "X-Teletrace-Dropped-Spans"
+)
+
+// acceptsProtobuf returns whether the client prefers protobuf responses over JSON.
+func acceptsProtobuf(c *gin.Context) bool {
+	return c.NegotiateFormat(gin.MIMEJSON, protobufContentType) == protobufContentType
+}
+
+// respondWithOTLP responds with spans encoded as an OTLP TracesData protobuf message.
+func respondWithOTLP(c *gin.Context, spans []*internalspan.InternalSpan, metadata *spansquery.Metadata) {
+	traces, err := modeltranslator.TranslateInternalModelToOTLP(spans)
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, fmt.Errorf("could not encode spans as OTLP: %w", err), c)
+		return
+	}
+	body, err := (&ptrace.ProtoMarshaler{}).MarshalTraces(traces)
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, fmt.Errorf("could not encode spans as OTLP: %w", err), c)
+		return
+	}
+	if metadata != nil && metadata.NextToken != "" {
+		c.Header(nextTokenHeader, string(metadata.NextToken))
+	}
+	c.Data(http.StatusOK, protobufContentType, body)
+}
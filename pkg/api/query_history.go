@@ -0,0 +1,97 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	queryhistoryv1 "github.com/teletrace/teletrace/pkg/model/queryhistory/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+var (
+	errNoQueryHistory = errors.New("query history is disabled")
+	errNoUser         = errors.New("the request is not authenticated as a user")
+)
+
+// queryHistoryUser returns the user whose query history a request works on,
+// or responds with an error if the query history isn't available to it.
+func (api *API) queryHistoryUser(c *gin.Context) (string, bool) {
+	if !api.requireMetadataStore(c) {
+		return "", false
+	}
+	if api.queryHistory == nil {
+		respondWithError(http.StatusNotImplemented, errNoQueryHistory, c)
+		return "", false
+	}
+	user := c.GetHeader(api.config.APIUserHeader)
+	if user == "" {
+		respondWithError(http.StatusUnauthorized, errNoUser, c)
+		return "", false
+	}
+	return user, true
+}
+
+// recordQuery adds a search to the query history of the requesting user, if any.
+// The search is recorded as sent, so relative timeframes are re-evaluated when it is re-run.
+func (api *API) recordQuery(c *gin.Context, req spansquery.SearchRequest) {
+	user := c.GetHeader(api.config.APIUserHeader)
+	if api.queryHistory == nil || user == "" {
+		return
+	}
+	if err := api.queryHistory.Record(c, user, req); err != nil {
+		// the search already succeeded, only its history entry is lost
+		api.logger.Warn("Failed to record query history", zap.String("user", user), zap.Error(err))
+	}
+}
+
+func (api *API) getQueryHistory(c *gin.Context) {
+	user, ok := api.queryHistoryUser(c)
+	if !ok {
+		return
+	}
+	entries, err := api.queryHistory.List(c, user)
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+	c.JSON(http.StatusOK, queryhistoryv1.GetQueryHistoryResponse{Entries: entries})
+}
+
+// rerunQuery runs a search from the query history of the requesting user again.
+func (api *API) rerunQuery(c *gin.Context) {
+	user, ok := api.queryHistoryUser(c)
+	if !ok {
+		return
+	}
+	entry, err := api.queryHistory.Get(c, user, c.Param("id"))
+	if errors.Is(err, metadatastore.ErrNotFound) {
+		respondWithError(http.StatusNotFound, err, c)
+		return
+	}
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+	if api.runSearch(c, entry.Request) {
+		api.recordQuery(c, entry.Request)
+	}
+}
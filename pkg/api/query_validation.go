@@ -0,0 +1,45 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"net/http"
+
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/queryvalidation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validateSearch validates a search request against the available tags without running it, responding with
+// its problems rather than failing, so they can be shown while the query is being built.
+func (api *API) validateSearch(c *gin.Context) {
+	var req spansquery.SearchRequest
+	if err := c.BindJSON(&req); err != nil {
+		respondWithError(http.StatusBadRequest, err, c)
+		return
+	}
+
+	tags, err := (*api.spanReader).GetAvailableTags(c, tagsquery.GetAvailableTagsRequest{})
+	if err != nil {
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return
+	}
+
+	errs := queryvalidation.Validate(req, tags.Tags)
+	c.JSON(http.StatusOK, spansquery.ValidateSearchResponse{Valid: len(errs) == 0, Errors: errs})
+}
@@ -0,0 +1,97 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/teletrace/teletrace/pkg/config"
+	"github.com/teletrace/teletrace/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// defaultTenant tags the rate limited requests of keys without an override, to keep the metric's cardinality bounded
+const defaultTenant = "default"
+
+var errRateLimited = errors.New("rate limit exceeded, retry later")
+
+// registerRateLimit limits the query requests per second of each API key or client. The limiter is registered even
+// without limits, so limits set by reloading the config file apply without a restart.
+func (api *API) registerRateLimit() {
+	defaultLimit, overrides, err := rateLimits(api.config)
+	if err != nil {
+		api.logger.Fatal("Failed to parse API rate limit overrides", zap.Error(err))
+	}
+	api.rateLimiter = ratelimit.NewLimiter(defaultLimit, overrides)
+}
+
+// reloadRateLimit applies the rate limits of cfg, keeping the current limits if its overrides are invalid.
+func (api *API) reloadRateLimit(cfg config.Config) {
+	defaultLimit, overrides, err := rateLimits(cfg)
+	if err != nil {
+		api.logger.Error("Failed to reload API rate limit overrides", zap.Error(err))
+		return
+	}
+	api.rateLimiter.SetLimits(defaultLimit, overrides)
+}
+
+// rateLimits returns the default limit and the limits of specific keys of cfg.
+func rateLimits(cfg config.Config) (ratelimit.Limit, map[string]ratelimit.Limit, error) {
+	overrides, err := ratelimit.ParseOverrides(cfg.APIRateLimitOverrides)
+	if err != nil {
+		return ratelimit.Limit{}, nil, err
+	}
+	return ratelimit.Limit{Rate: cfg.APIRateLimitRequestsPerSecond, Burst: cfg.APIRateLimitBurst}, overrides, nil
+}
+
+// rateLimitKey returns the API key of the request, or the client IP if it has none.
+func (api *API) rateLimitKey(c *gin.Context) string {
+	if key := c.GetHeader(api.config.APIRateLimitKeyHeader); key != "" {
+		return key
+	}
+	return c.ClientIP()
+}
+
+// rateLimitMiddleware responds with 429 and a Retry-After header to query requests exceeding the rate limit of their key.
+func (api *API) rateLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := api.rateLimitKey(c)
+		allowed, wait := api.rateLimiter.Allow(key, 1)
+		if allowed {
+			c.Next()
+			return
+		}
+
+		tenant := defaultTenant
+		if api.rateLimiter.Overridden(key) {
+			tenant = key
+		}
+		_ = stats.RecordWithTags(c.Request.Context(),
+			[]tag.Mutator{tag.Upsert(tenantKey, tenant)},
+			rateLimitedRequests.M(1),
+		)
+		c.Header("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(wait.Seconds())))))
+		respondWithError(http.StatusTooManyRequests, errRateLimited, c)
+		c.Abort()
+	}
+}
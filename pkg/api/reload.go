@@ -0,0 +1,37 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"github.com/teletrace/teletrace/pkg/config"
+	"github.com/teletrace/teletrace/pkg/logs"
+	"github.com/teletrace/teletrace/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+// Reload applies the reloadable settings of cfg without restarting the API, see config.Watch.
+func (api *API) Reload(cfg config.Config) {
+	if err := logs.SetLevel(cfg); err != nil {
+		api.logger.Error("Failed to reload log level", zap.Error(err))
+	}
+	if api.tracer != nil {
+		if err := tracing.SetSampleRatio(cfg); err != nil {
+			api.logger.Error("Failed to reload self tracing sample ratio", zap.Error(err))
+		}
+	}
+	api.reloadRateLimit(cfg)
+}
@@ -0,0 +1,40 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/spanreader/resultcache"
+
+	"go.uber.org/zap"
+)
+
+// registerSearchCache caches the results of searches over completed time ranges, if enabled.
+func (api *API) registerSearchCache() {
+	if !api.config.APISearchCacheEnabled {
+		return
+	}
+	sr, err := resultcache.NewSpanReader(*api.spanReader, resultcache.Config{
+		TTL:          time.Duration(api.config.APISearchCacheTTLSeconds) * time.Second,
+		MaxEntries:   api.config.APISearchCacheMaxEntries,
+		SettlePeriod: time.Duration(api.config.APISearchCacheSettleSeconds) * time.Second,
+	})
+	if err != nil {
+		api.logger.Fatal("Failed to create search result cache", zap.Error(err))
+	}
+	api.spanReader = &sr
+}
@@ -0,0 +1,35 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"github.com/teletrace/teletrace/pkg/spanreader/servicealias"
+
+	"go.uber.org/zap"
+)
+
+// registerServiceAliases queries and returns the aliases of services as the services themselves, if configured.
+func (api *API) registerServiceAliases() {
+	if api.config.ServiceAliases == "" {
+		return
+	}
+	aliases, err := servicealias.ParseAliases(api.config.ServiceAliases)
+	if err != nil {
+		api.logger.Fatal("Failed to parse service aliases", zap.Error(err))
+	}
+	sr := servicealias.NewSpanReader(*api.spanReader, aliases)
+	api.spanReader = &sr
+}
@@ -0,0 +1,129 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/alerts"
+	"github.com/teletrace/teletrace/pkg/annotations"
+	"github.com/teletrace/teletrace/pkg/audit"
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	"github.com/teletrace/teletrace/pkg/queryhistory"
+	"github.com/teletrace/teletrace/pkg/settings"
+	"github.com/teletrace/teletrace/pkg/slos"
+	"github.com/teletrace/teletrace/pkg/snapshots"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+var errNoMetadataStore = errors.New("no metadata store is configured")
+
+// SetMetadataStore sets the store persisting the settings, annotations, snapshots, query history, audit log, alert rules, SLOs and other non-span data served by the API.
+// Routes depending on it respond with 501 Not Implemented until it is set.
+func (api *API) SetMetadataStore(store metadatastore.MetadataStore) {
+	api.metadataStore = store
+	api.annotations = annotations.NewStore(store)
+	api.snapshots = snapshots.NewStore(store)
+	api.slos = slos.NewStore(store)
+	api.sloCalculator = slos.NewCalculator(*api.spanReader)
+	api.alertRules = alerts.NewStore(store)
+	if api.config.AlertsEnabled {
+		interval := time.Duration(api.config.AlertsEvaluationIntervalSeconds) * time.Second
+		if interval <= 0 {
+			api.logger.Fatal("Alert rules evaluation interval must be positive", zap.Duration("interval", interval))
+		}
+		api.alertEvaluator = alerts.NewEvaluator(api.logger, *api.spanReader, api.alertRules, interval)
+		api.alertNotifier = alerts.NewNotifier(api.logger, alerts.NotifierConfig{
+			UIBaseURL:   api.config.AlertsUIBaseURL,
+			MaxAttempts: api.config.AlertsNotificationMaxAttempts,
+			Timeout:     time.Duration(api.config.AlertsNotificationTimeoutSeconds) * time.Second,
+		})
+		api.alertEvaluator.SetNotifier(api.alertNotifier)
+	}
+	if api.config.QueryHistorySize > 0 {
+		api.queryHistory = queryhistory.NewHistory(store, api.config.QueryHistorySize)
+	}
+	if api.config.AuditLogEnabled {
+		api.auditLog = audit.NewLog(store)
+	}
+}
+
+// requireMetadataStore responds with an error if no metadata store is set.
+func (api *API) requireMetadataStore(c *gin.Context) bool {
+	if api.metadataStore == nil {
+		respondWithError(http.StatusNotImplemented, errNoMetadataStore, c)
+		return false
+	}
+	return true
+}
+
+func (api *API) listSettings(c *gin.Context) {
+	if !api.requireMetadataStore(c) {
+		return
+	}
+	values, err := settings.List(c, api.metadataStore)
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+	c.JSON(http.StatusOK, values)
+}
+
+func (api *API) getSetting(c *gin.Context) {
+	if !api.requireMetadataStore(c) {
+		return
+	}
+	value, err := settings.GetJSON(c, api.metadataStore, c.Param("key"))
+	if errors.Is(err, metadatastore.ErrNotFound) {
+		respondWithError(http.StatusNotFound, err, c)
+		return
+	}
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", value)
+}
+
+func (api *API) setSetting(c *gin.Context) {
+	if !api.requireMetadataStore(c) {
+		return
+	}
+	key := c.Param("key")
+	value, err := c.GetRawData()
+	if err != nil {
+		respondWithError(http.StatusBadRequest, err, c)
+		return
+	}
+	if err := settings.Validate(key, value); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, settings.ErrReadOnly) {
+			status = http.StatusForbidden
+		}
+		respondWithError(status, err, c)
+		return
+	}
+	record := metadatastore.Record{Namespace: settings.Namespace, Key: key, Value: value}
+	if err := api.metadataStore.Put(c, record); err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
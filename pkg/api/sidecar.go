@@ -0,0 +1,109 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/teletrace/teletrace/blobstore"
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// registerSidecarStore creates the store holding the full values of the span attributes truncated by the exporters.
+func (api *API) registerSidecarStore() {
+	cfg := blobstore.Config{
+		Type:       api.config.SidecarStoreType,
+		Directory:  api.config.SidecarDirectory,
+		S3Bucket:   api.config.SidecarS3Bucket,
+		S3Region:   api.config.SidecarS3Region,
+		S3Prefix:   api.config.SidecarS3Prefix,
+		S3Endpoint: api.config.SidecarS3Endpoint,
+	}
+	if !cfg.Enabled() {
+		return
+	}
+	store, err := blobstore.NewStore(cfg)
+	if err != nil {
+		api.logger.Fatal("Failed to create sidecar store", zap.Error(err))
+	}
+	api.sidecarStore = store
+}
+
+// getSpanAttributeValue responds with the full value of a span attribute,
+// fetched from the sidecar store if the stored value was truncated.
+func (api *API) getSpanAttributeValue(c *gin.Context) {
+	traceId, spanId, key := c.Param("id"), c.Param("spanId"), c.Param("key")
+	res, err := (*api.spanReader).Search(c, spanSearchRequest(traceId, spanId))
+	if err != nil {
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return
+	}
+	if len(res.Spans) == 0 {
+		respondWithError(http.StatusNotFound, fmt.Errorf("span %s of trace %s not found", spanId, traceId), c)
+		return
+	}
+	span := res.Spans[0].Span
+	attributes := span.Attributes
+
+	blobKey, truncated := attributes[blobstore.ReferenceAttributePrefix+key].(string)
+	if !truncated {
+		value, ok := attributes[key]
+		if !ok {
+			respondWithError(http.StatusNotFound, fmt.Errorf("attribute %q not found", key), c)
+			return
+		}
+		c.Data(http.StatusOK, "application/octet-stream", attributeValueBytes(value, span.BinaryAttributes[key]))
+		return
+	}
+	if api.sidecarStore == nil {
+		respondWithError(http.StatusNotImplemented, fmt.Errorf("attribute %q is truncated and no sidecar store is configured", key), c)
+		return
+	}
+	if err := blobstore.ValidateKey(blobKey); err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+
+	value, err := api.sidecarStore.Get(c, blobKey)
+	if errors.Is(err, blobstore.ErrNotFound) {
+		respondWithError(http.StatusNotFound, fmt.Errorf("full value of attribute %q not found", key), c)
+		return
+	}
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+	c.Data(http.StatusOK, "application/octet-stream", value)
+}
+
+// attributeValueBytes returns the raw bytes of a stored attribute value, decoding binary values by the encoding of
+// the attribute (empty if it isn't binary).
+func attributeValueBytes(value any, encoding string) []byte {
+	if s, ok := value.(string); ok {
+		if encoding != "" {
+			if b, err := internalspan.DecodeBinaryValue(s, encoding); err == nil {
+				return b
+			}
+		}
+		return []byte(s)
+	}
+	return []byte(fmt.Sprint(value))
+}
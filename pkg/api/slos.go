@@ -0,0 +1,159 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	slosv1 "github.com/teletrace/teletrace/pkg/model/slos/v1"
+	"github.com/teletrace/teletrace/pkg/slos"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (api *API) getSLOs(c *gin.Context) {
+	if !api.requireMetadataStore(c) {
+		return
+	}
+	all, err := api.slos.List(c)
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+	role := requestRole(c)
+	res := slosv1.GetSLOsResponse{SLOs: []slosv1.SLO{}}
+	for _, slo := range all {
+		if slo.Role == role {
+			res.SLOs = append(res.SLOs, slo)
+		}
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (api *API) createSLO(c *gin.Context) {
+	if !api.requireMetadataStore(c) {
+		return
+	}
+	var req slosv1.CreateSLORequest
+	if isValidationError := api.validateRequestBody(&req, c); isValidationError {
+		return
+	}
+	slo, err := api.slos.Create(c, req, requestRole(c))
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+	c.JSON(http.StatusCreated, slo)
+}
+
+// getSLO responds with an SLO, its error budget and its burn rates within the default windows.
+func (api *API) getSLO(c *gin.Context) {
+	if !api.requireMetadataStore(c) {
+		return
+	}
+	slo, ok := api.findSLO(c)
+	if !ok {
+		return
+	}
+	budget, err := api.sloCalculator.ErrorBudget(c, *slo)
+	if err != nil {
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return
+	}
+	burnRates, err := api.sloCalculator.BurnRates(c, *slo, burnRateWindows(slos.DefaultBurnRateWindows, *slo))
+	if err != nil {
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return
+	}
+	c.JSON(http.StatusOK, slosv1.GetSLOResponse{SLO: *slo, ErrorBudget: *budget, BurnRates: burnRates})
+}
+
+// getSLOBurnRates responds with the burn rates of an SLO within the windows of the window query parameters,
+// e.g. ?window=5m&window=1h, or within the default windows.
+func (api *API) getSLOBurnRates(c *gin.Context) {
+	if !api.requireMetadataStore(c) {
+		return
+	}
+	windows := slos.DefaultBurnRateWindows
+	if values := c.QueryArray("window"); len(values) > 0 {
+		windows = nil
+		for _, value := range values {
+			window, err := time.ParseDuration(value)
+			if err != nil || window <= 0 {
+				respondWithError(http.StatusBadRequest, fmt.Errorf("window must be a positive duration, e.g. 1h: %q", value), c)
+				return
+			}
+			windows = append(windows, window)
+		}
+	}
+	slo, ok := api.findSLO(c)
+	if !ok {
+		return
+	}
+	burnRates, err := api.sloCalculator.BurnRates(c, *slo, burnRateWindows(windows, *slo))
+	if err != nil {
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return
+	}
+	c.JSON(http.StatusOK, slosv1.GetBurnRatesResponse{BurnRates: burnRates})
+}
+
+func (api *API) deleteSLO(c *gin.Context) {
+	if !api.requireMetadataStore(c) {
+		return
+	}
+	slo, ok := api.findSLO(c)
+	if !ok {
+		return
+	}
+	if err := api.slos.Delete(c, slo.Id); err != nil && !errors.Is(err, metadatastore.ErrNotFound) {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// findSLO returns the SLO of the request path, or responds with 404 if it doesn't exist
+// or was created by another access control role.
+func (api *API) findSLO(c *gin.Context) (*slosv1.SLO, bool) {
+	id := c.Param("id")
+	slo, err := api.slos.Get(c, id)
+	if errors.Is(err, metadatastore.ErrNotFound) || (err == nil && slo.Role != requestRole(c)) {
+		respondWithError(http.StatusNotFound, fmt.Errorf("SLO %s not found", id), c)
+		return nil, false
+	}
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return nil, false
+	}
+	return slo, true
+}
+
+// burnRateWindows returns the windows which fit in the SLO window, as longer windows would query spans the SLO ignores.
+func burnRateWindows(windows []time.Duration, slo slosv1.SLO) []time.Duration {
+	sloWindow := time.Duration(slo.WindowDays) * 24 * time.Hour
+	var result []time.Duration
+	for _, window := range windows {
+		if window <= sloWindow {
+			result = append(result, window)
+		}
+	}
+	return result
+}
@@ -0,0 +1,92 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	snapshotsv1 "github.com/teletrace/teletrace/pkg/model/snapshots/v1"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createSnapshot snapshots all the spans of a trace under a token, so it can be shared after its retention.
+func (api *API) createSnapshot(c *gin.Context) {
+	if !api.requireMetadataStore(c) {
+		return
+	}
+	var req snapshotsv1.CreateSnapshotRequest
+	if isValidationError := api.validateRequestBody(&req, c); isValidationError {
+		return
+	}
+
+	traceId := c.Param("id")
+	res, err := (*api.spanReader).Search(c, traceSearchRequest(traceId))
+	if err != nil {
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return
+	}
+	if len(res.Spans) == 0 {
+		respondWithError(http.StatusNotFound, fmt.Errorf("trace %s not found", traceId), c)
+		return
+	}
+
+	snapshot, err := api.snapshots.Create(c, traceId, res.Spans, time.Duration(req.ExpiresInSeconds)*time.Second)
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+	c.JSON(http.StatusCreated, snapshotsv1.CreateSnapshotResponse{
+		Token:             snapshot.Token,
+		ExpiresAtUnixNano: snapshot.ExpiresAtUnixNano,
+	})
+}
+
+func (api *API) getSnapshot(c *gin.Context) {
+	if !api.requireMetadataStore(c) {
+		return
+	}
+	snapshot, err := api.snapshots.Get(c, c.Param("token"))
+	if errors.Is(err, metadatastore.ErrNotFound) {
+		respondWithError(http.StatusNotFound, err, c)
+		return
+	}
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+	c.JSON(http.StatusOK, snapshot)
+}
+
+func (api *API) deleteSnapshot(c *gin.Context) {
+	if !api.requireMetadataStore(c) {
+		return
+	}
+	err := api.snapshots.Delete(c, c.Param("token"))
+	if errors.Is(err, metadatastore.ErrNotFound) {
+		respondWithError(http.StatusNotFound, err, c)
+		return
+	}
+	if err != nil {
+		respondWithError(http.StatusInternalServerError, err, c)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,133 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	spanlinks "github.com/teletrace/teletrace/pkg/model/spanlinks/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSpanLinks is the number of outgoing links, and of incoming links, followed for a span
+const maxSpanLinks = 100
+
+// getSpanLinks responds with the spans a span links to and the spans linking to it,
+// to navigate across asynchronous boundaries, e.g. from a message consumer to its producers.
+func (api *API) getSpanLinks(c *gin.Context) {
+	traceId, spanId := c.Param("id"), c.Param("spanId")
+	res, err := (*api.spanReader).Search(c, spanSearchRequest(traceId, spanId))
+	if err != nil {
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return
+	}
+	if len(res.Spans) == 0 {
+		respondWithError(http.StatusNotFound, fmt.Errorf("span %s of trace %s not found", spanId, traceId), c)
+		return
+	}
+
+	result := spanlinks.GetSpanLinksResponse{
+		Outgoing: make([]spanlinks.LinkedSpan, 0),
+		Incoming: make([]spanlinks.LinkedSpan, 0),
+	}
+	// links may cross traces of any time
+	timeframe := model.Timeframe{StartTime: 0, EndTime: uint64(time.Now().UnixNano())}
+	links := res.Spans[0].Span.Links
+	if len(links) > maxSpanLinks {
+		links, result.Truncated = links[:maxSpanLinks], true
+	}
+	if result.Outgoing, err = api.outgoingLinks(c, timeframe, links); err != nil {
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return
+	}
+
+	res, err = (*api.spanReader).Search(c, spansquery.SearchRequest{
+		Timeframe: timeframe,
+		Sort:      []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: false}},
+		SearchFilters: []model.SearchFilter{
+			linkFilter("span.links.traceId", spansquery.OPERATOR_EQUALS, traceId),
+			linkFilter("span.links.spanId", spansquery.OPERATOR_EQUALS, spanId),
+		},
+		Metadata: &spansquery.Metadata{},
+		Limit:    maxSpanLinks,
+	})
+	if err != nil {
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return
+	}
+	for _, span := range res.Spans {
+		for _, link := range span.Span.Links {
+			// the span may also have links to other spans
+			if link.TraceId == traceId && link.SpanId == spanId {
+				result.Incoming = append(result.Incoming, spanlinks.LinkedSpan{
+					TraceId: span.Span.TraceId, SpanId: span.Span.SpanId, Attributes: link.Attributes, Span: span,
+				})
+				break
+			}
+		}
+	}
+	if len(res.Spans) >= maxSpanLinks {
+		result.Truncated = true
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// outgoingLinks returns the spans at the other end of links, searching the linked spans stored.
+func (api *API) outgoingLinks(
+	c *gin.Context, timeframe model.Timeframe, links []*internalspan.SpanLink,
+) ([]spanlinks.LinkedSpan, error) {
+	outgoing := make([]spanlinks.LinkedSpan, 0, len(links))
+	spanIds := make([]any, 0, len(links))
+	for _, link := range links {
+		// links written without their span context can't be followed
+		if link.TraceId == "" || link.SpanId == "" {
+			continue
+		}
+		outgoing = append(outgoing, spanlinks.LinkedSpan{TraceId: link.TraceId, SpanId: link.SpanId, Attributes: link.Attributes})
+		spanIds = append(spanIds, link.SpanId)
+	}
+	if len(spanIds) == 0 {
+		return outgoing, nil
+	}
+
+	res, err := (*api.spanReader).Search(c, spansquery.SearchRequest{
+		Timeframe:     timeframe,
+		SearchFilters: []model.SearchFilter{linkFilter("span.spanId", spansquery.OPERATOR_IN, spanIds)},
+		Metadata:      &spansquery.Metadata{},
+		Limit:         len(spanIds),
+	})
+	if err != nil {
+		return nil, err
+	}
+	spans := make(map[string]*internalspan.InternalSpan, len(res.Spans))
+	for _, span := range res.Spans {
+		spans[span.Span.TraceId+span.Span.SpanId] = span
+	}
+	for i := range outgoing {
+		outgoing[i].Span = spans[outgoing[i].TraceId+outgoing[i].SpanId]
+	}
+	return outgoing, nil
+}
+
+func linkFilter(key model.FilterKey, operator model.FilterOperator, value any) model.SearchFilter {
+	return model.SearchFilter{KeyValueFilter: &model.KeyValueFilter{Key: key, Operator: operator, Value: value}}
+}
@@ -0,0 +1,40 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/spanreader/tagvaluescache"
+
+	"go.uber.org/zap"
+)
+
+// registerTagValuesCache caches tag values per tag, time bucket and filters, if enabled.
+func (api *API) registerTagValuesCache() {
+	if !api.config.APITagValuesCacheEnabled {
+		return
+	}
+	sr, err := tagvaluescache.NewSpanReader(*api.spanReader, tagvaluescache.Config{
+		TTL:        time.Duration(api.config.APITagValuesCacheTTLSeconds) * time.Second,
+		MaxEntries: api.config.APITagValuesCacheMaxEntries,
+		TimeBucket: time.Duration(api.config.APITagValuesCacheTimeBucketSeconds) * time.Second,
+	})
+	if err != nil {
+		api.logger.Fatal("Failed to create tag values cache", zap.Error(err))
+	}
+	api.spanReader = &sr
+}
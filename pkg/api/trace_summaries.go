@@ -0,0 +1,48 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"net/http"
+
+	tracesummariesmodel "github.com/teletrace/teletrace/pkg/model/tracesummaries/v1"
+	"github.com/teletrace/teletrace/pkg/tracesummaries"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerTraceSummarizer creates the trace summarizer on top of the (possibly restricted) span reader.
+func (api *API) registerTraceSummarizer() {
+	api.traceSummarizer = tracesummaries.NewSummarizer(api.logger, *api.spanReader, tracesummaries.Config{
+		MaxTraces: api.config.TraceSummariesMaxTraces,
+	})
+}
+
+func (api *API) summarizeTraces(c *gin.Context) {
+	var req tracesummariesmodel.SummarizeRequest
+	isValidationError := api.validateRequestBody(&req, c)
+	if isValidationError {
+		return
+	}
+	handleTimeframe(&req.Timeframe)
+
+	res, err := api.traceSummarizer.Summarize(c, req)
+	if err != nil {
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,50 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/teletrace/teletrace/pkg/clockskew"
+	tracetreemodel "github.com/teletrace/teletrace/pkg/model/tracetree/v1"
+	"github.com/teletrace/teletrace/pkg/tracetree"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getTraceTree responds with the spans of a trace assembled into a tree, so clients don't rebuild it.
+func (api *API) getTraceTree(c *gin.Context) {
+	traceId := c.Param("id")
+	res, err := (*api.spanReader).Search(c, traceSearchRequest(traceId))
+	if err != nil {
+		respondWithError(spanReaderErrorStatusCode(err), err, c)
+		return
+	}
+	if len(res.Spans) == 0 {
+		respondWithError(http.StatusNotFound, fmt.Errorf("trace %s not found", traceId), c)
+		return
+	}
+
+	var treeRes tracetreemodel.GetTraceTreeResponse
+	if api.config.APIClockSkewAdjustmentEnabled {
+		treeRes.ClockSkewAdjustments = clockskew.Adjust(res.Spans)
+	}
+	treeRes.Roots = tracetree.Build(api.withEnumNames(res.Spans))
+	treeRes.SpanCount = len(res.Spans)
+	treeRes.MaxDepth = tracetree.MaxDepth(treeRes.Roots)
+	c.JSON(http.StatusOK, treeRes)
+}
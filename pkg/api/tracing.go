@@ -0,0 +1,83 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/teletrace/teletrace/pkg/spanreader/traced"
+	"github.com/teletrace/teletrace/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const tracerName = "github.com/teletrace/teletrace/pkg/api"
+
+// registerTracing traces API requests and the storage queries serving them with OpenTelemetry,
+// so slow requests can be diagnosed with the traces of teletrace itself.
+func (api *API) registerTracing() {
+	if !api.config.SelfTracingEnabled {
+		return
+	}
+	tp, err := tracing.NewTracerProvider(context.Background(), api.config)
+	if err != nil {
+		api.logger.Fatal("Failed to initialize self tracing", zap.Error(err))
+	}
+	api.tracer = tp.Tracer(tracerName)
+
+	sr := traced.NewSpanReader(*api.spanReader, tp)
+	api.spanReader = &sr
+}
+
+// tracingMiddleware starts a server span for every API request, continuing the trace of the caller if any.
+func (api *API) tracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if !strings.HasPrefix(route, apiPrefix) {
+			// static files and unknown routes
+			c.Next()
+			return
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := api.tracer.Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethodKey.String(c.Request.Method),
+				semconv.HTTPRouteKey.String(route),
+				semconv.HTTPTargetKey.String(c.Request.URL.RequestURI()),
+			),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
@@ -16,13 +16,57 @@
 
 package api
 
-import "github.com/gin-gonic/gin"
+import (
+	"net/http"
+	"strings"
+
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusClientClosedRequest is the non-standard status of requests whose client disconnected before the response
+const statusClientClosedRequest = 499
 
 type errorResponse struct {
 	ErrorMessage string `json:"errorMessage"`
+	// ErrorCode classifies errors of the span reader, e.g. INVALID_QUERY
+	ErrorCode spanreader.ErrorCode `json:"errorCode,omitempty"`
 }
 
 func respondWithError(statusCode int, err error, c *gin.Context) {
 	errResponse := &errorResponse{ErrorMessage: err.Error()}
+	if code := spanreader.Code(err); code != spanreader.ERROR_CODE_INTERNAL {
+		errResponse.ErrorCode = code
+	}
 	c.JSON(statusCode, errResponse)
 }
+
+// spanReaderErrorStatusCode returns the response status code of a failed span reader call by its error code.
+func spanReaderErrorStatusCode(err error) int {
+	switch spanreader.Code(err) {
+	case spanreader.ERROR_CODE_NOT_FOUND:
+		return http.StatusNotFound
+	case spanreader.ERROR_CODE_INVALID_QUERY:
+		return http.StatusBadRequest
+	case spanreader.ERROR_CODE_BACKEND_UNAVAILABLE:
+		return http.StatusServiceUnavailable
+	case spanreader.ERROR_CODE_TIMEOUT:
+		return http.StatusGatewayTimeout
+	case spanreader.ERROR_CODE_CANCELED:
+		return statusClientClosedRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// splitList splits a comma separated config value, ignoring empty items.
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,119 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader/acl"
+
+	"go.uber.org/zap"
+)
+
+// warmUp runs the queries users typically start with, so that backend caches (and the
+// response cache, when enabled) are populated before the first user request after a deploy.
+func (api *API) warmUp(ctx context.Context) {
+	start := time.Now()
+	tags := api.warmUpTags(ctx)
+
+	if api.aclPolicy == nil {
+		api.warmUpScope(ctx, "", tags)
+	} else {
+		// queries are restricted per role, so each role is warmed up separately
+		for _, role := range api.aclPolicy.RoleNames() {
+			api.warmUpScope(acl.WithRole(ctx, role), role+":", tags)
+		}
+	}
+
+	api.logger.Info("Finished warming up caches", zap.Duration("duration", time.Since(start)))
+}
+
+// warmUpTags returns the configured warm-up tags, followed by the tags most filtered on in the query history.
+func (api *API) warmUpTags(ctx context.Context) []string {
+	tags := splitList(api.config.APIWarmUpTags)
+	if api.queryHistory == nil || api.config.APIWarmUpPopularTags <= 0 {
+		return tags
+	}
+
+	popular, err := api.queryHistory.PopularTags(ctx, api.config.APIWarmUpPopularTags)
+	if err != nil {
+		api.logger.Warn("Failed to get popular tags for warm-up", zap.Error(err))
+		return tags
+	}
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		seen[tag] = true
+	}
+	for _, tag := range popular {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// warmUpScope warms up the queries of a single role, cacheKeyPrefix scopes the cached responses to it.
+func (api *API) warmUpScope(ctx context.Context, cacheKeyPrefix string, tags []string) {
+	availableTagsReq := tagsquery.GetAvailableTagsRequest{Limit: api.tagsLimit(0)}
+	availableTags, err := (*api.spanReader).GetAvailableTags(ctx, availableTagsReq)
+	if err != nil {
+		api.logger.Warn("Failed to warm up available tags", zap.Error(err))
+	} else if api.cache != nil {
+		key, err := cacheKey(path.Join(apiPrefix, "/tags"), availableTagsReq)
+		if err == nil {
+			api.cache.Set(cacheKeyPrefix+key, availableTags)
+		}
+	}
+
+	if len(tags) == 0 {
+		return
+	}
+	now := time.Now()
+	tagValuesReq := tagsquery.TagValuesRequest{
+		Timeframe: &model.Timeframe{
+			StartTime: uint64(now.Add(-time.Duration(api.config.APIWarmUpTimeframeMinutes) * time.Minute).UnixNano()),
+			EndTime:   uint64(now.UnixNano()),
+		},
+		Limit: api.tagValuesLimit(0),
+	}
+	if _, err := (*api.spanReader).GetTagsValues(ctx, tagValuesReq, tags); err != nil {
+		api.logger.Warn("Failed to warm up tag values", zap.Strings("tags", tags), zap.Error(err))
+	}
+
+	if api.cache == nil {
+		return
+	}
+	// the filter sidebar requests the values of each tag without a timeframe along with the current ones,
+	// being absolute those can't be cached ahead
+	sidebarReq := tagsquery.TagValuesRequest{SearchFilters: []model.SearchFilter{}, Limit: api.tagValuesLimit(0)}
+	for _, tag := range tags {
+		tagValues, err := api.loadTagValues(ctx, sidebarReq, tag)
+		if err != nil {
+			api.logger.Warn("Failed to warm up tag values", zap.String("tag", tag), zap.Error(err))
+			continue
+		}
+		key, err := cacheKey(path.Join(apiPrefix, "/tags", tag), sidebarReq)
+		if err == nil {
+			api.cache.Set(cacheKeyPrefix+key, tagValues)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/teletrace/teletrace/blobstore"
+	archive "github.com/teletrace/teletrace/pkg/model/archive/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/modeltranslator"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+const (
+	blobKeyPrefix  = "trace-"
+	restoreTimeout = 30 * time.Second
+)
+
+var (
+	// ErrNotArchived is returned when restoring a trace which wasn't archived.
+	ErrNotArchived = errors.New("trace is not archived")
+	// ErrTooManyTraces is returned when a request holds more trace IDs than the configured maximum.
+	ErrTooManyTraces = errors.New("too many traces")
+)
+
+// Config configures the archiver.
+type Config struct {
+	// MaxTraces is the maximum number of traces archived or restored by a single request
+	MaxTraces int
+	// RestoreEndpoint is the OTLP/HTTP traces endpoint restored traces are sent to, e.g. http://localhost:4318/v1/traces
+	RestoreEndpoint string
+}
+
+// Archiver archives traces to a blob store, e.g. an S3 bucket, for the compliance retention of specific incidents,
+// and restores them back into the live backend on demand.
+type Archiver struct {
+	logger     *zap.Logger
+	spanReader spanreader.SpanReader
+	store      blobstore.Store
+	client     *http.Client
+	cfg        Config
+}
+
+func NewArchiver(logger *zap.Logger, sr spanreader.SpanReader, store blobstore.Store, cfg Config) *Archiver {
+	return &Archiver{
+		logger:     logger,
+		spanReader: sr,
+		store:      store,
+		client:     &http.Client{Timeout: restoreTimeout},
+		cfg:        cfg,
+	}
+}
+
+// Archive stores each of the requested traces in a blob, encoded as an OTLP/HTTP JSON export request.
+// Archiving a trace again replaces its blob, so it holds the spans received since it was last archived.
+func (a *Archiver) Archive(ctx context.Context, req archive.ArchiveRequest) (*archive.ArchiveResponse, error) {
+	traceIds := req.TraceIds
+	truncated := false
+	if len(traceIds) > a.cfg.MaxTraces {
+		return nil, fmt.Errorf("at most %d traces can be archived at once: %w", a.cfg.MaxTraces, ErrTooManyTraces)
+	}
+	if len(traceIds) == 0 {
+		var err error
+		traceIds, truncated, err = a.matchingTraces(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("could not find matching traces: %w", err)
+		}
+	}
+
+	archived := []string{}
+	for _, traceId := range traceIds {
+		spans, err := spanreader.TraceSpans(ctx, a.spanReader, traceId)
+		if err != nil {
+			return nil, fmt.Errorf("could not get spans of trace %s: %w", traceId, err)
+		}
+		if len(spans) == 0 {
+			continue
+		}
+
+		td, err := modeltranslator.TranslateInternalModelToOTLP(spans)
+		if err != nil {
+			return nil, fmt.Errorf("could not translate trace %s: %w", traceId, err)
+		}
+		blob, err := (&ptrace.JSONMarshaler{}).MarshalTraces(td)
+		if err != nil {
+			return nil, fmt.Errorf("could not encode trace %s: %w", traceId, err)
+		}
+		if err := a.store.Put(ctx, blobKey(traceId), blob); err != nil {
+			return nil, fmt.Errorf("could not archive trace %s: %w", traceId, err)
+		}
+		archived = append(archived, traceId)
+	}
+
+	a.logger.Info("Archived traces", zap.Strings("traceIds", archived))
+	return &archive.ArchiveResponse{TraceIds: archived, Truncated: truncated}, nil
+}
+
+// Restore sends the spans of each of the requested archived traces to the restore endpoint. Since span writes are
+// idempotent, restoring a trace which is still live doesn't duplicate its spans.
+func (a *Archiver) Restore(ctx context.Context, req archive.RestoreRequest) (*archive.RestoreResponse, error) {
+	if len(req.TraceIds) > a.cfg.MaxTraces {
+		return nil, fmt.Errorf("at most %d traces can be restored at once: %w", a.cfg.MaxTraces, ErrTooManyTraces)
+	}
+
+	res := &archive.RestoreResponse{TraceIds: []string{}}
+	for _, traceId := range req.TraceIds {
+		blob, err := a.store.Get(ctx, blobKey(traceId))
+		if errors.Is(err, blobstore.ErrNotFound) {
+			return nil, fmt.Errorf("could not restore trace %s: %w", traceId, ErrNotArchived)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read archived trace %s: %w", traceId, err)
+		}
+
+		// the blob is an OTLP/HTTP JSON export request, it's decoded only to be validated and counted
+		td, err := (&ptrace.JSONUnmarshaler{}).UnmarshalTraces(blob)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode archived trace %s: %w", traceId, err)
+		}
+		if err := a.send(ctx, blob); err != nil {
+			return nil, fmt.Errorf("could not restore trace %s: %w", traceId, err)
+		}
+		res.TraceIds = append(res.TraceIds, traceId)
+		res.SpanCount += td.SpanCount()
+	}
+
+	a.logger.Info("Restored traces", zap.Strings("traceIds", res.TraceIds))
+	return res, nil
+}
+
+// matchingTraces returns the IDs of the traces of the most recent spans matching the request,
+// up to the configured maximum number of traces.
+func (a *Archiver) matchingTraces(ctx context.Context, req archive.ArchiveRequest) ([]string, bool, error) {
+	var traceIds []string
+	seen := make(map[string]bool)
+	var token spansquery.ContinuationToken
+	for {
+		res, err := a.spanReader.Search(ctx, spansquery.SearchRequest{
+			Timeframe:     req.Timeframe,
+			Sort:          []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: false}},
+			SearchFilters: spanreader.CopyFilters(req.SearchFilters),
+			Metadata:      &spansquery.Metadata{NextToken: token},
+		})
+		if err != nil {
+			return nil, false, err
+		}
+
+		for _, span := range res.Spans {
+			traceId := span.Span.TraceId
+			if seen[traceId] {
+				continue
+			}
+			if len(traceIds) == a.cfg.MaxTraces {
+				return traceIds, true, nil
+			}
+			seen[traceId] = true
+			traceIds = append(traceIds, traceId)
+		}
+
+		if len(res.Spans) == 0 || res.Metadata == nil || res.Metadata.NextToken == "" {
+			return traceIds, false, nil
+		}
+		token = res.Metadata.NextToken
+	}
+}
+
+// send posts an OTLP/HTTP JSON payload to the restore endpoint.
+func (a *Archiver) send(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.cfg.RestoreEndpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send trace to %s: %w", a.cfg.RestoreEndpoint, err)
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%s responded with status %d", a.cfg.RestoreEndpoint, res.StatusCode)
+	}
+	return nil
+}
+
+func blobKey(traceId string) string {
+	return blobKeyPrefix + strings.ToLower(traceId)
+}
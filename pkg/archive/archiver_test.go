@@ -0,0 +1,173 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package archive
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/teletrace/teletrace/blobstore"
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	archive "github.com/teletrace/teletrace/pkg/model/archive/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+const pageSize = 2
+
+// traceSpanReader serves the spans it holds, in pages of pageSize spans.
+type traceSpanReader struct {
+	spanreader.SpanReader
+	spans []*internalspan.InternalSpan
+}
+
+func (sr *traceSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	var matching []*internalspan.InternalSpan
+	for _, span := range sr.spans {
+		if matchesFilters(span, r) {
+			matching = append(matching, span)
+		}
+	}
+
+	offset, _ := strconv.Atoi(string(r.Metadata.NextToken))
+	end := offset + pageSize
+	metadata := &spansquery.Metadata{NextToken: spansquery.ContinuationToken(strconv.Itoa(end))}
+	if end >= len(matching) {
+		end = len(matching)
+		metadata.NextToken = ""
+	}
+	return &spansquery.SearchResponse{Metadata: metadata, Spans: matching[offset:end]}, nil
+}
+
+func matchesFilters(span *internalspan.InternalSpan, r spansquery.SearchRequest) bool {
+	for _, f := range r.SearchFilters {
+		switch f.KeyValueFilter.Key {
+		case "span.traceId":
+			if span.Span.TraceId != f.KeyValueFilter.Value {
+				return false
+			}
+		case "span.name":
+			if span.Span.Name != f.KeyValueFilter.Value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func newSpan(traceId string, spanId string, name string) *internalspan.InternalSpan {
+	return &internalspan.InternalSpan{
+		Resource: &internalspan.Resource{Attributes: internalspan.Attributes{"service.name": "cart"}},
+		Scope:    &internalspan.InstrumentationScope{Name: "otelhttp", Version: "1.0"},
+		Span: &internalspan.Span{
+			TraceId:           traceId,
+			SpanId:            spanId,
+			Name:              name,
+			Kind:              "Server",
+			StartTimeUnixNano: 10,
+			EndTimeUnixNano:   20,
+			Attributes:        internalspan.Attributes{"http.status_code": int64(500), "retried": true},
+			Events:            []*internalspan.SpanEvent{{Name: "exception", TimeUnixNano: 15}},
+			Links:             []*internalspan.SpanLink{{TraceId: "ff", SpanId: "ee"}},
+			Status:            &internalspan.SpanStatus{Code: "Error", Message: "timeout"},
+		},
+	}
+}
+
+func newArchiver(t *testing.T, restoreEndpoint string) *Archiver {
+	srMock, _ := mock.NewSpanReaderMock()
+	sr := &traceSpanReader{SpanReader: srMock, spans: []*internalspan.InternalSpan{
+		newSpan("aa", "01", "GET /cart"),
+		newSpan("aa", "02", "SELECT"),
+		newSpan("bb", "03", "SELECT"),
+		newSpan("cc", "04", "GET /health"),
+	}}
+	store, err := blobstore.NewStore(blobstore.Config{Type: blobstore.TypeDisk, Directory: t.TempDir()})
+	assert.NoError(t, err)
+	return NewArchiver(zap.NewNop(), sr, store, Config{MaxTraces: 2, RestoreEndpoint: restoreEndpoint})
+}
+
+func TestArchiveAndRestore(t *testing.T) {
+	var restored []ptrace.Traces
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		td, err := (&ptrace.JSONUnmarshaler{}).UnmarshalTraces(body)
+		assert.NoError(t, err)
+		restored = append(restored, td)
+	}))
+	defer server.Close()
+	archiver := newArchiver(t, server.URL)
+
+	res, err := archiver.Archive(context.Background(), archive.ArchiveRequest{TraceIds: []string{"aa", "dd"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"aa"}, res.TraceIds)
+
+	_, err = archiver.Restore(context.Background(), archive.RestoreRequest{TraceIds: []string{"bb"}})
+	assert.ErrorIs(t, err, ErrNotArchived)
+
+	restoreRes, err := archiver.Restore(context.Background(), archive.RestoreRequest{TraceIds: []string{"aa"}})
+	assert.NoError(t, err)
+	assert.Equal(t, &archive.RestoreResponse{TraceIds: []string{"aa"}, SpanCount: 2}, restoreRes)
+
+	assert.Len(t, restored, 1)
+	assert.Equal(t, 1, restored[0].ResourceSpans().Len())
+	scopeSpans := restored[0].ResourceSpans().At(0).ScopeSpans()
+	assert.Equal(t, 1, scopeSpans.Len())
+	assert.Equal(t, "otelhttp", scopeSpans.At(0).Scope().Name())
+	spans := scopeSpans.At(0).Spans()
+	assert.Equal(t, 2, spans.Len())
+	span := spans.At(0)
+	assert.Equal(t, "0000000000000001", span.SpanID().HexString())
+	assert.Equal(t, ptrace.SpanKindServer, span.Kind())
+	assert.Equal(t, ptrace.StatusCodeError, span.Status().Code())
+	assert.Equal(t, "timeout", span.Status().Message())
+	assert.Equal(t, pcommon.Timestamp(10), span.StartTimestamp())
+	assert.Equal(t, map[string]any{"http.status_code": int64(500), "retried": true}, span.Attributes().AsRaw())
+	assert.Equal(t, "exception", span.Events().At(0).Name())
+	assert.Equal(t, "00000000000000ee", span.Links().At(0).SpanID().HexString())
+}
+
+func TestArchiveByFilters(t *testing.T) {
+	archiver := newArchiver(t, "")
+
+	res, err := archiver.Archive(context.Background(), archive.ArchiveRequest{
+		SearchFilters: []model.SearchFilter{{KeyValueFilter: &model.KeyValueFilter{
+			Key: "span.name", Operator: spansquery.OPERATOR_EQUALS, Value: "SELECT",
+		}}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"aa", "bb"}, res.TraceIds)
+	assert.False(t, res.Truncated)
+
+	res, err = archiver.Archive(context.Background(), archive.ArchiveRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, res.TraceIds, 2)
+	assert.True(t, res.Truncated)
+
+	_, err = archiver.Archive(context.Background(), archive.ArchiveRequest{TraceIds: []string{"aa", "bb", "cc"}})
+	assert.ErrorIs(t, err, ErrTooManyTraces)
+}
@@ -0,0 +1,154 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package arrowexport encodes spans as an Apache Arrow IPC stream, so query results can be loaded directly into
+// dataframe libraries (e.g. pandas or Polars) for offline analysis.
+package arrowexport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/ipc"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+)
+
+// ContentType is the media type of an Arrow IPC stream.
+const ContentType = "application/vnd.apache.arrow.stream"
+
+// Schema is the schema of exported spans, with a row per span.
+// Attributes and events are nested and vary between spans, so they are encoded as JSON strings.
+var Schema = arrow.NewSchema([]arrow.Field{
+	{Name: "trace_id", Type: arrow.BinaryTypes.String},
+	{Name: "span_id", Type: arrow.BinaryTypes.String},
+	{Name: "parent_span_id", Type: arrow.BinaryTypes.String},
+	{Name: "name", Type: arrow.BinaryTypes.String},
+	{Name: "kind", Type: arrow.BinaryTypes.String},
+	{Name: "start_time", Type: arrow.FixedWidthTypes.Timestamp_ns},
+	{Name: "end_time", Type: arrow.FixedWidthTypes.Timestamp_ns},
+	{Name: "duration", Type: arrow.FixedWidthTypes.Duration_ns},
+	{Name: "status_code", Type: arrow.BinaryTypes.String},
+	{Name: "status_message", Type: arrow.BinaryTypes.String},
+	{Name: "service_name", Type: arrow.BinaryTypes.String},
+	{Name: "scope_name", Type: arrow.BinaryTypes.String},
+	{Name: "attributes", Type: arrow.BinaryTypes.String},
+	{Name: "resource_attributes", Type: arrow.BinaryTypes.String},
+	{Name: "events", Type: arrow.BinaryTypes.String},
+}, nil)
+
+// Writer writes spans to an Arrow IPC stream, a record batch per write.
+type Writer struct {
+	builder *array.RecordBuilder
+	writer  *ipc.Writer
+}
+
+// NewWriter returns a writer of an Arrow IPC stream of spans to w. It must be closed to end the stream.
+func NewWriter(w io.Writer) *Writer {
+	mem := memory.NewGoAllocator()
+	return &Writer{
+		builder: array.NewRecordBuilder(mem, Schema),
+		writer:  ipc.NewWriter(w, ipc.WithSchema(Schema), ipc.WithAllocator(mem)),
+	}
+}
+
+// Write writes the spans as a record batch.
+func (w *Writer) Write(spans []*internalspan.InternalSpan) error {
+	w.builder.Reserve(len(spans))
+	for _, s := range spans {
+		if err := w.appendSpan(s); err != nil {
+			return err
+		}
+	}
+	record := w.builder.NewRecord()
+	defer record.Release()
+	if err := w.writer.Write(record); err != nil {
+		return fmt.Errorf("could not write record batch: %w", err)
+	}
+	return nil
+}
+
+// Close ends the stream, without closing the underlying writer.
+func (w *Writer) Close() error {
+	w.builder.Release()
+	return w.writer.Close()
+}
+
+func (w *Writer) appendSpan(s *internalspan.InternalSpan) error {
+	span := s.Span
+	if span == nil {
+		span = &internalspan.Span{}
+	}
+	var statusCode, statusMessage string
+	if span.Status != nil {
+		statusCode, statusMessage = span.Status.Code, span.Status.Message
+	}
+	var serviceName string
+	var resourceAttributes internalspan.Attributes
+	if s.Resource != nil {
+		resourceAttributes = s.Resource.Attributes
+		serviceName, _ = resourceAttributes["service.name"].(string)
+	}
+	var scopeName string
+	if s.Scope != nil {
+		scopeName = s.Scope.Name
+	}
+	var durationNano uint64
+	if s.ExternalFields != nil {
+		durationNano = s.ExternalFields.DurationNano
+	}
+	attributes, err := encodeJSON(span.Attributes)
+	if err != nil {
+		return err
+	}
+	resourceAttributesJSON, err := encodeJSON(resourceAttributes)
+	if err != nil {
+		return err
+	}
+	events, err := encodeJSON(span.Events)
+	if err != nil {
+		return err
+	}
+
+	appendString := func(i int, v string) { w.builder.Field(i).(*array.StringBuilder).Append(v) }
+	appendString(0, span.TraceId)
+	appendString(1, span.SpanId)
+	appendString(2, span.ParentSpanId)
+	appendString(3, span.Name)
+	appendString(4, span.Kind)
+	w.builder.Field(5).(*array.TimestampBuilder).Append(arrow.Timestamp(span.StartTimeUnixNano))
+	w.builder.Field(6).(*array.TimestampBuilder).Append(arrow.Timestamp(span.EndTimeUnixNano))
+	w.builder.Field(7).(*array.DurationBuilder).Append(arrow.Duration(durationNano))
+	appendString(8, statusCode)
+	appendString(9, statusMessage)
+	appendString(10, serviceName)
+	appendString(11, scopeName)
+	appendString(12, attributes)
+	appendString(13, resourceAttributesJSON)
+	appendString(14, events)
+	return nil
+}
+
+func encodeJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("could not encode span field: %w", err)
+	}
+	return string(b), nil
+}
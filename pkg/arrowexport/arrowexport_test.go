@@ -0,0 +1,58 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package arrowexport
+
+import (
+	"bytes"
+	"testing"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	spanformatutiltests "github.com/teletrace/teletrace/model/internalspan/v1/util"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/ipc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	span := spanformatutiltests.GenInternalSpan(
+		map[string]any{"http.method": "GET"}, map[string]any{"service.name": "frontend"}, nil,
+	)
+	assert.NoError(t, w.Write([]*internalspan.InternalSpan{span, span}))
+	assert.NoError(t, w.Write([]*internalspan.InternalSpan{span}))
+	assert.NoError(t, w.Close())
+
+	r, err := ipc.NewReader(&buf)
+	assert.NoError(t, err)
+	defer r.Release()
+	assert.True(t, r.Schema().Equal(Schema))
+
+	var rows []int64
+	for r.Next() {
+		record := r.Record()
+		rows = append(rows, record.NumRows())
+		assert.Equal(t, "1234567887654321", record.Column(0).(*array.String).Value(0))
+		assert.Equal(t, "span_name", record.Column(3).(*array.String).Value(0))
+		assert.Equal(t, arrow.Duration(1000000000), record.Column(7).(*array.Duration).Value(0))
+		assert.Equal(t, "frontend", record.Column(10).(*array.String).Value(0))
+		assert.Equal(t, `{"http.method":"GET"}`, record.Column(12).(*array.String).Value(0))
+	}
+	assert.NoError(t, r.Err())
+	assert.Equal(t, []int64{2, 1}, rows)
+}
@@ -0,0 +1,144 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	"github.com/teletrace/teletrace/pkg/model"
+	auditv1 "github.com/teletrace/teletrace/pkg/model/audit/v1"
+)
+
+// Namespace is the metadata store namespace holding the audit log, a record per entry.
+const Namespace = "audit-log"
+
+// Log records audit entries in a metadata store. Entries are only ever created, never updated or deleted,
+// and are keyed by their timestamp so the store lists them in chronological order.
+type Log struct {
+	store metadatastore.MetadataStore
+	now   func() time.Time
+}
+
+// NewLog returns an audit log stored in store.
+func NewLog(store metadatastore.MetadataStore) *Log {
+	return &Log{store: store, now: time.Now}
+}
+
+// Query selects audit entries, empty fields match any entry.
+type Query struct {
+	User   string
+	Action string
+	// FromUnixNano and ToUnixNano bound the timestamps of the entries, inclusive
+	FromUnixNano uint64
+	ToUnixNano   uint64
+	Limit        int
+}
+
+// Record appends an entry to the audit log, setting its id and timestamp.
+func (l *Log) Record(ctx context.Context, entry auditv1.Entry) error {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Errorf("could not generate audit entry id: %w", err)
+	}
+	entry.Id = hex.EncodeToString(id)
+	entry.TimestampUnixNano = uint64(l.now().UnixNano())
+
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("could not encode audit entry: %w", err)
+	}
+	// zero padded, so the keys sort like the timestamps
+	key := fmt.Sprintf("%020d-%s", entry.TimestampUnixNano, entry.Id)
+	return l.store.Create(ctx, metadatastore.Record{Namespace: Namespace, Key: key, Value: value})
+}
+
+// List returns the entries matching q, the most recent first.
+func (l *Log) List(ctx context.Context, q Query) ([]auditv1.Entry, error) {
+	records, err := l.store.List(ctx, Namespace)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Key > records[j].Key })
+
+	entries := []auditv1.Entry{}
+	for _, record := range records {
+		if q.Limit > 0 && len(entries) == q.Limit {
+			break
+		}
+		var entry auditv1.Entry
+		if err := json.Unmarshal(record.Value, &entry); err != nil {
+			return nil, fmt.Errorf("could not decode audit entry %s: %w", record.Key, err)
+		}
+		if (q.User != "" && entry.User != q.User) ||
+			(q.Action != "" && entry.Action != q.Action) ||
+			(q.FromUnixNano > 0 && entry.TimestampUnixNano < q.FromUnixNano) ||
+			(q.ToUnixNano > 0 && entry.TimestampUnixNano > q.ToUnixNano) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Fingerprint returns a short digest identifying an API key in the audit log without revealing it.
+func Fingerprint(apiKey string) string {
+	digest := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(digest[:8])
+}
+
+// Summarize describes the timeframe and filters of a request, e.g.
+// "timeframe now-1h..now, span.name equals GET /users AND span.attributes.http.status_code gte 500".
+func Summarize(timeframe *model.Timeframe, filters []model.SearchFilter) string {
+	var parts []string
+	if timeframe != nil {
+		parts = append(parts, "timeframe "+summarizeTimeframe(*timeframe))
+	}
+	var conditions []string
+	for _, filter := range filters {
+		if kv := filter.KeyValueFilter; kv != nil {
+			conditions = append(conditions, fmt.Sprintf("%s %s %v", kv.Key, kv.Operator, kv.Value))
+		}
+	}
+	if len(conditions) > 0 {
+		parts = append(parts, strings.Join(conditions, " AND "))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// summarizeTimeframe prefers the time expressions as sent, which are more readable than the resolved timestamps.
+func summarizeTimeframe(t model.Timeframe) string {
+	start, end := t.Start, t.End
+	if start == "" {
+		start = time.Unix(0, int64(t.StartTime)).UTC().Format(time.RFC3339)
+	}
+	if end == "" {
+		// the end time of a relative timeframe is resolved to now
+		end = "now"
+		if t.Start == "" && t.EndTime > 0 {
+			end = time.Unix(0, int64(t.EndTime)).UTC().Format(time.RFC3339)
+		}
+	}
+	return start + ".." + end
+}
@@ -0,0 +1,88 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore/memory"
+	"github.com/teletrace/teletrace/pkg/model"
+	auditv1 "github.com/teletrace/teletrace/pkg/model/audit/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLog(t *testing.T) {
+	ctx := context.Background()
+	log := NewLog(memory.NewMetadataStore())
+	now := time.Unix(1000, 0)
+	log.now = func() time.Time { return now }
+
+	for _, entry := range []auditv1.Entry{
+		{User: "alice", Action: auditv1.ActionQuery},
+		{User: "bob", Action: auditv1.ActionQuery},
+		{User: "alice", Action: auditv1.ActionDelete},
+	} {
+		assert.NoError(t, log.Record(ctx, entry))
+		now = now.Add(time.Second)
+	}
+
+	entries, err := log.List(ctx, Query{})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, auditv1.ActionDelete, entries[0].Action, "the most recent entry is first")
+	assert.Equal(t, uint64(time.Unix(1002, 0).UnixNano()), entries[0].TimestampUnixNano)
+	assert.NotEmpty(t, entries[0].Id)
+
+	entries, err = log.List(ctx, Query{User: "alice"})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	entries, err = log.List(ctx, Query{Action: auditv1.ActionQuery, Limit: 1})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "bob", entries[0].User)
+
+	entries, err = log.List(ctx, Query{
+		FromUnixNano: uint64(time.Unix(1000, 0).UnixNano()),
+		ToUnixNano:   uint64(time.Unix(1001, 0).UnixNano()),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestSummarize(t *testing.T) {
+	filters := []model.SearchFilter{
+		{KeyValueFilter: &model.KeyValueFilter{Key: "span.name", Operator: spansquery.OPERATOR_EQUALS, Value: "GET /users"}},
+		{KeyValueFilter: &model.KeyValueFilter{Key: "span.attributes.http.status_code", Operator: spansquery.OPERATOR_GTE, Value: 500}},
+	}
+	assert.Equal(t,
+		"timeframe now-1h..now, span.name equals GET /users AND span.attributes.http.status_code gte 500",
+		Summarize(&model.Timeframe{Start: "now-1h"}, filters))
+	assert.Equal(t,
+		"timeframe 1970-01-01T00:00:01Z..1970-01-01T00:00:02Z",
+		Summarize(&model.Timeframe{StartTime: 1e9, EndTime: 2e9}, nil))
+	assert.Empty(t, Summarize(nil, nil))
+}
+
+func TestFingerprint(t *testing.T) {
+	assert.Len(t, Fingerprint("secret"), 16)
+	assert.Equal(t, Fingerprint("secret"), Fingerprint("secret"))
+	assert.NotEqual(t, Fingerprint("secret"), Fingerprint("other"))
+}
@@ -0,0 +1,99 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type lruEntry struct {
+	key       string
+	value     interface{}
+	fetchedAt time.Time
+}
+
+// LRUCache is an in-memory cache whose values expire after a ttl, evicting the least recently used value when full.
+type LRUCache struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu sync.Mutex
+	// order holds the entries from the most to the least recently used
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// NewLRUCache returns a new cache, values are served for ttl. A non-positive maxEntries means no limit.
+func NewLRUCache(ttl time.Duration, maxEntries int) (*LRUCache, error) {
+	if ttl <= 0 {
+		return nil, errInvalidTTL
+	}
+	return &LRUCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}, nil
+}
+
+// Get returns the value cached under key, unless it's missing or expired.
+func (c *LRUCache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := elem.Value.(*lruEntry)
+	if c.now().Sub(e.fetchedAt) > c.ttl {
+		c.remove(elem)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return e.value, true
+}
+
+// Set stores a value under key, replacing any existing one and evicting the least recently used value if full.
+func (c *LRUCache) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	if c.maxEntries > 0 && c.order.Len() >= c.maxEntries {
+		c.remove(c.order.Back())
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, fetchedAt: c.now()})
+}
+
+// Len returns the number of cached values, including expired ones not evicted yet.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// remove must be called with the lock held.
+func (c *LRUCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry).key)
+}
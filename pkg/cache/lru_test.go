@@ -0,0 +1,71 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLRUCache(t *testing.T, maxEntries int) (*LRUCache, *time.Time) {
+	c, err := NewLRUCache(time.Minute, maxEntries)
+	assert.NoError(t, err)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestLRUInvalidTTL(t *testing.T) {
+	_, err := NewLRUCache(0, 0)
+	assert.ErrorIs(t, err, errInvalidTTL)
+}
+
+func TestLRUExpiry(t *testing.T) {
+	c, now := newTestLRUCache(t, 0)
+	c.Set("key", 1)
+
+	value, ok := c.Get("key")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	*now = now.Add(2 * time.Minute)
+	_, ok = c.Get("key")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len(), "expired values are removed")
+}
+
+func TestLRUEviction(t *testing.T) {
+	c, _ := newTestLRUCache(t, 2)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	// a becomes the most recently used
+	_, _ = c.Get("a")
+	c.Set("c", 3)
+
+	_, ok := c.Get("b")
+	assert.False(t, ok, "the least recently used value is evicted")
+	_, ok = c.Get("a")
+	assert.True(t, ok)
+	_, ok = c.Get("c")
+	assert.True(t, ok)
+
+	c.Set("c", 4)
+	value, _ := c.Get("c")
+	assert.Equal(t, 4, value)
+	assert.Equal(t, 2, c.Len())
+}
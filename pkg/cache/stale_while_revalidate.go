@@ -0,0 +1,170 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var errInvalidTTL = errors.New("invalid cache ttl, must be greater than zero")
+
+// Status describes how a cached value was served.
+type Status string
+
+const (
+	// StatusMiss means the value was not cached (or too stale) and was loaded synchronously.
+	StatusMiss Status = "MISS"
+	// StatusHit means the value was served from the cache while still fresh.
+	StatusHit Status = "HIT"
+	// StatusStale means the value was served from the cache after its ttl expired,
+	// while a refresh runs in the background.
+	StatusStale Status = "STALE"
+)
+
+// Loader loads the value to be cached.
+type Loader func(ctx context.Context) (interface{}, error)
+
+// Result is a value returned from the cache along with its freshness info.
+type Result struct {
+	Value  interface{}
+	Status Status
+	Age    time.Duration
+}
+
+type entry struct {
+	value      interface{}
+	fetchedAt  time.Time
+	refreshing bool
+}
+
+// StaleWhileRevalidateCache is an in-memory cache that keeps serving values
+// for a grace period after they expire, while refreshing them in the background.
+type StaleWhileRevalidateCache struct {
+	logger     *zap.Logger
+	ttl        time.Duration
+	maxStale   time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// NewStaleWhileRevalidateCache returns a new cache, values are fresh for ttl and
+// are served stale for up to maxStale after that. A non-positive maxEntries means no limit.
+func NewStaleWhileRevalidateCache(
+	logger *zap.Logger, ttl time.Duration, maxStale time.Duration, maxEntries int,
+) (*StaleWhileRevalidateCache, error) {
+	if ttl <= 0 {
+		return nil, errInvalidTTL
+	}
+	if maxStale < 0 {
+		maxStale = 0
+	}
+	return &StaleWhileRevalidateCache{
+		logger:     logger,
+		ttl:        ttl,
+		maxStale:   maxStale,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    make(map[string]*entry),
+	}, nil
+}
+
+// Get returns the value cached under key, using load to fetch it when missing or too stale.
+// Stale values are returned immediately and refreshed in the background. Load errors are not cached.
+func (c *StaleWhileRevalidateCache) Get(ctx context.Context, key string, load Loader) (Result, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok {
+		age := c.now().Sub(e.fetchedAt)
+		if age <= c.ttl {
+			c.mu.Unlock()
+			return Result{Value: e.value, Status: StatusHit, Age: age}, nil
+		}
+		if age <= c.ttl+c.maxStale {
+			if !e.refreshing {
+				e.refreshing = true
+				go c.refresh(key, load)
+			}
+			c.mu.Unlock()
+			return Result{Value: e.value, Status: StatusStale, Age: age}, nil
+		}
+	}
+	c.mu.Unlock()
+
+	value, err := load(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	c.set(key, value)
+	return Result{Value: value, Status: StatusMiss}, nil
+}
+
+// Set stores a value under key, replacing any existing one.
+func (c *StaleWhileRevalidateCache) Set(key string, value interface{}) {
+	c.set(key, value)
+}
+
+func (c *StaleWhileRevalidateCache) refresh(key string, load Loader) {
+	value, err := load(context.Background())
+	if err != nil {
+		c.logger.Warn("Failed to refresh stale cache entry", zap.String("key", key), zap.Error(err))
+		c.mu.Lock()
+		if e, ok := c.entries[key]; ok {
+			e.refreshing = false
+		}
+		c.mu.Unlock()
+		return
+	}
+	c.set(key, value)
+}
+
+func (c *StaleWhileRevalidateCache) set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if _, ok := c.entries[key]; !ok && c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+	c.entries[key] = &entry{value: value, fetchedAt: now}
+}
+
+// evict removes expired entries, or the oldest entry if none has expired.
+// Must be called with the lock held.
+func (c *StaleWhileRevalidateCache) evict(now time.Time) {
+	var oldestKey string
+	var oldest time.Time
+	for k, e := range c.entries {
+		if now.Sub(e.fetchedAt) > c.ttl+c.maxStale {
+			delete(c.entries, k)
+			continue
+		}
+		if oldestKey == "" || e.fetchedAt.Before(oldest) {
+			oldestKey, oldest = k, e.fetchedAt
+		}
+	}
+	if len(c.entries) >= c.maxEntries {
+		delete(c.entries, oldestKey)
+	}
+}
@@ -0,0 +1,141 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+)
+
+func newTestCache(t *testing.T, maxEntries int) (*StaleWhileRevalidateCache, *time.Time) {
+	c, err := NewStaleWhileRevalidateCache(zap.NewNop(), time.Minute, 5*time.Minute, maxEntries)
+	assert.NoError(t, err)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestInvalidTTL(t *testing.T) {
+	_, err := NewStaleWhileRevalidateCache(zap.NewNop(), 0, time.Minute, 0)
+	assert.ErrorIs(t, err, errInvalidTTL)
+}
+
+func TestHitAndMiss(t *testing.T) {
+	c, _ := newTestCache(t, 0)
+	calls := 0
+	load := func(ctx context.Context) (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+
+	res, err := c.Get(context.Background(), "key", load)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusMiss, res.Status)
+	assert.Equal(t, 1, res.Value)
+
+	res, err = c.Get(context.Background(), "key", load)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusHit, res.Status)
+	assert.Equal(t, 1, res.Value)
+	assert.Equal(t, 1, calls)
+}
+
+func TestStaleServedAndRefreshed(t *testing.T) {
+	c, now := newTestCache(t, 0)
+	calls := atomic.NewInt32(0)
+	refreshed := make(chan struct{}, 1)
+	load := func(ctx context.Context) (interface{}, error) {
+		v := calls.Inc()
+		if v > 1 {
+			refreshed <- struct{}{}
+		}
+		return v, nil
+	}
+
+	_, err := c.Get(context.Background(), "key", load)
+	assert.NoError(t, err)
+
+	*now = now.Add(2 * time.Minute)
+	res, err := c.Get(context.Background(), "key", load)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusStale, res.Status)
+	assert.Equal(t, int32(1), res.Value)
+	assert.Equal(t, 2*time.Minute, res.Age)
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("stale entry was not refreshed")
+	}
+
+	assert.Eventually(t, func() bool {
+		res, _ := c.Get(context.Background(), "key", load)
+		return res.Status == StatusHit && res.Value == int32(2)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTooStaleLoadsSynchronously(t *testing.T) {
+	c, now := newTestCache(t, 0)
+	calls := 0
+	load := func(ctx context.Context) (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+
+	_, _ = c.Get(context.Background(), "key", load)
+	*now = now.Add(10 * time.Minute)
+	res, err := c.Get(context.Background(), "key", load)
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusMiss, res.Status)
+	assert.Equal(t, 2, res.Value)
+}
+
+func TestLoadErrorNotCached(t *testing.T) {
+	c, _ := newTestCache(t, 0)
+	loadErr := errors.New("load failed")
+
+	_, err := c.Get(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+		return nil, loadErr
+	})
+	assert.ErrorIs(t, err, loadErr)
+
+	res, err := c.Get(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+		return "value", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, StatusMiss, res.Status)
+}
+
+func TestMaxEntriesEvictsOldest(t *testing.T) {
+	c, now := newTestCache(t, 2)
+
+	c.Set("a", 1)
+	*now = now.Add(time.Second)
+	c.Set("b", 2)
+	*now = now.Add(time.Second)
+	c.Set("c", 3)
+
+	assert.Len(t, c.entries, 2)
+	assert.NotContains(t, c.entries, "a")
+}
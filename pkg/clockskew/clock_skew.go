@@ -0,0 +1,111 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clockskew
+
+import (
+	"sort"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// Adjust shifts the timestamps of the spans of a trace that start before their parent, which happens when the
+// clocks of the hosts emitting them are skewed, and returns the adjustments made.
+// A skewed span is shifted so that it's centered within its parent (or starts with it, if it's longer than its parent),
+// and its descendants are shifted along with it, as they were timed by the same clock.
+// The spans are modified in place.
+func Adjust(spans []*internalspan.InternalSpan) []spansquery.ClockSkewAdjustment {
+	spansById := make(map[string]*internalspan.InternalSpan, len(spans))
+	for _, span := range spans {
+		spansById[span.Span.SpanId] = span
+	}
+
+	children := make(map[string][]*internalspan.InternalSpan)
+	var roots []*internalspan.InternalSpan
+	for _, span := range spans {
+		if _, ok := spansById[span.Span.ParentSpanId]; ok && span.Span.ParentSpanId != span.Span.SpanId {
+			children[span.Span.ParentSpanId] = append(children[span.Span.ParentSpanId], span)
+		} else {
+			roots = append(roots, span)
+		}
+	}
+
+	adjustments := []spansquery.ClockSkewAdjustment{}
+	visited := make(map[string]bool, len(spans))
+	var adjustSubtree func(parent *internalspan.InternalSpan, offset uint64)
+	adjustSubtree = func(parent *internalspan.InternalSpan, offset uint64) {
+		for _, child := range children[parent.Span.SpanId] {
+			// guards against cycles of corrupted parent IDs
+			if visited[child.Span.SpanId] {
+				continue
+			}
+			visited[child.Span.SpanId] = true
+
+			childOffset := offset + skew(parent.Span, child.Span, offset)
+			if childOffset > 0 {
+				shift(child, childOffset)
+				adjustments = append(adjustments, spansquery.ClockSkewAdjustment{
+					SpanId:         child.Span.SpanId,
+					AdjustmentNano: childOffset,
+				})
+			}
+			adjustSubtree(child, childOffset)
+		}
+	}
+	for _, root := range roots {
+		visited[root.Span.SpanId] = true
+		adjustSubtree(root, 0)
+	}
+
+	sort.Slice(adjustments, func(i, j int) bool {
+		return adjustments[i].SpanId < adjustments[j].SpanId
+	})
+	return adjustments
+}
+
+// skew returns the duration to add to the child timestamps, once shifted by offset, so it doesn't start before its
+// (already adjusted) parent.
+func skew(parent *internalspan.Span, child *internalspan.Span, offset uint64) uint64 {
+	childStart := child.StartTimeUnixNano + offset
+	if childStart >= parent.StartTimeUnixNano {
+		return 0
+	}
+
+	// the latency between the parent and the child is unknown, it's assumed to be equal before and after the child
+	var latency uint64
+	parentDuration := spanDuration(parent)
+	childDuration := spanDuration(child)
+	if childDuration < parentDuration {
+		latency = (parentDuration - childDuration) / 2
+	}
+	return parent.StartTimeUnixNano + latency - childStart
+}
+
+func spanDuration(span *internalspan.Span) uint64 {
+	if span.EndTimeUnixNano < span.StartTimeUnixNano {
+		return 0
+	}
+	return span.EndTimeUnixNano - span.StartTimeUnixNano
+}
+
+func shift(span *internalspan.InternalSpan, offset uint64) {
+	span.Span.StartTimeUnixNano += offset
+	span.Span.EndTimeUnixNano += offset
+	for _, event := range span.Span.Events {
+		event.TimeUnixNano += offset
+	}
+}
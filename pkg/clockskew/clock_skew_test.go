@@ -0,0 +1,94 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clockskew
+
+import (
+	"testing"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSpan(spanId string, parentSpanId string, start uint64, end uint64) *internalspan.InternalSpan {
+	return &internalspan.InternalSpan{
+		Span: &internalspan.Span{
+			SpanId:            spanId,
+			ParentSpanId:      parentSpanId,
+			StartTimeUnixNano: start,
+			EndTimeUnixNano:   end,
+		},
+	}
+}
+
+func TestAdjustCentersSkewedChildWithinParent(t *testing.T) {
+	root := newSpan("root", "", 1000, 2000)
+	// the child host clock is 500ns behind, the child and its descendants are shifted
+	child := newSpan("child", "root", 700, 1100)
+	child.Span.Events = []*internalspan.SpanEvent{{TimeUnixNano: 800}}
+	grandchild := newSpan("grandchild", "child", 750, 850)
+	sibling := newSpan("sibling", "root", 1100, 1200)
+
+	adjustments := Adjust([]*internalspan.InternalSpan{root, child, grandchild, sibling})
+
+	assert.Equal(t, []spansquery.ClockSkewAdjustment{
+		{SpanId: "child", AdjustmentNano: 600},
+		{SpanId: "grandchild", AdjustmentNano: 600},
+	}, adjustments)
+	assert.Equal(t, uint64(1300), child.Span.StartTimeUnixNano)
+	assert.Equal(t, uint64(1700), child.Span.EndTimeUnixNano)
+	assert.Equal(t, uint64(1400), child.Span.Events[0].TimeUnixNano)
+	assert.Equal(t, uint64(1350), grandchild.Span.StartTimeUnixNano)
+	assert.Equal(t, uint64(1100), sibling.Span.StartTimeUnixNano)
+	assert.Equal(t, uint64(1000), root.Span.StartTimeUnixNano)
+}
+
+func TestAdjustAlignsChildLongerThanParent(t *testing.T) {
+	root := newSpan("root", "", 1000, 1100)
+	child := newSpan("child", "root", 900, 1200)
+
+	adjustments := Adjust([]*internalspan.InternalSpan{child, root})
+
+	assert.Equal(t, []spansquery.ClockSkewAdjustment{{SpanId: "child", AdjustmentNano: 100}}, adjustments)
+	assert.Equal(t, uint64(1000), child.Span.StartTimeUnixNano)
+}
+
+func TestAdjustNestedSkew(t *testing.T) {
+	root := newSpan("root", "", 1000, 2000)
+	child := newSpan("child", "root", 500, 1500)
+	// skewed relatively to its parent as well, once the parent is shifted
+	grandchild := newSpan("grandchild", "child", 200, 400)
+
+	adjustments := Adjust([]*internalspan.InternalSpan{root, child, grandchild})
+
+	assert.Equal(t, []spansquery.ClockSkewAdjustment{
+		{SpanId: "child", AdjustmentNano: 500},
+		{SpanId: "grandchild", AdjustmentNano: 1200},
+	}, adjustments)
+	assert.Equal(t, uint64(1400), grandchild.Span.StartTimeUnixNano)
+}
+
+func TestAdjustWithoutSkew(t *testing.T) {
+	root := newSpan("root", "", 1000, 2000)
+	child := newSpan("child", "root", 1100, 1900)
+	// the parent of an orphan span is missing, so it can't be adjusted
+	orphan := newSpan("orphan", "missing", 10, 20)
+
+	assert.Empty(t, Adjust([]*internalspan.InternalSpan{root, child, orphan}))
+	assert.Equal(t, uint64(10), orphan.Span.StartTimeUnixNano)
+}
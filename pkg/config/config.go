@@ -18,7 +18,10 @@ package config
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 )
 
@@ -27,15 +30,345 @@ const (
 	configFileExt  = "yaml"
 	configPath     = "."
 
+	// envPrefix namespaces the env variables of config options, avoiding collisions in shared environments
+	envPrefix = "TELETRACE_"
+
 	debugEnvName = "DEBUG"
 	debugDefault = true
 
+	logLevelEnvName = "LOG_LEVEL"
+	logLevelDefault = ""
+
 	apiPortEnvName = "API_PORT"
 	apiPortDefault = 8080
 
+	adminPortEnvName = "ADMIN_PORT"
+	adminPortDefault = 8081
+
+	adminHostEnvName = "ADMIN_HOST"
+	adminHostDefault = "127.0.0.1"
+
+	adminPprofEnabledEnvName = "ADMIN_PPROF_ENABLED"
+	adminPprofEnabledDefault = false
+
+	adminLogLevelEnabledEnvName = "ADMIN_LOG_LEVEL_ENABLED"
+	adminLogLevelEnabledDefault = false
+
+	apiCacheModeEnvName = "API_CACHE_MODE"
+	apiCacheModeDefault = "none"
+
+	apiCacheTTLSecondsEnvName = "API_CACHE_TTL_SECONDS"
+	apiCacheTTLSecondsDefault = 30
+
+	apiCacheMaxStalenessSecondsEnvName = "API_CACHE_MAX_STALENESS_SECONDS"
+	apiCacheMaxStalenessSecondsDefault = 300
+
+	apiCacheMaxEntriesEnvName = "API_CACHE_MAX_ENTRIES"
+	apiCacheMaxEntriesDefault = 1000
+
+	apiSearchCacheEnabledEnvName = "API_SEARCH_CACHE_ENABLED"
+	apiSearchCacheEnabledDefault = false
+
+	apiSearchCacheTTLSecondsEnvName = "API_SEARCH_CACHE_TTL_SECONDS"
+	apiSearchCacheTTLSecondsDefault = 300
+
+	apiSearchCacheMaxEntriesEnvName = "API_SEARCH_CACHE_MAX_ENTRIES"
+	apiSearchCacheMaxEntriesDefault = 500
+
+	apiSearchCacheSettleSecondsEnvName = "API_SEARCH_CACHE_SETTLE_SECONDS"
+	apiSearchCacheSettleSecondsDefault = 60
+
+	apiTagValuesCacheEnabledEnvName = "API_TAG_VALUES_CACHE_ENABLED"
+	apiTagValuesCacheEnabledDefault = false
+
+	apiTagValuesCacheTTLSecondsEnvName = "API_TAG_VALUES_CACHE_TTL_SECONDS"
+	apiTagValuesCacheTTLSecondsDefault = 15
+
+	apiTagValuesCacheMaxEntriesEnvName = "API_TAG_VALUES_CACHE_MAX_ENTRIES"
+	apiTagValuesCacheMaxEntriesDefault = 1000
+
+	apiTagValuesCacheTimeBucketSecondsEnvName = "API_TAG_VALUES_CACHE_TIME_BUCKET_SECONDS"
+	apiTagValuesCacheTimeBucketSecondsDefault = 15
+
+	apiSearchDefaultLimitEnvName = "API_SEARCH_DEFAULT_LIMIT"
+	apiSearchDefaultLimitDefault = 200
+
+	apiSearchMaxLimitEnvName = "API_SEARCH_MAX_LIMIT"
+	apiSearchMaxLimitDefault = 1000
+
+	apiExportMaxSpansEnvName = "API_EXPORT_MAX_SPANS"
+	apiExportMaxSpansDefault = 1000000
+
+	apiTagsDefaultLimitEnvName = "API_TAGS_DEFAULT_LIMIT"
+	apiTagsDefaultLimitDefault = 1000
+
+	apiTagsMaxLimitEnvName = "API_TAGS_MAX_LIMIT"
+	apiTagsMaxLimitDefault = 10000
+
+	apiTagValuesDefaultLimitEnvName = "API_TAG_VALUES_DEFAULT_LIMIT"
+	apiTagValuesDefaultLimitDefault = 100
+
+	apiTagValuesMaxLimitEnvName = "API_TAG_VALUES_MAX_LIMIT"
+	apiTagValuesMaxLimitDefault = 1000
+
+	apiMaxConcurrentQueriesEnvName = "API_MAX_CONCURRENT_QUERIES"
+	apiMaxConcurrentQueriesDefault = 0
+
+	apiQueryQueueSizeEnvName = "API_QUERY_QUEUE_SIZE"
+	apiQueryQueueSizeDefault = 100
+
+	apiQueryQueueTimeoutSecondsEnvName = "API_QUERY_QUEUE_TIMEOUT_SECONDS"
+	apiQueryQueueTimeoutSecondsDefault = 10
+
+	apiRateLimitRequestsPerSecondEnvName = "API_RATE_LIMIT_REQUESTS_PER_SECOND"
+	apiRateLimitRequestsPerSecondDefault = 0
+
+	apiRateLimitBurstEnvName = "API_RATE_LIMIT_BURST"
+	apiRateLimitBurstDefault = 0
+
+	apiRateLimitKeyHeaderEnvName = "API_RATE_LIMIT_KEY_HEADER"
+	apiRateLimitKeyHeaderDefault = "X-API-Key"
+
+	apiRateLimitOverridesEnvName = "API_RATE_LIMIT_OVERRIDES"
+	apiRateLimitOverridesDefault = ""
+
+	apiCORSAllowedOriginsEnvName = "API_CORS_ALLOWED_ORIGINS"
+	apiCORSAllowedOriginsDefault = "*"
+
+	apiCORSAllowedMethodsEnvName = "API_CORS_ALLOWED_METHODS"
+	apiCORSAllowedMethodsDefault = "GET,POST,PUT,PATCH,DELETE"
+
+	apiCORSAllowedHeadersEnvName = "API_CORS_ALLOWED_HEADERS"
+	apiCORSAllowedHeadersDefault = "Content-Type"
+
+	apiCORSAllowCredentialsEnvName = "API_CORS_ALLOW_CREDENTIALS"
+	apiCORSAllowCredentialsDefault = false
+
+	apiCORSMaxAgeSecondsEnvName = "API_CORS_MAX_AGE_SECONDS"
+	apiCORSMaxAgeSecondsDefault = 43200
+
+	apiSecurityHeadersEnabledEnvName = "API_SECURITY_HEADERS_ENABLED"
+	apiSecurityHeadersEnabledDefault = true
+
+	apiFrameOptionsEnvName = "API_FRAME_OPTIONS"
+	apiFrameOptionsDefault = "SAMEORIGIN"
+
+	apiContentSecurityPolicyEnvName = "API_CONTENT_SECURITY_POLICY"
+	apiContentSecurityPolicyDefault = ""
+
+	apiHSTSMaxAgeSecondsEnvName = "API_HSTS_MAX_AGE_SECONDS"
+	apiHSTSMaxAgeSecondsDefault = 0
+
+	apiCompressionEnabledEnvName = "API_COMPRESSION_ENABLED"
+	apiCompressionEnabledDefault = true
+
+	apiCompressionMinSizeBytesEnvName = "API_COMPRESSION_MIN_SIZE_BYTES"
+	apiCompressionMinSizeBytesDefault = 1024
+
+	apiWarmUpEnabledEnvName = "API_WARMUP_ENABLED"
+	apiWarmUpEnabledDefault = false
+
+	apiWarmUpTagsEnvName = "API_WARMUP_TAGS"
+	apiWarmUpTagsDefault = "resource.attributes.service.name"
+
+	apiWarmUpTimeframeMinutesEnvName = "API_WARMUP_TIMEFRAME_MINUTES"
+	apiWarmUpTimeframeMinutesDefault = 60
+
+	apiWarmUpPopularTagsEnvName = "API_WARMUP_POPULAR_TAGS"
+	apiWarmUpPopularTagsDefault = 5
+
+	apiPercentileThresholdTTLSecondsEnvName = "API_PERCENTILE_THRESHOLD_TTL_SECONDS"
+	apiPercentileThresholdTTLSecondsDefault = 60
+
+	apiClockSkewAdjustmentEnabledEnvName = "API_CLOCK_SKEW_ADJUSTMENT_ENABLED"
+	apiClockSkewAdjustmentEnabledDefault = true
+
+	apiOTLPEnumNamesEnvName = "API_OTLP_ENUM_NAMES"
+	apiOTLPEnumNamesDefault = false
+
+	apiLogLinkTemplatesEnvName = "API_LOG_LINK_TEMPLATES"
+	apiLogLinkTemplatesDefault = ""
+
+	apiMetricsEnabledEnvName = "API_METRICS_ENABLED"
+	apiMetricsEnabledDefault = true
+
+	selfTracingEnabledEnvName = "SELF_TRACING_ENABLED"
+	selfTracingEnabledDefault = false
+
+	selfTracingEndpointEnvName = "SELF_TRACING_ENDPOINT"
+	selfTracingEndpointDefault = "http://localhost:4318"
+
+	selfTracingSampleRatioEnvName = "SELF_TRACING_SAMPLE_RATIO"
+	selfTracingSampleRatioDefault = 1.0
+
 	spansStoragePluginEnvName = "SPANS_STORAGE_PLUGIN"
 	spansStoragePluginDefault = "elasticsearch"
 
+	storageQueryTimeoutSecondsEnvName = "STORAGE_QUERY_TIMEOUT_SECONDS"
+	storageQueryTimeoutSecondsDefault = 30
+
+	storageSlowQueryThresholdMillisecondsEnvName = "STORAGE_SLOW_QUERY_THRESHOLD_MILLISECONDS"
+	storageSlowQueryThresholdMillisecondsDefault = 5000
+
+	storageMigrationTargetPluginEnvName = "STORAGE_MIGRATION_TARGET_PLUGIN"
+	storageMigrationTargetPluginDefault = ""
+
+	storageMigrationTargetSettingsEnvName = "STORAGE_MIGRATION_TARGET_SETTINGS"
+	storageMigrationTargetSettingsDefault = ""
+
+	storageMigrationCutoverEnvName = "STORAGE_MIGRATION_CUTOVER"
+	storageMigrationCutoverDefault = false
+
+	storageCircuitBreakerEnabledEnvName = "STORAGE_CIRCUIT_BREAKER_ENABLED"
+	storageCircuitBreakerEnabledDefault = false
+
+	storageCircuitBreakerFailureThresholdEnvName = "STORAGE_CIRCUIT_BREAKER_FAILURE_THRESHOLD"
+	storageCircuitBreakerFailureThresholdDefault = 5
+
+	storageCircuitBreakerOpenSecondsEnvName = "STORAGE_CIRCUIT_BREAKER_OPEN_SECONDS"
+	storageCircuitBreakerOpenSecondsDefault = 30
+
+	readDedupModeEnvName = "READ_DEDUP_MODE"
+	readDedupModeDefault = ""
+
+	serviceAliasesEnvName = "SERVICE_ALIASES"
+	serviceAliasesDefault = ""
+
+	aclPolicyFileEnvName = "ACL_POLICY_FILE"
+	aclPolicyFileDefault = ""
+
+	aclRoleHeaderEnvName = "ACL_ROLE_HEADER"
+	aclRoleHeaderDefault = "X-Teletrace-Role"
+
+	apiUserHeaderEnvName = "API_USER_HEADER"
+	apiUserHeaderDefault = "X-Teletrace-User"
+
+	queryHistorySizeEnvName = "QUERY_HISTORY_SIZE"
+	queryHistorySizeDefault = 50
+
+	auditLogEnabledEnvName = "AUDIT_LOG_ENABLED"
+	auditLogEnabledDefault = false
+
+	auditLogAPIKeyHeaderEnvName = "AUDIT_LOG_API_KEY_HEADER"
+	auditLogAPIKeyHeaderDefault = "X-API-Key"
+
+	nPlusOneMinRepetitionsEnvName = "N_PLUS_ONE_MIN_REPETITIONS"
+	nPlusOneMinRepetitionsDefault = 10
+
+	nPlusOneMaxChildDurationMillisecondsEnvName = "N_PLUS_ONE_MAX_CHILD_DURATION_MILLISECONDS"
+	nPlusOneMaxChildDurationMillisecondsDefault = 50
+
+	nPlusOneMaxTracesEnvName = "N_PLUS_ONE_MAX_TRACES"
+	nPlusOneMaxTracesDefault = 100
+
+	incompleteTracesMaxSpansEnvName = "INCOMPLETE_TRACES_MAX_SPANS"
+	incompleteTracesMaxSpansDefault = 10000
+
+	incompleteTracesMaxTracesEnvName = "INCOMPLETE_TRACES_MAX_TRACES"
+	incompleteTracesMaxTracesDefault = 100
+
+	incompleteTracesGracePeriodSecondsEnvName = "INCOMPLETE_TRACES_GRACE_PERIOD_SECONDS"
+	incompleteTracesGracePeriodSecondsDefault = 60
+
+	errorGroupsMaxSpansEnvName = "ERROR_GROUPS_MAX_SPANS"
+	errorGroupsMaxSpansDefault = 10000
+
+	ingestionLagMaxSpansEnvName = "INGESTION_LAG_MAX_SPANS"
+	ingestionLagMaxSpansDefault = 10000
+
+	flamegraphMaxTracesEnvName = "FLAMEGRAPH_MAX_TRACES"
+	flamegraphMaxTracesDefault = 100
+
+	traceSummariesMaxTracesEnvName = "TRACE_SUMMARIES_MAX_TRACES"
+	traceSummariesMaxTracesDefault = 100
+
+	alertsEnabledEnvName = "ALERTS_ENABLED"
+	alertsEnabledDefault = false
+
+	alertsEvaluationIntervalSecondsEnvName = "ALERTS_EVALUATION_INTERVAL_SECONDS"
+	alertsEvaluationIntervalSecondsDefault = 60
+
+	alertsUIBaseURLEnvName = "ALERTS_UI_BASE_URL"
+	alertsUIBaseURLDefault = "http://localhost:8080"
+
+	alertsNotificationMaxAttemptsEnvName = "ALERTS_NOTIFICATION_MAX_ATTEMPTS"
+	alertsNotificationMaxAttemptsDefault = 5
+
+	alertsNotificationTimeoutSecondsEnvName = "ALERTS_NOTIFICATION_TIMEOUT_SECONDS"
+	alertsNotificationTimeoutSecondsDefault = 10
+
+	anomaliesEnabledEnvName = "ANOMALIES_ENABLED"
+	anomaliesEnabledDefault = false
+
+	anomaliesIntervalSecondsEnvName = "ANOMALIES_INTERVAL_SECONDS"
+	anomaliesIntervalSecondsDefault = 900
+
+	anomaliesWindowMinutesEnvName = "ANOMALIES_WINDOW_MINUTES"
+	anomaliesWindowMinutesDefault = 60
+
+	anomaliesBaselineOffsetHoursEnvName = "ANOMALIES_BASELINE_OFFSET_HOURS"
+	anomaliesBaselineOffsetHoursDefault = 168
+
+	anomaliesMinSpansEnvName = "ANOMALIES_MIN_SPANS"
+	anomaliesMinSpansDefault = 100
+
+	anomaliesRegressionRatioEnvName = "ANOMALIES_REGRESSION_RATIO"
+	anomaliesRegressionRatioDefault = 2.0
+
+	anomaliesMaxOperationsEnvName = "ANOMALIES_MAX_OPERATIONS"
+	anomaliesMaxOperationsDefault = 200
+
+	metricCorrelationPrometheusURLEnvName = "METRIC_CORRELATION_PROMETHEUS_URL"
+	metricCorrelationPrometheusURLDefault = ""
+
+	metricCorrelationTimeoutSecondsEnvName = "METRIC_CORRELATION_TIMEOUT_SECONDS"
+	metricCorrelationTimeoutSecondsDefault = 10
+
+	metricCorrelationMaxSpansEnvName = "METRIC_CORRELATION_MAX_SPANS"
+	metricCorrelationMaxSpansDefault = 10000
+
+	sidecarStoreTypeEnvName = "SIDECAR_STORE_TYPE"
+	sidecarStoreTypeDefault = ""
+
+	sidecarDirectoryEnvName = "SIDECAR_DIRECTORY"
+	sidecarDirectoryDefault = ""
+
+	sidecarS3BucketEnvName = "SIDECAR_S3_BUCKET"
+	sidecarS3BucketDefault = ""
+
+	sidecarS3RegionEnvName = "SIDECAR_S3_REGION"
+	sidecarS3RegionDefault = ""
+
+	sidecarS3PrefixEnvName = "SIDECAR_S3_PREFIX"
+	sidecarS3PrefixDefault = ""
+
+	sidecarS3EndpointEnvName = "SIDECAR_S3_ENDPOINT"
+	sidecarS3EndpointDefault = ""
+
+	archiveStoreTypeEnvName = "ARCHIVE_STORE_TYPE"
+	archiveStoreTypeDefault = ""
+
+	archiveDirectoryEnvName = "ARCHIVE_DIRECTORY"
+	archiveDirectoryDefault = ""
+
+	archiveS3BucketEnvName = "ARCHIVE_S3_BUCKET"
+	archiveS3BucketDefault = ""
+
+	archiveS3RegionEnvName = "ARCHIVE_S3_REGION"
+	archiveS3RegionDefault = ""
+
+	archiveS3PrefixEnvName = "ARCHIVE_S3_PREFIX"
+	archiveS3PrefixDefault = ""
+
+	archiveS3EndpointEnvName = "ARCHIVE_S3_ENDPOINT"
+	archiveS3EndpointDefault = ""
+
+	archiveMaxTracesEnvName = "ARCHIVE_MAX_TRACES"
+	archiveMaxTracesDefault = 100
+
+	archiveRestoreEndpointEnvName = "ARCHIVE_RESTORE_ENDPOINT"
+	archiveRestoreEndpointDefault = "http://localhost:4318/v1/traces"
+
 	esEndpointEnvName = "ES_ENDPOINT"
 	esEndpointDefault = "http://0.0.0.0:9200"
 
@@ -48,15 +381,60 @@ const (
 	esApiKeyEnvName = "ES_API_KEY"
 	esApiKeyDefault = ""
 
+	esApiKeyFileEnvName = "ES_API_KEY_FILE"
+	esApiKeyFileDefault = ""
+
+	esWriteEndpointEnvName = "ES_WRITE_ENDPOINT"
+	esWriteEndpointDefault = ""
+
+	esWriteUsernameEnvName = "ES_WRITE_USERNAME"
+	esWriteUsernameDefault = ""
+
+	esWritePasswordEnvName = "ES_WRITE_PASSWORD"
+	esWritePasswordDefault = ""
+
+	esWriteApiKeyEnvName = "ES_WRITE_API_KEY"
+	esWriteApiKeyDefault = ""
+
+	esWriteApiKeyFileEnvName = "ES_WRITE_API_KEY_FILE"
+	esWriteApiKeyFileDefault = ""
+
 	esServiceTokenEnvName = "ES_SERVICE_TOKEN"
 	esServiceTokenDefault = ""
 
+	esServiceTokenFileEnvName = "ES_SERVICE_TOKEN_FILE"
+	esServiceTokenFileDefault = ""
+
+	esTLSCertFileEnvName = "ES_TLS_CERT_FILE"
+	esTLSCertFileDefault = ""
+
+	esTLSKeyFileEnvName = "ES_TLS_KEY_FILE"
+	esTLSKeyFileDefault = ""
+
+	esTLSCAFileEnvName = "ES_TLS_CA_FILE"
+	esTLSCAFileDefault = ""
+
+	esTLSInsecureSkipVerifyEnvName = "ES_TLS_INSECURE_SKIP_VERIFY"
+	esTLSInsecureSkipVerifyDefault = false
+
+	esDistributionEnvName = "ES_DISTRIBUTION"
+	esDistributionDefault = "elasticsearch"
+
+	esAWSSigV4EnvName = "ES_AWS_SIGV4"
+	esAWSSigV4Default = false
+
+	esAWSRegionEnvName = "ES_AWS_REGION"
+	esAWSRegionDefault = ""
+
 	esForceCreateConfigEnvName = "ES_FORCE_CREATE_CONFIG"
 	esForceCreateConfigDefault = false
 
 	esIndexEnvName = "ES_INDEX"
 	esIndexDefault = "teletrace-traces"
 
+	esRemoteIndicesEnvName = "ES_REMOTE_INDICES"
+	esRemoteIndicesDefault = ""
+
 	esIndexerWorkersCountEnvName = "ES_INDEXER_WORKERS_COUNT"
 	esIndexerWorkersCountDefault = 5
 
@@ -65,31 +443,355 @@ const (
 
 	sqlitePathEnvName        = "SQLITE_PATH"
 	sqlitePathEnvNameDefault = "embedded_spans.db"
+
+	sqliteShardByEnvName = "SQLITE_SHARD_BY"
+	sqliteShardByDefault = ""
+
+	sqliteShardsEnvName = "SQLITE_SHARDS"
+	sqliteShardsDefault = 8
+
+	cassandraHostsEnvName = "CASSANDRA_HOSTS"
+	cassandraHostsDefault = "127.0.0.1"
+
+	cassandraKeyspaceEnvName = "CASSANDRA_KEYSPACE"
+	cassandraKeyspaceDefault = "teletrace"
+
+	cassandraUsernameEnvName = "CASSANDRA_USERNAME"
+	cassandraUsernameDefault = ""
+
+	cassandraPasswordEnvName = "CASSANDRA_PASSWORD"
+	cassandraPasswordDefault = ""
+
+	cassandraConsistencyEnvName = "CASSANDRA_CONSISTENCY"
+	cassandraConsistencyDefault = "LOCAL_ONE"
+
+	cassandraMaxScannedSpansEnvName = "CASSANDRA_MAX_SCANNED_SPANS"
+	cassandraMaxScannedSpansDefault = 10000
+
+	cassandraMaxLookbackHoursEnvName = "CASSANDRA_MAX_LOOKBACK_HOURS"
+	cassandraMaxLookbackHoursDefault = 168
+
+	badgerDirectoryEnvName = "BADGER_DIRECTORY"
+	badgerDirectoryDefault = "embedded_spans_badger"
+
+	badgerMaxScannedSpansEnvName = "BADGER_MAX_SCANNED_SPANS"
+	badgerMaxScannedSpansDefault = 10000
+
+	parquetDirectoryEnvName = "PARQUET_DIRECTORY"
+	parquetDirectoryDefault = "embedded_spans_parquet"
+
+	parquetMaxScannedSpansEnvName = "PARQUET_MAX_SCANNED_SPANS"
+	parquetMaxScannedSpansDefault = 10000
+
+	grpcPluginPathEnvName = "GRPC_PLUGIN_PATH"
+	grpcPluginPathDefault = ""
+
+	grpcPluginStartTimeoutSecondsEnvName = "GRPC_PLUGIN_START_TIMEOUT_SECONDS"
+	grpcPluginStartTimeoutSecondsDefault = 30
+
+	federationConfigFileEnvName = "FEDERATION_CONFIG_FILE"
+	federationConfigFileDefault = ""
+
+	metadataSQLitePathEnvName = "METADATA_SQLITE_PATH"
+	metadataSQLitePathDefault = ""
+
+	metadataPostgresDSNEnvName = "METADATA_POSTGRES_DSN"
+	metadataPostgresDSNDefault = ""
 )
 
 // Config defines global configurations used throughout the application.
 type Config struct {
 	Debug              bool   `mapstructure:"debug"`
+	LogLevel           string `mapstructure:"log_level" reloadable:"true"`
 	APIPort            int    `mapstructure:"api_port"`
 	SpansStoragePlugin string `mapstructure:"spans_storage_plugin"`
 
+	// Admin server configs, the admin port serves operational endpoints which shouldn't be exposed publicly,
+	// it's bound to the loopback address by default
+	AdminHost            string `mapstructure:"admin_host"`
+	AdminPort            int    `mapstructure:"admin_port"`
+	AdminPprofEnabled    bool   `mapstructure:"admin_pprof_enabled"`
+	AdminLogLevelEnabled bool   `mapstructure:"admin_log_level_enabled"`
+
+	// API cache and warm-up configs
+	APICacheMode                string `mapstructure:"api_cache_mode"`
+	APICacheTTLSeconds          int    `mapstructure:"api_cache_ttl_seconds"`
+	APICacheMaxStalenessSeconds int    `mapstructure:"api_cache_max_staleness_seconds"`
+	APICacheMaxEntries          int    `mapstructure:"api_cache_max_entries"`
+	// APISearchCache* cache the results of searches whose time range ended at least APISearchCacheSettleSeconds ago
+	APISearchCacheEnabled       bool `mapstructure:"api_search_cache_enabled"`
+	APISearchCacheTTLSeconds    int  `mapstructure:"api_search_cache_ttl_seconds"`
+	APISearchCacheMaxEntries    int  `mapstructure:"api_search_cache_max_entries"`
+	APISearchCacheSettleSeconds int  `mapstructure:"api_search_cache_settle_seconds"`
+	// APITagValuesCache* cache tag values per tag, time bucket and filters, for autocomplete
+	APITagValuesCacheEnabled           bool   `mapstructure:"api_tag_values_cache_enabled"`
+	APITagValuesCacheTTLSeconds        int    `mapstructure:"api_tag_values_cache_ttl_seconds"`
+	APITagValuesCacheMaxEntries        int    `mapstructure:"api_tag_values_cache_max_entries"`
+	APITagValuesCacheTimeBucketSeconds int    `mapstructure:"api_tag_values_cache_time_bucket_seconds"`
+	APIWarmUpEnabled                   bool   `mapstructure:"api_warmup_enabled"`
+	APIWarmUpTags                      string `mapstructure:"api_warmup_tags"`
+	APIWarmUpTimeframeMinutes          int    `mapstructure:"api_warmup_timeframe_minutes"`
+	APIWarmUpPopularTags               int    `mapstructure:"api_warmup_popular_tags"`
+	// APIPercentileThresholdTTLSeconds is for how long the thresholds of percentile filters are reused, 0 to compute
+	// them for every request
+	APIPercentileThresholdTTLSeconds int `mapstructure:"api_percentile_threshold_ttl_seconds"`
+
+	// API result size limits, requests without a limit get the default and larger limits are capped at the maximum
+	APISearchDefaultLimit    int `mapstructure:"api_search_default_limit"`
+	APISearchMaxLimit        int `mapstructure:"api_search_max_limit"`
+	APIExportMaxSpans        int `mapstructure:"api_export_max_spans"`
+	APITagsDefaultLimit      int `mapstructure:"api_tags_default_limit"`
+	APITagsMaxLimit          int `mapstructure:"api_tags_max_limit"`
+	APITagValuesDefaultLimit int `mapstructure:"api_tag_values_default_limit"`
+	APITagValuesMaxLimit     int `mapstructure:"api_tag_values_max_limit"`
+
+	// API admission control configs, limiting the concurrent query requests (0 disables the limit)
+	APIMaxConcurrentQueries     int `mapstructure:"api_max_concurrent_queries"`
+	APIQueryQueueSize           int `mapstructure:"api_query_queue_size"`
+	APIQueryQueueTimeoutSeconds int `mapstructure:"api_query_queue_timeout_seconds"`
+
+	// API rate limiting configs, limiting the query requests per second of each API key or client (0 disables the limit)
+	APIRateLimitRequestsPerSecond float64 `mapstructure:"api_rate_limit_requests_per_second" reloadable:"true"`
+	APIRateLimitBurst             int     `mapstructure:"api_rate_limit_burst" reloadable:"true"`
+	APIRateLimitKeyHeader         string  `mapstructure:"api_rate_limit_key_header"`
+	APIRateLimitOverrides         string  `mapstructure:"api_rate_limit_overrides" reloadable:"true"`
+
+	// API CORS configs, comma separated lists allowing a UI hosted on another domain to call the API
+	APICORSAllowedOrigins   string `mapstructure:"api_cors_allowed_origins"`
+	APICORSAllowedMethods   string `mapstructure:"api_cors_allowed_methods"`
+	APICORSAllowedHeaders   string `mapstructure:"api_cors_allowed_headers"`
+	APICORSAllowCredentials bool   `mapstructure:"api_cors_allow_credentials"`
+	APICORSMaxAgeSeconds    int    `mapstructure:"api_cors_max_age_seconds"`
+
+	// API security headers configs, empty values (and a zero HSTS max age) omit their header
+	APISecurityHeadersEnabled bool   `mapstructure:"api_security_headers_enabled"`
+	APIFrameOptions           string `mapstructure:"api_frame_options"`
+	APIContentSecurityPolicy  string `mapstructure:"api_content_security_policy"`
+	APIHSTSMaxAgeSeconds      int    `mapstructure:"api_hsts_max_age_seconds"`
+
+	// API response compression configs, negotiating zstd or gzip with the Accept-Encoding request header
+	APICompressionEnabled      bool `mapstructure:"api_compression_enabled"`
+	APICompressionMinSizeBytes int  `mapstructure:"api_compression_min_size_bytes"`
+
+	// APIMetricsEnabled exposes the metrics of the API, storage queries and exporters in Prometheus format on /metrics
+	// of the admin port
+	APIMetricsEnabled bool `mapstructure:"api_metrics_enabled"`
+
+	// Self tracing configs, for tracing the API and storage queries of teletrace with OpenTelemetry
+	SelfTracingEnabled     bool    `mapstructure:"self_tracing_enabled"`
+	SelfTracingEndpoint    string  `mapstructure:"self_tracing_endpoint"`
+	SelfTracingSampleRatio float64 `mapstructure:"self_tracing_sample_ratio" reloadable:"true"`
+
+	// APIClockSkewAdjustmentEnabled adjusts the timestamps of spans starting before their parent when returning a trace
+	APIClockSkewAdjustmentEnabled bool `mapstructure:"api_clock_skew_adjustment_enabled"`
+	// APIOTLPEnumNames returns the span kind and status code of spans and of tag values as their OTLP enum names,
+	// e.g. SPAN_KIND_SERVER rather than the stored Server
+	APIOTLPEnumNames bool `mapstructure:"api_otlp_enum_names"`
+	// APILogLinkTemplates is a JSON list of the templates of the links from the spans of a trace to their logs
+	APILogLinkTemplates string `mapstructure:"api_log_link_templates"`
+
+	// StorageQueryTimeoutSeconds bounds each storage query, 0 disables the timeout
+	StorageQueryTimeoutSeconds int `mapstructure:"storage_query_timeout_seconds"`
+	// StorageSlowQueryThresholdMilliseconds logs and counts slower storage queries, 0 disables the slow query log
+	StorageSlowQueryThresholdMilliseconds int `mapstructure:"storage_slow_query_threshold_milliseconds"`
+
+	// Storage migration configs, reading from SPANS_STORAGE_PLUGIN until the cutover and from the target plugin after it,
+	// configured by the global config overridden by the target settings, a JSON object of option names and values
+	StorageMigrationTargetPlugin   string `mapstructure:"storage_migration_target_plugin"`
+	StorageMigrationTargetSettings string `mapstructure:"storage_migration_target_settings"`
+	StorageMigrationCutover        bool   `mapstructure:"storage_migration_cutover" reloadable:"true"`
+
+	// Storage circuit breaker configs
+	StorageCircuitBreakerEnabled          bool `mapstructure:"storage_circuit_breaker_enabled"`
+	StorageCircuitBreakerFailureThreshold int  `mapstructure:"storage_circuit_breaker_failure_threshold"`
+	StorageCircuitBreakerOpenSeconds      int  `mapstructure:"storage_circuit_breaker_open_seconds"`
+
+	// Read deduplication configs
+	ReadDedupMode string `mapstructure:"read_dedup_mode"`
+
+	// Service aliases configs
+	ServiceAliases string `mapstructure:"service_aliases"`
+
+	// Access control configs
+	ACLPolicyFile string `mapstructure:"acl_policy_file"`
+	ACLRoleHeader string `mapstructure:"acl_role_header"`
+
+	// APIUserHeader is the request header holding the authenticated user, set by a trusted authenticating proxy
+	APIUserHeader string `mapstructure:"api_user_header"`
+	// QueryHistorySize is the number of recent searches recorded per user, 0 disables the query history
+	QueryHistorySize int `mapstructure:"query_history_size"`
+	// AuditLogEnabled records the queries, exports and deletions sent to the API in an append-only audit log
+	AuditLogEnabled bool `mapstructure:"audit_log_enabled"`
+	// AuditLogAPIKeyHeader is the request header holding the API key, recorded in the audit log by its fingerprint
+	AuditLogAPIKeyHeader string `mapstructure:"audit_log_api_key_header"`
+
+	// N+1 detector configs
+	NPlusOneMinRepetitions               int `mapstructure:"n_plus_one_min_repetitions"`
+	NPlusOneMaxChildDurationMilliseconds int `mapstructure:"n_plus_one_max_child_duration_milliseconds"`
+	NPlusOneMaxTraces                    int `mapstructure:"n_plus_one_max_traces"`
+
+	// Incomplete traces detector configs
+	IncompleteTracesMaxSpans           int `mapstructure:"incomplete_traces_max_spans"`
+	IncompleteTracesMaxTraces          int `mapstructure:"incomplete_traces_max_traces"`
+	IncompleteTracesGracePeriodSeconds int `mapstructure:"incomplete_traces_grace_period_seconds"`
+
+	// Error analysis configs
+	ErrorGroupsMaxSpans int `mapstructure:"error_groups_max_spans"`
+
+	// Ingestion lag analysis configs
+	IngestionLagMaxSpans int `mapstructure:"ingestion_lag_max_spans"`
+
+	// Flamegraph aggregation configs
+	FlamegraphMaxTraces int `mapstructure:"flamegraph_max_traces"`
+
+	// Trace summaries configs
+	TraceSummariesMaxTraces int `mapstructure:"trace_summaries_max_traces"`
+
+	// Alerting configs, the alert rules are persisted in the metadata store
+	AlertsEnabled                   bool `mapstructure:"alerts_enabled"`
+	AlertsEvaluationIntervalSeconds int  `mapstructure:"alerts_evaluation_interval_seconds"`
+	// AlertsUIBaseURL is the URL the trace links of alert notifications point to
+	AlertsUIBaseURL                  string `mapstructure:"alerts_ui_base_url"`
+	AlertsNotificationMaxAttempts    int    `mapstructure:"alerts_notification_max_attempts"`
+	AlertsNotificationTimeoutSeconds int    `mapstructure:"alerts_notification_timeout_seconds"`
+
+	// Latency anomaly detection configs
+	AnomaliesEnabled             bool    `mapstructure:"anomalies_enabled"`
+	AnomaliesIntervalSeconds     int     `mapstructure:"anomalies_interval_seconds"`
+	AnomaliesWindowMinutes       int     `mapstructure:"anomalies_window_minutes"`
+	AnomaliesBaselineOffsetHours int     `mapstructure:"anomalies_baseline_offset_hours"`
+	AnomaliesMinSpans            int     `mapstructure:"anomalies_min_spans"`
+	AnomaliesRegressionRatio     float64 `mapstructure:"anomalies_regression_ratio"`
+	AnomaliesMaxOperations       int     `mapstructure:"anomalies_max_operations"`
+
+	// Metric to trace correlation configs
+	MetricCorrelationPrometheusURL  string `mapstructure:"metric_correlation_prometheus_url"`
+	MetricCorrelationTimeoutSeconds int    `mapstructure:"metric_correlation_timeout_seconds"`
+	MetricCorrelationMaxSpans       int    `mapstructure:"metric_correlation_max_spans"`
+
+	// Sidecar store configs, holding the full values of truncated span attributes
+	SidecarStoreType  string `mapstructure:"sidecar_store_type"`
+	SidecarDirectory  string `mapstructure:"sidecar_directory"`
+	SidecarS3Bucket   string `mapstructure:"sidecar_s3_bucket"`
+	SidecarS3Region   string `mapstructure:"sidecar_s3_region"`
+	SidecarS3Prefix   string `mapstructure:"sidecar_s3_prefix"`
+	SidecarS3Endpoint string `mapstructure:"sidecar_s3_endpoint"`
+
+	// Archive store configs, holding the traces archived for compliance retention
+	ArchiveStoreType       string `mapstructure:"archive_store_type"`
+	ArchiveDirectory       string `mapstructure:"archive_directory"`
+	ArchiveS3Bucket        string `mapstructure:"archive_s3_bucket"`
+	ArchiveS3Region        string `mapstructure:"archive_s3_region"`
+	ArchiveS3Prefix        string `mapstructure:"archive_s3_prefix"`
+	ArchiveS3Endpoint      string `mapstructure:"archive_s3_endpoint"`
+	ArchiveMaxTraces       int    `mapstructure:"archive_max_traces"`
+	ArchiveRestoreEndpoint string `mapstructure:"archive_restore_endpoint"`
+
 	// Elasticsearch configs
 	ESEndpoints                    string `mapstructure:"es_endpoint"`
 	ESUsername                     string `mapstructure:"es_username"`
-	ESPassword                     string `mapstructure:"es_password"`
-	ESAPIKey                       string `mapstructure:"es_api_key"`
-	ESServiceToken                 string `mapstructure:"es_service_token"`
+	ESPassword                     string `mapstructure:"es_password" secret:"true"`
+	ESAPIKey                       string `mapstructure:"es_api_key" secret:"true"`
+	ESAPIKeyFile                   string `mapstructure:"es_api_key_file"`
+	ESServiceToken                 string `mapstructure:"es_service_token" secret:"true"`
+	ESServiceTokenFile             string `mapstructure:"es_service_token_file"`
+	ESTLSCertFile                  string `mapstructure:"es_tls_cert_file"`
+	ESTLSKeyFile                   string `mapstructure:"es_tls_key_file"`
+	ESTLSCAFile                    string `mapstructure:"es_tls_ca_file"`
+	ESTLSInsecureSkipVerify        bool   `mapstructure:"es_tls_insecure_skip_verify"`
+	ESDistribution                 string `mapstructure:"es_distribution"`
+	ESAWSSigV4                     bool   `mapstructure:"es_aws_sigv4"`
+	ESAWSRegion                    string `mapstructure:"es_aws_region"`
 	ESForceCreateConfig            bool   `mapstructure:"es_force_create_config"`
 	ESIndex                        string `mapstructure:"es_index"`
+	ESRemoteIndices                string `mapstructure:"es_remote_indices"`
 	ESIndexerWorkersCount          int    `mapstructure:"es_indexer_workers_count"`
 	ESIndexerFlushThresholdSeconds int    `mapstructure:"es_indexer_flush_threshold_seconds"`
 	SQLitePath                     string `mapstructure:"sqlite_path"`
+	SQLiteShardBy                  string `mapstructure:"sqlite_shard_by"`
+	SQLiteShards                   int    `mapstructure:"sqlite_shards"`
+
+	// Cassandra configs, of the cassandra spans storage plugin reading the tables written by the cassandra exporter
+	CassandraHosts            string `mapstructure:"cassandra_hosts"`
+	CassandraKeyspace         string `mapstructure:"cassandra_keyspace"`
+	CassandraUsername         string `mapstructure:"cassandra_username"`
+	CassandraPassword         string `mapstructure:"cassandra_password" secret:"true"`
+	CassandraConsistency      string `mapstructure:"cassandra_consistency"`
+	CassandraMaxScannedSpans  int    `mapstructure:"cassandra_max_scanned_spans"`
+	CassandraMaxLookbackHours int    `mapstructure:"cassandra_max_lookback_hours"`
+
+	// Badger configs, of the badger spans storage plugin reading the database written by the badger exporter
+	BadgerDirectory       string `mapstructure:"badger_directory"`
+	BadgerMaxScannedSpans int    `mapstructure:"badger_max_scanned_spans"`
+
+	// Parquet configs, of the parquet spans storage plugin querying the span files written by the parquet exporter
+	ParquetDirectory       string `mapstructure:"parquet_directory"`
+	ParquetMaxScannedSpans int    `mapstructure:"parquet_max_scanned_spans"`
+
+	// Elasticsearch write configs, overriding the endpoint and credentials of the writes made by Teletrace itself,
+	// i.e. of the metadata, so that reads may be served by replicas or with read only credentials
+	ESWriteEndpoint   string `mapstructure:"es_write_endpoint"`
+	ESWriteUsername   string `mapstructure:"es_write_username"`
+	ESWritePassword   string `mapstructure:"es_write_password" secret:"true"`
+	ESWriteAPIKey     string `mapstructure:"es_write_api_key" secret:"true"`
+	ESWriteAPIKeyFile string `mapstructure:"es_write_api_key_file"`
+
+	// gRPC storage plugin configs, used by the grpc spans storage plugin to launch an external plugin binary
+	GRPCPluginPath                string `mapstructure:"grpc_plugin_path"`
+	GRPCPluginStartTimeoutSeconds int    `mapstructure:"grpc_plugin_start_timeout_seconds"`
+
+	// FederationConfigFile configures several storage backends searched as one, instead of SPANS_STORAGE_PLUGIN
+	FederationConfigFile string `mapstructure:"federation_config_file"`
+
+	// Metadata store configs
+	MetadataSQLitePath  string `mapstructure:"metadata_sqlite_path"`
+	MetadataPostgresDSN string `mapstructure:"metadata_postgres_dsn" secret:"true"`
 }
 
 // NewConfig creates and returns a Config based on prioritized sources.
 // default values (lowest priority) < config file < env variables (highest priority)
+// Secret references (file:// or vault://) of the secret settings are resolved to their values.
 func NewConfig() (Config, error) {
 	c := Config{}
+	v, err := newViper()
+	if err != nil {
+		return c, err
+	}
+
+	err = v.Unmarshal(&c)
+	if err != nil {
+		return c, fmt.Errorf("error unmarshaling config to struct: %w", err)
+	}
+
+	if err := resolveSecrets(&c); err != nil {
+		return c, err
+	}
+
+	return c, nil
+}
+
+// WithSettings returns a copy of cfg with the given settings overridden, keyed by their option names, e.g. es_endpoint.
+// It configures storage backends differing from the global config by a few options.
+func (cfg Config) WithSettings(settings map[string]any) (Config, error) {
+	c := cfg
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		ErrorUnused:      true,
+		WeaklyTypedInput: true,
+		Result:           &c,
+	})
+	if err != nil {
+		return cfg, err
+	}
+	if err := decoder.Decode(settings); err != nil {
+		return cfg, fmt.Errorf("error overriding config settings: %w", err)
+	}
+	if err := resolveSecrets(&c); err != nil {
+		return cfg, err
+	}
+	return c, nil
+}
+
+func newViper() (*viper.Viper, error) {
 	v := viper.New()
 
 	setDefaults(v)
@@ -101,35 +803,229 @@ func NewConfig() (Config, error) {
 	err := v.ReadInConfig()
 	if err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return c, fmt.Errorf("error loading config file %s: %w", configPath, err)
+			return nil, fmt.Errorf("error loading config file %s: %w", configPath, err)
 		}
 	}
 
 	// overrides config file with env variables
-	v.AutomaticEnv()
-
-	err = v.Unmarshal(&c)
-	if err != nil {
-		return c, fmt.Errorf("error unmarshaling config to struct: %w", err)
+	if err := bindEnvs(v); err != nil {
+		return nil, err
 	}
 
-	return c, nil
+	return v, nil
+}
+
+// bindEnvs binds every Config field to the env variable named after its option, e.g. ES_PASSWORD,
+// and to the same name with envPrefix, e.g. TELETRACE_ES_PASSWORD, which takes precedence.
+// Binding every field makes it overridable even when it has no default value.
+func bindEnvs(v *viper.Viper) error {
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("mapstructure")
+		option := strings.ToUpper(key)
+		if err := v.BindEnv(key, envPrefix+option, option); err != nil {
+			return fmt.Errorf("error binding env variables of %s: %w", option, err)
+		}
+	}
+	return nil
 }
 
 func setDefaults(v *viper.Viper) {
 	v.SetDefault(debugEnvName, debugDefault)
+	v.SetDefault(logLevelEnvName, logLevelDefault)
 	v.SetDefault(apiPortEnvName, apiPortDefault)
+	v.SetDefault(adminPortEnvName, adminPortDefault)
+	v.SetDefault(adminHostEnvName, adminHostDefault)
+	v.SetDefault(adminPprofEnabledEnvName, adminPprofEnabledDefault)
+	v.SetDefault(adminLogLevelEnabledEnvName, adminLogLevelEnabledDefault)
 	v.SetDefault(spansStoragePluginEnvName, spansStoragePluginDefault)
 
+	// API cache and warm-up defaults
+	v.SetDefault(apiCacheModeEnvName, apiCacheModeDefault)
+	v.SetDefault(apiCacheTTLSecondsEnvName, apiCacheTTLSecondsDefault)
+	v.SetDefault(apiCacheMaxStalenessSecondsEnvName, apiCacheMaxStalenessSecondsDefault)
+	v.SetDefault(apiCacheMaxEntriesEnvName, apiCacheMaxEntriesDefault)
+	v.SetDefault(apiSearchCacheEnabledEnvName, apiSearchCacheEnabledDefault)
+	v.SetDefault(apiSearchCacheTTLSecondsEnvName, apiSearchCacheTTLSecondsDefault)
+	v.SetDefault(apiSearchCacheMaxEntriesEnvName, apiSearchCacheMaxEntriesDefault)
+	v.SetDefault(apiSearchCacheSettleSecondsEnvName, apiSearchCacheSettleSecondsDefault)
+	v.SetDefault(apiTagValuesCacheEnabledEnvName, apiTagValuesCacheEnabledDefault)
+	v.SetDefault(apiTagValuesCacheTTLSecondsEnvName, apiTagValuesCacheTTLSecondsDefault)
+	v.SetDefault(apiTagValuesCacheMaxEntriesEnvName, apiTagValuesCacheMaxEntriesDefault)
+	v.SetDefault(apiTagValuesCacheTimeBucketSecondsEnvName, apiTagValuesCacheTimeBucketSecondsDefault)
+	v.SetDefault(apiSearchDefaultLimitEnvName, apiSearchDefaultLimitDefault)
+	v.SetDefault(apiSearchMaxLimitEnvName, apiSearchMaxLimitDefault)
+	v.SetDefault(apiExportMaxSpansEnvName, apiExportMaxSpansDefault)
+	v.SetDefault(apiTagsDefaultLimitEnvName, apiTagsDefaultLimitDefault)
+	v.SetDefault(apiTagsMaxLimitEnvName, apiTagsMaxLimitDefault)
+	v.SetDefault(apiTagValuesDefaultLimitEnvName, apiTagValuesDefaultLimitDefault)
+	v.SetDefault(apiTagValuesMaxLimitEnvName, apiTagValuesMaxLimitDefault)
+	v.SetDefault(apiMaxConcurrentQueriesEnvName, apiMaxConcurrentQueriesDefault)
+	v.SetDefault(apiQueryQueueSizeEnvName, apiQueryQueueSizeDefault)
+	v.SetDefault(apiQueryQueueTimeoutSecondsEnvName, apiQueryQueueTimeoutSecondsDefault)
+	v.SetDefault(apiRateLimitRequestsPerSecondEnvName, apiRateLimitRequestsPerSecondDefault)
+	v.SetDefault(apiRateLimitBurstEnvName, apiRateLimitBurstDefault)
+	v.SetDefault(apiRateLimitKeyHeaderEnvName, apiRateLimitKeyHeaderDefault)
+	v.SetDefault(apiRateLimitOverridesEnvName, apiRateLimitOverridesDefault)
+	v.SetDefault(apiCORSAllowedOriginsEnvName, apiCORSAllowedOriginsDefault)
+	v.SetDefault(apiCORSAllowedMethodsEnvName, apiCORSAllowedMethodsDefault)
+	v.SetDefault(apiCORSAllowedHeadersEnvName, apiCORSAllowedHeadersDefault)
+	v.SetDefault(apiCORSAllowCredentialsEnvName, apiCORSAllowCredentialsDefault)
+	v.SetDefault(apiCORSMaxAgeSecondsEnvName, apiCORSMaxAgeSecondsDefault)
+	v.SetDefault(apiSecurityHeadersEnabledEnvName, apiSecurityHeadersEnabledDefault)
+	v.SetDefault(apiFrameOptionsEnvName, apiFrameOptionsDefault)
+	v.SetDefault(apiContentSecurityPolicyEnvName, apiContentSecurityPolicyDefault)
+	v.SetDefault(apiHSTSMaxAgeSecondsEnvName, apiHSTSMaxAgeSecondsDefault)
+	v.SetDefault(apiCompressionEnabledEnvName, apiCompressionEnabledDefault)
+	v.SetDefault(apiCompressionMinSizeBytesEnvName, apiCompressionMinSizeBytesDefault)
+	v.SetDefault(apiWarmUpEnabledEnvName, apiWarmUpEnabledDefault)
+	v.SetDefault(apiWarmUpTagsEnvName, apiWarmUpTagsDefault)
+	v.SetDefault(apiWarmUpTimeframeMinutesEnvName, apiWarmUpTimeframeMinutesDefault)
+	v.SetDefault(apiWarmUpPopularTagsEnvName, apiWarmUpPopularTagsDefault)
+	v.SetDefault(apiPercentileThresholdTTLSecondsEnvName, apiPercentileThresholdTTLSecondsDefault)
+	v.SetDefault(apiClockSkewAdjustmentEnabledEnvName, apiClockSkewAdjustmentEnabledDefault)
+	v.SetDefault(apiOTLPEnumNamesEnvName, apiOTLPEnumNamesDefault)
+	v.SetDefault(apiLogLinkTemplatesEnvName, apiLogLinkTemplatesDefault)
+	v.SetDefault(apiMetricsEnabledEnvName, apiMetricsEnabledDefault)
+
+	// Self tracing defaults
+	v.SetDefault(selfTracingEnabledEnvName, selfTracingEnabledDefault)
+	v.SetDefault(selfTracingEndpointEnvName, selfTracingEndpointDefault)
+	v.SetDefault(selfTracingSampleRatioEnvName, selfTracingSampleRatioDefault)
+
+	// Storage migration defaults
+	v.SetDefault(storageMigrationTargetPluginEnvName, storageMigrationTargetPluginDefault)
+	v.SetDefault(storageMigrationTargetSettingsEnvName, storageMigrationTargetSettingsDefault)
+	v.SetDefault(storageMigrationCutoverEnvName, storageMigrationCutoverDefault)
+
+	// Storage circuit breaker defaults
+	v.SetDefault(storageCircuitBreakerEnabledEnvName, storageCircuitBreakerEnabledDefault)
+	v.SetDefault(storageCircuitBreakerFailureThresholdEnvName, storageCircuitBreakerFailureThresholdDefault)
+	v.SetDefault(storageCircuitBreakerOpenSecondsEnvName, storageCircuitBreakerOpenSecondsDefault)
+	v.SetDefault(storageQueryTimeoutSecondsEnvName, storageQueryTimeoutSecondsDefault)
+	v.SetDefault(storageSlowQueryThresholdMillisecondsEnvName, storageSlowQueryThresholdMillisecondsDefault)
+
+	// Read deduplication defaults
+	v.SetDefault(readDedupModeEnvName, readDedupModeDefault)
+
+	// Service aliases defaults
+	v.SetDefault(serviceAliasesEnvName, serviceAliasesDefault)
+
+	// Access control defaults
+	v.SetDefault(aclPolicyFileEnvName, aclPolicyFileDefault)
+	v.SetDefault(aclRoleHeaderEnvName, aclRoleHeaderDefault)
+
+	// User and query history defaults
+	v.SetDefault(apiUserHeaderEnvName, apiUserHeaderDefault)
+	v.SetDefault(queryHistorySizeEnvName, queryHistorySizeDefault)
+	v.SetDefault(auditLogEnabledEnvName, auditLogEnabledDefault)
+	v.SetDefault(auditLogAPIKeyHeaderEnvName, auditLogAPIKeyHeaderDefault)
+
+	// N+1 detector defaults
+	v.SetDefault(nPlusOneMinRepetitionsEnvName, nPlusOneMinRepetitionsDefault)
+	v.SetDefault(nPlusOneMaxChildDurationMillisecondsEnvName, nPlusOneMaxChildDurationMillisecondsDefault)
+	v.SetDefault(nPlusOneMaxTracesEnvName, nPlusOneMaxTracesDefault)
+
+	// Incomplete traces detector defaults
+	v.SetDefault(incompleteTracesMaxSpansEnvName, incompleteTracesMaxSpansDefault)
+	v.SetDefault(incompleteTracesMaxTracesEnvName, incompleteTracesMaxTracesDefault)
+	v.SetDefault(incompleteTracesGracePeriodSecondsEnvName, incompleteTracesGracePeriodSecondsDefault)
+
+	// Error analysis defaults
+	v.SetDefault(errorGroupsMaxSpansEnvName, errorGroupsMaxSpansDefault)
+
+	// Ingestion lag analysis defaults
+	v.SetDefault(ingestionLagMaxSpansEnvName, ingestionLagMaxSpansDefault)
+
+	// Flamegraph aggregation defaults
+	v.SetDefault(flamegraphMaxTracesEnvName, flamegraphMaxTracesDefault)
+
+	// Trace summaries defaults
+	v.SetDefault(traceSummariesMaxTracesEnvName, traceSummariesMaxTracesDefault)
+
+	// Alerting defaults
+	v.SetDefault(alertsEnabledEnvName, alertsEnabledDefault)
+	v.SetDefault(alertsEvaluationIntervalSecondsEnvName, alertsEvaluationIntervalSecondsDefault)
+	v.SetDefault(alertsUIBaseURLEnvName, alertsUIBaseURLDefault)
+	v.SetDefault(alertsNotificationMaxAttemptsEnvName, alertsNotificationMaxAttemptsDefault)
+	v.SetDefault(alertsNotificationTimeoutSecondsEnvName, alertsNotificationTimeoutSecondsDefault)
+
+	// Latency anomaly detection defaults
+	v.SetDefault(anomaliesEnabledEnvName, anomaliesEnabledDefault)
+	v.SetDefault(anomaliesIntervalSecondsEnvName, anomaliesIntervalSecondsDefault)
+	v.SetDefault(anomaliesWindowMinutesEnvName, anomaliesWindowMinutesDefault)
+	v.SetDefault(anomaliesBaselineOffsetHoursEnvName, anomaliesBaselineOffsetHoursDefault)
+	v.SetDefault(anomaliesMinSpansEnvName, anomaliesMinSpansDefault)
+	v.SetDefault(anomaliesRegressionRatioEnvName, anomaliesRegressionRatioDefault)
+	v.SetDefault(anomaliesMaxOperationsEnvName, anomaliesMaxOperationsDefault)
+
+	// Metric to trace correlation defaults
+	v.SetDefault(metricCorrelationPrometheusURLEnvName, metricCorrelationPrometheusURLDefault)
+	v.SetDefault(metricCorrelationTimeoutSecondsEnvName, metricCorrelationTimeoutSecondsDefault)
+	v.SetDefault(metricCorrelationMaxSpansEnvName, metricCorrelationMaxSpansDefault)
+
+	// Sidecar store defaults
+	v.SetDefault(sidecarStoreTypeEnvName, sidecarStoreTypeDefault)
+	v.SetDefault(sidecarDirectoryEnvName, sidecarDirectoryDefault)
+	v.SetDefault(sidecarS3BucketEnvName, sidecarS3BucketDefault)
+	v.SetDefault(sidecarS3RegionEnvName, sidecarS3RegionDefault)
+	v.SetDefault(sidecarS3PrefixEnvName, sidecarS3PrefixDefault)
+	v.SetDefault(sidecarS3EndpointEnvName, sidecarS3EndpointDefault)
+
+	// Archive store defaults
+	v.SetDefault(archiveStoreTypeEnvName, archiveStoreTypeDefault)
+	v.SetDefault(archiveDirectoryEnvName, archiveDirectoryDefault)
+	v.SetDefault(archiveS3BucketEnvName, archiveS3BucketDefault)
+	v.SetDefault(archiveS3RegionEnvName, archiveS3RegionDefault)
+	v.SetDefault(archiveS3PrefixEnvName, archiveS3PrefixDefault)
+	v.SetDefault(archiveS3EndpointEnvName, archiveS3EndpointDefault)
+	v.SetDefault(archiveMaxTracesEnvName, archiveMaxTracesDefault)
+	v.SetDefault(archiveRestoreEndpointEnvName, archiveRestoreEndpointDefault)
+
 	// Elasticsearch defaults
 	v.SetDefault(esEndpointEnvName, esEndpointDefault)
 	v.SetDefault(esUsernameEnvName, esUsernameDefault)
 	v.SetDefault(esPasswordEnvName, esPasswordDefault)
 	v.SetDefault(esApiKeyEnvName, esApiKeyDefault)
+	v.SetDefault(esApiKeyFileEnvName, esApiKeyFileDefault)
 	v.SetDefault(esServiceTokenEnvName, esServiceTokenDefault)
+	v.SetDefault(esServiceTokenFileEnvName, esServiceTokenFileDefault)
+	v.SetDefault(esWriteEndpointEnvName, esWriteEndpointDefault)
+	v.SetDefault(esWriteUsernameEnvName, esWriteUsernameDefault)
+	v.SetDefault(esWritePasswordEnvName, esWritePasswordDefault)
+	v.SetDefault(esWriteApiKeyEnvName, esWriteApiKeyDefault)
+	v.SetDefault(esWriteApiKeyFileEnvName, esWriteApiKeyFileDefault)
+	v.SetDefault(esTLSCertFileEnvName, esTLSCertFileDefault)
+	v.SetDefault(esTLSKeyFileEnvName, esTLSKeyFileDefault)
+	v.SetDefault(esTLSCAFileEnvName, esTLSCAFileDefault)
+	v.SetDefault(esTLSInsecureSkipVerifyEnvName, esTLSInsecureSkipVerifyDefault)
+	v.SetDefault(esDistributionEnvName, esDistributionDefault)
+	v.SetDefault(esAWSSigV4EnvName, esAWSSigV4Default)
+	v.SetDefault(esAWSRegionEnvName, esAWSRegionDefault)
 	v.SetDefault(esForceCreateConfigEnvName, esForceCreateConfigDefault)
 	v.SetDefault(esIndexEnvName, esIndexDefault)
+	v.SetDefault(esRemoteIndicesEnvName, esRemoteIndicesDefault)
 	v.SetDefault(esIndexerFlushThresholdSecondsEnvName, esIndexerFlushThresholdSecondsDefault)
 	v.SetDefault(esIndexerWorkersCountEnvName, esIndexerWorkersCountDefault)
 	v.SetDefault(sqlitePathEnvName, sqlitePathEnvNameDefault)
+	v.SetDefault(sqliteShardByEnvName, sqliteShardByDefault)
+	v.SetDefault(sqliteShardsEnvName, sqliteShardsDefault)
+	v.SetDefault(cassandraHostsEnvName, cassandraHostsDefault)
+	v.SetDefault(cassandraKeyspaceEnvName, cassandraKeyspaceDefault)
+	v.SetDefault(cassandraUsernameEnvName, cassandraUsernameDefault)
+	v.SetDefault(cassandraPasswordEnvName, cassandraPasswordDefault)
+	v.SetDefault(cassandraConsistencyEnvName, cassandraConsistencyDefault)
+	v.SetDefault(cassandraMaxScannedSpansEnvName, cassandraMaxScannedSpansDefault)
+	v.SetDefault(cassandraMaxLookbackHoursEnvName, cassandraMaxLookbackHoursDefault)
+	v.SetDefault(badgerDirectoryEnvName, badgerDirectoryDefault)
+	v.SetDefault(badgerMaxScannedSpansEnvName, badgerMaxScannedSpansDefault)
+	v.SetDefault(parquetDirectoryEnvName, parquetDirectoryDefault)
+	v.SetDefault(parquetMaxScannedSpansEnvName, parquetMaxScannedSpansDefault)
+	v.SetDefault(grpcPluginPathEnvName, grpcPluginPathDefault)
+	v.SetDefault(grpcPluginStartTimeoutSecondsEnvName, grpcPluginStartTimeoutSecondsDefault)
+	v.SetDefault(federationConfigFileEnvName, federationConfigFileDefault)
+
+	// Metadata store defaults
+	v.SetDefault(metadataSQLitePathEnvName, metadataSQLitePathDefault)
+	v.SetDefault(metadataPostgresDSNEnvName, metadataPostgresDSNDefault)
 }
@@ -18,12 +18,16 @@ package config
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 )
 
 func TestDefaultValuesSource(t *testing.T) {
@@ -34,6 +38,7 @@ func TestDefaultValuesSource(t *testing.T) {
 	assert.Equal(t, expectedConfig.Debug, actualConfig.Debug)
 	assert.Equal(t, expectedConfig.APIPort, actualConfig.APIPort)
 	assert.Equal(t, expectedConfig.SpansStoragePlugin, actualConfig.SpansStoragePlugin)
+	assert.Equal(t, "127.0.0.1", actualConfig.AdminHost, "the admin port must not be exposed by default")
 }
 
 func TestConfigFileSource(t *testing.T) {
@@ -98,3 +103,121 @@ func writeEnvFile(t *testing.T, content []byte) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestChangedSettings(t *testing.T) {
+	cfg := Config{APIPort: 8080, LogLevel: "info", SelfTracingSampleRatio: 1}
+	next := Config{APIPort: 9090, LogLevel: "debug", SelfTracingSampleRatio: 1}
+
+	reloaded, restartRequired := changedSettings(cfg, next)
+	assert.Equal(t, []string{"LOG_LEVEL"}, reloaded)
+	assert.Equal(t, []string{"API_PORT"}, restartRequired)
+
+	applied := withReloadableSettings(cfg, next)
+	assert.Equal(t, Config{APIPort: 8080, LogLevel: "debug", SelfTracingSampleRatio: 1}, applied)
+}
+
+func TestWatchReloadsConfigFile(t *testing.T) {
+	writeEnvFile(t, []byte("LOG_LEVEL: info\nAPI_PORT: 1234"))
+	cfg, err := NewConfig()
+	assert.NoError(t, err)
+
+	reloaded := make(chan Config, 1)
+	assert.NoError(t, Watch(zap.NewNop(), cfg, func(c Config) { reloaded <- c }))
+
+	path := filepath.Join(configPath, configFilename+"."+configFileExt)
+	assert.NoError(t, os.WriteFile(path, []byte("LOG_LEVEL: debug\nAPI_PORT: 5678"), 0o644))
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case c := <-reloaded:
+			// the file may be reloaded while it's being written, before the change is complete
+			if c.LogLevel != "debug" {
+				continue
+			}
+			assert.Equal(t, 1234, c.APIPort, "settings requiring a restart aren't applied")
+			return
+		case <-timeout:
+			t.Fatal("config file change wasn't reloaded")
+		}
+	}
+}
+
+func TestPrefixedEnvVarSource(t *testing.T) {
+	t.Setenv("API_PORT", "1234")
+	t.Setenv("TELETRACE_API_PORT", "5678")
+	t.Setenv("TELETRACE_ES_PASSWORD", "secret")
+	t.Setenv("SQLITE_PATH", "/data/spans.db")
+
+	actualConfig, err := NewConfig()
+	assert.NoError(t, err)
+
+	assert.Equal(t, 5678, actualConfig.APIPort, "prefixed env variables take precedence")
+	assert.Equal(t, "secret", actualConfig.ESPassword)
+	assert.Equal(t, "/data/spans.db", actualConfig.SQLitePath)
+}
+
+func TestFileSecretReference(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "es_password")
+	assert.NoError(t, os.WriteFile(path, []byte("file-secret\n"), 0o600))
+	t.Setenv("ES_PASSWORD", "file://"+path)
+	t.Setenv("ES_USERNAME", "file://not-a-secret-setting")
+
+	actualConfig, err := NewConfig()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "file-secret", actualConfig.ESPassword)
+	assert.Equal(t, "file://not-a-secret-setting", actualConfig.ESUsername, "only secret settings are resolved")
+
+	t.Setenv("ES_PASSWORD", "file://"+filepath.Join(t.TempDir(), "missing"))
+	_, err = NewConfig()
+	assert.ErrorContains(t, err, "ES_PASSWORD")
+}
+
+func TestVaultSecretReference(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/teletrace":
+			_, _ = w.Write([]byte(`{"data": {"data": {"es_api_key": "kv2-secret"}, "metadata": {"version": 1}}}`))
+		case "/v1/kv/teletrace":
+			_, _ = w.Write([]byte(`{"data": {"dsn": "kv1-secret"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "token")
+
+	t.Setenv("ES_API_KEY", "vault://secret/data/teletrace#es_api_key")
+	t.Setenv("METADATA_POSTGRES_DSN", "vault://kv/teletrace#dsn")
+	actualConfig, err := NewConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "kv2-secret", actualConfig.ESAPIKey)
+	assert.Equal(t, "kv1-secret", actualConfig.MetadataPostgresDSN)
+
+	for _, ref := range []string{"vault://secret/data/teletrace#missing", "vault://secret/data/other#key", "vault://secret/data/teletrace"} {
+		t.Setenv("ES_API_KEY", ref)
+		_, err = NewConfig()
+		assert.Error(t, err, ref)
+	}
+}
+
+func TestWithSettings(t *testing.T) {
+	cfg, err := NewConfig()
+	assert.NoError(t, err)
+
+	overridden, err := cfg.WithSettings(map[string]any{"sqlite_path": "/data/archive.db", "ES_INDEXER_WORKERS_COUNT": "2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "/data/archive.db", overridden.SQLitePath)
+	assert.Equal(t, 2, overridden.ESIndexerWorkersCount)
+	assert.Equal(t, cfg.APIPort, overridden.APIPort)
+	assert.Equal(t, sqlitePathEnvNameDefault, cfg.SQLitePath, "the original config isn't modified")
+
+	_, err = cfg.WithSettings(map[string]any{"no_such_option": true})
+	assert.Error(t, err)
+}
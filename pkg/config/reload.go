@@ -0,0 +1,107 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// reloadableTag marks the Config fields which are applied by Watch without restarting the process
+const reloadableTag = "reloadable"
+
+// Watch watches the config file and calls onChange with current updated by the reloadable settings
+// whenever they change. Changes to other settings are logged as requiring a restart, and aren't applied.
+// Env variables keep overriding the config file, so settings set by env variables can't be reloaded.
+// It doesn't watch anything when there is no config file.
+func Watch(logger *zap.Logger, current Config, onChange func(Config)) error {
+	v, err := newViper()
+	if err != nil {
+		return err
+	}
+	if v.ConfigFileUsed() == "" {
+		logger.Debug("No config file to watch")
+		return nil
+	}
+
+	// the config file is compared before resolving secret references, which are resolved only on startup
+	var previous Config
+	if err := v.Unmarshal(&previous); err != nil {
+		return fmt.Errorf("error unmarshaling config to struct: %w", err)
+	}
+
+	v.OnConfigChange(func(e fsnotify.Event) {
+		var next Config
+		if err := v.Unmarshal(&next); err != nil {
+			logger.Error("Failed to reload config file", zap.String("file", e.Name), zap.Error(err))
+			return
+		}
+
+		reloaded, restartRequired := changedSettings(previous, next)
+		if len(restartRequired) > 0 {
+			logger.Warn("Changed settings require a restart to take effect", zap.Strings("settings", restartRequired))
+		}
+		if len(reloaded) == 0 {
+			return
+		}
+		previous = withReloadableSettings(previous, next)
+		current = withReloadableSettings(current, next)
+		logger.Info("Reloaded settings", zap.Strings("settings", reloaded))
+		onChange(current)
+	})
+	v.WatchConfig()
+	logger.Info("Watching config file for changes", zap.String("file", v.ConfigFileUsed()))
+	return nil
+}
+
+// changedSettings returns the names of the reloadable settings and of the other settings which differ between cfg and next.
+func changedSettings(cfg Config, next Config) (reloaded []string, restartRequired []string) {
+	t := reflect.TypeOf(cfg)
+	current, updated := reflect.ValueOf(cfg), reflect.ValueOf(next)
+	for i := 0; i < t.NumField(); i++ {
+		if reflect.DeepEqual(current.Field(i).Interface(), updated.Field(i).Interface()) {
+			continue
+		}
+		name := strings.ToUpper(t.Field(i).Tag.Get("mapstructure"))
+		if isReloadable(t.Field(i)) {
+			reloaded = append(reloaded, name)
+		} else {
+			restartRequired = append(restartRequired, name)
+		}
+	}
+	return reloaded, restartRequired
+}
+
+// withReloadableSettings returns cfg with its reloadable settings taken from next.
+func withReloadableSettings(cfg Config, next Config) Config {
+	t := reflect.TypeOf(cfg)
+	result, updated := reflect.ValueOf(&cfg).Elem(), reflect.ValueOf(next)
+	for i := 0; i < t.NumField(); i++ {
+		if isReloadable(t.Field(i)) {
+			result.Field(i).Set(updated.Field(i))
+		}
+	}
+	return cfg
+}
+
+func isReloadable(field reflect.StructField) bool {
+	return field.Tag.Get(reloadableTag) == "true"
+}
@@ -0,0 +1,139 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// secretTag marks the Config fields whose value may be a reference to a secret, resolved by NewConfig
+const secretTag = "secret"
+
+const (
+	fileSecretScheme  = "file://"
+	vaultSecretScheme = "vault://"
+
+	vaultAddrEnvName      = "VAULT_ADDR"
+	vaultTokenEnvName     = "VAULT_TOKEN"
+	vaultNamespaceEnvName = "VAULT_NAMESPACE"
+	vaultTimeout          = 10 * time.Second
+)
+
+// resolveSecrets replaces the secret references in the secret settings of cfg with the secrets they refer to.
+// Values which aren't references are kept as is.
+func resolveSecrets(cfg *Config) error {
+	t := reflect.TypeOf(*cfg)
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get(secretTag) != "true" {
+			continue
+		}
+		secret, err := resolveSecret(v.Field(i).String())
+		if err != nil {
+			return fmt.Errorf("error resolving %s: %w", strings.ToUpper(t.Field(i).Tag.Get("mapstructure")), err)
+		}
+		v.Field(i).SetString(secret)
+	}
+	return nil
+}
+
+// resolveSecret returns the secret ref refers to, either
+// file://<path> - the trimmed content of the file at path, e.g. a mounted docker or kubernetes secret, or
+// vault://<path>#<key> - the key of the Vault secret at path, e.g. vault://secret/data/teletrace#es_password.
+// Any other ref is returned as is.
+func resolveSecret(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, fileSecretScheme):
+		return readFileSecret(strings.TrimPrefix(ref, fileSecretScheme))
+	case strings.HasPrefix(ref, vaultSecretScheme):
+		return readVaultSecret(strings.TrimPrefix(ref, vaultSecretScheme))
+	default:
+		return ref, nil
+	}
+}
+
+func readFileSecret(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("could not read secret file: %w", err)
+	}
+
+	secret := strings.TrimSpace(string(content))
+	if secret == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+	return secret, nil
+}
+
+// readVaultSecret reads a key of a secret from the Vault HTTP API, addressed by the VAULT_ADDR env variable
+// and authenticated by the VAULT_TOKEN env variable. Both KV version 1 and 2 secrets are supported,
+// the path of a version 2 secret includes its data prefix (e.g. secret/data/teletrace).
+func readVaultSecret(ref string) (string, error) {
+	path, key, found := strings.Cut(ref, "#")
+	if !found || path == "" || key == "" {
+		return "", fmt.Errorf("vault secret reference %q should be of the form vault://<path>#<key>", vaultSecretScheme+ref)
+	}
+
+	addr := os.Getenv(vaultAddrEnvName)
+	if addr == "" {
+		return "", fmt.Errorf("%s is required to read vault secrets", vaultAddrEnvName)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(addr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", fmt.Errorf("could not create vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", os.Getenv(vaultTokenEnvName))
+	if namespace := os.Getenv(vaultNamespaceEnvName); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	client := &http.Client{Timeout: vaultTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not read vault secret %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not read vault secret %s: %s", path, resp.Status)
+	}
+
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("could not decode vault secret %s: %w", path, err)
+	}
+
+	data := body.Data
+	// KV version 2 nests the secret data next to its metadata
+	if nested, ok := data["data"].(map[string]any); ok && data["metadata"] != nil {
+		data = nested
+	}
+
+	secret, ok := data[key].(string)
+	if !ok || secret == "" {
+		return "", fmt.Errorf("vault secret %s has no %s key", path, key)
+	}
+	return secret, nil
+}
@@ -0,0 +1,193 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package errorgroups
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	errorgroups "github.com/teletrace/teletrace/pkg/model/errorgroups/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"go.uber.org/zap"
+)
+
+const (
+	statusCodeTag   = "span.status.code"
+	errorStatusCode = "Error"
+	// exceptionEvent is the name of the span events recording exceptions, per the OpenTelemetry semantic conventions
+	exceptionEvent      = "exception"
+	exceptionTypeKey    = "exception.type"
+	exceptionMessageKey = "exception.message"
+	// maxExampleTraces is the number of example traces of each group
+	maxExampleTraces = 5
+)
+
+// Config bounds the work of a single analysis.
+type Config struct {
+	// MaxSpans is the maximum number of error spans grouped
+	MaxSpans int
+}
+
+// Analyzer groups error spans by their exception type or status message, giving an overview of the errors
+// derived from traces.
+type Analyzer struct {
+	logger     *zap.Logger
+	spanReader spanreader.SpanReader
+	cfg        Config
+}
+
+func NewAnalyzer(logger *zap.Logger, sr spanreader.SpanReader, cfg Config) *Analyzer {
+	return &Analyzer{
+		logger:     logger,
+		spanReader: sr,
+		cfg:        cfg,
+	}
+}
+
+// Analyze groups the most recent error spans matching the request, up to the configured maximum number of spans.
+func (a *Analyzer) Analyze(ctx context.Context, req errorgroups.AnalyzeRequest) (*errorgroups.AnalyzeResponse, error) {
+	filters := spanreader.CopyFilters(req.SearchFilters)
+	filters = append(filters, model.SearchFilter{KeyValueFilter: &model.KeyValueFilter{
+		Key:      statusCodeTag,
+		Operator: spansquery.OPERATOR_EQUALS,
+		Value:    errorStatusCode,
+	}})
+
+	groups := make(map[string]*errorgroups.ErrorGroup)
+	scannedSpans := 0
+	truncated := false
+	var token spansquery.ContinuationToken
+	for {
+		res, err := a.spanReader.Search(ctx, spansquery.SearchRequest{
+			Timeframe:     req.Timeframe,
+			Sort:          []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: false}},
+			SearchFilters: spanreader.CopyFilters(filters),
+			Metadata:      &spansquery.Metadata{NextToken: token},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not scan error spans: %w", err)
+		}
+
+		for _, span := range res.Spans {
+			if scannedSpans == a.cfg.MaxSpans {
+				truncated = true
+				break
+			}
+			addSpan(groups, span)
+			scannedSpans++
+		}
+
+		if truncated || len(res.Spans) == 0 || res.Metadata == nil || res.Metadata.NextToken == "" {
+			break
+		}
+		token = res.Metadata.NextToken
+	}
+
+	return &errorgroups.AnalyzeResponse{
+		Groups:       sortGroups(groups),
+		ScannedSpans: scannedSpans,
+		Truncated:    truncated,
+	}, nil
+}
+
+// addSpan adds an error span to its group. The spans are added the most recent first.
+func addSpan(groups map[string]*errorgroups.ErrorGroup, span *internalspan.InternalSpan) {
+	exceptionType, message := describe(span)
+	key := "status:" + message
+	if exceptionType != "" {
+		key = "exception:" + exceptionType
+	}
+
+	group, ok := groups[key]
+	if !ok {
+		group = &errorgroups.ErrorGroup{
+			ExceptionType:    exceptionType,
+			Message:          message,
+			Services:         []string{},
+			ExampleTraceIds:  []string{},
+			LastSeenUnixNano: span.Span.StartTimeUnixNano,
+		}
+		groups[key] = group
+	}
+	group.Count++
+	if group.FirstSeenUnixNano == 0 || span.Span.StartTimeUnixNano < group.FirstSeenUnixNano {
+		group.FirstSeenUnixNano = span.Span.StartTimeUnixNano
+	}
+	if span.Span.StartTimeUnixNano > group.LastSeenUnixNano {
+		group.LastSeenUnixNano = span.Span.StartTimeUnixNano
+	}
+	if service := spanreader.ServiceName(span); service != "" && !contains(group.Services, service) {
+		group.Services = append(group.Services, service)
+	}
+	if len(group.ExampleTraceIds) < maxExampleTraces && !contains(group.ExampleTraceIds, span.Span.TraceId) {
+		group.ExampleTraceIds = append(group.ExampleTraceIds, span.Span.TraceId)
+	}
+}
+
+// describe returns the exception type and message of an error span, recorded by an exception event or
+// as span attributes, or its status message if it didn't record an exception.
+func describe(span *internalspan.InternalSpan) (string, string) {
+	for _, event := range span.Span.Events {
+		if event.Name != exceptionEvent {
+			continue
+		}
+		if exceptionType, _ := event.Attributes[exceptionTypeKey].(string); exceptionType != "" {
+			message, _ := event.Attributes[exceptionMessageKey].(string)
+			return exceptionType, message
+		}
+	}
+	if exceptionType, _ := span.Span.Attributes[exceptionTypeKey].(string); exceptionType != "" {
+		message, _ := span.Span.Attributes[exceptionMessageKey].(string)
+		return exceptionType, message
+	}
+	if span.Span.Status != nil {
+		return "", span.Span.Status.Message
+	}
+	return "", ""
+}
+
+// sortGroups returns the groups, the most frequent first, then the most recently seen.
+func sortGroups(groups map[string]*errorgroups.ErrorGroup) []errorgroups.ErrorGroup {
+	result := make([]errorgroups.ErrorGroup, 0, len(groups))
+	for _, group := range groups {
+		sort.Strings(group.Services)
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		if result[i].LastSeenUnixNano != result[j].LastSeenUnixNano {
+			return result[i].LastSeenUnixNano > result[j].LastSeenUnixNano
+		}
+		return result[i].ExceptionType+result[i].Message < result[j].ExceptionType+result[j].Message
+	})
+	return result
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package errorgroups
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	errorgroups "github.com/teletrace/teletrace/pkg/model/errorgroups/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+const pageSize = 2
+
+// pagedSpanReader serves the spans it holds in pages of pageSize spans.
+type pagedSpanReader struct {
+	spanreader.SpanReader
+	spans    []*internalspan.InternalSpan
+	requests []spansquery.SearchRequest
+}
+
+func (sr *pagedSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	sr.requests = append(sr.requests, r)
+	offset, _ := strconv.Atoi(string(r.Metadata.NextToken))
+	end := offset + pageSize
+	metadata := &spansquery.Metadata{NextToken: spansquery.ContinuationToken(strconv.Itoa(end))}
+	if end >= len(sr.spans) {
+		end = len(sr.spans)
+		metadata = nil
+	}
+	return &spansquery.SearchResponse{Spans: sr.spans[offset:end], Metadata: metadata}, nil
+}
+
+func errorSpan(traceId string, service string, start uint64, attributes internalspan.Attributes, events []*internalspan.SpanEvent, message string) *internalspan.InternalSpan {
+	return &internalspan.InternalSpan{
+		Resource: &internalspan.Resource{Attributes: internalspan.Attributes{"service.name": service}},
+		Span: &internalspan.Span{
+			TraceId:           traceId,
+			StartTimeUnixNano: start,
+			Attributes:        attributes,
+			Events:            events,
+			Status:            &internalspan.SpanStatus{Code: "Error", Message: message},
+		},
+	}
+}
+
+func exception(exceptionType string, message string) []*internalspan.SpanEvent {
+	return []*internalspan.SpanEvent{
+		{Name: "log"},
+		{Name: "exception", Attributes: internalspan.Attributes{"exception.type": exceptionType, "exception.message": message}},
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	sr := &pagedSpanReader{spans: []*internalspan.InternalSpan{
+		errorSpan("t5", "checkout", 50, nil, exception("TimeoutError", "query took 31s"), ""),
+		errorSpan("t4", "payments", 40, nil, exception("TimeoutError", "query took 30s"), ""),
+		errorSpan("t4", "checkout", 39, internalspan.Attributes{"exception.type": "KeyError", "exception.message": "'id'"}, nil, ""),
+		errorSpan("t3", "checkout", 30, nil, nil, "upstream unavailable"),
+		errorSpan("t2", "checkout", 20, nil, exception("TimeoutError", "query took 32s"), ""),
+		errorSpan("t1", "checkout", 10, nil, nil, "upstream unavailable"),
+	}}
+	analyzer := NewAnalyzer(zap.NewNop(), sr, Config{MaxSpans: 100})
+
+	filters := []model.SearchFilter{{KeyValueFilter: &model.KeyValueFilter{Key: "span.name", Operator: spansquery.OPERATOR_EQUALS, Value: "query"}}}
+	res, err := analyzer.Analyze(context.Background(), errorgroups.AnalyzeRequest{
+		Timeframe:     model.Timeframe{StartTime: 0, EndTime: 100},
+		SearchFilters: filters,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, filters, 1)
+	assert.Len(t, sr.requests[0].SearchFilters, 2)
+	assert.Equal(t, "Error", sr.requests[0].SearchFilters[1].KeyValueFilter.Value)
+	assert.Equal(t, 6, res.ScannedSpans)
+	assert.False(t, res.Truncated)
+
+	assert.Equal(t, []errorgroups.ErrorGroup{
+		{
+			ExceptionType:     "TimeoutError",
+			Message:           "query took 31s",
+			Services:          []string{"checkout", "payments"},
+			Count:             3,
+			FirstSeenUnixNano: 20,
+			LastSeenUnixNano:  50,
+			ExampleTraceIds:   []string{"t5", "t4", "t2"},
+		},
+		{
+			Message:           "upstream unavailable",
+			Services:          []string{"checkout"},
+			Count:             2,
+			FirstSeenUnixNano: 10,
+			LastSeenUnixNano:  30,
+			ExampleTraceIds:   []string{"t3", "t1"},
+		},
+		{
+			ExceptionType:     "KeyError",
+			Message:           "'id'",
+			Services:          []string{"checkout"},
+			Count:             1,
+			FirstSeenUnixNano: 39,
+			LastSeenUnixNano:  39,
+			ExampleTraceIds:   []string{"t4"},
+		},
+	}, res.Groups)
+
+	analyzer = NewAnalyzer(zap.NewNop(), sr, Config{MaxSpans: 3})
+	res, err = analyzer.Analyze(context.Background(), errorgroups.AnalyzeRequest{Timeframe: model.Timeframe{StartTime: 0, EndTime: 100}})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, res.ScannedSpans)
+	assert.True(t, res.Truncated)
+}
@@ -0,0 +1,170 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package flamegraph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	flamegraph "github.com/teletrace/teletrace/pkg/model/flamegraph/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	tracetreemodel "github.com/teletrace/teletrace/pkg/model/tracetree/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/tracetree"
+
+	"go.uber.org/zap"
+)
+
+const (
+	rootName             = "all"
+	serviceNameAttribute = "service.name"
+)
+
+// Config bounds the work of a single aggregation.
+type Config struct {
+	// MaxTraces is the maximum number of traces aggregated
+	MaxTraces int
+}
+
+// Aggregator merges many traces into a single flamegraph of call paths, to spot systemic hotspots
+// rather than inspecting one trace at a time.
+type Aggregator struct {
+	logger     *zap.Logger
+	spanReader spanreader.SpanReader
+	cfg        Config
+}
+
+func NewAggregator(logger *zap.Logger, sr spanreader.SpanReader, cfg Config) *Aggregator {
+	return &Aggregator{
+		logger:     logger,
+		spanReader: sr,
+		cfg:        cfg,
+	}
+}
+
+// Aggregate merges the most recent traces having spans matching the request, up to the configured maximum number
+// of traces. The spans of each trace are assembled into a tree, and merged into the flamegraph by call path.
+func (a *Aggregator) Aggregate(ctx context.Context, req flamegraph.AggregateRequest) (*flamegraph.AggregateResponse, error) {
+	traceIds, truncated, err := a.matchingTraces(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("could not find matching traces: %w", err)
+	}
+
+	root := &flamegraph.Node{Name: rootName, Children: []*flamegraph.Node{}}
+	for _, traceId := range traceIds {
+		spans, err := spanreader.TraceSpans(ctx, a.spanReader, traceId)
+		if err != nil {
+			return nil, fmt.Errorf("could not get spans of trace %s: %w", traceId, err)
+		}
+		for _, tree := range tracetree.Build(spans) {
+			root.TotalTimeNano += merge(root, tree)
+		}
+		root.Count++
+	}
+	sortChildren(root)
+
+	return &flamegraph.AggregateResponse{
+		Root:       root,
+		TraceCount: len(traceIds),
+		Truncated:  truncated,
+	}, nil
+}
+
+// matchingTraces returns the IDs of the traces of the most recent spans matching the request,
+// up to the configured maximum number of traces.
+func (a *Aggregator) matchingTraces(ctx context.Context, req flamegraph.AggregateRequest) ([]string, bool, error) {
+	var traceIds []string
+	seen := make(map[string]bool)
+	var token spansquery.ContinuationToken
+	for {
+		res, err := a.spanReader.Search(ctx, spansquery.SearchRequest{
+			Timeframe:     req.Timeframe,
+			Sort:          []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: false}},
+			SearchFilters: spanreader.CopyFilters(req.SearchFilters),
+			Metadata:      &spansquery.Metadata{NextToken: token},
+		})
+		if err != nil {
+			return nil, false, err
+		}
+
+		for _, span := range res.Spans {
+			traceId := span.Span.TraceId
+			if seen[traceId] {
+				continue
+			}
+			if len(traceIds) == a.cfg.MaxTraces {
+				return traceIds, true, nil
+			}
+			seen[traceId] = true
+			traceIds = append(traceIds, traceId)
+		}
+
+		if len(res.Spans) == 0 || res.Metadata == nil || res.Metadata.NextToken == "" {
+			return traceIds, false, nil
+		}
+		token = res.Metadata.NextToken
+	}
+}
+
+// merge adds the span of a trace tree node and its descendants to the child of parent with the same call path,
+// and returns the duration of the span.
+func merge(parent *flamegraph.Node, node *tracetreemodel.Node) uint64 {
+	span := node.Span
+	serviceName := ""
+	if span.Resource != nil {
+		serviceName, _ = span.Resource.Attributes[serviceNameAttribute].(string)
+	}
+	var child *flamegraph.Node
+	for _, c := range parent.Children {
+		if c.Name == span.Span.Name && c.ServiceName == serviceName {
+			child = c
+			break
+		}
+	}
+	if child == nil {
+		child = &flamegraph.Node{Name: span.Span.Name, ServiceName: serviceName, Children: []*flamegraph.Node{}}
+		parent.Children = append(parent.Children, child)
+	}
+
+	duration := uint64(0)
+	if span.Span.EndTimeUnixNano > span.Span.StartTimeUnixNano {
+		duration = span.Span.EndTimeUnixNano - span.Span.StartTimeUnixNano
+	}
+	child.Count++
+	child.TotalTimeNano += duration
+	child.SelfTimeNano += node.SelfTimeNano
+	for _, grandchild := range node.Children {
+		merge(child, grandchild)
+	}
+	return duration
+}
+
+func sortChildren(node *flamegraph.Node) {
+	sort.Slice(node.Children, func(i, j int) bool {
+		a, b := node.Children[i], node.Children[j]
+		if a.TotalTimeNano != b.TotalTimeNano {
+			return a.TotalTimeNano > b.TotalTimeNano
+		}
+		if a.ServiceName != b.ServiceName {
+			return a.ServiceName < b.ServiceName
+		}
+		return a.Name < b.Name
+	})
+	for _, child := range node.Children {
+		sortChildren(child)
+	}
+}
@@ -0,0 +1,140 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package flamegraph
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	flamegraph "github.com/teletrace/teletrace/pkg/model/flamegraph/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+const pageSize = 2
+
+// traceSpanReader serves the spans of the traces it holds, in pages of pageSize spans.
+type traceSpanReader struct {
+	spanreader.SpanReader
+	spans []*internalspan.InternalSpan
+}
+
+func (sr *traceSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	var matching []*internalspan.InternalSpan
+	for _, span := range sr.spans {
+		if matchesFilters(span, r) {
+			matching = append(matching, span)
+		}
+	}
+
+	offset, _ := strconv.Atoi(string(r.Metadata.NextToken))
+	end := offset + pageSize
+	metadata := &spansquery.Metadata{NextToken: spansquery.ContinuationToken(strconv.Itoa(end))}
+	if end >= len(matching) {
+		end = len(matching)
+		metadata.NextToken = ""
+	}
+	return &spansquery.SearchResponse{Metadata: metadata, Spans: matching[offset:end]}, nil
+}
+
+func matchesFilters(span *internalspan.InternalSpan, r spansquery.SearchRequest) bool {
+	for _, f := range r.SearchFilters {
+		switch f.KeyValueFilter.Key {
+		case "span.traceId":
+			if span.Span.TraceId != f.KeyValueFilter.Value {
+				return false
+			}
+		case "span.name":
+			if span.Span.Name != f.KeyValueFilter.Value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func newSpan(traceId string, spanId string, parentSpanId string, service string, name string, start uint64, end uint64) *internalspan.InternalSpan {
+	return &internalspan.InternalSpan{
+		Resource: &internalspan.Resource{Attributes: internalspan.Attributes{"service.name": service}},
+		Span: &internalspan.Span{
+			TraceId:           traceId,
+			SpanId:            spanId,
+			ParentSpanId:      parentSpanId,
+			Name:              name,
+			StartTimeUnixNano: start,
+			EndTimeUnixNano:   end,
+		},
+	}
+}
+
+func newAggregator(maxTraces int) *Aggregator {
+	srMock, _ := mock.NewSpanReaderMock()
+	sr := &traceSpanReader{SpanReader: srMock, spans: []*internalspan.InternalSpan{
+		newSpan("t1", "a", "", "frontend", "GET /cart", 0, 100),
+		newSpan("t1", "b", "a", "cart", "SELECT", 10, 50),
+		newSpan("t1", "c", "a", "cart", "SELECT", 60, 80),
+		newSpan("t2", "d", "", "frontend", "GET /cart", 0, 200),
+		newSpan("t2", "e", "d", "cart", "SELECT", 0, 150),
+		newSpan("t3", "f", "", "frontend", "GET /health", 0, 10),
+	}}
+	return NewAggregator(zap.NewNop(), sr, Config{MaxTraces: maxTraces})
+}
+
+func TestAggregate(t *testing.T) {
+	res, err := newAggregator(10).Aggregate(context.Background(), flamegraph.AggregateRequest{
+		SearchFilters: []model.SearchFilter{{KeyValueFilter: &model.KeyValueFilter{
+			Key: "span.name", Operator: spansquery.OPERATOR_EQUALS, Value: "SELECT",
+		}}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res.TraceCount)
+	assert.False(t, res.Truncated)
+	assert.Equal(t, &flamegraph.Node{
+		Name:          "all",
+		Count:         2,
+		TotalTimeNano: 300,
+		Children: []*flamegraph.Node{{
+			Name:          "GET /cart",
+			ServiceName:   "frontend",
+			Count:         2,
+			TotalTimeNano: 300,
+			SelfTimeNano:  40 + 50,
+			Children: []*flamegraph.Node{{
+				Name:          "SELECT",
+				ServiceName:   "cart",
+				Count:         3,
+				TotalTimeNano: 40 + 20 + 150,
+				SelfTimeNano:  40 + 20 + 150,
+				Children:      []*flamegraph.Node{},
+			}},
+		}},
+	}, res.Root)
+}
+
+func TestAggregateTruncates(t *testing.T) {
+	res, err := newAggregator(2).Aggregate(context.Background(), flamegraph.AggregateRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res.TraceCount)
+	assert.True(t, res.Truncated)
+	assert.Len(t, res.Root.Children, 1)
+}
@@ -0,0 +1,221 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package incompletetraces
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	incompletetraces "github.com/teletrace/teletrace/pkg/model/incompletetraces/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"go.uber.org/zap"
+)
+
+// Config bounds the work of a single detection.
+type Config struct {
+	// MaxSpans is the maximum number of spans scanned for suspected incomplete traces
+	MaxSpans int
+	// MaxTraces is the maximum number of suspected traces verified by fetching all their spans
+	MaxTraces int
+	// GracePeriod excludes the most recent spans, whose traces may still be being ingested
+	GracePeriod time.Duration
+}
+
+// Detector finds traces missing their root span, or holding spans referencing absent parents,
+// which typically indicate a misconfigured exporter or dropped data.
+type Detector struct {
+	logger     *zap.Logger
+	spanReader spanreader.SpanReader
+	cfg        Config
+}
+
+func NewDetector(logger *zap.Logger, sr spanreader.SpanReader, cfg Config) *Detector {
+	return &Detector{
+		logger:     logger,
+		spanReader: sr,
+		cfg:        cfg,
+	}
+}
+
+// Detect scans the spans matching the request, and verifies the traces that look incomplete within the scanned spans
+// against all their spans, as their missing spans may be out of the timeframe or filtered out.
+func (d *Detector) Detect(ctx context.Context, req incompletetraces.DetectRequest) (*incompletetraces.DetectResponse, error) {
+	timeframe := req.Timeframe
+	if latest := uint64(time.Now().Add(-d.cfg.GracePeriod).UnixNano()); timeframe.EndTime > latest {
+		timeframe.EndTime = latest
+	}
+
+	scanned, truncated, err := d.scan(ctx, timeframe, req.SearchFilters)
+	if err != nil {
+		return nil, fmt.Errorf("could not scan spans: %w", err)
+	}
+
+	suspectedTraceIds := suspectedTraces(scanned)
+	if len(suspectedTraceIds) > d.cfg.MaxTraces {
+		suspectedTraceIds = suspectedTraceIds[:d.cfg.MaxTraces]
+		truncated = true
+	}
+
+	traces := []incompletetraces.IncompleteTrace{}
+	for _, traceId := range suspectedTraceIds {
+		spans, err := spanreader.TraceSpans(ctx, d.spanReader, traceId)
+		if err != nil {
+			return nil, fmt.Errorf("could not get spans of trace %s: %w", traceId, err)
+		}
+		if trace, ok := checkTrace(traceId, spans); ok {
+			traces = append(traces, trace)
+		}
+	}
+
+	return &incompletetraces.DetectResponse{
+		IncompleteTraces: traces,
+		Services:         summarizeServices(traces),
+		ScannedSpans:     countSpans(scanned),
+		ScannedTraces:    len(scanned),
+		Truncated:        truncated,
+	}, nil
+}
+
+// scan returns the spans matching the request grouped by trace, up to the configured maximum number of spans.
+func (d *Detector) scan(ctx context.Context, timeframe model.Timeframe, filters []model.SearchFilter) (map[string][]*internalspan.InternalSpan, bool, error) {
+	spansByTrace := make(map[string][]*internalspan.InternalSpan)
+	scannedSpans := 0
+	var token spansquery.ContinuationToken
+	for {
+		res, err := d.spanReader.Search(ctx, spansquery.SearchRequest{
+			Timeframe:     timeframe,
+			Sort:          []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: false}},
+			SearchFilters: spanreader.CopyFilters(filters),
+			Metadata:      &spansquery.Metadata{NextToken: token},
+		})
+		if err != nil {
+			return nil, false, err
+		}
+
+		for _, span := range res.Spans {
+			if scannedSpans == d.cfg.MaxSpans {
+				return spansByTrace, true, nil
+			}
+			spansByTrace[span.Span.TraceId] = append(spansByTrace[span.Span.TraceId], span)
+			scannedSpans++
+		}
+
+		if len(res.Spans) == 0 || res.Metadata == nil || res.Metadata.NextToken == "" {
+			return spansByTrace, false, nil
+		}
+		token = res.Metadata.NextToken
+	}
+}
+
+// suspectedTraces returns the IDs of the traces that are incomplete within the scanned spans, sorted for stable results.
+func suspectedTraces(spansByTrace map[string][]*internalspan.InternalSpan) []string {
+	var traceIds []string
+	for traceId, spans := range spansByTrace {
+		if _, incomplete := checkTrace(traceId, spans); incomplete {
+			traceIds = append(traceIds, traceId)
+		}
+	}
+	sort.Strings(traceIds)
+	return traceIds
+}
+
+// checkTrace returns the trace details if it's missing its root span or has orphan spans.
+func checkTrace(traceId string, spans []*internalspan.InternalSpan) (incompletetraces.IncompleteTrace, bool) {
+	spanIds := make(map[string]bool, len(spans))
+	for _, span := range spans {
+		spanIds[span.Span.SpanId] = true
+	}
+
+	trace := incompletetraces.IncompleteTrace{
+		TraceId:     traceId,
+		SpanCount:   len(spans),
+		MissingRoot: true,
+		OrphanSpans: []incompletetraces.OrphanSpan{},
+	}
+	services := make(map[string]bool)
+	for _, span := range spans {
+		service := spanreader.ServiceName(span)
+		services[service] = true
+		if span.Span.ParentSpanId == "" {
+			trace.MissingRoot = false
+		} else if !spanIds[span.Span.ParentSpanId] {
+			trace.OrphanSpans = append(trace.OrphanSpans, incompletetraces.OrphanSpan{
+				SpanId:       span.Span.SpanId,
+				ParentSpanId: span.Span.ParentSpanId,
+				Name:         span.Span.Name,
+				Service:      service,
+			})
+		}
+	}
+	if !trace.MissingRoot && len(trace.OrphanSpans) == 0 {
+		return trace, false
+	}
+
+	for service := range services {
+		trace.Services = append(trace.Services, service)
+	}
+	sort.Strings(trace.Services)
+	sort.Slice(trace.OrphanSpans, func(i, j int) bool {
+		return trace.OrphanSpans[i].SpanId < trace.OrphanSpans[j].SpanId
+	})
+	return trace, true
+}
+
+func summarizeServices(traces []incompletetraces.IncompleteTrace) []incompletetraces.ServiceSummary {
+	summaries := make(map[string]*incompletetraces.ServiceSummary)
+	for _, trace := range traces {
+		traceServices := make(map[string]bool)
+		for _, orphan := range trace.OrphanSpans {
+			summary, ok := summaries[orphan.Service]
+			if !ok {
+				summary = &incompletetraces.ServiceSummary{Service: orphan.Service}
+				summaries[orphan.Service] = summary
+			}
+			summary.OrphanSpans++
+			if !traceServices[orphan.Service] {
+				traceServices[orphan.Service] = true
+				summary.IncompleteTraces++
+			}
+		}
+	}
+
+	result := make([]incompletetraces.ServiceSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, *summary)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].OrphanSpans != result[j].OrphanSpans {
+			return result[i].OrphanSpans > result[j].OrphanSpans
+		}
+		return result[i].Service < result[j].Service
+	})
+	return result
+}
+
+func countSpans(spansByTrace map[string][]*internalspan.InternalSpan) int {
+	count := 0
+	for _, spans := range spansByTrace {
+		count += len(spans)
+	}
+	return count
+}
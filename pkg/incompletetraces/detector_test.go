@@ -0,0 +1,168 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package incompletetraces
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	incompletetraces "github.com/teletrace/teletrace/pkg/model/incompletetraces/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+const pageSize = 2
+
+// traceSpanReader serves the spans it holds by timeframe or trace ID, in pages of pageSize spans.
+type traceSpanReader struct {
+	spanreader.SpanReader
+	spans []*internalspan.InternalSpan
+}
+
+func (sr *traceSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	var matching []*internalspan.InternalSpan
+	for _, span := range sr.spans {
+		if span.Span.StartTimeUnixNano < r.Timeframe.StartTime || span.Span.StartTimeUnixNano > r.Timeframe.EndTime {
+			continue
+		}
+		if len(r.SearchFilters) > 0 && span.Span.TraceId != r.SearchFilters[0].KeyValueFilter.Value {
+			continue
+		}
+		matching = append(matching, span)
+	}
+
+	offset, _ := strconv.Atoi(string(r.Metadata.NextToken))
+	end := offset + pageSize
+	metadata := &spansquery.Metadata{NextToken: spansquery.ContinuationToken(strconv.Itoa(end))}
+	if end >= len(matching) {
+		end = len(matching)
+		metadata.NextToken = ""
+	}
+	return &spansquery.SearchResponse{Metadata: metadata, Spans: matching[offset:end]}, nil
+}
+
+func newSpan(traceId string, spanId string, parentSpanId string, service string, start uint64) *internalspan.InternalSpan {
+	return &internalspan.InternalSpan{
+		Resource: &internalspan.Resource{Attributes: internalspan.Attributes{"service.name": service}},
+		Span: &internalspan.Span{
+			TraceId:           traceId,
+			SpanId:            spanId,
+			ParentSpanId:      parentSpanId,
+			Name:              spanId,
+			StartTimeUnixNano: start,
+		},
+	}
+}
+
+func newTestDetector(spans []*internalspan.InternalSpan, cfg Config) *Detector {
+	srMock, _ := mock.NewSpanReaderMock()
+	return NewDetector(zap.NewNop(), &traceSpanReader{SpanReader: srMock, spans: spans}, cfg)
+}
+
+func TestDetect(t *testing.T) {
+	spans := []*internalspan.InternalSpan{
+		// complete
+		newSpan("complete", "a", "", "frontend", 100),
+		newSpan("complete", "b", "a", "orders", 110),
+		// the root span was dropped
+		newSpan("missing-root", "c", "dropped", "orders", 120),
+		newSpan("missing-root", "d", "c", "orders", 130),
+		// the payments spans weren't exported
+		newSpan("orphans", "e", "", "frontend", 140),
+		newSpan("orphans", "f", "e", "orders", 150),
+		newSpan("orphans", "g", "payments", "inventory", 160),
+		// the root span started before the timeframe, but the trace is complete
+		newSpan("started-before", "h", "", "frontend", 10),
+		newSpan("started-before", "i", "h", "orders", 170),
+	}
+	detector := newTestDetector(spans, Config{MaxSpans: 100, MaxTraces: 10})
+
+	res, err := detector.Detect(context.Background(), incompletetraces.DetectRequest{
+		Timeframe: model.Timeframe{StartTime: 100, EndTime: 200},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []incompletetraces.IncompleteTrace{
+		{
+			TraceId:     "missing-root",
+			SpanCount:   2,
+			MissingRoot: true,
+			OrphanSpans: []incompletetraces.OrphanSpan{{SpanId: "c", ParentSpanId: "dropped", Name: "c", Service: "orders"}},
+			Services:    []string{"orders"},
+		},
+		{
+			TraceId:     "orphans",
+			SpanCount:   3,
+			MissingRoot: false,
+			OrphanSpans: []incompletetraces.OrphanSpan{{SpanId: "g", ParentSpanId: "payments", Name: "g", Service: "inventory"}},
+			Services:    []string{"frontend", "inventory", "orders"},
+		},
+	}, res.IncompleteTraces)
+	assert.Equal(t, []incompletetraces.ServiceSummary{
+		{Service: "inventory", IncompleteTraces: 1, OrphanSpans: 1},
+		{Service: "orders", IncompleteTraces: 1, OrphanSpans: 1},
+	}, res.Services)
+	assert.Equal(t, 8, res.ScannedSpans)
+	assert.Equal(t, 4, res.ScannedTraces)
+	assert.False(t, res.Truncated)
+}
+
+func TestDetectTruncated(t *testing.T) {
+	spans := []*internalspan.InternalSpan{
+		newSpan("trace-a", "a", "dropped", "orders", 100),
+		newSpan("trace-b", "b", "dropped", "orders", 110),
+		newSpan("trace-c", "c", "dropped", "orders", 120),
+	}
+
+	res, err := newTestDetector(spans, Config{MaxSpans: 2, MaxTraces: 10}).Detect(context.Background(), incompletetraces.DetectRequest{
+		Timeframe: model.Timeframe{StartTime: 0, EndTime: 200},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res.ScannedSpans)
+	assert.Len(t, res.IncompleteTraces, 2)
+	assert.True(t, res.Truncated)
+
+	res, err = newTestDetector(spans, Config{MaxSpans: 100, MaxTraces: 1}).Detect(context.Background(), incompletetraces.DetectRequest{
+		Timeframe: model.Timeframe{StartTime: 0, EndTime: 200},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, res.IncompleteTraces, 1)
+	assert.True(t, res.Truncated)
+}
+
+func TestDetectGracePeriod(t *testing.T) {
+	now := uint64(time.Now().UnixNano())
+	spans := []*internalspan.InternalSpan{
+		newSpan("in-progress", "a", "not-ingested-yet", "orders", now),
+	}
+	detector := newTestDetector(spans, Config{MaxSpans: 100, MaxTraces: 10, GracePeriod: time.Minute})
+
+	res, err := detector.Detect(context.Background(), incompletetraces.DetectRequest{
+		Timeframe: model.Timeframe{StartTime: 0, EndTime: now + uint64(time.Second)},
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, res.IncompleteTraces)
+	assert.Equal(t, 0, res.ScannedSpans)
+}
@@ -0,0 +1,151 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package ingestionlag
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	ingestionlag "github.com/teletrace/teletrace/pkg/model/ingestionlag/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"go.uber.org/zap"
+)
+
+// Config bounds the work of a single analysis.
+type Config struct {
+	// MaxSpans is the maximum number of spans measured
+	MaxSpans int
+}
+
+// Analyzer measures the ingestion lag of spans, the time from their end until they were ingested, per service,
+// making delays of the ingestion pipeline visible.
+type Analyzer struct {
+	logger     *zap.Logger
+	spanReader spanreader.SpanReader
+	cfg        Config
+}
+
+func NewAnalyzer(logger *zap.Logger, sr spanreader.SpanReader, cfg Config) *Analyzer {
+	return &Analyzer{
+		logger:     logger,
+		spanReader: sr,
+		cfg:        cfg,
+	}
+}
+
+// Analyze measures the most recent spans matching the request, up to the configured maximum number of spans.
+// Spans without an ingestion time, e.g. written by older exporters, are skipped.
+func (a *Analyzer) Analyze(ctx context.Context, req ingestionlag.AnalyzeRequest) (*ingestionlag.AnalyzeResponse, error) {
+	var all []uint64
+	lags := make(map[string][]uint64)
+	scannedSpans := 0
+	truncated := false
+	var token spansquery.ContinuationToken
+	for {
+		res, err := a.spanReader.Search(ctx, spansquery.SearchRequest{
+			Timeframe:     req.Timeframe,
+			Sort:          []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: false}},
+			SearchFilters: spanreader.CopyFilters(req.SearchFilters),
+			Metadata:      &spansquery.Metadata{NextToken: token},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not scan spans: %w", err)
+		}
+
+		for _, span := range res.Spans {
+			if scannedSpans == a.cfg.MaxSpans {
+				truncated = true
+				break
+			}
+			scannedSpans++
+			lag, ok := ingestionLag(span)
+			if !ok {
+				continue
+			}
+			service := spanreader.ServiceName(span)
+			lags[service] = append(lags[service], lag)
+			all = append(all, lag)
+		}
+
+		if truncated || len(res.Spans) == 0 || res.Metadata == nil || res.Metadata.NextToken == "" {
+			break
+		}
+		token = res.Metadata.NextToken
+	}
+
+	services := make([]ingestionlag.ServiceLag, 0, len(lags))
+	for service, serviceLags := range lags {
+		services = append(services, ingestionlag.ServiceLag{Service: service, Lag: distribution(serviceLags)})
+	}
+	sort.Slice(services, func(i, j int) bool {
+		if services[i].P95Nano != services[j].P95Nano {
+			return services[i].P95Nano > services[j].P95Nano
+		}
+		return services[i].Service < services[j].Service
+	})
+
+	return &ingestionlag.AnalyzeResponse{
+		Overall:      distribution(all),
+		Services:     services,
+		ScannedSpans: scannedSpans,
+		Truncated:    truncated,
+	}, nil
+}
+
+// ingestionLag returns the time from the end of a span until it was ingested, 0 for spans ingested before their
+// end due to clock skew between the emitter and the collector. ok is false if the span has no ingestion time.
+func ingestionLag(span *internalspan.InternalSpan) (lag uint64, ok bool) {
+	if span.Span == nil || span.IngestionTimeUnixNano == 0 {
+		return 0, false
+	}
+	if span.IngestionTimeUnixNano < span.Span.EndTimeUnixNano {
+		return 0, true
+	}
+	return span.IngestionTimeUnixNano - span.Span.EndTimeUnixNano, true
+}
+
+// distribution returns the distribution of lags, which it sorts.
+func distribution(lags []uint64) ingestionlag.Lag {
+	if len(lags) == 0 {
+		return ingestionlag.Lag{}
+	}
+	sort.Slice(lags, func(i, j int) bool { return lags[i] < lags[j] })
+
+	var sum float64
+	for _, lag := range lags {
+		sum += float64(lag)
+	}
+	return ingestionlag.Lag{
+		Count:   len(lags),
+		MinNano: lags[0],
+		AvgNano: uint64(sum / float64(len(lags))),
+		P50Nano: percentile(lags, 0.5),
+		P95Nano: percentile(lags, 0.95),
+		P99Nano: percentile(lags, 0.99),
+		MaxNano: lags[len(lags)-1],
+	}
+}
+
+// percentile returns the nearest-rank percentile p (0 < p <= 1) of sorted lags.
+func percentile(sorted []uint64, p float64) uint64 {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[rank-1]
+}
@@ -0,0 +1,118 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package ingestionlag
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	ingestionlag "github.com/teletrace/teletrace/pkg/model/ingestionlag/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+const pageSize = 2
+
+// pagedSpanReader serves the spans it holds in pages of pageSize spans.
+type pagedSpanReader struct {
+	spanreader.SpanReader
+	spans    []*internalspan.InternalSpan
+	requests []spansquery.SearchRequest
+}
+
+func (sr *pagedSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	sr.requests = append(sr.requests, r)
+	offset, _ := strconv.Atoi(string(r.Metadata.NextToken))
+	end := offset + pageSize
+	metadata := &spansquery.Metadata{NextToken: spansquery.ContinuationToken(strconv.Itoa(end))}
+	if end >= len(sr.spans) {
+		end = len(sr.spans)
+		metadata = nil
+	}
+	return &spansquery.SearchResponse{Spans: sr.spans[offset:end], Metadata: metadata}, nil
+}
+
+func ingestedSpan(service string, end uint64, ingestion uint64) *internalspan.InternalSpan {
+	return &internalspan.InternalSpan{
+		Resource:              &internalspan.Resource{Attributes: internalspan.Attributes{"service.name": service}},
+		Span:                  &internalspan.Span{EndTimeUnixNano: end},
+		IngestionTimeUnixNano: ingestion,
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	sr := &pagedSpanReader{spans: []*internalspan.InternalSpan{
+		ingestedSpan("checkout", 1000, 1100),
+		ingestedSpan("checkout", 1000, 1300),
+		ingestedSpan("payments", 1000, 6000),
+		ingestedSpan("checkout", 1000, 1200),
+		ingestedSpan("payments", 1000, 0),
+		ingestedSpan("frontend", 1000, 900),
+	}}
+	filters := []model.SearchFilter{{KeyValueFilter: &model.KeyValueFilter{
+		Key: "span.kind", Operator: spansquery.OPERATOR_EQUALS, Value: "Server",
+	}}}
+	analyzer := NewAnalyzer(zap.NewNop(), sr, Config{MaxSpans: 100})
+
+	res, err := analyzer.Analyze(context.Background(), ingestionlag.AnalyzeRequest{SearchFilters: filters})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 6, res.ScannedSpans)
+	assert.False(t, res.Truncated)
+	assert.Equal(t, ingestionlag.Lag{Count: 5, MinNano: 0, AvgNano: 1120, P50Nano: 200, P95Nano: 5000, P99Nano: 5000, MaxNano: 5000}, res.Overall)
+	assert.Equal(t, []ingestionlag.ServiceLag{
+		{Service: "payments", Lag: ingestionlag.Lag{Count: 1, MinNano: 5000, AvgNano: 5000, P50Nano: 5000, P95Nano: 5000, P99Nano: 5000, MaxNano: 5000}},
+		{Service: "checkout", Lag: ingestionlag.Lag{Count: 3, MinNano: 100, AvgNano: 200, P50Nano: 200, P95Nano: 300, P99Nano: 300, MaxNano: 300}},
+		{Service: "frontend", Lag: ingestionlag.Lag{Count: 1}},
+	}, res.Services)
+
+	assert.Len(t, sr.requests, 3)
+	for _, r := range sr.requests {
+		assert.Equal(t, filters, r.SearchFilters)
+	}
+}
+
+func TestAnalyzeTruncatesAtMaxSpans(t *testing.T) {
+	sr := &pagedSpanReader{spans: []*internalspan.InternalSpan{
+		ingestedSpan("checkout", 1000, 1100),
+		ingestedSpan("checkout", 1000, 1200),
+		ingestedSpan("checkout", 1000, 1300),
+	}}
+	analyzer := NewAnalyzer(zap.NewNop(), sr, Config{MaxSpans: 2})
+
+	res, err := analyzer.Analyze(context.Background(), ingestionlag.AnalyzeRequest{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, res.ScannedSpans)
+	assert.True(t, res.Truncated)
+	assert.Equal(t, 2, res.Overall.Count)
+}
+
+func TestAnalyzeWithoutSpans(t *testing.T) {
+	analyzer := NewAnalyzer(zap.NewNop(), &pagedSpanReader{}, Config{MaxSpans: 2})
+
+	res, err := analyzer.Analyze(context.Background(), ingestionlag.AnalyzeRequest{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, ingestionlag.Lag{}, res.Overall)
+	assert.Empty(t, res.Services)
+}
@@ -0,0 +1,118 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package loglinks renders the links from the spans of a trace to their logs in logging backends
+// (e.g. a Loki or Kibana search URL), so the UI can deep-link from a span to its logs.
+package loglinks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// placeholderPattern matches the ${name} placeholders of a template URL
+var placeholderPattern = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// placeholders maps the placeholders of a template URL to the functions returning their values for a span,
+// given the padded start and end times of the span.
+var placeholders = map[string]func(span *internalspan.InternalSpan, start time.Time, end time.Time) string{
+	"trace_id": func(span *internalspan.InternalSpan, _ time.Time, _ time.Time) string { return span.Span.TraceId },
+	"span_id":  func(span *internalspan.InternalSpan, _ time.Time, _ time.Time) string { return span.Span.SpanId },
+	"service_name": func(span *internalspan.InternalSpan, _ time.Time, _ time.Time) string {
+		if span.Resource == nil {
+			return ""
+		}
+		service, _ := span.Resource.Attributes["service.name"].(string)
+		return service
+	},
+	"start_ms": func(_ *internalspan.InternalSpan, start time.Time, _ time.Time) string {
+		return strconv.FormatInt(start.UnixMilli(), 10)
+	},
+	"end_ms": func(_ *internalspan.InternalSpan, _ time.Time, end time.Time) string {
+		return strconv.FormatInt(end.UnixMilli(), 10)
+	},
+	"start_time": func(_ *internalspan.InternalSpan, start time.Time, _ time.Time) string {
+		return start.UTC().Format(time.RFC3339)
+	},
+	"end_time": func(_ *internalspan.InternalSpan, _ time.Time, end time.Time) string {
+		return end.UTC().Format(time.RFC3339)
+	},
+}
+
+// Template is a link to the logs of a span, its URL holding ${name} placeholders replaced with the values of the span.
+type Template struct {
+	// Name is the name of the link shown to the user, e.g. Loki
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// PaddingSeconds widens the time range of the span, so logs emitted right before and after it are included
+	PaddingSeconds int `json:"paddingSeconds"`
+}
+
+// ParseTemplates parses a JSON list of templates, rejecting templates with unknown placeholders.
+func ParseTemplates(value string) ([]Template, error) {
+	var templates []Template
+	if err := json.Unmarshal([]byte(value), &templates); err != nil {
+		return nil, fmt.Errorf("invalid log link templates: %w", err)
+	}
+	for _, t := range templates {
+		if t.Name == "" || t.URL == "" {
+			return nil, fmt.Errorf("invalid log link template %+v, expected a name and a url", t)
+		}
+		if t.PaddingSeconds < 0 {
+			return nil, fmt.Errorf("invalid log link template %s, padding seconds must not be negative", t.Name)
+		}
+		for _, match := range placeholderPattern.FindAllStringSubmatch(t.URL, -1) {
+			if _, ok := placeholders[match[1]]; !ok {
+				return nil, fmt.Errorf("invalid log link template %s, unknown placeholder %s", t.Name, match[0])
+			}
+		}
+	}
+	return templates, nil
+}
+
+// Render returns the links of each of the spans to its logs by span ID, the values of the placeholders being
+// URL-escaped. Returns nil if there are no templates.
+func Render(templates []Template, spans []*internalspan.InternalSpan) map[string][]spansquery.LogLink {
+	if len(templates) == 0 {
+		return nil
+	}
+	links := make(map[string][]spansquery.LogLink, len(spans))
+	for _, span := range spans {
+		for _, t := range templates {
+			links[span.Span.SpanId] = append(links[span.Span.SpanId], spansquery.LogLink{Name: t.Name, URL: t.render(span)})
+		}
+	}
+	return links
+}
+
+func (t Template) render(span *internalspan.InternalSpan) string {
+	padding := time.Duration(t.PaddingSeconds) * time.Second
+	start := time.Unix(0, int64(span.Span.StartTimeUnixNano)).Add(-padding)
+	end := time.Unix(0, int64(span.Span.EndTimeUnixNano)).Add(padding)
+	return placeholderPattern.ReplaceAllStringFunc(t.URL, func(placeholder string) string {
+		value := placeholders[placeholder[2:len(placeholder)-1]](span, start, end)
+		// query escaping is valid in both the path and the query, once spaces aren't escaped as +
+		return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+	})
+}
@@ -0,0 +1,79 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loglinks
+
+import (
+	"testing"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSpan(spanId string, service string, start uint64, end uint64) *internalspan.InternalSpan {
+	return &internalspan.InternalSpan{
+		Resource: &internalspan.Resource{Attributes: map[string]any{"service.name": service}},
+		Span: &internalspan.Span{
+			TraceId:           "trace",
+			SpanId:            spanId,
+			StartTimeUnixNano: start,
+			EndTimeUnixNano:   end,
+		},
+	}
+}
+
+func TestParseTemplates(t *testing.T) {
+	templates, err := ParseTemplates(`[{"name": "Loki", "url": "http://loki/explore?q=${trace_id}", "paddingSeconds": 5}]`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Template{{Name: "Loki", URL: "http://loki/explore?q=${trace_id}", PaddingSeconds: 5}}, templates)
+}
+
+func TestParseTemplatesRejectsInvalidTemplates(t *testing.T) {
+	for _, value := range []string{
+		`{"name": "Loki"}`,
+		`[{"url": "http://loki"}]`,
+		`[{"name": "Loki"}]`,
+		`[{"name": "Loki", "url": "http://loki", "paddingSeconds": -1}]`,
+		`[{"name": "Loki", "url": "http://loki/explore?q=${traceId}"}]`,
+	} {
+		_, err := ParseTemplates(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestRenderReplacesPlaceholders(t *testing.T) {
+	templates := []Template{
+		{Name: "Loki", URL: `http://loki/explore?q={service="${service_name}"} |= "${trace_id}"&from=${start_ms}&to=${end_ms}`},
+		{Name: "Kibana", URL: "http://kibana/app/discover#/?time=(from:'${start_time}',to:'${end_time}')&span=${span_id}", PaddingSeconds: 60},
+	}
+	span := newSpan("span", "checkout api", 1_700_000_000_500_000_000, 1_700_000_001_000_000_000)
+
+	links := Render(templates, []*internalspan.InternalSpan{span})
+
+	assert.Equal(t, map[string][]spansquery.LogLink{
+		"span": {
+			{Name: "Loki", URL: `http://loki/explore?q={service="checkout%20api"} |= "trace"&from=1700000000500&to=1700000001000`},
+			{Name: "Kibana", URL: "http://kibana/app/discover#/?time=(from:'2023-11-14T22%3A12%3A20Z',to:'2023-11-14T22%3A14%3A21Z')&span=span"},
+		},
+	}, links)
+}
+
+func TestRenderWithoutTemplates(t *testing.T) {
+	assert.Nil(t, Render(nil, []*internalspan.InternalSpan{newSpan("span", "api", 1, 2)}))
+}
@@ -17,19 +17,51 @@
 package logs
 
 import (
+	"fmt"
 	"log"
+	"net/http"
 
 	"github.com/teletrace/teletrace/pkg/config"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
+// level is the level of the loggers created by NewLogger, which can be changed at runtime
+var level = zap.NewAtomicLevel()
+
 // NewLogger returns a new zap logger with custom config based on the debug mode.
+// The log level defaults to debug in debug mode and to info otherwise, unless set by cfg.LogLevel.
 func NewLogger(cfg config.Config) (*zap.Logger, error) {
+	zapCfg := zap.NewProductionConfig()
 	if cfg.Debug {
-		return zap.NewDevelopment()
+		zapCfg = zap.NewDevelopmentConfig()
+	}
+	level.SetLevel(zapCfg.Level.Level())
+	if err := SetLevel(cfg); err != nil {
+		return nil, err
+	}
+	zapCfg.Level = level
+	return zapCfg.Build()
+}
+
+// SetLevel changes the level of the loggers created by NewLogger to cfg.LogLevel, if set.
+func SetLevel(cfg config.Config) error {
+	if cfg.LogLevel == "" {
+		return nil
+	}
+	lvl, err := zapcore.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
 	}
-	return zap.NewProduction()
+	level.SetLevel(lvl)
+	return nil
+}
+
+// LevelHandler returns an HTTP handler reporting the level of the loggers created by NewLogger on GET,
+// and changing it on PUT, e.g. with a {"level":"debug"} body.
+func LevelHandler() http.Handler {
+	return level
 }
 
 // FlushBufferedLogs attempts to flush any buffered log entries of a zap logger.
@@ -17,6 +17,9 @@
 package logs
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/teletrace/teletrace/pkg/config"
@@ -44,3 +47,30 @@ func TestNewProdLogger(t *testing.T) {
 func isLoggerLvlEnabled(logger *zap.Logger, lvl zapcore.Level) bool {
 	return logger.Core().Enabled(lvl)
 }
+
+func TestLevelHandler(t *testing.T) {
+	logger, err := NewLogger(config.Config{Debug: false})
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(`{"level":"debug"}`))
+	resRecorder := httptest.NewRecorder()
+	LevelHandler().ServeHTTP(resRecorder, req)
+
+	assert.Equal(t, http.StatusOK, resRecorder.Code)
+	assert.True(t, isLoggerLvlEnabled(logger, zap.DebugLevel))
+
+	req = httptest.NewRequest(http.MethodGet, "/log/level", nil)
+	resRecorder = httptest.NewRecorder()
+	LevelHandler().ServeHTTP(resRecorder, req)
+	assert.JSONEq(t, `{"level":"debug"}`, resRecorder.Body.String())
+}
+
+func TestLogLevelOverridesDebugMode(t *testing.T) {
+	logger, err := NewLogger(config.Config{Debug: true, LogLevel: "warn"})
+	assert.NoError(t, err)
+	assert.False(t, isLoggerLvlEnabled(logger, zap.InfoLevel))
+	assert.True(t, isLoggerLvlEnabled(logger, zap.WarnLevel))
+
+	_, err = NewLogger(config.Config{LogLevel: "verbose"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,56 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metadatastore
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("metadata record not found")
+	ErrAlreadyExists = errors.New("metadata record already exists")
+)
+
+// Record is a single metadata entry, identified by its namespace and key.
+// Each subsystem (e.g. saved searches, alerts, jobs) owns its own namespace.
+type Record struct {
+	Namespace string
+	Key       string
+	Value     []byte
+	UpdatedAt time.Time
+}
+
+// MetadataStore persists non-span data, used by subsystems that need
+// storage of their own state regardless of the configured spans storage.
+type MetadataStore interface {
+	// Initialize prepares the underlying storage (e.g. creates tables or indices).
+	Initialize() error
+	// Get returns the record stored under namespace and key, or ErrNotFound.
+	Get(ctx context.Context, namespace string, key string) (*Record, error)
+	// List returns all records of a namespace, ordered by key.
+	List(ctx context.Context, namespace string) ([]Record, error)
+	// Put creates or replaces a record.
+	Put(ctx context.Context, record Record) error
+	// Create creates a record, or returns ErrAlreadyExists if it is already stored.
+	Create(ctx context.Context, record Record) error
+	// Delete removes a record, or returns ErrNotFound if it is not stored.
+	Delete(ctx context.Context, namespace string, key string) error
+	// Close releases the resources held by the store.
+	Close() error
+}
@@ -0,0 +1,44 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metadatastore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// GetJSON reads the record stored under namespace and key into v.
+func GetJSON(ctx context.Context, store MetadataStore, namespace string, key string, v interface{}) error {
+	record, err := store.Get(ctx, namespace, key)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(record.Value, v); err != nil {
+		return fmt.Errorf("could not decode metadata record %s/%s: %w", namespace, key, err)
+	}
+	return nil
+}
+
+// PutJSON stores v as JSON under namespace and key.
+func PutJSON(ctx context.Context, store MetadataStore, namespace string, key string, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("could not encode metadata record %s/%s: %w", namespace, key, err)
+	}
+	return store.Put(ctx, Record{Namespace: namespace, Key: key, Value: value})
+}
@@ -0,0 +1,118 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+)
+
+type metadataStore struct {
+	mu         sync.RWMutex
+	namespaces map[string]map[string]metadatastore.Record
+}
+
+// NewMetadataStore returns a MetadataStore that keeps records in memory only.
+// Records are lost on restart, which makes it suitable for tests and single-node development setups.
+func NewMetadataStore() metadatastore.MetadataStore {
+	return &metadataStore{
+		namespaces: make(map[string]map[string]metadatastore.Record),
+	}
+}
+
+func (s *metadataStore) Initialize() error {
+	return nil
+}
+
+func (s *metadataStore) Get(ctx context.Context, namespace string, key string) (*metadatastore.Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.namespaces[namespace][key]
+	if !ok {
+		return nil, metadatastore.ErrNotFound
+	}
+	record.Value = copyValue(record.Value)
+	return &record, nil
+}
+
+func (s *metadataStore) List(ctx context.Context, namespace string) ([]metadatastore.Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := make([]metadatastore.Record, 0, len(s.namespaces[namespace]))
+	for _, record := range s.namespaces[namespace] {
+		record.Value = copyValue(record.Value)
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Key < records[j].Key })
+	return records, nil
+}
+
+func (s *metadataStore) Put(ctx context.Context, record metadatastore.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.put(record)
+	return nil
+}
+
+func (s *metadataStore) Create(ctx context.Context, record metadatastore.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.namespaces[record.Namespace][record.Key]; ok {
+		return metadatastore.ErrAlreadyExists
+	}
+	s.put(record)
+	return nil
+}
+
+func (s *metadataStore) Delete(ctx context.Context, namespace string, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.namespaces[namespace][key]; !ok {
+		return metadatastore.ErrNotFound
+	}
+	delete(s.namespaces[namespace], key)
+	return nil
+}
+
+func (s *metadataStore) Close() error {
+	return nil
+}
+
+// put stores a record, must be called with the lock held.
+func (s *metadataStore) put(record metadatastore.Record) {
+	records, ok := s.namespaces[record.Namespace]
+	if !ok {
+		records = make(map[string]metadatastore.Record)
+		s.namespaces[record.Namespace] = records
+	}
+	record.Value = copyValue(record.Value)
+	record.UpdatedAt = time.Now().UTC()
+	records[record.Key] = record
+}
+
+func copyValue(value []byte) []byte {
+	return append([]byte(nil), value...)
+}
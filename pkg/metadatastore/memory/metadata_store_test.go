@@ -0,0 +1,72 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadataStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMetadataStore()
+	assert.NoError(t, store.Initialize())
+
+	_, err := store.Get(ctx, "alerts", "a")
+	assert.ErrorIs(t, err, metadatastore.ErrNotFound)
+
+	assert.NoError(t, store.Create(ctx, metadatastore.Record{Namespace: "alerts", Key: "b", Value: []byte("2")}))
+	assert.ErrorIs(t, store.Create(ctx, metadatastore.Record{Namespace: "alerts", Key: "b"}), metadatastore.ErrAlreadyExists)
+	assert.NoError(t, store.Put(ctx, metadatastore.Record{Namespace: "alerts", Key: "a", Value: []byte("1")}))
+	assert.NoError(t, store.Put(ctx, metadatastore.Record{Namespace: "jobs", Key: "c", Value: []byte("3")}))
+
+	record, err := store.Get(ctx, "alerts", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("1"), record.Value)
+	assert.False(t, record.UpdatedAt.IsZero())
+
+	records, err := store.List(ctx, "alerts")
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "a", records[0].Key)
+	assert.Equal(t, "b", records[1].Key)
+
+	assert.NoError(t, store.Delete(ctx, "alerts", "a"))
+	assert.ErrorIs(t, store.Delete(ctx, "alerts", "a"), metadatastore.ErrNotFound)
+
+	records, err = store.List(ctx, "alerts")
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+}
+
+func TestJSONHelpers(t *testing.T) {
+	type settings struct {
+		Theme string `json:"theme"`
+	}
+	ctx := context.Background()
+	store := NewMetadataStore()
+
+	assert.NoError(t, metadatastore.PutJSON(ctx, store, "settings", "user", settings{Theme: "dark"}))
+
+	var actual settings
+	assert.NoError(t, metadatastore.GetJSON(ctx, store, "settings", "user", &actual))
+	assert.Equal(t, "dark", actual.Theme)
+}
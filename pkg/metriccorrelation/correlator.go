@@ -0,0 +1,287 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metriccorrelation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	metriccorrelation "github.com/teletrace/teletrace/pkg/model/metriccorrelation/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"go.uber.org/zap"
+)
+
+const (
+	traceIdTag   = "span.traceId"
+	serviceTag   = "resource.attributes.service.name"
+	operationTag = "span.name"
+	durationTag  = "externalFields.durationNano"
+)
+
+// serviceLabels are the metric labels naming the service of a series, the job label of the series exported by
+// the OpenTelemetry Prometheus exporters being service.namespace/service.name
+var serviceLabels = []string{"service_name", "service", "job"}
+
+// operationLabels are the metric labels naming the operation of a series, i.e. the name of its spans
+var operationLabels = []string{"span_name", "operation"}
+
+// Config configures the correlation of metrics to traces.
+type Config struct {
+	// PrometheusURL is the URL of the Prometheus API the exemplars are queried from, exemplars aren't queried if empty
+	PrometheusURL string
+	// Timeout bounds a single exemplars query
+	Timeout time.Duration
+	// MaxSpans is the maximum number of spans scanned to match a metric heuristically
+	MaxSpans int
+}
+
+// Correlator finds the traces responsible for the series of a metric: the traces referenced by the exemplars of
+// the series, followed by the slowest traces of the service of the series within the timeframe.
+type Correlator struct {
+	logger     *zap.Logger
+	spanReader spanreader.SpanReader
+	client     *http.Client
+	cfg        Config
+	now        func() time.Time
+}
+
+func NewCorrelator(logger *zap.Logger, sr spanreader.SpanReader, cfg Config) *Correlator {
+	return &Correlator{
+		logger:     logger,
+		spanReader: sr,
+		client:     &http.Client{Timeout: cfg.Timeout},
+		cfg:        cfg,
+		now:        time.Now,
+	}
+}
+
+// Correlate returns up to the request limit of candidate traces of the series of the metric. The traces of
+// exemplars are only returned if their spans are found, so traces past their retention, or hidden by the access
+// control, are left out. A failure to query the exemplars is reported in the response rather than failing it.
+func (c *Correlator) Correlate(ctx context.Context, req metriccorrelation.CorrelateRequest) (*metriccorrelation.CorrelateResponse, error) {
+	limit := req.EffectiveLimit()
+	res := &metriccorrelation.CorrelateResponse{Candidates: []metriccorrelation.Candidate{}}
+	seen := make(map[string]bool)
+
+	if c.cfg.PrometheusURL != "" {
+		exemplars, err := c.queryExemplars(ctx, req.Metric, req.Labels, req.Timeframe)
+		if err != nil {
+			c.logger.Warn("Failed to query exemplars", zap.String("metric", req.Metric), zap.Error(err))
+			res.ExemplarsError = err.Error()
+		}
+		// each exemplar trace is looked up, so at most limit traces are
+		lookups := 0
+		for _, e := range exemplars {
+			if seen[e.traceId] {
+				continue
+			}
+			if lookups == limit {
+				break
+			}
+			seen[e.traceId] = true
+			lookups++
+			candidate, err := c.exemplarCandidate(ctx, e)
+			if err != nil {
+				return nil, err
+			}
+			if candidate != nil {
+				res.Candidates = append(res.Candidates, *candidate)
+			}
+		}
+	}
+
+	service := labelValue(req.Labels, serviceLabels)
+	if len(res.Candidates) == limit || service == "" {
+		return res, nil
+	}
+	if i := strings.LastIndex(service, "/"); i >= 0 {
+		service = service[i+1:]
+	}
+	candidates, truncated, err := c.match(ctx, req, service)
+	if err != nil {
+		return nil, err
+	}
+	res.Truncated = truncated
+	for _, candidate := range candidates {
+		if len(res.Candidates) == limit {
+			break
+		}
+		if !seen[candidate.TraceId] {
+			res.Candidates = append(res.Candidates, candidate)
+		}
+	}
+	return res, nil
+}
+
+// exemplarCandidate returns the candidate of the trace of an exemplar, described by the span which recorded the
+// exemplar, or else by the root span of the trace. Returns nil if the trace isn't found.
+func (c *Correlator) exemplarCandidate(ctx context.Context, e exemplar) (*metriccorrelation.Candidate, error) {
+	res, err := c.spanReader.Search(ctx, spansquery.SearchRequest{
+		Timeframe: model.Timeframe{EndTime: uint64(c.now().UnixNano())},
+		SearchFilters: []model.SearchFilter{{KeyValueFilter: &model.KeyValueFilter{
+			Key:      traceIdTag,
+			Operator: spansquery.OPERATOR_EQUALS,
+			Value:    e.traceId,
+		}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get the trace of exemplar %s: %w", e.traceId, err)
+	}
+	span := describingSpan(res.Spans, e.spanId)
+	if span == nil {
+		return nil, nil
+	}
+	candidate := newCandidate(span, metriccorrelation.SOURCE_EXEMPLAR)
+	candidate.TimeUnixNano = e.timeUnixNano
+	value := e.value
+	candidate.ExemplarValue = &value
+	return &candidate, nil
+}
+
+// describingSpan returns the span with spanId, or else the root span, or else the earliest span.
+func describingSpan(spans []*internalspan.InternalSpan, spanId string) *internalspan.InternalSpan {
+	var result *internalspan.InternalSpan
+	for _, span := range spans {
+		if spanId != "" && span.Span.SpanId == spanId {
+			return span
+		}
+		if result == nil || precedes(span, result) {
+			result = span
+		}
+	}
+	return result
+}
+
+// precedes returns whether a describes a trace better than b: root spans first, then the earliest.
+func precedes(a *internalspan.InternalSpan, b *internalspan.InternalSpan) bool {
+	if isRoot := a.Span.ParentSpanId == ""; isRoot != (b.Span.ParentSpanId == "") {
+		return isRoot
+	}
+	return a.Span.StartTimeUnixNano < b.Span.StartTimeUnixNano
+}
+
+// match returns the candidates of the traces with spans of the service, and of the operation if the labels name
+// it, within the timeframe, each described by its slowest matching span, the slowest first. Returns whether more
+// spans matched than the configured maximum number of scanned spans.
+func (c *Correlator) match(ctx context.Context, req metriccorrelation.CorrelateRequest, service string) ([]metriccorrelation.Candidate, bool, error) {
+	filters := []model.SearchFilter{{KeyValueFilter: &model.KeyValueFilter{
+		Key:      serviceTag,
+		Operator: spansquery.OPERATOR_EQUALS,
+		Value:    service,
+	}}}
+	if operation := labelValue(req.Labels, operationLabels); operation != "" {
+		filters = append(filters, model.SearchFilter{KeyValueFilter: &model.KeyValueFilter{
+			Key:      operationTag,
+			Operator: spansquery.OPERATOR_EQUALS,
+			Value:    operation,
+		}})
+	}
+	if req.MinDurationNano > 0 {
+		filters = append(filters, model.SearchFilter{KeyValueFilter: &model.KeyValueFilter{
+			Key:      durationTag,
+			Operator: spansquery.OPERATOR_GTE,
+			Value:    float64(req.MinDurationNano),
+		}})
+	}
+
+	slowest := make(map[string]*internalspan.InternalSpan)
+	scannedSpans := 0
+	truncated := false
+	var token spansquery.ContinuationToken
+	for {
+		res, err := c.spanReader.Search(ctx, spansquery.SearchRequest{
+			Timeframe:     req.Timeframe,
+			Sort:          []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: false}},
+			SearchFilters: spanreader.CopyFilters(filters),
+			Metadata:      &spansquery.Metadata{NextToken: token},
+		})
+		if err != nil {
+			return nil, false, fmt.Errorf("could not scan the spans of service %s: %w", service, err)
+		}
+
+		for _, span := range res.Spans {
+			if scannedSpans == c.cfg.MaxSpans {
+				truncated = true
+				break
+			}
+			scannedSpans++
+			if s, ok := slowest[span.Span.TraceId]; !ok || duration(span) > duration(s) {
+				slowest[span.Span.TraceId] = span
+			}
+		}
+
+		if truncated || len(res.Spans) == 0 || res.Metadata == nil || res.Metadata.NextToken == "" {
+			break
+		}
+		token = res.Metadata.NextToken
+	}
+
+	candidates := make([]metriccorrelation.Candidate, 0, len(slowest))
+	for _, span := range slowest {
+		candidates = append(candidates, newCandidate(span, metriccorrelation.SOURCE_HEURISTIC))
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].DurationNano != candidates[j].DurationNano {
+			return candidates[i].DurationNano > candidates[j].DurationNano
+		}
+		if candidates[i].TimeUnixNano != candidates[j].TimeUnixNano {
+			return candidates[i].TimeUnixNano > candidates[j].TimeUnixNano
+		}
+		return candidates[i].TraceId < candidates[j].TraceId
+	})
+	return candidates, truncated, nil
+}
+
+func newCandidate(span *internalspan.InternalSpan, source string) metriccorrelation.Candidate {
+	candidate := metriccorrelation.Candidate{
+		TraceId:      span.Span.TraceId,
+		Source:       source,
+		SpanId:       span.Span.SpanId,
+		SpanName:     span.Span.Name,
+		TimeUnixNano: span.Span.StartTimeUnixNano,
+		DurationNano: duration(span),
+	}
+	if span.Resource != nil {
+		candidate.ServiceName, _ = span.Resource.Attributes["service.name"].(string)
+	}
+	return candidate
+}
+
+func duration(span *internalspan.InternalSpan) uint64 {
+	if span.Span.EndTimeUnixNano < span.Span.StartTimeUnixNano {
+		return 0
+	}
+	return span.Span.EndTimeUnixNano - span.Span.StartTimeUnixNano
+}
+
+// labelValue returns the value of the first of the names set in labels.
+func labelValue(labels map[string]string, names []string) string {
+	for _, name := range names {
+		if value := labels[name]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,219 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metriccorrelation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	metriccorrelation "github.com/teletrace/teletrace/pkg/model/metriccorrelation/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+const pageSize = 2
+
+// filteringSpanReader serves the spans it holds matching the trace ID and service filters, in pages of pageSize spans.
+type filteringSpanReader struct {
+	spanreader.SpanReader
+	spans    []*internalspan.InternalSpan
+	requests []spansquery.SearchRequest
+}
+
+func (sr *filteringSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	sr.requests = append(sr.requests, r)
+	var matching []*internalspan.InternalSpan
+	for _, span := range sr.spans {
+		if matches(span, r.SearchFilters) {
+			matching = append(matching, span)
+		}
+	}
+	offset := 0
+	if r.Metadata != nil {
+		offset, _ = strconv.Atoi(string(r.Metadata.NextToken))
+	}
+	end := offset + pageSize
+	metadata := &spansquery.Metadata{NextToken: spansquery.ContinuationToken(strconv.Itoa(end))}
+	if end >= len(matching) {
+		end = len(matching)
+		metadata = nil
+	}
+	return &spansquery.SearchResponse{Spans: matching[offset:end], Metadata: metadata}, nil
+}
+
+func matches(span *internalspan.InternalSpan, filters []model.SearchFilter) bool {
+	for _, f := range filters {
+		switch f.KeyValueFilter.Key {
+		case traceIdTag:
+			if span.Span.TraceId != f.KeyValueFilter.Value {
+				return false
+			}
+		case serviceTag:
+			if span.Resource.Attributes["service.name"] != f.KeyValueFilter.Value {
+				return false
+			}
+		case durationTag:
+			if float64(duration(span)) < f.KeyValueFilter.Value.(float64) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func newSpan(traceId string, spanId string, parentSpanId string, service string, start uint64, end uint64) *internalspan.InternalSpan {
+	return &internalspan.InternalSpan{
+		Resource: &internalspan.Resource{Attributes: internalspan.Attributes{"service.name": service}},
+		Span: &internalspan.Span{
+			TraceId:           traceId,
+			SpanId:            spanId,
+			ParentSpanId:      parentSpanId,
+			Name:              spanId,
+			StartTimeUnixNano: start,
+			EndTimeUnixNano:   end,
+		},
+	}
+}
+
+func newCorrelator(sr spanreader.SpanReader, prometheusURL string) *Correlator {
+	return NewCorrelator(zap.NewNop(), sr, Config{PrometheusURL: prometheusURL, Timeout: time.Second, MaxSpans: 100})
+}
+
+func TestSelector(t *testing.T) {
+	assert.Equal(t, `http_requests_total{job="shop/checkout",route="/\"cart\""}`,
+		selector("http_requests_total", map[string]string{"route": `/"cart"`, "job": "shop/checkout"}))
+	assert.Equal(t, "up{}", selector("up", nil))
+}
+
+func TestCorrelateReturnsExemplarTracesFirst(t *testing.T) {
+	var query string
+	prometheus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/query_exemplars", r.URL.Path)
+		query = r.URL.Query().Get("query")
+		assert.Equal(t, "1700000000.000", r.URL.Query().Get("start"))
+		_, _ = w.Write([]byte(`{"status": "success", "data": [{
+			"seriesLabels": {"__name__": "http_server_duration_seconds_bucket", "job": "shop/checkout"},
+			"exemplars": [
+				{"labels": {"trace_id": "t1"}, "value": "0.2", "timestamp": 1700000001.5},
+				{"labels": {"trace_id": "t2", "span_id": "t2-child"}, "value": "1.5", "timestamp": 1700000002},
+				{"labels": {"trace_id": "missing"}, "value": "0.1", "timestamp": 1700000003},
+				{"labels": {"other": "label"}, "value": "9", "timestamp": 1700000004}
+			]
+		}]}`))
+	}))
+	defer prometheus.Close()
+	sr := &filteringSpanReader{spans: []*internalspan.InternalSpan{
+		newSpan("t1", "t1-child", "t1-root", "checkout", 1_700_000_001_100_000_000, 1_700_000_001_200_000_000),
+		newSpan("t1", "t1-root", "", "frontend", 1_700_000_001_000_000_000, 1_700_000_001_300_000_000),
+		newSpan("t2", "t2-root", "", "frontend", 1_700_000_001_000_000_000, 1_700_000_003_000_000_000),
+		newSpan("t2", "t2-child", "t2-root", "checkout", 1_700_000_001_100_000_000, 1_700_000_002_600_000_000),
+		newSpan("t3", "t3-root", "", "checkout", 1_700_000_005_000_000_000, 1_700_000_006_000_000_000),
+	}}
+
+	res, err := newCorrelator(sr, prometheus.URL).Correlate(context.Background(), metriccorrelation.CorrelateRequest{
+		Metric:    "http_server_duration_seconds_bucket",
+		Labels:    map[string]string{"job": "shop/checkout"},
+		Timeframe: model.Timeframe{StartTime: 1_700_000_000_000_000_000, EndTime: 1_700_000_010_000_000_000},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `http_server_duration_seconds_bucket{job="shop/checkout"}`, query)
+	assert.Empty(t, res.ExemplarsError)
+	highest, lowest := 1.5, 0.2
+	assert.Equal(t, []metriccorrelation.Candidate{
+		{
+			TraceId: "t2", Source: metriccorrelation.SOURCE_EXEMPLAR, SpanId: "t2-child", ServiceName: "checkout",
+			SpanName: "t2-child", TimeUnixNano: 1_700_000_002_000_000_000, DurationNano: 1_500_000_000, ExemplarValue: &highest,
+		},
+		{
+			TraceId: "t1", Source: metriccorrelation.SOURCE_EXEMPLAR, SpanId: "t1-root", ServiceName: "frontend",
+			SpanName: "t1-root", TimeUnixNano: 1_700_000_001_500_000_000, DurationNano: 300_000_000, ExemplarValue: &lowest,
+		},
+		{
+			TraceId: "t3", Source: metriccorrelation.SOURCE_HEURISTIC, SpanId: "t3-root", ServiceName: "checkout",
+			SpanName: "t3-root", TimeUnixNano: 1_700_000_005_000_000_000, DurationNano: 1_000_000_000,
+		},
+	}, res.Candidates)
+}
+
+func TestCorrelateMatchesSlowestTracesOfService(t *testing.T) {
+	sr := &filteringSpanReader{spans: []*internalspan.InternalSpan{
+		newSpan("t1", "t1-a", "", "checkout", 100, 200),
+		newSpan("t1", "t1-b", "t1-a", "checkout", 110, 600),
+		newSpan("t2", "t2-a", "", "checkout", 300, 350),
+		newSpan("t3", "t3-a", "", "checkout", 400, 1400),
+		newSpan("t4", "t4-a", "", "frontend", 100, 9000),
+	}}
+
+	res, err := newCorrelator(sr, "").Correlate(context.Background(), metriccorrelation.CorrelateRequest{
+		Metric:          "http_server_duration_seconds_bucket",
+		Labels:          map[string]string{"service_name": "checkout"},
+		Timeframe:       model.Timeframe{StartTime: 0, EndTime: 10000},
+		MinDurationNano: 100,
+		Limit:           2,
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, res.Candidates, 2)
+	assert.Equal(t, "t3", res.Candidates[0].TraceId)
+	assert.Equal(t, uint64(1000), res.Candidates[0].DurationNano)
+	assert.Equal(t, "t1", res.Candidates[1].TraceId)
+	assert.Equal(t, "t1-b", res.Candidates[1].SpanId)
+	assert.Equal(t, metriccorrelation.SOURCE_HEURISTIC, res.Candidates[1].Source)
+	assert.False(t, res.Truncated)
+}
+
+func TestCorrelateReportsFailedExemplarsQuery(t *testing.T) {
+	prometheus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"status": "error", "errorType": "bad_data", "error": "invalid parameter"}`))
+	}))
+	defer prometheus.Close()
+	sr := &filteringSpanReader{spans: []*internalspan.InternalSpan{newSpan("t1", "t1-a", "", "checkout", 100, 200)}}
+
+	res, err := newCorrelator(sr, prometheus.URL).Correlate(context.Background(), metriccorrelation.CorrelateRequest{
+		Metric:    "http_requests_total",
+		Labels:    map[string]string{"service": "checkout"},
+		Timeframe: model.Timeframe{StartTime: 0, EndTime: 10000},
+	})
+
+	assert.NoError(t, err)
+	assert.Contains(t, res.ExemplarsError, "invalid parameter")
+	assert.Len(t, res.Candidates, 1)
+}
+
+func TestCorrelateWithoutServiceLabel(t *testing.T) {
+	sr := &filteringSpanReader{spans: []*internalspan.InternalSpan{newSpan("t1", "t1-a", "", "checkout", 100, 200)}}
+
+	res, err := newCorrelator(sr, "").Correlate(context.Background(), metriccorrelation.CorrelateRequest{
+		Metric:    "http_requests_total",
+		Timeframe: model.Timeframe{StartTime: 0, EndTime: 10000},
+	})
+
+	assert.NoError(t, err)
+	assert.Empty(t, res.Candidates)
+	assert.Empty(t, sr.requests)
+}
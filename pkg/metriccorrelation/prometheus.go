@@ -0,0 +1,130 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metriccorrelation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/teletrace/teletrace/pkg/model"
+)
+
+// traceIdLabels and spanIdLabels are the exemplar labels holding the trace and span IDs, as named by the
+// OpenTelemetry and Prometheus client libraries
+var (
+	traceIdLabels = []string{"trace_id", "traceID", "traceId", "TraceID"}
+	spanIdLabels  = []string{"span_id", "spanID", "spanId", "SpanID"}
+)
+
+// exemplar is an observation of a metric series referencing the trace it was recorded within.
+type exemplar struct {
+	traceId      string
+	spanId       string
+	value        float64
+	timeUnixNano uint64
+}
+
+// exemplarsResponse is the response of the Prometheus exemplars query API.
+type exemplarsResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   []struct {
+		Exemplars []struct {
+			Labels    map[string]string `json:"labels"`
+			Value     string            `json:"value"`
+			Timestamp float64           `json:"timestamp"`
+		} `json:"exemplars"`
+	} `json:"data"`
+}
+
+// queryExemplars returns the exemplars of the series of the metric within the timeframe which reference a trace,
+// the highest values first, then the most recent.
+func (c *Correlator) queryExemplars(ctx context.Context, metric string, labels map[string]string, timeframe model.Timeframe) ([]exemplar, error) {
+	query := url.Values{}
+	query.Set("query", selector(metric, labels))
+	query.Set("start", formatSeconds(timeframe.StartTime))
+	query.Set("end", formatSeconds(timeframe.EndTime))
+	endpoint := strings.TrimSuffix(c.cfg.PrometheusURL, "/") + "/api/v1/query_exemplars?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not query exemplars: %w", err)
+	}
+	defer res.Body.Close()
+
+	var body exemplarsResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("could not decode exemplars, Prometheus responded with status %d: %w", res.StatusCode, err)
+	}
+	if body.Status != "success" {
+		return nil, fmt.Errorf("could not query exemplars, Prometheus responded with status %d: %s", res.StatusCode, body.Error)
+	}
+
+	var exemplars []exemplar
+	for _, series := range body.Data {
+		for _, e := range series.Exemplars {
+			traceId := labelValue(e.Labels, traceIdLabels)
+			if traceId == "" {
+				continue
+			}
+			value, err := strconv.ParseFloat(e.Value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid exemplar value %q: %w", e.Value, err)
+			}
+			exemplars = append(exemplars, exemplar{
+				traceId:      traceId,
+				spanId:       labelValue(e.Labels, spanIdLabels),
+				value:        value,
+				timeUnixNano: uint64(e.Timestamp * 1e9),
+			})
+		}
+	}
+	sort.SliceStable(exemplars, func(i, j int) bool {
+		if exemplars[i].value != exemplars[j].value {
+			return exemplars[i].value > exemplars[j].value
+		}
+		return exemplars[i].timeUnixNano > exemplars[j].timeUnixNano
+	})
+	return exemplars, nil
+}
+
+// selector returns the PromQL selector of the series of the metric with the exact label values.
+func selector(metric string, labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	matchers := make([]string, 0, len(names))
+	for _, name := range names {
+		matchers = append(matchers, name+"="+strconv.Quote(labels[name]))
+	}
+	return metric + "{" + strings.Join(matchers, ",") + "}"
+}
+
+func formatSeconds(unixNano uint64) string {
+	return strconv.FormatFloat(float64(unixNano)/1e9, 'f', 3, 64)
+}
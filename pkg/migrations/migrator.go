@@ -0,0 +1,161 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrSchemaTooNew is returned when the storage was migrated by a newer version, which this version can't read.
+	ErrSchemaTooNew = errors.New("storage schema is newer than supported")
+	// ErrLockTimeout is returned when another instance holds the migrations lock for longer than the lock timeout.
+	ErrLockTimeout = errors.New("timed out waiting for the migrations lock")
+)
+
+// Migration upgrades the schema of a storage from the previous version to Version.
+// Migrations should be idempotent, as a migration interrupted before its version was recorded is applied again.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context) error
+}
+
+// Store persists the schema version of a storage, and the lock serializing the migrations of concurrent instances.
+// Each storage plugin implements it on top of its own storage.
+type Store interface {
+	// Version returns the version of the last applied migration, 0 if none was applied
+	Version(ctx context.Context) (int, error)
+	SetVersion(ctx context.Context, version int) error
+	// Lock acquires the lock for owner until expiresAt, and returns false if another owner holds an unexpired lock.
+	// Acquiring a lock the owner holds extends it
+	Lock(ctx context.Context, owner string, expiresAt time.Time) (bool, error)
+	Unlock(ctx context.Context, owner string) error
+}
+
+// Config configures the migrations lock.
+type Config struct {
+	// LockTTL is how long the lock is held, after which another instance may take it over, e.g. if its holder crashed.
+	// It must exceed the duration of the longest migration
+	LockTTL time.Duration
+	// LockTimeout is how long to wait for the lock held by another instance
+	LockTimeout time.Duration
+	// LockRetryInterval is the interval between attempts to acquire the lock
+	LockRetryInterval time.Duration
+}
+
+func NewDefaultConfig() Config {
+	return Config{
+		LockTTL:           5 * time.Minute,
+		LockTimeout:       10 * time.Minute,
+		LockRetryInterval: time.Second,
+	}
+}
+
+// Migrator applies the pending migrations of a storage on startup.
+type Migrator struct {
+	logger     *zap.Logger
+	name       string
+	store      Store
+	migrations []Migration
+	cfg        Config
+	owner      string
+}
+
+// NewMigrator creates a migrator of the storage called name. Migrations must be ordered by version, starting at 1
+// without gaps, and may only be appended to, as the version of a storage is the number of migrations applied to it.
+func NewMigrator(logger *zap.Logger, name string, store Store, migrations []Migration, cfg Config) (*Migrator, error) {
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %q of %s has version %d, expected %d", m.Description, name, m.Version, i+1)
+		}
+	}
+	hostname, _ := os.Hostname()
+	return &Migrator{
+		logger:     logger.With(zap.String("storage", name)),
+		name:       name,
+		store:      store,
+		migrations: migrations,
+		cfg:        cfg,
+		owner:      fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano()),
+	}, nil
+}
+
+// Up applies the pending migrations in order, holding the lock so concurrent instances don't apply them twice.
+// The version is recorded after each migration, so a failed migration is retried on the next startup.
+func (m *Migrator) Up(ctx context.Context) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		// the lock is released even if ctx is done, so other instances don't wait for it to expire
+		if err := m.store.Unlock(context.Background(), m.owner); err != nil {
+			m.logger.Warn("Failed to release the migrations lock", zap.Error(err))
+		}
+	}()
+
+	version, err := m.store.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get the schema version of %s: %w", m.name, err)
+	}
+	latest := len(m.migrations)
+	if version > latest {
+		return fmt.Errorf("%s is at version %d, while the latest supported version is %d: %w", m.name, version, latest, ErrSchemaTooNew)
+	}
+
+	for _, migration := range m.migrations[version:] {
+		m.logger.Info("Applying migration",
+			zap.Int("version", migration.Version), zap.String("description", migration.Description))
+		if _, err := m.store.Lock(ctx, m.owner, time.Now().Add(m.cfg.LockTTL)); err != nil {
+			return fmt.Errorf("could not extend the migrations lock of %s: %w", m.name, err)
+		}
+		if err := migration.Up(ctx); err != nil {
+			return fmt.Errorf("could not apply migration %d of %s: %w", migration.Version, m.name, err)
+		}
+		if err := m.store.SetVersion(ctx, migration.Version); err != nil {
+			return fmt.Errorf("could not record migration %d of %s: %w", migration.Version, m.name, err)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) lock(ctx context.Context) error {
+	deadline := time.Now().Add(m.cfg.LockTimeout)
+	for {
+		locked, err := m.store.Lock(ctx, m.owner, time.Now().Add(m.cfg.LockTTL))
+		if err != nil {
+			return fmt.Errorf("could not acquire the migrations lock of %s: %w", m.name, err)
+		}
+		if locked {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("could not migrate %s: %w", m.name, ErrLockTimeout)
+		}
+		m.logger.Info("Waiting for the migrations lock held by another instance")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(m.cfg.LockRetryInterval):
+		}
+	}
+}
@@ -0,0 +1,136 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package migrations
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type memoryStore struct {
+	mu        sync.Mutex
+	version   int
+	owner     string
+	expiresAt time.Time
+}
+
+func (s *memoryStore) Version(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version, nil
+}
+
+func (s *memoryStore) SetVersion(ctx context.Context, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = version
+	return nil
+}
+
+func (s *memoryStore) Lock(ctx context.Context, owner string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.owner != "" && s.owner != owner && time.Now().Before(s.expiresAt) {
+		return false, nil
+	}
+	s.owner, s.expiresAt = owner, expiresAt
+	return true, nil
+}
+
+func (s *memoryStore) Unlock(ctx context.Context, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.owner == owner {
+		s.owner = ""
+	}
+	return nil
+}
+
+func testConfig() Config {
+	return Config{LockTTL: time.Minute, LockTimeout: time.Second, LockRetryInterval: time.Millisecond}
+}
+
+func TestUp(t *testing.T) {
+	store := &memoryStore{}
+	var applied []int
+	var mu sync.Mutex
+	migration := func(version int) Migration {
+		return Migration{Version: version, Up: func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			applied = append(applied, version)
+			return nil
+		}}
+	}
+	migrations := []Migration{migration(1), migration(2)}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			migrator, err := NewMigrator(zap.NewNop(), "test", store, migrations, testConfig())
+			assert.NoError(t, err)
+			assert.NoError(t, migrator.Up(context.Background()))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, []int{1, 2}, applied)
+	assert.Equal(t, 2, store.version)
+	assert.Empty(t, store.owner)
+
+	migrator, _ := NewMigrator(zap.NewNop(), "test", store, append(migrations, migration(3)), testConfig())
+	assert.NoError(t, migrator.Up(context.Background()))
+	assert.Equal(t, []int{1, 2, 3}, applied)
+}
+
+func TestUpFailure(t *testing.T) {
+	store := &memoryStore{}
+	migrations := []Migration{
+		{Version: 1, Up: func(ctx context.Context) error { return nil }},
+		{Version: 2, Up: func(ctx context.Context) error { return errors.New("failed") }},
+	}
+	migrator, _ := NewMigrator(zap.NewNop(), "test", store, migrations, testConfig())
+	assert.Error(t, migrator.Up(context.Background()))
+	assert.Equal(t, 1, store.version)
+	assert.Empty(t, store.owner)
+}
+
+func TestUpSchemaTooNew(t *testing.T) {
+	store := &memoryStore{version: 2}
+	migrator, _ := NewMigrator(zap.NewNop(), "test", store, []Migration{{Version: 1}}, testConfig())
+	assert.ErrorIs(t, migrator.Up(context.Background()), ErrSchemaTooNew)
+}
+
+func TestUpLockTimeout(t *testing.T) {
+	store := &memoryStore{owner: "other", expiresAt: time.Now().Add(time.Minute)}
+	migrator, _ := NewMigrator(zap.NewNop(), "test", store, nil, testConfig())
+	assert.ErrorIs(t, migrator.Up(context.Background()), ErrLockTimeout)
+
+	store.expiresAt = time.Now()
+	assert.NoError(t, migrator.Up(context.Background()))
+}
+
+func TestNewMigratorRejectsGaps(t *testing.T) {
+	_, err := NewMigrator(zap.NewNop(), "test", &memoryStore{}, []Migration{{Version: 1}, {Version: 3}}, testConfig())
+	assert.Error(t, err)
+}
@@ -0,0 +1,178 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package alerts
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+const (
+	// CONDITION_SPAN_COUNT compares the number of spans matching the rule filters within the window,
+	// e.g. with a span.duration filter and a threshold of 0 the rule fires on any slow span
+	CONDITION_SPAN_COUNT = "span_count"
+	// CONDITION_ERROR_RATE compares the fraction, between 0 and 1, of the spans matching the rule filters
+	// within the window which have an error status
+	CONDITION_ERROR_RATE = "error_rate"
+)
+
+const (
+	STATE_PENDING = "pending"
+	STATE_OK      = "ok"
+	STATE_FIRING  = "firing"
+	// STATE_ERROR is the state of a rule whose last evaluation failed, its previous state is unknown
+	STATE_ERROR = "error"
+)
+
+const (
+	// WEBHOOK_FORMAT_JSON posts the Notification as is
+	WEBHOOK_FORMAT_JSON = "json"
+	// WEBHOOK_FORMAT_SLACK posts a Slack incoming webhook message
+	WEBHOOK_FORMAT_SLACK = "slack"
+)
+
+const (
+	EVENT_FIRING   = "firing"
+	EVENT_RESOLVED = "resolved"
+)
+
+const (
+	DELIVERY_PENDING   = "pending"
+	DELIVERY_DELIVERED = "delivered"
+	DELIVERY_FAILED    = "failed"
+)
+
+// MaxWindowSeconds is the maximum window of a rule, bounding the spans queried by every evaluation.
+const MaxWindowSeconds = 7 * 24 * 60 * 60
+
+// Rule fires when the value of its condition, over the spans matching its filters within its window, exceeds its threshold.
+type Rule struct {
+	Id            string               `json:"id"`
+	Name          string               `json:"name"`
+	SearchFilters []model.SearchFilter `json:"filters"`
+	Condition     string               `json:"condition"`
+	Threshold     float64              `json:"threshold"`
+	WindowSeconds int                  `json:"windowSeconds"`
+	Webhooks      []Webhook            `json:"webhooks"`
+	// Role is the access control role the rule was created by, its evaluations see the spans of that role only
+	Role              string `json:"role,omitempty"`
+	CreatedAtUnixNano uint64 `json:"createdAtUnixNano"`
+}
+
+// Webhook is notified when its rule starts firing and when it resolves.
+type Webhook struct {
+	Url string `json:"url"`
+	// Format is either WEBHOOK_FORMAT_JSON, the default, or WEBHOOK_FORMAT_SLACK
+	Format string `json:"format,omitempty"`
+}
+
+// Notification is posted to the webhooks of a rule when it starts firing or resolves.
+type Notification struct {
+	RuleId              string  `json:"ruleId"`
+	RuleName            string  `json:"ruleName"`
+	Event               string  `json:"event"`
+	Condition           string  `json:"condition"`
+	Threshold           float64 `json:"threshold"`
+	Value               float64 `json:"value"`
+	EvaluatedAtUnixNano uint64  `json:"evaluatedAtUnixNano"`
+	FiringSinceUnixNano uint64  `json:"firingSinceUnixNano"`
+	// TraceLinks link to example traces matching the rule, sent when it starts firing
+	TraceLinks []string `json:"traceLinks,omitempty"`
+}
+
+// Delivery tracks the delivery of a notification to a webhook.
+type Delivery struct {
+	Url               string `json:"url"`
+	Event             string `json:"event"`
+	Status            string `json:"status"`
+	Attempts          int    `json:"attempts"`
+	Error             string `json:"error,omitempty"`
+	UpdatedAtUnixNano uint64 `json:"updatedAtUnixNano"`
+}
+
+// RuleState is the result of the last evaluation of a rule.
+type RuleState struct {
+	State string `json:"state"`
+	// Value is the value of the rule condition, compared to its threshold
+	Value               float64 `json:"value"`
+	Error               string  `json:"error,omitempty"`
+	EvaluatedAtUnixNano uint64  `json:"evaluatedAtUnixNano,omitempty"`
+	// FiringSinceUnixNano is the time of the evaluation the rule started firing at
+	FiringSinceUnixNano uint64 `json:"firingSinceUnixNano,omitempty"`
+}
+
+// RuleStatus is a rule with its current state.
+type RuleStatus struct {
+	Rule
+	State RuleState `json:"state"`
+	// Deliveries are the recent notifications of the rule, the most recent first
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+type CreateRuleRequest struct {
+	Name          string               `json:"name"`
+	SearchFilters []model.SearchFilter `json:"filters"`
+	Condition     string               `json:"condition"`
+	Threshold     float64              `json:"threshold"`
+	WindowSeconds int                  `json:"windowSeconds"`
+	Webhooks      []Webhook            `json:"webhooks"`
+}
+
+func (r *CreateRuleRequest) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch r.Condition {
+	case CONDITION_SPAN_COUNT:
+		if r.Threshold < 0 {
+			return fmt.Errorf("threshold of %s cannot be negative", r.Condition)
+		}
+	case CONDITION_ERROR_RATE:
+		if r.Threshold < 0 || r.Threshold >= 1 {
+			return fmt.Errorf("threshold of %s must be between 0 and 1", r.Condition)
+		}
+	default:
+		return fmt.Errorf("condition must be %s or %s", CONDITION_SPAN_COUNT, CONDITION_ERROR_RATE)
+	}
+	if r.WindowSeconds <= 0 || r.WindowSeconds > MaxWindowSeconds {
+		return fmt.Errorf("windowSeconds must be between 1 and %d", MaxWindowSeconds)
+	}
+	for _, webhook := range r.Webhooks {
+		if err := webhook.validate(); err != nil {
+			return err
+		}
+	}
+
+	return spansquery.ValidateFilters(r.SearchFilters)
+}
+
+type GetRulesResponse struct {
+	Rules []RuleStatus `json:"rules"`
+}
+
+func (w Webhook) validate() error {
+	u, err := url.Parse(w.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook url must be an absolute http or https url")
+	}
+	if w.Format != "" && w.Format != WEBHOOK_FORMAT_JSON && w.Format != WEBHOOK_FORMAT_SLACK {
+		return fmt.Errorf("webhook format must be %s or %s", WEBHOOK_FORMAT_JSON, WEBHOOK_FORMAT_SLACK)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package annotations
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// MaxTextLength is the maximum length of the text of an annotation, in characters.
+const MaxTextLength = 10000
+
+// Annotation is a note attached to a trace, or to a single span of it, e.g. an incident finding.
+type Annotation struct {
+	Id      string `json:"id"`
+	TraceId string `json:"traceId"`
+	// SpanId is set for annotations of a single span
+	SpanId            string `json:"spanId,omitempty"`
+	Author            string `json:"author"`
+	Text              string `json:"text"`
+	CreatedAtUnixNano uint64 `json:"createdAtUnixNano"`
+}
+
+// AddAnnotationRequest attaches a note to the trace of the request path.
+type AddAnnotationRequest struct {
+	// SpanId attaches the note to a single span of the trace
+	SpanId string `json:"spanId"`
+	Author string `json:"author"`
+	Text   string `json:"text"`
+}
+
+func (r *AddAnnotationRequest) Validate() error {
+	if r.Author == "" {
+		return fmt.Errorf("author is required")
+	}
+	if r.Text == "" {
+		return fmt.Errorf("text is required")
+	}
+	if utf8.RuneCountInString(r.Text) > MaxTextLength {
+		return fmt.Errorf("text cannot be longer than %d characters", MaxTextLength)
+	}
+	return nil
+}
+
+type GetAnnotationsResponse struct {
+	Annotations []Annotation `json:"annotations"`
+}
@@ -0,0 +1,44 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package anomalies
+
+// Finding is a latency regression of an operation: a latency statistic of its spans within the window
+// exceeding the same statistic within the baseline window by at least the regression ratio.
+type Finding struct {
+	Service   string `json:"service"`
+	Operation string `json:"operation"`
+	// Statistic is the compared latency statistic, e.g. p99
+	Statistic string `json:"statistic"`
+	// BaselineNano and CurrentNano are the statistic within the baseline window and the window, in nanoseconds
+	BaselineNano  float64 `json:"baselineNano"`
+	CurrentNano   float64 `json:"currentNano"`
+	Ratio         float64 `json:"ratio"`
+	Spans         int     `json:"spans"`
+	BaselineSpans int     `json:"baselineSpans"`
+}
+
+type GetFindingsResponse struct {
+	// Findings are the regressions found by the last detection, the largest ratio first, then by service and operation
+	Findings []Finding `json:"findings"`
+	// AnalyzedOperations is the number of operations with enough spans in both windows to be compared
+	AnalyzedOperations    int    `json:"analyzedOperations"`
+	WindowStartUnixNano   uint64 `json:"windowStartUnixNano"`
+	WindowEndUnixNano     uint64 `json:"windowEndUnixNano"`
+	BaselineStartUnixNano uint64 `json:"baselineStartUnixNano"`
+	BaselineEndUnixNano   uint64 `json:"baselineEndUnixNano"`
+	// DetectedAtUnixNano is the time of the last detection, 0 until the first detection completes
+	DetectedAtUnixNano uint64 `json:"detectedAtUnixNano"`
+}
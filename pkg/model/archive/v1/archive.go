@@ -0,0 +1,80 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package archive
+
+import (
+	"fmt"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// ArchiveRequest archives the traces with the given IDs, or if no IDs are given,
+// the traces of the most recent spans matching the timeframe and filters.
+type ArchiveRequest struct {
+	TraceIds      []string             `json:"traceIds,omitempty"`
+	Timeframe     model.Timeframe      `json:"timeframe"`
+	SearchFilters []model.SearchFilter `json:"filters"`
+}
+
+type ArchiveResponse struct {
+	// TraceIds are the IDs of the archived traces, traces without spans are not archived
+	TraceIds []string `json:"traceIds"`
+	// Truncated is set if more traces matched the filters than were archived
+	Truncated bool `json:"truncated"`
+}
+
+// RestoreRequest restores the archived traces with the given IDs into the live backend.
+type RestoreRequest struct {
+	TraceIds []string `json:"traceIds"`
+}
+
+type RestoreResponse struct {
+	TraceIds  []string `json:"traceIds"`
+	SpanCount int      `json:"spanCount"`
+}
+
+func (r *ArchiveRequest) Validate() error {
+	if len(r.TraceIds) > 0 {
+		return validateTraceIds(r.TraceIds)
+	}
+	if err := r.Timeframe.Validate(); err != nil {
+		return err
+	}
+
+	return spansquery.ValidateFilters(r.SearchFilters)
+}
+
+func (r *RestoreRequest) Validate() error {
+	if len(r.TraceIds) == 0 {
+		return fmt.Errorf("at least one trace ID is required")
+	}
+	return validateTraceIds(r.TraceIds)
+}
+
+func validateTraceIds(traceIds []string) error {
+	for _, traceId := range traceIds {
+		if traceId == "" {
+			return fmt.Errorf("trace IDs must not be empty")
+		}
+		for _, c := range traceId {
+			if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+				return fmt.Errorf("invalid trace ID %q, expected a hex string", traceId)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package audit
+
+// Actions of the audited requests
+const (
+	ActionQuery  = "query"
+	ActionExport = "export"
+	ActionDelete = "delete"
+)
+
+// Entry records an audited API request, who sent it, what it asked for and how it was answered.
+type Entry struct {
+	Id                string `json:"id"`
+	TimestampUnixNano uint64 `json:"timestampUnixNano"`
+	// User is the authenticated user of the request, empty if it had none
+	User string `json:"user,omitempty"`
+	// APIKeyFingerprint identifies the API key of the request without revealing it, empty if it had none
+	APIKeyFingerprint string `json:"apiKeyFingerprint,omitempty"`
+	ClientIP          string `json:"clientIp"`
+	Action            string `json:"action"`
+	Method            string `json:"method"`
+	Route             string `json:"route"`
+	Path              string `json:"path"`
+	StatusCode        int    `json:"statusCode"`
+	// Summary describes the request, e.g. its timeframe and filters
+	Summary string `json:"summary,omitempty"`
+	// ResultCount is the number of spans, events or traces returned, if the request returns any
+	ResultCount *int `json:"resultCount,omitempty"`
+}
+
+type GetAuditLogResponse struct {
+	// Entries are the matching audit entries, the most recent first
+	Entries []Entry `json:"entries"`
+}
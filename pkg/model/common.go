@@ -19,6 +19,10 @@ package model
 type Timeframe struct {
 	StartTime uint64 `json:"startTimeUnixNanoSec"`
 	EndTime   uint64 `json:"endTimeUnixNanoSec"`
+	// Start and End are time expressions relative to now (e.g. "now-15m") or RFC3339 timestamps, resolved into
+	// StartTime and EndTime when the request is handled, so saved searches and shared links keep a moving time range
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
 }
 
 type ContinuationToken string
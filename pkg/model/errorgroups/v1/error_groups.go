@@ -0,0 +1,57 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package errorgroups
+
+import (
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// AnalyzeRequest groups the error spans matching the timeframe and filters.
+type AnalyzeRequest struct {
+	Timeframe     model.Timeframe      `json:"timeframe"`
+	SearchFilters []model.SearchFilter `json:"filters"`
+}
+
+// ErrorGroup is a group of error spans with the same exception type, or the same status message
+// for error spans without an exception.
+type ErrorGroup struct {
+	ExceptionType string `json:"exceptionType,omitempty"`
+	// Message is the exception message, or the status message, of the most recent span of the group
+	Message           string   `json:"message"`
+	Services          []string `json:"services"`
+	Count             int      `json:"count"`
+	FirstSeenUnixNano uint64   `json:"firstSeenUnixNano"`
+	LastSeenUnixNano  uint64   `json:"lastSeenUnixNano"`
+	// ExampleTraceIds are the traces of the most recent spans of the group
+	ExampleTraceIds []string `json:"exampleTraceIds"`
+}
+
+type AnalyzeResponse struct {
+	// Groups are the error groups, the most frequent first
+	Groups []ErrorGroup `json:"groups"`
+	// ScannedSpans is the number of error spans grouped, Truncated is set if more spans matched
+	ScannedSpans int  `json:"scannedSpans"`
+	Truncated    bool `json:"truncated"`
+}
+
+func (r *AnalyzeRequest) Validate() error {
+	if err := r.Timeframe.Validate(); err != nil {
+		return err
+	}
+
+	return spansquery.ValidateFilters(r.SearchFilters)
+}
@@ -0,0 +1,82 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package eventsquery
+
+import (
+	"fmt"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+const (
+	// DefaultLimit is the number of events returned by searches without a limit
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// SearchRequest searches the span events matching a name and attributes, within the timeframe.
+type SearchRequest struct {
+	Timeframe model.Timeframe `json:"timeframe"`
+	// Name is the exact name of the events, e.g. "exception". Events of any name match if empty
+	Name string `json:"name,omitempty"`
+	// Attributes are the attribute values the events must have, e.g. {"exception.type": "TimeoutError"}
+	Attributes map[string]any `json:"attributes,omitempty"`
+	// SearchFilters restrict the spans owning the events
+	SearchFilters []model.SearchFilter `json:"filters"`
+	Limit         int                  `json:"limit"`
+}
+
+// Event is a span event, with the identifiers of the span owning it.
+type Event struct {
+	TraceId      string         `json:"traceId"`
+	SpanId       string         `json:"spanId"`
+	SpanName     string         `json:"spanName"`
+	ServiceName  string         `json:"serviceName,omitempty"`
+	Name         string         `json:"name"`
+	TimeUnixNano uint64         `json:"timeUnixNano"`
+	Attributes   map[string]any `json:"attributes"`
+}
+
+type SearchResponse struct {
+	// Events are the matching events, the most recent first
+	Events []Event `json:"events"`
+}
+
+func (r *SearchRequest) Validate() error {
+	if err := r.Timeframe.Validate(); err != nil {
+		return err
+	}
+	if r.Limit < 0 || r.Limit > MaxLimit {
+		return fmt.Errorf("limit must be between 0 and %d", MaxLimit)
+	}
+	for key, value := range r.Attributes {
+		switch value.(type) {
+		case string, float64, bool:
+		default:
+			return fmt.Errorf("value of attribute %s must be a string, a number or a boolean", key)
+		}
+	}
+	return spansquery.ValidateFilters(r.SearchFilters)
+}
+
+// EffectiveLimit returns the number of events the request returns at most.
+func (r *SearchRequest) EffectiveLimit() int {
+	if r.Limit > 0 {
+		return r.Limit
+	}
+	return DefaultLimit
+}
@@ -0,0 +1,58 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package flamegraph
+
+import (
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// AggregateRequest merges the traces of the spans matching the timeframe and filters into a flamegraph.
+type AggregateRequest struct {
+	Timeframe     model.Timeframe      `json:"timeframe"`
+	SearchFilters []model.SearchFilter `json:"filters"`
+}
+
+// Node is a call path of the flamegraph: the spans of a service and name, whose ancestors are the
+// call path of the parent node, across all the aggregated traces.
+type Node struct {
+	Name        string `json:"name"`
+	ServiceName string `json:"serviceName,omitempty"`
+	// Count is the number of spans of the call path
+	Count int `json:"count"`
+	// TotalTimeNano is the cumulative duration of the spans of the call path
+	TotalTimeNano uint64 `json:"totalTimeNano"`
+	// SelfTimeNano is the cumulative duration of the spans not covered by any of their children
+	SelfTimeNano uint64 `json:"selfTimeNano"`
+	// Children are ordered by their total time, the largest first
+	Children []*Node `json:"children"`
+}
+
+type AggregateResponse struct {
+	// Root is the root of the flamegraph, whose children are the root spans of the traces
+	Root *Node `json:"root"`
+	// TraceCount is the number of traces aggregated, Truncated is set if more traces matched
+	TraceCount int  `json:"traceCount"`
+	Truncated  bool `json:"truncated"`
+}
+
+func (r *AggregateRequest) Validate() error {
+	if err := r.Timeframe.Validate(); err != nil {
+		return err
+	}
+
+	return spansquery.ValidateFilters(r.SearchFilters)
+}
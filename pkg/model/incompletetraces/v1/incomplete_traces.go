@@ -0,0 +1,71 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package incompletetraces
+
+import (
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// DetectRequest looks for incomplete traces among the spans matching the timeframe and filters.
+type DetectRequest struct {
+	Timeframe     model.Timeframe      `json:"timeframe"`
+	SearchFilters []model.SearchFilter `json:"filters"`
+}
+
+// OrphanSpan is a span referencing a parent span which is absent from its trace.
+type OrphanSpan struct {
+	SpanId       string `json:"spanId"`
+	ParentSpanId string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Service      string `json:"service"`
+}
+
+// IncompleteTrace is a trace missing its root span, or holding orphan spans.
+type IncompleteTrace struct {
+	TraceId     string       `json:"traceId"`
+	SpanCount   int          `json:"spanCount"`
+	MissingRoot bool         `json:"missingRoot"`
+	OrphanSpans []OrphanSpan `json:"orphanSpans"`
+	// Services are the services of the spans of the trace
+	Services []string `json:"services"`
+}
+
+// ServiceSummary counts the orphan spans of a service, i.e. spans whose parent, typically emitted by its caller, is absent.
+type ServiceSummary struct {
+	Service          string `json:"service"`
+	IncompleteTraces int    `json:"incompleteTraces"`
+	OrphanSpans      int    `json:"orphanSpans"`
+}
+
+type DetectResponse struct {
+	IncompleteTraces []IncompleteTrace `json:"incompleteTraces"`
+	Services         []ServiceSummary  `json:"services"`
+	// ScannedSpans and ScannedTraces are the spans matching the request that were scanned, and their traces.
+	// Truncated is set if more spans matched, or more traces were suspected than could be verified.
+	ScannedSpans  int  `json:"scannedSpans"`
+	ScannedTraces int  `json:"scannedTraces"`
+	Truncated     bool `json:"truncated"`
+}
+
+func (r *DetectRequest) Validate() error {
+	if err := r.Timeframe.Validate(); err != nil {
+		return err
+	}
+
+	return spansquery.ValidateFilters(r.SearchFilters)
+}
@@ -0,0 +1,63 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package ingestionlag
+
+import (
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// AnalyzeRequest measures the ingestion lag of the spans matching the timeframe and filters.
+type AnalyzeRequest struct {
+	Timeframe     model.Timeframe      `json:"timeframe"`
+	SearchFilters []model.SearchFilter `json:"filters"`
+}
+
+// Lag is the distribution of the ingestion lag of spans, the time from their end until they were ingested,
+// in nanoseconds.
+type Lag struct {
+	Count   int    `json:"count"`
+	MinNano uint64 `json:"minNano"`
+	AvgNano uint64 `json:"avgNano"`
+	P50Nano uint64 `json:"p50Nano"`
+	P95Nano uint64 `json:"p95Nano"`
+	P99Nano uint64 `json:"p99Nano"`
+	MaxNano uint64 `json:"maxNano"`
+}
+
+// ServiceLag is the ingestion lag of the spans of a service.
+type ServiceLag struct {
+	Service string `json:"service"`
+	Lag
+}
+
+type AnalyzeResponse struct {
+	// Overall is the ingestion lag of all the scanned spans with an ingestion time
+	Overall Lag `json:"overall"`
+	// Services are the ingestion lags of each service, the most lagging (by p95) first
+	Services []ServiceLag `json:"services"`
+	// ScannedSpans is the number of spans scanned, Truncated is set if more spans matched
+	ScannedSpans int  `json:"scannedSpans"`
+	Truncated    bool `json:"truncated"`
+}
+
+func (r *AnalyzeRequest) Validate() error {
+	if err := r.Timeframe.Validate(); err != nil {
+		return err
+	}
+
+	return spansquery.ValidateFilters(r.SearchFilters)
+}
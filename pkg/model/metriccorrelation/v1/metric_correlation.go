@@ -0,0 +1,106 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metriccorrelation
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/teletrace/teletrace/pkg/model"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// The sources of candidate traces
+const (
+	// SOURCE_EXEMPLAR traces are referenced by the exemplars of the metric series
+	SOURCE_EXEMPLAR = "exemplar"
+	// SOURCE_HEURISTIC traces have spans of the service of the metric series within the timeframe
+	SOURCE_HEURISTIC = "heuristic"
+)
+
+var (
+	metricNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNamePattern  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// CorrelateRequest finds the traces responsible for the series of a metric within the timeframe,
+// e.g. the series of a firing Prometheus alert.
+type CorrelateRequest struct {
+	// Metric is the name of the metric, e.g. http_server_duration_seconds_bucket
+	Metric string `json:"metric"`
+	// Labels select the series of the metric by their exact values
+	Labels    map[string]string `json:"labels,omitempty"`
+	Timeframe model.Timeframe   `json:"timeframe"`
+	// MinDurationNano restricts the heuristically matched spans to the slower ones, e.g. to the threshold of
+	// a latency alert
+	MinDurationNano uint64 `json:"minDurationNano,omitempty"`
+	// Limit is the maximum number of candidate traces, DefaultLimit if not set
+	Limit int `json:"limit,omitempty"`
+}
+
+// Candidate is a trace which may be responsible for the series of the metric.
+type Candidate struct {
+	TraceId string `json:"traceId"`
+	Source  string `json:"source"`
+	// SpanId is the span the exemplar was recorded by, or the slowest matched span of the trace
+	SpanId      string `json:"spanId"`
+	ServiceName string `json:"serviceName"`
+	SpanName    string `json:"spanName"`
+	// TimeUnixNano is the time of the exemplar, or the start time of the span
+	TimeUnixNano uint64 `json:"timeUnixNano"`
+	DurationNano uint64 `json:"durationNano"`
+	// ExemplarValue is the value of the exemplar, e.g. the observed latency in seconds
+	ExemplarValue *float64 `json:"exemplarValue,omitempty"`
+}
+
+type CorrelateResponse struct {
+	// Candidates are the traces referenced by exemplars, the highest exemplar values first, followed by the
+	// heuristically matched traces, the slowest first
+	Candidates []Candidate `json:"candidates"`
+	// ExemplarsError is set if the exemplars couldn't be queried, so only heuristically matched traces are returned
+	ExemplarsError string `json:"exemplarsError,omitempty"`
+	// Truncated is set if the heuristic matching scanned the maximum number of spans
+	Truncated bool `json:"truncated"`
+}
+
+func (r *CorrelateRequest) Validate() error {
+	if !metricNamePattern.MatchString(r.Metric) {
+		return fmt.Errorf("invalid metric name %q", r.Metric)
+	}
+	for name := range r.Labels {
+		if !labelNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+	}
+	if r.Limit < 0 || r.Limit > MaxLimit {
+		return fmt.Errorf("limit must be between 0 and %d", MaxLimit)
+	}
+
+	return r.Timeframe.Validate()
+}
+
+// EffectiveLimit returns the requested limit, or DefaultLimit if not set.
+func (r *CorrelateRequest) EffectiveLimit() int {
+	if r.Limit == 0 {
+		return DefaultLimit
+	}
+	return r.Limit
+}
@@ -0,0 +1,81 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nplusone
+
+import (
+	"fmt"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// DetectRequest looks for N+1 patterns in the traces of the spans matching the timeframe and filters.
+// Zero thresholds fall back to the configured defaults.
+type DetectRequest struct {
+	Timeframe     model.Timeframe      `json:"timeframe"`
+	SearchFilters []model.SearchFilter `json:"filters"`
+	// MinRepetitions is the minimum number of near-identical children of a parent to report them
+	MinRepetitions int `json:"minRepetitions"`
+	// MaxChildDurationNano is the maximum duration of a child to be considered short
+	MaxChildDurationNano uint64 `json:"maxChildDurationNano"`
+}
+
+// Finding is a group of near-identical short db or http children of a single parent span.
+type Finding struct {
+	TraceId        string `json:"traceId"`
+	ParentSpanId   string `json:"parentSpanId"`
+	ParentSpanName string `json:"parentSpanName"`
+	Service        string `json:"service"`
+	// ChildType is either db or http
+	ChildType string `json:"childType"`
+	// ChildSignature is the normalized statement or route shared by the children, e.g. "SELECT * FROM users WHERE id = ?"
+	ChildSignature    string `json:"childSignature"`
+	Repetitions       int    `json:"repetitions"`
+	TotalDurationNano uint64 `json:"totalDurationNano"`
+}
+
+// OperationAggregate summarizes the findings of a single operation, i.e. a parent span name repeating a child signature.
+type OperationAggregate struct {
+	Service          string `json:"service"`
+	ParentSpanName   string `json:"parentSpanName"`
+	ChildType        string `json:"childType"`
+	ChildSignature   string `json:"childSignature"`
+	Traces           int    `json:"traces"`
+	Occurrences      int    `json:"occurrences"`
+	TotalRepetitions int    `json:"totalRepetitions"`
+	MaxRepetitions   int    `json:"maxRepetitions"`
+}
+
+type DetectResponse struct {
+	Findings   []Finding            `json:"findings"`
+	Operations []OperationAggregate `json:"operations"`
+	// AnalyzedTraces is the number of traces analyzed, Truncated is set if more traces had candidate parents
+	AnalyzedTraces int  `json:"analyzedTraces"`
+	Truncated      bool `json:"truncated"`
+}
+
+func (r *DetectRequest) Validate() error {
+	if err := r.Timeframe.Validate(); err != nil {
+		return err
+	}
+
+	if r.MinRepetitions < 0 {
+		return fmt.Errorf("minRepetitions cannot be negative")
+	}
+
+	return spansquery.ValidateFilters(r.SearchFilters)
+}
@@ -0,0 +1,32 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package queryhistory
+
+import (
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// Entry is a search run by a user, as it was requested, so relative timeframes stay relative when it's re-run.
+type Entry struct {
+	Id                 string                   `json:"id"`
+	Request            spansquery.SearchRequest `json:"request"`
+	ExecutedAtUnixNano uint64                   `json:"executedAtUnixNano"`
+}
+
+type GetQueryHistoryResponse struct {
+	// Entries are the recent searches of the user, the most recent first
+	Entries []Entry `json:"entries"`
+}
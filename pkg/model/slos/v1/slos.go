@@ -0,0 +1,120 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package slos
+
+import (
+	"fmt"
+)
+
+const (
+	// SLO_TYPE_AVAILABILITY counts the spans with an error status as bad
+	SLO_TYPE_AVAILABILITY = "availability"
+	// SLO_TYPE_LATENCY counts the spans slower than the latency threshold as bad
+	SLO_TYPE_LATENCY = "latency"
+)
+
+// MaxWindowDays is the maximum compliance window of an SLO, bounding the spans queried for its error budget.
+const MaxWindowDays = 90
+
+// SLO is a service level objective: the fraction of the spans of a service, or of a single operation of it,
+// which should be good over a rolling window.
+type SLO struct {
+	Id      string `json:"id"`
+	Name    string `json:"name"`
+	Service string `json:"service"`
+	// Operation is the span name the SLO is limited to, all the spans of the service if empty
+	Operation string `json:"operation,omitempty"`
+	Type      string `json:"type"`
+	// Objective is the target fraction of good spans, e.g. 0.999
+	Objective float64 `json:"objective"`
+	// LatencyThresholdMilliseconds is the duration above which spans are bad, for latency SLOs
+	LatencyThresholdMilliseconds int `json:"latencyThresholdMilliseconds,omitempty"`
+	WindowDays                   int `json:"windowDays"`
+	// Role is the access control role the SLO was created by, it's computed from the spans of that role only
+	Role              string `json:"role,omitempty"`
+	CreatedAtUnixNano uint64 `json:"createdAtUnixNano"`
+}
+
+// ErrorBudget is the compliance of an SLO over its window.
+type ErrorBudget struct {
+	TotalSpans int `json:"totalSpans"`
+	BadSpans   int `json:"badSpans"`
+	// SLI is the fraction of good spans, 1 when there are no spans
+	SLI float64 `json:"sli"`
+	// AllowedBadSpans is the number of bad spans the objective allows over the window
+	AllowedBadSpans float64 `json:"allowedBadSpans"`
+	// Remaining is the fraction of the error budget left, negative once the objective is missed
+	Remaining float64 `json:"remaining"`
+}
+
+// BurnRate is the pace the error budget is consumed at within a window: 1 consumes exactly the budget
+// over the SLO window, 10 consumes it 10 times faster.
+type BurnRate struct {
+	WindowSeconds int     `json:"windowSeconds"`
+	TotalSpans    int     `json:"totalSpans"`
+	BadSpans      int     `json:"badSpans"`
+	Rate          float64 `json:"rate"`
+}
+
+type CreateSLORequest struct {
+	Name                         string  `json:"name"`
+	Service                      string  `json:"service"`
+	Operation                    string  `json:"operation"`
+	Type                         string  `json:"type"`
+	Objective                    float64 `json:"objective"`
+	LatencyThresholdMilliseconds int     `json:"latencyThresholdMilliseconds"`
+	WindowDays                   int     `json:"windowDays"`
+}
+
+func (r *CreateSLORequest) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.Service == "" {
+		return fmt.Errorf("service is required")
+	}
+	switch r.Type {
+	case SLO_TYPE_AVAILABILITY:
+	case SLO_TYPE_LATENCY:
+		if r.LatencyThresholdMilliseconds <= 0 {
+			return fmt.Errorf("latencyThresholdMilliseconds must be positive for %s SLOs", r.Type)
+		}
+	default:
+		return fmt.Errorf("type must be %s or %s", SLO_TYPE_AVAILABILITY, SLO_TYPE_LATENCY)
+	}
+	if r.Objective <= 0 || r.Objective >= 1 {
+		return fmt.Errorf("objective must be between 0 and 1")
+	}
+	if r.WindowDays <= 0 || r.WindowDays > MaxWindowDays {
+		return fmt.Errorf("windowDays must be between 1 and %d", MaxWindowDays)
+	}
+	return nil
+}
+
+type GetSLOsResponse struct {
+	SLOs []SLO `json:"slos"`
+}
+
+// GetSLOResponse is an SLO with its error budget and its burn rates within the default windows.
+type GetSLOResponse struct {
+	SLO
+	ErrorBudget ErrorBudget `json:"errorBudget"`
+	BurnRates   []BurnRate  `json:"burnRates"`
+}
+
+type GetBurnRatesResponse struct {
+	BurnRates []BurnRate `json:"burnRates"`
+}
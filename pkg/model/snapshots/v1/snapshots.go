@@ -0,0 +1,50 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package snapshots
+
+import (
+	"fmt"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+)
+
+// CreateSnapshotRequest snapshots the trace of the request path.
+type CreateSnapshotRequest struct {
+	// ExpiresInSeconds is how long the snapshot is kept, 0 keeps it until it's deleted
+	ExpiresInSeconds int64 `json:"expiresInSeconds"`
+}
+
+func (r *CreateSnapshotRequest) Validate() error {
+	if r.ExpiresInSeconds < 0 {
+		return fmt.Errorf("expiresInSeconds cannot be negative")
+	}
+	return nil
+}
+
+// Snapshot is a copy of all the spans of a trace, shared by its token, which outlives the retention of the trace.
+type Snapshot struct {
+	Token             string `json:"token"`
+	TraceId           string `json:"traceId"`
+	CreatedAtUnixNano uint64 `json:"createdAtUnixNano"`
+	// ExpiresAtUnixNano is omitted for snapshots kept until they're deleted
+	ExpiresAtUnixNano uint64                       `json:"expiresAtUnixNano,omitempty"`
+	Spans             []*internalspan.InternalSpan `json:"spans"`
+}
+
+type CreateSnapshotResponse struct {
+	Token             string `json:"token"`
+	ExpiresAtUnixNano uint64 `json:"expiresAtUnixNano,omitempty"`
+}
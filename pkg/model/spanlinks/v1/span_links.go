@@ -0,0 +1,39 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package spanlinks
+
+import (
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+)
+
+// LinkedSpan is a span at the other end of a span link.
+type LinkedSpan struct {
+	TraceId string `json:"traceId"`
+	SpanId  string `json:"spanId"`
+	// Attributes are the attributes of the link, e.g. the messaging operation it crosses
+	Attributes map[string]any `json:"attributes"`
+	// Span is the linked span, nil if it isn't stored, e.g. when it was already deleted by retention
+	Span *internalspan.InternalSpan `json:"span"`
+}
+
+type GetSpanLinksResponse struct {
+	// Outgoing are the spans the span links to
+	Outgoing []LinkedSpan `json:"outgoing"`
+	// Incoming are the spans linking to the span, the most recent first
+	Incoming []LinkedSpan `json:"incoming"`
+	// Truncated is set if the span has more links, or is linked by more spans, than returned
+	Truncated bool `json:"truncated"`
+}
@@ -0,0 +1,49 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package model
+
+import (
+	"github.com/teletrace/teletrace/pkg/model"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+)
+
+// enumFilterKeys are the keys of the span fields holding OTLP enums, with the functions normalizing their values
+var enumFilterKeys = map[model.FilterKey]func(any) (string, bool){
+	"span.kind":        internalspan.NormalizeSpanKind,
+	"span.status.code": internalspan.NormalizeStatusCode,
+}
+
+// NormalizeFilterValue returns the value of a filter on the span kind or status code as stored, so clients can filter
+// by the OTLP enum names (e.g. SPAN_KIND_SERVER) or numbers instead of the stored names (e.g. Server).
+// Values of other keys, and values which aren't enum values, are returned as is.
+func NormalizeFilterValue(key model.FilterKey, value model.FilterValue) model.FilterValue {
+	normalize, ok := enumFilterKeys[key]
+	if !ok {
+		return value
+	}
+	if values, ok := value.([]any); ok {
+		normalized := make([]any, len(values))
+		for i, v := range values {
+			normalized[i] = NormalizeFilterValue(key, v)
+		}
+		return normalized
+	}
+	if name, ok := normalize(value); ok {
+		return name
+	}
+	return value
+}
@@ -17,9 +17,14 @@
 package model
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/teletrace/teletrace/pkg/model"
+	"github.com/teletrace/teletrace/pkg/model/annotations/v1"
 
 	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
 )
@@ -37,6 +42,13 @@ const (
 	OPERATOR_GTE          = "gte"
 	OPERATOR_LT           = "lt"
 	OPERATOR_LTE          = "lte"
+	// OPERATOR_BINARY_PREFIX matches binary attribute values starting with the bytes of the filter value, which is
+	// base64 encoded (e.g. "yv4=" for the bytes 0xca 0xfe)
+	OPERATOR_BINARY_PREFIX = "binary_prefix"
+	// OPERATOR_GT_PERCENTILE and OPERATOR_LT_PERCENTILE compare numeric values to a percentile of the recent values of
+	// the tag, computed when the search runs, the filter value being a PercentileThreshold
+	OPERATOR_GT_PERCENTILE = "gt_percentile"
+	OPERATOR_LT_PERCENTILE = "lt_percentile"
 )
 
 type (
@@ -45,6 +57,20 @@ type (
 	ContinuationToken string
 )
 
+// attributeFieldPrefixes are the prefixes of the fields of attribute values
+var attributeFieldPrefixes = []string{"span.attributes.", "resource.attributes.", "scope.attributes."}
+
+// IsAttribute returns whether the field is the value of an attribute of the spans, e.g.
+// span.attributes.http.status_code, rather than a field all spans have.
+func (f SortField) IsAttribute() bool {
+	for _, prefix := range attributeFieldPrefixes {
+		if strings.HasPrefix(string(f), prefix) && len(f) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 type Sort struct {
 	Field     SortField `json:"field"`
 	Ascending bool      `json:"ascending"`
@@ -54,22 +80,564 @@ type Metadata struct {
 	NextToken ContinuationToken `json:"nextToken"`
 }
 
+// MaxSampleSize is the maximum number of spans a sampled search may return.
+const MaxSampleSize = 1000
+
+// Sample requests a random sample of the matching spans instead of a sorted page of them.
+// Sampled results are not sorted and have no continuation token.
+type Sample struct {
+	Size int `json:"size"`
+	// Seed makes the sample reproducible, where supported by the storage backend
+	Seed *int64 `json:"seed"`
+}
+
+// SampleMetadata describes the population a sampled search was drawn from.
+type SampleMetadata struct {
+	EstimatedTotal uint64 `json:"estimatedTotal"`
+}
+
+// FieldGroup is a group of span fields which a search may skip returning, to shrink the results of list views.
+type FieldGroup string
+
+const (
+	FIELD_GROUP_SPAN_ATTRIBUTES     FieldGroup = "span.attributes"
+	FIELD_GROUP_SPAN_EVENTS         FieldGroup = "span.events"
+	FIELD_GROUP_SPAN_LINKS          FieldGroup = "span.links"
+	FIELD_GROUP_RESOURCE_ATTRIBUTES FieldGroup = "resource.attributes"
+	FIELD_GROUP_SCOPE               FieldGroup = "scope"
+)
+
+// FieldGroups are all the field groups, returned by a search without fields
+var FieldGroups = []FieldGroup{
+	FIELD_GROUP_SPAN_ATTRIBUTES,
+	FIELD_GROUP_SPAN_EVENTS,
+	FIELD_GROUP_SPAN_LINKS,
+	FIELD_GROUP_RESOURCE_ATTRIBUTES,
+	FIELD_GROUP_SCOPE,
+}
+
+// AggregationFunction is a function of the values of a tag over the spans of an aggregation group.
+type AggregationFunction string
+
+const (
+	AGGREGATION_COUNT AggregationFunction = "count"
+	AGGREGATION_SUM   AggregationFunction = "sum"
+	AGGREGATION_AVG   AggregationFunction = "avg"
+	AGGREGATION_MIN   AggregationFunction = "min"
+	AGGREGATION_MAX   AggregationFunction = "max"
+	AGGREGATION_P50   AggregationFunction = "p50"
+	AGGREGATION_P90   AggregationFunction = "p90"
+	AGGREGATION_P95   AggregationFunction = "p95"
+	AGGREGATION_P99   AggregationFunction = "p99"
+)
+
+// AggregationPercentiles maps the percentile aggregation functions to their percentile
+var AggregationPercentiles = map[AggregationFunction]float64{
+	AGGREGATION_P50: 0.5,
+	AGGREGATION_P90: 0.9,
+	AGGREGATION_P95: 0.95,
+	AGGREGATION_P99: 0.99,
+}
+
+// AggregationInterval is the interval of the time buckets of bucketed aggregations.
+type AggregationInterval string
+
+const (
+	INTERVAL_HOUR AggregationInterval = "hour"
+	INTERVAL_DAY  AggregationInterval = "day"
+)
+
+// DefaultPercentileWindow is the window of a percentile threshold without one
+const DefaultPercentileWindow = "1h"
+
+// PercentileThreshold is the value of a percentile filter, a percentile of the values of the filtered tag within a
+// window until now, e.g. the p95 of externalFields.durationNano of the "GET /cart" spans of checkout in the last hour.
+type PercentileThreshold struct {
+	Percentile AggregationFunction `json:"percentile"`
+	// Service and Operation (the span name) restrict the spans the percentile is computed of, all spans if not set
+	Service   string `json:"service,omitempty"`
+	Operation string `json:"operation,omitempty"`
+	// Window is how far back the spans are taken from, e.g. "15m" or "1d", DefaultPercentileWindow if not set
+	Window string `json:"window,omitempty"`
+}
+
+// ParsePercentileThreshold parses the value of a percentile filter, setting the default window if missing.
+func ParsePercentileThreshold(v any) (*PercentileThreshold, error) {
+	// filter values are decoded from JSON as maps, so they are decoded again as a threshold
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	var t PercentileThreshold
+	if err := decoder.Decode(&t); err != nil {
+		return nil, fmt.Errorf("percentile threshold must be an object with percentile, service, operation and window: %w", err)
+	}
+	if _, ok := AggregationPercentiles[t.Percentile]; !ok {
+		return nil, fmt.Errorf("percentile must be one of p50, p90, p95 or p99, got %q", t.Percentile)
+	}
+	if t.Window == "" {
+		t.Window = DefaultPercentileWindow
+	}
+	if _, err := model.ParseTimeExpression("now-"+t.Window, time.Now()); err != nil {
+		return nil, fmt.Errorf("invalid percentile window %q, expected e.g. 15m, 1h or 1d", t.Window)
+	}
+	return &t, nil
+}
+
+// Timeframe returns the timeframe the percentile is computed over, the window until now.
+func (t PercentileThreshold) Timeframe() model.Timeframe {
+	return model.Timeframe{Start: "now-" + t.Window, End: "now"}
+}
+
+const (
+	// DefaultAggregationGroups is the number of groups of an aggregated search without a limit
+	DefaultAggregationGroups = 100
+	MaxAggregationGroups     = 1000
+	// MaxAggregatedSpans is the maximum number of matching spans aggregated by an aggregated search
+	MaxAggregatedSpans = 100000
+)
+
+// Metric is an aggregation function of the numeric values of a tag, e.g. the average of externalFields.durationNano.
+type Metric struct {
+	Function AggregationFunction `json:"function"`
+	// Field is the tag whose values are aggregated, not needed to count the spans
+	Field string `json:"field"`
+}
+
+// Name returns the name of the metric in the aggregation results, e.g. "avg(externalFields.durationNano)" or "count".
+func (m Metric) Name() string {
+	if m.Function == AGGREGATION_COUNT {
+		return string(m.Function)
+	}
+	return fmt.Sprintf("%s(%s)", m.Function, m.Field)
+}
+
+// Aggregations requests metrics of the matching spans grouped by the values of a tag, instead of the spans.
+type Aggregations struct {
+	Metrics []Metric `json:"metrics"`
+	// GroupBy is the tag whose values group the spans, e.g. span.attributes.http.status_code. The matching spans are
+	// a single group if not set, and the spans without the tag are grouped under a null value.
+	GroupBy string `json:"groupBy"`
+	// Limit is the maximum number of groups, the largest first, DefaultAggregationGroups if not set
+	Limit int `json:"limit"`
+	// Interval buckets the groups by the start time of the spans, hourly or daily, the spans aren't bucketed if not set
+	Interval AggregationInterval `json:"interval"`
+	// Timezone is the IANA timezone whose local hours and days the buckets are aligned with, e.g. "America/New_York",
+	// UTC if not set
+	Timezone string `json:"timezone"`
+}
+
+// Location returns the location of the timezone of the buckets, UTC if not set.
+func (a *Aggregations) Location() (*time.Location, error) {
+	if a.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(a.Timezone)
+}
+
+// BucketStart returns the start of the bucket of the interval in loc of a time in nanoseconds since the epoch. Hours
+// start at the local hour, so they're aligned with the local time of zones with half hour offsets as well, and the
+// hour repeated when the clocks are set back is bucketed twice. Days start at the local midnight.
+func BucketStart(interval AggregationInterval, loc *time.Location, unixNano uint64) uint64 {
+	t := time.Unix(0, int64(unixNano)).In(loc)
+	switch interval {
+	case INTERVAL_HOUR:
+		_, offset := t.Zone()
+		local := t.Add(time.Duration(offset) * time.Second).Truncate(time.Hour)
+		return uint64(local.Add(-time.Duration(offset) * time.Second).UnixNano())
+	case INTERVAL_DAY:
+		return uint64(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc).UnixNano())
+	default:
+		return 0
+	}
+}
+
+// EffectiveLimit returns the requested limit of groups, or DefaultAggregationGroups if not set.
+func (a *Aggregations) EffectiveLimit() int {
+	if a.Limit == 0 {
+		return DefaultAggregationGroups
+	}
+	return a.Limit
+}
+
+// AggregationGroup holds the metrics of the spans having a value of the group by tag, within a time bucket if the
+// aggregations are bucketed.
+type AggregationGroup struct {
+	// BucketStartUnixNano is the start of the time bucket of the group, set if the aggregations are bucketed
+	BucketStartUnixNano uint64 `json:"bucketStartUnixNano,omitempty"`
+	Value               any    `json:"value"`
+	Count               uint64 `json:"count"`
+	// Metrics are the values of the metrics by their name, omitting the metrics of tags without numeric values in the group
+	Metrics map[string]float64 `json:"metrics"`
+}
+
+type AggregationsResult struct {
+	Groups []AggregationGroup `json:"groups"`
+	// Truncated is set if more spans matched than MaxAggregatedSpans, the metrics being of the most recent spans
+	Truncated bool `json:"truncated"`
+}
+
 type SearchRequest struct {
 	Timeframe     model.Timeframe      `json:"timeframe"`
 	Sort          []Sort               `json:"sort" default:"[{\"Field\": \"TimestampNano\", \"Ascending\": false}]"`
 	SearchFilters []model.SearchFilter `json:"filters"`
 	Metadata      *Metadata            `json:"metadata"`
+	Sample        *Sample              `json:"sample"`
+	// Limit is the maximum number of spans in a page of results, 0 uses the server default
+	Limit int `json:"limit"`
+	// Debug returns how the storage plugin ran the search along with its results
+	Debug bool `json:"debug"`
+	// Aggregations returns metrics of the matching spans instead of the spans
+	Aggregations *Aggregations `json:"aggregations"`
+	// Fields are the field groups returned along with the IDs, name, kind, times, status and external fields of the
+	// spans, all of them if not set
+	Fields []FieldGroup `json:"fields"`
+}
+
+// IncludesField returns whether the spans returned by the search have the fields of a field group.
+func (sr *SearchRequest) IncludesField(group FieldGroup) bool {
+	if sr.Fields == nil {
+		return true
+	}
+	for _, f := range sr.Fields {
+		if f == group {
+			return true
+		}
+	}
+	return false
+}
+
+// Project returns the spans without the field groups not included by the search. Spans missing any field group are
+// copied, as spans may be shared, e.g. by a cache of search results.
+func (sr *SearchRequest) Project(spans []*internalspan.InternalSpan) []*internalspan.InternalSpan {
+	if sr.Fields == nil {
+		return spans
+	}
+	projected := make([]*internalspan.InternalSpan, len(spans))
+	for i, s := range spans {
+		span := *s
+		if span.Span != nil {
+			inner := *span.Span
+			if !sr.IncludesField(FIELD_GROUP_SPAN_ATTRIBUTES) {
+				inner.Attributes = nil
+				inner.BinaryAttributes = nil
+			}
+			if !sr.IncludesField(FIELD_GROUP_SPAN_EVENTS) {
+				inner.Events = nil
+			}
+			if !sr.IncludesField(FIELD_GROUP_SPAN_LINKS) {
+				inner.Links = nil
+			}
+			span.Span = &inner
+		}
+		if span.Resource != nil && !sr.IncludesField(FIELD_GROUP_RESOURCE_ATTRIBUTES) {
+			span.Resource = &internalspan.Resource{DroppedAttributesCount: span.Resource.DroppedAttributesCount}
+		}
+		if !sr.IncludesField(FIELD_GROUP_SCOPE) {
+			span.Scope = nil
+		}
+		projected[i] = &span
+	}
+	return projected
+}
+
+// DebugInfo describes how the storage plugin ran a search, to understand why it is slow or matches nothing.
+type DebugInfo struct {
+	// Query is the query generated by the storage plugin, e.g. SQL or Elasticsearch query DSL
+	Query string `json:"query"`
+	// QueryPlan is the plan the storage backend chose for the query, where supported (e.g. sqlite EXPLAIN QUERY PLAN)
+	QueryPlan []string `json:"queryPlan,omitempty"`
 }
 
 type SearchResponse struct {
 	Metadata *Metadata                    `json:"metadata"`
 	Spans    []*internalspan.InternalSpan `json:"spans"`
+	Sample   *SampleMetadata              `json:"sample,omitempty"`
+	Debug    *DebugInfo                   `json:"debug,omitempty"`
+	// Aggregations are the results of an aggregated search, which has no spans
+	Aggregations *AggregationsResult `json:"aggregations,omitempty"`
+	// Partial is set when matching spans were dropped from the results as they couldn't be read
+	Partial *PartialResult `json:"partial,omitempty"`
+}
+
+// maxPartialResultReasons is the number of distinct reasons kept by a partial result
+const maxPartialResultReasons = 10
+
+// PartialResult reports the matching spans dropped from the results of a search, so the results are known to be
+// incomplete.
+type PartialResult struct {
+	DroppedSpans int `json:"droppedSpans"`
+	// Reasons are the distinct reasons the spans were dropped, up to 10
+	Reasons []string `json:"reasons"`
+}
+
+// AddDroppedSpan records a matching span dropped from the results for reason.
+func (r *SearchResponse) AddDroppedSpan(reason string) {
+	r.MergePartial(&PartialResult{DroppedSpans: 1, Reasons: []string{reason}})
+}
+
+// MergePartial adds the spans dropped from another response to the ones dropped from r, if any.
+func (r *SearchResponse) MergePartial(other *PartialResult) {
+	if other == nil {
+		return
+	}
+	if r.Partial == nil {
+		r.Partial = &PartialResult{Reasons: []string{}}
+	}
+	r.Partial.DroppedSpans += other.DroppedSpans
+	for _, reason := range other.Reasons {
+		if len(r.Partial.Reasons) < maxPartialResultReasons && !r.Partial.hasReason(reason) {
+			r.Partial.Reasons = append(r.Partial.Reasons, reason)
+		}
+	}
+}
+
+func (p *PartialResult) hasReason(reason string) bool {
+	for _, r := range p.Reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// ClockSkewAdjustment notes a span whose timestamps were shifted when assembling a trace,
+// as it (or one of its ancestors) started before its parent, typically due to skewed host clocks.
+type ClockSkewAdjustment struct {
+	SpanId string `json:"spanId"`
+	// AdjustmentNano is the duration added to the timestamps of the span
+	AdjustmentNano uint64 `json:"adjustmentNano"`
+}
+
+// LogLink is a link from a span to its logs in a logging backend, rendered from a configured template.
+type LogLink struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// GetTraceResponse holds the spans of a single trace.
+type GetTraceResponse struct {
+	SearchResponse
+	ClockSkewAdjustments []ClockSkewAdjustment `json:"clockSkewAdjustments"`
+	// Annotations are the notes attached to the trace and its spans, when a metadata store is configured
+	Annotations []annotations.Annotation `json:"annotations,omitempty"`
+	// LogLinks are the links of each span to its logs by span ID, when log link templates are configured
+	LogLinks map[string][]LogLink `json:"logLinks,omitempty"`
+}
+
+// SearchTraceRequest searches the spans of a single trace matching the filters.
+type SearchTraceRequest struct {
+	SearchFilters []model.SearchFilter `json:"filters"`
+}
+
+// SearchTraceResponse holds the IDs of the matching spans of a trace, in the order of their start time.
+type SearchTraceResponse struct {
+	SpanIds []string `json:"spanIds"`
+	// Truncated is set if more spans matched than the maximum returned
+	Truncated bool `json:"truncated"`
+}
+
+const (
+	// DefaultTracesLimit is the number of traces in a page of trace search results without a limit
+	DefaultTracesLimit = 20
+	MaxTracesLimit     = 100
+	// MaxTracesSpans is the maximum number of spans of the traces returned in a page of trace search results
+	MaxTracesSpans = 10000
+)
+
+// SearchTracesRequest searches the traces having any span matching the filters within the timeframe,
+// returning each trace with all of its spans.
+type SearchTracesRequest struct {
+	Timeframe     model.Timeframe      `json:"timeframe"`
+	SearchFilters []model.SearchFilter `json:"filters"`
+	Metadata      *Metadata            `json:"metadata"`
+	// Limit is the maximum number of traces in a page of results, DefaultTracesLimit if not set
+	Limit int `json:"limit"`
+}
+
+// TraceResult is a matching trace with its spans, in the order of their start time.
+type TraceResult struct {
+	TraceId string `json:"traceId"`
+	// LatestMatchTimeUnixNano is the start time of the most recent matching span of the trace
+	LatestMatchTimeUnixNano uint64                       `json:"latestMatchTimeUnixNano"`
+	Spans                   []*internalspan.InternalSpan `json:"spans"`
+}
+
+type SearchTracesResponse struct {
+	Metadata *Metadata `json:"metadata"`
+	// Traces are ordered by their most recent matching span, the most recent first
+	Traces []*TraceResult `json:"traces"`
+	// SpansTruncated is set if the traces have more spans than MaxTracesSpans, some traces missing some of their spans
+	SpansTruncated bool `json:"spansTruncated"`
+}
+
+// ValidationError is a problem of a search request found by validating it without running it.
+type ValidationError struct {
+	// FilterIndex is the index of the invalid filter, omitted for problems of the request itself
+	FilterIndex *int `json:"filterIndex,omitempty"`
+	// Field is the invalid part of the filter, either "key", "operator" or "value"
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidateSearchResponse holds the problems of a validated search request, so they can be shown next to the filters.
+type ValidateSearchResponse struct {
+	Valid  bool              `json:"valid"`
+	Errors []ValidationError `json:"errors"`
 }
 
 func (sr *SearchRequest) Validate() error {
-	if (sr.Timeframe.EndTime < sr.Timeframe.StartTime) && (sr.Timeframe.EndTime != 0) {
-		return fmt.Errorf("endTime cannot be smaller than startTime")
+	if err := sr.Timeframe.Validate(); err != nil {
+		return err
+	}
+
+	if err := ValidateFilters(sr.SearchFilters); err != nil {
+		return err
+	}
+
+	if sr.Limit < 0 {
+		return fmt.Errorf("limit cannot be negative")
 	}
 
+	if sr.Sample != nil {
+		if sr.Sample.Size <= 0 || sr.Sample.Size > MaxSampleSize {
+			return fmt.Errorf("sample size must be between 1 and %d", MaxSampleSize)
+		}
+		if sr.Metadata != nil && sr.Metadata.NextToken != "" {
+			return fmt.Errorf("sampled search results cannot be paginated")
+		}
+	}
+
+	for _, f := range sr.Fields {
+		if !isFieldGroup(f) {
+			return fmt.Errorf("unknown field group %q", f)
+		}
+	}
+
+	if sr.Aggregations != nil {
+		if sr.Sample != nil {
+			return fmt.Errorf("sampled search results cannot be aggregated")
+		}
+		return sr.Aggregations.Validate()
+	}
+
+	return nil
+}
+
+func (a *Aggregations) Validate() error {
+	if len(a.Metrics) == 0 {
+		return fmt.Errorf("aggregations must have at least one metric")
+	}
+	for _, m := range a.Metrics {
+		if !isAggregationFunction(m.Function) {
+			return fmt.Errorf("unknown aggregation function %q", m.Function)
+		}
+		if m.Function != AGGREGATION_COUNT && m.Field == "" {
+			return fmt.Errorf("%s aggregation must have a field", m.Function)
+		}
+	}
+	if a.Limit < 0 || a.Limit > MaxAggregationGroups {
+		return fmt.Errorf("aggregation limit must be between 0 and %d", MaxAggregationGroups)
+	}
+	switch a.Interval {
+	case "":
+		if a.Timezone != "" {
+			return fmt.Errorf("aggregation timezone requires an interval")
+		}
+	case INTERVAL_HOUR, INTERVAL_DAY:
+		if _, err := a.Location(); err != nil {
+			return fmt.Errorf("unknown aggregation timezone %q", a.Timezone)
+		}
+	default:
+		return fmt.Errorf("unknown aggregation interval %q, expected %q or %q", a.Interval, INTERVAL_HOUR, INTERVAL_DAY)
+	}
+	return nil
+}
+
+func isAggregationFunction(f AggregationFunction) bool {
+	switch f {
+	case AGGREGATION_COUNT, AGGREGATION_SUM, AGGREGATION_AVG, AGGREGATION_MIN, AGGREGATION_MAX:
+		return true
+	}
+	_, ok := AggregationPercentiles[f]
+	return ok
+}
+
+func isFieldGroup(f FieldGroup) bool {
+	for _, group := range FieldGroups {
+		if f == group {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *SearchTracesRequest) Validate() error {
+	if err := r.Timeframe.Validate(); err != nil {
+		return err
+	}
+	if r.Limit < 0 || r.Limit > MaxTracesLimit {
+		return fmt.Errorf("limit must be between 0 and %d", MaxTracesLimit)
+	}
+	return ValidateFilters(r.SearchFilters)
+}
+
+// EffectiveLimit returns the requested limit, or DefaultTracesLimit if not set.
+func (r *SearchTracesRequest) EffectiveLimit() int {
+	if r.Limit == 0 {
+		return DefaultTracesLimit
+	}
+	return r.Limit
+}
+
+func (r *SearchTraceRequest) Validate() error {
+	return ValidateFilters(r.SearchFilters)
+}
+
+// ValidateFilters validates filter values which must be of a specific form.
+func ValidateFilters(filters []model.SearchFilter) error {
+	for _, f := range filters {
+		if f.KeyValueFilter == nil {
+			continue
+		}
+		switch f.KeyValueFilter.Operator {
+		case OPERATOR_BINARY_PREFIX:
+			if _, err := BinaryPrefix(f.KeyValueFilter.Value); err != nil {
+				return fmt.Errorf("%s filter value of %s must be base64 encoded bytes, e.g. \"AAEC\": %v",
+					OPERATOR_BINARY_PREFIX, f.KeyValueFilter.Key, err)
+			}
+		case OPERATOR_GT, OPERATOR_GTE, OPERATOR_LT, OPERATOR_LTE:
+			// range filters compare numbers, a string value would be compared as a string by some storages
+			if !IsNumber(f.KeyValueFilter.Value) {
+				return fmt.Errorf("%s filter value of %s must be a number, got %v",
+					f.KeyValueFilter.Operator, f.KeyValueFilter.Key, f.KeyValueFilter.Value)
+			}
+		case OPERATOR_GT_PERCENTILE, OPERATOR_LT_PERCENTILE:
+			if _, err := ParsePercentileThreshold(f.KeyValueFilter.Value); err != nil {
+				return fmt.Errorf("%s filter value of %s: %w", f.KeyValueFilter.Operator, f.KeyValueFilter.Key, err)
+			}
+		}
+	}
 	return nil
 }
+
+// BinaryPrefix returns the bytes of the value of a binary_prefix filter.
+func BinaryPrefix(value model.FilterValue) ([]byte, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a string, got %v", value)
+	}
+	return internalspan.DecodeBinaryValue(s, internalspan.BinaryEncodingBase64)
+}
+
+// IsNumber returns whether v is a numeric filter value.
+func IsNumber(v any) bool {
+	switch v.(type) {
+	case float64, float32, int, int64, int32, uint64, uint32, json.Number:
+		return true
+	default:
+		return false
+	}
+}
@@ -17,27 +17,150 @@
 package tagsquery
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// TagValuesSortField is the field the values of a tag are sorted by.
+type TagValuesSortField string
+
+const (
+	// SORT_BY_COUNT sorts the values by the number of spans having them, descending by default
+	SORT_BY_COUNT TagValuesSortField = "count"
+	// SORT_BY_VALUE sorts the values alphabetically, or numerically for numbers, ascending by default
+	SORT_BY_VALUE TagValuesSortField = "value"
+	// SORT_BY_LAST_SEEN sorts the values by the start time of the most recent span having them, descending by default
+	SORT_BY_LAST_SEEN TagValuesSortField = "lastSeen"
+)
+
+type SortOrder string
+
+const (
+	SORT_ORDER_ASC  SortOrder = "asc"
+	SORT_ORDER_DESC SortOrder = "desc"
 )
 
 type TagValuesRequest struct {
 	Timeframe     *model.Timeframe     `json:"timeframe"`
 	SearchFilters []model.SearchFilter `json:"filters"`
+	// Limit is the maximum number of values returned per tag, the first in the sort order, 0 uses the server default
+	Limit int `json:"limit"`
+	// SortBy is the field the values are sorted by, SORT_BY_COUNT if not set
+	SortBy TagValuesSortField `json:"sortBy"`
+	// SortOrder is the direction of the sort, the default direction of the sort field if not set
+	SortOrder SortOrder `json:"sortOrder"`
 }
 
 func (r *TagValuesRequest) Validate() error {
-	if r.Timeframe != nil && r.Timeframe.EndTime < r.Timeframe.StartTime && r.Timeframe.EndTime != 0 {
-		return fmt.Errorf("endTime cannot be smaller than startTime")
+	if r.Timeframe != nil {
+		if err := r.Timeframe.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if r.Limit < 0 {
+		return fmt.Errorf("limit cannot be negative")
 	}
 
-	return nil
+	switch r.SortBy {
+	case "", SORT_BY_COUNT, SORT_BY_VALUE, SORT_BY_LAST_SEEN:
+	default:
+		return fmt.Errorf("unknown sort field %q, must be one of %s, %s or %s", r.SortBy, SORT_BY_COUNT, SORT_BY_VALUE, SORT_BY_LAST_SEEN)
+	}
+	switch r.SortOrder {
+	case "", SORT_ORDER_ASC, SORT_ORDER_DESC:
+	default:
+		return fmt.Errorf("unknown sort order %q, must be %s or %s", r.SortOrder, SORT_ORDER_ASC, SORT_ORDER_DESC)
+	}
+
+	return spansquery.ValidateFilters(r.SearchFilters)
+}
+
+// Sort returns the field the values are sorted by and whether they are sorted in ascending order,
+// resolving the defaults of the request.
+func (r *TagValuesRequest) Sort() (TagValuesSortField, bool) {
+	field := r.SortBy
+	if field == "" {
+		field = SORT_BY_COUNT
+	}
+	if r.SortOrder == "" {
+		return field, field == SORT_BY_VALUE
+	}
+	return field, r.SortOrder == SORT_ORDER_ASC
 }
 
 type TagValueInfo struct {
 	Value any `json:"value"`
 	Count int `json:"count"`
+	// LastSeenUnixNano is the start time of the most recent span having the value, set when sorting by SORT_BY_LAST_SEEN
+	LastSeenUnixNano uint64 `json:"lastSeenUnixNano,omitempty"`
+}
+
+// SortValues sorts values in the sort order of r, values sorting the same being sorted by value.
+func SortValues(values []TagValueInfo, r TagValuesRequest) {
+	field, ascending := r.Sort()
+	sort.SliceStable(values, func(i, j int) bool {
+		var c int
+		switch field {
+		case SORT_BY_VALUE:
+			c = compareValues(values[i].Value, values[j].Value)
+		case SORT_BY_LAST_SEEN:
+			c = compareNumbers(float64(values[i].LastSeenUnixNano), float64(values[j].LastSeenUnixNano))
+		default:
+			c = compareNumbers(float64(values[i].Count), float64(values[j].Count))
+		}
+		if !ascending {
+			c = -c
+		}
+		if c == 0 {
+			return compareValues(values[i].Value, values[j].Value) < 0
+		}
+		return c < 0
+	})
+}
+
+// compareValues compares tag values, numerically if both are numbers.
+func compareValues(a any, b any) int {
+	an, aok := number(a)
+	bn, bok := number(b)
+	if aok && bok {
+		return compareNumbers(an, bn)
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func compareNumbers(a float64, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
 }
 
 type TagValuesResponse struct {
@@ -61,18 +184,49 @@ type TagStatisticsRequest struct {
 }
 
 func (r *TagStatisticsRequest) Validate() error {
-	if r.Timeframe != nil && r.Timeframe.EndTime < r.Timeframe.StartTime && r.Timeframe.EndTime != 0 {
-		return fmt.Errorf("endTime cannot be smaller than startTime")
+	if r.Timeframe != nil {
+		if err := r.Timeframe.Validate(); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return spansquery.ValidateFilters(r.SearchFilters)
 }
 
 type TagStatisticsResponse struct {
 	Statistics map[TagStatistic]float64 `json:"statistics"`
+	// Count is the number of values the statistics are computed of, 0 if the backend doesn't report it
+	Count int64 `json:"count,omitempty"`
+	// UpperBounds are the statistics that are an upper bound of the actual value rather than the exact value,
+	// such as percentiles combined from several backends
+	UpperBounds []TagStatistic `json:"upperBounds,omitempty"`
 }
 
-type GetAvailableTagsRequest struct{}
+// MaxStatisticsBatchTags is the maximum number of tags of a batched statistics request
+const MaxStatisticsBatchTags = 50
+
+// TagsStatisticsBatchRequest requests the statistics of several tags of the spans matching the same filters.
+type TagsStatisticsBatchRequest struct {
+	TagStatisticsRequest
+	Tags []string `json:"tags"`
+}
+
+func (r *TagsStatisticsBatchRequest) Validate() error {
+	if len(r.Tags) == 0 || len(r.Tags) > MaxStatisticsBatchTags {
+		return fmt.Errorf("tags must have between 1 and %d tags", MaxStatisticsBatchTags)
+	}
+	return r.TagStatisticsRequest.Validate()
+}
+
+// TagsStatisticsBatchResponse holds the statistics of the requested tags by tag.
+type TagsStatisticsBatchResponse struct {
+	Tags map[string]*TagStatisticsResponse `json:"tags"`
+}
+
+type GetAvailableTagsRequest struct {
+	// Limit is the maximum number of tags returned, 0 uses the server default
+	Limit int `json:"limit"`
+}
 
 type GetAvailableTagsResponse struct {
 	Tags []TagInfo
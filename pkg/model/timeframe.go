@@ -0,0 +1,109 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var timeExpressionRegexp = regexp.MustCompile(`^now(?:([+-])(\d+)([smhdw]))?$`)
+
+var timeExpressionUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// ParseTimeExpression parses a time relative to now, e.g. "now", "now-15m" or "now+1h", or an RFC3339 timestamp,
+// e.g. "2023-01-02T15:04:05Z". The supported relative units are s, m, h, d (24 hours) and w (7 days).
+func ParseTimeExpression(expr string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, expr); err == nil {
+		return t, nil
+	}
+	match := timeExpressionRegexp.FindStringSubmatch(expr)
+	if match == nil {
+		return time.Time{}, fmt.Errorf(
+			"invalid time expression %q, expected e.g. now, now-15m or an RFC3339 timestamp", expr,
+		)
+	}
+	if match[1] == "" {
+		return now, nil
+	}
+	amount, err := strconv.Atoi(match[2])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time expression %q: %w", expr, err)
+	}
+	offset := time.Duration(amount) * timeExpressionUnits[match[3]]
+	if match[1] == "-" {
+		offset = -offset
+	}
+	return now.Add(offset), nil
+}
+
+// Resolve sets StartTime and EndTime from the time expressions of t, relative to now.
+// t isn't modified if any of its expressions is invalid.
+func (t *Timeframe) Resolve(now time.Time) error {
+	startTime, endTime := t.StartTime, t.EndTime
+	if t.Start != "" {
+		start, err := parseUnixNano(t.Start, now)
+		if err != nil {
+			return err
+		}
+		startTime = start
+	}
+	if t.End != "" {
+		end, err := parseUnixNano(t.End, now)
+		if err != nil {
+			return err
+		}
+		endTime = end
+	}
+	t.StartTime, t.EndTime = startTime, endTime
+	return nil
+}
+
+func parseUnixNano(expr string, now time.Time) (uint64, error) {
+	t, err := ParseTimeExpression(expr, now)
+	if err != nil {
+		return 0, err
+	}
+	if t.Before(time.Unix(0, 0)) {
+		return 0, fmt.Errorf("time expression %q is before the Unix epoch", expr)
+	}
+	return uint64(t.UnixNano()), nil
+}
+
+// Validate validates the time expressions of t, and that it doesn't end before it starts.
+func (t Timeframe) Validate() error {
+	if t.Start != "" && t.StartTime != 0 {
+		return fmt.Errorf("start and startTimeUnixNanoSec cannot both be set")
+	}
+	if t.End != "" && t.EndTime != 0 {
+		return fmt.Errorf("end and endTimeUnixNanoSec cannot both be set")
+	}
+	if err := t.Resolve(time.Now()); err != nil {
+		return err
+	}
+	if (t.EndTime < t.StartTime) && (t.EndTime != 0) {
+		return fmt.Errorf("endTime cannot be smaller than startTime")
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package tracesummaries
+
+import (
+	"fmt"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 1000
+)
+
+// SummarizeRequest summarizes the traces of the most recent spans matching the timeframe and filters.
+type SummarizeRequest struct {
+	Timeframe     model.Timeframe      `json:"timeframe"`
+	SearchFilters []model.SearchFilter `json:"filters"`
+	// Limit is the maximum number of traces summarized, DefaultLimit if not set
+	Limit int `json:"limit,omitempty"`
+}
+
+// TraceSummary holds the aggregates of the spans of a single trace.
+type TraceSummary struct {
+	TraceId string `json:"traceId"`
+	// RootSpanName and RootServiceName are of the root span, or of the earliest span if the root is missing
+	RootSpanName      string `json:"rootSpanName"`
+	RootServiceName   string `json:"rootServiceName"`
+	SpanCount         int    `json:"spanCount"`
+	ErrorCount        int    `json:"errorCount"`
+	StartTimeUnixNano uint64 `json:"startTimeUnixNano"`
+	// DurationNano spans from the earliest start to the latest end of the spans of the trace
+	DurationNano uint64 `json:"durationNano"`
+	// Services are the names of the services involved in the trace, sorted
+	Services []string `json:"services"`
+}
+
+type SummarizeResponse struct {
+	// Traces are ordered by their most recent matching span, the most recent first
+	Traces []*TraceSummary `json:"traces"`
+	// Truncated is set if more traces matched than were summarized
+	Truncated bool `json:"truncated"`
+}
+
+func (r *SummarizeRequest) Validate() error {
+	if err := r.Timeframe.Validate(); err != nil {
+		return err
+	}
+	if r.Limit < 0 || r.Limit > MaxLimit {
+		return fmt.Errorf("limit must be between 0 and %d", MaxLimit)
+	}
+
+	return spansquery.ValidateFilters(r.SearchFilters)
+}
+
+// EffectiveLimit returns the requested limit, or DefaultLimit if not set.
+func (r *SummarizeRequest) EffectiveLimit() int {
+	if r.Limit == 0 {
+		return DefaultLimit
+	}
+	return r.Limit
+}
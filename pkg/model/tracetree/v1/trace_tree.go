@@ -0,0 +1,41 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package tracetree
+
+import (
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// Node is a span of a trace tree, with its children ordered by start time.
+type Node struct {
+	Span *internalspan.InternalSpan `json:"span"`
+	// Depth is the number of ancestors of the span, 0 for the roots
+	Depth int `json:"depth"`
+	// SelfTimeNano is the part of the span duration not covered by any of its children
+	SelfTimeNano uint64 `json:"selfTimeNano"`
+	// Orphan is set for spans whose parent isn't part of the trace, which are placed at the root
+	Orphan   bool    `json:"orphan,omitempty"`
+	Children []*Node `json:"children"`
+}
+
+type GetTraceTreeResponse struct {
+	// Roots are the root spans of the trace and its orphan spans, ordered by start time
+	Roots                []*Node                          `json:"roots"`
+	SpanCount            int                              `json:"spanCount"`
+	MaxDepth             int                              `json:"maxDepth"`
+	ClockSkewAdjustments []spansquery.ClockSkewAdjustment `json:"clockSkewAdjustments,omitempty"`
+}
@@ -0,0 +1,218 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package modeltranslator
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	internalspanv1 "github.com/teletrace/teletrace/model/internalspan/v1"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// TranslateInternalModelToOTLP converts spans from the InternalSpan model back to the OTLP format,
+// grouping the spans of the same resource and instrumentation scope, e.g. for serving them as OTLP protobuf.
+// The external fields computed at ingestion (e.g. the duration) have no OTLP equivalent and are dropped.
+func TranslateInternalModelToOTLP(spans []*internalspanv1.InternalSpan) (ptrace.Traces, error) {
+	td := ptrace.NewTraces()
+	resourceSpansByKey := map[string]ptrace.ResourceSpans{}
+	scopeSpansByKey := map[string]ptrace.ScopeSpans{}
+
+	for _, internalSpan := range spans {
+		resourceKey, err := groupKey(internalSpan.Resource)
+		if err != nil {
+			return td, err
+		}
+		resourceSpans, ok := resourceSpansByKey[resourceKey]
+		if !ok {
+			resourceSpans = td.ResourceSpans().AppendEmpty()
+			if err := setOTLPResource(resourceSpans.Resource(), internalSpan.Resource); err != nil {
+				return td, err
+			}
+			resourceSpansByKey[resourceKey] = resourceSpans
+		}
+
+		scopeKey, err := groupKey(internalSpan.Scope)
+		if err != nil {
+			return td, err
+		}
+		scopeKey = resourceKey + "/" + scopeKey
+		scopeSpans, ok := scopeSpansByKey[scopeKey]
+		if !ok {
+			scopeSpans = resourceSpans.ScopeSpans().AppendEmpty()
+			if err := setOTLPScope(scopeSpans.Scope(), internalSpan.Scope); err != nil {
+				return td, err
+			}
+			scopeSpansByKey[scopeKey] = scopeSpans
+		}
+
+		if internalSpan.Span == nil {
+			continue
+		}
+		if err := setOTLPSpan(scopeSpans.Spans().AppendEmpty(), internalSpan.Span); err != nil {
+			return td, fmt.Errorf("could not translate span %s: %w", internalSpan.Span.SpanId, err)
+		}
+	}
+	return td, nil
+}
+
+// groupKey identifies a resource or scope by its JSON encoding, which is deterministic as map keys are sorted.
+func groupKey(v any) (string, error) {
+	key, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("could not encode span group: %w", err)
+	}
+	return string(key), nil
+}
+
+func setOTLPResource(resource pcommon.Resource, internalResource *internalspanv1.Resource) error {
+	if internalResource == nil {
+		return nil
+	}
+	resource.SetDroppedAttributesCount(internalResource.DroppedAttributesCount)
+	return resource.Attributes().FromRaw(internalResource.Attributes)
+}
+
+func setOTLPScope(scope pcommon.InstrumentationScope, internalScope *internalspanv1.InstrumentationScope) error {
+	if internalScope == nil {
+		return nil
+	}
+	scope.SetName(internalScope.Name)
+	scope.SetVersion(internalScope.Version)
+	scope.SetDroppedAttributesCount(internalScope.DroppedAttributesCount)
+	return scope.Attributes().FromRaw(internalScope.Attributes)
+}
+
+func setOTLPSpan(span ptrace.Span, internalSpan *internalspanv1.Span) error {
+	traceId, err := parseTraceId(internalSpan.TraceId)
+	if err != nil {
+		return err
+	}
+	spanId, err := parseSpanId(internalSpan.SpanId)
+	if err != nil {
+		return err
+	}
+	parentSpanId, err := parseSpanId(internalSpan.ParentSpanId)
+	if err != nil {
+		return err
+	}
+	span.SetTraceID(traceId)
+	span.SetSpanID(spanId)
+	span.SetParentSpanID(parentSpanId)
+	span.TraceState().FromRaw(internalSpan.TraceState)
+	span.SetName(internalSpan.Name)
+	span.SetKind(parseSpanKind(internalSpan.Kind))
+	span.SetStartTimestamp(pcommon.Timestamp(internalSpan.StartTimeUnixNano))
+	span.SetEndTimestamp(pcommon.Timestamp(internalSpan.EndTimeUnixNano))
+	span.SetDroppedAttributesCount(internalSpan.DroppedAttributesCount)
+	span.SetDroppedEventsCount(internalSpan.DroppedEventsCount)
+	span.SetDroppedLinksCount(internalSpan.DroppedLinksCount)
+	if err := span.Attributes().FromRaw(internalSpan.Attributes); err != nil {
+		return err
+	}
+	if internalSpan.Status != nil {
+		span.Status().SetCode(parseStatusCode(internalSpan.Status.Code))
+		span.Status().SetMessage(internalSpan.Status.Message)
+	}
+
+	for _, internalEvent := range internalSpan.Events {
+		event := span.Events().AppendEmpty()
+		event.SetName(internalEvent.Name)
+		event.SetTimestamp(pcommon.Timestamp(internalEvent.TimeUnixNano))
+		event.SetDroppedAttributesCount(internalEvent.DroppedAttributesCount)
+		if err := event.Attributes().FromRaw(internalEvent.Attributes); err != nil {
+			return err
+		}
+	}
+
+	for _, internalLink := range internalSpan.Links {
+		link := span.Links().AppendEmpty()
+		if traceId, err = parseTraceId(internalLink.TraceId); err != nil {
+			return err
+		}
+		if spanId, err = parseSpanId(internalLink.SpanId); err != nil {
+			return err
+		}
+		link.SetTraceID(traceId)
+		link.SetSpanID(spanId)
+		link.TraceState().FromRaw(internalLink.TraceState)
+		link.SetDroppedAttributesCount(internalLink.DroppedAttributesCount)
+		if err := link.Attributes().FromRaw(internalLink.Attributes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseTraceId decodes a hex trace ID, left-padding shorter IDs (e.g. 64 bit trace IDs of Zipkin) with zeros.
+func parseTraceId(s string) (pcommon.TraceID, error) {
+	traceId := pcommon.NewTraceIDEmpty()
+	if s == "" {
+		return traceId, nil
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) > len(traceId) {
+		return traceId, fmt.Errorf("invalid trace id %q", s)
+	}
+	copy(traceId[len(traceId)-len(b):], b)
+	return traceId, nil
+}
+
+// parseSpanId decodes a hex span ID, left-padding shorter IDs with zeros.
+func parseSpanId(s string) (pcommon.SpanID, error) {
+	spanId := pcommon.NewSpanIDEmpty()
+	if s == "" {
+		return spanId, nil
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) > len(spanId) {
+		return spanId, fmt.Errorf("invalid trace id %q", s)
+	}
+	copy(spanId[len(spanId)-len(b):], b)
+	return spanId, nil
+}
+
+// parseSpanKind accepts the kinds as named by pdata (e.g. "Server") and by the OTLP enum (e.g. "SPAN_KIND_SERVER").
+func parseSpanKind(kind string) ptrace.SpanKind {
+	switch strings.TrimPrefix(strings.ToUpper(kind), "SPAN_KIND_") {
+	case "INTERNAL":
+		return ptrace.SpanKindInternal
+	case "SERVER":
+		return ptrace.SpanKindServer
+	case "CLIENT":
+		return ptrace.SpanKindClient
+	case "PRODUCER":
+		return ptrace.SpanKindProducer
+	case "CONSUMER":
+		return ptrace.SpanKindConsumer
+	}
+	return ptrace.SpanKindUnspecified
+}
+
+// parseStatusCode accepts the codes as named by pdata (e.g. "Error") and by the OTLP enum (e.g. "STATUS_CODE_ERROR").
+func parseStatusCode(code string) ptrace.StatusCode {
+	switch strings.TrimPrefix(strings.ToUpper(code), "STATUS_CODE_") {
+	case "OK":
+		return ptrace.StatusCodeOk
+	case "ERROR":
+		return ptrace.StatusCodeError
+	}
+	return ptrace.StatusCodeUnset
+}
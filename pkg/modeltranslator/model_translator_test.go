@@ -36,6 +36,41 @@ func TestModelTranslator(t *testing.T) {
 	assert.ElementsMatch(t, expectedInternalSpans, actualInternalSpans)
 }
 
+func TestModelTranslatorRoundTrip(t *testing.T) {
+	internalSpans := createExpectedInternalSpans()
+
+	traces, err := TranslateInternalModelToOTLP(internalSpans)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, traces.ResourceSpans().Len())
+	assert.Equal(t, 2, traces.ResourceSpans().At(0).ScopeSpans().Len())
+	assert.Equal(t, len(internalSpans), traces.SpanCount())
+
+	assert.ElementsMatch(t, internalSpans, TranslateOTLPToInternalModel(traces))
+}
+
+func TestParseIds(t *testing.T) {
+	traceId, err := parseTraceId("1234567887654321")
+	assert.NoError(t, err)
+	assert.Equal(t, "00000000000000001234567887654321", traceId.String())
+	spanId, err := parseSpanId("")
+	assert.NoError(t, err)
+	assert.True(t, spanId.IsEmpty())
+	_, err = parseSpanId("not-hex")
+	assert.Error(t, err)
+	_, err = parseSpanId("0102030405060708090a")
+	assert.Error(t, err)
+}
+
+func TestParseSpanKindAndStatusCode(t *testing.T) {
+	assert.Equal(t, ptrace.SpanKindServer, parseSpanKind("Server"))
+	assert.Equal(t, ptrace.SpanKindClient, parseSpanKind("SPAN_KIND_CLIENT"))
+	assert.Equal(t, ptrace.SpanKindInternal, parseSpanKind("INTERNAL"))
+	assert.Equal(t, ptrace.SpanKindUnspecified, parseSpanKind("unknown"))
+	assert.Equal(t, ptrace.StatusCodeError, parseStatusCode("Error"))
+	assert.Equal(t, ptrace.StatusCodeOk, parseStatusCode("STATUS_CODE_OK"))
+	assert.Equal(t, ptrace.StatusCodeUnset, parseStatusCode(""))
+}
+
 func createOTLPTraces() ptrace.Traces {
 	td := ptrace.NewTraces()
 
@@ -0,0 +1,262 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nplusone
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	nplusone "github.com/teletrace/teletrace/pkg/model/nplusone/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"go.uber.org/zap"
+)
+
+// Config holds the default thresholds of the detector, and bounds the work of a single detection.
+type Config struct {
+	MinRepetitions   int
+	MaxChildDuration time.Duration
+	MaxTraces        int
+}
+
+// Detector finds N+1 patterns, i.e. parent spans with many near-identical short db or http children.
+// Candidate parents are found through their precomputed child count, so only their traces are fetched.
+type Detector struct {
+	logger     *zap.Logger
+	spanReader spanreader.SpanReader
+	cfg        Config
+}
+
+func NewDetector(logger *zap.Logger, sr spanreader.SpanReader, cfg Config) *Detector {
+	return &Detector{
+		logger:     logger,
+		spanReader: sr,
+		cfg:        cfg,
+	}
+}
+
+// Detect analyzes the traces of the spans matching the request, and returns the findings and their aggregates per operation.
+func (d *Detector) Detect(ctx context.Context, req nplusone.DetectRequest) (*nplusone.DetectResponse, error) {
+	minRepetitions := req.MinRepetitions
+	if minRepetitions == 0 {
+		minRepetitions = d.cfg.MinRepetitions
+	}
+	maxChildDuration := req.MaxChildDurationNano
+	if maxChildDuration == 0 {
+		maxChildDuration = uint64(d.cfg.MaxChildDuration)
+	}
+
+	traceIds, truncated, err := d.candidateTraces(ctx, req, minRepetitions)
+	if err != nil {
+		return nil, fmt.Errorf("could not find candidate parent spans: %w", err)
+	}
+
+	findings := []nplusone.Finding{}
+	for _, traceId := range traceIds {
+		spans, err := spanreader.TraceSpans(ctx, d.spanReader, traceId)
+		if err != nil {
+			return nil, fmt.Errorf("could not get spans of trace %s: %w", traceId, err)
+		}
+		findings = append(findings, detectInTrace(spans, minRepetitions, maxChildDuration)...)
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Repetitions != findings[j].Repetitions {
+			return findings[i].Repetitions > findings[j].Repetitions
+		}
+		if findings[i].TraceId != findings[j].TraceId {
+			return findings[i].TraceId < findings[j].TraceId
+		}
+		if findings[i].ParentSpanId != findings[j].ParentSpanId {
+			return findings[i].ParentSpanId < findings[j].ParentSpanId
+		}
+		return findings[i].ChildSignature < findings[j].ChildSignature
+	})
+
+	return &nplusone.DetectResponse{
+		Findings:       findings,
+		Operations:     aggregateOperations(findings),
+		AnalyzedTraces: len(traceIds),
+		Truncated:      truncated,
+	}, nil
+}
+
+// candidateTraces returns the IDs of the traces holding spans with at least minRepetitions children,
+// largest fan-outs first, up to the configured maximum number of traces.
+func (d *Detector) candidateTraces(ctx context.Context, req nplusone.DetectRequest, minRepetitions int) ([]string, bool, error) {
+	var traceIds []string
+	seen := make(map[string]bool)
+	var token spansquery.ContinuationToken
+	for {
+		res, err := d.spanReader.Search(ctx, spansquery.SearchRequest{
+			Timeframe:     req.Timeframe,
+			Sort:          []spansquery.Sort{{Field: "externalFields.childCount", Ascending: false}},
+			SearchFilters: candidateFilters(req.SearchFilters, minRepetitions),
+			Metadata:      &spansquery.Metadata{NextToken: token},
+		})
+		if err != nil {
+			return nil, false, err
+		}
+
+		for _, span := range res.Spans {
+			traceId := span.Span.TraceId
+			if seen[traceId] {
+				continue
+			}
+			if len(traceIds) == d.cfg.MaxTraces {
+				return traceIds, true, nil
+			}
+			seen[traceId] = true
+			traceIds = append(traceIds, traceId)
+		}
+
+		if len(res.Spans) == 0 || res.Metadata == nil || res.Metadata.NextToken == "" {
+			return traceIds, false, nil
+		}
+		token = res.Metadata.NextToken
+	}
+}
+
+// candidateFilters returns a copy of the request filters with the minimal child count filter added.
+func candidateFilters(filters []model.SearchFilter, minRepetitions int) []model.SearchFilter {
+	return append(spanreader.CopyFilters(filters), model.SearchFilter{
+		KeyValueFilter: &model.KeyValueFilter{
+			Key:      "externalFields.childCount",
+			Operator: spansquery.OPERATOR_GTE,
+			Value:    float64(minRepetitions),
+		},
+	})
+}
+
+type childGroupKey struct {
+	parentSpanId string
+	childType    string
+	signature    string
+}
+
+// detectInTrace groups the short db and http children of each parent by their signature,
+// and reports the groups with at least minRepetitions children.
+func detectInTrace(spans []*internalspan.InternalSpan, minRepetitions int, maxChildDuration uint64) []nplusone.Finding {
+	spansById := make(map[string]*internalspan.InternalSpan, len(spans))
+	groups := make(map[childGroupKey][]*internalspan.InternalSpan)
+	for _, span := range spans {
+		spansById[span.Span.SpanId] = span
+		if span.Span.ParentSpanId == "" || duration(span) > maxChildDuration {
+			continue
+		}
+		childType, signature := childSignature(span.Span)
+		if childType == "" {
+			continue
+		}
+		key := childGroupKey{parentSpanId: span.Span.ParentSpanId, childType: childType, signature: signature}
+		groups[key] = append(groups[key], span)
+	}
+
+	var findings []nplusone.Finding
+	for key, children := range groups {
+		if len(children) < minRepetitions {
+			continue
+		}
+		finding := nplusone.Finding{
+			TraceId:        children[0].Span.TraceId,
+			ParentSpanId:   key.parentSpanId,
+			Service:        spanreader.ServiceName(children[0]),
+			ChildType:      key.childType,
+			ChildSignature: key.signature,
+			Repetitions:    len(children),
+		}
+		// the parent may be missing, e.g. when it wasn't ingested yet
+		if parent, ok := spansById[key.parentSpanId]; ok {
+			finding.ParentSpanName = parent.Span.Name
+			finding.Service = spanreader.ServiceName(parent)
+		}
+		for _, child := range children {
+			finding.TotalDurationNano += duration(child)
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+type operationKey struct {
+	service        string
+	parentSpanName string
+	childType      string
+	childSignature string
+}
+
+func aggregateOperations(findings []nplusone.Finding) []nplusone.OperationAggregate {
+	operations := make(map[operationKey]*nplusone.OperationAggregate)
+	traces := make(map[operationKey]map[string]bool)
+	for _, f := range findings {
+		key := operationKey{service: f.Service, parentSpanName: f.ParentSpanName, childType: f.ChildType, childSignature: f.ChildSignature}
+		op, ok := operations[key]
+		if !ok {
+			op = &nplusone.OperationAggregate{
+				Service:        f.Service,
+				ParentSpanName: f.ParentSpanName,
+				ChildType:      f.ChildType,
+				ChildSignature: f.ChildSignature,
+			}
+			operations[key] = op
+			traces[key] = make(map[string]bool)
+		}
+		op.Occurrences++
+		op.TotalRepetitions += f.Repetitions
+		if f.Repetitions > op.MaxRepetitions {
+			op.MaxRepetitions = f.Repetitions
+		}
+		traces[key][f.TraceId] = true
+		op.Traces = len(traces[key])
+	}
+
+	result := make([]nplusone.OperationAggregate, 0, len(operations))
+	for _, op := range operations {
+		result = append(result, *op)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Occurrences != result[j].Occurrences {
+			return result[i].Occurrences > result[j].Occurrences
+		}
+		if result[i].TotalRepetitions != result[j].TotalRepetitions {
+			return result[i].TotalRepetitions > result[j].TotalRepetitions
+		}
+		if result[i].Service != result[j].Service {
+			return result[i].Service < result[j].Service
+		}
+		if result[i].ParentSpanName != result[j].ParentSpanName {
+			return result[i].ParentSpanName < result[j].ParentSpanName
+		}
+		return result[i].ChildSignature < result[j].ChildSignature
+	})
+	return result
+}
+
+func duration(span *internalspan.InternalSpan) uint64 {
+	if span.ExternalFields != nil {
+		return span.ExternalFields.DurationNano
+	}
+	if span.Span.EndTimeUnixNano < span.Span.StartTimeUnixNano {
+		return 0
+	}
+	return span.Span.EndTimeUnixNano - span.Span.StartTimeUnixNano
+}
@@ -0,0 +1,203 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nplusone
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	nplusone "github.com/teletrace/teletrace/pkg/model/nplusone/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+const pageSize = 3
+
+// traceSpanReader serves the spans of the traces it holds, in pages of pageSize spans.
+type traceSpanReader struct {
+	spanreader.SpanReader
+	spans []*internalspan.InternalSpan
+}
+
+func (sr *traceSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	var matching []*internalspan.InternalSpan
+	for _, span := range sr.spans {
+		if matchesFilters(span, r) {
+			matching = append(matching, span)
+		}
+	}
+
+	offset, _ := strconv.Atoi(string(r.Metadata.NextToken))
+	end := offset + pageSize
+	metadata := &spansquery.Metadata{NextToken: spansquery.ContinuationToken(strconv.Itoa(end))}
+	if end >= len(matching) {
+		end = len(matching)
+		metadata.NextToken = ""
+	}
+	return &spansquery.SearchResponse{Metadata: metadata, Spans: matching[offset:end]}, nil
+}
+
+func matchesFilters(span *internalspan.InternalSpan, r spansquery.SearchRequest) bool {
+	for _, f := range r.SearchFilters {
+		switch f.KeyValueFilter.Key {
+		case "span.traceId":
+			if span.Span.TraceId != f.KeyValueFilter.Value {
+				return false
+			}
+		case "externalFields.childCount":
+			if float64(span.ExternalFields.ChildCount) < f.KeyValueFilter.Value.(float64) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func newSpan(traceId string, spanId string, parentSpanId string, name string, durationNano uint64, attributes internalspan.Attributes) *internalspan.InternalSpan {
+	return &internalspan.InternalSpan{
+		Resource: &internalspan.Resource{Attributes: internalspan.Attributes{"service.name": "orders"}},
+		Span: &internalspan.Span{
+			TraceId:      traceId,
+			SpanId:       spanId,
+			ParentSpanId: parentSpanId,
+			Name:         name,
+			Attributes:   attributes,
+		},
+		ExternalFields: &internalspan.ExternalFields{DurationNano: durationNano},
+	}
+}
+
+// newNPlusOneTrace creates a trace whose root span selects a user per order, and calls an http service per order.
+func newNPlusOneTrace(traceId string, orders int) []*internalspan.InternalSpan {
+	root := newSpan(traceId, traceId+"-root", "", "GET /orders", uint64(time.Second), nil)
+	root.ExternalFields.ChildCount = uint64(2*orders + 1)
+	spans := []*internalspan.InternalSpan{root}
+	for i := 0; i < orders; i++ {
+		spans = append(spans,
+			newSpan(traceId, fmt.Sprintf("%s-db-%d", traceId, i), root.Span.SpanId, "SELECT users", uint64(time.Millisecond), internalspan.Attributes{
+				"db.system":    "postgresql",
+				"db.statement": fmt.Sprintf("SELECT * FROM users WHERE id = %d AND status = 'active'", i),
+			}),
+			newSpan(traceId, fmt.Sprintf("%s-http-%d", traceId, i), root.Span.SpanId, "GET", uint64(time.Millisecond), internalspan.Attributes{
+				"http.method": "GET",
+				"http.url":    fmt.Sprintf("http://inventory/items/%d?fields=all", 1000+i),
+			}),
+		)
+	}
+	// a single slow query isn't part of the pattern
+	spans = append(spans, newSpan(traceId, traceId+"-slow", root.Span.SpanId, "SELECT orders", uint64(time.Second), internalspan.Attributes{
+		"db.system":    "postgresql",
+		"db.statement": "SELECT * FROM orders",
+	}))
+	return spans
+}
+
+func newTestDetector(spans []*internalspan.InternalSpan, cfg Config) *Detector {
+	srMock, _ := mock.NewSpanReaderMock()
+	return NewDetector(zap.NewNop(), &traceSpanReader{SpanReader: srMock, spans: spans}, cfg)
+}
+
+func TestDetect(t *testing.T) {
+	var spans []*internalspan.InternalSpan
+	spans = append(spans, newNPlusOneTrace("trace-a", 5)...)
+	spans = append(spans, newNPlusOneTrace("trace-b", 3)...)
+	spans = append(spans, newNPlusOneTrace("trace-c", 0)...)
+	detector := newTestDetector(spans, Config{MinRepetitions: 3, MaxChildDuration: 50 * time.Millisecond, MaxTraces: 10})
+
+	res, err := detector.Detect(context.Background(), nplusone.DetectRequest{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, res.AnalyzedTraces)
+	assert.False(t, res.Truncated)
+	assert.Equal(t, []nplusone.Finding{
+		{
+			TraceId: "trace-a", ParentSpanId: "trace-a-root", ParentSpanName: "GET /orders", Service: "orders",
+			ChildType: "http", ChildSignature: "GET http://inventory/items/{id}",
+			Repetitions: 5, TotalDurationNano: uint64(5 * time.Millisecond),
+		},
+		{
+			TraceId: "trace-a", ParentSpanId: "trace-a-root", ParentSpanName: "GET /orders", Service: "orders",
+			ChildType: "db", ChildSignature: "SELECT * FROM users WHERE id = ? AND status = ?",
+			Repetitions: 5, TotalDurationNano: uint64(5 * time.Millisecond),
+		},
+		{
+			TraceId: "trace-b", ParentSpanId: "trace-b-root", ParentSpanName: "GET /orders", Service: "orders",
+			ChildType: "http", ChildSignature: "GET http://inventory/items/{id}",
+			Repetitions: 3, TotalDurationNano: uint64(3 * time.Millisecond),
+		},
+		{
+			TraceId: "trace-b", ParentSpanId: "trace-b-root", ParentSpanName: "GET /orders", Service: "orders",
+			ChildType: "db", ChildSignature: "SELECT * FROM users WHERE id = ? AND status = ?",
+			Repetitions: 3, TotalDurationNano: uint64(3 * time.Millisecond),
+		},
+	}, res.Findings)
+	assert.Equal(t, []nplusone.OperationAggregate{
+		{
+			Service: "orders", ParentSpanName: "GET /orders", ChildType: "http",
+			ChildSignature: "GET http://inventory/items/{id}",
+			Traces:         2, Occurrences: 2, TotalRepetitions: 8, MaxRepetitions: 5,
+		},
+		{
+			Service: "orders", ParentSpanName: "GET /orders", ChildType: "db",
+			ChildSignature: "SELECT * FROM users WHERE id = ? AND status = ?",
+			Traces:         2, Occurrences: 2, TotalRepetitions: 8, MaxRepetitions: 5,
+		},
+	}, res.Operations)
+}
+
+func TestDetectRequestThresholds(t *testing.T) {
+	detector := newTestDetector(newNPlusOneTrace("trace-a", 5), Config{MinRepetitions: 3, MaxChildDuration: 50 * time.Millisecond, MaxTraces: 10})
+
+	res, err := detector.Detect(context.Background(), nplusone.DetectRequest{MinRepetitions: 6})
+	assert.NoError(t, err)
+	assert.Empty(t, res.Findings)
+
+	res, err = detector.Detect(context.Background(), nplusone.DetectRequest{MaxChildDurationNano: uint64(time.Microsecond)})
+	assert.NoError(t, err)
+	assert.Empty(t, res.Findings)
+}
+
+func TestDetectMaxTraces(t *testing.T) {
+	var spans []*internalspan.InternalSpan
+	spans = append(spans, newNPlusOneTrace("trace-a", 3)...)
+	spans = append(spans, newNPlusOneTrace("trace-b", 3)...)
+	detector := newTestDetector(spans, Config{MinRepetitions: 3, MaxChildDuration: 50 * time.Millisecond, MaxTraces: 1})
+
+	res, err := detector.Detect(context.Background(), nplusone.DetectRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.AnalyzedTraces)
+	assert.True(t, res.Truncated)
+	assert.Len(t, res.Findings, 2)
+}
+
+func TestNormalizeStatement(t *testing.T) {
+	assert.Equal(t, "SELECT * FROM users WHERE id IN (?) AND name = ?",
+		normalizeStatement("SELECT *\n  FROM users WHERE id IN (1, 2, 3) AND name = 'O''Brien'"))
+}
+
+func TestNormalizeURL(t *testing.T) {
+	assert.Equal(t, "https://api/users/{id}/orders/{id}",
+		normalizeURL("https://api/users/42/orders/3f2c9a1e-8b7d-4c6e-9f00-1a2b3c4d5e6f?expand=items"))
+}
@@ -0,0 +1,92 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nplusone
+
+import (
+	"regexp"
+	"strings"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+)
+
+const (
+	childTypeDB   = "db"
+	childTypeHTTP = "http"
+)
+
+var (
+	quotedLiteralRegex  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numericLiteralRegex = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	inListRegex         = regexp.MustCompile(`(?i)\bIN\s*\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	whitespaceRegex     = regexp.MustCompile(`\s+`)
+	pathIdRegex         = regexp.MustCompile(`^(?:\d+|[0-9a-fA-F-]{8,})$`)
+)
+
+// childSignature returns the type of a db or http span and a signature shared by the near-identical spans,
+// i.e. spans running the same statement or calling the same route with different parameters.
+// Other spans have an empty type.
+func childSignature(span *internalspan.Span) (string, string) {
+	if _, ok := span.Attributes["db.system"]; ok {
+		if statement := stringAttribute(span.Attributes, "db.statement", "db.query.text"); statement != "" {
+			return childTypeDB, normalizeStatement(statement)
+		}
+		return childTypeDB, span.Name
+	}
+
+	if method := stringAttribute(span.Attributes, "http.method", "http.request.method"); method != "" {
+		if route := stringAttribute(span.Attributes, "http.route"); route != "" {
+			return childTypeHTTP, method + " " + route
+		}
+		if url := stringAttribute(span.Attributes, "http.url", "url.full", "http.target", "url.path"); url != "" {
+			return childTypeHTTP, method + " " + normalizeURL(url)
+		}
+		return childTypeHTTP, span.Name
+	}
+
+	return "", ""
+}
+
+// normalizeStatement replaces the literals of a statement with placeholders.
+func normalizeStatement(statement string) string {
+	statement = quotedLiteralRegex.ReplaceAllString(statement, "?")
+	statement = numericLiteralRegex.ReplaceAllString(statement, "?")
+	statement = inListRegex.ReplaceAllString(statement, "IN (?)")
+	return strings.TrimSpace(whitespaceRegex.ReplaceAllString(statement, " "))
+}
+
+// normalizeURL drops the query of a URL and replaces the path segments that look like IDs with a placeholder.
+func normalizeURL(url string) string {
+	if i := strings.IndexAny(url, "?#"); i >= 0 {
+		url = url[:i]
+	}
+	segments := strings.Split(url, "/")
+	for i, segment := range segments {
+		if pathIdRegex.MatchString(segment) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func stringAttribute(attributes internalspan.Attributes, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := attributes[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
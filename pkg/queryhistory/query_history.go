@@ -0,0 +1,162 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package queryhistory
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	queryhistoryv1 "github.com/teletrace/teletrace/pkg/model/queryhistory/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// Namespace is the metadata store namespace holding the query history, a record per user.
+const Namespace = "query-history"
+
+// History records the recent searches of each user in a metadata store.
+type History struct {
+	store metadatastore.MetadataStore
+	size  int
+	now   func() time.Time
+
+	// mu serializes the updates of the history records, read and rewritten on every search
+	mu sync.Mutex
+}
+
+// NewHistory returns a history keeping the size most recent searches of each user.
+func NewHistory(store metadatastore.MetadataStore, size int) *History {
+	return &History{store: store, size: size, now: time.Now}
+}
+
+// Record adds a search of user to the top of their history, dropping the oldest searches beyond the history size.
+// Re-running a search moves it to the top under the same id instead of recording it twice.
+func (h *History) Record(ctx context.Context, user string, r spansquery.SearchRequest) error {
+	request, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("could not encode search request: %w", err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entries, err := h.List(ctx, user)
+	if err != nil {
+		return err
+	}
+	recorded := queryhistoryv1.Entry{Request: r, ExecutedAtUnixNano: uint64(h.now().UnixNano())}
+	rest := make([]queryhistoryv1.Entry, 0, len(entries))
+	for _, entry := range entries {
+		if entryRequest, err := json.Marshal(entry.Request); err == nil && string(entryRequest) == string(request) {
+			recorded.Id = entry.Id
+			continue
+		}
+		rest = append(rest, entry)
+	}
+	if recorded.Id == "" {
+		if recorded.Id, err = newId(); err != nil {
+			return err
+		}
+	}
+
+	updated := append([]queryhistoryv1.Entry{recorded}, rest...)
+	if len(updated) > h.size {
+		updated = updated[:h.size]
+	}
+	return metadatastore.PutJSON(ctx, h.store, Namespace, user, updated)
+}
+
+// List returns the recent searches of user, the most recent first.
+func (h *History) List(ctx context.Context, user string) ([]queryhistoryv1.Entry, error) {
+	entries := []queryhistoryv1.Entry{}
+	err := metadatastore.GetJSON(ctx, h.store, Namespace, user, &entries)
+	if err != nil && !errors.Is(err, metadatastore.ErrNotFound) {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Get returns a search in the history of user, or metadatastore.ErrNotFound.
+func (h *History) Get(ctx context.Context, user string, id string) (*queryhistoryv1.Entry, error) {
+	entries, err := h.List(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Id == id {
+			return &entry, nil
+		}
+	}
+	return nil, metadatastore.ErrNotFound
+}
+
+// PopularTags returns the tags filtered on by the most searches in the histories of all users, the most popular first,
+// up to limit. Histories which can't be decoded are skipped.
+func (h *History) PopularTags(ctx context.Context, limit int) ([]string, error) {
+	records, err := h.store.List(ctx, Namespace)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, record := range records {
+		var entries []queryhistoryv1.Entry
+		if err := json.Unmarshal(record.Value, &entries); err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			// a tag filtered on twice by a search counts once
+			searched := make(map[string]bool)
+			for _, filter := range entry.Request.SearchFilters {
+				if filter.KeyValueFilter != nil {
+					searched[string(filter.KeyValueFilter.Key)] = true
+				}
+			}
+			for tag := range searched {
+				counts[tag]++
+			}
+		}
+	}
+
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+	return tags, nil
+}
+
+func newId() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("could not generate query history id: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,100 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package queryhistory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	"github.com/teletrace/teletrace/pkg/metadatastore/memory"
+	"github.com/teletrace/teletrace/pkg/model"
+	queryhistory "github.com/teletrace/teletrace/pkg/model/queryhistory/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func search(start string) spansquery.SearchRequest {
+	return spansquery.SearchRequest{Timeframe: model.Timeframe{Start: start}}
+}
+
+func starts(entries []queryhistory.Entry) []string {
+	var result []string
+	for _, entry := range entries {
+		result = append(result, entry.Request.Timeframe.Start)
+	}
+	return result
+}
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	history := NewHistory(memory.NewMetadataStore(), 3)
+
+	for _, start := range []string{"now-1m", "now-2m", "now-3m", "now-1m", "now-4m"} {
+		assert.NoError(t, history.Record(ctx, "alice", search(start)))
+	}
+	assert.NoError(t, history.Record(ctx, "bob", search("now-1h")))
+
+	entries, err := history.List(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"now-4m", "now-1m", "now-3m"}, starts(entries))
+
+	rerun := entries[2]
+	assert.NoError(t, history.Record(ctx, "alice", rerun.Request))
+	entries, err = history.List(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"now-3m", "now-4m", "now-1m"}, starts(entries))
+	assert.Equal(t, rerun.Id, entries[0].Id)
+
+	entry, err := history.Get(ctx, "alice", entries[2].Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "now-1m", entry.Request.Timeframe.Start)
+	_, err = history.Get(ctx, "bob", entries[2].Id)
+	assert.ErrorIs(t, err, metadatastore.ErrNotFound)
+
+	entries, err = history.List(ctx, "carol")
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func filtered(start string, tags ...string) spansquery.SearchRequest {
+	r := search(start)
+	for _, tag := range tags {
+		r.SearchFilters = append(r.SearchFilters, model.SearchFilter{
+			KeyValueFilter: &model.KeyValueFilter{Key: model.FilterKey(tag), Operator: spansquery.OPERATOR_EQUALS, Value: "v"},
+		})
+	}
+	return r
+}
+
+func TestPopularTags(t *testing.T) {
+	ctx := context.Background()
+	history := NewHistory(memory.NewMetadataStore(), 10)
+
+	assert.NoError(t, history.Record(ctx, "alice", filtered("now-1m", "span.name", "span.name")))
+	assert.NoError(t, history.Record(ctx, "alice", filtered("now-2m", "span.name", "resource.attributes.service.name")))
+	assert.NoError(t, history.Record(ctx, "bob", filtered("now-1m", "span.attributes.http.route")))
+	assert.NoError(t, history.Record(ctx, "bob", filtered("now-2m", "resource.attributes.service.name", "span.name")))
+
+	tags, err := history.PopularTags(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"span.name", "resource.attributes.service.name"}, tags)
+
+	tags, err = history.PopularTags(ctx, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"span.name", "resource.attributes.service.name", "span.attributes.http.route"}, tags)
+}
@@ -0,0 +1,201 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package queryvalidation validates search requests without running them, against the tags known to the storage,
+// so the problems of a query can be shown while it's being built.
+package queryvalidation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+)
+
+const (
+	fieldKey      = "key"
+	fieldOperator = "operator"
+	fieldValue    = "value"
+
+	// the tag types reported by the storage plugins, named after the OpenTelemetry pcommon value types
+	typeStr    = "Str"
+	typeInt    = "Int"
+	typeDouble = "Double"
+	typeBool   = "Bool"
+)
+
+// operators are the supported filter operators
+var operators = map[model.FilterOperator]bool{
+	spansquery.OPERATOR_EQUALS:        true,
+	spansquery.OPERATOR_NOT_EQUALS:    true,
+	spansquery.OPERATOR_IN:            true,
+	spansquery.OPERATOR_NOT_IN:        true,
+	spansquery.OPERATOR_CONTAINS:      true,
+	spansquery.OPERATOR_NOT_CONTAINS:  true,
+	spansquery.OPERATOR_EXISTS:        true,
+	spansquery.OPERATOR_NOT_EXISTS:    true,
+	spansquery.OPERATOR_GT:            true,
+	spansquery.OPERATOR_GTE:           true,
+	spansquery.OPERATOR_LT:            true,
+	spansquery.OPERATOR_LTE:           true,
+	spansquery.OPERATOR_BINARY_PREFIX: true,
+	spansquery.OPERATOR_GT_PERCENTILE: true,
+	spansquery.OPERATOR_LT_PERCENTILE: true,
+}
+
+// Validate returns the problems of r, checking its filters against tags, the tags available in the storage.
+func Validate(r spansquery.SearchRequest, tags []tagsquery.TagInfo) []spansquery.ValidationError {
+	errs := make([]spansquery.ValidationError, 0)
+
+	// the problems of the request itself, its filters are validated below in detail
+	requestWithoutFilters := r
+	requestWithoutFilters.SearchFilters = nil
+	if err := requestWithoutFilters.Validate(); err != nil {
+		errs = append(errs, spansquery.ValidationError{Message: err.Error()})
+	}
+
+	tagTypes := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		tagTypes[tag.Name] = tag.Type
+	}
+	for i, f := range r.SearchFilters {
+		index := i
+		for _, err := range validateFilter(f, tagTypes) {
+			err.FilterIndex = &index
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func validateFilter(f model.SearchFilter, tagTypes map[string]string) []spansquery.ValidationError {
+	kv := f.KeyValueFilter
+	if kv == nil {
+		return []spansquery.ValidationError{{Message: "filter must be a key value filter"}}
+	}
+
+	var errs []spansquery.ValidationError
+	tagType, knownTag := tagTypes[string(kv.Key)]
+	if kv.Key == "" {
+		errs = append(errs, spansquery.ValidationError{Field: fieldKey, Message: "key is required"})
+	} else if !knownTag {
+		errs = append(errs, spansquery.ValidationError{Field: fieldKey, Message: fmt.Sprintf("unknown tag %s", kv.Key)})
+	}
+
+	if !operators[kv.Operator] {
+		return append(errs, spansquery.ValidationError{
+			Field:   fieldOperator,
+			Message: fmt.Sprintf("unknown operator %q, expected one of %s", kv.Operator, strings.Join(operatorNames(), ", ")),
+		})
+	}
+
+	if err := validateValue(kv, tagType); err != "" {
+		errs = append(errs, spansquery.ValidationError{Field: fieldValue, Message: err})
+	}
+	return errs
+}
+
+// validateValue returns the problem of the value of kv given the type of its tag (empty if unknown), if any.
+func validateValue(kv *model.KeyValueFilter, tagType string) string {
+	switch kv.Operator {
+	case spansquery.OPERATOR_EXISTS, spansquery.OPERATOR_NOT_EXISTS:
+		return ""
+	case spansquery.OPERATOR_BINARY_PREFIX:
+		if err := spansquery.ValidateFilters([]model.SearchFilter{{KeyValueFilter: kv}}); err != nil {
+			return err.Error()
+		}
+		return ""
+	case spansquery.OPERATOR_IN, spansquery.OPERATOR_NOT_IN:
+		values, ok := kv.Value.([]any)
+		if !ok || len(values) == 0 {
+			return fmt.Sprintf("%s requires a non empty list of values", kv.Operator)
+		}
+		for _, v := range values {
+			if err := validateScalar(v, tagType); err != "" {
+				return err
+			}
+		}
+		return ""
+	case spansquery.OPERATOR_GT, spansquery.OPERATOR_GTE, spansquery.OPERATOR_LT, spansquery.OPERATOR_LTE:
+		if tagType != "" && !isNumericType(tagType) {
+			return fmt.Sprintf("%s requires a numeric tag, %s is of type %s", kv.Operator, kv.Key, tagType)
+		}
+		if !spansquery.IsNumber(kv.Value) {
+			return fmt.Sprintf("%s requires a numeric value", kv.Operator)
+		}
+		return ""
+	case spansquery.OPERATOR_GT_PERCENTILE, spansquery.OPERATOR_LT_PERCENTILE:
+		if tagType != "" && !isNumericType(tagType) {
+			return fmt.Sprintf("%s requires a numeric tag, %s is of type %s", kv.Operator, kv.Key, tagType)
+		}
+		if _, err := spansquery.ParsePercentileThreshold(kv.Value); err != nil {
+			return err.Error()
+		}
+		return ""
+	case spansquery.OPERATOR_CONTAINS, spansquery.OPERATOR_NOT_CONTAINS:
+		if tagType != "" && tagType != typeStr {
+			return fmt.Sprintf("%s requires a string tag, %s is of type %s", kv.Operator, kv.Key, tagType)
+		}
+		if _, ok := kv.Value.(string); !ok {
+			return fmt.Sprintf("%s requires a string value", kv.Operator)
+		}
+		return ""
+	default:
+		return validateScalar(kv.Value, tagType)
+	}
+}
+
+// validateScalar returns the problem of comparing v to the values of a tag of tagType (empty if unknown), if any.
+func validateScalar(v any, tagType string) string {
+	switch v.(type) {
+	case nil:
+		return "value is required"
+	case []any, map[string]any:
+		return "value must be a string, number or boolean"
+	}
+
+	var matches bool
+	switch tagType {
+	case typeStr:
+		_, matches = v.(string)
+	case typeInt, typeDouble:
+		matches = spansquery.IsNumber(v)
+	case typeBool:
+		_, matches = v.(bool)
+	default:
+		// unknown tags and types which can't be checked
+		matches = true
+	}
+	if !matches {
+		return fmt.Sprintf("value %v doesn't match the tag type %s", v, tagType)
+	}
+	return ""
+}
+
+func isNumericType(tagType string) bool {
+	return tagType == typeInt || tagType == typeDouble
+}
+
+func operatorNames() []string {
+	names := make([]string, 0, len(operators))
+	for op := range operators {
+		names = append(names, string(op))
+	}
+	sort.Strings(names)
+	return names
+}
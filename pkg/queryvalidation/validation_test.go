@@ -0,0 +1,93 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package queryvalidation
+
+import (
+	"testing"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var tags = []tagsquery.TagInfo{
+	{Name: "span.name", Type: "Str"},
+	{Name: "span.status.code", Type: "Int"},
+	{Name: "span.attributes.http.retry", Type: "Bool"},
+}
+
+func filter(key string, operator string, value any) model.SearchFilter {
+	return model.SearchFilter{KeyValueFilter: &model.KeyValueFilter{
+		Key: model.FilterKey(key), Operator: model.FilterOperator(operator), Value: value,
+	}}
+}
+
+func TestValidFilters(t *testing.T) {
+	r := spansquery.SearchRequest{SearchFilters: []model.SearchFilter{
+		filter("span.name", spansquery.OPERATOR_CONTAINS, "GET"),
+		filter("span.status.code", spansquery.OPERATOR_GTE, float64(1)),
+		filter("span.status.code", spansquery.OPERATOR_IN, []any{float64(1), float64(2)}),
+		filter("span.attributes.http.retry", spansquery.OPERATOR_EQUALS, true),
+		filter("span.name", spansquery.OPERATOR_EXISTS, nil),
+		filter("span.status.code", spansquery.OPERATOR_GT_PERCENTILE, map[string]any{"percentile": "p95", "window": "15m"}),
+	}}
+	assert.Empty(t, Validate(r, tags))
+}
+
+func TestInvalidFilters(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		filter model.SearchFilter
+		field  string
+	}{
+		{"unknown tag", filter("span.nme", spansquery.OPERATOR_EQUALS, "GET"), fieldKey},
+		{"missing key", filter("", spansquery.OPERATOR_EQUALS, "GET"), fieldKey},
+		{"unknown operator", filter("span.name", "like", "GET"), fieldOperator},
+		{"type mismatch", filter("span.status.code", spansquery.OPERATOR_EQUALS, "error"), fieldValue},
+		{"range of a string tag", filter("span.name", spansquery.OPERATOR_GT, float64(1)), fieldValue},
+		{"non numeric range", filter("span.status.code", spansquery.OPERATOR_LT, "2"), fieldValue},
+		{"contains of a numeric tag", filter("span.status.code", spansquery.OPERATOR_CONTAINS, "1"), fieldValue},
+		{"scalar in", filter("span.name", spansquery.OPERATOR_IN, "GET"), fieldValue},
+		{"in type mismatch", filter("span.status.code", spansquery.OPERATOR_IN, []any{float64(1), "2"}), fieldValue},
+		{"missing value", filter("span.name", spansquery.OPERATOR_EQUALS, nil), fieldValue},
+		{"bad binary value", filter("span.name", spansquery.OPERATOR_BINARY_PREFIX, "hex:cafe"), fieldValue},
+		{"percentile of a string tag", filter("span.name", spansquery.OPERATOR_GT_PERCENTILE, map[string]any{"percentile": "p95"}), fieldValue},
+		{"unknown percentile", filter("span.status.code", spansquery.OPERATOR_LT_PERCENTILE, map[string]any{"percentile": "p42"}), fieldValue},
+		{"bad percentile window", filter("span.status.code", spansquery.OPERATOR_GT_PERCENTILE, map[string]any{"percentile": "p95", "window": "hour"}), fieldValue},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := spansquery.SearchRequest{SearchFilters: []model.SearchFilter{filter("span.name", spansquery.OPERATOR_EXISTS, nil), tc.filter}}
+			errs := Validate(r, tags)
+			assert.Len(t, errs, 1)
+			assert.Equal(t, 1, *errs[0].FilterIndex)
+			assert.Equal(t, tc.field, errs[0].Field)
+			assert.NotEmpty(t, errs[0].Message)
+		})
+	}
+}
+
+func TestInvalidRequest(t *testing.T) {
+	r := spansquery.SearchRequest{
+		Timeframe:     model.Timeframe{StartTime: 2, EndTime: 1},
+		SearchFilters: []model.SearchFilter{{}},
+	}
+	errs := Validate(r, tags)
+	assert.Len(t, errs, 2)
+	assert.Nil(t, errs[0].FilterIndex, "timeframe problems aren't of a filter")
+	assert.Equal(t, 0, *errs[1].FilterIndex)
+}
@@ -0,0 +1,147 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+)
+
+// Namespace is the metadata store namespace holding the settings.
+const Namespace = "settings"
+
+const (
+	// UIPrefix prefixes the keys of UI preferences, which hold any JSON value
+	UIPrefix = "ui."
+	// FeaturePrefix prefixes the keys of feature flags, which hold a boolean
+	FeaturePrefix = "feature."
+	// InstallationPrefix prefixes the keys of installation metadata, which can't be set through the API
+	InstallationPrefix = "installation."
+)
+
+var (
+	ErrUnknownSetting = errors.New("unknown setting")
+	ErrReadOnly       = errors.New("setting is read-only")
+)
+
+// SystemId is the identifier of the installation.
+var SystemId = Define(InstallationPrefix+"system-id", "")
+
+// validators holds the type check of each defined setting by its key.
+var validators sync.Map
+
+// Setting is a typed setting stored under Key, whose value is Default until it is set.
+type Setting[T any] struct {
+	Key     string
+	Default T
+}
+
+// Define returns a setting of type T, whose values set by key through SetJSON must decode into T.
+func Define[T any](key string, defaultValue T) Setting[T] {
+	validators.Store(key, func(value json.RawMessage) error {
+		var v T
+		return json.Unmarshal(value, &v)
+	})
+	return Setting[T]{Key: key, Default: defaultValue}
+}
+
+// Get returns the value of the setting, or its default if it isn't set.
+func (s Setting[T]) Get(ctx context.Context, store metadatastore.MetadataStore) (T, error) {
+	var value T
+	err := metadatastore.GetJSON(ctx, store, Namespace, s.Key, &value)
+	if errors.Is(err, metadatastore.ErrNotFound) {
+		return s.Default, nil
+	}
+	if err != nil {
+		return s.Default, err
+	}
+	return value, nil
+}
+
+// Set stores the value of the setting.
+func (s Setting[T]) Set(ctx context.Context, store metadatastore.MetadataStore, value T) error {
+	return metadatastore.PutJSON(ctx, store, Namespace, s.Key, value)
+}
+
+// Feature returns the feature flag of name, disabled until it is set.
+func Feature(name string) Setting[bool] {
+	return Setting[bool]{Key: FeaturePrefix + name}
+}
+
+// List returns the JSON values of all the stored settings by key.
+func List(ctx context.Context, store metadatastore.MetadataStore) (map[string]json.RawMessage, error) {
+	records, err := store.List(ctx, Namespace)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]json.RawMessage, len(records))
+	for _, record := range records {
+		values[record.Key] = record.Value
+	}
+	return values, nil
+}
+
+// GetJSON returns the JSON value of the setting stored under key, or metadatastore.ErrNotFound.
+func GetJSON(ctx context.Context, store metadatastore.MetadataStore, key string) (json.RawMessage, error) {
+	record, err := store.Get(ctx, Namespace, key)
+	if err != nil {
+		return nil, err
+	}
+	return record.Value, nil
+}
+
+// SetJSON stores the JSON value of the setting under key, after checking that the setting may be set and that the
+// value matches its type.
+func SetJSON(ctx context.Context, store metadatastore.MetadataStore, key string, value json.RawMessage) error {
+	if err := Validate(key, value); err != nil {
+		return err
+	}
+	return store.Put(ctx, metadatastore.Record{Namespace: Namespace, Key: key, Value: value})
+}
+
+// Validate checks that the setting of key may be set through the API to value.
+func Validate(key string, value json.RawMessage) error {
+	if !json.Valid(value) {
+		return fmt.Errorf("invalid value of setting %s: not valid JSON", key)
+	}
+	switch {
+	case strings.HasPrefix(key, InstallationPrefix):
+		return fmt.Errorf("%w: %s", ErrReadOnly, key)
+	case strings.HasPrefix(key, UIPrefix) && len(key) > len(UIPrefix):
+		return nil
+	case strings.HasPrefix(key, FeaturePrefix) && len(key) > len(FeaturePrefix):
+		var enabled bool
+		if err := json.Unmarshal(value, &enabled); err != nil {
+			return fmt.Errorf("invalid value of feature flag %s, expected a boolean", key)
+		}
+		return nil
+	}
+	validator, ok := validators.Load(key)
+	if !ok {
+		return fmt.Errorf("%w: %s, expected a defined setting or a %s or %s prefixed key",
+			ErrUnknownSetting, key, UIPrefix, FeaturePrefix)
+	}
+	if err := validator.(func(json.RawMessage) error)(value); err != nil {
+		return fmt.Errorf("invalid value of setting %s: %v", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore/memory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedSetting(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMetadataStore()
+	retention := Define("retention-days", 7)
+
+	days, err := retention.Get(ctx, store)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, days)
+
+	assert.NoError(t, retention.Set(ctx, store, 30))
+	days, err = retention.Get(ctx, store)
+	assert.NoError(t, err)
+	assert.Equal(t, 30, days)
+
+	assert.NoError(t, SetJSON(ctx, store, "retention-days", json.RawMessage(`14`)))
+	assert.Error(t, SetJSON(ctx, store, "retention-days", json.RawMessage(`"two weeks"`)))
+	days, err = retention.Get(ctx, store)
+	assert.NoError(t, err)
+	assert.Equal(t, 14, days)
+}
+
+func TestSetJSON(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMetadataStore()
+
+	assert.NoError(t, SetJSON(ctx, store, "ui.theme", json.RawMessage(`{"mode": "dark"}`)))
+	assert.NoError(t, SetJSON(ctx, store, "feature.trace-tree", json.RawMessage(`true`)))
+	assert.Error(t, SetJSON(ctx, store, "feature.trace-tree", json.RawMessage(`"yes"`)))
+	assert.Error(t, SetJSON(ctx, store, "ui.theme", json.RawMessage(`{`)))
+	assert.ErrorIs(t, SetJSON(ctx, store, "unknown", json.RawMessage(`1`)), ErrUnknownSetting)
+	assert.ErrorIs(t, SetJSON(ctx, store, SystemId.Key, json.RawMessage(`"id"`)), ErrReadOnly)
+
+	enabled, err := Feature("trace-tree").Get(ctx, store)
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+
+	values, err := List(ctx, store)
+	assert.NoError(t, err)
+	assert.Len(t, values, 2)
+	assert.JSONEq(t, `{"mode": "dark"}`, string(values["ui.theme"]))
+}
@@ -0,0 +1,171 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package slos
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	slosv1 "github.com/teletrace/teletrace/pkg/model/slos/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/acl"
+)
+
+const (
+	serviceTag      = "resource.attributes.service.name"
+	operationTag    = "span.name"
+	durationTag     = "externalFields.durationNano"
+	statusCodeTag   = "span.status.code"
+	errorStatusCode = "Error"
+	// statusCodeValuesLimit covers all the span status codes: Unset, Ok and Error
+	statusCodeValuesLimit = 10
+)
+
+// DefaultBurnRateWindows are the windows of the burn rates reported with an SLO, the short ones catch fast burns
+// and the long ones slow burns.
+var DefaultBurnRateWindows = []time.Duration{time.Hour, 6 * time.Hour, 24 * time.Hour, 72 * time.Hour}
+
+// Calculator computes the compliance of SLOs from the stored spans.
+type Calculator struct {
+	spanReader spanreader.SpanReader
+	now        func() time.Time
+}
+
+func NewCalculator(sr spanreader.SpanReader) *Calculator {
+	return &Calculator{spanReader: sr, now: time.Now}
+}
+
+// ErrorBudget returns the compliance of an SLO over its window ending now.
+func (c *Calculator) ErrorBudget(ctx context.Context, slo slosv1.SLO) (*slosv1.ErrorBudget, error) {
+	total, bad, err := c.count(ctx, slo, time.Duration(slo.WindowDays)*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	budget := &slosv1.ErrorBudget{
+		TotalSpans:      total,
+		BadSpans:        bad,
+		SLI:             1,
+		AllowedBadSpans: float64(total) * (1 - slo.Objective),
+		Remaining:       1,
+	}
+	if total > 0 {
+		budget.SLI = float64(total-bad) / float64(total)
+		budget.Remaining = 1 - float64(bad)/budget.AllowedBadSpans
+	}
+	return budget, nil
+}
+
+// BurnRates returns the burn rates of an SLO within windows ending now.
+func (c *Calculator) BurnRates(ctx context.Context, slo slosv1.SLO, windows []time.Duration) ([]slosv1.BurnRate, error) {
+	burnRates := make([]slosv1.BurnRate, 0, len(windows))
+	for _, window := range windows {
+		total, bad, err := c.count(ctx, slo, window)
+		if err != nil {
+			return nil, err
+		}
+		burnRate := slosv1.BurnRate{WindowSeconds: int(window.Seconds()), TotalSpans: total, BadSpans: bad}
+		if total > 0 {
+			burnRate.Rate = float64(bad) / float64(total) / (1 - slo.Objective)
+		}
+		burnRates = append(burnRates, burnRate)
+	}
+	return burnRates, nil
+}
+
+// count returns the number of spans of an SLO within the window ending now, and how many of them are bad.
+func (c *Calculator) count(ctx context.Context, slo slosv1.SLO, window time.Duration) (int, int, error) {
+	if slo.Role != "" {
+		ctx = acl.WithRole(ctx, slo.Role)
+	}
+	now := c.now()
+	timeframe := model.Timeframe{
+		StartTime: uint64(now.Add(-window).UnixNano()),
+		EndTime:   uint64(now.UnixNano()),
+	}
+
+	counts, err := c.statusCounts(ctx, timeframe, filters(slo))
+	if err != nil {
+		return 0, 0, err
+	}
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+
+	switch slo.Type {
+	case slosv1.SLO_TYPE_AVAILABILITY:
+		return total, counts[errorStatusCode], nil
+	case slosv1.SLO_TYPE_LATENCY:
+		slow := append(filters(slo), model.SearchFilter{KeyValueFilter: &model.KeyValueFilter{
+			Key:      durationTag,
+			Operator: spansquery.OPERATOR_GT,
+			Value:    float64(time.Duration(slo.LatencyThresholdMilliseconds) * time.Millisecond),
+		}})
+		slowCounts, err := c.statusCounts(ctx, timeframe, slow)
+		if err != nil {
+			return 0, 0, err
+		}
+		bad := 0
+		for _, count := range slowCounts {
+			bad += count
+		}
+		return total, bad, nil
+	default:
+		return 0, 0, fmt.Errorf("unknown SLO type %q", slo.Type)
+	}
+}
+
+// statusCounts counts the spans matching the filters by their status codes, counting all of them in a single aggregation.
+func (c *Calculator) statusCounts(ctx context.Context, timeframe model.Timeframe, filters []model.SearchFilter) (map[string]int, error) {
+	res, err := c.spanReader.GetTagsValues(ctx, tagsquery.TagValuesRequest{
+		Timeframe:     &timeframe,
+		SearchFilters: filters,
+		Limit:         statusCodeValuesLimit,
+	}, []string{statusCodeTag})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	if values := res[statusCodeTag]; values != nil {
+		for _, value := range values.Values {
+			counts[fmt.Sprint(value.Value)] += value.Count
+		}
+	}
+	return counts, nil
+}
+
+// filters returns new filters matching the spans of an SLO, built for every query since readers may modify them.
+func filters(slo slosv1.SLO) []model.SearchFilter {
+	result := []model.SearchFilter{{KeyValueFilter: &model.KeyValueFilter{
+		Key:      serviceTag,
+		Operator: spansquery.OPERATOR_EQUALS,
+		Value:    slo.Service,
+	}}}
+	if slo.Operation != "" {
+		result = append(result, model.SearchFilter{KeyValueFilter: &model.KeyValueFilter{
+			Key:      operationTag,
+			Operator: spansquery.OPERATOR_EQUALS,
+			Value:    slo.Operation,
+		}})
+	}
+	return result
+}
@@ -0,0 +1,125 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package slos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore/memory"
+	slosv1 "github.com/teletrace/teletrace/pkg/model/slos/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// windowSpanReader counts the spans of each status code within the last hour and before it,
+// and the slow spans as a fraction of them.
+type windowSpanReader struct {
+	spanreader.SpanReader
+	now      time.Time
+	lastHour map[string]int
+	earlier  map[string]int
+	slow     float64
+	requests []tagsquery.TagValuesRequest
+}
+
+func (sr *windowSpanReader) GetTagsValues(ctx context.Context, r tagsquery.TagValuesRequest, tags []string) (map[string]*tagsquery.TagValuesResponse, error) {
+	sr.requests = append(sr.requests, r)
+	counts := map[string]int{}
+	for code, count := range sr.lastHour {
+		counts[code] += count
+	}
+	if r.Timeframe.StartTime < uint64(sr.now.Add(-time.Hour).UnixNano()) {
+		for code, count := range sr.earlier {
+			counts[code] += count
+		}
+	}
+
+	values := &tagsquery.TagValuesResponse{}
+	for code, count := range counts {
+		if r.SearchFilters[len(r.SearchFilters)-1].KeyValueFilter.Key == durationTag {
+			count = int(float64(count) * sr.slow)
+		}
+		values.Values = append(values.Values, tagsquery.TagValueInfo{Value: code, Count: count})
+	}
+	return map[string]*tagsquery.TagValuesResponse{statusCodeTag: values}, nil
+}
+
+func TestCalculator(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	sr := &windowSpanReader{
+		now:      now,
+		lastHour: map[string]int{"Ok": 900, "Error": 100},
+		earlier:  map[string]int{"Ok": 99000, "Unset": 900, "Error": 100},
+		slow:     0.01,
+	}
+	calculator := NewCalculator(sr)
+	calculator.now = func() time.Time { return now }
+
+	store := NewStore(memory.NewMetadataStore())
+	availability, err := store.Create(ctx, slosv1.CreateSLORequest{
+		Name: "checkout availability", Service: "checkout", Operation: "POST /checkout",
+		Type: slosv1.SLO_TYPE_AVAILABILITY, Objective: 0.99, WindowDays: 30,
+	}, "")
+	assert.NoError(t, err)
+
+	budget, err := calculator.ErrorBudget(ctx, *availability)
+	assert.NoError(t, err)
+	assert.Equal(t, 101000, budget.TotalSpans)
+	assert.Equal(t, 200, budget.BadSpans)
+	assert.InDelta(t, 0.99802, budget.SLI, 0.00001)
+	assert.InDelta(t, 1010, budget.AllowedBadSpans, 0.001)
+	assert.InDelta(t, 1-200.0/1010, budget.Remaining, 0.00001)
+	assert.Equal(t, uint64(now.Add(-30*24*time.Hour).UnixNano()), sr.requests[0].Timeframe.StartTime)
+	assert.Len(t, sr.requests[0].SearchFilters, 2)
+
+	burnRates, err := calculator.BurnRates(ctx, *availability, []time.Duration{time.Hour, 24 * time.Hour})
+	assert.NoError(t, err)
+	assert.Equal(t, 3600, burnRates[0].WindowSeconds)
+	// 10% of the spans of the last hour failed, burning the 1% budget 10 times faster than allowed
+	assert.InDelta(t, 10, burnRates[0].Rate, 0.00001)
+	assert.InDelta(t, 200.0/101000/0.01, burnRates[1].Rate, 0.00001)
+
+	latency, err := store.Create(ctx, slosv1.CreateSLORequest{
+		Name: "checkout latency", Service: "checkout", Type: slosv1.SLO_TYPE_LATENCY,
+		Objective: 0.95, LatencyThresholdMilliseconds: 500, WindowDays: 7,
+	}, "")
+	assert.NoError(t, err)
+	budget, err = calculator.ErrorBudget(ctx, *latency)
+	assert.NoError(t, err)
+	assert.Equal(t, 101000, budget.TotalSpans)
+	assert.Equal(t, 1010, budget.BadSpans)
+	slowFilter := sr.requests[len(sr.requests)-1].SearchFilters[1].KeyValueFilter
+	assert.Equal(t, float64(500*time.Millisecond), slowFilter.Value)
+
+	slos, err := store.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, slos, 2)
+}
+
+func TestErrorBudgetWithoutSpans(t *testing.T) {
+	calculator := NewCalculator(&windowSpanReader{now: time.Now()})
+	budget, err := calculator.ErrorBudget(context.Background(), slosv1.SLO{
+		Service: "checkout", Type: slosv1.SLO_TYPE_AVAILABILITY, Objective: 0.99, WindowDays: 30,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), budget.SLI)
+	assert.Equal(t, float64(1), budget.Remaining)
+}
@@ -0,0 +1,109 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package slos
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	slosv1 "github.com/teletrace/teletrace/pkg/model/slos/v1"
+)
+
+// Namespace is the metadata store namespace holding the SLOs, keyed by their ids.
+const Namespace = "slos"
+
+// Store persists the SLOs in a metadata store.
+type Store struct {
+	store metadatastore.MetadataStore
+	now   func() time.Time
+}
+
+func NewStore(store metadatastore.MetadataStore) *Store {
+	return &Store{store: store, now: time.Now}
+}
+
+// Create persists a new SLO, computed on behalf of role if the access control is enabled, and returns it.
+func (s *Store) Create(ctx context.Context, r slosv1.CreateSLORequest, role string) (*slosv1.SLO, error) {
+	id, err := newId()
+	if err != nil {
+		return nil, err
+	}
+	slo := slosv1.SLO{
+		Id:                           id,
+		Name:                         r.Name,
+		Service:                      r.Service,
+		Operation:                    r.Operation,
+		Type:                         r.Type,
+		Objective:                    r.Objective,
+		LatencyThresholdMilliseconds: r.LatencyThresholdMilliseconds,
+		WindowDays:                   r.WindowDays,
+		Role:                         role,
+		CreatedAtUnixNano:            uint64(s.now().UnixNano()),
+	}
+	if err := metadatastore.PutJSON(ctx, s.store, Namespace, id, slo); err != nil {
+		return nil, err
+	}
+	return &slo, nil
+}
+
+// Get returns an SLO, or metadatastore.ErrNotFound.
+func (s *Store) Get(ctx context.Context, id string) (*slosv1.SLO, error) {
+	var slo slosv1.SLO
+	if err := metadatastore.GetJSON(ctx, s.store, Namespace, id, &slo); err != nil {
+		return nil, err
+	}
+	return &slo, nil
+}
+
+// List returns all the SLOs, the oldest first.
+func (s *Store) List(ctx context.Context) ([]slosv1.SLO, error) {
+	records, err := s.store.List(ctx, Namespace)
+	if err != nil {
+		return nil, err
+	}
+	slos := make([]slosv1.SLO, 0, len(records))
+	for _, record := range records {
+		var slo slosv1.SLO
+		if err := json.Unmarshal(record.Value, &slo); err != nil {
+			return nil, fmt.Errorf("could not decode SLO %s: %w", record.Key, err)
+		}
+		slos = append(slos, slo)
+	}
+	sort.SliceStable(slos, func(i, j int) bool {
+		return slos[i].CreatedAtUnixNano < slos[j].CreatedAtUnixNano
+	})
+	return slos, nil
+}
+
+// Delete removes an SLO, or returns metadatastore.ErrNotFound.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	return s.store.Delete(ctx, Namespace, id)
+}
+
+func newId() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("could not generate SLO id: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
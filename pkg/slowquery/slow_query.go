@@ -0,0 +1,115 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package slowquery logs and counts the storage queries running longer than a threshold,
+// to help diagnose pathological user queries.
+package slowquery
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/model"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// maxQueryLength truncates the logged queries, as generated queries may embed long filter values
+const maxQueryLength = 16 * 1024
+
+var (
+	operationKey = tag.MustNewKey("operation")
+
+	slowQueries = stats.Int64(
+		"teletrace_storage_slow_queries",
+		"Number of storage queries running longer than the slow query threshold",
+		stats.UnitDimensionless,
+	)
+
+	registerViewsOnce sync.Once
+	errRegisterViews  error
+)
+
+// Log logs and counts slow queries. A nil Log observes nothing.
+type Log struct {
+	logger    *zap.Logger
+	threshold time.Duration
+}
+
+// NewLog returns a Log of the queries running longer than threshold, or nil if threshold isn't positive.
+func NewLog(logger *zap.Logger, threshold time.Duration) (*Log, error) {
+	if threshold <= 0 {
+		return nil, nil
+	}
+	registerViewsOnce.Do(func() {
+		errRegisterViews = view.Register(&view.View{
+			Name:        slowQueries.Name(),
+			Description: slowQueries.Description(),
+			Measure:     slowQueries,
+			TagKeys:     []tag.Key{operationKey},
+			Aggregation: view.Sum(),
+		})
+	})
+	if errRegisterViews != nil {
+		return nil, errRegisterViews
+	}
+	return &Log{logger: logger, threshold: threshold}, nil
+}
+
+// Observe logs and counts query of operation, generated for filters, if it has been running longer than the threshold
+// since start. Failed queries are observed as well, as timed out queries are the slowest of all.
+func (l *Log) Observe(ctx context.Context, operation string, query string, filters []model.SearchFilter, start time.Time) {
+	if l == nil {
+		return
+	}
+	duration := time.Since(start)
+	if duration < l.threshold {
+		return
+	}
+
+	if len(query) > maxQueryLength {
+		query = query[:maxQueryLength] + "..."
+	}
+	_ = stats.RecordWithTags(ctx, []tag.Mutator{tag.Upsert(operationKey, operation)}, slowQueries.M(1))
+	l.logger.Warn("Slow storage query",
+		zap.String("operation", operation),
+		zap.Duration("duration", duration),
+		zap.String("query", query),
+		zap.Any("filters", filters),
+	)
+}
+
+type operationContextKey struct{}
+
+type operationContext struct {
+	operation string
+	filters   []model.SearchFilter
+}
+
+// WithOperation returns a copy of ctx carrying the operation and filters of the queries made with it,
+// for plugins observing their queries where only the context is available, e.g. in an http transport.
+func WithOperation(ctx context.Context, operation string, filters []model.SearchFilter) context.Context {
+	return context.WithValue(ctx, operationContextKey{}, operationContext{operation: operation, filters: filters})
+}
+
+// OperationFromContext returns the operation and filters carried by ctx, if any.
+func OperationFromContext(ctx context.Context) (operation string, filters []model.SearchFilter, ok bool) {
+	oc, ok := ctx.Value(operationContextKey{}).(operationContext)
+	return oc.operation, oc.filters, ok
+}
@@ -0,0 +1,67 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package slowquery
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/model"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestObserve(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	l, err := NewLog(zap.New(core), time.Second)
+	assert.NoError(t, err)
+	filters := []model.SearchFilter{{KeyValueFilter: &model.KeyValueFilter{Key: "span.name", Operator: "equals", Value: "GET"}}}
+
+	l.Observe(context.Background(), "search", "SELECT 1", filters, time.Now())
+	assert.Equal(t, 0, logs.Len(), "fast queries aren't logged")
+
+	l.Observe(context.Background(), "search", "SELECT 1", filters, time.Now().Add(-2*time.Second))
+	assert.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "search", fields["operation"])
+	assert.Equal(t, "SELECT 1", fields["query"])
+	assert.NotNil(t, fields["filters"])
+
+	l.Observe(context.Background(), "search", strings.Repeat("x", 2*maxQueryLength), nil, time.Now().Add(-2*time.Second))
+	assert.Len(t, logs.All()[1].ContextMap()["query"], maxQueryLength+len("..."))
+}
+
+func TestDisabledLog(t *testing.T) {
+	l, err := NewLog(zap.NewNop(), 0)
+	assert.NoError(t, err)
+	assert.Nil(t, l)
+	l.Observe(context.Background(), "search", "SELECT 1", nil, time.Now().Add(-time.Hour))
+}
+
+func TestOperationContext(t *testing.T) {
+	_, _, ok := OperationFromContext(context.Background())
+	assert.False(t, ok)
+
+	filters := []model.SearchFilter{{KeyValueFilter: &model.KeyValueFilter{Key: "span.name"}}}
+	operation, actualFilters, ok := OperationFromContext(WithOperation(context.Background(), "tag_values", filters))
+	assert.True(t, ok)
+	assert.Equal(t, "tag_values", operation)
+	assert.Equal(t, filters, actualFilters)
+}
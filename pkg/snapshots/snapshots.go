@@ -0,0 +1,103 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package snapshots
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	snapshotsv1 "github.com/teletrace/teletrace/pkg/model/snapshots/v1"
+)
+
+// Namespace is the metadata store namespace holding the snapshots.
+const Namespace = "snapshots"
+
+// tokenBytes is the number of random bytes of a snapshot token, enough that tokens can't be guessed.
+const tokenBytes = 12
+
+// Store persists the snapshots of traces in a metadata store.
+type Store struct {
+	store metadatastore.MetadataStore
+	now   func() time.Time
+}
+
+func NewStore(store metadatastore.MetadataStore) *Store {
+	return &Store{store: store, now: time.Now}
+}
+
+// Create snapshots the spans of a trace under a new token, kept for expiresIn, or until deleted when it's 0.
+func (s *Store) Create(
+	ctx context.Context, traceId string, spans []*internalspan.InternalSpan, expiresIn time.Duration,
+) (*snapshotsv1.Snapshot, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	snapshot := snapshotsv1.Snapshot{
+		Token:             token,
+		TraceId:           traceId,
+		CreatedAtUnixNano: uint64(now.UnixNano()),
+		Spans:             spans,
+	}
+	if expiresIn > 0 {
+		snapshot.ExpiresAtUnixNano = uint64(now.Add(expiresIn).UnixNano())
+	}
+	value, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode snapshot: %w", err)
+	}
+	if err := s.store.Create(ctx, metadatastore.Record{Namespace: Namespace, Key: token, Value: value}); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// Get returns the snapshot of token, or metadatastore.ErrNotFound if it doesn't exist or expired.
+// Expired snapshots are deleted when they're read.
+func (s *Store) Get(ctx context.Context, token string) (*snapshotsv1.Snapshot, error) {
+	var snapshot snapshotsv1.Snapshot
+	if err := metadatastore.GetJSON(ctx, s.store, Namespace, token, &snapshot); err != nil {
+		return nil, err
+	}
+	if snapshot.ExpiresAtUnixNano != 0 && uint64(s.now().UnixNano()) >= snapshot.ExpiresAtUnixNano {
+		if err := s.store.Delete(ctx, Namespace, token); err != nil && !errors.Is(err, metadatastore.ErrNotFound) {
+			return nil, err
+		}
+		return nil, metadatastore.ErrNotFound
+	}
+	return &snapshot, nil
+}
+
+// Delete removes the snapshot of token, or returns metadatastore.ErrNotFound.
+func (s *Store) Delete(ctx context.Context, token string) error {
+	return s.store.Delete(ctx, Namespace, token)
+}
+
+func newToken() (string, error) {
+	token := make([]byte, tokenBytes)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("could not generate snapshot token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
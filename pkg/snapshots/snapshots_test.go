@@ -0,0 +1,59 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package snapshots
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	"github.com/teletrace/teletrace/pkg/metadatastore/memory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshots(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(memory.NewMetadataStore())
+	now := time.Unix(1700000000, 0)
+	store.now = func() time.Time { return now }
+	spans := []*internalspan.InternalSpan{{Span: &internalspan.Span{TraceId: "trace", SpanId: "span"}}}
+
+	kept, err := store.Create(ctx, "trace", spans, 0)
+	assert.NoError(t, err)
+	expiring, err := store.Create(ctx, "trace", spans, time.Hour)
+	assert.NoError(t, err)
+	assert.NotEqual(t, kept.Token, expiring.Token)
+	assert.Zero(t, kept.ExpiresAtUnixNano)
+
+	snapshot, err := store.Get(ctx, expiring.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, "span", snapshot.Spans[0].Span.SpanId)
+
+	now = now.Add(2 * time.Hour)
+	_, err = store.Get(ctx, expiring.Token)
+	assert.ErrorIs(t, err, metadatastore.ErrNotFound)
+	assert.ErrorIs(t, store.Delete(ctx, expiring.Token), metadatastore.ErrNotFound, "expired snapshots are deleted")
+
+	snapshot, err = store.Get(ctx, kept.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, "trace", snapshot.TraceId)
+	assert.NoError(t, store.Delete(ctx, kept.Token))
+	_, err = store.Get(ctx, kept.Token)
+	assert.ErrorIs(t, err, metadatastore.ErrNotFound)
+}
@@ -0,0 +1,140 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package acl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const policyYaml = `
+roles:
+  payments:
+    filters:
+      - key: resource.attributes.k8s.namespace.name
+        operator: in
+        value: [payments, payments-staging]
+  admin: {}
+`
+
+type recordingSpanReader struct {
+	spanreader.SpanReader
+	searchRequest    spansquery.SearchRequest
+	tagValuesRequest tagsquery.TagValuesRequest
+}
+
+func (sr *recordingSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	sr.searchRequest = r
+	// readers may modify the filters they get, the policy must not be affected
+	for _, f := range r.SearchFilters {
+		f.KeyValueFilter.Key += ".keyword"
+	}
+	return sr.SpanReader.Search(ctx, r)
+}
+
+func (sr *recordingSpanReader) GetTagsValues(
+	ctx context.Context, r tagsquery.TagValuesRequest, tags []string,
+) (map[string]*tagsquery.TagValuesResponse, error) {
+	sr.tagValuesRequest = r
+	return sr.SpanReader.GetTagsValues(ctx, r, tags)
+}
+
+func newTestSpanReader(t *testing.T) (spanreader.SpanReader, *recordingSpanReader) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(policyYaml), 0o600))
+	policy, err := LoadPolicy(path)
+	assert.NoError(t, err)
+
+	srMock, _ := mock.NewSpanReaderMock()
+	recorder := &recordingSpanReader{SpanReader: srMock}
+	return NewSpanReader(recorder, policy), recorder
+}
+
+func TestLoadPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(policyYaml), 0o600))
+
+	policy, err := LoadPolicy(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"admin", "payments"}, policy.RoleNames())
+	assert.True(t, policy.HasRole("payments"))
+	assert.False(t, policy.HasRole("unknown"))
+}
+
+func TestLoadInvalidPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("roles:\n  payments:\n    filters:\n      - key: service.name\n"), 0o600))
+
+	_, err := LoadPolicy(path)
+
+	assert.Error(t, err)
+}
+
+func TestSearchInjectsRoleFilters(t *testing.T) {
+	sr, recorder := newTestSpanReader(t)
+	ctx := WithRole(context.Background(), "payments")
+	requestFilter := model.SearchFilter{KeyValueFilter: &model.KeyValueFilter{Key: "span.name", Operator: "equals", Value: "GET"}}
+
+	for i := 0; i < 2; i++ {
+		_, err := sr.Search(ctx, spansquery.SearchRequest{SearchFilters: []model.SearchFilter{requestFilter}})
+		assert.NoError(t, err)
+	}
+
+	assert.Len(t, recorder.searchRequest.SearchFilters, 2)
+	injected := recorder.searchRequest.SearchFilters[1].KeyValueFilter
+	assert.Equal(t, model.FilterKey("resource.attributes.k8s.namespace.name.keyword"), injected.Key)
+	assert.Equal(t, model.FilterOperator(spansquery.OPERATOR_IN), injected.Operator)
+}
+
+func TestTagsValuesInjectsRoleFilters(t *testing.T) {
+	sr, recorder := newTestSpanReader(t)
+
+	_, err := sr.GetTagsValues(WithRole(context.Background(), "payments"), tagsquery.TagValuesRequest{}, []string{"span.name"})
+
+	assert.NoError(t, err)
+	assert.Len(t, recorder.tagValuesRequest.SearchFilters, 1)
+}
+
+func TestUnrestrictedRole(t *testing.T) {
+	sr, recorder := newTestSpanReader(t)
+
+	_, err := sr.Search(WithRole(context.Background(), "admin"), spansquery.SearchRequest{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, recorder.searchRequest.SearchFilters)
+}
+
+func TestAccessDenied(t *testing.T) {
+	sr, _ := newTestSpanReader(t)
+
+	_, err := sr.Search(context.Background(), spansquery.SearchRequest{})
+	assert.ErrorIs(t, err, ErrAccessDenied)
+
+	_, err = sr.GetTagsStatistics(WithRole(context.Background(), "unknown"), tagsquery.TagStatisticsRequest{}, "duration")
+	assert.ErrorIs(t, err, ErrAccessDenied)
+}
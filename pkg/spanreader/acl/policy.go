@@ -0,0 +1,119 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package acl
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/teletrace/teletrace/pkg/model"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+var ErrAccessDenied = errors.New("access denied")
+
+// RolePolicy defines the mandatory filters applied to every query of a role.
+// A role without filters may see all traces.
+type RolePolicy struct {
+	Filters []model.KeyValueFilter `mapstructure:"filters"`
+}
+
+// Policy maps role (or tenant) names to their policies.
+type Policy struct {
+	Roles map[string]RolePolicy `mapstructure:"roles"`
+}
+
+// LoadPolicy reads a policy from a yaml or json file, e.g.
+//
+//	roles:
+//	  payments:
+//	    filters:
+//	      - key: resource.attributes.k8s.namespace.name
+//	        operator: in
+//	        value: [payments, payments-staging]
+//	  admin: {}
+func LoadPolicy(path string) (Policy, error) {
+	var policy Policy
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return policy, fmt.Errorf("error loading acl policy file %s: %w", path, err)
+	}
+	// decoding the raw settings, since viper's Unmarshal drops roles with an empty policy
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		ErrorUnused: true,
+		Result:      &policy,
+	})
+	if err != nil {
+		return policy, err
+	}
+	if err := decoder.Decode(map[string]any{"roles": v.Get("roles")}); err != nil {
+		return policy, fmt.Errorf("error unmarshaling acl policy: %w", err)
+	}
+	if err := policy.validate(); err != nil {
+		return policy, err
+	}
+	return policy, nil
+}
+
+func (p Policy) validate() error {
+	if len(p.Roles) == 0 {
+		return fmt.Errorf("acl policy must define at least one role")
+	}
+	for role, rolePolicy := range p.Roles {
+		for _, f := range rolePolicy.Filters {
+			if f.Key == "" || f.Operator == "" {
+				return fmt.Errorf("acl policy of role %s has a filter without key or operator", role)
+			}
+		}
+	}
+	return nil
+}
+
+// HasRole returns whether the policy defines the given role.
+func (p Policy) HasRole(role string) bool {
+	_, ok := p.Roles[role]
+	return ok
+}
+
+// RoleNames returns the sorted names of the roles defined by the policy.
+func (p Policy) RoleNames() []string {
+	names := make([]string, 0, len(p.Roles))
+	for role := range p.Roles {
+		names = append(names, role)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Filters returns the mandatory filters of a role, or ErrAccessDenied for unknown roles.
+// Filters are copied on every call since readers may modify the filters they are given.
+func (p Policy) Filters(role string) ([]model.SearchFilter, error) {
+	rolePolicy, ok := p.Roles[role]
+	if !ok {
+		return nil, ErrAccessDenied
+	}
+	filters := make([]model.SearchFilter, 0, len(rolePolicy.Filters))
+	for _, f := range rolePolicy.Filters {
+		f := f
+		filters = append(filters, model.SearchFilter{KeyValueFilter: &f})
+	}
+	return filters, nil
+}
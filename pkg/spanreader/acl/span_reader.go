@@ -0,0 +1,154 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package acl
+
+import (
+	"context"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+)
+
+// spanReader implements every method explicitly (rather than embedding the wrapped reader),
+// so methods added to the interface can't bypass the policy unnoticed.
+type spanReader struct {
+	next   spanreader.SpanReader
+	policy Policy
+}
+
+// NewSpanReader wraps sr so that every spans query is restricted by the policy of the role
+// carried by the query context (see WithRole). Queries without a known role are denied.
+func NewSpanReader(sr spanreader.SpanReader, policy Policy) spanreader.SpanReader {
+	return &spanReader{next: sr, policy: policy}
+}
+
+func (sr *spanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	filters, err := sr.roleFilters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.SearchFilters = withFilters(r.SearchFilters, filters)
+	return sr.next.Search(ctx, r)
+}
+
+func (sr *spanReader) GetAvailableTags(ctx context.Context, r tagsquery.GetAvailableTagsRequest) (*tagsquery.GetAvailableTagsResponse, error) {
+	// tag names aren't restricted, but the role must still be known
+	if _, err := sr.roleFilters(ctx); err != nil {
+		return nil, err
+	}
+	return sr.next.GetAvailableTags(ctx, r)
+}
+
+func (sr *spanReader) GetTagsValues(
+	ctx context.Context, r tagsquery.TagValuesRequest, tags []string,
+) (map[string]*tagsquery.TagValuesResponse, error) {
+	filters, err := sr.roleFilters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.SearchFilters = withFilters(r.SearchFilters, filters)
+	return sr.next.GetTagsValues(ctx, r, tags)
+}
+
+func (sr *spanReader) GetTagsStatistics(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tag string,
+) (*tagsquery.TagStatisticsResponse, error) {
+	filters, err := sr.roleFilters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.SearchFilters = withFilters(r.SearchFilters, filters)
+	return sr.next.GetTagsStatistics(ctx, r, tag)
+}
+
+func (sr *spanReader) GetTagsStatisticsBatch(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string,
+) (map[string]*tagsquery.TagStatisticsResponse, error) {
+	filters, err := sr.roleFilters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.SearchFilters = withFilters(r.SearchFilters, filters)
+	return sr.next.GetTagsStatisticsBatch(ctx, r, tags)
+}
+
+func (sr *spanReader) SearchEvents(ctx context.Context, r eventsquery.SearchRequest) (*eventsquery.SearchResponse, error) {
+	filters, err := sr.roleFilters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.SearchFilters = withFilters(r.SearchFilters, filters)
+	return sr.next.SearchEvents(ctx, r)
+}
+
+// SearchTraces finds the traces of restricted roles through the restricted Search, so traces are matched by the spans
+// of the role only, and include none of the spans the role can't see.
+func (sr *spanReader) SearchTraces(ctx context.Context, r spansquery.SearchTracesRequest) (*spansquery.SearchTracesResponse, error) {
+	filters, err := sr.roleFilters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(filters) == 0 {
+		return sr.next.SearchTraces(ctx, r)
+	}
+	return spanreader.SearchTracesInSpans(ctx, sr, r)
+}
+
+// Aggregate aggregates the spans of restricted roles with their role filters added to the request, as Search does.
+func (sr *spanReader) Aggregate(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	filters, err := sr.roleFilters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.SearchFilters = withFilters(r.SearchFilters, filters)
+	return sr.next.Aggregate(ctx, r)
+}
+
+func (sr *spanReader) Initialize() error {
+	return sr.next.Initialize()
+}
+
+func (sr *spanReader) GetSystemId(ctx context.Context, r metadata.GetSystemIdRequest) (*metadata.GetSystemIdResponse, error) {
+	return sr.next.GetSystemId(ctx, r)
+}
+
+func (sr *spanReader) SetSystemId(ctx context.Context, r metadata.SetSystemIdRequest) (*metadata.SetSystemIdResponse, error) {
+	return sr.next.SetSystemId(ctx, r)
+}
+
+func (sr *spanReader) Ping(ctx context.Context) error {
+	return sr.next.Ping(ctx)
+}
+
+func (sr *spanReader) roleFilters(ctx context.Context) ([]model.SearchFilter, error) {
+	role, ok := RoleFromContext(ctx)
+	if !ok {
+		return nil, ErrAccessDenied
+	}
+	return sr.policy.Filters(role)
+}
+
+// withFilters returns a new slice, so the caller's request filters aren't modified.
+func withFilters(requestFilters []model.SearchFilter, filters []model.SearchFilter) []model.SearchFilter {
+	result := make([]model.SearchFilter, 0, len(requestFilters)+len(filters))
+	result = append(result, requestFilters...)
+	return append(result, filters...)
+}
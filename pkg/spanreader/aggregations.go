@@ -0,0 +1,67 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spanreader
+
+import (
+	"context"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader/inmemory"
+)
+
+// aggregatedSpansPageSize is the page size of the searches of the spans aggregated by AggregateInSpans
+const aggregatedSpansPageSize = 1000
+
+// AggregateInSpans runs a search with aggregations: the matching spans are searched with sr, most recent first,
+// and aggregated in memory. At most spansquery.MaxAggregatedSpans spans are aggregated.
+func AggregateInSpans(ctx context.Context, sr SpanReader, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	aggregator := inmemory.NewAggregator(*r.Aggregations)
+	count := 0
+	truncated := false
+	var token spansquery.ContinuationToken
+	for !truncated {
+		res, err := sr.Search(ctx, spansquery.SearchRequest{
+			Timeframe:     r.Timeframe,
+			Sort:          []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: false}},
+			SearchFilters: CopyFilters(r.SearchFilters),
+			Metadata:      &spansquery.Metadata{NextToken: token},
+			Limit:         aggregatedSpansPageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, span := range res.Spans {
+			if count == spansquery.MaxAggregatedSpans {
+				truncated = true
+				break
+			}
+			aggregator.Add(span)
+			count++
+		}
+		if len(res.Spans) == 0 || res.Metadata == nil || res.Metadata.NextToken == "" {
+			break
+		}
+		token = res.Metadata.NextToken
+	}
+
+	return &spansquery.SearchResponse{
+		Metadata:     &spansquery.Metadata{},
+		Spans:        []*internalspan.InternalSpan{},
+		Aggregations: &spansquery.AggregationsResult{Groups: aggregator.Result(), Truncated: truncated},
+	}, nil
+}
@@ -0,0 +1,140 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/teletrace/teletrace/circuitbreaker"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+var errBackend = errors.New("connection refused")
+
+type failingSpanReader struct {
+	spanreader.SpanReader
+	err   error
+	calls int
+}
+
+func (sr *failingSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	sr.calls++
+	if sr.err != nil {
+		return nil, sr.err
+	}
+	return sr.SpanReader.Search(ctx, r)
+}
+
+func newTestSpanReader(t *testing.T) (*spanReader, *failingSpanReader, *time.Time) {
+	srMock, err := mock.NewSpanReaderMock()
+	assert.NoError(t, err)
+	backend := &failingSpanReader{SpanReader: srMock}
+	sr := NewSpanReader(zap.NewNop(), backend, Config{FailureThreshold: 3, OpenDuration: 30 * time.Second}).(*spanReader)
+
+	now := time.Now()
+	sr.breaker.Now = func() time.Time { return now }
+	return sr, backend, &now
+}
+
+func TestOpensAfterConsecutiveFailures(t *testing.T) {
+	sr, backend, _ := newTestSpanReader(t)
+	backend.err = errBackend
+
+	for i := 0; i < 3; i++ {
+		_, err := sr.Search(context.Background(), spansquery.SearchRequest{})
+		assert.ErrorIs(t, err, errBackend)
+	}
+	_, err := sr.Search(context.Background(), spansquery.SearchRequest{})
+	assert.ErrorIs(t, err, ErrOpen)
+	assert.Equal(t, 3, backend.calls)
+}
+
+func TestSuccessResetsFailures(t *testing.T) {
+	sr, backend, _ := newTestSpanReader(t)
+
+	for i := 0; i < 5; i++ {
+		backend.err = errBackend
+		_, _ = sr.Search(context.Background(), spansquery.SearchRequest{})
+		_, _ = sr.Search(context.Background(), spansquery.SearchRequest{})
+		backend.err = nil
+		_, err := sr.Search(context.Background(), spansquery.SearchRequest{})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, circuitbreaker.StateClosed, sr.breaker.State())
+}
+
+func TestRecoversAfterOpenDuration(t *testing.T) {
+	sr, backend, now := newTestSpanReader(t)
+	backend.err = errBackend
+	for i := 0; i < 3; i++ {
+		_, _ = sr.Search(context.Background(), spansquery.SearchRequest{})
+	}
+
+	// a failed trial call opens the breaker again
+	*now = now.Add(30 * time.Second)
+	_, err := sr.Search(context.Background(), spansquery.SearchRequest{})
+	assert.ErrorIs(t, err, errBackend)
+	_, err = sr.Search(context.Background(), spansquery.SearchRequest{})
+	assert.ErrorIs(t, err, ErrOpen)
+
+	// a successful trial call closes it
+	*now = now.Add(30 * time.Second)
+	backend.err = nil
+	_, err = sr.Search(context.Background(), spansquery.SearchRequest{})
+	assert.NoError(t, err)
+	_, err = sr.Search(context.Background(), spansquery.SearchRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, 6, backend.calls)
+}
+
+func TestCanceledCallsAreNotFailures(t *testing.T) {
+	sr, backend, _ := newTestSpanReader(t)
+	backend.err = context.Canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for i := 0; i < 5; i++ {
+		_, err := sr.Search(ctx, spansquery.SearchRequest{})
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+	assert.Equal(t, circuitbreaker.StateClosed, sr.breaker.State())
+}
+
+func TestInvalidQueriesAreNotFailures(t *testing.T) {
+	sr, backend, _ := newTestSpanReader(t)
+	backend.err = fmt.Errorf("%w: malformed token", spanreader.ErrInvalidQuery)
+
+	for i := 0; i < 5; i++ {
+		_, err := sr.Search(context.Background(), spansquery.SearchRequest{})
+		assert.ErrorIs(t, err, spanreader.ErrInvalidQuery)
+	}
+	assert.Equal(t, circuitbreaker.StateClosed, sr.breaker.State())
+}
+
+func TestOpenIsBackendUnavailable(t *testing.T) {
+	assert.ErrorIs(t, ErrOpen, spanreader.ErrBackendUnavailable)
+	assert.Equal(t, "storage backend is unavailable, circuit breaker is open", ErrOpen.Error())
+}
@@ -0,0 +1,166 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/teletrace/teletrace/circuitbreaker"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"go.uber.org/zap"
+)
+
+// ErrOpen is returned without calling the backend while the circuit breaker is open.
+// It wraps spanreader.ErrBackendUnavailable.
+var ErrOpen = fmt.Errorf("%w, circuit breaker is open", spanreader.ErrBackendUnavailable)
+
+// Config defines when the circuit breaker opens and for how long.
+type Config = circuitbreaker.Config
+
+type spanReader struct {
+	next    spanreader.SpanReader
+	breaker *circuitbreaker.Breaker
+}
+
+// NewSpanReader wraps sr with a circuit breaker, so calls fast-fail with ErrOpen
+// while the storage backend keeps failing, instead of waiting on it.
+func NewSpanReader(logger *zap.Logger, sr spanreader.SpanReader, cfg Config) spanreader.SpanReader {
+	// a query failing as invalid or not found tells nothing about the backend
+	return &spanReader{next: sr, breaker: circuitbreaker.New(logger, cfg, spanreader.IsClientError)}
+}
+
+func (sr *spanReader) do(ctx context.Context, fn func() error) error {
+	err := sr.breaker.Do(ctx, fn)
+	if errors.Is(err, circuitbreaker.ErrOpen) {
+		return ErrOpen
+	}
+	return err
+}
+
+func (sr *spanReader) Initialize() error {
+	return sr.next.Initialize()
+}
+
+func (sr *spanReader) Search(ctx context.Context, r spansquery.SearchRequest) (res *spansquery.SearchResponse, err error) {
+	err = sr.do(ctx, func() error {
+		res, err = sr.next.Search(ctx, r)
+		return err
+	})
+	return res, err
+}
+
+func (sr *spanReader) GetAvailableTags(
+	ctx context.Context, r tagsquery.GetAvailableTagsRequest,
+) (res *tagsquery.GetAvailableTagsResponse, err error) {
+	err = sr.do(ctx, func() error {
+		res, err = sr.next.GetAvailableTags(ctx, r)
+		return err
+	})
+	return res, err
+}
+
+func (sr *spanReader) GetTagsValues(
+	ctx context.Context, r tagsquery.TagValuesRequest, tags []string,
+) (res map[string]*tagsquery.TagValuesResponse, err error) {
+	err = sr.do(ctx, func() error {
+		res, err = sr.next.GetTagsValues(ctx, r, tags)
+		return err
+	})
+	return res, err
+}
+
+func (sr *spanReader) GetTagsStatistics(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tag string,
+) (res *tagsquery.TagStatisticsResponse, err error) {
+	err = sr.do(ctx, func() error {
+		res, err = sr.next.GetTagsStatistics(ctx, r, tag)
+		return err
+	})
+	return res, err
+}
+
+func (sr *spanReader) GetTagsStatisticsBatch(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string,
+) (res map[string]*tagsquery.TagStatisticsResponse, err error) {
+	err = sr.do(ctx, func() error {
+		res, err = sr.next.GetTagsStatisticsBatch(ctx, r, tags)
+		return err
+	})
+	return res, err
+}
+
+func (sr *spanReader) SearchEvents(
+	ctx context.Context, r eventsquery.SearchRequest,
+) (res *eventsquery.SearchResponse, err error) {
+	err = sr.do(ctx, func() error {
+		res, err = sr.next.SearchEvents(ctx, r)
+		return err
+	})
+	return res, err
+}
+
+func (sr *spanReader) SearchTraces(
+	ctx context.Context, r spansquery.SearchTracesRequest,
+) (res *spansquery.SearchTracesResponse, err error) {
+	err = sr.do(ctx, func() error {
+		res, err = sr.next.SearchTraces(ctx, r)
+		return err
+	})
+	return res, err
+}
+
+func (sr *spanReader) Aggregate(
+	ctx context.Context, r spansquery.SearchRequest,
+) (res *spansquery.SearchResponse, err error) {
+	err = sr.do(ctx, func() error {
+		res, err = sr.next.Aggregate(ctx, r)
+		return err
+	})
+	return res, err
+}
+
+func (sr *spanReader) GetSystemId(
+	ctx context.Context, r metadata.GetSystemIdRequest,
+) (res *metadata.GetSystemIdResponse, err error) {
+	err = sr.do(ctx, func() error {
+		res, err = sr.next.GetSystemId(ctx, r)
+		return err
+	})
+	return res, err
+}
+
+func (sr *spanReader) SetSystemId(
+	ctx context.Context, r metadata.SetSystemIdRequest,
+) (res *metadata.SetSystemIdResponse, err error) {
+	err = sr.do(ctx, func() error {
+		res, err = sr.next.SetSystemId(ctx, r)
+		return err
+	})
+	return res, err
+}
+
+// Ping bypasses the circuit breaker, so readiness reflects the storage backend even while the circuit is open.
+func (sr *spanReader) Ping(ctx context.Context) error {
+	return sr.next.Ping(ctx)
+}
@@ -0,0 +1,55 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package spanreader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrQueryTimeout is returned by span reader calls whose storage query didn't complete within the query timeout.
+var ErrQueryTimeout = errors.New("query timed out")
+
+// ErrQueryCanceled is returned by span reader calls whose storage query was abandoned by the caller, e.g. when the
+// client of an API request disconnects. It wraps context.Canceled, so it isn't counted as a storage failure.
+var ErrQueryCanceled = fmt.Errorf("query canceled: %w", context.Canceled)
+
+// WithQueryTimeout returns a copy of ctx which is done after timeout, or ctx as is if timeout isn't positive.
+func WithQueryTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// QueryContextError returns err wrapped by ErrQueryTimeout if ctx exceeded its deadline, or by ErrQueryCanceled
+// if ctx was canceled, so the cause survives plugins formatting the errors of their clients. Otherwise err is
+// returned as is.
+func QueryContextError(ctx context.Context, err error) error {
+	if err == nil || errors.Is(err, ErrQueryTimeout) || errors.Is(err, ErrQueryCanceled) {
+		return err
+	}
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("%w: %v", ErrQueryTimeout, err)
+	case errors.Is(ctx.Err(), context.Canceled):
+		return ErrQueryCanceled
+	default:
+		return err
+	}
+}
@@ -0,0 +1,132 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cutover
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+)
+
+// SpanReader reads from the source backend of a storage migration until the cutover, and from its target
+// backend after it, while the collector writes spans to both of them.
+type SpanReader struct {
+	source  spanreader.SpanReader
+	target  spanreader.SpanReader
+	cutover atomic.Bool
+}
+
+// NewSpanReader returns a span reader migrating from source to target, reading from target if cutover is set.
+func NewSpanReader(source spanreader.SpanReader, target spanreader.SpanReader, cutover bool) *SpanReader {
+	sr := &SpanReader{source: source, target: target}
+	sr.cutover.Store(cutover)
+	return sr
+}
+
+// SetCutover switches the reads to the target backend if cutover is set, or back to the source backend otherwise.
+func (sr *SpanReader) SetCutover(cutover bool) {
+	sr.cutover.Store(cutover)
+}
+
+// CutOver returns whether the reads are switched to the target backend.
+func (sr *SpanReader) CutOver() bool {
+	return sr.cutover.Load()
+}
+
+func (sr *SpanReader) active() spanreader.SpanReader {
+	if sr.cutover.Load() {
+		return sr.target
+	}
+	return sr.source
+}
+
+// Initialize initializes both backends, so either can be switched to.
+func (sr *SpanReader) Initialize() error {
+	if err := sr.source.Initialize(); err != nil {
+		return fmt.Errorf("migration source: %w", err)
+	}
+	if err := sr.target.Initialize(); err != nil {
+		return fmt.Errorf("migration target: %w", err)
+	}
+	return nil
+}
+
+func (sr *SpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	return sr.active().Search(ctx, r)
+}
+
+func (sr *SpanReader) GetAvailableTags(ctx context.Context, r tagsquery.GetAvailableTagsRequest) (*tagsquery.GetAvailableTagsResponse, error) {
+	return sr.active().GetAvailableTags(ctx, r)
+}
+
+func (sr *SpanReader) GetTagsValues(
+	ctx context.Context, r tagsquery.TagValuesRequest, tags []string,
+) (map[string]*tagsquery.TagValuesResponse, error) {
+	return sr.active().GetTagsValues(ctx, r, tags)
+}
+
+func (sr *SpanReader) GetTagsStatistics(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tag string,
+) (*tagsquery.TagStatisticsResponse, error) {
+	return sr.active().GetTagsStatistics(ctx, r, tag)
+}
+
+func (sr *SpanReader) GetTagsStatisticsBatch(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string,
+) (map[string]*tagsquery.TagStatisticsResponse, error) {
+	return sr.active().GetTagsStatisticsBatch(ctx, r, tags)
+}
+
+func (sr *SpanReader) SearchEvents(ctx context.Context, r eventsquery.SearchRequest) (*eventsquery.SearchResponse, error) {
+	return sr.active().SearchEvents(ctx, r)
+}
+
+func (sr *SpanReader) SearchTraces(ctx context.Context, r spansquery.SearchTracesRequest) (*spansquery.SearchTracesResponse, error) {
+	return sr.active().SearchTraces(ctx, r)
+}
+
+func (sr *SpanReader) Aggregate(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	return sr.active().Aggregate(ctx, r)
+}
+
+func (sr *SpanReader) GetSystemId(ctx context.Context, r metadata.GetSystemIdRequest) (*metadata.GetSystemIdResponse, error) {
+	return sr.active().GetSystemId(ctx, r)
+}
+
+// SetSystemId sets the system id in both backends, so it's kept after the cutover.
+func (sr *SpanReader) SetSystemId(ctx context.Context, r metadata.SetSystemIdRequest) (*metadata.SetSystemIdResponse, error) {
+	if _, err := sr.target.SetSystemId(ctx, r); err != nil {
+		return nil, fmt.Errorf("migration target: %w", err)
+	}
+	return sr.source.SetSystemId(ctx, r)
+}
+
+// Ping checks both backends, as the migration requires both of them until it's completed.
+func (sr *SpanReader) Ping(ctx context.Context) error {
+	if err := sr.source.Ping(ctx); err != nil {
+		return fmt.Errorf("migration source: %w", err)
+	}
+	if err := sr.target.Ping(ctx); err != nil {
+		return fmt.Errorf("migration target: %w", err)
+	}
+	return nil
+}
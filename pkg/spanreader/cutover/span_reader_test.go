@@ -0,0 +1,97 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cutover
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// namedSpanReader returns a span named after its backend, and records the system id set in it.
+type namedSpanReader struct {
+	spanreader.SpanReader
+	name     string
+	systemId string
+	pingErr  error
+}
+
+func newNamedSpanReader(name string) *namedSpanReader {
+	sr, _ := mock.NewSpanReaderMock()
+	return &namedSpanReader{SpanReader: sr, name: name}
+}
+
+func (sr *namedSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	res, err := sr.SpanReader.Search(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	res.Spans[0].Span.Name = sr.name
+	return res, nil
+}
+
+func (sr *namedSpanReader) SetSystemId(ctx context.Context, r metadata.SetSystemIdRequest) (*metadata.SetSystemIdResponse, error) {
+	sr.systemId = r.Value
+	return &metadata.SetSystemIdResponse{}, nil
+}
+
+func (sr *namedSpanReader) Ping(ctx context.Context) error {
+	return sr.pingErr
+}
+
+func searchedBackend(t *testing.T, sr spanreader.SpanReader) string {
+	res, err := sr.Search(context.Background(), spansquery.SearchRequest{})
+	assert.NoError(t, err)
+	return res.Spans[0].Span.Name
+}
+
+func TestCutover(t *testing.T) {
+	sr := NewSpanReader(newNamedSpanReader("sqlite"), newNamedSpanReader("elasticsearch"), false)
+	assert.Equal(t, "sqlite", searchedBackend(t, sr))
+
+	sr.SetCutover(true)
+	assert.True(t, sr.CutOver())
+	assert.Equal(t, "elasticsearch", searchedBackend(t, sr))
+
+	sr.SetCutover(false)
+	assert.Equal(t, "sqlite", searchedBackend(t, sr), "the cutover can be rolled back")
+}
+
+func TestSetSystemIdInBothBackends(t *testing.T) {
+	source, target := newNamedSpanReader("sqlite"), newNamedSpanReader("elasticsearch")
+	sr := NewSpanReader(source, target, false)
+
+	_, err := sr.SetSystemId(context.Background(), metadata.SetSystemIdRequest{Value: "system"})
+	assert.NoError(t, err)
+	assert.Equal(t, "system", source.systemId)
+	assert.Equal(t, "system", target.systemId)
+}
+
+func TestPingBothBackends(t *testing.T) {
+	target := newNamedSpanReader("elasticsearch")
+	sr := NewSpanReader(newNamedSpanReader("sqlite"), target, false)
+	assert.NoError(t, sr.Ping(context.Background()))
+
+	target.pingErr = errors.New("unreachable")
+	assert.ErrorContains(t, sr.Ping(context.Background()), "migration target: unreachable")
+}
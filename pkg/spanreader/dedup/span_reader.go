@@ -0,0 +1,192 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package dedup
+
+import (
+	"context"
+	"fmt"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+)
+
+const (
+	// ModeLatest keeps the latest ingested copy of a duplicate span
+	ModeLatest = "latest"
+	// ModeUnion keeps the latest ingested copy of a duplicate span, with the attributes of all of its copies
+	ModeUnion = "union"
+)
+
+// ValidateMode validates a merge mode of duplicate spans.
+func ValidateMode(mode string) error {
+	if mode != ModeLatest && mode != ModeUnion {
+		return fmt.Errorf("invalid span deduplication mode %q, expected %s or %s", mode, ModeLatest, ModeUnion)
+	}
+	return nil
+}
+
+type spanReader struct {
+	next spanreader.SpanReader
+	mode string
+}
+
+// NewSpanReader wraps sr so that the copies of a span written more than once, identified by their trace id and
+// span id, are merged into a single span in search results, according to mode.
+func NewSpanReader(sr spanreader.SpanReader, mode string) (spanreader.SpanReader, error) {
+	if err := ValidateMode(mode); err != nil {
+		return nil, err
+	}
+	return &spanReader{next: sr, mode: mode}, nil
+}
+
+func (sr *spanReader) Initialize() error {
+	return sr.next.Initialize()
+}
+
+func (sr *spanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	res, err := sr.next.Search(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	res.Spans = sr.merge(res.Spans)
+	return res, nil
+}
+
+func (sr *spanReader) GetAvailableTags(ctx context.Context, r tagsquery.GetAvailableTagsRequest) (*tagsquery.GetAvailableTagsResponse, error) {
+	return sr.next.GetAvailableTags(ctx, r)
+}
+
+func (sr *spanReader) GetTagsValues(
+	ctx context.Context, r tagsquery.TagValuesRequest, tags []string,
+) (map[string]*tagsquery.TagValuesResponse, error) {
+	return sr.next.GetTagsValues(ctx, r, tags)
+}
+
+func (sr *spanReader) GetTagsStatistics(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tag string,
+) (*tagsquery.TagStatisticsResponse, error) {
+	return sr.next.GetTagsStatistics(ctx, r, tag)
+}
+
+func (sr *spanReader) GetTagsStatisticsBatch(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string,
+) (map[string]*tagsquery.TagStatisticsResponse, error) {
+	return sr.next.GetTagsStatisticsBatch(ctx, r, tags)
+}
+
+func (sr *spanReader) SearchEvents(ctx context.Context, r eventsquery.SearchRequest) (*eventsquery.SearchResponse, error) {
+	return sr.next.SearchEvents(ctx, r)
+}
+
+func (sr *spanReader) SearchTraces(ctx context.Context, r spansquery.SearchTracesRequest) (*spansquery.SearchTracesResponse, error) {
+	res, err := sr.next.SearchTraces(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	for _, trace := range res.Traces {
+		trace.Spans = sr.merge(trace.Spans)
+	}
+	return res, nil
+}
+
+// Aggregate aggregates the merged spans in memory, as the aggregations of the storage would count every copy.
+func (sr *spanReader) Aggregate(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	return spanreader.AggregateInSpans(ctx, sr, r)
+}
+
+func (sr *spanReader) GetSystemId(ctx context.Context, r metadata.GetSystemIdRequest) (*metadata.GetSystemIdResponse, error) {
+	return sr.next.GetSystemId(ctx, r)
+}
+
+func (sr *spanReader) SetSystemId(ctx context.Context, r metadata.SetSystemIdRequest) (*metadata.SetSystemIdResponse, error) {
+	return sr.next.SetSystemId(ctx, r)
+}
+
+func (sr *spanReader) Ping(ctx context.Context) error {
+	return sr.next.Ping(ctx)
+}
+
+type spanKey struct {
+	traceId string
+	spanId  string
+}
+
+// merge returns spans with a single span per trace id and span id, in the order of their first copy.
+// The spans returned by the wrapped reader aren't modified.
+func (sr *spanReader) merge(spans []*internalspan.InternalSpan) []*internalspan.InternalSpan {
+	merged := make([]*internalspan.InternalSpan, 0, len(spans))
+	indexes := make(map[spanKey]int, len(spans))
+	for _, span := range spans {
+		if span.Span == nil {
+			merged = append(merged, span)
+			continue
+		}
+		key := spanKey{traceId: span.Span.TraceId, spanId: span.Span.SpanId}
+		i, ok := indexes[key]
+		if !ok {
+			indexes[key] = len(merged)
+			merged = append(merged, span)
+			continue
+		}
+
+		latest, other := merged[i], span
+		if other.IngestionTimeUnixNano > latest.IngestionTimeUnixNano {
+			latest, other = other, latest
+		}
+		if sr.mode == ModeUnion {
+			latest = unionAttributes(latest, other)
+		}
+		merged[i] = latest
+	}
+	return merged
+}
+
+// unionAttributes returns a copy of latest with the attributes of other it's missing.
+func unionAttributes(latest *internalspan.InternalSpan, other *internalspan.InternalSpan) *internalspan.InternalSpan {
+	union := *latest
+
+	s := *latest.Span
+	s.Attributes = mergeAttributes(other.Span.Attributes, latest.Span.Attributes)
+	union.Span = &s
+
+	if latest.Resource != nil && other.Resource != nil {
+		resource := *latest.Resource
+		resource.Attributes = mergeAttributes(other.Resource.Attributes, latest.Resource.Attributes)
+		union.Resource = &resource
+	}
+	if latest.Scope != nil && other.Scope != nil {
+		scope := *latest.Scope
+		scope.Attributes = mergeAttributes(other.Scope.Attributes, latest.Scope.Attributes)
+		union.Scope = &scope
+	}
+	return &union
+}
+
+// mergeAttributes returns the union of both attributes, preferring the values of latest.
+func mergeAttributes(other internalspan.Attributes, latest internalspan.Attributes) internalspan.Attributes {
+	union := make(internalspan.Attributes, len(latest)+len(other))
+	for key, value := range other {
+		union[key] = value
+	}
+	for key, value := range latest {
+		union[key] = value
+	}
+	return union
+}
@@ -0,0 +1,94 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package dedup
+
+import (
+	"context"
+	"testing"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fixedSpanReader struct {
+	spanreader.SpanReader
+	spans []*internalspan.InternalSpan
+}
+
+func (sr *fixedSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	return &spansquery.SearchResponse{Spans: sr.spans}, nil
+}
+
+func newSpan(spanId string, ingestionTime uint64, attributes internalspan.Attributes) *internalspan.InternalSpan {
+	return &internalspan.InternalSpan{
+		Resource:              &internalspan.Resource{Attributes: internalspan.Attributes{"service.name": "checkout"}},
+		Span:                  &internalspan.Span{TraceId: "trace", SpanId: spanId, Attributes: attributes},
+		IngestionTimeUnixNano: ingestionTime,
+	}
+}
+
+func search(t *testing.T, mode string, spans ...*internalspan.InternalSpan) []*internalspan.InternalSpan {
+	srMock, err := mock.NewSpanReaderMock()
+	assert.NoError(t, err)
+	sr, err := NewSpanReader(&fixedSpanReader{SpanReader: srMock, spans: spans}, mode)
+	assert.NoError(t, err)
+
+	res, err := sr.Search(context.Background(), spansquery.SearchRequest{})
+	assert.NoError(t, err)
+	return res.Spans
+}
+
+func TestLatestKeepsLatestIngestedCopy(t *testing.T) {
+	first := newSpan("a", 100, internalspan.Attributes{"retry": "0", "http.method": "GET"})
+	other := newSpan("b", 150, nil)
+	retried := newSpan("a", 200, internalspan.Attributes{"retry": "1"})
+
+	spans := search(t, ModeLatest, first, other, retried)
+
+	assert.Equal(t, []*internalspan.InternalSpan{retried, other}, spans)
+}
+
+func TestUnionMergesAttributesOfAllCopies(t *testing.T) {
+	first := newSpan("a", 100, internalspan.Attributes{"retry": "0", "http.method": "GET"})
+	retried := newSpan("a", 200, internalspan.Attributes{"retry": "1"})
+	retried.Resource.Attributes["host.name"] = "node-1"
+
+	spans := search(t, ModeUnion, retried, first)
+
+	assert.Len(t, spans, 1)
+	assert.Equal(t, internalspan.Attributes{"retry": "1", "http.method": "GET"}, spans[0].Span.Attributes)
+	assert.Equal(t, internalspan.Attributes{"service.name": "checkout", "host.name": "node-1"}, spans[0].Resource.Attributes)
+	assert.Equal(t, uint64(200), spans[0].IngestionTimeUnixNano)
+	assert.Equal(t, internalspan.Attributes{"retry": "1"}, retried.Span.Attributes, "the read spans must not be modified")
+}
+
+func TestSpansOfDifferentTracesAreNotMerged(t *testing.T) {
+	first := newSpan("a", 100, nil)
+	other := newSpan("a", 200, nil)
+	other.Span.TraceId = "other trace"
+
+	assert.Len(t, search(t, ModeLatest, first, other), 2)
+}
+
+func TestValidateMode(t *testing.T) {
+	assert.NoError(t, ValidateMode(ModeLatest))
+	assert.NoError(t, ValidateMode(ModeUnion))
+	assert.Error(t, ValidateMode("first"))
+}
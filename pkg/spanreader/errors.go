@@ -0,0 +1,70 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spanreader
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned by span reader calls for data missing in the storage, e.g. an index that doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrInvalidQuery is returned by span reader calls whose request can't be run by the storage plugin, e.g. with a
+// malformed continuation token or a filter the backend rejects.
+var ErrInvalidQuery = errors.New("invalid query")
+
+// ErrBackendUnavailable is returned by span reader calls which couldn't reach the storage backend.
+var ErrBackendUnavailable = errors.New("storage backend is unavailable")
+
+// ErrorCode classifies the errors of span reader calls, so they are handled by their cause rather than their message.
+// Plugins wrap the errors of their clients with the matching error, e.g. fmt.Errorf("%w: %v", ErrInvalidQuery, err).
+type ErrorCode string
+
+const (
+	ERROR_CODE_NOT_FOUND           ErrorCode = "NOT_FOUND"
+	ERROR_CODE_INVALID_QUERY       ErrorCode = "INVALID_QUERY"
+	ERROR_CODE_BACKEND_UNAVAILABLE ErrorCode = "BACKEND_UNAVAILABLE"
+	ERROR_CODE_TIMEOUT             ErrorCode = "TIMEOUT"
+	ERROR_CODE_CANCELED            ErrorCode = "CANCELED"
+	ERROR_CODE_INTERNAL            ErrorCode = "INTERNAL"
+)
+
+// Code returns the code of an error of a span reader call, ERROR_CODE_INTERNAL for unclassified errors.
+func Code(err error) ErrorCode {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return ERROR_CODE_NOT_FOUND
+	case errors.Is(err, ErrInvalidQuery):
+		return ERROR_CODE_INVALID_QUERY
+	case errors.Is(err, ErrBackendUnavailable):
+		return ERROR_CODE_BACKEND_UNAVAILABLE
+	case errors.Is(err, ErrQueryTimeout), errors.Is(err, context.DeadlineExceeded):
+		return ERROR_CODE_TIMEOUT
+	case errors.Is(err, context.Canceled):
+		return ERROR_CODE_CANCELED
+	default:
+		return ERROR_CODE_INTERNAL
+	}
+}
+
+// IsClientError returns whether err is caused by the request rather than by the storage backend,
+// so it doesn't tell anything about the health of the backend.
+func IsClientError(err error) bool {
+	code := Code(err)
+	return code == ERROR_CODE_NOT_FOUND || code == ERROR_CODE_INVALID_QUERY
+}
@@ -0,0 +1,46 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spanreader_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCode(t *testing.T) {
+	timedOut, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	for err, code := range map[error]spanreader.ErrorCode{
+		fmt.Errorf("%w: no such index", spanreader.ErrNotFound):                spanreader.ERROR_CODE_NOT_FOUND,
+		fmt.Errorf("%w: malformed token", spanreader.ErrInvalidQuery):          spanreader.ERROR_CODE_INVALID_QUERY,
+		fmt.Errorf("%w: connection refused", spanreader.ErrBackendUnavailable): spanreader.ERROR_CODE_BACKEND_UNAVAILABLE,
+		spanreader.QueryContextError(timedOut, errors.New("search failed")):    spanreader.ERROR_CODE_TIMEOUT,
+		spanreader.ErrQueryCanceled:                                            spanreader.ERROR_CODE_CANCELED,
+		errors.New("disk is full"):                                             spanreader.ERROR_CODE_INTERNAL,
+	} {
+		assert.Equal(t, code, spanreader.Code(err), err.Error())
+	}
+	assert.True(t, spanreader.IsClientError(fmt.Errorf("%w: malformed token", spanreader.ErrInvalidQuery)))
+	assert.False(t, spanreader.IsClientError(spanreader.ErrBackendUnavailable))
+}
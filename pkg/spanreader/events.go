@@ -0,0 +1,126 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package spanreader
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// searchEventsMaxPages bounds the pages of spans scanned by SearchEventsInSpans, as spans may match the
+// filters through different events than the ones searched for
+const searchEventsMaxPages = 10
+
+// SearchEventsInSpans implements SpanReader.SearchEvents for storage backends which store events as part of
+// their spans: the spans having events of the name and attributes are searched with sr, most recent first,
+// and their events are matched in memory.
+func SearchEventsInSpans(ctx context.Context, sr SpanReader, r eventsquery.SearchRequest) (*eventsquery.SearchResponse, error) {
+	filters := append([]model.SearchFilter{}, r.SearchFilters...)
+	if r.Name != "" {
+		filters = append(filters, eventFilter("span.events.name", r.Name))
+	}
+	for key, value := range r.Attributes {
+		filters = append(filters, eventFilter(model.FilterKey("span.events.attributes."+key), value))
+	}
+
+	limit := r.EffectiveLimit()
+	events := make([]eventsquery.Event, 0)
+	var token spansquery.ContinuationToken
+	for page := 0; page < searchEventsMaxPages && len(events) < limit; page++ {
+		res, err := sr.Search(ctx, spansquery.SearchRequest{
+			Timeframe:     r.Timeframe,
+			Sort:          []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: false}},
+			SearchFilters: CopyFilters(filters),
+			Metadata:      &spansquery.Metadata{NextToken: token},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, span := range res.Spans {
+			events = append(events, matchingEvents(span, r)...)
+		}
+		if len(res.Spans) == 0 || res.Metadata == nil || res.Metadata.NextToken == "" {
+			break
+		}
+		token = res.Metadata.NextToken
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].TimeUnixNano > events[j].TimeUnixNano })
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return &eventsquery.SearchResponse{Events: events}, nil
+}
+
+func eventFilter(key model.FilterKey, value any) model.SearchFilter {
+	return model.SearchFilter{KeyValueFilter: &model.KeyValueFilter{
+		Key: key, Operator: spansquery.OPERATOR_EQUALS, Value: value,
+	}}
+}
+
+// matchingEvents returns the events of span matching the request. The span matched the request filters,
+// but possibly through several events, each having some of the searched attributes.
+func matchingEvents(span *internalspan.InternalSpan, r eventsquery.SearchRequest) []eventsquery.Event {
+	var events []eventsquery.Event
+	if span.Span == nil {
+		return events
+	}
+	for _, e := range span.Span.Events {
+		if e == nil || (r.Name != "" && e.Name != r.Name) || !inTimeframe(e.TimeUnixNano, r.Timeframe) {
+			continue
+		}
+		if !hasAttributes(e.Attributes, r.Attributes) {
+			continue
+		}
+		event := eventsquery.Event{
+			TraceId:      span.Span.TraceId,
+			SpanId:       span.Span.SpanId,
+			SpanName:     span.Span.Name,
+			Name:         e.Name,
+			TimeUnixNano: e.TimeUnixNano,
+			Attributes:   e.Attributes,
+		}
+		if span.Resource != nil {
+			event.ServiceName, _ = span.Resource.Attributes["service.name"].(string)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func inTimeframe(timeUnixNano uint64, tf model.Timeframe) bool {
+	return timeUnixNano >= tf.StartTime && (tf.EndTime == 0 || timeUnixNano <= tf.EndTime)
+}
+
+func hasAttributes(attributes internalspan.Attributes, expected map[string]any) bool {
+	for key, value := range expected {
+		actual, ok := attributes[key]
+		if !ok {
+			return false
+		}
+		// numbers may be decoded as integers or floats, depending on the storage backend
+		if fmt.Sprint(actual) != fmt.Sprint(value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package spanreader_test
+
+import (
+	"context"
+	"testing"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type eventsSpanReader struct {
+	spanreader.SpanReader
+	filters []model.SearchFilter
+}
+
+func (sr *eventsSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	sr.filters = r.SearchFilters
+	span := func(spanId string, events ...*internalspan.SpanEvent) *internalspan.InternalSpan {
+		return &internalspan.InternalSpan{
+			Resource: &internalspan.Resource{Attributes: internalspan.Attributes{"service.name": "cart"}},
+			Span:     &internalspan.Span{TraceId: "t", SpanId: spanId, Name: "GET /cart", Events: events},
+		}
+	}
+	return &spansquery.SearchResponse{Spans: []*internalspan.InternalSpan{
+		span("s1",
+			&internalspan.SpanEvent{Name: "exception", TimeUnixNano: 10, Attributes: internalspan.Attributes{"exception.type": "TimeoutError"}},
+			&internalspan.SpanEvent{Name: "retry", TimeUnixNano: 20, Attributes: internalspan.Attributes{"retries": float64(3)}},
+		),
+		span("s2",
+			&internalspan.SpanEvent{Name: "exception", TimeUnixNano: 30, Attributes: internalspan.Attributes{"exception.type": "TimeoutError"}},
+			&internalspan.SpanEvent{Name: "exception", TimeUnixNano: 5000, Attributes: internalspan.Attributes{"exception.type": "TimeoutError"}},
+		),
+	}}, nil
+}
+
+func TestSearchEventsInSpans(t *testing.T) {
+	srMock, _ := mock.NewSpanReaderMock()
+	sr := &eventsSpanReader{SpanReader: srMock}
+
+	res, err := spanreader.SearchEventsInSpans(context.Background(), sr, eventsquery.SearchRequest{
+		Timeframe:  model.Timeframe{EndTime: 1000},
+		Name:       "exception",
+		Attributes: map[string]any{"exception.type": "TimeoutError"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, sr.filters, 2)
+	// events of other names, or outside the timeframe, don't match even if their span does
+	assert.Len(t, res.Events, 2)
+	assert.Equal(t, "s2", res.Events[0].SpanId)
+	assert.Equal(t, uint64(30), res.Events[0].TimeUnixNano)
+	assert.Equal(t, "cart", res.Events[0].ServiceName)
+
+	res, err = spanreader.SearchEventsInSpans(context.Background(), sr, eventsquery.SearchRequest{
+		Timeframe:  model.Timeframe{EndTime: 1000},
+		Attributes: map[string]any{"retries": float64(3)},
+		Limit:      1,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, res.Events, 1)
+	assert.Equal(t, "retry", res.Events[0].Name)
+}
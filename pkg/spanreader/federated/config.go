@@ -0,0 +1,86 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package federated
+
+import (
+	"fmt"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// BackendConfig configures a storage backend of the federation.
+type BackendConfig struct {
+	// Name identifies the backend in errors and continuation tokens
+	Name string `mapstructure:"name"`
+	// Plugin is the spans storage plugin of the backend, as in SPANS_STORAGE_PLUGIN
+	Plugin string `mapstructure:"plugin"`
+	// Settings override the configuration options of the plugin for the backend, e.g. es_endpoint
+	Settings map[string]any `mapstructure:"settings"`
+}
+
+// Config configures the storage backends queried by the federating span reader.
+type Config struct {
+	Backends []BackendConfig `mapstructure:"backends"`
+}
+
+// LoadConfig reads the federation config from a yaml or json file, e.g.
+//
+//	backends:
+//	  - name: live
+//	    plugin: elasticsearch
+//	    settings:
+//	      es_endpoint: http://es-live:9200
+//	  - name: archive
+//	    plugin: sqlite
+//	    settings:
+//	      sqlite_path: /data/archive.db
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return cfg, fmt.Errorf("error loading federation config file %s: %w", path, err)
+	}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		ErrorUnused: true,
+		Result:      &cfg,
+	})
+	if err != nil {
+		return cfg, err
+	}
+	if err := decoder.Decode(map[string]any{"backends": v.Get("backends")}); err != nil {
+		return cfg, fmt.Errorf("error unmarshaling federation config: %w", err)
+	}
+	return cfg, cfg.validate()
+}
+
+func (cfg Config) validate() error {
+	if len(cfg.Backends) < 2 {
+		return fmt.Errorf("federation config must define at least two backends")
+	}
+	names := map[string]bool{}
+	for i, backend := range cfg.Backends {
+		if backend.Name == "" || backend.Plugin == "" {
+			return fmt.Errorf("backend %d of the federation config has no name or plugin", i)
+		}
+		if names[backend.Name] {
+			return fmt.Errorf("federation config defines backend %s more than once", backend.Name)
+		}
+		names[backend.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package federated
+
+import (
+	"encoding/json"
+	"strings"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// defaultSort is the order of searches without a sort, the most recent spans first.
+var defaultSort = []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: false}}
+
+// less returns whether a is ordered before b by sorts. Spans missing a sort field are ordered last.
+func less(a *internalspan.InternalSpan, b *internalspan.InternalSpan, sorts []spansquery.Sort) bool {
+	for _, s := range sorts {
+		field := strings.TrimSuffix(string(s.Field), ".keyword")
+		c := compare(sortValue(a, field), sortValue(b, field))
+		if c == 0 {
+			continue
+		}
+		if s.Ascending {
+			return c < 0
+		}
+		return c > 0
+	}
+	return false
+}
+
+// compare compares numbers numerically and any other values by their string form, nil values being the greatest.
+func compare(a any, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	an, aok := number(a)
+	bn, bok := number(b)
+	if aok && bok {
+		switch {
+		case an < bn:
+			return -1
+		case an > bn:
+			return 1
+		default:
+			return 0
+		}
+	}
+	return strings.Compare(toString(a), toString(b))
+}
+
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func toString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// sortValue returns the value of field in span, or nil if the span doesn't have it.
+func sortValue(span *internalspan.InternalSpan, field string) any {
+	if value, ok := attributeValue(span, field); ok {
+		return value
+	}
+	s := span.Span
+	switch field {
+	case "span.startTimeUnixNano":
+		if s != nil {
+			return s.StartTimeUnixNano
+		}
+	case "span.endTimeUnixNano":
+		if s != nil {
+			return s.EndTimeUnixNano
+		}
+	case "span.name":
+		if s != nil {
+			return s.Name
+		}
+	case "span.kind":
+		if s != nil {
+			return s.Kind
+		}
+	case "span.traceId":
+		if s != nil {
+			return s.TraceId
+		}
+	case "span.spanId":
+		if s != nil {
+			return s.SpanId
+		}
+	case "span.status.code":
+		if s != nil && s.Status != nil {
+			return s.Status.Code
+		}
+	case "externalFields.durationNano":
+		if span.ExternalFields != nil {
+			return span.ExternalFields.DurationNano
+		}
+	case "externalFields.childCount":
+		if span.ExternalFields != nil {
+			return span.ExternalFields.ChildCount
+		}
+	case "ingestionTimeUnixNano":
+		return span.IngestionTimeUnixNano
+	}
+	return nil
+}
+
+func attributeValue(span *internalspan.InternalSpan, field string) (any, bool) {
+	var attributes internalspan.Attributes
+	switch {
+	case strings.HasPrefix(field, "span.attributes.") && span.Span != nil:
+		attributes, field = span.Span.Attributes, strings.TrimPrefix(field, "span.attributes.")
+	case strings.HasPrefix(field, "resource.attributes.") && span.Resource != nil:
+		attributes, field = span.Resource.Attributes, strings.TrimPrefix(field, "resource.attributes.")
+	case strings.HasPrefix(field, "scope.attributes.") && span.Scope != nil:
+		attributes, field = span.Scope.Attributes, strings.TrimPrefix(field, "scope.attributes.")
+	default:
+		return nil, false
+	}
+	value, ok := attributes[field]
+	return value, ok
+}
@@ -0,0 +1,601 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package federated
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+)
+
+// Backend is a span reader of the federation.
+type Backend struct {
+	Name   string
+	Reader spanreader.SpanReader
+}
+
+type spanReader struct {
+	backends []Backend
+}
+
+// NewSpanReader returns a span reader fanning out to the span readers of backends, merging their results
+// as if they were a single storage. The system id is read from and written to the first backend.
+func NewSpanReader(backends []Backend) (spanreader.SpanReader, error) {
+	if len(backends) == 0 {
+		return nil, errors.New("a federated span reader requires at least one backend")
+	}
+	return &spanReader{backends: backends}, nil
+}
+
+// fanOut calls call for each backend concurrently, returning the results in the order of the backends,
+// or the error of the first failing backend.
+func fanOut[T any](ctx context.Context, backends []Backend, call func(ctx context.Context, b Backend) (T, error)) ([]T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]T, len(backends))
+	errs := make([]error, len(backends))
+	var wg sync.WaitGroup
+	for i, b := range backends {
+		wg.Add(1)
+		go func(i int, b Backend) {
+			defer wg.Done()
+			results[i], errs[i] = call(ctx, b)
+			if errs[i] != nil {
+				// the results of the other backends are useless without the failed one
+				cancel()
+			}
+		}(i, b)
+	}
+	wg.Wait()
+
+	var err error
+	for i, e := range errs {
+		if e == nil {
+			continue
+		}
+		// prefer the cause over the cancellations of the other backends it caused
+		if err == nil || (errors.Is(err, context.Canceled) && !errors.Is(e, context.Canceled)) {
+			err = fmt.Errorf("backend %s: %w", backends[i].Name, e)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (sr *spanReader) Initialize() error {
+	for _, b := range sr.backends {
+		if err := b.Reader.Initialize(); err != nil {
+			return fmt.Errorf("backend %s: %w", b.Name, err)
+		}
+	}
+	return nil
+}
+
+// cursor is the position of a paginated search in the results of a backend: the token of its current page,
+// and the number of spans of that page already returned.
+type cursor struct {
+	Token spansquery.ContinuationToken `json:"token,omitempty"`
+	Skip  int                          `json:"skip,omitempty"`
+	Done  bool                         `json:"done,omitempty"`
+}
+
+func decodeCursors(token spansquery.ContinuationToken) (map[string]cursor, error) {
+	cursors := map[string]cursor{}
+	if token == "" {
+		return cursors, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(string(token))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid continuation token: %v", spanreader.ErrInvalidQuery, err)
+	}
+	if err := json.Unmarshal(data, &cursors); err != nil {
+		return nil, fmt.Errorf("%w: invalid continuation token: %v", spanreader.ErrInvalidQuery, err)
+	}
+	return cursors, nil
+}
+
+func encodeCursors(cursors map[string]cursor) spansquery.ContinuationToken {
+	data, _ := json.Marshal(cursors)
+	return spansquery.ContinuationToken(base64.RawURLEncoding.EncodeToString(data))
+}
+
+// page is the spans of a backend's page not returned yet.
+type page struct {
+	spans     []*internalspan.InternalSpan
+	nextToken spansquery.ContinuationToken
+	consumed  int
+	partial   *spansquery.PartialResult
+}
+
+func (p *page) head() *internalspan.InternalSpan {
+	if p == nil || p.consumed == len(p.spans) {
+		return nil
+	}
+	return p.spans[p.consumed]
+}
+
+// Search merges the pages of the backends in the order of the request. A merged page ends once a backend with more
+// results runs out of fetched spans, as its next spans may precede the spans of the other backends, so the
+// continuation token keeps, for each backend, how many spans of its current page were already returned.
+func (sr *spanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	if r.Sample != nil {
+		return sr.sample(ctx, r)
+	}
+
+	var token spansquery.ContinuationToken
+	if r.Metadata != nil {
+		token = r.Metadata.NextToken
+	}
+	cursors, err := decodeCursors(token)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := fanOut(ctx, sr.backends, func(ctx context.Context, b Backend) (*page, error) {
+		c := cursors[b.Name]
+		if c.Done {
+			return nil, nil
+		}
+		req := r
+		req.Metadata = &spansquery.Metadata{NextToken: c.Token}
+		res, err := b.Reader.Search(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		p := &page{spans: res.Spans, partial: res.Partial}
+		if res.Metadata != nil {
+			p.nextToken = res.Metadata.NextToken
+		}
+		if c.Skip > len(p.spans) {
+			c.Skip = len(p.spans)
+		}
+		p.consumed = c.Skip
+		return p, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sorts := r.Sort
+	if len(sorts) == 0 {
+		sorts = defaultSort
+	}
+	var spans []*internalspan.InternalSpan
+	for r.Limit <= 0 || len(spans) < r.Limit {
+		next := -1
+		for i, p := range pages {
+			head := p.head()
+			if head == nil {
+				if p != nil && p.nextToken != "" {
+					// the next page of the backend may have spans preceding the heads of the others
+					next = -1
+					break
+				}
+				continue
+			}
+			if next == -1 || less(head, pages[next].head(), sorts) {
+				next = i
+			}
+		}
+		if next == -1 {
+			break
+		}
+		spans = append(spans, pages[next].head())
+		pages[next].consumed++
+	}
+
+	next := map[string]cursor{}
+	done := true
+	for i, b := range sr.backends {
+		p := pages[i]
+		switch {
+		case p == nil || (p.head() == nil && p.nextToken == ""):
+			next[b.Name] = cursor{Done: true}
+			continue
+		case p.head() == nil:
+			next[b.Name] = cursor{Token: p.nextToken}
+		default:
+			next[b.Name] = cursor{Token: cursors[b.Name].Token, Skip: p.consumed}
+		}
+		done = false
+	}
+	res := &spansquery.SearchResponse{Metadata: &spansquery.Metadata{}, Spans: spans}
+	if !done {
+		res.Metadata.NextToken = encodeCursors(next)
+	}
+	for _, p := range pages {
+		if p != nil {
+			res.MergePartial(p.partial)
+		}
+	}
+	return res, nil
+}
+
+// sample draws the sample from the samples of the backends, in proportion to their estimated totals.
+func (sr *spanReader) sample(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	responses, err := fanOut(ctx, sr.backends, func(ctx context.Context, b Backend) (*spansquery.SearchResponse, error) {
+		return b.Reader.Search(ctx, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var total uint64
+	var spans []*internalspan.InternalSpan
+	for _, res := range responses {
+		spans = append(spans, res.Spans...)
+		if res.Sample != nil {
+			total += res.Sample.EstimatedTotal
+		}
+	}
+	if len(spans) > r.Sample.Size {
+		seed := time.Now().UnixNano()
+		if r.Sample.Seed != nil {
+			seed = *r.Sample.Seed
+		}
+		random := rand.New(rand.NewSource(seed))
+		random.Shuffle(len(spans), func(i, j int) { spans[i], spans[j] = spans[j], spans[i] })
+		spans = spans[:r.Sample.Size]
+	}
+	res := &spansquery.SearchResponse{
+		Metadata: &spansquery.Metadata{},
+		Spans:    spans,
+		Sample:   &spansquery.SampleMetadata{EstimatedTotal: total},
+	}
+	for _, backendRes := range responses {
+		res.MergePartial(backendRes.Partial)
+	}
+	return res, nil
+}
+
+// GetAvailableTags returns the union of the tags of the backends, with the type of the first backend having a tag.
+func (sr *spanReader) GetAvailableTags(ctx context.Context, r tagsquery.GetAvailableTagsRequest) (*tagsquery.GetAvailableTagsResponse, error) {
+	responses, err := fanOut(ctx, sr.backends, func(ctx context.Context, b Backend) (*tagsquery.GetAvailableTagsResponse, error) {
+		return b.Reader.GetAvailableTags(ctx, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &tagsquery.GetAvailableTagsResponse{Tags: []tagsquery.TagInfo{}}
+	seen := map[string]bool{}
+	for _, backendRes := range responses {
+		for _, tag := range backendRes.Tags {
+			if seen[tag.Name] {
+				continue
+			}
+			seen[tag.Name] = true
+			res.Tags = append(res.Tags, tag)
+		}
+	}
+	if r.Limit > 0 && len(res.Tags) > r.Limit {
+		res.Tags = res.Tags[:r.Limit]
+	}
+	return res, nil
+}
+
+// GetTagsValues sums the counts of the values of each tag across the backends, in the sort order of the request.
+func (sr *spanReader) GetTagsValues(
+	ctx context.Context, r tagsquery.TagValuesRequest, tags []string,
+) (map[string]*tagsquery.TagValuesResponse, error) {
+	responses, err := fanOut(ctx, sr.backends, func(ctx context.Context, b Backend) (map[string]*tagsquery.TagValuesResponse, error) {
+		return b.Reader.GetTagsValues(ctx, r, tags)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[string]*tagsquery.TagValuesResponse{}
+	for _, tag := range tags {
+		var values []tagsquery.TagValueInfo
+		indexes := map[string]int{}
+		for _, backendRes := range responses {
+			tagRes, ok := backendRes[tag]
+			if !ok || tagRes == nil {
+				continue
+			}
+			for _, value := range tagRes.Values {
+				key := toString(value.Value)
+				if i, ok := indexes[key]; ok {
+					values[i].Count += value.Count
+					if value.LastSeenUnixNano > values[i].LastSeenUnixNano {
+						values[i].LastSeenUnixNano = value.LastSeenUnixNano
+					}
+					continue
+				}
+				indexes[key] = len(values)
+				values = append(values, value)
+			}
+		}
+		if values == nil {
+			continue
+		}
+		tagsquery.SortValues(values, r)
+		if r.Limit > 0 && len(values) > r.Limit {
+			values = values[:r.Limit]
+		}
+		res[tag] = &tagsquery.TagValuesResponse{Values: values}
+	}
+	return res, nil
+}
+
+// GetTagsStatistics combines the statistics of the backends: the minimum of their minimums and the maximum of
+// their maximums. The average is the mean of the backends' averages weighted by their counts. Percentiles can't
+// be combined exactly without the spans, so the p99 is the highest of the backends' p99s, reported as an upper
+// bound when more than one backend has values.
+func (sr *spanReader) GetTagsStatistics(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tag string,
+) (*tagsquery.TagStatisticsResponse, error) {
+	responses, err := fanOut(ctx, sr.backends, func(ctx context.Context, b Backend) (*tagsquery.TagStatisticsResponse, error) {
+		return b.Reader.GetTagsStatistics(ctx, r, tag)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mergeStatistics(responses), nil
+}
+
+// GetTagsStatisticsBatch combines the statistics of each tag of the backends as GetTagsStatistics does.
+func (sr *spanReader) GetTagsStatisticsBatch(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string,
+) (map[string]*tagsquery.TagStatisticsResponse, error) {
+	responses, err := fanOut(ctx, sr.backends, func(ctx context.Context, b Backend) (map[string]*tagsquery.TagStatisticsResponse, error) {
+		return b.Reader.GetTagsStatisticsBatch(ctx, r, tags)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]*tagsquery.TagStatisticsResponse, len(tags))
+	for _, tag := range tags {
+		tagResponses := make([]*tagsquery.TagStatisticsResponse, 0, len(responses))
+		for _, backendRes := range responses {
+			tagResponses = append(tagResponses, backendRes[tag])
+		}
+		res[tag] = mergeStatistics(tagResponses)
+	}
+	return res, nil
+}
+
+// mergeStatistics combines the statistics of a tag of the backends, skipping backends with no statistics.
+// Averages are weighted by the counts of the backends, or equally weighted if a backend doesn't report its count.
+func mergeStatistics(responses []*tagsquery.TagStatisticsResponse) *tagsquery.TagStatisticsResponse {
+	res := &tagsquery.TagStatisticsResponse{Statistics: map[tagsquery.TagStatistic]float64{}}
+	var avgSum, avgWeights float64
+	var avgs []float64
+	percentiles := 0
+	counted := true
+	for _, backendRes := range responses {
+		if backendRes == nil || len(backendRes.Statistics) == 0 {
+			continue
+		}
+		res.Count += backendRes.Count
+		counted = counted && backendRes.Count > 0
+		for statistic, value := range backendRes.Statistics {
+			current, ok := res.Statistics[statistic]
+			switch {
+			case statistic == tagsquery.AVG:
+				avgs = append(avgs, value)
+				avgSum += value * float64(backendRes.Count)
+				avgWeights += float64(backendRes.Count)
+				continue
+			case statistic == tagsquery.P99:
+				percentiles++
+			}
+			switch {
+			case !ok:
+				res.Statistics[statistic] = value
+			case statistic == tagsquery.MIN:
+				if value < current {
+					res.Statistics[statistic] = value
+				}
+			default:
+				if value > current {
+					res.Statistics[statistic] = value
+				}
+			}
+		}
+	}
+	if !counted {
+		res.Count = 0
+	}
+	switch {
+	case len(avgs) == 0:
+	case counted:
+		res.Statistics[tagsquery.AVG] = avgSum / avgWeights
+	default:
+		var sum float64
+		for _, avg := range avgs {
+			sum += avg
+		}
+		res.Statistics[tagsquery.AVG] = sum / float64(len(avgs))
+	}
+	if percentiles > 1 {
+		res.UpperBounds = []tagsquery.TagStatistic{tagsquery.P99}
+	}
+	return res
+}
+
+// SearchEvents merges the events of the backends, most recent first.
+func (sr *spanReader) SearchEvents(ctx context.Context, r eventsquery.SearchRequest) (*eventsquery.SearchResponse, error) {
+	responses, err := fanOut(ctx, sr.backends, func(ctx context.Context, b Backend) (*eventsquery.SearchResponse, error) {
+		return b.Reader.SearchEvents(ctx, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &eventsquery.SearchResponse{Events: []eventsquery.Event{}}
+	for _, backendRes := range responses {
+		res.Events = append(res.Events, backendRes.Events...)
+	}
+	sort.SliceStable(res.Events, func(i, j int) bool { return res.Events[i].TimeUnixNano > res.Events[j].TimeUnixNano })
+	if limit := r.EffectiveLimit(); len(res.Events) > limit {
+		res.Events = res.Events[:limit]
+	}
+	return res, nil
+}
+
+// tracesPage is the traces of a backend's page not returned yet.
+type tracesPage struct {
+	traces    []*spansquery.TraceResult
+	nextToken spansquery.ContinuationToken
+	consumed  int
+	truncated bool
+}
+
+func (p *tracesPage) head() *spansquery.TraceResult {
+	if p == nil || p.consumed == len(p.traces) {
+		return nil
+	}
+	return p.traces[p.consumed]
+}
+
+// SearchTraces merges the traces of the backends by their most recent matching span, paginated as Search. A trace
+// found in several backends of the same page is returned once, with the spans of all of them.
+func (sr *spanReader) SearchTraces(ctx context.Context, r spansquery.SearchTracesRequest) (*spansquery.SearchTracesResponse, error) {
+	var token spansquery.ContinuationToken
+	if r.Metadata != nil {
+		token = r.Metadata.NextToken
+	}
+	cursors, err := decodeCursors(token)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := fanOut(ctx, sr.backends, func(ctx context.Context, b Backend) (*tracesPage, error) {
+		c := cursors[b.Name]
+		if c.Done {
+			return nil, nil
+		}
+		req := r
+		req.Metadata = &spansquery.Metadata{NextToken: c.Token}
+		res, err := b.Reader.SearchTraces(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		p := &tracesPage{traces: res.Traces, truncated: res.SpansTruncated}
+		if res.Metadata != nil {
+			p.nextToken = res.Metadata.NextToken
+		}
+		if c.Skip > len(p.traces) {
+			c.Skip = len(p.traces)
+		}
+		p.consumed = c.Skip
+		return p, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &spansquery.SearchTracesResponse{Metadata: &spansquery.Metadata{}, Traces: []*spansquery.TraceResult{}}
+	merged := make(map[string]*spansquery.TraceResult)
+	for len(res.Traces) < r.EffectiveLimit() {
+		next := -1
+		for i, p := range pages {
+			head := p.head()
+			if head == nil {
+				if p != nil && p.nextToken != "" {
+					// the next page of the backend may have traces preceding the heads of the others
+					next = -1
+					break
+				}
+				continue
+			}
+			if next == -1 || head.LatestMatchTimeUnixNano > pages[next].head().LatestMatchTimeUnixNano {
+				next = i
+			}
+		}
+		if next == -1 {
+			break
+		}
+		trace := pages[next].head()
+		pages[next].consumed++
+		if existing, ok := merged[trace.TraceId]; ok {
+			existing.Spans = append(existing.Spans, trace.Spans...)
+			sort.SliceStable(existing.Spans, func(i, j int) bool {
+				return existing.Spans[i].Span.StartTimeUnixNano < existing.Spans[j].Span.StartTimeUnixNano
+			})
+			continue
+		}
+		merged[trace.TraceId] = trace
+		res.Traces = append(res.Traces, trace)
+	}
+
+	next := map[string]cursor{}
+	done := true
+	for i, b := range sr.backends {
+		p := pages[i]
+		if p != nil && p.truncated {
+			res.SpansTruncated = true
+		}
+		switch {
+		case p == nil || (p.head() == nil && p.nextToken == ""):
+			next[b.Name] = cursor{Done: true}
+			continue
+		case p.head() == nil:
+			next[b.Name] = cursor{Token: p.nextToken}
+		default:
+			next[b.Name] = cursor{Token: cursors[b.Name].Token, Skip: p.consumed}
+		}
+		done = false
+	}
+	if !done {
+		res.Metadata.NextToken = encodeCursors(next)
+	}
+	return res, nil
+}
+
+// Aggregate aggregates in the single backend, or the merged spans of several backends in memory, as neither the
+// percentiles nor the top groups of several backends can be combined.
+func (sr *spanReader) Aggregate(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	if len(sr.backends) == 1 {
+		return sr.backends[0].Reader.Aggregate(ctx, r)
+	}
+	return spanreader.AggregateInSpans(ctx, sr, r)
+}
+
+func (sr *spanReader) GetSystemId(ctx context.Context, r metadata.GetSystemIdRequest) (*metadata.GetSystemIdResponse, error) {
+	return sr.backends[0].Reader.GetSystemId(ctx, r)
+}
+
+func (sr *spanReader) SetSystemId(ctx context.Context, r metadata.SetSystemIdRequest) (*metadata.SetSystemIdResponse, error) {
+	return sr.backends[0].Reader.SetSystemId(ctx, r)
+}
+
+// Ping checks all of the backends, as searches fail if any of them is unreachable.
+func (sr *spanReader) Ping(ctx context.Context) error {
+	_, err := fanOut(ctx, sr.backends, func(ctx context.Context, b Backend) (struct{}, error) {
+		return struct{}{}, b.Reader.Ping(ctx)
+	})
+	return err
+}
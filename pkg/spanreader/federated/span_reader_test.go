@@ -0,0 +1,247 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package federated
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const pageSize = 2
+
+// pagedSpanReader serves the spans it holds, most recent first, in pages of pageSize spans.
+type pagedSpanReader struct {
+	spanreader.SpanReader
+	spans []*internalspan.InternalSpan
+	err   error
+	// dropped are the reasons of the spans reported dropped from each page
+	dropped []string
+}
+
+func newPagedSpanReader(startTimes ...uint64) *pagedSpanReader {
+	sr, _ := mock.NewSpanReaderMock()
+	var spans []*internalspan.InternalSpan
+	for _, startTime := range startTimes {
+		spans = append(spans, &internalspan.InternalSpan{Span: &internalspan.Span{
+			SpanId:            strconv.FormatUint(startTime, 10),
+			StartTimeUnixNano: startTime,
+		}})
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Span.StartTimeUnixNano > spans[j].Span.StartTimeUnixNano })
+	return &pagedSpanReader{SpanReader: sr, spans: spans}
+}
+
+func (sr *pagedSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	if sr.err != nil {
+		return nil, sr.err
+	}
+	offset, _ := strconv.Atoi(string(r.Metadata.NextToken))
+	end := offset + pageSize
+	metadata := &spansquery.Metadata{NextToken: spansquery.ContinuationToken(strconv.Itoa(end))}
+	if end >= len(sr.spans) {
+		end = len(sr.spans)
+		metadata.NextToken = ""
+	}
+	res := &spansquery.SearchResponse{Metadata: metadata, Spans: sr.spans[offset:end]}
+	for _, reason := range sr.dropped {
+		res.AddDroppedSpan(reason)
+	}
+	return res, nil
+}
+
+func (sr *pagedSpanReader) GetTagsStatistics(ctx context.Context, r tagsquery.TagStatisticsRequest, tag string) (*tagsquery.TagStatisticsResponse, error) {
+	min, max := float64(sr.spans[len(sr.spans)-1].Span.StartTimeUnixNano), float64(sr.spans[0].Span.StartTimeUnixNano)
+	return &tagsquery.TagStatisticsResponse{Statistics: map[tagsquery.TagStatistic]float64{
+		tagsquery.MIN: min, tagsquery.MAX: max, tagsquery.AVG: (min + max) / 2, tagsquery.P99: max,
+	}, Count: int64(len(sr.spans))}, nil
+}
+
+func (sr *pagedSpanReader) GetTagsStatisticsBatch(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string,
+) (map[string]*tagsquery.TagStatisticsResponse, error) {
+	res := make(map[string]*tagsquery.TagStatisticsResponse, len(tags))
+	for _, tag := range tags {
+		res[tag], _ = sr.GetTagsStatistics(ctx, r, tag)
+	}
+	return res, nil
+}
+
+func searchAll(t *testing.T, sr spanreader.SpanReader, limit int) ([]uint64, int) {
+	var startTimes []uint64
+	pages := 0
+	r := spansquery.SearchRequest{Metadata: &spansquery.Metadata{}, Limit: limit}
+	for {
+		res, err := sr.Search(context.Background(), r)
+		assert.NoError(t, err)
+		pages++
+		for _, span := range res.Spans {
+			startTimes = append(startTimes, span.Span.StartTimeUnixNano)
+		}
+		if res.Metadata.NextToken == "" {
+			return startTimes, pages
+		}
+		r.Metadata.NextToken = res.Metadata.NextToken
+	}
+}
+
+func TestSearchMergesPagesInOrder(t *testing.T) {
+	sr, err := NewSpanReader([]Backend{
+		{Name: "live", Reader: newPagedSpanReader(10, 9, 3, 2)},
+		{Name: "archive", Reader: newPagedSpanReader(8, 7, 6, 5, 4, 1)},
+	})
+	assert.NoError(t, err)
+
+	for _, limit := range []int{0, 1, 3, 100} {
+		startTimes, _ := searchAll(t, sr, limit)
+		assert.Equal(t, []uint64{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, startTimes, "limit %d", limit)
+	}
+	_, pages := searchAll(t, sr, 3)
+	// pages end early when a backend runs out of fetched spans
+	assert.Equal(t, 5, pages)
+}
+
+func TestSearchSortsByField(t *testing.T) {
+	live, archive := newPagedSpanReader(2), newPagedSpanReader(1)
+	live.spans[0].Span.Name = "b"
+	archive.spans[0].Span.Name = "a"
+	sr, _ := NewSpanReader([]Backend{{Name: "live", Reader: live}, {Name: "archive", Reader: archive}})
+
+	res, err := sr.Search(context.Background(), spansquery.SearchRequest{
+		Sort: []spansquery.Sort{{Field: "span.name", Ascending: true}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "a", res.Spans[0].Span.Name)
+	assert.Equal(t, "b", res.Spans[1].Span.Name)
+}
+
+func TestSearchMergesPartialResults(t *testing.T) {
+	live, archive := newPagedSpanReader(2), newPagedSpanReader(1)
+	live.dropped = []string{"failed to read span"}
+	archive.dropped = []string{"failed to read span", "failed to convert span"}
+	sr, _ := NewSpanReader([]Backend{{Name: "live", Reader: live}, {Name: "archive", Reader: archive}})
+
+	res, err := sr.Search(context.Background(), spansquery.SearchRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, res.Spans, 2)
+	assert.Equal(t, &spansquery.PartialResult{
+		DroppedSpans: 3, Reasons: []string{"failed to read span", "failed to convert span"},
+	}, res.Partial)
+}
+
+func TestSearchFailsWithBackend(t *testing.T) {
+	failing := newPagedSpanReader(1)
+	failing.err = errors.New("unreachable")
+	sr, _ := NewSpanReader([]Backend{{Name: "live", Reader: newPagedSpanReader(2)}, {Name: "archive", Reader: failing}})
+
+	_, err := sr.Search(context.Background(), spansquery.SearchRequest{})
+	assert.ErrorContains(t, err, "backend archive: unreachable")
+}
+
+func TestCombinesTags(t *testing.T) {
+	sr, _ := NewSpanReader([]Backend{
+		{Name: "live", Reader: newPagedSpanReader(10, 2)},
+		{Name: "archive", Reader: newPagedSpanReader(6, 1)},
+	})
+	ctx := context.Background()
+
+	tags, err := sr.GetAvailableTags(ctx, tagsquery.GetAvailableTagsRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, []tagsquery.TagInfo{{Name: "custom-tag", Type: "string"}}, tags.Tags)
+
+	values, err := sr.GetTagsValues(ctx, tagsquery.TagValuesRequest{}, []string{"span.attributes.custom-tag", "span.attributes.missing"})
+	assert.NoError(t, err)
+	assert.Equal(t, []tagsquery.TagValueInfo{{Value: "custom-value", Count: 6}}, values["span.attributes.custom-tag"].Values)
+	assert.NotContains(t, values, "span.attributes.missing")
+
+	stats, err := sr.GetTagsStatistics(ctx, tagsquery.TagStatisticsRequest{}, "span.startTimeUnixNano")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, stats.Statistics[tagsquery.MIN])
+	assert.Equal(t, 10.0, stats.Statistics[tagsquery.MAX])
+	assert.Equal(t, 4.75, stats.Statistics[tagsquery.AVG])
+	assert.Equal(t, 10.0, stats.Statistics[tagsquery.P99])
+	assert.Equal(t, int64(4), stats.Count)
+	assert.Equal(t, []tagsquery.TagStatistic{tagsquery.P99}, stats.UpperBounds)
+
+	batch, err := sr.GetTagsStatisticsBatch(ctx, tagsquery.TagStatisticsRequest{}, []string{"span.startTimeUnixNano", "span.endTimeUnixNano"})
+	assert.NoError(t, err)
+	assert.Len(t, batch, 2)
+	assert.Equal(t, stats, batch["span.endTimeUnixNano"])
+}
+
+func TestMergeStatisticsWeightsAverages(t *testing.T) {
+	res := mergeStatistics([]*tagsquery.TagStatisticsResponse{
+		{Statistics: map[tagsquery.TagStatistic]float64{tagsquery.AVG: 1, tagsquery.P99: 1}, Count: 3},
+		{Statistics: map[tagsquery.TagStatistic]float64{tagsquery.AVG: 5, tagsquery.P99: 5}, Count: 1},
+		{Statistics: map[tagsquery.TagStatistic]float64{}},
+		nil,
+	})
+	assert.Equal(t, &tagsquery.TagStatisticsResponse{
+		Statistics:  map[tagsquery.TagStatistic]float64{tagsquery.AVG: 2, tagsquery.P99: 5},
+		Count:       4,
+		UpperBounds: []tagsquery.TagStatistic{tagsquery.P99},
+	}, res)
+}
+
+func TestMergeStatisticsWithoutCounts(t *testing.T) {
+	res := mergeStatistics([]*tagsquery.TagStatisticsResponse{
+		{Statistics: map[tagsquery.TagStatistic]float64{tagsquery.AVG: 1}, Count: 3},
+		{Statistics: map[tagsquery.TagStatistic]float64{tagsquery.AVG: 5}},
+	})
+	assert.Equal(t, &tagsquery.TagStatisticsResponse{Statistics: map[tagsquery.TagStatistic]float64{tagsquery.AVG: 3}}, res)
+}
+
+func TestMergeStatisticsOfSingleBackendIsExact(t *testing.T) {
+	res := mergeStatistics([]*tagsquery.TagStatisticsResponse{
+		{Statistics: map[tagsquery.TagStatistic]float64{tagsquery.P99: 5}, Count: 3},
+		{Statistics: map[tagsquery.TagStatistic]float64{}},
+	})
+	assert.Empty(t, res.UpperBounds)
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "federation.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+backends:
+  - name: live
+    plugin: elasticsearch
+    settings:
+      es_endpoint: http://es-live:9200
+  - name: archive
+    plugin: sqlite
+`), 0o600))
+
+	cfg, err := LoadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://es-live:9200", cfg.Backends[0].Settings["es_endpoint"])
+	assert.Equal(t, "sqlite", cfg.Backends[1].Plugin)
+
+	assert.NoError(t, os.WriteFile(path, []byte("backends:\n  - name: live\n    plugin: sqlite\n"), 0o600))
+	_, err = LoadConfig(path)
+	assert.ErrorContains(t, err, "at least two backends")
+}
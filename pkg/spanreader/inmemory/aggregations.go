@@ -0,0 +1,166 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inmemory
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// Aggregator computes the metrics of spans grouped by the values of a tag, and by time buckets of their start time if
+// the aggregations are bucketed, adding one span at a time.
+type Aggregator struct {
+	r spansquery.Aggregations
+	// loc is the location of the timezone of the buckets
+	loc *time.Location
+	// fields are the tags whose values are aggregated by the metrics
+	fields []string
+	groups map[string]*aggregationGroup
+}
+
+type aggregationGroup struct {
+	bucket uint64
+	value  any
+	count  uint64
+	values map[string][]float64
+}
+
+func NewAggregator(r spansquery.Aggregations) *Aggregator {
+	loc, err := r.Location()
+	if err != nil {
+		// the timezone is validated with the request
+		loc = time.UTC
+	}
+	a := &Aggregator{r: r, loc: loc, groups: make(map[string]*aggregationGroup)}
+	seen := make(map[string]bool)
+	for _, m := range r.Metrics {
+		if m.Function != spansquery.AGGREGATION_COUNT && !seen[m.Field] {
+			seen[m.Field] = true
+			a.fields = append(a.fields, m.Field)
+		}
+	}
+	return a
+}
+
+// Add adds span to the group of its value of the group by tag, in the bucket of its start time. A span having several
+// values of the tag, e.g. an array attribute, is added to the group of each of them.
+func (a *Aggregator) Add(span *internalspan.InternalSpan) {
+	var bucket uint64
+	if a.r.Interval != "" && span.Span != nil {
+		bucket = spansquery.BucketStart(a.r.Interval, a.loc, span.Span.StartTimeUnixNano)
+	}
+	values := []any{nil}
+	if a.r.GroupBy != "" {
+		if v := Values(span, a.r.GroupBy); len(v) > 0 {
+			values = v
+		}
+	}
+	added := make(map[string]bool, len(values))
+	for _, value := range values {
+		key := strconv.FormatUint(bucket, 10) + "/" + groupKey(value)
+		if added[key] {
+			continue
+		}
+		added[key] = true
+		group, ok := a.groups[key]
+		if !ok {
+			group = &aggregationGroup{bucket: bucket, value: value, values: make(map[string][]float64)}
+			a.groups[key] = group
+		}
+		group.count++
+		for _, field := range a.fields {
+			for _, v := range Values(span, field) {
+				if n, ok := Number(v); ok {
+					group.values[field] = append(group.values[field], n)
+				}
+			}
+		}
+	}
+}
+
+// Result returns the metrics of the largest groups, up to the limit of the aggregations. The groups of bucketed
+// aggregations are then ordered by their bucket, the largest first within a bucket.
+func (a *Aggregator) Result() []spansquery.AggregationGroup {
+	keys := make([]string, 0, len(a.groups))
+	for key := range a.groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		gi, gj := a.groups[keys[i]], a.groups[keys[j]]
+		if gi.count != gj.count {
+			return gi.count > gj.count
+		}
+		return keys[i] < keys[j]
+	})
+	if limit := a.r.EffectiveLimit(); len(keys) > limit {
+		keys = keys[:limit]
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return a.groups[keys[i]].bucket < a.groups[keys[j]].bucket })
+
+	groups := make([]spansquery.AggregationGroup, 0, len(keys))
+	for _, key := range keys {
+		g := a.groups[key]
+		group := spansquery.AggregationGroup{BucketStartUnixNano: g.bucket, Value: g.value, Count: g.count, Metrics: make(map[string]float64)}
+		for _, m := range a.r.Metrics {
+			if m.Function == spansquery.AGGREGATION_COUNT {
+				group.Metrics[m.Name()] = float64(g.count)
+				continue
+			}
+			if values := g.values[m.Field]; len(values) > 0 {
+				group.Metrics[m.Name()] = metric(m.Function, values)
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// metric returns the value of an aggregation function of values, which it sorts.
+func metric(f spansquery.AggregationFunction, values []float64) float64 {
+	sort.Float64s(values)
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	switch f {
+	case spansquery.AGGREGATION_SUM:
+		return sum
+	case spansquery.AGGREGATION_AVG:
+		return sum / float64(len(values))
+	case spansquery.AGGREGATION_MIN:
+		return values[0]
+	case spansquery.AGGREGATION_MAX:
+		return values[len(values)-1]
+	default:
+		return percentile(values, spansquery.AggregationPercentiles[f])
+	}
+}
+
+// groupKey returns the key of the group of a value, numbers being grouped by their value whatever their type.
+func groupKey(value any) string {
+	if value == nil {
+		return ""
+	}
+	if n, ok := Number(value); ok {
+		return "n:" + strconv.FormatFloat(n, 'g', -1, 64)
+	}
+	return "s:" + toString(value)
+}
@@ -0,0 +1,263 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package inmemory
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSpan(spanId string, startTime uint64, duration uint64, attributes internalspan.Attributes) *internalspan.InternalSpan {
+	return &internalspan.InternalSpan{
+		Resource: &internalspan.Resource{Attributes: internalspan.Attributes{"service.name": "checkout"}},
+		Scope:    &internalspan.InstrumentationScope{Name: "net/http"},
+		Span: &internalspan.Span{
+			TraceId:           "trace-" + spanId,
+			SpanId:            spanId,
+			Name:              "GET /cart",
+			Kind:              "Server",
+			StartTimeUnixNano: startTime,
+			EndTimeUnixNano:   startTime + duration,
+			Attributes:        attributes,
+			Status:            &internalspan.SpanStatus{Code: "Error"},
+			Events: []*internalspan.SpanEvent{
+				{Name: "exception", Attributes: internalspan.Attributes{"exception.type": "Timeout"}},
+			},
+		},
+		ExternalFields: &internalspan.ExternalFields{DurationNano: duration},
+	}
+}
+
+func filter(key string, operator string, value any) model.SearchFilter {
+	return model.SearchFilter{KeyValueFilter: &model.KeyValueFilter{
+		Key: model.FilterKey(key), Operator: model.FilterOperator(operator), Value: value,
+	}}
+}
+
+func TestMatch(t *testing.T) {
+	span := newSpan("a", 100, 10, internalspan.Attributes{
+		"http.status_code": json.Number("503"),
+		"http.headers":     map[string]any{"accept": "text/html"},
+		"tags":             []any{"blue", "green"},
+		"payload":          "cafe01",
+		"payload.text":     "yv4B",
+	})
+	span.Span.BinaryAttributes = internalspan.BinaryAttributes{"payload": internalspan.BinaryEncodingHex}
+
+	tests := []struct {
+		name   string
+		filter model.SearchFilter
+		want   bool
+	}{
+		{"equals field", filter("span.name", "equals", "GET /cart"), true},
+		{"equals number", filter("span.attributes.http.status_code", "equals", 503.0), true},
+		{"equals number as string", filter("span.attributes.http.status_code", "equals", "503"), true},
+		{"equals enum name", filter("span.kind", "equals", "SPAN_KIND_SERVER"), true},
+		{"not equals", filter("span.status.code", "not_equals", "Error"), false},
+		{"in", filter("resource.attributes.service.name", "in", []any{"cart", "checkout"}), true},
+		{"not in", filter("resource.attributes.service.name", "not_in", []any{"cart"}), true},
+		{"contains is case-insensitive", filter("span.name", "contains", "cart"), true},
+		{"not contains", filter("span.name", "not_contains", "CART"), false},
+		{"exists", filter("span.attributes.http.status_code", "exists", nil), true},
+		{"not exists", filter("span.attributes.http.route", "not_exists", nil), true},
+		{"gt", filter("externalFields.durationNano", "gt", 9), true},
+		{"lte", filter("externalFields.durationNano", "lte", 9), false},
+		{"nested kvlist", filter("span.attributes.http.headers.accept", "equals", "text/html"), true},
+		{"array element", filter("span.attributes.tags", "equals", "green"), true},
+		{"array negative", filter("span.attributes.tags", "not_equals", "green"), false},
+		{"event attribute", filter("span.events.attributes.exception.type", "equals", "Timeout"), true},
+		{"event name", filter("span.events.name", "equals", "log"), false},
+		{"binary prefix", filter("span.attributes.payload", "binary_prefix", "yv4="), true},
+		{"binary prefix mismatch", filter("span.attributes.payload", "binary_prefix", "vu8="), false},
+		{"binary prefix of a string", filter("span.attributes.payload.text", "binary_prefix", "yv4="), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Match(span, []model.SearchFilter{tt.filter}))
+		})
+	}
+}
+
+func TestSearchSortsAndPages(t *testing.T) {
+	spans := []*internalspan.InternalSpan{
+		newSpan("a", 100, 30, nil),
+		newSpan("b", 300, 10, nil),
+		newSpan("c", 200, 20, nil),
+		newSpan("d", 50, 40, nil),
+	}
+	r := spansquery.SearchRequest{Timeframe: model.Timeframe{StartTime: 100, EndTime: 300}, Limit: 2}
+
+	res, err := Search(spans, r)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, spanIds(res.Spans))
+	assert.Equal(t, spansquery.ContinuationToken("2"), res.Metadata.NextToken)
+
+	r.Metadata = res.Metadata
+	res, err = Search(spans, r)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, spanIds(res.Spans))
+	assert.Empty(t, res.Metadata.NextToken)
+
+	r = spansquery.SearchRequest{Sort: []spansquery.Sort{{Field: "externalFields.durationNano", Ascending: true}}}
+	res, err = Search(spans, r)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "c", "a", "d"}, spanIds(res.Spans))
+
+	_, err = Search(spans, spansquery.SearchRequest{Metadata: &spansquery.Metadata{NextToken: "x"}})
+	assert.Error(t, err)
+}
+
+func TestSearchSample(t *testing.T) {
+	spans := []*internalspan.InternalSpan{newSpan("a", 1, 1, nil), newSpan("b", 2, 1, nil), newSpan("c", 3, 1, nil)}
+	res, err := Search(spans, spansquery.SearchRequest{Sample: &spansquery.Sample{Size: 2}})
+	assert.NoError(t, err)
+	assert.Len(t, res.Spans, 2)
+	assert.Equal(t, uint64(3), res.Sample.EstimatedTotal)
+}
+
+func TestTagsValues(t *testing.T) {
+	spans := []*internalspan.InternalSpan{
+		newSpan("a", 1, 1, internalspan.Attributes{"tags": []any{"blue", "blue"}}),
+		newSpan("b", 2, 1, internalspan.Attributes{"tags": []any{"blue", "green"}}),
+		newSpan("c", 3, 1, internalspan.Attributes{"tags": "red"}),
+	}
+	res := TagsValues(spans, tagsquery.TagValuesRequest{
+		SearchFilters: []model.SearchFilter{filter("span.spanId", "not_equals", "c")},
+	}, []string{"span.attributes.tags"})
+
+	assert.Equal(t, []tagsquery.TagValueInfo{
+		{Value: "blue", Count: 2},
+		{Value: "green", Count: 1},
+	}, res["span.attributes.tags"].Values)
+}
+
+func TestTagStatistics(t *testing.T) {
+	var spans []*internalspan.InternalSpan
+	for i := uint64(1); i <= 100; i++ {
+		spans = append(spans, newSpan("a", i, i, nil))
+	}
+	res := TagStatistics(spans, tagsquery.TagStatisticsRequest{
+		DesiredStatistics: []tagsquery.TagStatistic{tagsquery.MIN, tagsquery.MAX, tagsquery.AVG, tagsquery.P99},
+	}, "externalFields.durationNano")
+
+	assert.Equal(t, 1.0, res.Statistics[tagsquery.MIN])
+	assert.Equal(t, 100.0, res.Statistics[tagsquery.MAX])
+	assert.Equal(t, 50.5, res.Statistics[tagsquery.AVG])
+	assert.InDelta(t, 99.01, res.Statistics[tagsquery.P99], 0.001)
+}
+
+func TestTagsStatistics(t *testing.T) {
+	spans := []*internalspan.InternalSpan{
+		newSpan("a", 1, 10, internalspan.Attributes{"retries": 1.0}),
+		newSpan("b", 2, 30, internalspan.Attributes{"retries": 3.0}),
+		newSpan("c", 3, 50, nil),
+	}
+	res := TagsStatistics(spans, tagsquery.TagStatisticsRequest{
+		SearchFilters:     []model.SearchFilter{filter("span.spanId", "not_equals", "c")},
+		DesiredStatistics: []tagsquery.TagStatistic{tagsquery.MIN, tagsquery.MAX},
+	}, []string{"externalFields.durationNano", "span.attributes.retries", "span.attributes.missing"})
+
+	assert.Equal(t, map[tagsquery.TagStatistic]float64{tagsquery.MIN: 10, tagsquery.MAX: 30}, res["externalFields.durationNano"].Statistics)
+	assert.Equal(t, map[tagsquery.TagStatistic]float64{tagsquery.MIN: 1, tagsquery.MAX: 3}, res["span.attributes.retries"].Statistics)
+	assert.Empty(t, res["span.attributes.missing"].Statistics)
+}
+
+func TestAggregator(t *testing.T) {
+	aggregator := NewAggregator(spansquery.Aggregations{
+		Metrics: []spansquery.Metric{
+			{Function: spansquery.AGGREGATION_COUNT},
+			{Function: spansquery.AGGREGATION_AVG, Field: "externalFields.durationNano"},
+			{Function: spansquery.AGGREGATION_MAX, Field: "externalFields.durationNano"},
+			{Function: spansquery.AGGREGATION_SUM, Field: "span.attributes.retries"},
+		},
+		GroupBy: "span.attributes.http.status_code",
+	})
+	aggregator.Add(newSpan("a", 1, 10, internalspan.Attributes{"http.status_code": json.Number("200")}))
+	aggregator.Add(newSpan("b", 2, 30, internalspan.Attributes{"http.status_code": 200.0}))
+	aggregator.Add(newSpan("c", 3, 50, internalspan.Attributes{"http.status_code": json.Number("503"), "retries": 2.0}))
+	aggregator.Add(newSpan("d", 4, 70, nil))
+
+	assert.Equal(t, []spansquery.AggregationGroup{
+		{Value: json.Number("200"), Count: 2, Metrics: map[string]float64{
+			"count": 2, "avg(externalFields.durationNano)": 20, "max(externalFields.durationNano)": 30,
+		}},
+		{Value: nil, Count: 1, Metrics: map[string]float64{
+			"count": 1, "avg(externalFields.durationNano)": 70, "max(externalFields.durationNano)": 70,
+		}},
+		{Value: json.Number("503"), Count: 1, Metrics: map[string]float64{
+			"count": 1, "avg(externalFields.durationNano)": 50, "max(externalFields.durationNano)": 50, "sum(span.attributes.retries)": 2,
+		}},
+	}, aggregator.Result())
+}
+
+func TestAggregatorBuckets(t *testing.T) {
+	unixNano := func(s string) uint64 {
+		t, _ := time.Parse(time.RFC3339, s)
+		return uint64(t.UnixNano())
+	}
+	spans := []*internalspan.InternalSpan{
+		newSpan("a", unixNano("2023-01-01T17:45:00Z"), 10, nil), // 23:15 in Kolkata
+		newSpan("b", unixNano("2023-01-01T18:00:00Z"), 30, nil), // 23:30 in Kolkata
+		newSpan("c", unixNano("2023-01-01T18:40:00Z"), 50, nil), // 00:10 of the next day in Kolkata
+	}
+	aggregate := func(interval spansquery.AggregationInterval) []spansquery.AggregationGroup {
+		aggregator := NewAggregator(spansquery.Aggregations{
+			Metrics:  []spansquery.Metric{{Function: spansquery.AGGREGATION_COUNT}},
+			Interval: interval,
+			Timezone: "Asia/Kolkata",
+		})
+		for _, span := range spans {
+			aggregator.Add(span)
+		}
+		return aggregator.Result()
+	}
+
+	assert.Equal(t, []spansquery.AggregationGroup{
+		{BucketStartUnixNano: unixNano("2022-12-31T18:30:00Z"), Count: 2, Metrics: map[string]float64{"count": 2}},
+		{BucketStartUnixNano: unixNano("2023-01-01T18:30:00Z"), Count: 1, Metrics: map[string]float64{"count": 1}},
+	}, aggregate(spansquery.INTERVAL_DAY))
+	assert.Equal(t, []spansquery.AggregationGroup{
+		{BucketStartUnixNano: unixNano("2023-01-01T17:30:00Z"), Count: 2, Metrics: map[string]float64{"count": 2}},
+		{BucketStartUnixNano: unixNano("2023-01-01T18:30:00Z"), Count: 1, Metrics: map[string]float64{"count": 1}},
+	}, aggregate(spansquery.INTERVAL_HOUR))
+}
+
+func TestAvailableTags(t *testing.T) {
+	span := newSpan("a", 1, 1, internalspan.Attributes{"retries": 2.0, "ratio": 0.5})
+	res := AvailableTags([]*internalspan.InternalSpan{span}, 0)
+
+	assert.Contains(t, res.Tags, tagsquery.TagInfo{Name: "span.attributes.retries", Type: NumberType})
+	assert.Contains(t, res.Tags, tagsquery.TagInfo{Name: "span.attributes.ratio", Type: DoubleType})
+	assert.Contains(t, res.Tags, tagsquery.TagInfo{Name: "span.events.attributes.exception.type", Type: TextType})
+	assert.Contains(t, res.Tags, tagsquery.TagInfo{Name: "span.name", Type: TextType})
+	assert.Len(t, AvailableTags([]*internalspan.InternalSpan{span}, 3).Tags, 3)
+}
+
+func spanIds(spans []*internalspan.InternalSpan) []string {
+	var ids []string
+	for _, s := range spans {
+		ids = append(ids, s.Span.SpanId)
+	}
+	return ids
+}
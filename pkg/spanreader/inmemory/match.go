@@ -0,0 +1,178 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package inmemory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// Match returns whether span matches all the filters. A filter on a key with several values (e.g. an attribute of
+// the events of the span, or an array attribute) matches if any of the values matches, and a negative filter
+// (e.g. not_equals) matches if none of them matches the positive one.
+func Match(span *internalspan.InternalSpan, filters []model.SearchFilter) bool {
+	for _, f := range filters {
+		if f.KeyValueFilter != nil && !matchFilter(span, *f.KeyValueFilter) {
+			return false
+		}
+	}
+	return true
+}
+
+// negativeOperators maps the negative operators to the positive ones they negate
+var negativeOperators = map[model.FilterOperator]model.FilterOperator{
+	spansquery.OPERATOR_NOT_EQUALS:   spansquery.OPERATOR_EQUALS,
+	spansquery.OPERATOR_NOT_IN:       spansquery.OPERATOR_IN,
+	spansquery.OPERATOR_NOT_CONTAINS: spansquery.OPERATOR_CONTAINS,
+	spansquery.OPERATOR_NOT_EXISTS:   spansquery.OPERATOR_EXISTS,
+}
+
+func matchFilter(span *internalspan.InternalSpan, f model.KeyValueFilter) bool {
+	if f.Operator == spansquery.OPERATOR_BINARY_PREFIX {
+		return matchBinaryPrefix(BinaryValues(span, string(f.Key)), f.Value)
+	}
+	values := Values(span, string(f.Key))
+	filterValue := spansquery.NormalizeFilterValue(f.Key, f.Value)
+	if operator, ok := negativeOperators[f.Operator]; ok {
+		return !matchValues(values, operator, filterValue)
+	}
+	return matchValues(values, f.Operator, filterValue)
+}
+
+func matchValues(values []any, operator model.FilterOperator, filterValue any) bool {
+	if operator == spansquery.OPERATOR_EXISTS {
+		return len(values) > 0
+	}
+	for _, value := range values {
+		if matchValue(value, operator, filterValue) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchBinaryPrefix returns whether any of the binary values starts with the bytes of a binary_prefix filter value.
+func matchBinaryPrefix(values [][]byte, filterValue any) bool {
+	prefix, err := spansquery.BinaryPrefix(filterValue)
+	if err != nil {
+		return false
+	}
+	for _, value := range values {
+		if bytes.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchValue(value any, operator model.FilterOperator, filterValue any) bool {
+	switch operator {
+	case spansquery.OPERATOR_EQUALS:
+		return Equal(value, filterValue)
+	case spansquery.OPERATOR_IN:
+		filterValues, _ := filterValue.([]any)
+		for _, v := range filterValues {
+			if Equal(value, v) {
+				return true
+			}
+		}
+		return false
+	case spansquery.OPERATOR_CONTAINS:
+		// contains is case-insensitive, as in the other storage plugins
+		return strings.Contains(strings.ToLower(toString(value)), strings.ToLower(toString(filterValue)))
+	case spansquery.OPERATOR_GT, spansquery.OPERATOR_GTE, spansquery.OPERATOR_LT, spansquery.OPERATOR_LTE:
+		a, aok := Number(value)
+		b, bok := Number(filterValue)
+		if !aok || !bok {
+			return false
+		}
+		switch operator {
+		case spansquery.OPERATOR_GT:
+			return a > b
+		case spansquery.OPERATOR_GTE:
+			return a >= b
+		case spansquery.OPERATOR_LT:
+			return a < b
+		default:
+			return a <= b
+		}
+	}
+	return false
+}
+
+// Equal returns whether a span value equals a filter value, comparing numbers numerically and any other values
+// by their string form, so e.g. the boolean true equals the filter value "true".
+func Equal(a any, b any) bool {
+	an, aok := Number(a)
+	bn, bok := Number(b)
+	if aok && bok {
+		return an == bn
+	}
+	return toString(a) == toString(b)
+}
+
+// Number returns a numeric value as a float64.
+func Number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func toString(v any) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case nil:
+		return ""
+	case map[string]any, []any:
+		b, _ := json.Marshal(s)
+		return string(b)
+	default:
+		return fmt.Sprint(s)
+	}
+}
+
+// InTimeframe returns whether span started within tf, whose end time is unbounded when 0.
+func InTimeframe(span *internalspan.InternalSpan, tf model.Timeframe) bool {
+	if span.Span == nil {
+		return false
+	}
+	return span.Span.StartTimeUnixNano >= tf.StartTime && (tf.EndTime == 0 || span.Span.StartTimeUnixNano <= tf.EndTime)
+}
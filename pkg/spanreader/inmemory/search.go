@@ -0,0 +1,165 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package inmemory
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// DefaultLimit is the page size of searches without a limit
+const DefaultLimit = 200
+
+// defaultSort is the order of searches without a sort, the most recent spans first.
+var defaultSort = []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: false}}
+
+// Filter returns the spans started within tf which match all the filters.
+func Filter(spans []*internalspan.InternalSpan, tf model.Timeframe, filters []model.SearchFilter) []*internalspan.InternalSpan {
+	result := make([]*internalspan.InternalSpan, 0, len(spans))
+	for _, span := range spans {
+		if span != nil && InTimeframe(span, tf) && Match(span, filters) {
+			result = append(result, span)
+		}
+	}
+	return result
+}
+
+// Search runs r over spans: it filters them, and returns either a sorted page of the matching spans or a sample
+// of them. The continuation token of a page is the offset of the next page in the sorted matching spans,
+// so paging is consistent as long as the spans searched don't change between the pages. Search only fails on an
+// invalid continuation token.
+func Search(spans []*internalspan.InternalSpan, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	matching := Filter(spans, r.Timeframe, r.SearchFilters)
+
+	if r.Sample != nil {
+		total := uint64(len(matching))
+		if len(matching) > r.Sample.Size {
+			seed := time.Now().UnixNano()
+			if r.Sample.Seed != nil {
+				seed = *r.Sample.Seed
+			}
+			random := rand.New(rand.NewSource(seed))
+			random.Shuffle(len(matching), func(i, j int) { matching[i], matching[j] = matching[j], matching[i] })
+			matching = matching[:r.Sample.Size]
+		}
+		return &spansquery.SearchResponse{
+			Metadata: &spansquery.Metadata{},
+			Spans:    matching,
+			Sample:   &spansquery.SampleMetadata{EstimatedTotal: total},
+		}, nil
+	}
+
+	offset := 0
+	if r.Metadata != nil && r.Metadata.NextToken != "" {
+		var err error
+		offset, err = strconv.Atoi(string(r.Metadata.NextToken))
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("invalid continuation token %q", r.Metadata.NextToken)
+		}
+	}
+	limit := r.Limit
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+
+	Sort(matching, r.Sort)
+	res := &spansquery.SearchResponse{Metadata: &spansquery.Metadata{}, Spans: make([]*internalspan.InternalSpan, 0)}
+	if offset >= len(matching) {
+		return res, nil
+	}
+	end := offset + limit
+	if end < len(matching) {
+		res.Metadata.NextToken = spansquery.ContinuationToken(strconv.Itoa(end))
+	} else {
+		end = len(matching)
+	}
+	res.Spans = append(res.Spans, matching[offset:end]...)
+	return res, nil
+}
+
+// Sort sorts spans by sorts, or the most recent first if there are none. Spans missing a sort field are ordered
+// last, and spans with a field of several values (e.g. an attribute of the events of the span) by the first one.
+func Sort(spans []*internalspan.InternalSpan, sorts []spansquery.Sort) {
+	if len(sorts) == 0 {
+		sorts = defaultSort
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return less(spans[i], spans[j], sorts) })
+}
+
+func less(a *internalspan.InternalSpan, b *internalspan.InternalSpan, sorts []spansquery.Sort) bool {
+	for _, s := range sorts {
+		c := compare(sortValue(a, string(s.Field)), sortValue(b, string(s.Field)))
+		if c == 0 {
+			continue
+		}
+		// spans missing the field are last in both orders
+		if s.Ascending || c == missing || c == -missing {
+			return c < 0
+		}
+		return c > 0
+	}
+	return false
+}
+
+// missing is the result of compare when one of the values is missing
+const missing = 2
+
+// compare compares numbers numerically and any other values by their string form, missing values being the greatest.
+func compare(a any, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return missing
+	case b == nil:
+		return -missing
+	}
+	an, aok := Number(a)
+	bn, bok := Number(b)
+	if aok && bok {
+		switch {
+		case an < bn:
+			return -1
+		case an > bn:
+			return 1
+		default:
+			return 0
+		}
+	}
+	as, bs := toString(a), toString(b)
+	switch {
+	case as < bs:
+		return -1
+	case as > bs:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func sortValue(span *internalspan.InternalSpan, field string) any {
+	if values := Values(span, field); len(values) > 0 {
+		return values[0]
+	}
+	return nil
+}
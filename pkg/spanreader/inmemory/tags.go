@@ -0,0 +1,261 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package inmemory
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+)
+
+// Types of tags, named after the OTLP value types of attributes
+const (
+	TextType   = "Str"
+	NumberType = "Int"
+	DoubleType = "Double"
+	BoolType   = "Bool"
+	SliceType  = "Slice"
+	MapType    = "Map"
+)
+
+// StaticTags are the tags of the span fields which aren't attributes, with their types
+var StaticTags = map[string]string{
+	"span.events.name":                    TextType,
+	"span.events.droppedAttributesCount":  NumberType,
+	"span.links.traceId":                  TextType,
+	"span.links.spanId":                   TextType,
+	"span.links.traceState":               TextType,
+	"span.links.droppedAttributesCount":   NumberType,
+	"scope.name":                          TextType,
+	"scope.version":                       TextType,
+	"scope.droppedAttributesCount":        NumberType,
+	"span.spanId":                         TextType,
+	"span.traceId":                        TextType,
+	"span.traceState":                     TextType,
+	"span.parentSpanId":                   TextType,
+	"span.name":                           TextType,
+	"span.kind":                           TextType,
+	"span.startTimeUnixNano":              NumberType,
+	"span.endTimeUnixNano":                NumberType,
+	"span.droppedAttributesCount":         NumberType,
+	"span.status.message":                 TextType,
+	"span.status.code":                    TextType,
+	"span.droppedResourceAttributesCount": NumberType,
+	"span.droppedEventsCount":             NumberType,
+	"span.droppedLinksCount":              NumberType,
+	"externalFields.durationNano":         NumberType,
+	"externalFields.childCount":           NumberType,
+}
+
+// AttributeType returns the type of the tag of an attribute value.
+func AttributeType(value any) string {
+	switch v := value.(type) {
+	case bool:
+		return BoolType
+	case float32, float64:
+		if f, _ := Number(v); f == math.Trunc(f) {
+			return NumberType
+		}
+		return DoubleType
+	case int, int32, int64, uint32, uint64:
+		return NumberType
+	case []any:
+		return SliceType
+	case map[string]any:
+		return MapType
+	default:
+		return TextType
+	}
+}
+
+// AttributeTags returns the tags of the attributes of span, e.g. "span.attributes.http.method", with their types.
+func AttributeTags(span *internalspan.InternalSpan) map[string]string {
+	tags := make(map[string]string)
+	add := func(prefix string, attributes internalspan.Attributes) {
+		for key, value := range attributes {
+			tags[prefix+key] = AttributeType(value)
+		}
+	}
+	if span.Resource != nil {
+		add("resource.attributes.", span.Resource.Attributes)
+	}
+	if span.Scope != nil {
+		add("scope.attributes.", span.Scope.Attributes)
+	}
+	if span.Span != nil {
+		add("span.attributes.", span.Span.Attributes)
+		for _, e := range span.Span.Events {
+			if e != nil {
+				add("span.events.attributes.", e.Attributes)
+			}
+		}
+		for _, l := range span.Span.Links {
+			if l != nil {
+				add("span.links.attributes.", l.Attributes)
+			}
+		}
+	}
+	return tags
+}
+
+// AvailableTags returns the static tags followed by the attribute tags of spans, by name, up to limit tags
+// unless limit is 0.
+func AvailableTags(spans []*internalspan.InternalSpan, limit int) *tagsquery.GetAvailableTagsResponse {
+	attributeTags := make(map[string]string)
+	for _, span := range spans {
+		for name, tagType := range AttributeTags(span) {
+			attributeTags[name] = tagType
+		}
+	}
+	return &tagsquery.GetAvailableTagsResponse{Tags: Tags(attributeTags, limit)}
+}
+
+// Tags returns the static tags followed by the attribute tags, by name, up to limit tags unless limit is 0.
+func Tags(attributeTags map[string]string, limit int) []tagsquery.TagInfo {
+	tags := make([]tagsquery.TagInfo, 0, len(StaticTags)+len(attributeTags))
+	tags = appendSorted(tags, StaticTags)
+	tags = appendSorted(tags, attributeTags)
+	if limit > 0 && len(tags) > limit {
+		tags = tags[:limit]
+	}
+	return tags
+}
+
+func appendSorted(tags []tagsquery.TagInfo, types map[string]string) []tagsquery.TagInfo {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		tags = append(tags, tagsquery.TagInfo{Name: name, Type: types[name]})
+	}
+	return tags
+}
+
+// TagsValues returns the values of each of the tags in the spans matching r, with the number of spans having them,
+// in the sort order of r and up to r.Limit values per tag unless it's 0.
+func TagsValues(spans []*internalspan.InternalSpan, r tagsquery.TagValuesRequest, tags []string) map[string]*tagsquery.TagValuesResponse {
+	matching := Filter(spans, timeframe(r.Timeframe), r.SearchFilters)
+	sortField, _ := r.Sort()
+	result := make(map[string]*tagsquery.TagValuesResponse, len(tags))
+	for _, tag := range tags {
+		var values []tagsquery.TagValueInfo
+		indexes := make(map[string]int)
+		for _, span := range matching {
+			var startTime uint64
+			if sortField == tagsquery.SORT_BY_LAST_SEEN && span.Span != nil {
+				startTime = span.Span.StartTimeUnixNano
+			}
+			seen := make(map[string]bool)
+			for _, value := range Values(span, tag) {
+				// values are counted once per span, and by type so the number 1 and the string "1" are told apart
+				key := fmt.Sprintf("%T:%s", value, toString(value))
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				if i, ok := indexes[key]; ok {
+					values[i].Count++
+					if startTime > values[i].LastSeenUnixNano {
+						values[i].LastSeenUnixNano = startTime
+					}
+					continue
+				}
+				indexes[key] = len(values)
+				values = append(values, tagsquery.TagValueInfo{Value: value, Count: 1, LastSeenUnixNano: startTime})
+			}
+		}
+		tagsquery.SortValues(values, r)
+		if r.Limit > 0 && len(values) > r.Limit {
+			values = values[:r.Limit]
+		}
+		result[tag] = &tagsquery.TagValuesResponse{Values: values}
+	}
+	return result
+}
+
+// TagStatistics returns the desired statistics of the numeric values of tag in the spans matching r.
+// Statistics of a tag without numeric values are omitted.
+func TagStatistics(spans []*internalspan.InternalSpan, r tagsquery.TagStatisticsRequest, tag string) *tagsquery.TagStatisticsResponse {
+	return TagsStatistics(spans, r, []string{tag})[tag]
+}
+
+// TagsStatistics returns the desired statistics of each of the tags in the spans matching r, filtering the spans once.
+func TagsStatistics(
+	spans []*internalspan.InternalSpan, r tagsquery.TagStatisticsRequest, tags []string,
+) map[string]*tagsquery.TagStatisticsResponse {
+	matching := Filter(spans, timeframe(r.Timeframe), r.SearchFilters)
+	res := make(map[string]*tagsquery.TagStatisticsResponse, len(tags))
+	for _, tag := range tags {
+		var values []float64
+		for _, span := range matching {
+			for _, value := range Values(span, tag) {
+				if n, ok := Number(value); ok {
+					values = append(values, n)
+				}
+			}
+		}
+		res[tag] = statistics(values, r.DesiredStatistics)
+	}
+	return res
+}
+
+// statistics returns the desired statistics of values, which are sorted in place.
+func statistics(values []float64, desired []tagsquery.TagStatistic) *tagsquery.TagStatisticsResponse {
+	res := &tagsquery.TagStatisticsResponse{Statistics: make(map[tagsquery.TagStatistic]float64)}
+	if len(values) == 0 {
+		return res
+	}
+	sort.Float64s(values)
+	res.Count = int64(len(values))
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	for _, s := range desired {
+		switch s {
+		case tagsquery.MIN:
+			res.Statistics[s] = values[0]
+		case tagsquery.MAX:
+			res.Statistics[s] = values[len(values)-1]
+		case tagsquery.AVG:
+			res.Statistics[s] = sum / float64(len(values))
+		case tagsquery.P99:
+			res.Statistics[s] = percentile(values, 0.99)
+		}
+	}
+	return res
+}
+
+// percentile returns the p percentile of sorted values, interpolating between the closest ranks.
+func percentile(sorted []float64, p float64) float64 {
+	rank := p * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+func timeframe(tf *model.Timeframe) model.Timeframe {
+	if tf == nil {
+		return model.Timeframe{}
+	}
+	return *tf
+}
@@ -0,0 +1,282 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package inmemory runs the queries of the span reader over spans held in memory, for storage plugins whose
+// backend can't filter, sort or aggregate spans by any of their fields, e.g. key-value and wide-column stores.
+// The plugins narrow the spans down with their own indexes, and leave the rest of the query to this package.
+package inmemory
+
+import (
+	"strings"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+)
+
+// attributeSet is the attributes of a span, its resource, scope, an event or a link, with the encodings of their
+// binary attributes
+type attributeSet struct {
+	attributes internalspan.Attributes
+	binary     internalspan.BinaryAttributes
+}
+
+// attributePrefixes maps the prefixes of attribute keys to the attributes of a span they refer to
+var attributePrefixes = []struct {
+	prefix     string
+	attributes func(span *internalspan.InternalSpan) []attributeSet
+}{
+	{"span.attributes.", spanAttributes},
+	{"resource.attributes.", resourceAttributes},
+	{"span.resource.attributes.", resourceAttributes},
+	{"scope.attributes.", scopeAttributes},
+	{"span.events.attributes.", eventAttributes},
+	{"span.event.attributes.", eventAttributes},
+	{"span.links.attributes.", linkAttributes},
+	{"span.link.attributes.", linkAttributes},
+}
+
+// Values returns the values of key in span, where key is a filter key or a tag, e.g. "span.name" or
+// "span.attributes.http.method". Keys of events and links have a value per event or link, and array attributes
+// a value per element. It returns nil if the span doesn't have the key.
+func Values(span *internalspan.InternalSpan, key string) []any {
+	key = strings.TrimSuffix(key, ".keyword")
+	for _, p := range attributePrefixes {
+		if strings.HasPrefix(key, p.prefix) {
+			var values []any
+			for _, set := range p.attributes(span) {
+				if value, ok := lookupAttribute(set.attributes, strings.TrimPrefix(key, p.prefix)); ok {
+					values = appendValue(values, value)
+				}
+			}
+			return values
+		}
+	}
+	return fieldValues(span, key)
+}
+
+// BinaryValues returns the decoded values of the binary attribute key in span, e.g. "span.attributes.payload", a
+// value per event or link for the attributes of events and links. Only the attributes typed as binary in the
+// BinaryAttributes next to them are decoded, it returns nil if the span doesn't have the key or it isn't binary.
+func BinaryValues(span *internalspan.InternalSpan, key string) [][]byte {
+	key = strings.TrimSuffix(key, ".keyword")
+	for _, p := range attributePrefixes {
+		if strings.HasPrefix(key, p.prefix) {
+			name := strings.TrimPrefix(key, p.prefix)
+			var values [][]byte
+			for _, set := range p.attributes(span) {
+				encoding, binary := set.binary[name]
+				value, ok := set.attributes[name].(string)
+				if !binary || !ok {
+					continue
+				}
+				if b, err := internalspan.DecodeBinaryValue(value, encoding); err == nil {
+					values = append(values, b)
+				}
+			}
+			return values
+		}
+	}
+	return nil
+}
+
+// lookupAttribute returns the value of an attribute, or of a value nested in a kvlist attribute by its full path,
+// e.g. "http.headers.accept" of the attribute "http.headers".
+func lookupAttribute(attributes map[string]any, key string) (any, bool) {
+	if value, ok := attributes[key]; ok {
+		return value, true
+	}
+	for i := strings.IndexByte(key, '.'); i >= 0; i = nextDot(key, i) {
+		if nested, ok := attributes[key[:i]].(map[string]any); ok {
+			if value, ok := lookupAttribute(nested, key[i+1:]); ok {
+				return value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func nextDot(key string, i int) int {
+	j := strings.IndexByte(key[i+1:], '.')
+	if j < 0 {
+		return -1
+	}
+	return i + 1 + j
+}
+
+// appendValue appends value, or its elements if it's an array attribute.
+func appendValue(values []any, value any) []any {
+	if elements, ok := value.([]any); ok {
+		return append(values, elements...)
+	}
+	return append(values, value)
+}
+
+func spanAttributes(span *internalspan.InternalSpan) []attributeSet {
+	if span.Span == nil {
+		return nil
+	}
+	return []attributeSet{{span.Span.Attributes, span.Span.BinaryAttributes}}
+}
+
+func resourceAttributes(span *internalspan.InternalSpan) []attributeSet {
+	if span.Resource == nil {
+		return nil
+	}
+	return []attributeSet{{span.Resource.Attributes, span.Resource.BinaryAttributes}}
+}
+
+func scopeAttributes(span *internalspan.InternalSpan) []attributeSet {
+	if span.Scope == nil {
+		return nil
+	}
+	return []attributeSet{{span.Scope.Attributes, span.Scope.BinaryAttributes}}
+}
+
+func eventAttributes(span *internalspan.InternalSpan) []attributeSet {
+	if span.Span == nil {
+		return nil
+	}
+	var result []attributeSet
+	for _, e := range span.Span.Events {
+		if e != nil {
+			result = append(result, attributeSet{e.Attributes, e.BinaryAttributes})
+		}
+	}
+	return result
+}
+
+func linkAttributes(span *internalspan.InternalSpan) []attributeSet {
+	if span.Span == nil {
+		return nil
+	}
+	var result []attributeSet
+	for _, l := range span.Span.Links {
+		if l != nil {
+			result = append(result, attributeSet{l.Attributes, l.BinaryAttributes})
+		}
+	}
+	return result
+}
+
+// fieldValues returns the values of the fields of the span model which aren't attributes.
+func fieldValues(span *internalspan.InternalSpan, key string) []any {
+	switch key {
+	case "ingestionTimeUnixNano":
+		return []any{span.IngestionTimeUnixNano}
+	case "externalFields.durationNano", "span.duration":
+		if span.ExternalFields != nil {
+			return []any{span.ExternalFields.DurationNano}
+		}
+		return nil
+	case "externalFields.childCount", "span.childCount":
+		if span.ExternalFields != nil {
+			return []any{span.ExternalFields.ChildCount}
+		}
+		return nil
+	case "span.droppedResourceAttributesCount", "resource.droppedAttributesCount":
+		if span.Resource != nil {
+			return []any{span.Resource.DroppedAttributesCount}
+		}
+		return nil
+	}
+
+	if strings.HasPrefix(key, "scope.") {
+		if span.Scope == nil {
+			return nil
+		}
+		switch key {
+		case "scope.name":
+			return []any{span.Scope.Name}
+		case "scope.version":
+			return []any{span.Scope.Version}
+		case "scope.droppedAttributesCount":
+			return []any{span.Scope.DroppedAttributesCount}
+		}
+		return nil
+	}
+
+	s := span.Span
+	if s == nil {
+		return nil
+	}
+	switch key {
+	case "span.traceId":
+		return []any{s.TraceId}
+	case "span.spanId":
+		return []any{s.SpanId}
+	case "span.traceState":
+		return []any{s.TraceState}
+	case "span.parentSpanId":
+		return []any{s.ParentSpanId}
+	case "span.name":
+		return []any{s.Name}
+	case "span.kind":
+		return []any{s.Kind}
+	case "span.startTimeUnixNano":
+		return []any{s.StartTimeUnixNano}
+	case "span.endTimeUnixNano":
+		return []any{s.EndTimeUnixNano}
+	case "span.droppedAttributesCount":
+		return []any{s.DroppedAttributesCount}
+	case "span.droppedEventsCount":
+		return []any{s.DroppedEventsCount}
+	case "span.droppedLinksCount":
+		return []any{s.DroppedLinksCount}
+	case "span.status.message":
+		if s.Status != nil {
+			return []any{s.Status.Message}
+		}
+	case "span.status.code":
+		if s.Status != nil {
+			return []any{s.Status.Code}
+		}
+	case "span.events.name", "span.events.timeUnixNano", "span.events.droppedAttributesCount", "span.events.spanId":
+		var values []any
+		for _, e := range s.Events {
+			if e == nil {
+				continue
+			}
+			switch key {
+			case "span.events.name":
+				values = append(values, e.Name)
+			case "span.events.timeUnixNano":
+				values = append(values, e.TimeUnixNano)
+			case "span.events.droppedAttributesCount":
+				values = append(values, e.DroppedAttributesCount)
+			default:
+				values = append(values, s.SpanId)
+			}
+		}
+		return values
+	case "span.links.traceId", "span.links.spanId", "span.links.traceState", "span.links.droppedAttributesCount":
+		var values []any
+		for _, l := range s.Links {
+			if l == nil {
+				continue
+			}
+			switch key {
+			case "span.links.traceId":
+				values = append(values, l.TraceId)
+			case "span.links.spanId":
+				values = append(values, l.SpanId)
+			case "span.links.traceState":
+				values = append(values, l.TraceState)
+			default:
+				values = append(values, l.DroppedAttributesCount)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package instrumented
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+var (
+	operationKey = tag.MustNewKey("operation")
+	resultKey    = tag.MustNewKey("result")
+
+	queryLatency = stats.Float64(
+		"teletrace_storage_query_latency",
+		"Latency of queries to the storage",
+		stats.UnitMilliseconds,
+	)
+	queryErrors = stats.Int64(
+		"teletrace_storage_query_errors",
+		"Number of failed queries to the storage",
+		stats.UnitDimensionless,
+	)
+
+	registerViewsOnce sync.Once
+	errRegisterViews  error
+)
+
+func registerViews() error {
+	registerViewsOnce.Do(func() {
+		errRegisterViews = view.Register(
+			&view.View{
+				Name:        queryLatency.Name(),
+				Description: queryLatency.Description(),
+				Measure:     queryLatency,
+				TagKeys:     []tag.Key{operationKey, resultKey},
+				Aggregation: view.Distribution(5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000),
+			},
+			&view.View{
+				Name:        queryErrors.Name(),
+				Description: queryErrors.Description(),
+				Measure:     queryErrors,
+				TagKeys:     []tag.Key{operationKey},
+				Aggregation: view.Sum(),
+			},
+		)
+	})
+	return errRegisterViews
+}
+
+// recordQuery records the latency of a storage query, and counts it as failed if it returned an error
+// other than its cancellation by the caller.
+func recordQuery(ctx context.Context, operation string, start time.Time, err error) {
+	result := resultSuccess
+	if err != nil && !errors.Is(err, context.Canceled) {
+		result = resultFailure
+		_ = stats.RecordWithTags(ctx, []tag.Mutator{tag.Upsert(operationKey, operation)}, queryErrors.M(1))
+	}
+	_ = stats.RecordWithTags(ctx,
+		[]tag.Mutator{tag.Upsert(operationKey, operation), tag.Upsert(resultKey, result)},
+		queryLatency.M(float64(time.Since(start))/float64(time.Millisecond)),
+	)
+}
@@ -0,0 +1,117 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package instrumented
+
+import (
+	"context"
+	"time"
+
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+)
+
+type spanReader struct {
+	next spanreader.SpanReader
+}
+
+// NewSpanReader wraps sr so that the latency and the errors of every storage query are recorded
+// with OpenCensus, tagged by the span reader method under operation.
+func NewSpanReader(sr spanreader.SpanReader) (spanreader.SpanReader, error) {
+	if err := registerViews(); err != nil {
+		return nil, err
+	}
+	return &spanReader{next: sr}, nil
+}
+
+func (sr *spanReader) Initialize() error {
+	return sr.next.Initialize()
+}
+
+func (sr *spanReader) Search(ctx context.Context, r spansquery.SearchRequest) (res *spansquery.SearchResponse, err error) {
+	defer func(start time.Time) { recordQuery(ctx, "search", start, err) }(time.Now())
+	return sr.next.Search(ctx, r)
+}
+
+func (sr *spanReader) GetAvailableTags(
+	ctx context.Context, r tagsquery.GetAvailableTagsRequest,
+) (res *tagsquery.GetAvailableTagsResponse, err error) {
+	defer func(start time.Time) { recordQuery(ctx, "get_available_tags", start, err) }(time.Now())
+	return sr.next.GetAvailableTags(ctx, r)
+}
+
+func (sr *spanReader) GetTagsValues(
+	ctx context.Context, r tagsquery.TagValuesRequest, tags []string,
+) (res map[string]*tagsquery.TagValuesResponse, err error) {
+	defer func(start time.Time) { recordQuery(ctx, "get_tags_values", start, err) }(time.Now())
+	return sr.next.GetTagsValues(ctx, r, tags)
+}
+
+func (sr *spanReader) GetTagsStatistics(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tag string,
+) (res *tagsquery.TagStatisticsResponse, err error) {
+	defer func(start time.Time) { recordQuery(ctx, "get_tags_statistics", start, err) }(time.Now())
+	return sr.next.GetTagsStatistics(ctx, r, tag)
+}
+
+func (sr *spanReader) GetTagsStatisticsBatch(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string,
+) (res map[string]*tagsquery.TagStatisticsResponse, err error) {
+	defer func(start time.Time) { recordQuery(ctx, "get_tags_statistics_batch", start, err) }(time.Now())
+	return sr.next.GetTagsStatisticsBatch(ctx, r, tags)
+}
+
+func (sr *spanReader) SearchEvents(
+	ctx context.Context, r eventsquery.SearchRequest,
+) (res *eventsquery.SearchResponse, err error) {
+	defer func(start time.Time) { recordQuery(ctx, "search_events", start, err) }(time.Now())
+	return sr.next.SearchEvents(ctx, r)
+}
+
+func (sr *spanReader) SearchTraces(
+	ctx context.Context, r spansquery.SearchTracesRequest,
+) (res *spansquery.SearchTracesResponse, err error) {
+	defer func(start time.Time) { recordQuery(ctx, "search_traces", start, err) }(time.Now())
+	return sr.next.SearchTraces(ctx, r)
+}
+
+func (sr *spanReader) Aggregate(
+	ctx context.Context, r spansquery.SearchRequest,
+) (res *spansquery.SearchResponse, err error) {
+	defer func(start time.Time) { recordQuery(ctx, "aggregate", start, err) }(time.Now())
+	return sr.next.Aggregate(ctx, r)
+}
+
+func (sr *spanReader) GetSystemId(
+	ctx context.Context, r metadata.GetSystemIdRequest,
+) (res *metadata.GetSystemIdResponse, err error) {
+	defer func(start time.Time) { recordQuery(ctx, "get_system_id", start, err) }(time.Now())
+	return sr.next.GetSystemId(ctx, r)
+}
+
+func (sr *spanReader) SetSystemId(
+	ctx context.Context, r metadata.SetSystemIdRequest,
+) (res *metadata.SetSystemIdResponse, err error) {
+	defer func(start time.Time) { recordQuery(ctx, "set_system_id", start, err) }(time.Now())
+	return sr.next.SetSystemId(ctx, r)
+}
+
+// Ping isn't recorded, as readiness probes aren't storage queries.
+func (sr *spanReader) Ping(ctx context.Context) error {
+	return sr.next.Ping(ctx)
+}
@@ -0,0 +1,89 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package instrumented
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats/view"
+)
+
+type failingSpanReader struct {
+	spanreader.SpanReader
+	err error
+}
+
+func (sr *failingSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	if sr.err != nil {
+		return nil, sr.err
+	}
+	return sr.SpanReader.Search(ctx, r)
+}
+
+func retrieveErrors(t *testing.T, operation string) int64 {
+	rows, err := view.RetrieveData(queryErrors.Name())
+	assert.NoError(t, err)
+	for _, row := range rows {
+		for _, tag := range row.Tags {
+			if tag.Key == operationKey && tag.Value == operation {
+				return int64(row.Data.(*view.SumData).Value)
+			}
+		}
+	}
+	return 0
+}
+
+func retrieveLatencyCount(t *testing.T, operation string) int64 {
+	rows, err := view.RetrieveData(queryLatency.Name())
+	assert.NoError(t, err)
+	var count int64
+	for _, row := range rows {
+		for _, tag := range row.Tags {
+			if tag.Key == operationKey && tag.Value == operation {
+				count += row.Data.(*view.DistributionData).Count
+			}
+		}
+	}
+	return count
+}
+
+func TestRecordsQueriesAndErrors(t *testing.T) {
+	srMock, err := mock.NewSpanReaderMock()
+	assert.NoError(t, err)
+	backend := &failingSpanReader{SpanReader: srMock}
+	sr, err := NewSpanReader(backend)
+	assert.NoError(t, err)
+	queriesBefore, errorsBefore := retrieveLatencyCount(t, "search"), retrieveErrors(t, "search")
+
+	_, err = sr.Search(context.Background(), spansquery.SearchRequest{})
+	assert.NoError(t, err)
+	backend.err = errors.New("connection refused")
+	_, err = sr.Search(context.Background(), spansquery.SearchRequest{})
+	assert.Error(t, err)
+	backend.err = context.Canceled
+	_, err = sr.Search(context.Background(), spansquery.SearchRequest{})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	assert.Equal(t, queriesBefore+3, retrieveLatencyCount(t, "search"))
+	assert.Equal(t, errorsBefore+1, retrieveErrors(t, "search"), "canceled queries aren't storage errors")
+}
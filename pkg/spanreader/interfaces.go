@@ -19,6 +19,7 @@ package spanreader
 import (
 	"context"
 
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
 	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
 	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
 	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
@@ -30,6 +31,17 @@ type SpanReader interface {
 	GetAvailableTags(ctx context.Context, r tagsquery.GetAvailableTagsRequest) (*tagsquery.GetAvailableTagsResponse, error)
 	GetTagsValues(ctx context.Context, r tagsquery.TagValuesRequest, tags []string) (map[string]*tagsquery.TagValuesResponse, error)
 	GetTagsStatistics(ctx context.Context, r tagsquery.TagStatisticsRequest, tag string) (*tagsquery.TagStatisticsResponse, error)
+	// GetTagsStatisticsBatch computes the statistics of several tags, in a single query where the backend supports it
+	GetTagsStatisticsBatch(ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string) (map[string]*tagsquery.TagStatisticsResponse, error)
+	// SearchEvents searches span events, returning each event with the span owning it
+	SearchEvents(ctx context.Context, r eventsquery.SearchRequest) (*eventsquery.SearchResponse, error)
+	// SearchTraces searches the traces having any span matching the filters, returning each trace with all of its spans
+	SearchTraces(ctx context.Context, r spansquery.SearchTracesRequest) (*spansquery.SearchTracesResponse, error)
+	// Aggregate computes the aggregations of a search of the matching spans, in the storage query where the backend
+	// supports it, and otherwise in memory with AggregateInSpans
+	Aggregate(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error)
 	GetSystemId(ctx context.Context, r metadata.GetSystemIdRequest) (*metadata.GetSystemIdResponse, error)
 	SetSystemId(ctx context.Context, r metadata.SetSystemIdRequest) (*metadata.SetSystemIdResponse, error)
+	// Ping checks that the storage backend is reachable and able to serve queries
+	Ping(ctx context.Context) error
 }
@@ -19,6 +19,7 @@ package mock
 import (
 	"context"
 
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
 	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
 	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
 	"github.com/teletrace/teletrace/pkg/spanreader"
@@ -71,6 +72,14 @@ func (sr spanReader) GetTagsValues(
 				},
 			},
 		},
+		"span.kind": {
+			Values: []tagsquery.TagValueInfo{
+				{
+					Value: "Server",
+					Count: 2,
+				},
+			},
+		},
 	}
 	return res, nil
 }
@@ -86,6 +95,52 @@ func (sr spanReader) GetTagsStatistics(ctx context.Context, r tagsquery.TagStati
 	}, nil
 }
 
+func (sr spanReader) GetTagsStatisticsBatch(ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string) (map[string]*tagsquery.TagStatisticsResponse, error) {
+	res := make(map[string]*tagsquery.TagStatisticsResponse, len(tags))
+	for _, tag := range tags {
+		stats, err := sr.GetTagsStatistics(ctx, r, tag)
+		if err != nil {
+			return nil, err
+		}
+		res[tag] = stats
+	}
+	return res, nil
+}
+
+func (sr spanReader) SearchEvents(ctx context.Context, r eventsquery.SearchRequest) (*eventsquery.SearchResponse, error) {
+	span := spanformatutiltests.GenInternalSpan(nil, nil, nil)
+	return &eventsquery.SearchResponse{
+		Events: []eventsquery.Event{
+			{
+				TraceId:      span.Span.TraceId,
+				SpanId:       span.Span.SpanId,
+				SpanName:     span.Span.Name,
+				Name:         "exception",
+				TimeUnixNano: span.Span.StartTimeUnixNano,
+				Attributes:   map[string]any{"exception.type": "TimeoutError"},
+			},
+		},
+	}, nil
+}
+
+func (sr spanReader) SearchTraces(ctx context.Context, r spansquery.SearchTracesRequest) (*spansquery.SearchTracesResponse, error) {
+	span := spanformatutiltests.GenInternalSpan(nil, nil, nil)
+	return &spansquery.SearchTracesResponse{
+		Metadata: &spansquery.Metadata{},
+		Traces: []*spansquery.TraceResult{
+			{
+				TraceId:                 span.Span.TraceId,
+				LatestMatchTimeUnixNano: span.Span.StartTimeUnixNano,
+				Spans:                   []*internalspan.InternalSpan{span},
+			},
+		},
+	}, nil
+}
+
+func (sr spanReader) Aggregate(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	return spanreader.AggregateInSpans(ctx, sr, r)
+}
+
 func (sr spanReader) Initialize() error {
 	return nil
 }
@@ -98,6 +153,10 @@ func (sr spanReader) SetSystemId(ctx context.Context, r metadata.SetSystemIdRequ
 	return &metadata.SetSystemIdResponse{}, nil
 }
 
+func (sr spanReader) Ping(ctx context.Context) error {
+	return nil
+}
+
 func NewSpanReaderMock() (spanreader.SpanReader, error) {
 	return spanReader{}, nil
 }
@@ -0,0 +1,255 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package percentile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/cache"
+	"github.com/teletrace/teletrace/pkg/model"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/acl"
+)
+
+const (
+	serviceNameTag = "resource.attributes.service.name"
+	operationTag   = "span.name"
+
+	// maxThresholds is the number of cached thresholds, the least recently used is evicted when full
+	maxThresholds = 1000
+)
+
+// operators are the range operators percentile filters are resolved into
+var operators = map[model.FilterOperator]model.FilterOperator{
+	spansquery.OPERATOR_GT_PERCENTILE: spansquery.OPERATOR_GT,
+	spansquery.OPERATOR_LT_PERCENTILE: spansquery.OPERATOR_LT,
+}
+
+type spanReader struct {
+	next spanreader.SpanReader
+	// thresholds are the computed thresholds by filter key and threshold, nil if not cached
+	thresholds *cache.LRUCache
+	now        func() time.Time
+}
+
+// NewSpanReader wraps sr so that percentile filters (gt_percentile and lt_percentile) are resolved into range filters
+// on the percentile of the recent values of their tag, computed from the spans of sr. The computed thresholds are
+// reused for ttl, or computed by every call if ttl isn't positive.
+func NewSpanReader(sr spanreader.SpanReader, ttl time.Duration) spanreader.SpanReader {
+	var thresholds *cache.LRUCache
+	if ttl > 0 {
+		// can't fail, as the ttl is positive
+		thresholds, _ = cache.NewLRUCache(ttl, maxThresholds)
+	}
+	return &spanReader{next: sr, thresholds: thresholds, now: time.Now}
+}
+
+func (sr *spanReader) Initialize() error {
+	return sr.next.Initialize()
+}
+
+func (sr *spanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	filters, err := sr.resolveFilters(ctx, r.SearchFilters)
+	if err != nil {
+		return nil, err
+	}
+	r.SearchFilters = filters
+	return sr.next.Search(ctx, r)
+}
+
+func (sr *spanReader) GetAvailableTags(ctx context.Context, r tagsquery.GetAvailableTagsRequest) (*tagsquery.GetAvailableTagsResponse, error) {
+	return sr.next.GetAvailableTags(ctx, r)
+}
+
+func (sr *spanReader) GetTagsValues(
+	ctx context.Context, r tagsquery.TagValuesRequest, tags []string,
+) (map[string]*tagsquery.TagValuesResponse, error) {
+	filters, err := sr.resolveFilters(ctx, r.SearchFilters)
+	if err != nil {
+		return nil, err
+	}
+	r.SearchFilters = filters
+	return sr.next.GetTagsValues(ctx, r, tags)
+}
+
+func (sr *spanReader) GetTagsStatistics(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tag string,
+) (*tagsquery.TagStatisticsResponse, error) {
+	filters, err := sr.resolveFilters(ctx, r.SearchFilters)
+	if err != nil {
+		return nil, err
+	}
+	r.SearchFilters = filters
+	return sr.next.GetTagsStatistics(ctx, r, tag)
+}
+
+func (sr *spanReader) GetTagsStatisticsBatch(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string,
+) (map[string]*tagsquery.TagStatisticsResponse, error) {
+	filters, err := sr.resolveFilters(ctx, r.SearchFilters)
+	if err != nil {
+		return nil, err
+	}
+	r.SearchFilters = filters
+	return sr.next.GetTagsStatisticsBatch(ctx, r, tags)
+}
+
+func (sr *spanReader) SearchEvents(ctx context.Context, r eventsquery.SearchRequest) (*eventsquery.SearchResponse, error) {
+	filters, err := sr.resolveFilters(ctx, r.SearchFilters)
+	if err != nil {
+		return nil, err
+	}
+	r.SearchFilters = filters
+	return sr.next.SearchEvents(ctx, r)
+}
+
+func (sr *spanReader) SearchTraces(ctx context.Context, r spansquery.SearchTracesRequest) (*spansquery.SearchTracesResponse, error) {
+	filters, err := sr.resolveFilters(ctx, r.SearchFilters)
+	if err != nil {
+		return nil, err
+	}
+	r.SearchFilters = filters
+	return sr.next.SearchTraces(ctx, r)
+}
+
+func (sr *spanReader) Aggregate(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	filters, err := sr.resolveFilters(ctx, r.SearchFilters)
+	if err != nil {
+		return nil, err
+	}
+	r.SearchFilters = filters
+	return sr.next.Aggregate(ctx, r)
+}
+
+func (sr *spanReader) GetSystemId(ctx context.Context, r metadata.GetSystemIdRequest) (*metadata.GetSystemIdResponse, error) {
+	return sr.next.GetSystemId(ctx, r)
+}
+
+func (sr *spanReader) SetSystemId(ctx context.Context, r metadata.SetSystemIdRequest) (*metadata.SetSystemIdResponse, error) {
+	return sr.next.SetSystemId(ctx, r)
+}
+
+func (sr *spanReader) Ping(ctx context.Context) error {
+	return sr.next.Ping(ctx)
+}
+
+// resolveFilters returns filters with the percentile filters replaced by range filters on their computed thresholds.
+// The given filters aren't modified.
+func (sr *spanReader) resolveFilters(ctx context.Context, filters []model.SearchFilter) ([]model.SearchFilter, error) {
+	resolved := make([]model.SearchFilter, len(filters))
+	for i, f := range filters {
+		resolved[i] = f
+		if f.KeyValueFilter == nil {
+			continue
+		}
+		operator, ok := operators[f.KeyValueFilter.Operator]
+		if !ok {
+			continue
+		}
+
+		t, err := spansquery.ParsePercentileThreshold(f.KeyValueFilter.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s filter of %s: %v", spanreader.ErrInvalidQuery, f.KeyValueFilter.Operator, f.KeyValueFilter.Key, err)
+		}
+		threshold, err := sr.threshold(ctx, string(f.KeyValueFilter.Key), *t)
+		if err != nil {
+			return nil, err
+		}
+		resolved[i].KeyValueFilter = &model.KeyValueFilter{Key: f.KeyValueFilter.Key, Operator: operator, Value: threshold}
+	}
+	return resolved, nil
+}
+
+// threshold returns the percentile of the values of tag described by t, from the cache if computed recently.
+func (sr *spanReader) threshold(ctx context.Context, tag string, t spansquery.PercentileThreshold) (float64, error) {
+	key, err := json.Marshal(struct {
+		Tag       string                         `json:"tag"`
+		Threshold spansquery.PercentileThreshold `json:"threshold"`
+	}{tag, t})
+	if err != nil {
+		return 0, err
+	}
+	cacheKey := string(key)
+	if role, ok := acl.RoleFromContext(ctx); ok {
+		// the spans the threshold is computed of are restricted per role
+		cacheKey = role + ":" + cacheKey
+	}
+	if sr.thresholds != nil {
+		if cached, ok := sr.thresholds.Get(cacheKey); ok {
+			return cached.(float64), nil
+		}
+	}
+
+	threshold, err := sr.computeThreshold(ctx, tag, t)
+	if err != nil {
+		return 0, err
+	}
+	if sr.thresholds != nil {
+		sr.thresholds.Set(cacheKey, threshold)
+	}
+	return threshold, nil
+}
+
+// computeThreshold aggregates the values of tag over the spans of the service and operation of t within its window.
+// The threshold isn't computed from only some of the spans, when a backend aggregating in memory has more than
+// spansquery.MaxAggregatedSpans spans within the window.
+func (sr *spanReader) computeThreshold(ctx context.Context, tag string, t spansquery.PercentileThreshold) (float64, error) {
+	timeframe := t.Timeframe()
+	// the window was validated along with the threshold, so it resolves
+	_ = timeframe.Resolve(sr.now())
+	var filters []model.SearchFilter
+	if t.Service != "" {
+		filters = append(filters, equalsFilter(serviceNameTag, t.Service))
+	}
+	if t.Operation != "" {
+		filters = append(filters, equalsFilter(operationTag, t.Operation))
+	}
+	metric := spansquery.Metric{Function: t.Percentile, Field: tag}
+
+	res, err := sr.next.Aggregate(ctx, spansquery.SearchRequest{
+		Timeframe:     timeframe,
+		SearchFilters: filters,
+		Aggregations:  &spansquery.Aggregations{Metrics: []spansquery.Metric{metric}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if res.Aggregations.Truncated {
+		return 0, fmt.Errorf("%w: more than %d spans within the last %s to compute the %s of %s from, narrow the window",
+			spanreader.ErrInvalidQuery, spansquery.MaxAggregatedSpans, t.Window, t.Percentile, tag)
+	}
+	for _, group := range res.Aggregations.Groups {
+		if threshold, ok := group.Metrics[metric.Name()]; ok {
+			return threshold, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: no numeric values of %s within the last %s to compute its %s from",
+		spanreader.ErrInvalidQuery, tag, t.Window, t.Percentile)
+}
+
+func equalsFilter(key string, value string) model.SearchFilter {
+	return model.SearchFilter{KeyValueFilter: &model.KeyValueFilter{
+		Key: model.FilterKey(key), Operator: spansquery.OPERATOR_EQUALS, Value: value,
+	}}
+}
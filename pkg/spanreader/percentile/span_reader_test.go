@@ -0,0 +1,165 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package percentile
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/acl"
+	"github.com/teletrace/teletrace/pkg/spanreader/inmemory"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Unix(1000000, 0)
+
+type inMemorySpanReader struct {
+	spanreader.SpanReader
+	spans    []*internalspan.InternalSpan
+	searches int
+	filters  []model.SearchFilter
+}
+
+func (sr *inMemorySpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	sr.searches++
+	sr.filters = r.SearchFilters
+	return inmemory.Search(sr.spans, r)
+}
+
+func (sr *inMemorySpanReader) Aggregate(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	return spanreader.AggregateInSpans(ctx, sr, r)
+}
+
+// truncatedSpanReader aggregates more spans than the in-memory aggregations do.
+type truncatedSpanReader struct {
+	*inMemorySpanReader
+}
+
+func (sr truncatedSpanReader) Aggregate(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	res, err := sr.inMemorySpanReader.Aggregate(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	res.Aggregations.Truncated = true
+	return res, nil
+}
+
+func newSpan(service string, name string, startTime time.Time, duration time.Duration) *internalspan.InternalSpan {
+	return &internalspan.InternalSpan{
+		Resource: &internalspan.Resource{Attributes: internalspan.Attributes{"service.name": service}},
+		Span: &internalspan.Span{
+			SpanId:            name + startTime.String(),
+			Name:              name,
+			StartTimeUnixNano: uint64(startTime.UnixNano()),
+			EndTimeUnixNano:   uint64(startTime.Add(duration).UnixNano()),
+		},
+		ExternalFields: &internalspan.ExternalFields{DurationNano: uint64(duration)},
+	}
+}
+
+func newSpanReader(t *testing.T, ttl time.Duration) (spanreader.SpanReader, *inMemorySpanReader) {
+	srMock, err := mock.NewSpanReaderMock()
+	assert.NoError(t, err)
+	next := &inMemorySpanReader{SpanReader: srMock}
+	for i := 1; i <= 10; i++ {
+		next.spans = append(next.spans, newSpan("checkout", "GET /cart", now.Add(-time.Duration(i)*time.Minute), time.Duration(i)*time.Millisecond))
+	}
+	// slower, and out of the window of the thresholds below
+	next.spans = append(next.spans, newSpan("checkout", "GET /cart", now.Add(-2*time.Hour), time.Second))
+	next.spans = append(next.spans, newSpan("billing", "GET /cart", now.Add(-time.Minute), time.Second))
+	sr := NewSpanReader(next, ttl)
+	sr.(*spanReader).now = func() time.Time { return now }
+	return sr, next
+}
+
+func percentileFilter(operator string, value any) model.SearchFilter {
+	return model.SearchFilter{KeyValueFilter: &model.KeyValueFilter{
+		Key: "externalFields.durationNano", Operator: model.FilterOperator(operator), Value: value,
+	}}
+}
+
+func TestResolvesPercentileFilters(t *testing.T) {
+	sr, next := newSpanReader(t, 0)
+	threshold := map[string]any{"percentile": "p90", "service": "checkout", "operation": "GET /cart"}
+
+	res, err := sr.Search(context.Background(), spansquery.SearchRequest{
+		Timeframe:     model.Timeframe{EndTime: uint64(now.UnixNano())},
+		SearchFilters: []model.SearchFilter{percentileFilter(spansquery.OPERATOR_GT_PERCENTILE, threshold)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.SearchFilter{percentileFilter(spansquery.OPERATOR_GT, float64(9100*time.Microsecond))}, next.filters)
+	assert.Len(t, res.Spans, 3)
+
+	_, err = sr.Search(context.Background(), spansquery.SearchRequest{
+		Timeframe:     model.Timeframe{EndTime: uint64(now.UnixNano())},
+		SearchFilters: []model.SearchFilter{percentileFilter(spansquery.OPERATOR_LT_PERCENTILE, map[string]any{"percentile": "p50"})},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.SearchFilter{percentileFilter(spansquery.OPERATOR_LT, float64(6*time.Millisecond))}, next.filters)
+}
+
+func TestCachesThresholds(t *testing.T) {
+	sr, next := newSpanReader(t, time.Minute)
+	r := spansquery.SearchRequest{
+		SearchFilters: []model.SearchFilter{percentileFilter(spansquery.OPERATOR_GT_PERCENTILE, map[string]any{"percentile": "p95"})},
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := sr.Search(context.Background(), r)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2+1, next.searches, "the threshold is computed once")
+
+	_, err := sr.Search(acl.WithRole(context.Background(), "viewer"), r)
+	assert.NoError(t, err)
+	assert.Equal(t, 3+2, next.searches, "thresholds are computed per role")
+}
+
+func TestInvalidPercentileFilters(t *testing.T) {
+	sr, _ := newSpanReader(t, 0)
+	for name, value := range map[string]any{
+		"unknown percentile": map[string]any{"percentile": "p42"},
+		"invalid window":     map[string]any{"percentile": "p95", "window": "hour"},
+		"unknown field":      map[string]any{"percentile": "p95", "services": "checkout"},
+		"no values":          map[string]any{"percentile": "p95", "service": "payments"},
+	} {
+		_, err := sr.Search(context.Background(), spansquery.SearchRequest{
+			SearchFilters: []model.SearchFilter{percentileFilter(spansquery.OPERATOR_GT_PERCENTILE, value)},
+		})
+		assert.True(t, errors.Is(err, spanreader.ErrInvalidQuery), name)
+	}
+}
+
+func TestTruncatedThresholds(t *testing.T) {
+	_, next := newSpanReader(t, 0)
+	sr := NewSpanReader(truncatedSpanReader{next}, 0)
+	sr.(*spanReader).now = func() time.Time { return now }
+
+	_, err := sr.Search(context.Background(), spansquery.SearchRequest{
+		SearchFilters: []model.SearchFilter{percentileFilter(spansquery.OPERATOR_GT_PERCENTILE, map[string]any{"percentile": "p95"})},
+	})
+	assert.True(t, errors.Is(err, spanreader.ErrInvalidQuery))
+	assert.Equal(t, 1, next.searches, "the spans aren't searched without the threshold")
+}
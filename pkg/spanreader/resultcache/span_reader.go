@@ -0,0 +1,177 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resultcache
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/cache"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+)
+
+// Config holds the settings of the search result cache.
+type Config struct {
+	// TTL is the duration for which a search result is served from the cache
+	TTL time.Duration
+	// MaxEntries is the maximum number of cached search results, the least recently used is evicted when full
+	MaxEntries int
+	// SettlePeriod is how long before now the time range of a search must end for its result to be cached,
+	// as recent spans may still be ingested
+	SettlePeriod time.Duration
+}
+
+type spanReader struct {
+	next         spanreader.SpanReader
+	cache        *cache.LRUCache
+	settlePeriod time.Duration
+	now          func() time.Time
+}
+
+// NewSpanReader wraps sr with a cache of the results of searches over completed time ranges, keyed by the normalized
+// search request, so repeatedly refreshed historical queries don't reach the storage backend.
+func NewSpanReader(sr spanreader.SpanReader, cfg Config) (spanreader.SpanReader, error) {
+	c, err := cache.NewLRUCache(cfg.TTL, cfg.MaxEntries)
+	if err != nil {
+		return nil, err
+	}
+	return &spanReader{next: sr, cache: c, settlePeriod: cfg.SettlePeriod, now: time.Now}, nil
+}
+
+func (sr *spanReader) Initialize() error {
+	return sr.next.Initialize()
+}
+
+func (sr *spanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	if !sr.cacheable(r) {
+		return sr.next.Search(ctx, r)
+	}
+	key, err := cacheKey(r)
+	if err != nil {
+		return sr.next.Search(ctx, r)
+	}
+
+	if cached, ok := sr.cache.Get(key); ok {
+		return copyResponse(cached.(*spansquery.SearchResponse)), nil
+	}
+	res, err := sr.next.Search(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	sr.cache.Set(key, copyResponse(res))
+	return res, nil
+}
+
+// cacheable returns whether the result of r is stable, so it can be cached.
+func (sr *spanReader) cacheable(r spansquery.SearchRequest) bool {
+	if r.Debug {
+		// debug results describe the query as run on the storage backend
+		return false
+	}
+	if r.Sample != nil && r.Sample.Seed == nil {
+		// unseeded samples are random
+		return false
+	}
+	settled := uint64(sr.now().Add(-sr.settlePeriod).UnixNano())
+	return r.Timeframe.EndTime != 0 && r.Timeframe.EndTime <= settled
+}
+
+// cacheKey returns the key of r, normalized so the same search with its filters in a different order shares a key.
+func cacheKey(r spansquery.SearchRequest) (string, error) {
+	filters := make([]string, len(r.SearchFilters))
+	for i, f := range r.SearchFilters {
+		filter, err := json.Marshal(f)
+		if err != nil {
+			return "", err
+		}
+		filters[i] = string(filter)
+	}
+	sort.Strings(filters)
+
+	r.SearchFilters = nil
+	req, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	filtersKey, err := json.Marshal(filters)
+	if err != nil {
+		return "", err
+	}
+	return string(req) + string(filtersKey), nil
+}
+
+// copyResponse returns a copy of res which can be modified without changing the cached response, such as by
+// deduplicating its spans. The spans themselves are shared and must not be modified.
+func copyResponse(res *spansquery.SearchResponse) *spansquery.SearchResponse {
+	copied := *res
+	copied.Spans = append([]*internalspan.InternalSpan(nil), res.Spans...)
+	return &copied
+}
+
+func (sr *spanReader) GetAvailableTags(
+	ctx context.Context, r tagsquery.GetAvailableTagsRequest,
+) (*tagsquery.GetAvailableTagsResponse, error) {
+	return sr.next.GetAvailableTags(ctx, r)
+}
+
+func (sr *spanReader) GetTagsValues(
+	ctx context.Context, r tagsquery.TagValuesRequest, tags []string,
+) (map[string]*tagsquery.TagValuesResponse, error) {
+	return sr.next.GetTagsValues(ctx, r, tags)
+}
+
+func (sr *spanReader) GetTagsStatistics(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tag string,
+) (*tagsquery.TagStatisticsResponse, error) {
+	return sr.next.GetTagsStatistics(ctx, r, tag)
+}
+
+func (sr *spanReader) GetTagsStatisticsBatch(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string,
+) (map[string]*tagsquery.TagStatisticsResponse, error) {
+	return sr.next.GetTagsStatisticsBatch(ctx, r, tags)
+}
+
+func (sr *spanReader) SearchEvents(ctx context.Context, r eventsquery.SearchRequest) (*eventsquery.SearchResponse, error) {
+	return sr.next.SearchEvents(ctx, r)
+}
+
+func (sr *spanReader) SearchTraces(ctx context.Context, r spansquery.SearchTracesRequest) (*spansquery.SearchTracesResponse, error) {
+	return sr.next.SearchTraces(ctx, r)
+}
+
+func (sr *spanReader) Aggregate(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	return sr.next.Aggregate(ctx, r)
+}
+
+func (sr *spanReader) GetSystemId(ctx context.Context, r metadata.GetSystemIdRequest) (*metadata.GetSystemIdResponse, error) {
+	return sr.next.GetSystemId(ctx, r)
+}
+
+func (sr *spanReader) SetSystemId(ctx context.Context, r metadata.SetSystemIdRequest) (*metadata.SetSystemIdResponse, error) {
+	return sr.next.SetSystemId(ctx, r)
+}
+
+func (sr *spanReader) Ping(ctx context.Context) error {
+	return sr.next.Ping(ctx)
+}
@@ -0,0 +1,113 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resultcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingSpanReader struct {
+	spanreader.SpanReader
+	searches int
+}
+
+func (sr *countingSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	sr.searches++
+	return &spansquery.SearchResponse{Spans: []*internalspan.InternalSpan{{Span: &internalspan.Span{SpanId: "a"}}}}, nil
+}
+
+var now = time.Unix(1700000000, 0)
+
+func newCachedSpanReader(t *testing.T) (spanreader.SpanReader, *countingSpanReader) {
+	srMock, err := mock.NewSpanReaderMock()
+	assert.NoError(t, err)
+	counting := &countingSpanReader{SpanReader: srMock}
+	sr, err := NewSpanReader(counting, Config{TTL: time.Minute, MaxEntries: 10, SettlePeriod: time.Minute})
+	assert.NoError(t, err)
+	sr.(*spanReader).now = func() time.Time { return now }
+	return sr, counting
+}
+
+func newFilter(key string, value string) model.SearchFilter {
+	return model.SearchFilter{
+		KeyValueFilter: &model.KeyValueFilter{Key: model.FilterKey(key), Operator: spansquery.OPERATOR_EQUALS, Value: value},
+	}
+}
+
+func timeframe(end time.Time) model.Timeframe {
+	return model.Timeframe{StartTime: uint64(end.Add(-time.Hour).UnixNano()), EndTime: uint64(end.UnixNano())}
+}
+
+func TestCachesCompletedTimeframes(t *testing.T) {
+	sr, counting := newCachedSpanReader(t)
+	r := spansquery.SearchRequest{
+		Timeframe:     timeframe(now.Add(-time.Hour)),
+		SearchFilters: []model.SearchFilter{newFilter("span.name", "GET"), newFilter("resource.attributes.service.name", "checkout")},
+	}
+
+	res, err := sr.Search(context.Background(), r)
+	assert.NoError(t, err)
+	res.Spans = nil
+
+	reordered := r
+	reordered.SearchFilters = []model.SearchFilter{r.SearchFilters[1], r.SearchFilters[0]}
+	res, err = sr.Search(context.Background(), reordered)
+	assert.NoError(t, err)
+	assert.Len(t, res.Spans, 1, "modifying a response mustn't change the cached one")
+	assert.Equal(t, 1, counting.searches)
+
+	other := r
+	other.SearchFilters = []model.SearchFilter{newFilter("span.name", "POST")}
+	_, err = sr.Search(context.Background(), other)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, counting.searches)
+}
+
+func TestSkipsUnstableSearches(t *testing.T) {
+	seed := int64(1)
+	requests := map[string]spansquery.SearchRequest{
+		"no end time":      {Timeframe: model.Timeframe{StartTime: uint64(now.Add(-time.Hour).UnixNano())}},
+		"unsettled":        {Timeframe: timeframe(now.Add(-time.Second))},
+		"debug":            {Timeframe: timeframe(now.Add(-time.Hour)), Debug: true},
+		"unseeded sample":  {Timeframe: timeframe(now.Add(-time.Hour)), Sample: &spansquery.Sample{Size: 10}},
+		"seeded sample":    {Timeframe: timeframe(now.Add(-time.Hour)), Sample: &spansquery.Sample{Size: 10, Seed: &seed}},
+		"completed search": {Timeframe: timeframe(now.Add(-time.Hour))},
+	}
+	expected := map[string]int{
+		"no end time": 2, "unsettled": 2, "debug": 2, "unseeded sample": 2, "seeded sample": 1, "completed search": 1,
+	}
+
+	for name, r := range requests {
+		t.Run(name, func(t *testing.T) {
+			sr, counting := newCachedSpanReader(t)
+			for i := 0; i < 2; i++ {
+				_, err := sr.Search(context.Background(), r)
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, expected[name], counting.searches)
+		})
+	}
+}
@@ -0,0 +1,303 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package servicealias
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+)
+
+const (
+	serviceNameAttribute = "service.name"
+	serviceNameTag       = "resource.attributes." + serviceNameAttribute
+)
+
+// ParseAliases parses comma separated alias=service pairs, e.g. "checkout-service=checkout,billing=payments",
+// into the services by alias.
+func ParseAliases(value string) (map[string]string, error) {
+	aliases := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		i := strings.Index(pair, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid service alias %q, expected alias=service", pair)
+		}
+		alias, service := strings.TrimSpace(pair[:i]), strings.TrimSpace(pair[i+1:])
+		if alias == "" || service == "" {
+			return nil, fmt.Errorf("invalid service alias %q, expected alias=service", pair)
+		}
+		aliases[alias] = service
+	}
+	for alias, service := range aliases {
+		if _, ok := aliases[service]; ok {
+			return nil, fmt.Errorf("service alias %q refers to %q, which is an alias itself", alias, service)
+		}
+	}
+	return aliases, nil
+}
+
+type spanReader struct {
+	next spanreader.SpanReader
+	// aliases are the services by alias
+	aliases map[string]string
+	// names are the service and its aliases by service, sorted
+	names map[string][]string
+}
+
+// NewSpanReader wraps sr so that the aliases of a service are queried and returned as the service, given the
+// services by alias, for spans stored before the service was renamed or emitted under a legacy name.
+func NewSpanReader(sr spanreader.SpanReader, aliases map[string]string) spanreader.SpanReader {
+	names := make(map[string][]string)
+	for alias, service := range aliases {
+		if _, ok := names[service]; !ok {
+			names[service] = []string{service}
+		}
+		names[service] = append(names[service], alias)
+	}
+	for _, n := range names {
+		sort.Strings(n)
+	}
+	return &spanReader{next: sr, aliases: aliases, names: names}
+}
+
+func (sr *spanReader) Initialize() error {
+	return sr.next.Initialize()
+}
+
+func (sr *spanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	r.SearchFilters = sr.expandFilters(r.SearchFilters)
+	res, err := sr.next.Search(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	res.Spans = sr.renameSpans(res.Spans)
+	return res, nil
+}
+
+func (sr *spanReader) GetAvailableTags(ctx context.Context, r tagsquery.GetAvailableTagsRequest) (*tagsquery.GetAvailableTagsResponse, error) {
+	return sr.next.GetAvailableTags(ctx, r)
+}
+
+func (sr *spanReader) GetTagsValues(
+	ctx context.Context, r tagsquery.TagValuesRequest, tags []string,
+) (map[string]*tagsquery.TagValuesResponse, error) {
+	r.SearchFilters = sr.expandFilters(r.SearchFilters)
+	res, err := sr.next.GetTagsValues(ctx, r, tags)
+	if err != nil {
+		return nil, err
+	}
+	if values, ok := res[serviceNameTag]; ok && values != nil {
+		merged := *values
+		merged.Values = sr.mergeTagValues(values.Values, r)
+		res[serviceNameTag] = &merged
+	}
+	return res, nil
+}
+
+func (sr *spanReader) GetTagsStatistics(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tag string,
+) (*tagsquery.TagStatisticsResponse, error) {
+	r.SearchFilters = sr.expandFilters(r.SearchFilters)
+	return sr.next.GetTagsStatistics(ctx, r, tag)
+}
+
+func (sr *spanReader) GetTagsStatisticsBatch(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string,
+) (map[string]*tagsquery.TagStatisticsResponse, error) {
+	r.SearchFilters = sr.expandFilters(r.SearchFilters)
+	return sr.next.GetTagsStatisticsBatch(ctx, r, tags)
+}
+
+func (sr *spanReader) SearchEvents(ctx context.Context, r eventsquery.SearchRequest) (*eventsquery.SearchResponse, error) {
+	r.SearchFilters = sr.expandFilters(r.SearchFilters)
+	res, err := sr.next.SearchEvents(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]eventsquery.Event, len(res.Events))
+	for i, event := range res.Events {
+		if service, ok := sr.aliases[event.ServiceName]; ok {
+			event.ServiceName = service
+		}
+		events[i] = event
+	}
+	return &eventsquery.SearchResponse{Events: events}, nil
+}
+
+func (sr *spanReader) SearchTraces(ctx context.Context, r spansquery.SearchTracesRequest) (*spansquery.SearchTracesResponse, error) {
+	r.SearchFilters = sr.expandFilters(r.SearchFilters)
+	res, err := sr.next.SearchTraces(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	for _, trace := range res.Traces {
+		trace.Spans = sr.renameSpans(trace.Spans)
+	}
+	return res, nil
+}
+
+// Aggregate aggregates the renamed spans in memory when grouped by service, so the groups of the aliases of a
+// service are merged, and otherwise in the storage with the service name filters expanded.
+func (sr *spanReader) Aggregate(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	if r.Aggregations.GroupBy == serviceNameTag {
+		return spanreader.AggregateInSpans(ctx, sr, r)
+	}
+	r.SearchFilters = sr.expandFilters(r.SearchFilters)
+	return sr.next.Aggregate(ctx, r)
+}
+
+func (sr *spanReader) GetSystemId(ctx context.Context, r metadata.GetSystemIdRequest) (*metadata.GetSystemIdResponse, error) {
+	return sr.next.GetSystemId(ctx, r)
+}
+
+func (sr *spanReader) SetSystemId(ctx context.Context, r metadata.SetSystemIdRequest) (*metadata.SetSystemIdResponse, error) {
+	return sr.next.SetSystemId(ctx, r)
+}
+
+func (sr *spanReader) Ping(ctx context.Context) error {
+	return sr.next.Ping(ctx)
+}
+
+// serviceNames returns the names of the service of name, i.e. the service and its aliases.
+func (sr *spanReader) serviceNames(name string) []string {
+	service := name
+	if s, ok := sr.aliases[name]; ok {
+		service = s
+	}
+	if names, ok := sr.names[service]; ok {
+		return names
+	}
+	return []string{name}
+}
+
+// expandFilters returns filters with the service name filters matching the aliases of their services as well,
+// i.e. equals and not_equals become in and not_in. The given filters aren't modified.
+func (sr *spanReader) expandFilters(filters []model.SearchFilter) []model.SearchFilter {
+	expanded := make([]model.SearchFilter, len(filters))
+	for i, f := range filters {
+		expanded[i] = f
+		if f.KeyValueFilter == nil || f.KeyValueFilter.Key != serviceNameTag {
+			continue
+		}
+
+		kv := *f.KeyValueFilter
+		switch kv.Operator {
+		case spansquery.OPERATOR_EQUALS, spansquery.OPERATOR_NOT_EQUALS:
+			name, ok := kv.Value.(string)
+			if !ok || len(sr.serviceNames(name)) == 1 {
+				continue
+			}
+			kv.Operator = spansquery.OPERATOR_IN
+			if f.KeyValueFilter.Operator == spansquery.OPERATOR_NOT_EQUALS {
+				kv.Operator = spansquery.OPERATOR_NOT_IN
+			}
+			kv.Value = toValues(sr.serviceNames(name))
+		case spansquery.OPERATOR_IN, spansquery.OPERATOR_NOT_IN:
+			values, ok := kv.Value.([]any)
+			if !ok {
+				continue
+			}
+			var names []any
+			for _, v := range values {
+				if name, ok := v.(string); ok {
+					names = append(names, toValues(sr.serviceNames(name))...)
+				} else {
+					names = append(names, v)
+				}
+			}
+			kv.Value = names
+		default:
+			continue
+		}
+		expanded[i].KeyValueFilter = &kv
+	}
+	return expanded
+}
+
+func toValues(names []string) []any {
+	values := make([]any, len(names))
+	for i, name := range names {
+		values[i] = name
+	}
+	return values
+}
+
+// renameSpans returns spans with the service names of their resources replaced by the services of the aliases.
+// The spans returned by the wrapped reader aren't modified.
+func (sr *spanReader) renameSpans(spans []*internalspan.InternalSpan) []*internalspan.InternalSpan {
+	renamed := make([]*internalspan.InternalSpan, len(spans))
+	for i, span := range spans {
+		renamed[i] = span
+		if span.Resource == nil {
+			continue
+		}
+		name, _ := span.Resource.Attributes[serviceNameAttribute].(string)
+		service, ok := sr.aliases[name]
+		if !ok {
+			continue
+		}
+
+		resource := *span.Resource
+		resource.Attributes = make(internalspan.Attributes, len(span.Resource.Attributes))
+		for key, value := range span.Resource.Attributes {
+			resource.Attributes[key] = value
+		}
+		resource.Attributes[serviceNameAttribute] = service
+
+		s := *span
+		s.Resource = &resource
+		renamed[i] = &s
+	}
+	return renamed
+}
+
+// mergeTagValues returns the service name values with the counts of aliases added to their services,
+// in the sort order of r.
+func (sr *spanReader) mergeTagValues(values []tagsquery.TagValueInfo, r tagsquery.TagValuesRequest) []tagsquery.TagValueInfo {
+	merged := make([]tagsquery.TagValueInfo, 0, len(values))
+	indexes := make(map[string]int, len(values))
+	for _, v := range values {
+		if name, ok := v.Value.(string); ok {
+			if service, ok := sr.aliases[name]; ok {
+				v.Value = service
+				name = service
+			}
+			if i, ok := indexes[name]; ok {
+				merged[i].Count += v.Count
+				if v.LastSeenUnixNano > merged[i].LastSeenUnixNano {
+					merged[i].LastSeenUnixNano = v.LastSeenUnixNano
+				}
+				continue
+			}
+			indexes[name] = len(merged)
+		}
+		merged = append(merged, v)
+	}
+	tagsquery.SortValues(merged, r)
+	return merged
+}
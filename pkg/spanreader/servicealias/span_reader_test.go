@@ -0,0 +1,154 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package servicealias
+
+import (
+	"context"
+	"testing"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingSpanReader struct {
+	spanreader.SpanReader
+	spans   []*internalspan.InternalSpan
+	values  []tagsquery.TagValueInfo
+	events  []eventsquery.Event
+	filters []model.SearchFilter
+}
+
+func (sr *recordingSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	sr.filters = r.SearchFilters
+	return &spansquery.SearchResponse{Spans: sr.spans}, nil
+}
+
+func (sr *recordingSpanReader) GetTagsValues(
+	ctx context.Context, r tagsquery.TagValuesRequest, tags []string,
+) (map[string]*tagsquery.TagValuesResponse, error) {
+	sr.filters = r.SearchFilters
+	return map[string]*tagsquery.TagValuesResponse{serviceNameTag: {Values: sr.values}}, nil
+}
+
+func (sr *recordingSpanReader) SearchEvents(ctx context.Context, r eventsquery.SearchRequest) (*eventsquery.SearchResponse, error) {
+	sr.filters = r.SearchFilters
+	return &eventsquery.SearchResponse{Events: sr.events}, nil
+}
+
+func newSpanReader(t *testing.T) (spanreader.SpanReader, *recordingSpanReader) {
+	srMock, err := mock.NewSpanReaderMock()
+	assert.NoError(t, err)
+	next := &recordingSpanReader{SpanReader: srMock}
+	return NewSpanReader(next, map[string]string{"checkout-service": "checkout", "checkout-legacy": "checkout"}), next
+}
+
+func serviceFilter(operator string, value any) model.SearchFilter {
+	return model.SearchFilter{KeyValueFilter: &model.KeyValueFilter{
+		Key: serviceNameTag, Operator: model.FilterOperator(operator), Value: value,
+	}}
+}
+
+func TestParseAliases(t *testing.T) {
+	aliases, err := ParseAliases(" checkout-service=checkout, billing = payments,")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"checkout-service": "checkout", "billing": "payments"}, aliases)
+
+	for _, value := range []string{"checkout", "=checkout", "checkout-service=", "a=b,b=c"} {
+		_, err := ParseAliases(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestSearchExpandsServiceNameFilters(t *testing.T) {
+	sr, next := newSpanReader(t)
+	filters := []model.SearchFilter{
+		serviceFilter(spansquery.OPERATOR_EQUALS, "checkout"),
+		serviceFilter(spansquery.OPERATOR_NOT_EQUALS, "checkout-legacy"),
+		serviceFilter(spansquery.OPERATOR_IN, []any{"frontend", "checkout-service"}),
+		serviceFilter(spansquery.OPERATOR_EQUALS, "frontend"),
+		serviceFilter(spansquery.OPERATOR_CONTAINS, "checkout"),
+	}
+
+	_, err := sr.Search(context.Background(), spansquery.SearchRequest{SearchFilters: filters})
+	assert.NoError(t, err)
+
+	checkout := []any{"checkout", "checkout-legacy", "checkout-service"}
+	assert.Equal(t, []model.SearchFilter{
+		serviceFilter(spansquery.OPERATOR_IN, checkout),
+		serviceFilter(spansquery.OPERATOR_NOT_IN, checkout),
+		serviceFilter(spansquery.OPERATOR_IN, append([]any{"frontend"}, checkout...)),
+		serviceFilter(spansquery.OPERATOR_EQUALS, "frontend"),
+		serviceFilter(spansquery.OPERATOR_CONTAINS, "checkout"),
+	}, next.filters)
+	assert.Equal(t, spansquery.OPERATOR_EQUALS, string(filters[0].KeyValueFilter.Operator), "the request filters shouldn't be modified")
+}
+
+func TestSearchRenamesAliasedServices(t *testing.T) {
+	sr, next := newSpanReader(t)
+	legacy := &internalspan.InternalSpan{
+		Resource: &internalspan.Resource{Attributes: internalspan.Attributes{"service.name": "checkout-legacy", "host.name": "a"}},
+		Span:     &internalspan.Span{SpanId: "1"},
+	}
+	frontend := &internalspan.InternalSpan{
+		Resource: &internalspan.Resource{Attributes: internalspan.Attributes{"service.name": "frontend"}},
+		Span:     &internalspan.Span{SpanId: "2"},
+	}
+	next.spans = []*internalspan.InternalSpan{legacy, frontend}
+
+	res, err := sr.Search(context.Background(), spansquery.SearchRequest{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, internalspan.Attributes{"service.name": "checkout", "host.name": "a"}, res.Spans[0].Resource.Attributes)
+	assert.Same(t, frontend, res.Spans[1])
+	assert.Equal(t, "checkout-legacy", legacy.Resource.Attributes["service.name"], "the stored spans shouldn't be modified")
+}
+
+func TestTagsValuesMergesAliasedServices(t *testing.T) {
+	sr, next := newSpanReader(t)
+	next.values = []tagsquery.TagValueInfo{
+		{Value: "frontend", Count: 5},
+		{Value: "checkout", Count: 3},
+		{Value: "checkout-legacy", Count: 2},
+		{Value: "checkout-service", Count: 1},
+	}
+
+	res, err := sr.GetTagsValues(context.Background(), tagsquery.TagValuesRequest{}, []string{serviceNameTag})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []tagsquery.TagValueInfo{{Value: "checkout", Count: 6}, {Value: "frontend", Count: 5}}, res[serviceNameTag].Values)
+}
+
+func TestSearchEventsRenamesAliasedServices(t *testing.T) {
+	sr, next := newSpanReader(t)
+	next.events = []eventsquery.Event{{ServiceName: "checkout-service"}, {ServiceName: "frontend"}}
+
+	res, err := sr.SearchEvents(context.Background(), eventsquery.SearchRequest{
+		SearchFilters: []model.SearchFilter{serviceFilter(spansquery.OPERATOR_EQUALS, "checkout")},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "checkout", res.Events[0].ServiceName)
+	assert.Equal(t, "frontend", res.Events[1].ServiceName)
+	assert.Equal(t, spansquery.OPERATOR_IN, string(next.filters[0].KeyValueFilter.Operator))
+	assert.Equal(t, "checkout-service", next.events[0].ServiceName)
+}
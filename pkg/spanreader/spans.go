@@ -0,0 +1,82 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spanreader
+
+import (
+	"context"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// CopyFilters copies filters, including their key value filters. Span readers may modify the filters they get,
+// so filters reused across searches are copied for every search.
+func CopyFilters(filters []model.SearchFilter) []model.SearchFilter {
+	result := make([]model.SearchFilter, 0, len(filters))
+	for _, f := range filters {
+		if f.KeyValueFilter != nil {
+			kv := *f.KeyValueFilter
+			f.KeyValueFilter = &kv
+		}
+		result = append(result, f)
+	}
+	return result
+}
+
+// TraceSpans returns all the spans of a trace, reading every page of the search.
+func TraceSpans(ctx context.Context, sr SpanReader, traceId string) ([]*internalspan.InternalSpan, error) {
+	var spans []*internalspan.InternalSpan
+	var token spansquery.ContinuationToken
+	for {
+		res, err := sr.Search(ctx, spansquery.SearchRequest{
+			Timeframe: model.Timeframe{
+				StartTime: 0,
+				EndTime:   uint64(time.Now().UnixNano()),
+			},
+			SearchFilters: []model.SearchFilter{
+				{
+					KeyValueFilter: &model.KeyValueFilter{
+						Key:      "span.traceId",
+						Operator: spansquery.OPERATOR_EQUALS,
+						Value:    traceId,
+					},
+				},
+			},
+			Metadata: &spansquery.Metadata{NextToken: token},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		spans = append(spans, res.Spans...)
+		if len(res.Spans) == 0 || res.Metadata == nil || res.Metadata.NextToken == "" {
+			return spans, nil
+		}
+		token = res.Metadata.NextToken
+	}
+}
+
+// ServiceName returns the service.name resource attribute of a span, or an empty string if it has none.
+func ServiceName(span *internalspan.InternalSpan) string {
+	if span.Resource == nil {
+		return ""
+	}
+	service, _ := span.Resource.Attributes["service.name"].(string)
+	return service
+}
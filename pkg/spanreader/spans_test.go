@@ -0,0 +1,82 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spanreader_test
+
+import (
+	"context"
+	"testing"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pagedSpanReader returns the spans of a trace one per page.
+type pagedSpanReader struct {
+	spanreader.SpanReader
+	spanIds []string
+}
+
+func (sr *pagedSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	page := 0
+	if r.Metadata != nil && r.Metadata.NextToken != "" {
+		page = int(r.Metadata.NextToken[0] - '0')
+	}
+	res := &spansquery.SearchResponse{Metadata: &spansquery.Metadata{}, Spans: []*internalspan.InternalSpan{{
+		Span: &internalspan.Span{TraceId: r.SearchFilters[0].KeyValueFilter.Value.(string), SpanId: sr.spanIds[page]},
+	}}}
+	if page+1 < len(sr.spanIds) {
+		res.Metadata.NextToken = spansquery.ContinuationToken(rune('0' + page + 1))
+	}
+	return res, nil
+}
+
+func TestTraceSpansReadsAllPages(t *testing.T) {
+	srMock, _ := mock.NewSpanReaderMock()
+	sr := &pagedSpanReader{SpanReader: srMock, spanIds: []string{"a", "b", "c"}}
+
+	spans, err := spanreader.TraceSpans(context.Background(), sr, "t1")
+	assert.NoError(t, err)
+	var spanIds []string
+	for _, span := range spans {
+		assert.Equal(t, "t1", span.Span.TraceId)
+		spanIds = append(spanIds, span.Span.SpanId)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, spanIds)
+}
+
+func TestCopyFiltersDoesNotShareKeyValueFilters(t *testing.T) {
+	filters := []model.SearchFilter{{KeyValueFilter: &model.KeyValueFilter{
+		Key: "span.name", Operator: spansquery.OPERATOR_EQUALS, Value: "GET /cart",
+	}}}
+
+	copied := spanreader.CopyFilters(filters)
+	copied[0].KeyValueFilter.Key = "span.attributes.http.route"
+
+	assert.Equal(t, model.FilterKey("span.name"), filters[0].KeyValueFilter.Key)
+}
+
+func TestServiceName(t *testing.T) {
+	assert.Equal(t, "", spanreader.ServiceName(&internalspan.InternalSpan{}))
+	assert.Equal(t, "cart", spanreader.ServiceName(&internalspan.InternalSpan{
+		Resource: &internalspan.Resource{Attributes: internalspan.Attributes{"service.name": "cart"}},
+	}))
+}
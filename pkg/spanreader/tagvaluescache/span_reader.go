@@ -0,0 +1,178 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package tagvaluescache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/cache"
+	"github.com/teletrace/teletrace/pkg/model"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+)
+
+// Config holds the settings of the tag values cache.
+type Config struct {
+	// TTL is the duration for which the values of a tag are served from the cache
+	TTL time.Duration
+	// MaxEntries is the maximum number of cached tag values, the least recently used is evicted when full
+	MaxEntries int
+	// TimeBucket is the granularity of the cached timeframes, timeframes starting and ending in the same buckets
+	// share their cached values
+	TimeBucket time.Duration
+}
+
+type spanReader struct {
+	next       spanreader.SpanReader
+	cache      *cache.LRUCache
+	timeBucket time.Duration
+}
+
+// NewSpanReader wraps sr with a cache of tag values, keyed by tag, time bucket and filters, so the same values
+// requested repeatedly while users type in autocomplete fields don't each scan the storage backend.
+func NewSpanReader(sr spanreader.SpanReader, cfg Config) (spanreader.SpanReader, error) {
+	if cfg.TimeBucket <= 0 {
+		return nil, fmt.Errorf("tag values cache time bucket must be positive")
+	}
+	c, err := cache.NewLRUCache(cfg.TTL, cfg.MaxEntries)
+	if err != nil {
+		return nil, err
+	}
+	return &spanReader{next: sr, cache: c, timeBucket: cfg.TimeBucket}, nil
+}
+
+func (sr *spanReader) Initialize() error {
+	return sr.next.Initialize()
+}
+
+func (sr *spanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	return sr.next.Search(ctx, r)
+}
+
+func (sr *spanReader) GetAvailableTags(
+	ctx context.Context, r tagsquery.GetAvailableTagsRequest,
+) (*tagsquery.GetAvailableTagsResponse, error) {
+	return sr.next.GetAvailableTags(ctx, r)
+}
+
+// GetTagsValues returns the cached values of tags, querying only the tags missing from the cache.
+// The returned responses are shared with the cache and must not be modified.
+func (sr *spanReader) GetTagsValues(
+	ctx context.Context, r tagsquery.TagValuesRequest, tags []string,
+) (map[string]*tagsquery.TagValuesResponse, error) {
+	requestKey, err := sr.requestKey(r)
+	if err != nil {
+		return sr.next.GetTagsValues(ctx, r, tags)
+	}
+
+	res := make(map[string]*tagsquery.TagValuesResponse, len(tags))
+	var missing []string
+	for _, tag := range tags {
+		if cached, ok := sr.cache.Get(tag + ":" + requestKey); ok {
+			res[tag] = cached.(*tagsquery.TagValuesResponse)
+		} else {
+			missing = append(missing, tag)
+		}
+	}
+	if len(missing) == 0 {
+		return res, nil
+	}
+
+	fetched, err := sr.next.GetTagsValues(ctx, r, missing)
+	if err != nil {
+		return nil, err
+	}
+	for tag, values := range fetched {
+		sr.cache.Set(tag+":"+requestKey, values)
+		res[tag] = values
+	}
+	return res, nil
+}
+
+// requestKey returns the time buckets of the request timeframe, its limit and sort, and the hash of its filters, sorted
+// so the same filters in a different order share a key.
+func (sr *spanReader) requestKey(r tagsquery.TagValuesRequest) (string, error) {
+	filters := make([]string, len(r.SearchFilters))
+	for i, f := range r.SearchFilters {
+		filter, err := json.Marshal(f)
+		if err != nil {
+			return "", err
+		}
+		filters[i] = string(filter)
+	}
+	sort.Strings(filters)
+	filtersJSON, err := json.Marshal(filters)
+	if err != nil {
+		return "", err
+	}
+	filtersHash := sha256.Sum256(filtersJSON)
+
+	sortField, ascending := r.Sort()
+	return fmt.Sprintf("%s:%d:%s:%t:%s", sr.timeframeKey(r.Timeframe), r.Limit, sortField, ascending, hex.EncodeToString(filtersHash[:])), nil
+}
+
+func (sr *spanReader) timeframeKey(timeframe *model.Timeframe) string {
+	if timeframe == nil {
+		return "-"
+	}
+	bucket := uint64(sr.timeBucket)
+	return fmt.Sprintf("%d-%d", timeframe.StartTime/bucket, timeframe.EndTime/bucket)
+}
+
+func (sr *spanReader) GetTagsStatistics(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tag string,
+) (*tagsquery.TagStatisticsResponse, error) {
+	return sr.next.GetTagsStatistics(ctx, r, tag)
+}
+
+func (sr *spanReader) GetTagsStatisticsBatch(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string,
+) (map[string]*tagsquery.TagStatisticsResponse, error) {
+	return sr.next.GetTagsStatisticsBatch(ctx, r, tags)
+}
+
+func (sr *spanReader) SearchEvents(ctx context.Context, r eventsquery.SearchRequest) (*eventsquery.SearchResponse, error) {
+	return sr.next.SearchEvents(ctx, r)
+}
+
+func (sr *spanReader) SearchTraces(ctx context.Context, r spansquery.SearchTracesRequest) (*spansquery.SearchTracesResponse, error) {
+	return sr.next.SearchTraces(ctx, r)
+}
+
+func (sr *spanReader) Aggregate(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	return sr.next.Aggregate(ctx, r)
+}
+
+func (sr *spanReader) GetSystemId(ctx context.Context, r metadata.GetSystemIdRequest) (*metadata.GetSystemIdResponse, error) {
+	return sr.next.GetSystemId(ctx, r)
+}
+
+func (sr *spanReader) SetSystemId(ctx context.Context, r metadata.SetSystemIdRequest) (*metadata.SetSystemIdResponse, error) {
+	return sr.next.SetSystemId(ctx, r)
+}
+
+func (sr *spanReader) Ping(ctx context.Context) error {
+	return sr.next.Ping(ctx)
+}
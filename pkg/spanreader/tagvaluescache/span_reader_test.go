@@ -0,0 +1,111 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package tagvaluescache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingSpanReader struct {
+	spanreader.SpanReader
+	queriedTags [][]string
+}
+
+func (sr *countingSpanReader) GetTagsValues(
+	ctx context.Context, r tagsquery.TagValuesRequest, tags []string,
+) (map[string]*tagsquery.TagValuesResponse, error) {
+	sr.queriedTags = append(sr.queriedTags, tags)
+	res := make(map[string]*tagsquery.TagValuesResponse, len(tags))
+	for _, tag := range tags {
+		res[tag] = &tagsquery.TagValuesResponse{Values: []tagsquery.TagValueInfo{{Value: tag + "-value", Count: 1}}}
+	}
+	return res, nil
+}
+
+func newCachedSpanReader(t *testing.T) (spanreader.SpanReader, *countingSpanReader) {
+	srMock, err := mock.NewSpanReaderMock()
+	assert.NoError(t, err)
+	counting := &countingSpanReader{SpanReader: srMock}
+	sr, err := NewSpanReader(counting, Config{TTL: time.Minute, MaxEntries: 10, TimeBucket: time.Minute})
+	assert.NoError(t, err)
+	return sr, counting
+}
+
+func newRequest(start time.Time, filters ...model.SearchFilter) tagsquery.TagValuesRequest {
+	return tagsquery.TagValuesRequest{
+		Timeframe:     &model.Timeframe{StartTime: uint64(start.UnixNano()), EndTime: uint64(start.Add(time.Hour).UnixNano())},
+		SearchFilters: filters,
+	}
+}
+
+func newFilter(key string, value string) model.SearchFilter {
+	return model.SearchFilter{
+		KeyValueFilter: &model.KeyValueFilter{Key: model.FilterKey(key), Operator: spansquery.OPERATOR_EQUALS, Value: value},
+	}
+}
+
+func TestInvalidTimeBucket(t *testing.T) {
+	srMock, err := mock.NewSpanReaderMock()
+	assert.NoError(t, err)
+	_, err = NewSpanReader(srMock, Config{TTL: time.Minute})
+	assert.Error(t, err)
+}
+
+func TestCachesValuesPerTimeBucket(t *testing.T) {
+	sr, counting := newCachedSpanReader(t)
+	start := time.Unix(1700000000, 0).Truncate(time.Minute)
+	service := newFilter("resource.attributes.service.name", "checkout")
+	method := newFilter("span.attributes.http.method", "GET")
+
+	_, err := sr.GetTagsValues(context.Background(), newRequest(start, service, method), []string{"span.name"})
+	assert.NoError(t, err)
+
+	res, err := sr.GetTagsValues(
+		context.Background(), newRequest(start.Add(10*time.Second), method, service), []string{"span.name"},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "span.name-value", res["span.name"].Values[0].Value)
+	assert.Len(t, counting.queriedTags, 1, "requests in the same time bucket with reordered filters share values")
+
+	_, err = sr.GetTagsValues(context.Background(), newRequest(start.Add(time.Minute), service, method), []string{"span.name"})
+	assert.NoError(t, err)
+	_, err = sr.GetTagsValues(context.Background(), newRequest(start, service), []string{"span.name"})
+	assert.NoError(t, err)
+	assert.Len(t, counting.queriedTags, 3)
+}
+
+func TestQueriesOnlyMissingTags(t *testing.T) {
+	sr, counting := newCachedSpanReader(t)
+	r := newRequest(time.Unix(1700000000, 0))
+
+	_, err := sr.GetTagsValues(context.Background(), r, []string{"span.name"})
+	assert.NoError(t, err)
+	res, err := sr.GetTagsValues(context.Background(), r, []string{"span.name", "span.kind"})
+	assert.NoError(t, err)
+
+	assert.Len(t, res, 2)
+	assert.Equal(t, [][]string{{"span.name"}, {"span.kind"}}, counting.queriedTags)
+}
@@ -0,0 +1,166 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package traced
+
+import (
+	"context"
+
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/teletrace/teletrace/pkg/spanreader/traced"
+
+var (
+	searchFiltersKey = attribute.Key("teletrace.search.filters")
+	searchSpansKey   = attribute.Key("teletrace.search.spans")
+	searchEventsKey  = attribute.Key("teletrace.search.events")
+	searchTracesKey  = attribute.Key("teletrace.search.traces")
+	searchGroupsKey  = attribute.Key("teletrace.search.groups")
+	tagsKey          = attribute.Key("teletrace.tags")
+)
+
+type spanReader struct {
+	next   spanreader.SpanReader
+	tracer trace.Tracer
+}
+
+// NewSpanReader wraps sr so that every storage query is traced as a span named after the span reader method,
+// parenting the spans of the query builders and storage clients started by the storage plugin.
+func NewSpanReader(sr spanreader.SpanReader, tp trace.TracerProvider) spanreader.SpanReader {
+	return &spanReader{next: sr, tracer: tp.Tracer(tracerName)}
+}
+
+func (sr *spanReader) Initialize() error {
+	return sr.next.Initialize()
+}
+
+func (sr *spanReader) Search(ctx context.Context, r spansquery.SearchRequest) (res *spansquery.SearchResponse, err error) {
+	ctx, span := sr.tracer.Start(ctx, "SpanReader.Search", trace.WithAttributes(searchFiltersKey.Int(len(r.SearchFilters))))
+	defer func() {
+		if res != nil {
+			span.SetAttributes(searchSpansKey.Int(len(res.Spans)))
+		}
+		tracing.EndSpan(span, err)
+	}()
+	return sr.next.Search(ctx, r)
+}
+
+func (sr *spanReader) GetAvailableTags(
+	ctx context.Context, r tagsquery.GetAvailableTagsRequest,
+) (res *tagsquery.GetAvailableTagsResponse, err error) {
+	ctx, span := sr.tracer.Start(ctx, "SpanReader.GetAvailableTags")
+	defer func() { tracing.EndSpan(span, err) }()
+	return sr.next.GetAvailableTags(ctx, r)
+}
+
+func (sr *spanReader) GetTagsValues(
+	ctx context.Context, r tagsquery.TagValuesRequest, tags []string,
+) (res map[string]*tagsquery.TagValuesResponse, err error) {
+	ctx, span := sr.tracer.Start(ctx, "SpanReader.GetTagsValues", trace.WithAttributes(
+		searchFiltersKey.Int(len(r.SearchFilters)), tagsKey.StringSlice(tags),
+	))
+	defer func() { tracing.EndSpan(span, err) }()
+	return sr.next.GetTagsValues(ctx, r, tags)
+}
+
+func (sr *spanReader) GetTagsStatistics(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tag string,
+) (res *tagsquery.TagStatisticsResponse, err error) {
+	ctx, span := sr.tracer.Start(ctx, "SpanReader.GetTagsStatistics", trace.WithAttributes(
+		searchFiltersKey.Int(len(r.SearchFilters)), tagsKey.StringSlice([]string{tag}),
+	))
+	defer func() { tracing.EndSpan(span, err) }()
+	return sr.next.GetTagsStatistics(ctx, r, tag)
+}
+
+func (sr *spanReader) GetTagsStatisticsBatch(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string,
+) (res map[string]*tagsquery.TagStatisticsResponse, err error) {
+	ctx, span := sr.tracer.Start(ctx, "SpanReader.GetTagsStatisticsBatch", trace.WithAttributes(
+		searchFiltersKey.Int(len(r.SearchFilters)), tagsKey.StringSlice(tags),
+	))
+	defer func() { tracing.EndSpan(span, err) }()
+	return sr.next.GetTagsStatisticsBatch(ctx, r, tags)
+}
+
+func (sr *spanReader) SearchEvents(
+	ctx context.Context, r eventsquery.SearchRequest,
+) (res *eventsquery.SearchResponse, err error) {
+	ctx, span := sr.tracer.Start(ctx, "SpanReader.SearchEvents", trace.WithAttributes(searchFiltersKey.Int(len(r.SearchFilters))))
+	defer func() {
+		if res != nil {
+			span.SetAttributes(searchEventsKey.Int(len(res.Events)))
+		}
+		tracing.EndSpan(span, err)
+	}()
+	return sr.next.SearchEvents(ctx, r)
+}
+
+func (sr *spanReader) SearchTraces(
+	ctx context.Context, r spansquery.SearchTracesRequest,
+) (res *spansquery.SearchTracesResponse, err error) {
+	ctx, span := sr.tracer.Start(ctx, "SpanReader.SearchTraces", trace.WithAttributes(searchFiltersKey.Int(len(r.SearchFilters))))
+	defer func() {
+		if res != nil {
+			span.SetAttributes(searchTracesKey.Int(len(res.Traces)))
+		}
+		tracing.EndSpan(span, err)
+	}()
+	return sr.next.SearchTraces(ctx, r)
+}
+
+func (sr *spanReader) Aggregate(
+	ctx context.Context, r spansquery.SearchRequest,
+) (res *spansquery.SearchResponse, err error) {
+	ctx, span := sr.tracer.Start(ctx, "SpanReader.Aggregate", trace.WithAttributes(searchFiltersKey.Int(len(r.SearchFilters))))
+	defer func() {
+		if res != nil && res.Aggregations != nil {
+			span.SetAttributes(searchGroupsKey.Int(len(res.Aggregations.Groups)))
+		}
+		tracing.EndSpan(span, err)
+	}()
+	return sr.next.Aggregate(ctx, r)
+}
+
+func (sr *spanReader) GetSystemId(
+	ctx context.Context, r metadata.GetSystemIdRequest,
+) (res *metadata.GetSystemIdResponse, err error) {
+	ctx, span := sr.tracer.Start(ctx, "SpanReader.GetSystemId")
+	defer func() { tracing.EndSpan(span, err) }()
+	return sr.next.GetSystemId(ctx, r)
+}
+
+func (sr *spanReader) SetSystemId(
+	ctx context.Context, r metadata.SetSystemIdRequest,
+) (res *metadata.SetSystemIdResponse, err error) {
+	ctx, span := sr.tracer.Start(ctx, "SpanReader.SetSystemId")
+	defer func() { tracing.EndSpan(span, err) }()
+	return sr.next.SetSystemId(ctx, r)
+}
+
+// Ping isn't traced, as readiness probes would flood the self traces.
+func (sr *spanReader) Ping(ctx context.Context) error {
+	return sr.next.Ping(ctx)
+}
@@ -0,0 +1,77 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package traced
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type failingSpanReader struct {
+	spanreader.SpanReader
+	err error
+}
+
+func (sr *failingSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	if sr.err != nil {
+		return nil, sr.err
+	}
+	// the storage plugin spans are children of the span reader span
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return nil, errors.New("missing span in context")
+	}
+	return sr.SpanReader.Search(ctx, r)
+}
+
+func TestTracesQueriesAndErrors(t *testing.T) {
+	srMock, err := mock.NewSpanReaderMock()
+	assert.NoError(t, err)
+	backend := &failingSpanReader{SpanReader: srMock}
+	recorder := tracetest.NewSpanRecorder()
+	sr := NewSpanReader(backend, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	_, err = sr.Search(context.Background(), spansquery.SearchRequest{})
+	assert.NoError(t, err)
+	backend.err = errors.New("connection refused")
+	_, err = sr.Search(context.Background(), spansquery.SearchRequest{})
+	assert.Error(t, err)
+	backend.err = context.Canceled
+	_, err = sr.Search(context.Background(), spansquery.SearchRequest{})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 3)
+	for _, span := range spans {
+		assert.Equal(t, "SpanReader.Search", span.Name())
+	}
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Contains(t, spans[0].Attributes(), searchFiltersKey.Int(0))
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "connection refused", spans[1].Status().Description)
+	assert.Equal(t, codes.Unset, spans[2].Status().Code, "canceled queries aren't storage errors")
+}
@@ -0,0 +1,159 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spanreader
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+const (
+	// searchTracesMaxPages bounds the pages of matching spans scanned by SearchTracesInSpans to find the traces
+	searchTracesMaxPages = 20
+	// tracesSpansPageSize is the page size of the searches of the spans of the matching traces
+	tracesSpansPageSize = 1000
+)
+
+// SearchTracesInSpans implements SpanReader.SearchTraces for storage backends without a native way to group the
+// matching spans by trace: the matching spans are searched with sr, most recent first, until enough distinct traces
+// are found, and the spans of the traces are added with AddTracesSpans. The continuation token is the number of
+// traces of the previous pages, which are skipped, so later pages scan more spans.
+func SearchTracesInSpans(ctx context.Context, sr SpanReader, r spansquery.SearchTracesRequest) (*spansquery.SearchTracesResponse, error) {
+	offset, err := TracesOffset(r.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	limit := r.EffectiveLimit()
+
+	traces := make([]*spansquery.TraceResult, 0, limit)
+	seen := make(map[string]bool)
+	more := false
+	var token spansquery.ContinuationToken
+	for page := 0; page < searchTracesMaxPages && !more; page++ {
+		res, err := sr.Search(ctx, spansquery.SearchRequest{
+			Timeframe:     r.Timeframe,
+			Sort:          []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: false}},
+			SearchFilters: CopyFilters(r.SearchFilters),
+			Metadata:      &spansquery.Metadata{NextToken: token},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, span := range res.Spans {
+			traceId := span.Span.TraceId
+			if seen[traceId] {
+				continue
+			}
+			seen[traceId] = true
+			if len(seen) > offset+limit {
+				more = true
+				break
+			}
+			if len(seen) > offset {
+				traces = append(traces, &spansquery.TraceResult{
+					TraceId:                 traceId,
+					LatestMatchTimeUnixNano: span.Span.StartTimeUnixNano,
+				})
+			}
+		}
+		if len(res.Spans) == 0 || res.Metadata == nil || res.Metadata.NextToken == "" {
+			break
+		}
+		token = res.Metadata.NextToken
+	}
+
+	truncated, err := AddTracesSpans(ctx, sr, traces)
+	if err != nil {
+		return nil, err
+	}
+	res := &spansquery.SearchTracesResponse{Metadata: &spansquery.Metadata{}, Traces: traces, SpansTruncated: truncated}
+	if more {
+		res.Metadata.NextToken = TracesOffsetToken(offset + limit)
+	}
+	return res, nil
+}
+
+// TracesOffset returns the number of traces of the previous pages of a trace search continued from metadata,
+// for storage backends paginating the traces by offset.
+func TracesOffset(metadata *spansquery.Metadata) (int, error) {
+	if metadata == nil || metadata.NextToken == "" {
+		return 0, nil
+	}
+	offset, err := strconv.Atoi(string(metadata.NextToken))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("%w: invalid continuation token %q", ErrInvalidQuery, metadata.NextToken)
+	}
+	return offset, nil
+}
+
+// TracesOffsetToken returns the continuation token of a trace search page starting after offset traces.
+func TracesOffsetToken(offset int) spansquery.ContinuationToken {
+	return spansquery.ContinuationToken(strconv.Itoa(offset))
+}
+
+// AddTracesSpans sets the spans of the traces, searched with sr in the order of their start time, regardless of the
+// timeframe of the search. At most spansquery.MaxTracesSpans spans are added, returning whether spans were left out.
+func AddTracesSpans(ctx context.Context, sr SpanReader, traces []*spansquery.TraceResult) (bool, error) {
+	if len(traces) == 0 {
+		return false, nil
+	}
+	byId := make(map[string]*spansquery.TraceResult, len(traces))
+	traceIds := make([]string, 0, len(traces))
+	for _, trace := range traces {
+		trace.Spans = make([]*internalspan.InternalSpan, 0)
+		byId[trace.TraceId] = trace
+		traceIds = append(traceIds, trace.TraceId)
+	}
+
+	count := 0
+	var token spansquery.ContinuationToken
+	for {
+		res, err := sr.Search(ctx, spansquery.SearchRequest{
+			Timeframe: model.Timeframe{StartTime: 0, EndTime: uint64(time.Now().UnixNano())},
+			Sort:      []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: true}},
+			SearchFilters: []model.SearchFilter{{KeyValueFilter: &model.KeyValueFilter{
+				Key: "span.traceId", Operator: spansquery.OPERATOR_IN, Value: traceIds,
+			}}},
+			Metadata: &spansquery.Metadata{NextToken: token},
+			Limit:    tracesSpansPageSize,
+		})
+		if err != nil {
+			return false, err
+		}
+		for _, span := range res.Spans {
+			trace, ok := byId[span.Span.TraceId]
+			if !ok {
+				continue
+			}
+			if count == spansquery.MaxTracesSpans {
+				return true, nil
+			}
+			trace.Spans = append(trace.Spans, span)
+			count++
+		}
+		if len(res.Spans) == 0 || res.Metadata == nil || res.Metadata.NextToken == "" {
+			return false, nil
+		}
+		token = res.Metadata.NextToken
+	}
+}
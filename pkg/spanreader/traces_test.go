@@ -0,0 +1,85 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spanreader_test
+
+import (
+	"context"
+	"testing"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tracesSpanReader returns the spans of the requested traces when searched by trace ID,
+// and the matching spans of traces t1, t2 and t3, most recent first, otherwise.
+type tracesSpanReader struct {
+	spanreader.SpanReader
+}
+
+func (sr *tracesSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	span := func(traceId string, spanId string, start uint64) *internalspan.InternalSpan {
+		return &internalspan.InternalSpan{Span: &internalspan.Span{TraceId: traceId, SpanId: spanId, StartTimeUnixNano: start}}
+	}
+	if f := r.SearchFilters[0].KeyValueFilter; f.Key == "span.traceId" {
+		var spans []*internalspan.InternalSpan
+		for _, traceId := range f.Value.([]string) {
+			spans = append(spans, span(traceId, traceId+"-root", 1), span(traceId, traceId+"-child", 2))
+		}
+		return &spansquery.SearchResponse{Spans: spans}, nil
+	}
+	return &spansquery.SearchResponse{Spans: []*internalspan.InternalSpan{
+		span("t1", "t1-child", 30), span("t2", "t2-child", 20), span("t1", "t1-root", 15), span("t3", "t3-child", 10),
+	}}, nil
+}
+
+func TestSearchTracesInSpans(t *testing.T) {
+	srMock, _ := mock.NewSpanReaderMock()
+	sr := &tracesSpanReader{SpanReader: srMock}
+	r := spansquery.SearchTracesRequest{
+		Timeframe: model.Timeframe{EndTime: 1000},
+		SearchFilters: []model.SearchFilter{{KeyValueFilter: &model.KeyValueFilter{
+			Key: "span.name", Operator: spansquery.OPERATOR_EQUALS, Value: "GET /cart",
+		}}},
+		Limit: 2,
+	}
+
+	res, err := spanreader.SearchTracesInSpans(context.Background(), sr, r)
+	assert.NoError(t, err)
+	assert.Len(t, res.Traces, 2)
+	assert.Equal(t, "t1", res.Traces[0].TraceId)
+	assert.Equal(t, uint64(30), res.Traces[0].LatestMatchTimeUnixNano)
+	assert.Len(t, res.Traces[0].Spans, 2)
+	assert.Equal(t, "t2", res.Traces[1].TraceId)
+	assert.False(t, res.SpansTruncated)
+	assert.Equal(t, spansquery.ContinuationToken("2"), res.Metadata.NextToken)
+
+	r.Metadata = res.Metadata
+	res, err = spanreader.SearchTracesInSpans(context.Background(), sr, r)
+	assert.NoError(t, err)
+	assert.Len(t, res.Traces, 1)
+	assert.Equal(t, "t3", res.Traces[0].TraceId)
+	assert.Empty(t, res.Metadata.NextToken)
+
+	r.Metadata = &spansquery.Metadata{NextToken: "invalid"}
+	_, err = spanreader.SearchTracesInSpans(context.Background(), sr, r)
+	assert.Error(t, err)
+}
@@ -0,0 +1,352 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package storageplugin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	stopTimeout = 5 * time.Second
+)
+
+// ErrServiceNotServed is returned when the plugin doesn't serve a requested service, e.g. a span writer
+// of a read only plugin.
+var ErrServiceNotServed = errors.New("the storage plugin doesn't serve the service")
+
+// Config configures the launched plugin.
+type Config struct {
+	// Path is the path of the plugin binary
+	Path string
+	// StartTimeout is the maximum time to wait for the plugin to start serving
+	StartTimeout time.Duration
+}
+
+// Client launches a plugin binary and calls the services it serves.
+type Client struct {
+	logger   *zap.Logger
+	cmd      *exec.Cmd
+	stdin    io.Closer
+	exited   chan struct{}
+	conn     *grpc.ClientConn
+	services map[string]bool
+	once     sync.Once
+}
+
+// NewClient launches the plugin binary and connects to it once it's serving.
+func NewClient(logger *zap.Logger, cfg Config) (*Client, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("the path of the storage plugin is required")
+	}
+
+	cmd := exec.Command(cfg.Path)
+	cmd.Env = append(os.Environ(), MagicCookieKey+"="+MagicCookieValue)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("could not launch storage plugin %s: %w", cfg.Path, err)
+	}
+
+	c := &Client{logger: logger.With(zap.String("plugin", cfg.Path)), cmd: cmd, stdin: stdin, exited: make(chan struct{})}
+	go c.logOutput(stderr)
+	go func() {
+		err := cmd.Wait()
+		c.logger.Info("Storage plugin exited", zap.Error(err))
+		close(c.exited)
+	}()
+
+	handshake, err := c.readHandshake(stdout, cfg.StartTimeout)
+	if err != nil {
+		c.stop()
+		return nil, err
+	}
+	if err := c.connect(handshake); err != nil {
+		c.stop()
+		return nil, err
+	}
+	return c, nil
+}
+
+// readHandshake reads the handshake line of the plugin, after which its stdout is logged.
+func (c *Client) readHandshake(stdout io.Reader, timeout time.Duration) (string, error) {
+	lines := make(chan string, 1)
+	scanner := bufio.NewScanner(stdout)
+	go func() {
+		if scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+		for scanner.Scan() {
+			c.logger.Info(scanner.Text())
+		}
+	}()
+
+	var timer <-chan time.Time
+	if timeout > 0 {
+		timer = time.After(timeout)
+	}
+	select {
+	case line, ok := <-lines:
+		if !ok {
+			return "", errors.New("storage plugin exited before serving")
+		}
+		return line, nil
+	case <-timer:
+		return "", fmt.Errorf("storage plugin didn't start serving within %s", timeout)
+	}
+}
+
+func (c *Client) connect(handshake string) error {
+	parts := strings.Split(handshake, "|")
+	if len(parts) != 4 {
+		return fmt.Errorf("unexpected storage plugin handshake: %q", handshake)
+	}
+	version, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return fmt.Errorf("unexpected storage plugin handshake: %q", handshake)
+	}
+	if version != ProtocolVersion {
+		return fmt.Errorf("storage plugin protocol version %d isn't supported, expected version %d", version, ProtocolVersion)
+	}
+	if parts[1] != "unix" {
+		return fmt.Errorf("storage plugin network %q isn't supported", parts[1])
+	}
+
+	c.services = map[string]bool{}
+	for _, service := range strings.Split(parts[3], ",") {
+		c.services[service] = true
+	}
+	c.conn, err = grpc.Dial("unix://"+parts[2],
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(jsonCodec{})),
+	)
+	if err != nil {
+		return fmt.Errorf("could not connect to storage plugin: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) logOutput(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		c.logger.Info(scanner.Text())
+	}
+}
+
+// SpanReader returns the span reader served by the plugin.
+func (c *Client) SpanReader() (spanreader.SpanReader, error) {
+	if !c.services[readerServiceName] {
+		return nil, fmt.Errorf("%w: %s", ErrServiceNotServed, readerServiceName)
+	}
+	return &spanReader{conn: c.conn}, nil
+}
+
+// SpanWriter returns the span writer served by the plugin.
+func (c *Client) SpanWriter() (SpanWriter, error) {
+	if !c.services[writerServiceName] {
+		return nil, fmt.Errorf("%w: %s", ErrServiceNotServed, writerServiceName)
+	}
+	return &spanWriter{conn: c.conn}, nil
+}
+
+// Close disconnects from the plugin and stops it.
+func (c *Client) Close() error {
+	var err error
+	if c.conn != nil {
+		err = c.conn.Close()
+	}
+	c.stop()
+	return err
+}
+
+// stop closes the stdin of the plugin, which it exits on, and kills it if it doesn't exit in time.
+func (c *Client) stop() {
+	c.once.Do(func() {
+		_ = c.stdin.Close()
+		select {
+		case <-c.exited:
+		case <-time.After(stopTimeout):
+			c.logger.Warn("Storage plugin didn't exit in time, killing it")
+			_ = c.cmd.Process.Kill()
+			<-c.exited
+		}
+	})
+}
+
+func invoke(ctx context.Context, conn *grpc.ClientConn, service string, method string, req any, resp any) error {
+	if err := conn.Invoke(ctx, "/"+service+"/"+method, req, resp); err != nil {
+		return fromStatus(err)
+	}
+	return nil
+}
+
+type spanReader struct {
+	conn *grpc.ClientConn
+}
+
+func (sr *spanReader) invoke(ctx context.Context, method string, req any, resp any) error {
+	return invoke(ctx, sr.conn, readerServiceName, method, req, resp)
+}
+
+func (sr *spanReader) Initialize() error {
+	return sr.invoke(context.Background(), "Initialize", &empty{}, &empty{})
+}
+
+func (sr *spanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	var res searchResponse
+	if err := sr.invoke(ctx, "Search", &r, &res); err != nil {
+		return nil, err
+	}
+	return &spansquery.SearchResponse{
+		Metadata: res.Metadata,
+		Spans:    fromWireSpans(res.Spans),
+		Sample:   res.Sample,
+		Debug:    res.Debug,
+		Partial:  res.Partial,
+	}, nil
+}
+
+func (sr *spanReader) GetAvailableTags(ctx context.Context, r tagsquery.GetAvailableTagsRequest) (*tagsquery.GetAvailableTagsResponse, error) {
+	var res tagsquery.GetAvailableTagsResponse
+	if err := sr.invoke(ctx, "GetAvailableTags", &r, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (sr *spanReader) GetTagsValues(
+	ctx context.Context, r tagsquery.TagValuesRequest, tags []string,
+) (map[string]*tagsquery.TagValuesResponse, error) {
+	var res tagsValuesResponse
+	if err := sr.invoke(ctx, "GetTagsValues", &tagsValuesRequest{Request: r, Tags: tags}, &res); err != nil {
+		return nil, err
+	}
+	return res.Values, nil
+}
+
+func (sr *spanReader) GetTagsStatistics(ctx context.Context, r tagsquery.TagStatisticsRequest, tag string) (*tagsquery.TagStatisticsResponse, error) {
+	var res tagsquery.TagStatisticsResponse
+	if err := sr.invoke(ctx, "GetTagsStatistics", &tagStatisticsRequest{Request: r, Tag: tag}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (sr *spanReader) GetTagsStatisticsBatch(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string,
+) (map[string]*tagsquery.TagStatisticsResponse, error) {
+	var res map[string]*tagsquery.TagStatisticsResponse
+	if err := sr.invoke(ctx, "GetTagsStatisticsBatch", &tagsStatisticsBatchRequest{Request: r, Tags: tags}, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (sr *spanReader) SearchEvents(ctx context.Context, r eventsquery.SearchRequest) (*eventsquery.SearchResponse, error) {
+	var res eventsquery.SearchResponse
+	if err := sr.invoke(ctx, "SearchEvents", &r, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (sr *spanReader) SearchTraces(ctx context.Context, r spansquery.SearchTracesRequest) (*spansquery.SearchTracesResponse, error) {
+	var res searchTracesResponse
+	if err := sr.invoke(ctx, "SearchTraces", &r, &res); err != nil {
+		return nil, err
+	}
+	return &spansquery.SearchTracesResponse{
+		Metadata:       res.Metadata,
+		Traces:         fromWireTraces(res.Traces),
+		SpansTruncated: res.SpansTruncated,
+	}, nil
+}
+
+// Aggregate aggregates in the plugin, or in memory through Search for plugins built before Aggregate was added to the
+// services, which don't implement it.
+func (sr *spanReader) Aggregate(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	var res spansquery.SearchResponse
+	err := sr.conn.Invoke(ctx, "/"+readerServiceName+"/Aggregate", &r, &res)
+	if status.Code(err) == codes.Unimplemented {
+		return spanreader.AggregateInSpans(ctx, sr, r)
+	}
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	res.Spans = []*internalspan.InternalSpan{}
+	return &res, nil
+}
+
+func (sr *spanReader) GetSystemId(ctx context.Context, r metadata.GetSystemIdRequest) (*metadata.GetSystemIdResponse, error) {
+	var res metadata.GetSystemIdResponse
+	if err := sr.invoke(ctx, "GetSystemId", &r, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (sr *spanReader) SetSystemId(ctx context.Context, r metadata.SetSystemIdRequest) (*metadata.SetSystemIdResponse, error) {
+	var res metadata.SetSystemIdResponse
+	if err := sr.invoke(ctx, "SetSystemId", &r, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (sr *spanReader) Ping(ctx context.Context) error {
+	return sr.invoke(ctx, "Ping", &empty{}, &empty{})
+}
+
+type spanWriter struct {
+	conn *grpc.ClientConn
+}
+
+func (sw *spanWriter) WriteSpans(ctx context.Context, spans []*internalspan.InternalSpan) error {
+	return invoke(ctx, sw.conn, writerServiceName, "WriteSpans", &writeSpansRequest{Spans: toWireSpans(spans)}, &empty{})
+}
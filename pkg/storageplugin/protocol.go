@@ -0,0 +1,222 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package storageplugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ProtocolVersion is incremented on breaking changes of the services, plugins of another version are rejected
+	ProtocolVersion = 1
+
+	// MagicCookieKey and MagicCookieValue are set in the environment of the plugins Teletrace launches,
+	// so a plugin binary run directly tells it's meant to be launched by Teletrace instead
+	MagicCookieKey   = "TELETRACE_STORAGE_PLUGIN"
+	MagicCookieValue = "7f3a9c1e5b2d4068"
+
+	readerServiceName = "teletrace.storage.v1.SpanReader"
+	writerServiceName = "teletrace.storage.v1.SpanWriter"
+)
+
+// SpanWriter writes spans to a storage backend.
+type SpanWriter interface {
+	WriteSpans(ctx context.Context, spans []*internalspan.InternalSpan) error
+}
+
+// jsonCodec encodes the messages of the services as JSON, the encoding of the API models,
+// so plugins need no generated code.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal keeps the numbers of attribute values as json.Number, not to lose the precision of large integers.
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+type empty struct{}
+
+// wireSpan holds the events and links of the span alongside it, as they aren't part of the span JSON.
+type wireSpan struct {
+	*internalspan.InternalSpan
+	Events []*internalspan.SpanEvent `json:"events"`
+	Links  []*internalspan.SpanLink  `json:"links"`
+}
+
+type searchResponse struct {
+	Metadata *spansquery.Metadata       `json:"metadata"`
+	Spans    []*wireSpan                `json:"spans"`
+	Sample   *spansquery.SampleMetadata `json:"sample,omitempty"`
+	Debug    *spansquery.DebugInfo      `json:"debug,omitempty"`
+	Partial  *spansquery.PartialResult  `json:"partial,omitempty"`
+}
+
+type searchTracesResponse struct {
+	Metadata       *spansquery.Metadata `json:"metadata"`
+	Traces         []*wireTrace         `json:"traces"`
+	SpansTruncated bool                 `json:"spansTruncated"`
+}
+
+type wireTrace struct {
+	TraceId                 string      `json:"traceId"`
+	LatestMatchTimeUnixNano uint64      `json:"latestMatchTimeUnixNano"`
+	Spans                   []*wireSpan `json:"spans"`
+}
+
+type tagsValuesRequest struct {
+	Request tagsquery.TagValuesRequest `json:"request"`
+	Tags    []string                   `json:"tags"`
+}
+
+type tagsValuesResponse struct {
+	Values map[string]*tagsquery.TagValuesResponse `json:"values"`
+}
+
+type tagStatisticsRequest struct {
+	Request tagsquery.TagStatisticsRequest `json:"request"`
+	Tag     string                         `json:"tag"`
+}
+
+type tagsStatisticsBatchRequest struct {
+	Request tagsquery.TagStatisticsRequest `json:"request"`
+	Tags    []string                       `json:"tags"`
+}
+
+type writeSpansRequest struct {
+	Spans []*wireSpan `json:"spans"`
+}
+
+func toWireSpans(spans []*internalspan.InternalSpan) []*wireSpan {
+	result := make([]*wireSpan, 0, len(spans))
+	for _, span := range spans {
+		w := &wireSpan{InternalSpan: span}
+		if span.Span != nil {
+			w.Events, w.Links = span.Span.Events, span.Span.Links
+		}
+		result = append(result, w)
+	}
+	return result
+}
+
+func fromWireSpans(spans []*wireSpan) []*internalspan.InternalSpan {
+	result := make([]*internalspan.InternalSpan, 0, len(spans))
+	for _, w := range spans {
+		if w.InternalSpan == nil {
+			continue
+		}
+		if w.Span != nil {
+			w.Span.Events, w.Span.Links = w.Events, w.Links
+		}
+		result = append(result, w.InternalSpan)
+	}
+	return result
+}
+
+// toStatus converts the errors of a plugin to gRPC statuses, keeping the error codes the API responds to distinctly.
+func toStatus(err error) error {
+	switch spanreader.Code(err) {
+	case spanreader.ERROR_CODE_NOT_FOUND:
+		return status.Error(codes.NotFound, err.Error())
+	case spanreader.ERROR_CODE_INVALID_QUERY:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case spanreader.ERROR_CODE_BACKEND_UNAVAILABLE:
+		return status.Error(codes.Unavailable, err.Error())
+	case spanreader.ERROR_CODE_TIMEOUT:
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case spanreader.ERROR_CODE_CANCELED:
+		return status.Error(codes.Canceled, err.Error())
+	default:
+		return status.Error(codes.Unknown, err.Error())
+	}
+}
+
+// fromStatus converts the gRPC statuses of plugin calls back to span reader errors.
+// The plugin process being unreachable is reported as the backend being unavailable.
+func fromStatus(err error) error {
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch s.Code() {
+	case codes.NotFound:
+		return wrapMessage(spanreader.ErrNotFound, s.Message())
+	case codes.InvalidArgument:
+		return wrapMessage(spanreader.ErrInvalidQuery, s.Message())
+	case codes.Unavailable:
+		return wrapMessage(spanreader.ErrBackendUnavailable, s.Message())
+	case codes.DeadlineExceeded:
+		return wrapMessage(spanreader.ErrQueryTimeout, s.Message())
+	case codes.Canceled:
+		return spanreader.ErrQueryCanceled
+	default:
+		return errors.New(s.Message())
+	}
+}
+
+// wrapMessage returns the message of a plugin error wrapped by target, which the message of errors wrapped by the
+// plugin already starts with.
+func wrapMessage(target error, message string) error {
+	return fmt.Errorf("%w: %s", target, strings.TrimPrefix(message, target.Error()+": "))
+}
+
+func toWireTraces(traces []*spansquery.TraceResult) []*wireTrace {
+	result := make([]*wireTrace, 0, len(traces))
+	for _, trace := range traces {
+		result = append(result, &wireTrace{
+			TraceId:                 trace.TraceId,
+			LatestMatchTimeUnixNano: trace.LatestMatchTimeUnixNano,
+			Spans:                   toWireSpans(trace.Spans),
+		})
+	}
+	return result
+}
+
+func fromWireTraces(traces []*wireTrace) []*spansquery.TraceResult {
+	result := make([]*spansquery.TraceResult, 0, len(traces))
+	for _, w := range traces {
+		if w == nil {
+			continue
+		}
+		result = append(result, &spansquery.TraceResult{
+			TraceId:                 w.TraceId,
+			LatestMatchTimeUnixNano: w.LatestMatchTimeUnixNano,
+			Spans:                   fromWireSpans(w.Spans),
+		})
+	}
+	return result
+}
@@ -0,0 +1,214 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package storageplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"google.golang.org/grpc"
+)
+
+// ErrNotLaunched is returned by Serve when the plugin binary isn't run by Teletrace.
+var ErrNotLaunched = errors.New("this binary is a Teletrace storage plugin, configure its path " +
+	"in GRPC_PLUGIN_PATH instead of running it directly")
+
+// Serve serves reader and writer to the Teletrace process which launched the plugin binary, until it exits.
+// Either of them may be nil for plugins which only read or write spans. Plugin binaries call it from main.
+func Serve(reader spanreader.SpanReader, writer SpanWriter) error {
+	if os.Getenv(MagicCookieKey) != MagicCookieValue {
+		return ErrNotLaunched
+	}
+	if reader == nil && writer == nil {
+		return errors.New("either a span reader or a span writer is required")
+	}
+
+	dir, err := os.MkdirTemp("", "teletrace-plugin")
+	if err != nil {
+		return fmt.Errorf("could not create the plugin socket directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "plugin.sock")
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		return fmt.Errorf("could not listen on the plugin socket: %w", err)
+	}
+
+	server := newServer(reader, writer)
+	go func() {
+		// Teletrace closes the stdin of the plugin when it exits, as signals aren't delivered if it crashes
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		stdinClosed := make(chan struct{})
+		go func() {
+			_, _ = io.Copy(io.Discard, os.Stdin)
+			close(stdinClosed)
+		}()
+		select {
+		case <-signals:
+		case <-stdinClosed:
+		}
+		server.GracefulStop()
+	}()
+
+	fmt.Println(handshake(socket, reader != nil, writer != nil))
+	return server.Serve(lis)
+}
+
+// handshake returns the line a plugin prints on startup: the protocol version, the network and address
+// it listens on and the services it serves, separated by '|'.
+func handshake(socket string, hasReader bool, hasWriter bool) string {
+	var services []string
+	if hasReader {
+		services = append(services, readerServiceName)
+	}
+	if hasWriter {
+		services = append(services, writerServiceName)
+	}
+	return fmt.Sprintf("%d|unix|%s|%s", ProtocolVersion, socket, strings.Join(services, ","))
+}
+
+func newServer(reader spanreader.SpanReader, writer SpanWriter) *grpc.Server {
+	server := grpc.NewServer(grpc.ForceServerCodec(jsonCodec{}))
+	if reader != nil {
+		server.RegisterService(&readerServiceDesc, reader)
+	}
+	if writer != nil {
+		server.RegisterService(&writerServiceDesc, writer)
+	}
+	return server
+}
+
+// unaryMethod describes a method of a service, decoding its request to Req and calling handle with the
+// implementation of the service.
+func unaryMethod[Srv any, Req any](service string, method string, handle func(ctx context.Context, srv Srv, req *Req) (any, error)) grpc.MethodDesc {
+	call := func(ctx context.Context, srv any, req any) (any, error) {
+		resp, err := handle(ctx, srv.(Srv), req.(*Req))
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		return resp, nil
+	}
+	return grpc.MethodDesc{
+		MethodName: method,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := new(Req)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return call(ctx, srv, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + service + "/" + method}
+			return interceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+				return call(ctx, srv, req)
+			})
+		},
+	}
+}
+
+func readerMethod[Req any](method string, handle func(ctx context.Context, sr spanreader.SpanReader, req *Req) (any, error)) grpc.MethodDesc {
+	return unaryMethod(readerServiceName, method, handle)
+}
+
+var readerServiceDesc = grpc.ServiceDesc{
+	ServiceName: readerServiceName,
+	HandlerType: (*spanreader.SpanReader)(nil),
+	Methods: []grpc.MethodDesc{
+		readerMethod("Initialize", func(ctx context.Context, sr spanreader.SpanReader, _ *empty) (any, error) {
+			return &empty{}, sr.Initialize()
+		}),
+		readerMethod("Search", func(ctx context.Context, sr spanreader.SpanReader, req *spansquery.SearchRequest) (any, error) {
+			res, err := sr.Search(ctx, *req)
+			if err != nil {
+				return nil, err
+			}
+			return &searchResponse{
+				Metadata: res.Metadata,
+				Spans:    toWireSpans(res.Spans),
+				Sample:   res.Sample,
+				Debug:    res.Debug,
+				Partial:  res.Partial,
+			}, nil
+		}),
+		readerMethod("GetAvailableTags", func(ctx context.Context, sr spanreader.SpanReader, req *tagsquery.GetAvailableTagsRequest) (any, error) {
+			return sr.GetAvailableTags(ctx, *req)
+		}),
+		readerMethod("GetTagsValues", func(ctx context.Context, sr spanreader.SpanReader, req *tagsValuesRequest) (any, error) {
+			values, err := sr.GetTagsValues(ctx, req.Request, req.Tags)
+			if err != nil {
+				return nil, err
+			}
+			return &tagsValuesResponse{Values: values}, nil
+		}),
+		readerMethod("GetTagsStatistics", func(ctx context.Context, sr spanreader.SpanReader, req *tagStatisticsRequest) (any, error) {
+			return sr.GetTagsStatistics(ctx, req.Request, req.Tag)
+		}),
+		readerMethod("GetTagsStatisticsBatch", func(ctx context.Context, sr spanreader.SpanReader, req *tagsStatisticsBatchRequest) (any, error) {
+			return sr.GetTagsStatisticsBatch(ctx, req.Request, req.Tags)
+		}),
+		readerMethod("SearchEvents", func(ctx context.Context, sr spanreader.SpanReader, req *eventsquery.SearchRequest) (any, error) {
+			return sr.SearchEvents(ctx, *req)
+		}),
+		readerMethod("SearchTraces", func(ctx context.Context, sr spanreader.SpanReader, req *spansquery.SearchTracesRequest) (any, error) {
+			res, err := sr.SearchTraces(ctx, *req)
+			if err != nil {
+				return nil, err
+			}
+			return &searchTracesResponse{Metadata: res.Metadata, Traces: toWireTraces(res.Traces), SpansTruncated: res.SpansTruncated}, nil
+		}),
+		readerMethod("Aggregate", func(ctx context.Context, sr spanreader.SpanReader, req *spansquery.SearchRequest) (any, error) {
+			res, err := sr.Aggregate(ctx, *req)
+			if err != nil {
+				return nil, err
+			}
+			return &spansquery.SearchResponse{Metadata: res.Metadata, Aggregations: res.Aggregations}, nil
+		}),
+		readerMethod("GetSystemId", func(ctx context.Context, sr spanreader.SpanReader, req *metadata.GetSystemIdRequest) (any, error) {
+			return sr.GetSystemId(ctx, *req)
+		}),
+		readerMethod("SetSystemId", func(ctx context.Context, sr spanreader.SpanReader, req *metadata.SetSystemIdRequest) (any, error) {
+			return sr.SetSystemId(ctx, *req)
+		}),
+		readerMethod("Ping", func(ctx context.Context, sr spanreader.SpanReader, _ *empty) (any, error) {
+			return &empty{}, sr.Ping(ctx)
+		}),
+	},
+}
+
+var writerServiceDesc = grpc.ServiceDesc{
+	ServiceName: writerServiceName,
+	HandlerType: (*SpanWriter)(nil),
+	Methods: []grpc.MethodDesc{
+		unaryMethod(writerServiceName, "WriteSpans", func(ctx context.Context, sw SpanWriter, req *writeSpansRequest) (any, error) {
+			return &empty{}, sw.WriteSpans(ctx, fromWireSpans(req.Spans))
+		}),
+	},
+}
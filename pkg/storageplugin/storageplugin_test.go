@@ -0,0 +1,175 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package storageplugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	spanformatutiltests "github.com/teletrace/teletrace/model/internalspan/v1/util"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+const (
+	timeoutToken = "timeout"
+	invalidToken = "invalid"
+)
+
+// memoryStore serves the spans written to it.
+type memoryStore struct {
+	spanreader.SpanReader
+	mu    sync.Mutex
+	spans []*internalspan.InternalSpan
+}
+
+func (s *memoryStore) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	if r.Metadata != nil && r.Metadata.NextToken == timeoutToken {
+		return nil, fmt.Errorf("%w: search took too long", spanreader.ErrQueryTimeout)
+	}
+	if r.Metadata != nil && r.Metadata.NextToken == invalidToken {
+		return nil, fmt.Errorf("%w: malformed token", spanreader.ErrInvalidQuery)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &spansquery.SearchResponse{Metadata: &spansquery.Metadata{}, Spans: s.spans}, nil
+}
+
+func (s *memoryStore) WriteSpans(ctx context.Context, spans []*internalspan.InternalSpan) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.spans = append(s.spans, spans...)
+	return nil
+}
+
+// TestMain serves a plugin when the test binary is launched by a plugin client, so the tests launch it as the
+// plugin binary.
+func TestMain(m *testing.M) {
+	if os.Getenv(MagicCookieKey) == MagicCookieValue {
+		reader, _ := mock.NewSpanReaderMock()
+		store := &memoryStore{SpanReader: reader}
+		if err := Serve(store, store); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func newTestClient(t *testing.T) *Client {
+	path, err := os.Executable()
+	assert.NoError(t, err)
+	client, err := NewClient(zap.NewNop(), Config{Path: path, StartTimeout: 10 * time.Second})
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestWriteAndSearchSpans(t *testing.T) {
+	client := newTestClient(t)
+	sw, err := client.SpanWriter()
+	assert.NoError(t, err)
+	sr, err := client.SpanReader()
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	assert.NoError(t, sr.Initialize())
+	assert.NoError(t, sr.Ping(ctx))
+
+	span := spanformatutiltests.GenInternalSpan(map[string]any{"http.status_code": 200}, nil, nil)
+	span.Span.Events = []*internalspan.SpanEvent{{Name: "exception", TimeUnixNano: 15}}
+	span.Span.Links = []*internalspan.SpanLink{{TraceId: "abcd", SpanId: "ef01"}}
+	assert.NoError(t, sw.WriteSpans(ctx, []*internalspan.InternalSpan{span}))
+
+	res, err := sr.Search(ctx, spansquery.SearchRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, res.Spans, 1)
+	got := res.Spans[0].Span
+	assert.Equal(t, span.Span.SpanId, got.SpanId)
+	assert.Equal(t, "200", fmt.Sprint(got.Attributes["http.status_code"]))
+	assert.Equal(t, "exception", got.Events[0].Name)
+	assert.Equal(t, "abcd", got.Links[0].TraceId)
+}
+
+func TestSpanReaderMethods(t *testing.T) {
+	sr, err := newTestClient(t).SpanReader()
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	values, err := sr.GetTagsValues(ctx, tagsquery.TagValuesRequest{}, []string{"span.attributes.custom-tag"})
+	assert.NoError(t, err)
+	assert.Equal(t, "custom-value", values["span.attributes.custom-tag"].Values[0].Value)
+
+	stats, err := sr.GetTagsStatistics(ctx, tagsquery.TagStatisticsRequest{}, "span.attributes.duration")
+	assert.NoError(t, err)
+	assert.Equal(t, 9.0, stats.Statistics["p99"])
+
+	batch, err := sr.GetTagsStatisticsBatch(ctx, tagsquery.TagStatisticsRequest{}, []string{"span.attributes.duration", "span.attributes.size"})
+	assert.NoError(t, err)
+	assert.Len(t, batch, 2)
+	assert.Equal(t, 9.0, batch["span.attributes.size"].Statistics["p99"])
+
+	tags, err := sr.GetAvailableTags(ctx, tagsquery.GetAvailableTagsRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "custom-tag", tags.Tags[0].Name)
+
+	traces, err := sr.SearchTraces(ctx, spansquery.SearchTracesRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, traces.Traces, 1)
+	assert.Equal(t, traces.Traces[0].TraceId, traces.Traces[0].Spans[0].Span.TraceId)
+}
+
+func TestErrorsKeepTheirCause(t *testing.T) {
+	sr, err := newTestClient(t).SpanReader()
+	assert.NoError(t, err)
+
+	_, err = sr.Search(context.Background(), spansquery.SearchRequest{Metadata: &spansquery.Metadata{NextToken: timeoutToken}})
+	assert.ErrorIs(t, err, spanreader.ErrQueryTimeout)
+
+	_, err = sr.Search(context.Background(), spansquery.SearchRequest{Metadata: &spansquery.Metadata{NextToken: invalidToken}})
+	assert.ErrorIs(t, err, spanreader.ErrInvalidQuery)
+	assert.EqualError(t, err, "invalid query: malformed token")
+}
+
+func TestHandshake(t *testing.T) {
+	c := &Client{logger: zap.NewNop()}
+	assert.ErrorContains(t, c.connect("2|unix|/tmp/plugin.sock|"+readerServiceName), "version 2")
+	assert.ErrorContains(t, c.connect("1|tcp|localhost:1234|"+readerServiceName), "network")
+	assert.ErrorContains(t, c.connect("listening"), "unexpected")
+
+	assert.NoError(t, c.connect(handshake("/tmp/plugin.sock", true, false)))
+	defer c.conn.Close()
+	_, err := c.SpanReader()
+	assert.NoError(t, err)
+	_, err = c.SpanWriter()
+	assert.ErrorIs(t, err, ErrServiceNotServed)
+}
+
+func TestServeNotLaunched(t *testing.T) {
+	reader, _ := mock.NewSpanReaderMock()
+	assert.ErrorIs(t, Serve(reader, nil), ErrNotLaunched)
+}
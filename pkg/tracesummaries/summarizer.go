@@ -0,0 +1,167 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package tracesummaries
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	tracesummaries "github.com/teletrace/teletrace/pkg/model/tracesummaries/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"go.uber.org/zap"
+)
+
+const errorStatusCode = "Error"
+
+// Config bounds the work of a single summarization.
+type Config struct {
+	// MaxTraces is the maximum number of traces summarized, regardless of the requested limit
+	MaxTraces int
+}
+
+// Summarizer aggregates the spans of the traces matching a search into per-trace summaries,
+// so results can be listed as traces rather than as raw spans.
+type Summarizer struct {
+	logger     *zap.Logger
+	spanReader spanreader.SpanReader
+	cfg        Config
+}
+
+func NewSummarizer(logger *zap.Logger, sr spanreader.SpanReader, cfg Config) *Summarizer {
+	return &Summarizer{
+		logger:     logger,
+		spanReader: sr,
+		cfg:        cfg,
+	}
+}
+
+// Summarize summarizes the traces of the most recent spans matching the request, up to the requested limit
+// and the configured maximum number of traces.
+func (s *Summarizer) Summarize(ctx context.Context, req tracesummaries.SummarizeRequest) (*tracesummaries.SummarizeResponse, error) {
+	limit := req.EffectiveLimit()
+	if s.cfg.MaxTraces > 0 && limit > s.cfg.MaxTraces {
+		limit = s.cfg.MaxTraces
+	}
+
+	traceIds, truncated, err := s.matchingTraces(ctx, req, limit)
+	if err != nil {
+		return nil, fmt.Errorf("could not find matching traces: %w", err)
+	}
+
+	summaries := make([]*tracesummaries.TraceSummary, 0, len(traceIds))
+	for _, traceId := range traceIds {
+		spans, err := spanreader.TraceSpans(ctx, s.spanReader, traceId)
+		if err != nil {
+			return nil, fmt.Errorf("could not get spans of trace %s: %w", traceId, err)
+		}
+		summaries = append(summaries, summarize(traceId, spans))
+	}
+
+	return &tracesummaries.SummarizeResponse{
+		Traces:    summaries,
+		Truncated: truncated,
+	}, nil
+}
+
+// matchingTraces returns the IDs of the traces of the most recent spans matching the request, up to limit traces.
+func (s *Summarizer) matchingTraces(ctx context.Context, req tracesummaries.SummarizeRequest, limit int) ([]string, bool, error) {
+	var traceIds []string
+	seen := make(map[string]bool)
+	var token spansquery.ContinuationToken
+	for {
+		res, err := s.spanReader.Search(ctx, spansquery.SearchRequest{
+			Timeframe:     req.Timeframe,
+			Sort:          []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: false}},
+			SearchFilters: spanreader.CopyFilters(req.SearchFilters),
+			Metadata:      &spansquery.Metadata{NextToken: token},
+		})
+		if err != nil {
+			return nil, false, err
+		}
+
+		for _, span := range res.Spans {
+			traceId := span.Span.TraceId
+			if seen[traceId] {
+				continue
+			}
+			if len(traceIds) == limit {
+				return traceIds, true, nil
+			}
+			seen[traceId] = true
+			traceIds = append(traceIds, traceId)
+		}
+
+		if len(res.Spans) == 0 || res.Metadata == nil || res.Metadata.NextToken == "" {
+			return traceIds, false, nil
+		}
+		token = res.Metadata.NextToken
+	}
+}
+
+// summarize aggregates the spans of a trace. The root span is the earliest span without a parent,
+// or the earliest span of the trace if none is without a parent, e.g. when the root span was not received yet.
+func summarize(traceId string, spans []*internalspan.InternalSpan) *tracesummaries.TraceSummary {
+	summary := &tracesummaries.TraceSummary{TraceId: traceId, Services: []string{}}
+	seenSpans := make(map[string]bool)
+	services := make(map[string]bool)
+	var root, earliest *internalspan.InternalSpan
+	var endTime uint64
+	for _, span := range spans {
+		if seenSpans[span.Span.SpanId] {
+			continue
+		}
+		seenSpans[span.Span.SpanId] = true
+
+		summary.SpanCount++
+		if span.Span.Status != nil && span.Span.Status.Code == errorStatusCode {
+			summary.ErrorCount++
+		}
+		if serviceName := spanreader.ServiceName(span); serviceName != "" && !services[serviceName] {
+			services[serviceName] = true
+			summary.Services = append(summary.Services, serviceName)
+		}
+
+		if earliest == nil || span.Span.StartTimeUnixNano < earliest.Span.StartTimeUnixNano {
+			earliest = span
+		}
+		if span.Span.EndTimeUnixNano > endTime {
+			endTime = span.Span.EndTimeUnixNano
+		}
+		if span.Span.ParentSpanId == "" && (root == nil || span.Span.StartTimeUnixNano < root.Span.StartTimeUnixNano) {
+			root = span
+		}
+	}
+	sort.Strings(summary.Services)
+
+	if root == nil {
+		root = earliest
+	}
+	if root != nil {
+		summary.RootSpanName = root.Span.Name
+		summary.RootServiceName = spanreader.ServiceName(root)
+	}
+	if earliest != nil {
+		summary.StartTimeUnixNano = earliest.Span.StartTimeUnixNano
+		if endTime > summary.StartTimeUnixNano {
+			summary.DurationNano = endTime - summary.StartTimeUnixNano
+		}
+	}
+	return summary
+}
@@ -0,0 +1,155 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package tracesummaries
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	tracesummaries "github.com/teletrace/teletrace/pkg/model/tracesummaries/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/mock"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+const pageSize = 2
+
+// traceSpanReader serves the spans it holds in the given order, in pages of pageSize spans.
+type traceSpanReader struct {
+	spanreader.SpanReader
+	spans []*internalspan.InternalSpan
+}
+
+func (sr *traceSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	var matching []*internalspan.InternalSpan
+	for _, span := range sr.spans {
+		if matchesFilters(span, r) {
+			matching = append(matching, span)
+		}
+	}
+
+	offset, _ := strconv.Atoi(string(r.Metadata.NextToken))
+	end := offset + pageSize
+	metadata := &spansquery.Metadata{NextToken: spansquery.ContinuationToken(strconv.Itoa(end))}
+	if end >= len(matching) {
+		end = len(matching)
+		metadata.NextToken = ""
+	}
+	return &spansquery.SearchResponse{Metadata: metadata, Spans: matching[offset:end]}, nil
+}
+
+func matchesFilters(span *internalspan.InternalSpan, r spansquery.SearchRequest) bool {
+	for _, f := range r.SearchFilters {
+		switch f.KeyValueFilter.Key {
+		case "span.traceId":
+			if span.Span.TraceId != f.KeyValueFilter.Value {
+				return false
+			}
+		case "span.name":
+			if span.Span.Name != f.KeyValueFilter.Value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func newSpan(traceId string, spanId string, parentSpanId string, service string, name string, start uint64, end uint64, statusCode string) *internalspan.InternalSpan {
+	return &internalspan.InternalSpan{
+		Resource: &internalspan.Resource{Attributes: internalspan.Attributes{"service.name": service}},
+		Span: &internalspan.Span{
+			TraceId:           traceId,
+			SpanId:            spanId,
+			ParentSpanId:      parentSpanId,
+			Name:              name,
+			StartTimeUnixNano: start,
+			EndTimeUnixNano:   end,
+			Status:            &internalspan.SpanStatus{Code: statusCode},
+		},
+	}
+}
+
+func newSummarizer(maxTraces int) *Summarizer {
+	srMock, _ := mock.NewSpanReaderMock()
+	sr := &traceSpanReader{SpanReader: srMock, spans: []*internalspan.InternalSpan{
+		newSpan("t2", "e", "d", "cart", "SELECT", 110, 260, "Error"),
+		newSpan("t2", "d", "", "frontend", "GET /cart", 100, 300, "Unset"),
+		newSpan("t1", "c", "a", "db", "SELECT", 60, 80, "Error"),
+		newSpan("t1", "b", "a", "cart", "SELECT", 10, 120, "Error"),
+		newSpan("t1", "a", "", "frontend", "GET /cart", 0, 100, "Ok"),
+		newSpan("t3", "g", "f", "cart", "SELECT", 5, 20, "Unset"),
+	}}
+	return NewSummarizer(zap.NewNop(), sr, Config{MaxTraces: maxTraces})
+}
+
+func TestSummarize(t *testing.T) {
+	res, err := newSummarizer(10).Summarize(context.Background(), tracesummaries.SummarizeRequest{
+		SearchFilters: []model.SearchFilter{{KeyValueFilter: &model.KeyValueFilter{
+			Key: "span.name", Operator: spansquery.OPERATOR_EQUALS, Value: "SELECT",
+		}}},
+	})
+	assert.NoError(t, err)
+	assert.False(t, res.Truncated)
+	assert.Equal(t, []*tracesummaries.TraceSummary{
+		{
+			TraceId:           "t2",
+			RootSpanName:      "GET /cart",
+			RootServiceName:   "frontend",
+			SpanCount:         2,
+			ErrorCount:        1,
+			StartTimeUnixNano: 100,
+			DurationNano:      200,
+			Services:          []string{"cart", "frontend"},
+		},
+		{
+			TraceId:           "t1",
+			RootSpanName:      "GET /cart",
+			RootServiceName:   "frontend",
+			SpanCount:         3,
+			ErrorCount:        2,
+			StartTimeUnixNano: 0,
+			DurationNano:      120,
+			Services:          []string{"cart", "db", "frontend"},
+		},
+		{
+			TraceId:           "t3",
+			RootSpanName:      "SELECT",
+			RootServiceName:   "cart",
+			SpanCount:         1,
+			StartTimeUnixNano: 5,
+			DurationNano:      15,
+			Services:          []string{"cart"},
+		},
+	}, res.Traces)
+}
+
+func TestSummarizeTruncates(t *testing.T) {
+	res, err := newSummarizer(10).Summarize(context.Background(), tracesummaries.SummarizeRequest{Limit: 1})
+	assert.NoError(t, err)
+	assert.True(t, res.Truncated)
+	assert.Len(t, res.Traces, 1)
+
+	res, err = newSummarizer(2).Summarize(context.Background(), tracesummaries.SummarizeRequest{})
+	assert.NoError(t, err)
+	assert.True(t, res.Truncated)
+	assert.Len(t, res.Traces, 2)
+}
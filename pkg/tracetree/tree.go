@@ -0,0 +1,139 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package tracetree
+
+import (
+	"sort"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	tracetree "github.com/teletrace/teletrace/pkg/model/tracetree/v1"
+)
+
+// Build assembles the spans of a trace into a tree, returning its roots ordered by start time.
+// Spans whose parent is missing from the trace, or which are part of a cycle of corrupted parent IDs,
+// are placed at the root and marked as orphans.
+func Build(spans []*internalspan.InternalSpan) []*tracetree.Node {
+	nodes := make(map[string]*tracetree.Node, len(spans))
+	ordered := make([]*tracetree.Node, 0, len(spans))
+	for _, span := range spans {
+		// the first copy of a span written more than once is kept
+		if _, ok := nodes[span.Span.SpanId]; ok {
+			continue
+		}
+		node := &tracetree.Node{Span: span, Children: []*tracetree.Node{}}
+		nodes[span.Span.SpanId] = node
+		ordered = append(ordered, node)
+	}
+	sortNodes(ordered)
+
+	roots := make([]*tracetree.Node, 0)
+	for _, node := range ordered {
+		parentSpanId := node.Span.Span.ParentSpanId
+		parent, ok := nodes[parentSpanId]
+		if !ok || parentSpanId == node.Span.Span.SpanId {
+			node.Orphan = parentSpanId != ""
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+
+	visited := make(map[*tracetree.Node]bool, len(ordered))
+	for _, root := range roots {
+		visit(root, 0, visited)
+	}
+	// spans in a cycle aren't reachable from any root, the earliest of each cycle is made a root
+	for _, node := range ordered {
+		if visited[node] {
+			continue
+		}
+		node.Orphan = true
+		roots = append(roots, node)
+		visit(node, 0, visited)
+	}
+	sortNodes(roots)
+	return roots
+}
+
+// visit sets the depth and self time of node and its descendants.
+func visit(node *tracetree.Node, depth int, visited map[*tracetree.Node]bool) {
+	visited[node] = true
+	node.Depth = depth
+	children := node.Children[:0]
+	for _, child := range node.Children {
+		// a child already visited closes a cycle
+		if !visited[child] {
+			children = append(children, child)
+		}
+	}
+	node.Children = children
+	node.SelfTimeNano = selfTime(node)
+	for _, child := range node.Children {
+		visit(child, depth+1, visited)
+	}
+}
+
+// selfTime returns the duration of the span of node not covered by its children, whose time ranges
+// are clipped to the span, as children may end after their parent (e.g. asynchronous work).
+func selfTime(node *tracetree.Node) uint64 {
+	start, end := node.Span.Span.StartTimeUnixNano, node.Span.Span.EndTimeUnixNano
+	if end <= start {
+		return 0
+	}
+	covered := uint64(0)
+	coveredUntil := start
+	// children are ordered by start time, so their overlapping ranges are merged in one pass
+	for _, child := range node.Children {
+		childStart, childEnd := child.Span.Span.StartTimeUnixNano, child.Span.Span.EndTimeUnixNano
+		if childStart < coveredUntil {
+			childStart = coveredUntil
+		}
+		if childEnd > end {
+			childEnd = end
+		}
+		if childEnd > childStart {
+			covered += childEnd - childStart
+			coveredUntil = childEnd
+		}
+	}
+	return end - start - covered
+}
+
+func sortNodes(nodes []*tracetree.Node) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, b := nodes[i].Span.Span, nodes[j].Span.Span
+		if a.StartTimeUnixNano != b.StartTimeUnixNano {
+			return a.StartTimeUnixNano < b.StartTimeUnixNano
+		}
+		return a.SpanId < b.SpanId
+	})
+}
+
+// MaxDepth returns the depth of the deepest node of the trees.
+func MaxDepth(roots []*tracetree.Node) int {
+	maxDepth := 0
+	var walk func(nodes []*tracetree.Node)
+	walk = func(nodes []*tracetree.Node) {
+		for _, node := range nodes {
+			if node.Depth > maxDepth {
+				maxDepth = node.Depth
+			}
+			walk(node.Children)
+		}
+	}
+	walk(roots)
+	return maxDepth
+}
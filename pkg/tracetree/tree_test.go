@@ -0,0 +1,85 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package tracetree
+
+import (
+	"testing"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	tracetree "github.com/teletrace/teletrace/pkg/model/tracetree/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSpan(spanId string, parentSpanId string, start uint64, end uint64) *internalspan.InternalSpan {
+	return &internalspan.InternalSpan{
+		Span: &internalspan.Span{
+			SpanId:            spanId,
+			ParentSpanId:      parentSpanId,
+			StartTimeUnixNano: start,
+			EndTimeUnixNano:   end,
+		},
+	}
+}
+
+func spanIds(nodes []*tracetree.Node) []string {
+	ids := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.Span.Span.SpanId)
+	}
+	return ids
+}
+
+func TestBuild(t *testing.T) {
+	roots := Build([]*internalspan.InternalSpan{
+		newSpan("second", "root", 1400, 1900),
+		newSpan("first", "root", 1100, 1500),
+		newSpan("leaf", "first", 1200, 1300),
+		newSpan("async", "second", 1800, 2500),
+		newSpan("root", "", 1000, 2000),
+		newSpan("orphan", "missing", 500, 600),
+	})
+
+	assert.Equal(t, []string{"orphan", "root"}, spanIds(roots))
+	assert.True(t, roots[0].Orphan)
+	root := roots[1]
+	assert.False(t, root.Orphan)
+	assert.Equal(t, []string{"first", "second"}, spanIds(root.Children))
+	// the overlapping children cover 1100-1900
+	assert.Equal(t, uint64(200), root.SelfTimeNano)
+	first, second := root.Children[0], root.Children[1]
+	assert.Equal(t, 1, first.Depth)
+	assert.Equal(t, uint64(300), first.SelfTimeNano)
+	assert.Equal(t, 2, first.Children[0].Depth)
+	assert.Equal(t, uint64(100), first.Children[0].SelfTimeNano)
+	// the asynchronous child is clipped to its parent
+	assert.Equal(t, uint64(400), second.SelfTimeNano)
+	assert.Equal(t, 2, MaxDepth(roots))
+}
+
+func TestBuildBreaksCycles(t *testing.T) {
+	roots := Build([]*internalspan.InternalSpan{
+		newSpan("a", "b", 100, 200),
+		newSpan("b", "a", 150, 160),
+		newSpan("self", "self", 300, 400),
+	})
+
+	assert.Equal(t, []string{"a", "self"}, spanIds(roots))
+	assert.Equal(t, []string{"b"}, spanIds(roots[0].Children))
+	assert.Empty(t, roots[0].Children[0].Children)
+	assert.True(t, roots[0].Orphan)
+	assert.True(t, roots[1].Orphan)
+}
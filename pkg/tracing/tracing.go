@@ -0,0 +1,131 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync/atomic"
+
+	"github.com/teletrace/teletrace/pkg/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const serviceName = "teletrace"
+
+// rootSampler samples the requests not traced by their caller, and can be changed by SetSampleRatio at runtime
+var rootSampler = &ratioSampler{}
+
+type ratioSampler struct {
+	sampler atomic.Pointer[sdktrace.Sampler]
+}
+
+func (s *ratioSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return (*s.sampler.Load()).ShouldSample(p)
+}
+
+func (s *ratioSampler) Description() string {
+	return (*s.sampler.Load()).Description()
+}
+
+// NewTracerProvider creates a tracer provider exporting the spans of teletrace itself over OTLP/HTTP,
+// either to the OTLP receiver of teletrace or to any other tracing backend.
+// It is registered as the global tracer provider, so the spans started by the storage plugins are exported as well.
+func NewTracerProvider(ctx context.Context, cfg config.Config) (*sdktrace.TracerProvider, error) {
+	if err := SetSampleRatio(cfg); err != nil {
+		return nil, err
+	}
+	opts, err := exporterOptions(cfg.SelfTracingEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create self tracing exporter: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+		// requests traced by the caller, e.g. the frontend, are always recorded
+		sdktrace.WithSampler(sdktrace.ParentBased(rootSampler)),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return tp, nil
+}
+
+// SetSampleRatio changes the ratio of the sampled requests not traced by their caller to cfg.SelfTracingSampleRatio.
+func SetSampleRatio(cfg config.Config) error {
+	if cfg.SelfTracingSampleRatio < 0 || cfg.SelfTracingSampleRatio > 1 {
+		return fmt.Errorf("self tracing sample ratio must be between 0 and 1, got %v", cfg.SelfTracingSampleRatio)
+	}
+	sampler := sdktrace.TraceIDRatioBased(cfg.SelfTracingSampleRatio)
+	rootSampler.sampler.Store(&sampler)
+	return nil
+}
+
+// exporterOptions returns the OTLP/HTTP exporter options for sending spans to endpoint,
+// e.g. http://localhost:4318 or https://collector.example.com/v1/traces.
+func exporterOptions(endpoint string) ([]otlptracehttp.Option, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid self tracing endpoint %s: %w", endpoint, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid self tracing endpoint %s: missing host", endpoint)
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host)}
+	switch u.Scheme {
+	case "http":
+		opts = append(opts, otlptracehttp.WithInsecure())
+	case "https":
+	default:
+		return nil, fmt.Errorf("invalid self tracing endpoint %s: unsupported scheme %s", endpoint, u.Scheme)
+	}
+	if u.Path != "" && u.Path != "/" {
+		opts = append(opts, otlptracehttp.WithURLPath(u.Path))
+	}
+	return opts, nil
+}
+
+// RecordError marks span as failed unless err is nil or the cancellation of the traced operation,
+// and returns err.
+func RecordError(span trace.Span, err error) error {
+	if err != nil && !errors.Is(err, context.Canceled) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// EndSpan records err on span and ends it.
+func EndSpan(span trace.Span, err error) {
+	_ = RecordError(span, err)
+	span.End()
+}
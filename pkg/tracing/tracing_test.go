@@ -0,0 +1,91 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/teletrace/teletrace/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestExporterOptions(t *testing.T) {
+	for endpoint, optsCount := range map[string]int{
+		"http://localhost:4318":                     2,
+		"https://collector.example.com/v1/traces":   2,
+		"http://localhost:4318/custom/path/traces/": 3,
+	} {
+		opts, err := exporterOptions(endpoint)
+		assert.NoError(t, err, endpoint)
+		assert.Len(t, opts, optsCount, endpoint)
+	}
+
+	for _, endpoint := range []string{"localhost:4318", "grpc://localhost:4317", "http://"} {
+		_, err := exporterOptions(endpoint)
+		assert.Error(t, err, endpoint)
+	}
+}
+
+func TestNewTracerProviderValidatesSampleRatio(t *testing.T) {
+	_, err := NewTracerProvider(context.Background(), config.Config{
+		SelfTracingEndpoint:    "http://localhost:4318",
+		SelfTracingSampleRatio: 2,
+	})
+	assert.Error(t, err)
+}
+
+func TestEndSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	for _, err := range []error{nil, errors.New("connection refused"), context.Canceled} {
+		_, span := tracer.Start(context.Background(), "query")
+		EndSpan(span, err)
+	}
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 3)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Len(t, spans[1].Events(), 1, "the error is recorded as a span event")
+	assert.Equal(t, codes.Unset, spans[2].Status().Code, "canceled operations aren't failures")
+}
+
+func TestSetSampleRatio(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(rootSampler), sdktrace.WithSpanProcessor(recorder),
+	).Tracer("test")
+
+	assert.NoError(t, SetSampleRatio(config.Config{SelfTracingSampleRatio: 0}))
+	_, span := tracer.Start(context.Background(), "request")
+	span.End()
+	assert.Empty(t, recorder.Ended())
+
+	assert.NoError(t, SetSampleRatio(config.Config{SelfTracingSampleRatio: 1}))
+	_, span = tracer.Start(context.Background(), "request")
+	span.End()
+	assert.Len(t, recorder.Ended(), 1)
+
+	assert.Error(t, SetSampleRatio(config.Config{SelfTracingSampleRatio: -1}))
+}
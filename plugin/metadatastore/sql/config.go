@@ -0,0 +1,49 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlmetadatastore
+
+import "github.com/teletrace/teletrace/pkg/config"
+
+const (
+	sqliteDriverName   = "sqlite3"
+	postgresDriverName = "postgres"
+)
+
+type SqlConfig struct {
+	Driver     string
+	DataSource string
+}
+
+// NewSqliteConfig returns the sqlite metadata store config,
+// falling back to the spans sqlite database when no dedicated path is configured.
+func NewSqliteConfig(cfg config.Config) SqlConfig {
+	path := cfg.MetadataSQLitePath
+	if path == "" {
+		path = cfg.SQLitePath
+	}
+	return SqlConfig{
+		Driver:     sqliteDriverName,
+		DataSource: path,
+	}
+}
+
+func NewPostgresConfig(cfg config.Config) SqlConfig {
+	return SqlConfig{
+		Driver:     postgresDriverName,
+		DataSource: cfg.MetadataPostgresDSN,
+	}
+}
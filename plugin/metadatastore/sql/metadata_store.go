@@ -0,0 +1,185 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlmetadatastore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	"github.com/teletrace/teletrace/pkg/migrations"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"go.uber.org/zap"
+)
+
+const tableName = "metadata_records"
+
+type metadataStore struct {
+	cfg    SqlConfig
+	logger *zap.Logger
+	ctx    context.Context
+	db     *sql.DB
+}
+
+// NewSqlMetadataStore returns a MetadataStore backed by a sqlite or Postgres database.
+func NewSqlMetadataStore(ctx context.Context, logger *zap.Logger, cfg SqlConfig) (metadatastore.MetadataStore, error) {
+	if cfg.Driver != sqliteDriverName && cfg.Driver != postgresDriverName {
+		return nil, fmt.Errorf("unsupported metadata store driver %s", cfg.Driver)
+	}
+	db, err := sql.Open(cfg.Driver, cfg.DataSource)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create a new metadata store for %s: %w", cfg.Driver, err)
+	}
+	return &metadataStore{
+		cfg:    cfg,
+		logger: logger,
+		ctx:    ctx,
+		db:     db,
+	}, nil
+}
+
+// Initialize applies the pending schema migrations of the metadata store.
+func (s *metadataStore) Initialize() error {
+	store := migrationStore{store: s}
+	if err := store.init(s.ctx); err != nil {
+		return err
+	}
+	migrator, err := migrations.NewMigrator(s.logger, "metadata store", store, s.schemaMigrations(), migrations.NewDefaultConfig())
+	if err != nil {
+		return err
+	}
+	if err := migrator.Up(s.ctx); err != nil {
+		return fmt.Errorf("failed to migrate metadata store: %w", err)
+	}
+	return nil
+}
+
+func (s *metadataStore) Get(ctx context.Context, namespace string, key string) (*metadatastore.Record, error) {
+	query := s.rebind(fmt.Sprintf(
+		"SELECT record_value, updated_at FROM %s WHERE namespace = ? AND record_key = ?", tableName,
+	))
+	record := metadatastore.Record{Namespace: namespace, Key: key}
+	var updatedAt int64
+	err := s.db.QueryRowContext(ctx, query, namespace, key).Scan(&record.Value, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, metadatastore.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata record: %w", err)
+	}
+	record.UpdatedAt = time.Unix(0, updatedAt).UTC()
+	return &record, nil
+}
+
+func (s *metadataStore) List(ctx context.Context, namespace string) ([]metadatastore.Record, error) {
+	query := s.rebind(fmt.Sprintf(
+		"SELECT record_key, record_value, updated_at FROM %s WHERE namespace = ? ORDER BY record_key", tableName,
+	))
+	rows, err := s.db.QueryContext(ctx, query, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list metadata records: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]metadatastore.Record, 0)
+	for rows.Next() {
+		record := metadatastore.Record{Namespace: namespace}
+		var updatedAt int64
+		if err := rows.Scan(&record.Key, &record.Value, &updatedAt); err != nil {
+			s.logger.Error("failed to get metadata record value", zap.Error(err))
+			continue
+		}
+		record.UpdatedAt = time.Unix(0, updatedAt).UTC()
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list metadata records: %w", err)
+	}
+	return records, nil
+}
+
+func (s *metadataStore) Put(ctx context.Context, record metadatastore.Record) error {
+	query := s.rebind(fmt.Sprintf(`INSERT INTO %s (namespace, record_key, record_value, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (namespace, record_key) DO UPDATE SET record_value = excluded.record_value, updated_at = excluded.updated_at`,
+		tableName,
+	))
+	_, err := s.db.ExecContext(ctx, query, record.Namespace, record.Key, record.Value, time.Now().UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to put metadata record: %w", err)
+	}
+	return nil
+}
+
+func (s *metadataStore) Create(ctx context.Context, record metadatastore.Record) error {
+	query := s.rebind(fmt.Sprintf(`INSERT INTO %s (namespace, record_key, record_value, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (namespace, record_key) DO NOTHING`,
+		tableName,
+	))
+	res, err := s.db.ExecContext(ctx, query, record.Namespace, record.Key, record.Value, time.Now().UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to create metadata record: %w", err)
+	}
+	return expectAffectedRow(res, metadatastore.ErrAlreadyExists)
+}
+
+func (s *metadataStore) Delete(ctx context.Context, namespace string, key string) error {
+	query := s.rebind(fmt.Sprintf("DELETE FROM %s WHERE namespace = ? AND record_key = ?", tableName))
+	res, err := s.db.ExecContext(ctx, query, namespace, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete metadata record: %w", err)
+	}
+	return expectAffectedRow(res, metadatastore.ErrNotFound)
+}
+
+func (s *metadataStore) Close() error {
+	return s.db.Close()
+}
+
+// rebind replaces "?" placeholders with the driver's placeholder style.
+func (s *metadataStore) rebind(query string) string {
+	if s.cfg.Driver != postgresDriverName {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func expectAffectedRow(res sql.Result, errNoRows error) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected metadata records: %w", err)
+	}
+	if affected == 0 {
+		return errNoRows
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlmetadatastore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	"github.com/teletrace/teletrace/pkg/migrations"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newTestStore(t *testing.T) metadatastore.MetadataStore {
+	cfg := SqlConfig{Driver: sqliteDriverName, DataSource: filepath.Join(t.TempDir(), "metadata.db")}
+	store, err := NewSqlMetadataStore(context.Background(), zap.NewNop(), cfg)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Initialize())
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestSqliteMetadataStore(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	_, err := store.Get(ctx, "alerts", "a")
+	assert.ErrorIs(t, err, metadatastore.ErrNotFound)
+
+	assert.NoError(t, store.Create(ctx, metadatastore.Record{Namespace: "alerts", Key: "b", Value: []byte("2")}))
+	assert.ErrorIs(t, store.Create(ctx, metadatastore.Record{Namespace: "alerts", Key: "b"}), metadatastore.ErrAlreadyExists)
+	assert.NoError(t, store.Put(ctx, metadatastore.Record{Namespace: "alerts", Key: "a", Value: []byte("1")}))
+	assert.NoError(t, store.Put(ctx, metadatastore.Record{Namespace: "alerts", Key: "a", Value: []byte("11")}))
+	assert.NoError(t, store.Put(ctx, metadatastore.Record{Namespace: "jobs", Key: "c", Value: []byte("3")}))
+
+	record, err := store.Get(ctx, "alerts", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("11"), record.Value)
+	assert.False(t, record.UpdatedAt.IsZero())
+
+	records, err := store.List(ctx, "alerts")
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "a", records[0].Key)
+	assert.Equal(t, "b", records[1].Key)
+
+	assert.NoError(t, store.Delete(ctx, "alerts", "a"))
+	assert.ErrorIs(t, store.Delete(ctx, "alerts", "a"), metadatastore.ErrNotFound)
+}
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t).(*metadataStore)
+	assert.NoError(t, store.Initialize())
+	migrationStore := migrationStore{store: store}
+
+	version, err := migrationStore.Version(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, len(store.schemaMigrations()), version)
+
+	locked, err := migrationStore.Lock(ctx, "a", time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, locked)
+	locked, err = migrationStore.Lock(ctx, "b", time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, locked)
+	locked, err = migrationStore.Lock(ctx, "a", time.Now())
+	assert.NoError(t, err)
+	assert.True(t, locked)
+	locked, err = migrationStore.Lock(ctx, "b", time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, locked)
+	assert.NoError(t, migrationStore.Unlock(ctx, "b"))
+
+	assert.NoError(t, migrationStore.SetVersion(ctx, version+1))
+	assert.ErrorIs(t, store.Initialize(), migrations.ErrSchemaTooNew)
+}
+
+func TestRebind(t *testing.T) {
+	sqlite := &metadataStore{cfg: SqlConfig{Driver: sqliteDriverName}}
+	postgres := &metadataStore{cfg: SqlConfig{Driver: postgresDriverName}}
+	query := "SELECT * FROM t WHERE a = ? AND b = ?"
+
+	assert.Equal(t, query, sqlite.rebind(query))
+	assert.Equal(t, "SELECT * FROM t WHERE a = $1 AND b = $2", postgres.rebind(query))
+}
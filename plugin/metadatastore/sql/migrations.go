@@ -0,0 +1,114 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package sqlmetadatastore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/migrations"
+)
+
+// schemaComponent identifies the metadata store in the schema tables, which other components may share.
+const schemaComponent = "metadata_records"
+
+// schemaMigrations returns the migrations of the metadata store, which may only be appended to.
+func (s *metadataStore) schemaMigrations() []migrations.Migration {
+	return []migrations.Migration{
+		{
+			Version:     1,
+			Description: "create the metadata records table",
+			Up: func(ctx context.Context) error {
+				valueType := "BLOB"
+				if s.cfg.Driver == postgresDriverName {
+					valueType = "BYTEA"
+				}
+				// databases created before migrations were versioned already have the table
+				_, err := s.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+					namespace TEXT NOT NULL,
+					record_key TEXT NOT NULL,
+					record_value %s,
+					updated_at BIGINT NOT NULL,
+					PRIMARY KEY (namespace, record_key)
+				)`, tableName, valueType))
+				return err
+			},
+		},
+	}
+}
+
+// migrationStore keeps the schema version and the migrations lock of the metadata store in tables of its database.
+type migrationStore struct {
+	store *metadataStore
+}
+
+func (m migrationStore) init(ctx context.Context) error {
+	for _, query := range []string{
+		"CREATE TABLE IF NOT EXISTS schema_versions (component TEXT PRIMARY KEY, version INTEGER NOT NULL)",
+		"CREATE TABLE IF NOT EXISTS schema_locks (component TEXT PRIMARY KEY, owner TEXT NOT NULL, expires_at BIGINT NOT NULL)",
+	} {
+		if _, err := m.store.db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to create schema tables: %w", err)
+		}
+	}
+	return nil
+}
+
+func (m migrationStore) Version(ctx context.Context) (int, error) {
+	var version int
+	err := m.store.db.QueryRowContext(ctx,
+		m.store.rebind("SELECT version FROM schema_versions WHERE component = ?"), schemaComponent,
+	).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return version, err
+}
+
+func (m migrationStore) SetVersion(ctx context.Context, version int) error {
+	_, err := m.store.db.ExecContext(ctx, m.store.rebind(`INSERT INTO schema_versions (component, version) VALUES (?, ?)
+		ON CONFLICT (component) DO UPDATE SET version = excluded.version`),
+		schemaComponent, version,
+	)
+	return err
+}
+
+// Lock inserts the lock row, or takes it over if it expired or is held by the same owner.
+func (m migrationStore) Lock(ctx context.Context, owner string, expiresAt time.Time) (bool, error) {
+	res, err := m.store.db.ExecContext(ctx, m.store.rebind(`INSERT INTO schema_locks (component, owner, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (component) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at
+		WHERE schema_locks.expires_at < ? OR schema_locks.owner = excluded.owner`),
+		schemaComponent, owner, expiresAt.UnixNano(), time.Now().UnixNano(),
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (m migrationStore) Unlock(ctx context.Context, owner string) error {
+	_, err := m.store.db.ExecContext(ctx,
+		m.store.rebind("DELETE FROM schema_locks WHERE component = ? AND owner = ?"), schemaComponent, owner,
+	)
+	return err
+}
@@ -0,0 +1,161 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package badgerspanreader
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/teletrace/teletrace/kvstore"
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// indexedFields are the tags of the span fields written to the tag index, besides the resource and span attributes,
+// see kvstore/write.go
+var indexedFields = map[string]bool{
+	"span.name":        true,
+	"span.kind":        true,
+	"span.status.code": true,
+	"span.events.name": true,
+	"scope.name":       true,
+}
+
+// queryPlan describes how the candidate spans of a request are read, either from the trace keys (when filtering by
+// trace ID), from the tag index keys of a tag (when filtering by the values of an indexed tag) or from the span keys,
+// before they are filtered in memory.
+type queryPlan struct {
+	traceIds  []string
+	tagKey    string
+	tagValues []string
+	// start and end are the timeframe of the index scans
+	start uint64
+	end   uint64
+}
+
+// newQueryPlan returns the plan reading the candidate spans of the filters in the timeframe [start, end].
+func newQueryPlan(filters []model.SearchFilter, start uint64, end uint64) queryPlan {
+	plan := queryPlan{start: start, end: end}
+	if traceIds, ok := traceIdsFilter(filters); ok {
+		plan.traceIds = traceIds
+		return plan
+	}
+	if key, values, ok := tagIndexFilter(filters); ok {
+		plan.tagKey, plan.tagValues = key, values
+	}
+	return plan
+}
+
+// query returns the key ranges read by the plan, for the debug info of searches.
+func (p queryPlan) query() string {
+	if len(p.traceIds) > 0 {
+		ranges := make([]string, 0, len(p.traceIds))
+		for _, traceId := range p.traceIds {
+			ranges = append(ranges, fmt.Sprintf("r/%s/*", traceId))
+		}
+		return strings.Join(ranges, "\n")
+	}
+	if p.tagKey != "" {
+		ranges := make([]string, 0, len(p.tagValues))
+		for _, value := range p.tagValues {
+			ranges = append(ranges, fmt.Sprintf("i/%s/%s/[%d, %d]", p.tagKey, value, p.start, p.end))
+		}
+		return strings.Join(ranges, "\n")
+	}
+	return fmt.Sprintf("s/[%d, %d]", p.start, p.end)
+}
+
+// describe returns the steps of the plan, for the debug info of searches.
+func (p queryPlan) describe() []string {
+	if len(p.traceIds) > 0 {
+		return []string{fmt.Sprintf("read the trace keys of %d traces", len(p.traceIds))}
+	}
+	if p.tagKey != "" {
+		return []string{fmt.Sprintf("scan the tag index keys of %s for %d values, from the latest", p.tagKey, len(p.tagValues))}
+	}
+	return []string{"scan the span keys, from the latest"}
+}
+
+// traceIdsFilter returns the trace IDs of an equals or in filter on the trace ID.
+func traceIdsFilter(filters []model.SearchFilter) ([]string, bool) {
+	for _, f := range filters {
+		if f.KeyValueFilter == nil || f.KeyValueFilter.Key != "span.traceId" {
+			continue
+		}
+		if values, ok := filterValues(*f.KeyValueFilter); ok {
+			return values, true
+		}
+	}
+	return nil, false
+}
+
+// tagIndexFilter returns the key and the values of the first equals or in filter on an indexed tag.
+func tagIndexFilter(filters []model.SearchFilter) (string, []string, bool) {
+	for _, f := range filters {
+		if f.KeyValueFilter == nil {
+			continue
+		}
+		key, ok := indexedKey(string(f.KeyValueFilter.Key))
+		if !ok {
+			continue
+		}
+		if values, ok := filterValues(*f.KeyValueFilter); ok {
+			return key, values, true
+		}
+	}
+	return "", nil, false
+}
+
+// indexedKey returns the key of a filter key in the tag index, if it's indexed.
+func indexedKey(key string) (string, bool) {
+	key = strings.TrimSuffix(key, ".keyword")
+	switch {
+	case indexedFields[key], strings.HasPrefix(key, "span.attributes."), strings.HasPrefix(key, "resource.attributes."):
+		return key, kvstore.Indexable(key)
+	case strings.HasPrefix(key, "span.resource.attributes."):
+		key = strings.TrimPrefix(key, "span.")
+		return key, kvstore.Indexable(key)
+	}
+	return "", false
+}
+
+// filterValues returns the values of an equals or in filter as written to the tag index.
+// It returns false if any of them isn't indexed.
+func filterValues(f model.KeyValueFilter) ([]string, bool) {
+	value := spansquery.NormalizeFilterValue(f.Key, f.Value)
+	var values []any
+	switch f.Operator {
+	case spansquery.OPERATOR_EQUALS:
+		values = []any{value}
+	case spansquery.OPERATOR_IN:
+		values, _ = value.([]any)
+	default:
+		return nil, false
+	}
+	if len(values) == 0 {
+		return nil, false
+	}
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		s, ok := kvstore.IndexValue(v)
+		if !ok || len(s) > kvstore.MaxIndexedValueLength || !kvstore.Indexable(s) {
+			return nil, false
+		}
+		result = append(result, s)
+	}
+	return result, true
+}
@@ -0,0 +1,78 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package badgerspanreader
+
+import (
+	"testing"
+
+	"github.com/teletrace/teletrace/pkg/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func filter(key string, operator string, value any) model.SearchFilter {
+	return model.SearchFilter{KeyValueFilter: &model.KeyValueFilter{
+		Key: model.FilterKey(key), Operator: model.FilterOperator(operator), Value: value,
+	}}
+}
+
+func TestQueryPlanReadsTracesByTraceId(t *testing.T) {
+	plan := newQueryPlan([]model.SearchFilter{
+		filter("span.name", "equals", "GET /cart"),
+		filter("span.traceId", "in", []any{"a", "b"}),
+	}, 0, 10)
+
+	assert.Equal(t, []string{"a", "b"}, plan.traceIds)
+	assert.Empty(t, plan.tagKey)
+	assert.Equal(t, "r/a/*\nr/b/*", plan.query())
+}
+
+func TestQueryPlanScansTagIndex(t *testing.T) {
+	plan := newQueryPlan([]model.SearchFilter{
+		filter("span.attributes.http.url", "contains", "cart"),
+		filter("span.kind", "in", []any{"SPAN_KIND_SERVER", 3.0}),
+	}, 10, 20)
+
+	assert.Equal(t, "span.kind", plan.tagKey)
+	assert.Equal(t, []string{"Server", "Client"}, plan.tagValues)
+	assert.Equal(t, "i/span.kind/Server/[10, 20]\ni/span.kind/Client/[10, 20]", plan.query())
+}
+
+func TestQueryPlanIndexesResourceAttributesAndNumbers(t *testing.T) {
+	plan := newQueryPlan([]model.SearchFilter{
+		filter("span.resource.attributes.k8s.pod.uid", "equals", "abc"),
+	}, 0, 0)
+	assert.Equal(t, "resource.attributes.k8s.pod.uid", plan.tagKey)
+
+	plan = newQueryPlan([]model.SearchFilter{
+		filter("span.attributes.http.status_code", "equals", 503.0),
+	}, 0, 0)
+	assert.Equal(t, []string{"503"}, plan.tagValues)
+}
+
+func TestQueryPlanScansSpansWithoutIndexedFilter(t *testing.T) {
+	plan := newQueryPlan([]model.SearchFilter{
+		filter("span.attributes.http.url", "contains", "cart"),
+		filter("span.attributes.payload", "equals", map[string]any{"a": 1}),
+		filter("span.attributes.key", "equals", "a\x00b"),
+	}, 10, 20)
+
+	assert.Empty(t, plan.traceIds)
+	assert.Empty(t, plan.tagKey)
+	assert.Equal(t, "s/[10, 20]", plan.query())
+	assert.Equal(t, []string{"scan the span keys, from the latest"}, plan.describe())
+}
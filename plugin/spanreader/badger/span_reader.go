@@ -0,0 +1,217 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package badgerspanreader
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/teletrace/teletrace/kvstore"
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/slowquery"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/inmemory"
+	"github.com/teletrace/teletrace/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+type spanReader struct {
+	cfg         BadgerConfig
+	logger      *zap.Logger
+	ctx         context.Context
+	store       *kvstore.Store
+	slowQueries *slowquery.Log
+}
+
+// candidates holds the candidate spans of a request, read by its query plan.
+type candidates struct {
+	spans []*internalspan.InternalSpan
+	plan  queryPlan
+	// truncated is set if the plan had more than MaxScannedSpans candidates
+	truncated bool
+}
+
+func (sr *spanReader) Initialize() error {
+	return nil
+}
+
+// readCandidates reads the candidate spans of the filters in the timeframe.
+func (sr *spanReader) readCandidates(
+	ctx context.Context, operation string, tf model.Timeframe, filters []model.SearchFilter,
+) (*candidates, error) {
+	end := tf.EndTime
+	if end == 0 {
+		end = uint64(time.Now().UnixNano())
+	}
+	plan := newQueryPlan(filters, tf.StartTime, end)
+	query := plan.query()
+
+	ctx, cancel := spanreader.WithQueryTimeout(ctx, sr.cfg.QueryTimeout)
+	defer cancel()
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+	defer sr.slowQueries.Observe(ctx, operation, query, filters, time.Now())
+
+	result := &candidates{plan: plan}
+	var err error
+	switch {
+	case len(plan.traceIds) > 0:
+		result.spans, result.truncated, err = sr.store.Traces(ctx, plan.traceIds, sr.cfg.MaxScannedSpans)
+	case plan.tagKey != "":
+		result.spans, result.truncated, err = sr.store.ScanTag(ctx, plan.tagKey, plan.tagValues, plan.start, plan.end, sr.cfg.MaxScannedSpans)
+	default:
+		result.spans, result.truncated, err = sr.store.Scan(ctx, plan.start, plan.end, sr.cfg.MaxScannedSpans)
+	}
+	if err != nil {
+		return nil, tracing.RecordError(span, spanreader.QueryContextError(ctx, err))
+	}
+	if result.truncated {
+		sr.logger.Debug("query has more candidate spans than read",
+			zap.String("operation", operation), zap.Int("maxScannedSpans", sr.cfg.MaxScannedSpans))
+	}
+	return result, nil
+}
+
+func (sr *spanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	c, err := sr.readCandidates(ctx, "search", r.Timeframe, r.SearchFilters)
+	if err != nil {
+		return nil, err
+	}
+	result, err := inmemory.Search(c.spans, r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", spanreader.ErrInvalidQuery, err)
+	}
+	if r.Debug {
+		queryPlan := append(c.plan.describe(), fmt.Sprintf("filter %d candidate spans in memory", len(c.spans)))
+		if c.truncated {
+			queryPlan = append(queryPlan, fmt.Sprintf("stopped scanning at %d candidate spans", sr.cfg.MaxScannedSpans))
+		}
+		result.Debug = &spansquery.DebugInfo{Query: c.plan.query(), QueryPlan: queryPlan}
+	}
+	return result, nil
+}
+
+func (sr *spanReader) GetAvailableTags(ctx context.Context, r tagsquery.GetAvailableTagsRequest) (*tagsquery.GetAvailableTagsResponse, error) {
+	query := "k/*"
+	ctx, cancel := spanreader.WithQueryTimeout(ctx, sr.cfg.QueryTimeout)
+	defer cancel()
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+	defer sr.slowQueries.Observe(ctx, "available_tags", query, nil, time.Now())
+
+	attributeTags, err := sr.store.Tags(ctx)
+	if err != nil {
+		return nil, tracing.RecordError(span, spanreader.QueryContextError(ctx, err))
+	}
+	return &tagsquery.GetAvailableTagsResponse{Tags: inmemory.Tags(attributeTags, r.Limit)}, nil
+}
+
+func (sr *spanReader) GetTagsValues(ctx context.Context, r tagsquery.TagValuesRequest, tags []string) (map[string]*tagsquery.TagValuesResponse, error) {
+	c, err := sr.readCandidates(ctx, "tag_values", timeframe(r.Timeframe), r.SearchFilters)
+	if err != nil {
+		return nil, err
+	}
+	return inmemory.TagsValues(c.spans, r, tags), nil
+}
+
+func (sr *spanReader) GetTagsStatistics(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tag string,
+) (*tagsquery.TagStatisticsResponse, error) {
+	c, err := sr.readCandidates(ctx, "tag_statistics", timeframe(r.Timeframe), r.SearchFilters)
+	if err != nil {
+		return nil, err
+	}
+	return inmemory.TagStatistics(c.spans, r, tag), nil
+}
+
+// GetTagsStatisticsBatch reads the candidate spans once for all the tags.
+func (sr *spanReader) GetTagsStatisticsBatch(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string,
+) (map[string]*tagsquery.TagStatisticsResponse, error) {
+	c, err := sr.readCandidates(ctx, "tag_statistics_batch", timeframe(r.Timeframe), r.SearchFilters)
+	if err != nil {
+		return nil, err
+	}
+	return inmemory.TagsStatistics(c.spans, r, tags), nil
+}
+
+func (sr *spanReader) SearchEvents(ctx context.Context, r eventsquery.SearchRequest) (*eventsquery.SearchResponse, error) {
+	return spanreader.SearchEventsInSpans(ctx, sr, r)
+}
+
+// SearchTraces groups the most recent matching spans by trace in memory.
+func (sr *spanReader) SearchTraces(ctx context.Context, r spansquery.SearchTracesRequest) (*spansquery.SearchTracesResponse, error) {
+	return spanreader.SearchTracesInSpans(ctx, sr, r)
+}
+
+// Aggregate aggregates the matching spans in memory.
+func (sr *spanReader) Aggregate(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	return spanreader.AggregateInSpans(ctx, sr, r)
+}
+
+func (sr *spanReader) GetSystemId(ctx context.Context, r metadata.GetSystemIdRequest) (*metadata.GetSystemIdResponse, error) {
+	return nil, fmt.Errorf("Not implemented method")
+}
+
+func (sr *spanReader) SetSystemId(ctx context.Context, r metadata.SetSystemIdRequest) (*metadata.SetSystemIdResponse, error) {
+	return nil, fmt.Errorf("Not implemented method")
+}
+
+// Ping reads a key of the database, failing if it's closed.
+func (sr *spanReader) Ping(ctx context.Context) error {
+	if err := sr.store.Ping(); err != nil {
+		return fmt.Errorf("failed to read badger database: %w", err)
+	}
+	return nil
+}
+
+func timeframe(tf *model.Timeframe) model.Timeframe {
+	if tf == nil {
+		return model.Timeframe{}
+	}
+	return *tf
+}
+
+// NewBadgerSpanReader returns a span reader of the Badger database written by the badger exporter. The database is
+// opened by the process, so the exporter must run in the same process, as in the all-in-one.
+func NewBadgerSpanReader(ctx context.Context, logger *zap.Logger, cfg BadgerConfig) (spanreader.SpanReader, error) {
+	store, err := kvstore.Open(logger, cfg.Directory)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create a new span reader for badger: %w", err)
+	}
+
+	slowQueries, err := slowquery.NewLog(logger, cfg.SlowQueryThreshold)
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("cannot create a new span reader for badger: %w", err)
+	}
+
+	return &spanReader{
+		cfg:         cfg,
+		logger:      logger,
+		ctx:         ctx,
+		store:       store,
+		slowQueries: slowQueries,
+	}, nil
+}
@@ -0,0 +1,135 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package badgerspanreader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/teletrace/teletrace/kvstore"
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func testSpan(traceId string, spanId string, startTime uint64, service string, kind string) *internalspan.InternalSpan {
+	return &internalspan.InternalSpan{
+		Resource: &internalspan.Resource{Attributes: internalspan.Attributes{"service.name": service}},
+		Span: &internalspan.Span{
+			TraceId:           traceId,
+			SpanId:            spanId,
+			Name:              "GET /cart",
+			Kind:              kind,
+			StartTimeUnixNano: startTime,
+			EndTimeUnixNano:   startTime + 100,
+		},
+		ExternalFields: &internalspan.ExternalFields{DurationNano: 100},
+	}
+}
+
+func newTestSpanReader(t *testing.T, maxScannedSpans int) *spanReader {
+	directory := t.TempDir()
+	// the exporter and the span reader share the store of the directory
+	store, err := kvstore.Open(zap.NewNop(), directory)
+	assert.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	assert.NoError(t, store.WriteSpans([]*internalspan.InternalSpan{
+		testSpan("t1", "s1", 1000, "cart", "Server"),
+		testSpan("t1", "s2", 2000, "cart", "Client"),
+		testSpan("t2", "s3", 3000, "checkout", "Server"),
+	}, 0))
+
+	sr, err := NewBadgerSpanReader(context.Background(), zap.NewNop(), BadgerConfig{
+		Directory: directory, MaxScannedSpans: maxScannedSpans,
+	})
+	assert.NoError(t, err)
+	t.Cleanup(func() { sr.(*spanReader).store.Close() })
+	return sr.(*spanReader)
+}
+
+func spanIds(res *spansquery.SearchResponse) []string {
+	ids := make([]string, 0, len(res.Spans))
+	for _, span := range res.Spans {
+		ids = append(ids, span.Span.SpanId)
+	}
+	return ids
+}
+
+func TestSearch(t *testing.T) {
+	sr := newTestSpanReader(t, 1000)
+	ctx := context.Background()
+
+	res, err := sr.Search(ctx, spansquery.SearchRequest{
+		Timeframe:     model.Timeframe{StartTime: 0, EndTime: 5000},
+		SearchFilters: []model.SearchFilter{filter("span.kind", "equals", "SPAN_KIND_SERVER")},
+		Sort:          []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: true}},
+		Debug:         true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"s1", "s3"}, spanIds(res))
+	assert.Equal(t, "i/span.kind/Server/[0, 5000]", res.Debug.Query)
+
+	res, err = sr.Search(ctx, spansquery.SearchRequest{
+		Timeframe:     model.Timeframe{StartTime: 1500, EndTime: 5000},
+		SearchFilters: []model.SearchFilter{filter("span.name", "contains", "cart")},
+		Sort:          []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: true}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"s2", "s3"}, spanIds(res))
+
+	res, err = sr.Search(ctx, spansquery.SearchRequest{
+		Timeframe:     model.Timeframe{StartTime: 0, EndTime: 5000},
+		SearchFilters: []model.SearchFilter{filter("span.traceId", "equals", "t1")},
+		Sort:          []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: true}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"s1", "s2"}, spanIds(res))
+}
+
+func TestSearchReadsLatestScannedSpans(t *testing.T) {
+	sr := newTestSpanReader(t, 2)
+
+	res, err := sr.Search(context.Background(), spansquery.SearchRequest{
+		Timeframe: model.Timeframe{StartTime: 0, EndTime: 5000},
+		Sort:      []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: true}},
+		Debug:     true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"s2", "s3"}, spanIds(res))
+	assert.Contains(t, res.Debug.QueryPlan, "stopped scanning at 2 candidate spans")
+}
+
+func TestTags(t *testing.T) {
+	sr := newTestSpanReader(t, 1000)
+	ctx := context.Background()
+
+	tags, err := sr.GetAvailableTags(ctx, tagsquery.GetAvailableTagsRequest{})
+	assert.NoError(t, err)
+	assert.Contains(t, tags.Tags, tagsquery.TagInfo{Name: "resource.attributes.service.name", Type: "Str"})
+
+	values, err := sr.GetTagsValues(ctx, tagsquery.TagValuesRequest{
+		Timeframe: &model.Timeframe{StartTime: 0, EndTime: 5000},
+	}, []string{"resource.attributes.service.name"})
+	assert.NoError(t, err)
+	assert.Len(t, values["resource.attributes.service.name"].Values, 2)
+	assert.Equal(t, "cart", values["resource.attributes.service.name"].Values[0].Value)
+	assert.Equal(t, 2, values["resource.attributes.service.name"].Values[0].Count)
+}
@@ -0,0 +1,230 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cassandraspanreader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"github.com/gocql/gocql"
+	"go.uber.org/zap"
+)
+
+// readConcurrency is the number of partitions read concurrently
+const readConcurrency = 16
+
+// keyspaceRegexp matches the valid keyspace names, which are interpolated in the queries of the reader
+var keyspaceRegexp = regexp.MustCompile(`^[a-zA-Z0-9_]{1,48}$`)
+
+type cassandraClient struct {
+	cfg     CassandraConfig
+	logger  *zap.Logger
+	session *gocql.Session
+}
+
+func newCassandraClient(logger *zap.Logger, cfg CassandraConfig) (*cassandraClient, error) {
+	if len(cfg.Hosts) == 0 {
+		return nil, fmt.Errorf("no cassandra hosts configured")
+	}
+	if !keyspaceRegexp.MatchString(cfg.Keyspace) {
+		return nil, fmt.Errorf("invalid keyspace %q", cfg.Keyspace)
+	}
+	if cfg.MaxScannedSpans <= 0 {
+		return nil, fmt.Errorf("max scanned spans must be positive, got %d", cfg.MaxScannedSpans)
+	}
+	consistency, err := gocql.ParseConsistencyWrapper(cfg.Consistency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid consistency: %w", err)
+	}
+
+	cluster := gocql.NewCluster(cfg.Hosts...)
+	cluster.Keyspace = cfg.Keyspace
+	cluster.Consistency = consistency
+	if cfg.Username != "" {
+		cluster.Authenticator = gocql.PasswordAuthenticator{Username: cfg.Username, Password: cfg.Password}
+	}
+	session, err := cluster.CreateSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to cassandra: %w", err)
+	}
+	return &cassandraClient{cfg: cfg, logger: logger, session: session}, nil
+}
+
+// spanRef references a span of the spans table from an index table.
+type spanRef struct {
+	startTime int64
+	traceId   string
+	spanId    string
+}
+
+// scan reads the references of the candidate spans of the plan from its index queries, the most recent buckets
+// first, up to MaxScannedSpans references. It returns whether there were more candidates.
+func (c *cassandraClient) scan(ctx context.Context, plan queryPlan) ([]spanRef, bool, error) {
+	var refs []spanRef
+	seen := make(map[spanRef]bool)
+	for bucket := plan.endBucket; bucket >= plan.startBucket; bucket-- {
+		bucketRefs, err := c.scanBucket(ctx, plan.indexes, bucket)
+		if err != nil {
+			return nil, false, err
+		}
+		sort.Slice(bucketRefs, func(i, j int) bool { return bucketRefs[i].startTime > bucketRefs[j].startTime })
+		for _, ref := range bucketRefs {
+			key := spanRef{traceId: ref.traceId, spanId: ref.spanId}
+			if seen[key] {
+				continue
+			}
+			if len(refs) == c.cfg.MaxScannedSpans {
+				return refs, true, nil
+			}
+			seen[key] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs, false, nil
+}
+
+// scanBucket reads the partitions of a bucket concurrently, each up to MaxScannedSpans references.
+func (c *cassandraClient) scanBucket(ctx context.Context, queries []indexQuery, bucket int64) ([]spanRef, error) {
+	var mu sync.Mutex
+	var refs []spanRef
+	var tasks []func() error
+	for _, q := range queries {
+		for shard := 0; shard < indexShards; shard++ {
+			q, shard := q, shard
+			tasks = append(tasks, func() error {
+				var partitionRefs []spanRef
+				iter := c.session.Query(q.query, q.args(bucket, shard)...).WithContext(ctx).Iter()
+				var ref spanRef
+				for len(partitionRefs) < c.cfg.MaxScannedSpans && iter.Scan(&ref.startTime, &ref.traceId, &ref.spanId) {
+					partitionRefs = append(partitionRefs, ref)
+				}
+				if err := iter.Close(); err != nil {
+					return fmt.Errorf("failed to scan %s: %w", q.description, err)
+				}
+				mu.Lock()
+				refs = append(refs, partitionRefs...)
+				mu.Unlock()
+				return nil
+			})
+		}
+	}
+	return refs, runConcurrently(tasks)
+}
+
+// fetch reads the referenced spans, a trace partition at a time.
+func (c *cassandraClient) fetch(ctx context.Context, refs []spanRef) ([]*internalspan.InternalSpan, error) {
+	spanIds := make(map[string][]string)
+	var traceIds []string
+	for _, ref := range refs {
+		if _, ok := spanIds[ref.traceId]; !ok {
+			traceIds = append(traceIds, ref.traceId)
+		}
+		spanIds[ref.traceId] = append(spanIds[ref.traceId], ref.spanId)
+	}
+	query := fmt.Sprintf("SELECT span FROM %s.spans WHERE trace_id = ? AND span_id IN ?", c.cfg.Keyspace)
+	return c.readSpans(ctx, traceIds, func(traceId string) *gocql.Query {
+		return c.session.Query(query, traceId, spanIds[traceId])
+	})
+}
+
+// fetchTraces reads the spans of the traces, up to MaxScannedSpans spans per trace.
+func (c *cassandraClient) fetchTraces(ctx context.Context, traceIds []string) ([]*internalspan.InternalSpan, error) {
+	query := fmt.Sprintf("SELECT span FROM %s.spans WHERE trace_id = ? LIMIT ?", c.cfg.Keyspace)
+	return c.readSpans(ctx, traceIds, func(traceId string) *gocql.Query {
+		return c.session.Query(query, traceId, c.cfg.MaxScannedSpans)
+	})
+}
+
+// readSpans runs the queries of the traces concurrently, skipping the spans which can't be decoded.
+func (c *cassandraClient) readSpans(
+	ctx context.Context, traceIds []string, query func(traceId string) *gocql.Query,
+) ([]*internalspan.InternalSpan, error) {
+	var mu sync.Mutex
+	var spans []*internalspan.InternalSpan
+	tasks := make([]func() error, 0, len(traceIds))
+	for _, traceId := range traceIds {
+		traceId := traceId
+		tasks = append(tasks, func() error {
+			iter := query(traceId).WithContext(ctx).Iter()
+			var data []byte
+			var traceSpans []*internalspan.InternalSpan
+			for iter.Scan(&data) {
+				span, err := decodeSpan(data)
+				if err != nil {
+					c.logger.Error("failed to decode span", zap.String("traceId", traceId), zap.Error(err))
+					continue
+				}
+				traceSpans = append(traceSpans, span)
+			}
+			if err := iter.Close(); err != nil {
+				return fmt.Errorf("failed to read spans of trace %s: %w", traceId, err)
+			}
+			mu.Lock()
+			spans = append(spans, traceSpans...)
+			mu.Unlock()
+			return nil
+		})
+	}
+	return spans, runConcurrently(tasks)
+}
+
+// runConcurrently runs the tasks, up to readConcurrency at a time, returning the first error.
+func runConcurrently(tasks []func() error) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	work := make(chan func() error)
+	for i := 0; i < readConcurrency && i < len(tasks); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range work {
+				if err := task(); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, task := range tasks {
+		work <- task
+	}
+	close(work)
+	wg.Wait()
+	return firstErr
+}
+
+// unavailableError returns err wrapped by spanreader.ErrBackendUnavailable if the cluster couldn't serve the query,
+// or else err as is.
+func unavailableError(err error) error {
+	var unavailable *gocql.RequestErrUnavailable
+	if errors.Is(err, gocql.ErrNoConnections) || errors.Is(err, gocql.ErrUnavailable) || errors.Is(err, gocql.ErrSessionClosed) ||
+		errors.Is(err, gocql.ErrConnectionClosed) || errors.As(err, &unavailable) {
+		return fmt.Errorf("%w: %v", spanreader.ErrBackendUnavailable, err)
+	}
+	return err
+}
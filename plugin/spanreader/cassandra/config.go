@@ -0,0 +1,60 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cassandraspanreader
+
+import (
+	"strings"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/config"
+)
+
+type CassandraConfig struct {
+	Hosts       []string
+	Keyspace    string
+	Username    string
+	Password    string
+	Consistency string
+	// MaxScannedSpans bounds the candidate spans read from the index tables by each query, the most recent first
+	MaxScannedSpans int
+	// MaxLookback bounds the time range of queries without a start time, as each hour of the range is a partition
+	MaxLookback time.Duration
+	// QueryTimeout interrupts queries running longer, 0 disables the timeout
+	QueryTimeout time.Duration
+	// SlowQueryThreshold logs and counts slower queries, 0 disables the slow query log
+	SlowQueryThreshold time.Duration
+}
+
+func NewCassandraConfig(cfg config.Config) CassandraConfig {
+	var hosts []string
+	for _, host := range strings.Split(cfg.CassandraHosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return CassandraConfig{
+		Hosts:              hosts,
+		Keyspace:           cfg.CassandraKeyspace,
+		Username:           cfg.CassandraUsername,
+		Password:           cfg.CassandraPassword,
+		Consistency:        cfg.CassandraConsistency,
+		MaxScannedSpans:    cfg.CassandraMaxScannedSpans,
+		MaxLookback:        time.Duration(cfg.CassandraMaxLookbackHours) * time.Hour,
+		QueryTimeout:       time.Duration(cfg.StorageQueryTimeoutSeconds) * time.Second,
+		SlowQueryThreshold: time.Duration(cfg.StorageSlowQueryThresholdMilliseconds) * time.Millisecond,
+	}
+}
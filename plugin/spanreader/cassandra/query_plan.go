@@ -0,0 +1,256 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cassandraspanreader
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader/inmemory"
+)
+
+// The schema is created by the cassandra exporter, see teletrace-otelcol/exporter/cassandraexporter/schema.go,
+// whose bucket size, number of shards and indexed tags the query plans rely on.
+const (
+	// bucketNano is the width of the time buckets of the index tables
+	bucketNano = int64(3600 * 1000 * 1000 * 1000)
+	// indexShards is the number of partitions of each bucket of the index tables
+	indexShards = 10
+	// maxIndexedValueLength bounds the tag values written to the tag index, longer values aren't indexed
+	maxIndexedValueLength = 256
+)
+
+// indexedFields are the tags of the span fields written to the tag index, besides the resource and span attributes
+var indexedFields = map[string]bool{
+	"span.name":        true,
+	"span.kind":        true,
+	"span.status.code": true,
+	"span.events.name": true,
+	"scope.name":       true,
+}
+
+// indexQuery reads the references of candidate spans from an index table, a partition at a time.
+type indexQuery struct {
+	// description describes the query in the debug info of searches
+	description string
+	query       string
+	// args returns the arguments of the query of a partition
+	args func(bucket int64, shard int) []any
+}
+
+// queryPlan describes how the candidate spans of a request are read, either from the partitions of the traces
+// (when filtering by trace ID) or from an index table, before they are filtered in memory.
+type queryPlan struct {
+	traceIds []string
+	indexes  []indexQuery
+	// startBucket and endBucket are the buckets of the timeframe, read from the most recent
+	startBucket int64
+	endBucket   int64
+}
+
+// newQueryPlan returns the plan reading the candidate spans of the filters in the timeframe [start, end].
+func newQueryPlan(keyspace string, filters []model.SearchFilter, start int64, end int64) queryPlan {
+	plan := queryPlan{startBucket: start / bucketNano, endBucket: end / bucketNano}
+	if traceIds, ok := traceIdsFilter(filters); ok {
+		plan.traceIds = traceIds
+		return plan
+	}
+	if q, ok := durationIndexQuery(keyspace, filters); ok {
+		plan.indexes = []indexQuery{q}
+		return plan
+	}
+	if qs, ok := tagIndexQueries(keyspace, filters, start, end); ok {
+		plan.indexes = qs
+		return plan
+	}
+	plan.indexes = []indexQuery{{
+		description: "span_time_index",
+		query: fmt.Sprintf("SELECT start_time, trace_id, span_id FROM %s.span_time_index "+
+			"WHERE bucket = ? AND shard = ? AND start_time >= ? AND start_time <= ?", keyspace),
+		args: func(bucket int64, shard int) []any { return []any{bucket, shard, start, end} },
+	}}
+	return plan
+}
+
+// queries returns the statements of the plan, for the debug info of searches.
+func (p queryPlan) queries(keyspace string) string {
+	if len(p.traceIds) > 0 {
+		return fmt.Sprintf("SELECT span FROM %s.spans WHERE trace_id = ?", keyspace)
+	}
+	queries := make([]string, 0, len(p.indexes))
+	for _, q := range p.indexes {
+		queries = append(queries, q.query)
+	}
+	return strings.Join(queries, ";\n")
+}
+
+// describe returns the steps of the plan, for the debug info of searches.
+func (p queryPlan) describe() []string {
+	if len(p.traceIds) > 0 {
+		return []string{fmt.Sprintf("read the spans of %d traces", len(p.traceIds))}
+	}
+	steps := make([]string, 0, len(p.indexes))
+	for _, q := range p.indexes {
+		steps = append(steps, fmt.Sprintf("scan %s in %d hour buckets of %d shards",
+			q.description, p.endBucket-p.startBucket+1, indexShards))
+	}
+	return steps
+}
+
+// traceIdsFilter returns the trace IDs of an equals or in filter on the trace ID.
+func traceIdsFilter(filters []model.SearchFilter) ([]string, bool) {
+	for _, f := range filters {
+		if f.KeyValueFilter == nil || f.KeyValueFilter.Key != "span.traceId" {
+			continue
+		}
+		if values, ok := filterValues(*f.KeyValueFilter); ok {
+			return values, true
+		}
+	}
+	return nil, false
+}
+
+// durationIndexQuery returns the query of the duration index for a filter on the service name along with a range
+// filter on the duration.
+func durationIndexQuery(keyspace string, filters []model.SearchFilter) (indexQuery, bool) {
+	var service string
+	var conditions []string
+	var values []any
+	for _, f := range filters {
+		kv := f.KeyValueFilter
+		if kv == nil {
+			continue
+		}
+		switch strings.TrimSuffix(string(kv.Key), ".keyword") {
+		case "resource.attributes.service.name", "span.resource.attributes.service.name":
+			if s, ok := kv.Value.(string); ok && kv.Operator == spansquery.OPERATOR_EQUALS {
+				service = s
+			}
+		case "externalFields.durationNano", "span.duration":
+			operator, ok := rangeOperators[kv.Operator]
+			n, isNumber := inmemory.Number(kv.Value)
+			if ok && isNumber {
+				conditions = append(conditions, "duration "+operator+" ?")
+				values = append(values, int64(n))
+			}
+		}
+	}
+	if service == "" || len(conditions) == 0 {
+		return indexQuery{}, false
+	}
+	return indexQuery{
+		description: fmt.Sprintf("duration_index for service %s", service),
+		query: fmt.Sprintf("SELECT start_time, trace_id, span_id FROM %s.duration_index "+
+			"WHERE service_name = ? AND bucket = ? AND shard = ? AND %s", keyspace, strings.Join(conditions, " AND ")),
+		args: func(bucket int64, shard int) []any {
+			return append([]any{service, bucket, shard}, values...)
+		},
+	}, true
+}
+
+var rangeOperators = map[model.FilterOperator]string{
+	spansquery.OPERATOR_GT:  ">",
+	spansquery.OPERATOR_GTE: ">=",
+	spansquery.OPERATOR_LT:  "<",
+	spansquery.OPERATOR_LTE: "<=",
+}
+
+// tagIndexQueries returns the queries of the tag index for the values of the first equals or in filter
+// on an indexed tag.
+func tagIndexQueries(keyspace string, filters []model.SearchFilter, start int64, end int64) ([]indexQuery, bool) {
+	for _, f := range filters {
+		if f.KeyValueFilter == nil {
+			continue
+		}
+		key, ok := indexedKey(string(f.KeyValueFilter.Key))
+		if !ok {
+			continue
+		}
+		values, ok := filterValues(*f.KeyValueFilter)
+		if !ok {
+			continue
+		}
+		queries := make([]indexQuery, 0, len(values))
+		for _, value := range values {
+			value := value
+			queries = append(queries, indexQuery{
+				description: fmt.Sprintf("tag_index for %s = %s", key, value),
+				query: fmt.Sprintf("SELECT start_time, trace_id, span_id FROM %s.tag_index "+
+					"WHERE tag_key = ? AND tag_value = ? AND bucket = ? AND shard = ? AND start_time >= ? AND start_time <= ?", keyspace),
+				args: func(bucket int64, shard int) []any { return []any{key, value, bucket, shard, start, end} },
+			})
+		}
+		return queries, true
+	}
+	return nil, false
+}
+
+// indexedKey returns the key of a filter key in the tag index, if it's indexed.
+func indexedKey(key string) (string, bool) {
+	key = strings.TrimSuffix(key, ".keyword")
+	switch {
+	case indexedFields[key], strings.HasPrefix(key, "span.attributes."), strings.HasPrefix(key, "resource.attributes."):
+		return key, true
+	case strings.HasPrefix(key, "span.resource.attributes."):
+		return strings.TrimPrefix(key, "span."), true
+	}
+	return "", false
+}
+
+// filterValues returns the values of an equals or in filter as written to the tag index.
+// It returns false if any of them isn't indexed.
+func filterValues(f model.KeyValueFilter) ([]string, bool) {
+	value := spansquery.NormalizeFilterValue(f.Key, f.Value)
+	var values []any
+	switch f.Operator {
+	case spansquery.OPERATOR_EQUALS:
+		values = []any{value}
+	case spansquery.OPERATOR_IN:
+		values, _ = value.([]any)
+	default:
+		return nil, false
+	}
+	if len(values) == 0 {
+		return nil, false
+	}
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		s, ok := indexValue(v)
+		if !ok || len(s) > maxIndexedValueLength {
+			return nil, false
+		}
+		result = append(result, s)
+	}
+	return result, true
+}
+
+// indexValue returns a scalar value as written to the tag index, numbers in their shortest decimal form.
+func indexValue(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	if n, ok := inmemory.Number(value); ok {
+		return strconv.FormatFloat(n, 'f', -1, 64), true
+	}
+	return "", false
+}
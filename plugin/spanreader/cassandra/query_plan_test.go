@@ -0,0 +1,106 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cassandraspanreader
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/teletrace/teletrace/pkg/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func filter(key string, operator string, value any) model.SearchFilter {
+	return model.SearchFilter{KeyValueFilter: &model.KeyValueFilter{
+		Key: model.FilterKey(key), Operator: model.FilterOperator(operator), Value: value,
+	}}
+}
+
+func TestQueryPlanReadsTracesByTraceId(t *testing.T) {
+	plan := newQueryPlan("teletrace", []model.SearchFilter{
+		filter("span.name", "equals", "GET /cart"),
+		filter("span.traceId", "in", []any{"a", "b"}),
+	}, 0, bucketNano)
+
+	assert.Equal(t, []string{"a", "b"}, plan.traceIds)
+	assert.Empty(t, plan.indexes)
+}
+
+func TestQueryPlanScansDurationIndex(t *testing.T) {
+	plan := newQueryPlan("teletrace", []model.SearchFilter{
+		filter("span.name", "equals", "GET /cart"),
+		filter("resource.attributes.service.name", "equals", "checkout"),
+		filter("externalFields.durationNano", "gte", 1000.0),
+		filter("externalFields.durationNano", "lt", json.Number("5000")),
+	}, 0, bucketNano)
+
+	assert.Len(t, plan.indexes, 1)
+	q := plan.indexes[0]
+	assert.Contains(t, q.query, "FROM teletrace.duration_index WHERE service_name = ? AND bucket = ? AND shard = ? AND duration >= ? AND duration < ?")
+	assert.Equal(t, []any{"checkout", int64(3), 7, int64(1000), int64(5000)}, q.args(3, 7))
+}
+
+func TestQueryPlanScansTagIndex(t *testing.T) {
+	plan := newQueryPlan("teletrace", []model.SearchFilter{
+		filter("span.attributes.http.url", "contains", "cart"),
+		filter("span.kind", "in", []any{"SPAN_KIND_SERVER", 2.0}),
+	}, 10, 20)
+
+	assert.Len(t, plan.indexes, 2)
+	assert.Contains(t, plan.indexes[0].query, "FROM teletrace.tag_index WHERE tag_key = ? AND tag_value = ?")
+	assert.Equal(t, []any{"span.kind", "Server", int64(0), 1, int64(10), int64(20)}, plan.indexes[0].args(0, 1))
+	assert.Equal(t, []any{"span.kind", "Server", int64(0), 1, int64(10), int64(20)}, plan.indexes[1].args(0, 1))
+}
+
+func TestQueryPlanIndexesResourceAttributesAndNumbers(t *testing.T) {
+	plan := newQueryPlan("teletrace", []model.SearchFilter{
+		filter("span.resource.attributes.k8s.pod.uid", "equals", "abc"),
+	}, 0, 0)
+	assert.Equal(t, "resource.attributes.k8s.pod.uid", plan.indexes[0].args(0, 0)[0])
+
+	plan = newQueryPlan("teletrace", []model.SearchFilter{
+		filter("span.attributes.http.status_code", "equals", 503.0),
+	}, 0, 0)
+	assert.Equal(t, "503", plan.indexes[0].args(0, 0)[1])
+}
+
+func TestQueryPlanScansTimeIndexWithoutIndexedFilter(t *testing.T) {
+	plan := newQueryPlan("teletrace", []model.SearchFilter{
+		filter("span.attributes.http.url", "contains", "cart"),
+		filter("span.attributes.payload", "equals", map[string]any{"a": 1}),
+	}, bucketNano, 3*bucketNano+1)
+
+	assert.Len(t, plan.indexes, 1)
+	assert.Contains(t, plan.indexes[0].query, "FROM teletrace.span_time_index")
+	assert.Equal(t, int64(1), plan.startBucket)
+	assert.Equal(t, int64(3), plan.endBucket)
+	assert.Equal(t, []string{"scan span_time_index in 3 hour buckets of 10 shards"}, plan.describe())
+}
+
+func TestDecodeSpan(t *testing.T) {
+	span, err := decodeSpan([]byte(`{
+		"span": {"spanId": "a", "attributes": {"retries": 3}},
+		"events": [{"name": "exception"}],
+		"links": [{"spanId": "b"}]
+	}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, json.Number("3"), span.Span.Attributes["retries"])
+	assert.Equal(t, "exception", span.Span.Events[0].Name)
+	assert.Equal(t, "b", span.Span.Links[0].SpanId)
+}
@@ -0,0 +1,36 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cassandraspanreader
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer starts the spans of running queries, exported when self tracing is enabled
+var tracer = otel.Tracer("github.com/teletrace/teletrace/plugin/spanreader/cassandra")
+
+// startQuerySpan starts a client span for running query against the cassandra cluster.
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "cassandra.query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemCassandra, semconv.DBStatementKey.String(query)),
+	)
+}
@@ -17,25 +17,31 @@
 package spanreaderes
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
 	"github.com/elastic/go-elasticsearch/v8"
 	"go.uber.org/zap"
 )
 
 func newTypedClient(logger *zap.Logger, cfg ElasticConfig) (*elasticsearch.TypedClient, error) {
-	esConfig := elasticsearch.Config{
-		Addresses: []string{cfg.Endpoint},
-		Username:  cfg.Username,
-		Password:  cfg.Password,
-	}
-
-	if cfg.ApiKey != "" {
-		esConfig.APIKey = cfg.ApiKey
+	esConfig, err := newESConfig(cfg)
+	if err != nil {
+		logger.Error("Could not create elasticsearch client config", zap.Error(err))
+		return nil, err
 	}
 
-	if cfg.ServiceToken != "" {
-		esConfig.ServiceToken = cfg.ServiceToken
+	esConfig.Transport, err = newSlowQueryTransport(logger, cfg.SlowQueryThreshold, esConfig.Transport)
+	if err != nil {
+		logger.Error("Could not create elasticsearch slow query log", zap.Error(err))
+		return nil, err
 	}
-
+	esConfig.Transport = newTracingTransport(esConfig.Transport)
 	es, err := elasticsearch.NewTypedClient(esConfig)
 	if err != nil {
 		logger.Error("Could not create a new typed elasticsearch client %+v", zap.Error(err))
@@ -46,25 +52,153 @@ func newTypedClient(logger *zap.Logger, cfg ElasticConfig) (*elasticsearch.Typed
 }
 
 func newRawClient(logger *zap.Logger, cfg ElasticConfig) (*elasticsearch.Client, error) {
+	esConfig, err := newESConfig(cfg)
+	if err != nil {
+		logger.Error("Could not create elasticsearch client config", zap.Error(err))
+		return nil, err
+	}
+
+	esConfig.Transport, err = newSlowQueryTransport(logger, cfg.SlowQueryThreshold, esConfig.Transport)
+	if err != nil {
+		logger.Error("Could not create elasticsearch slow query log", zap.Error(err))
+		return nil, err
+	}
+	esConfig.Transport = newTracingTransport(esConfig.Transport)
+	es, err := elasticsearch.NewClient(esConfig)
+	if err != nil {
+		logger.Error("Could not create a new raw elasticsearch client %+v", zap.Error(err))
+		return nil, err
+	}
+
+	return es, nil
+}
+
+// newESConfig builds the client config shared by the typed and raw clients.
+// API key and service token credentials may be given inline or loaded from a file,
+// and take precedence over basic auth when set.
+func newESConfig(cfg ElasticConfig) (elasticsearch.Config, error) {
 	esConfig := elasticsearch.Config{
 		Addresses: []string{cfg.Endpoint},
 		Username:  cfg.Username,
 		Password:  cfg.Password,
 	}
 
-	if cfg.ApiKey != "" {
-		esConfig.APIKey = cfg.ApiKey
+	apiKey, err := resolveCredential("api key", cfg.ApiKey, cfg.ApiKeyFile)
+	if err != nil {
+		return esConfig, err
+	}
+
+	serviceToken, err := resolveCredential("service token", cfg.ServiceToken, cfg.ServiceTokenFile)
+	if err != nil {
+		return esConfig, err
 	}
 
-	if cfg.ServiceToken != "" {
-		esConfig.ServiceToken = cfg.ServiceToken
+	if apiKey != "" && serviceToken != "" {
+		return esConfig, fmt.Errorf("only one of elasticsearch api key and service token can be configured")
 	}
 
-	es, err := elasticsearch.NewClient(esConfig)
+	if apiKey != "" {
+		esConfig.APIKey = encodeApiKey(apiKey)
+	}
+
+	if serviceToken != "" {
+		esConfig.ServiceToken = serviceToken
+	}
+
+	tlsConfig, err := newClientTLSConfig(cfg.TLS)
 	if err != nil {
-		logger.Error("Could not create a new raw elasticsearch client %+v", zap.Error(err))
-		return nil, err
+		return esConfig, err
+	}
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		esConfig.Transport = transport
 	}
 
-	return es, nil
+	switch {
+	case cfg.Distribution == "" || cfg.Distribution == DistributionElasticsearch:
+	case isOpenSearch(cfg.Distribution):
+		next := esConfig.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		esConfig.Transport, err = newOpenSearchTransport(next, cfg)
+		if err != nil {
+			return esConfig, err
+		}
+	default:
+		return esConfig, fmt.Errorf("unsupported elasticsearch distribution %s", cfg.Distribution)
+	}
+
+	return esConfig, nil
+}
+
+// newClientTLSConfig returns the TLS config for connecting to clusters using a private CA or requiring mTLS,
+// or nil when the default TLS settings should be used.
+func newClientTLSConfig(cfg TLSConfig) (*tls.Config, error) {
+	if cfg.CertFile == "" && cfg.KeyFile == "" && cfg.CAFile == "" && !cfg.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify, //nolint:gosec // explicitly enabled by config
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return nil, fmt.Errorf("both elasticsearch tls cert file and key file must be configured")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load elasticsearch client certificate: %+v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if cfg.CAFile != "" {
+		caCert, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read elasticsearch ca file: %+v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no valid certificates found in elasticsearch ca file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// resolveCredential returns the inline credential value, or the trimmed content of path if given.
+func resolveCredential(name string, value string, path string) (string, error) {
+	if path == "" {
+		return value, nil
+	}
+
+	if value != "" {
+		return "", fmt.Errorf("elasticsearch %s cannot be configured both inline and from a file", name)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("could not read elasticsearch %s file: %+v", name, err)
+	}
+
+	credential := strings.TrimSpace(string(content))
+	if credential == "" {
+		return "", fmt.Errorf("elasticsearch %s file %s is empty", name, path)
+	}
+
+	return credential, nil
+}
+
+// encodeApiKey accepts an API key either in its encoded form (as returned by the
+// create API key endpoint) or as "id:api_key", and returns the encoded form.
+func encodeApiKey(apiKey string) string {
+	if strings.Contains(apiKey, ":") {
+		return base64.StdEncoding.EncodeToString([]byte(apiKey))
+	}
+	return apiKey
 }
@@ -0,0 +1,173 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spanreaderes
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewESConfigApiKeyFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_key")
+	assert.NoError(t, os.WriteFile(path, []byte("id:secret\n"), 0o600))
+
+	esConfig, err := newESConfig(ElasticConfig{Endpoint: "http://localhost:9200", ApiKeyFile: path})
+
+	assert.NoError(t, err)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("id:secret")), esConfig.APIKey)
+	assert.Empty(t, esConfig.ServiceToken)
+}
+
+func TestNewESConfigServiceTokenFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "service_token")
+	assert.NoError(t, os.WriteFile(path, []byte("  token  "), 0o600))
+
+	esConfig, err := newESConfig(ElasticConfig{Endpoint: "http://localhost:9200", ServiceTokenFile: path})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "token", esConfig.ServiceToken)
+}
+
+func TestNewESConfigEncodedApiKeyUnchanged(t *testing.T) {
+	esConfig, err := newESConfig(ElasticConfig{Endpoint: "http://localhost:9200", ApiKey: "aWQ6c2VjcmV0"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "aWQ6c2VjcmV0", esConfig.APIKey)
+}
+
+func TestNewESConfigInvalidCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty")
+	assert.NoError(t, os.WriteFile(path, []byte("\n"), 0o600))
+
+	tests := map[string]ElasticConfig{
+		"api key and service token": {ApiKey: "key", ServiceToken: "token"},
+		"inline and file":           {ApiKey: "key", ApiKeyFile: path},
+		"missing file":              {ServiceTokenFile: filepath.Join(t.TempDir(), "missing")},
+		"empty file":                {ApiKeyFile: path},
+	}
+
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := newESConfig(cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewESConfigTLS(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+
+	esConfig, err := newESConfig(ElasticConfig{
+		Endpoint: "https://localhost:9200",
+		TLS:      TLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: certFile},
+	})
+
+	assert.NoError(t, err)
+	transport, ok := esConfig.Transport.(*http.Transport)
+	assert.True(t, ok)
+	assert.Len(t, transport.TLSClientConfig.Certificates, 1)
+	assert.NotNil(t, transport.TLSClientConfig.RootCAs)
+	assert.False(t, transport.TLSClientConfig.InsecureSkipVerify)
+}
+
+func TestNewESConfigWithoutTLS(t *testing.T) {
+	esConfig, err := newESConfig(ElasticConfig{Endpoint: "http://localhost:9200"})
+
+	assert.NoError(t, err)
+	assert.Nil(t, esConfig.Transport)
+}
+
+func TestNewESConfigInvalidTLS(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+
+	tests := map[string]TLSConfig{
+		"cert without key": {CertFile: certFile},
+		"key without cert": {KeyFile: keyFile},
+		"invalid ca file":  {CAFile: keyFile},
+		"missing ca file":  {CAFile: filepath.Join(t.TempDir(), "missing")},
+	}
+
+	for name, tlsCfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := newESConfig(ElasticConfig{TLS: tlsCfg})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func writeTestCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "teletrace"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestWriteSettings(t *testing.T) {
+	cfg := config.Config{ESEndpoints: "http://replica:9200", ESUsername: "reader", ESPassword: "read", ESIndex: "spans"}
+
+	metaCfg := NewElasticMetaConfig(cfg)
+	assert.Equal(t, "http://replica:9200", metaCfg.Endpoint, "reads and writes share the settings by default")
+	assert.Equal(t, "reader", metaCfg.Username)
+
+	cfg.ESWriteEndpoint = "http://ingest:9200"
+	cfg.ESWriteAPIKey = "id:secret"
+	metaCfg = NewElasticMetaConfig(cfg)
+	assert.Equal(t, "http://ingest:9200", metaCfg.Endpoint)
+	assert.Equal(t, "id:secret", metaCfg.ApiKey)
+	assert.Empty(t, metaCfg.Username)
+	assert.Empty(t, metaCfg.Password)
+	assert.Equal(t, "meta-spans", metaCfg.Index)
+
+	storeCfg := NewElasticMetadataStoreConfig(cfg)
+	assert.Equal(t, "http://ingest:9200", storeCfg.Endpoint)
+	assert.Equal(t, "metadata-spans", storeCfg.Index)
+
+	spansCfg := NewElasticConfig(cfg)
+	assert.Equal(t, "http://replica:9200", spansCfg.Endpoint)
+	assert.Equal(t, "reader", spansCfg.Username)
+}
@@ -18,37 +18,115 @@ package spanreaderes
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/teletrace/teletrace/pkg/config"
 )
 
 type ElasticConfig struct {
-	Endpoint     string
-	Username     string
-	Password     string
-	ApiKey       string
-	ServiceToken string
-	Index        string
+	Endpoint         string
+	Username         string
+	Password         string
+	ApiKey           string
+	ApiKeyFile       string
+	ServiceToken     string
+	ServiceTokenFile string
+	TLS              TLSConfig
+	Distribution     string
+	AWSSigV4         bool
+	AWSRegion        string
+	Index            string
+	// RemoteIndices are cross-cluster index patterns (cluster:index-*) searched along with Index
+	RemoteIndices []string
+	// QueryTimeout cancels search requests running longer, 0 disables the timeout
+	QueryTimeout time.Duration
+	// SlowQueryThreshold logs and counts slower requests, 0 disables the slow query log
+	SlowQueryThreshold time.Duration
+}
+
+// SearchIndices returns the comma separated local and remote indices to search.
+func (c ElasticConfig) SearchIndices() string {
+	return strings.Join(append([]string{c.Index}, c.RemoteIndices...), ",")
+}
+
+func parseRemoteIndices(remoteIndices string) []string {
+	var result []string
+	for _, idx := range strings.Split(remoteIndices, ",") {
+		if idx = strings.TrimSpace(idx); idx != "" {
+			result = append(result, idx)
+		}
+	}
+	return result
+}
+
+// TLSConfig holds the client certificate and server verification settings of the Elasticsearch client.
+type TLSConfig struct {
+	CertFile           string
+	KeyFile            string
+	CAFile             string
+	InsecureSkipVerify bool
+}
+
+func newTLSConfig(cfg config.Config) TLSConfig {
+	return TLSConfig{
+		CertFile:           cfg.ESTLSCertFile,
+		KeyFile:            cfg.ESTLSKeyFile,
+		CAFile:             cfg.ESTLSCAFile,
+		InsecureSkipVerify: cfg.ESTLSInsecureSkipVerify,
+	}
 }
 
 func NewElasticConfig(cfg config.Config) ElasticConfig {
 	return ElasticConfig{
-		Endpoint:     cfg.ESEndpoints,
-		Username:     cfg.ESUsername,
-		Password:     cfg.ESPassword,
-		ApiKey:       cfg.ESAPIKey,
-		ServiceToken: cfg.ESServiceToken,
-		Index:        cfg.ESIndex,
+		Endpoint:           cfg.ESEndpoints,
+		Username:           cfg.ESUsername,
+		Password:           cfg.ESPassword,
+		ApiKey:             cfg.ESAPIKey,
+		ApiKeyFile:         cfg.ESAPIKeyFile,
+		ServiceToken:       cfg.ESServiceToken,
+		ServiceTokenFile:   cfg.ESServiceTokenFile,
+		TLS:                newTLSConfig(cfg),
+		Distribution:       cfg.ESDistribution,
+		AWSSigV4:           cfg.ESAWSSigV4,
+		AWSRegion:          cfg.ESAWSRegion,
+		Index:              cfg.ESIndex,
+		RemoteIndices:      parseRemoteIndices(cfg.ESRemoteIndices),
+		QueryTimeout:       time.Duration(cfg.StorageQueryTimeoutSeconds) * time.Second,
+		SlowQueryThreshold: time.Duration(cfg.StorageSlowQueryThresholdMilliseconds) * time.Millisecond,
 	}
 }
 
 func NewElasticMetaConfig(cfg config.Config) ElasticConfig {
-	return ElasticConfig{
-		Endpoint:     cfg.ESEndpoints,
-		Username:     cfg.ESUsername,
-		Password:     cfg.ESPassword,
-		ApiKey:       cfg.ESAPIKey,
-		ServiceToken: cfg.ESServiceToken,
-		Index:        fmt.Sprintf("meta-%s", cfg.ESIndex),
+	return withWriteSettings(ElasticConfig{
+		Endpoint:         cfg.ESEndpoints,
+		Username:         cfg.ESUsername,
+		Password:         cfg.ESPassword,
+		ApiKey:           cfg.ESAPIKey,
+		ApiKeyFile:       cfg.ESAPIKeyFile,
+		ServiceToken:     cfg.ESServiceToken,
+		ServiceTokenFile: cfg.ESServiceTokenFile,
+		TLS:              newTLSConfig(cfg),
+		Distribution:     cfg.ESDistribution,
+		AWSSigV4:         cfg.ESAWSSigV4,
+		AWSRegion:        cfg.ESAWSRegion,
+		Index:            fmt.Sprintf("meta-%s", cfg.ESIndex),
+	}, cfg)
+}
+
+// withWriteSettings returns esCfg with the write endpoint and credentials of cfg, where set. Write credentials replace
+// all of the read credentials, so the writes don't fall back to another form of read credentials.
+func withWriteSettings(esCfg ElasticConfig, cfg config.Config) ElasticConfig {
+	if cfg.ESWriteEndpoint != "" {
+		esCfg.Endpoint = cfg.ESWriteEndpoint
+	}
+	if cfg.ESWriteUsername != "" || cfg.ESWritePassword != "" || cfg.ESWriteAPIKey != "" || cfg.ESWriteAPIKeyFile != "" {
+		esCfg.Username = cfg.ESWriteUsername
+		esCfg.Password = cfg.ESWritePassword
+		esCfg.ApiKey = cfg.ESWriteAPIKey
+		esCfg.ApiKeyFile = cfg.ESWriteAPIKeyFile
+		esCfg.ServiceToken = ""
+		esCfg.ServiceTokenFile = ""
 	}
+	return esCfg
 }
@@ -16,11 +16,19 @@
 
 package errors
 
-import "fmt"
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/teletrace/teletrace/pkg/spanreader"
+)
 
 const (
-	IndexNotFoundError string = "index_not_found_exception"
-	Unknown            string = "unknown"
+	IndexNotFoundError         string = "index_not_found_exception"
+	ResourceAlreadyExistsError string = "resource_already_exists_exception"
+	Unknown                    string = "unknown"
 )
 
 type ElasticSearchError struct {
@@ -33,22 +41,43 @@ func (e ElasticSearchError) Error() string {
 	return e.Message
 }
 
+// Unwrap returns the span reader error of the response status, e.g. spanreader.ErrInvalidQuery for a request
+// rejected by the cluster, or nil for other statuses.
+func (e ElasticSearchError) Unwrap() error {
+	if e.ErrorType == IndexNotFoundError {
+		return spanreader.ErrNotFound
+	}
+	status, _ := strconv.Atoi(strings.SplitN(e.HttpStatus, " ", 2)[0])
+	switch status {
+	case http.StatusBadRequest:
+		return spanreader.ErrInvalidQuery
+	case http.StatusNotFound:
+		return spanreader.ErrNotFound
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return spanreader.ErrBackendUnavailable
+	default:
+		return nil
+	}
+}
+
 func ESErrorFromHttpResponse(status string, body map[string]any) (*ElasticSearchError, error) {
 	errorMap, errorMapExists := body["error"]
 	if !errorMapExists {
 		return nil, fmt.Errorf("missing 'error' object in response: %+v", body)
 	}
 
-	errorReason, reasonExists := errorMap.(map[string]any)["reason"]
-	errorType, typeExists := errorMap.(map[string]any)["type"]
-
 	message := "an error occurred"
-	if !reasonExists {
-		message = errorReason.(string)
-	}
 	finalErrorType := Unknown
-	if !typeExists {
-		finalErrorType = errorType.(string)
+	// the error of some APIs is a string rather than an object
+	if errorObject, ok := errorMap.(map[string]any); ok {
+		if reason, ok := errorObject["reason"].(string); ok {
+			message = reason
+		}
+		if errorType, ok := errorObject["type"].(string); ok {
+			finalErrorType = errorType
+		}
+	} else if reason, ok := errorMap.(string); ok {
+		message = reason
 	}
 	return &ElasticSearchError{
 		Message:    message,
@@ -0,0 +1,48 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package errors
+
+import (
+	stderrors "errors"
+	"testing"
+
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestESErrorFromHttpResponse(t *testing.T) {
+	err, parseErr := ESErrorFromHttpResponse("404 Not Found", map[string]any{
+		"error": map[string]any{"type": IndexNotFoundError, "reason": "no such index [spans]"},
+	})
+	assert.NoError(t, parseErr)
+	assert.Equal(t, IndexNotFoundError, err.ErrorType)
+	assert.EqualError(t, err, "no such index [spans]")
+	assert.ErrorIs(t, err, spanreader.ErrNotFound)
+}
+
+func TestElasticSearchErrorCause(t *testing.T) {
+	for status, expected := range map[string]error{
+		"400 Bad Request":           spanreader.ErrInvalidQuery,
+		"429 Too Many Requests":     spanreader.ErrBackendUnavailable,
+		"503 Service Unavailable":   spanreader.ErrBackendUnavailable,
+		"500 Internal Server Error": nil,
+	} {
+		err := &ElasticSearchError{Message: "failed", HttpStatus: status, ErrorType: Unknown}
+		assert.Equal(t, expected, stderrors.Unwrap(err), status)
+	}
+}
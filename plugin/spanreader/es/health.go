@@ -0,0 +1,66 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spanreaderes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const clusterHealthRed = "red"
+
+// Ping checks that the cluster is reachable and its health isn't red, i.e. all of its primary shards are allocated.
+// OpenSearch Serverless has no cluster health API, so a count query on the search indices is run instead.
+func (sr *spanReader) Ping(ctx context.Context) error {
+	if sr.cfg.Distribution == DistributionOpenSearchServerless {
+		res, err := sr.rawClient.Count(
+			sr.rawClient.Count.WithContext(ctx),
+			sr.rawClient.Count.WithIndex(strings.Split(sr.cfg.SearchIndices(), ",")...),
+			sr.rawClient.Count.WithIgnoreUnavailable(true),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to count spans: %w", err)
+		}
+		defer res.Body.Close()
+		if res.IsError() {
+			return fmt.Errorf("failed to count spans: %s", res.Status())
+		}
+		return nil
+	}
+
+	res, err := sr.rawClient.Cluster.Health(sr.rawClient.Cluster.Health.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to get cluster health: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to get cluster health: %s", res.Status())
+	}
+
+	var health struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&health); err != nil {
+		return fmt.Errorf("failed to decode cluster health: %w", err)
+	}
+	if health.Status == clusterHealthRed {
+		return fmt.Errorf("cluster health is %s", health.Status)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spanreaderes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/config"
+	"github.com/teletrace/teletrace/pkg/metadatastore"
+	"github.com/teletrace/teletrace/pkg/migrations"
+	"github.com/teletrace/teletrace/plugin/spanreader/es/tagscontroller"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"go.uber.org/zap"
+)
+
+const (
+	metadataStoreIndexMapping = `{
+	"mappings": {
+		"properties": {
+			"namespace": {"type": "keyword"},
+			"key": {"type": "keyword"},
+			"value": {"type": "binary"},
+			"updated_at": {"type": "date", "format": "epoch_millis"}
+		}
+	}
+}`
+	// metadataStoreMaxListSize is the maximum number of records returned when listing a namespace
+	metadataStoreMaxListSize = 10000
+)
+
+type metadataDocument struct {
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+	Value     []byte `json:"value"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+func (d metadataDocument) toRecord() metadatastore.Record {
+	return metadatastore.Record{
+		Namespace: d.Namespace,
+		Key:       d.Key,
+		Value:     d.Value,
+		UpdatedAt: time.UnixMilli(d.UpdatedAt).UTC(),
+	}
+}
+
+type metadataStore struct {
+	cfg    ElasticConfig
+	logger *zap.Logger
+	ctx    context.Context
+	client *elasticsearch.Client
+}
+
+func NewElasticMetadataStoreConfig(cfg config.Config) ElasticConfig {
+	esCfg := withWriteSettings(NewElasticConfig(cfg), cfg)
+	esCfg.Index = fmt.Sprintf("metadata-%s", cfg.ESIndex)
+	esCfg.RemoteIndices = nil
+	return esCfg
+}
+
+// NewMetadataStore returns a MetadataStore that keeps each record as a document in a dedicated index.
+func NewMetadataStore(ctx context.Context, logger *zap.Logger, cfg ElasticConfig) (metadatastore.MetadataStore, error) {
+	client, err := newRawClient(logger, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create a new metadata store: %w", err)
+	}
+	return &metadataStore{
+		cfg:    cfg,
+		logger: logger,
+		ctx:    ctx,
+		client: client,
+	}, nil
+}
+
+// Initialize applies the pending schema migrations of the metadata index.
+func (s *metadataStore) Initialize() error {
+	migrator, err := migrations.NewMigrator(s.logger, "metadata index", migrationStore{client: s.client, migrated: s.cfg.Index}, s.schemaMigrations(), migrations.NewDefaultConfig())
+	if err != nil {
+		return err
+	}
+	if err := migrator.Up(s.ctx); err != nil {
+		return fmt.Errorf("failed to migrate metadata index: %w", err)
+	}
+	return nil
+}
+
+func (s *metadataStore) Get(ctx context.Context, namespace string, key string) (*metadatastore.Record, error) {
+	res, err := s.client.Get(s.cfg.Index, metadataDocumentId(namespace, key), s.client.Get.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("could not get metadata record: %+v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, metadatastore.ErrNotFound
+	}
+	if err := tagscontroller.SummarizeResponseError(res); err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Source metadataDocument `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode body: %v", err)
+	}
+	record := body.Source.toRecord()
+	return &record, nil
+}
+
+func (s *metadataStore) List(ctx context.Context, namespace string) ([]metadatastore.Record, error) {
+	query, err := json.Marshal(map[string]any{
+		"query": map[string]any{"term": map[string]any{"namespace": namespace}},
+		"sort":  []any{map[string]any{"key": "asc"}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not build metadata query: %+v", err)
+	}
+
+	res, err := s.client.Search(
+		s.client.Search.WithContext(ctx),
+		s.client.Search.WithIndex(s.cfg.Index),
+		s.client.Search.WithBody(bytes.NewReader(query)),
+		s.client.Search.WithSize(metadataStoreMaxListSize),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not list metadata records: %+v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return []metadatastore.Record{}, nil
+	}
+	if err := tagscontroller.SummarizeResponseError(res); err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Hits struct {
+			Hits []struct {
+				Source metadataDocument `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode body: %v", err)
+	}
+
+	records := make([]metadatastore.Record, 0, len(body.Hits.Hits))
+	for _, hit := range body.Hits.Hits {
+		records = append(records, hit.Source.toRecord())
+	}
+	return records, nil
+}
+
+func (s *metadataStore) Put(ctx context.Context, record metadatastore.Record) error {
+	return s.index(ctx, record, "index")
+}
+
+func (s *metadataStore) Create(ctx context.Context, record metadatastore.Record) error {
+	return s.index(ctx, record, "create")
+}
+
+func (s *metadataStore) index(ctx context.Context, record metadatastore.Record, opType string) error {
+	doc, err := json.Marshal(metadataDocument{
+		Namespace: record.Namespace,
+		Key:       record.Key,
+		Value:     record.Value,
+		UpdatedAt: time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return fmt.Errorf("could not encode metadata record: %+v", err)
+	}
+
+	res, err := s.client.Index(
+		s.cfg.Index,
+		bytes.NewReader(doc),
+		s.client.Index.WithContext(ctx),
+		s.client.Index.WithDocumentID(metadataDocumentId(record.Namespace, record.Key)),
+		s.client.Index.WithOpType(opType),
+		s.client.Index.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		return fmt.Errorf("could not index metadata record: %+v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusConflict {
+		return metadatastore.ErrAlreadyExists
+	}
+	return tagscontroller.SummarizeResponseError(res)
+}
+
+func (s *metadataStore) Delete(ctx context.Context, namespace string, key string) error {
+	res, err := s.client.Delete(
+		s.cfg.Index,
+		metadataDocumentId(namespace, key),
+		s.client.Delete.WithContext(ctx),
+		s.client.Delete.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		return fmt.Errorf("could not delete metadata record: %+v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return metadatastore.ErrNotFound
+	}
+	return tagscontroller.SummarizeResponseError(res)
+}
+
+func (s *metadataStore) Close() error {
+	return nil
+}
+
+// metadataDocumentId returns a url-safe document id, since the client doesn't escape ids in request paths.
+func metadataDocumentId(namespace string, key string) string {
+	return url.PathEscape(namespace + ":" + key)
+}
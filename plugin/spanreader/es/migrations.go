@@ -0,0 +1,273 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package spanreaderes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/migrations"
+	eserrors "github.com/teletrace/teletrace/plugin/spanreader/es/errors"
+	"github.com/teletrace/teletrace/plugin/spanreader/es/tagscontroller"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+const (
+	// schemaIndexPrefix prefixes the index holding the schema version and the migrations lock of an index
+	schemaIndexPrefix = "schema-"
+	schemaVersionId   = "version"
+	schemaLockId      = "lock"
+)
+
+type schemaVersionDocument struct {
+	Version int `json:"version"`
+}
+
+type schemaLockDocument struct {
+	Owner     string `json:"owner"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// schemaMigrations returns the migrations of the metadata index, which may only be appended to.
+func (s *metadataStore) schemaMigrations() []migrations.Migration {
+	return []migrations.Migration{
+		{
+			Version:     1,
+			Description: "create the metadata index",
+			Up:          s.createIndex,
+		},
+	}
+}
+
+// createIndex creates the metadata index with its mapping, unless it was created before migrations were versioned.
+func (s *metadataStore) createIndex(ctx context.Context) error {
+	res, err := s.client.Indices.Exists([]string{s.cfg.Index}, s.client.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("could not check metadata index: %+v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	res, err = s.client.Indices.Create(
+		s.cfg.Index,
+		s.client.Indices.Create.WithContext(ctx),
+		s.client.Indices.Create.WithBody(strings.NewReader(metadataStoreIndexMapping)),
+	)
+	if err != nil {
+		return fmt.Errorf("could not create metadata index: %+v", err)
+	}
+	defer res.Body.Close()
+	return tagscontroller.SummarizeResponseError(res)
+}
+
+// spanIndexMapping pins the types of the fields the spans are searched, sorted and aggregated by, which would
+// otherwise be mapped by the first span written. The other fields are mapped dynamically.
+const spanIndexMapping = `{
+	"mappings": {
+		"properties": {
+			"span": {
+				"properties": {
+					"spanId": {"type": "text", "fields": {"keyword": {"type": "keyword", "ignore_above": 256}}},
+					"traceId": {"type": "text", "fields": {"keyword": {"type": "keyword", "ignore_above": 256}}},
+					"parentSpanId": {"type": "text", "fields": {"keyword": {"type": "keyword", "ignore_above": 256}}},
+					"startTimeUnixNano": {"type": "long"},
+					"endTimeUnixNano": {"type": "long"}
+				}
+			},
+			"externalFields": {
+				"properties": {
+					"durationNano": {"type": "long"}
+				}
+			}
+		}
+	}
+}`
+
+// spanIndexMigrations returns the migrations of the span index, which may only be appended to. Changes of the
+// mapping of the spans, or of how they're indexed, are applied to existing indices by appending migrations here.
+func (sr *spanReader) spanIndexMigrations() []migrations.Migration {
+	return []migrations.Migration{
+		{
+			Version:     1,
+			Description: "create the span index",
+			Up:          sr.createIndex,
+		},
+	}
+}
+
+// createIndex creates the span index with its mapping, unless the exporter, or a version without migrations of the
+// span index, created it already.
+func (sr *spanReader) createIndex(ctx context.Context) error {
+	res, err := sr.rawClient.Indices.Exists([]string{sr.cfg.Index}, sr.rawClient.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("could not check span index: %+v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	res, err = sr.rawClient.Indices.Create(
+		sr.cfg.Index,
+		sr.rawClient.Indices.Create.WithContext(ctx),
+		sr.rawClient.Indices.Create.WithBody(strings.NewReader(spanIndexMapping)),
+	)
+	if err != nil {
+		return fmt.Errorf("could not create span index: %+v", err)
+	}
+	defer res.Body.Close()
+	err = tagscontroller.SummarizeResponseError(res)
+	// the exporter may have created the index since it was checked
+	var esErr *eserrors.ElasticSearchError
+	if errors.As(err, &esErr) && esErr.ErrorType == eserrors.ResourceAlreadyExistsError {
+		return nil
+	}
+	return err
+}
+
+// migrationStore keeps the schema version and the migrations lock of an index as documents of a dedicated index,
+// relying on optimistic concurrency control to acquire the lock.
+type migrationStore struct {
+	client *elasticsearch.Client
+	// migrated is the index whose schema is migrated
+	migrated string
+}
+
+func (m migrationStore) index() string {
+	return schemaIndexPrefix + m.migrated
+}
+
+func (m migrationStore) Version(ctx context.Context) (int, error) {
+	var doc schemaVersionDocument
+	if _, _, _, err := m.get(ctx, schemaVersionId, &doc); err != nil {
+		return 0, err
+	}
+	return doc.Version, nil
+}
+
+func (m migrationStore) SetVersion(ctx context.Context, version int) error {
+	_, err := m.put(ctx, schemaVersionId, schemaVersionDocument{Version: version})
+	return err
+}
+
+// Lock creates the lock document, or takes it over if it expired or is held by the same owner.
+// Concurrent instances racing for the lock conflict on its sequence number, so only one of them acquires it.
+func (m migrationStore) Lock(ctx context.Context, owner string, expiresAt time.Time) (bool, error) {
+	var current schemaLockDocument
+	found, seqNo, primaryTerm, err := m.get(ctx, schemaLockId, &current)
+	if err != nil {
+		return false, err
+	}
+
+	lock := schemaLockDocument{Owner: owner, ExpiresAt: expiresAt.UnixMilli()}
+	if !found {
+		conflict, err := m.put(ctx, schemaLockId, lock, m.client.Index.WithOpType("create"))
+		return !conflict, err
+	}
+	if current.Owner != owner && current.ExpiresAt > time.Now().UnixMilli() {
+		return false, nil
+	}
+	conflict, err := m.put(ctx, schemaLockId, lock,
+		m.client.Index.WithIfSeqNo(seqNo),
+		m.client.Index.WithIfPrimaryTerm(primaryTerm),
+	)
+	return !conflict, err
+}
+
+func (m migrationStore) Unlock(ctx context.Context, owner string) error {
+	var current schemaLockDocument
+	found, seqNo, primaryTerm, err := m.get(ctx, schemaLockId, &current)
+	if err != nil || !found || current.Owner != owner {
+		return err
+	}
+
+	res, err := m.client.Delete(
+		m.index(),
+		schemaLockId,
+		m.client.Delete.WithContext(ctx),
+		m.client.Delete.WithIfSeqNo(seqNo),
+		m.client.Delete.WithIfPrimaryTerm(primaryTerm),
+	)
+	if err != nil {
+		return fmt.Errorf("could not delete schema lock: %+v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusConflict {
+		return nil
+	}
+	return tagscontroller.SummarizeResponseError(res)
+}
+
+// get reads the source of a schema document into source, along with its sequence number and primary term.
+// A missing document, or a missing index, isn't found.
+func (m migrationStore) get(ctx context.Context, id string, source any) (found bool, seqNo int, primaryTerm int, err error) {
+	res, err := m.client.Get(m.index(), id, m.client.Get.WithContext(ctx))
+	if err != nil {
+		return false, 0, 0, fmt.Errorf("could not get schema document: %+v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return false, 0, 0, nil
+	}
+	if err := tagscontroller.SummarizeResponseError(res); err != nil {
+		return false, 0, 0, err
+	}
+
+	var body struct {
+		SeqNo       int             `json:"_seq_no"`
+		PrimaryTerm int             `json:"_primary_term"`
+		Source      json.RawMessage `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return false, 0, 0, fmt.Errorf("failed to decode body: %v", err)
+	}
+	if err := json.Unmarshal(body.Source, source); err != nil {
+		return false, 0, 0, fmt.Errorf("failed to decode schema document: %v", err)
+	}
+	return true, body.SeqNo, body.PrimaryTerm, nil
+}
+
+// put indexes a schema document, and returns whether it conflicted with a concurrent write.
+func (m migrationStore) put(ctx context.Context, id string, doc any, opts ...func(*esapi.IndexRequest)) (bool, error) {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return false, fmt.Errorf("could not encode schema document: %+v", err)
+	}
+
+	opts = append([]func(*esapi.IndexRequest){
+		m.client.Index.WithContext(ctx),
+		m.client.Index.WithDocumentID(id),
+	}, opts...)
+	res, err := m.client.Index(m.index(), bytes.NewReader(body), opts...)
+	if err != nil {
+		return false, fmt.Errorf("could not index schema document: %+v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusConflict {
+		return true, nil
+	}
+	return false, tagscontroller.SummarizeResponseError(res)
+}
@@ -0,0 +1,70 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spanreaderes
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateSpanIndex(t *testing.T) {
+	for name, tc := range map[string]struct {
+		exists   bool
+		status   int
+		response string
+		create   bool
+	}{
+		"missing":         {status: http.StatusOK, response: `{"acknowledged": true}`, create: true},
+		"existing":        {exists: true},
+		"created by race": {status: http.StatusBadRequest, response: `{"error": {"type": "resource_already_exists_exception", "reason": "exists"}, "status": 400}`, create: true},
+	} {
+		var mapping string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Elastic-Product", "Elasticsearch")
+			w.Header().Set("Content-Type", "application/json")
+			assert.Equal(t, "/teletrace-traces", r.URL.Path, name)
+			switch {
+			case r.Method == http.MethodHead && tc.exists:
+				w.WriteHeader(http.StatusOK)
+			case r.Method == http.MethodHead:
+				w.WriteHeader(http.StatusNotFound)
+			case r.Method == http.MethodPut:
+				body, _ := io.ReadAll(r.Body)
+				mapping = string(body)
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.response))
+			}
+		}))
+		client, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{server.URL}})
+		assert.NoError(t, err)
+		sr := &spanReader{cfg: ElasticConfig{Index: "teletrace-traces"}, rawClient: client}
+
+		assert.NoError(t, sr.createIndex(context.Background()), name)
+		if tc.create {
+			assert.JSONEq(t, spanIndexMapping, mapping, name)
+		} else {
+			assert.Empty(t, mapping, name)
+		}
+		server.Close()
+	}
+}
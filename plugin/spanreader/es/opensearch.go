@@ -0,0 +1,106 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spanreaderes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/teletrace/teletrace/blobstore/sigv4"
+)
+
+const (
+	DistributionElasticsearch        = "elasticsearch"
+	DistributionOpenSearch           = "opensearch"
+	DistributionOpenSearchServerless = "opensearch-serverless"
+
+	// compatibleMediaTypePrefix is the versioned media type sent by the elasticsearch client,
+	// which OpenSearch doesn't accept
+	compatibleMediaTypePrefix = "application/vnd.elasticsearch+json"
+)
+
+// openSearchTransport adapts the elasticsearch client to OpenSearch: it negotiates plain JSON
+// instead of the elasticsearch versioned media types, reports the product header the client
+// verifies, and signs requests with sigv4 when configured.
+type openSearchTransport struct {
+	next   http.RoundTripper
+	signer *sigv4.Signer
+}
+
+func newOpenSearchTransport(next http.RoundTripper, cfg ElasticConfig) (http.RoundTripper, error) {
+	transport := &openSearchTransport{next: next}
+
+	var service string
+	switch cfg.Distribution {
+	case DistributionOpenSearch:
+		if cfg.AWSSigV4 {
+			service = sigv4.ServiceOpenSearch
+		}
+	case DistributionOpenSearchServerless:
+		// serverless collections only support IAM authentication
+		service = sigv4.ServiceOpenSearchServerless
+	default:
+		return nil, fmt.Errorf("unsupported opensearch distribution %s", cfg.Distribution)
+	}
+	if service == "" {
+		return transport, nil
+	}
+
+	if cfg.AWSRegion == "" {
+		return nil, fmt.Errorf("aws region must be configured for sigv4 authentication")
+	}
+	signer, err := sigv4.NewSigner(context.Background(), cfg.AWSRegion, service)
+	if err != nil {
+		return nil, err
+	}
+	transport.signer = signer
+
+	return transport, nil
+}
+
+func (t *openSearchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for _, header := range []string{"Accept", "Content-Type"} {
+		if strings.HasPrefix(req.Header.Get(header), compatibleMediaTypePrefix) {
+			req.Header.Set(header, "application/json")
+		}
+	}
+
+	if t.signer != nil {
+		// the basic auth header set by the client would conflict with the sigv4 authorization
+		req.Header.Del("Authorization")
+		if err := t.signer.Sign(req); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.Header == nil {
+		res.Header = http.Header{}
+	}
+	res.Header.Set("X-Elastic-Product", "Elasticsearch")
+	return res, nil
+}
+
+func isOpenSearch(distribution string) bool {
+	return distribution == DistributionOpenSearch || distribution == DistributionOpenSearchServerless
+}
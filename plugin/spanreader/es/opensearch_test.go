@@ -0,0 +1,79 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spanreaderes
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/teletrace/teletrace/blobstore/sigv4"
+)
+
+func newTestSigner(t *testing.T, service string) *sigv4.Signer {
+	signer, err := sigv4.NewSigner(context.Background(), "us-east-1", service)
+	assert.NoError(t, err)
+	signer.Credentials = credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "")
+	signer.Now = func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) }
+	return signer
+}
+
+func TestOpenSearchServerlessTransport(t *testing.T) {
+	var received *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	transport := &openSearchTransport{next: http.DefaultTransport, signer: newTestSigner(t, sigv4.ServiceOpenSearchServerless)}
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/traces/_search", strings.NewReader(`{"size":0}`))
+	req.Header.Set("Content-Type", "application/vnd.elasticsearch+json;compatible-with=8")
+	req.Header.Set("Accept", "application/vnd.elasticsearch+json;compatible-with=8")
+	req.SetBasicAuth("elastic", "")
+
+	res, err := transport.RoundTrip(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Elasticsearch", res.Header.Get("X-Elastic-Product"))
+	assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+	assert.Equal(t, "application/json", received.Header.Get("Accept"))
+	hash := sha256.Sum256([]byte(`{"size":0}`))
+	assert.Equal(t, hex.EncodeToString(hash[:]), received.Header.Get("X-Amz-Content-Sha256"))
+	assert.True(t, strings.HasPrefix(received.Header.Get("Authorization"), sigv4.Algorithm))
+	assert.Contains(t, received.Header.Get("Authorization"), "/us-east-1/aoss/aws4_request")
+}
+
+func TestNewESConfigOpenSearch(t *testing.T) {
+	esConfig, err := newESConfig(ElasticConfig{Endpoint: "https://localhost:9200", Distribution: DistributionOpenSearch})
+	assert.NoError(t, err)
+	assert.IsType(t, &openSearchTransport{}, esConfig.Transport)
+
+	_, err = newESConfig(ElasticConfig{Endpoint: "https://localhost:9200", Distribution: DistributionOpenSearchServerless})
+	assert.Error(t, err, "region is required for serverless collections")
+
+	_, err = newESConfig(ElasticConfig{Endpoint: "https://localhost:9200", Distribution: "solr"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,205 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package searchcontroller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/tracing"
+	"github.com/teletrace/teletrace/plugin/spanreader/es/tagscontroller"
+	spanreaderes "github.com/teletrace/teletrace/plugin/spanreader/es/utils"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// Aggregate computes the aggregations of r with a terms aggregation of groupByField, the field of the group by tag
+// in the index, and a missing aggregation of the spans without the tag, each with a sub aggregation per metric.
+func (sc *searchController) Aggregate(
+	ctx context.Context, r spansquery.SearchRequest, groupByField string,
+) (*spansquery.SearchResponse, error) {
+	_, span := spanreaderes.Tracer.Start(ctx, "elasticsearch.build_aggregate_request")
+	body, err := buildAggregateBody(r, groupByField)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not build aggregate request: %v", spanreader.ErrInvalidQuery, err)
+	}
+
+	res, err := sc.rawClient.Search(
+		sc.rawClient.Search.WithContext(ctx),
+		sc.rawClient.Search.WithIndex(strings.Split(sc.idx, ",")...),
+		sc.rawClient.Search.WithBody(bytes.NewReader(body)),
+		sc.rawClient.Search.WithIgnoreUnavailable(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Could not aggregate spans: %w", err)
+	}
+
+	defer res.Body.Close()
+	if err := tagscontroller.SummarizeResponseError(res); err != nil {
+		return nil, err
+	}
+
+	var resBody map[string]any
+	decoder := json.NewDecoder(res.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&resBody); err != nil {
+		return nil, fmt.Errorf("failed to decode body: %v", err)
+	}
+
+	return &spansquery.SearchResponse{
+		Metadata: &spansquery.Metadata{},
+		Spans:    []*internalspan.InternalSpan{},
+		Aggregations: &spansquery.AggregationsResult{
+			Groups: parseAggregateResponse(resBody, *r.Aggregations),
+		},
+	}, nil
+}
+
+// buildAggregateBody returns the body of the search of the aggregations of r. Without a group by tag, the matching
+// spans are a single group of a filter aggregation matching all of them.
+func buildAggregateBody(r spansquery.SearchRequest, groupByField string) ([]byte, error) {
+	req, err := buildSearchRequest(spansquery.SearchRequest{Timeframe: r.Timeframe, SearchFilters: r.SearchFilters})
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := make(map[string]any)
+	for _, m := range r.Aggregations.Metrics {
+		switch m.Function {
+		case spansquery.AGGREGATION_COUNT:
+			// the count of a group is the document count of its bucket
+		case spansquery.AGGREGATION_SUM, spansquery.AGGREGATION_AVG, spansquery.AGGREGATION_MIN, spansquery.AGGREGATION_MAX:
+			metrics[m.Name()] = map[string]any{string(m.Function): map[string]any{"field": m.Field}}
+		default:
+			metrics[m.Name()] = map[string]any{"percentiles": map[string]any{
+				"field":    m.Field,
+				"percents": []float64{spansquery.AggregationPercentiles[m.Function] * 100},
+			}}
+		}
+	}
+
+	aggs := map[string]any{}
+	if r.Aggregations.GroupBy == "" {
+		aggs["all"] = map[string]any{"filter": map[string]any{"match_all": map[string]any{}}, "aggs": metrics}
+	} else {
+		aggs["groups"] = map[string]any{
+			"terms": map[string]any{"field": groupByField, "size": r.Aggregations.EffectiveLimit()},
+			"aggs":  metrics,
+		}
+		aggs["ungrouped"] = map[string]any{"missing": map[string]any{"field": groupByField}, "aggs": metrics}
+	}
+
+	return json.Marshal(map[string]any{
+		"size":  0,
+		"query": req.Query,
+		"aggs":  aggs,
+	})
+}
+
+// parseAggregateResponse returns the groups of the buckets of the aggregations, the largest first, the spans without
+// the group by tag being grouped under a null value.
+func parseAggregateResponse(body map[string]any, r spansquery.Aggregations) []spansquery.AggregationGroup {
+	groups := []spansquery.AggregationGroup{}
+	aggs, _ := body["aggregations"].(map[string]any)
+	if all, ok := aggs["all"].(map[string]any); ok {
+		if group, ok := parseAggregationBucket(all, nil, r); ok {
+			groups = append(groups, group)
+		}
+	}
+	terms, _ := aggs["groups"].(map[string]any)
+	buckets, _ := terms["buckets"].([]any)
+	for _, b := range buckets {
+		bucket, _ := b.(map[string]any)
+		if group, ok := parseAggregationBucket(bucket, bucketKey(bucket), r); ok {
+			groups = append(groups, group)
+		}
+	}
+	if ungrouped, ok := aggs["ungrouped"].(map[string]any); ok {
+		if group, ok := parseAggregationBucket(ungrouped, nil, r); ok {
+			groups = append(groups, group)
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Count > groups[j].Count })
+	if limit := r.EffectiveLimit(); len(groups) > limit {
+		groups = groups[:limit]
+	}
+	return groups
+}
+
+// parseAggregationBucket returns the group of value of a bucket, and false if the bucket has no spans.
+func parseAggregationBucket(bucket map[string]any, value any, r spansquery.Aggregations) (spansquery.AggregationGroup, bool) {
+	docCount, _ := bucket["doc_count"].(json.Number)
+	count, _ := docCount.Int64()
+	if count == 0 {
+		return spansquery.AggregationGroup{}, false
+	}
+	group := spansquery.AggregationGroup{Value: value, Count: uint64(count), Metrics: make(map[string]float64)}
+	for _, m := range r.Metrics {
+		if m.Function == spansquery.AGGREGATION_COUNT {
+			group.Metrics[m.Name()] = float64(count)
+			continue
+		}
+		metric, _ := bucket[m.Name()].(map[string]any)
+		raw := metric["value"]
+		if values, ok := metric["values"].(map[string]any); ok {
+			raw = values[strconv.FormatFloat(spansquery.AggregationPercentiles[m.Function]*100, 'f', 1, 64)]
+		}
+		number, ok := raw.(json.Number)
+		if !ok {
+			// the metrics of fields without values in the group are null
+			continue
+		}
+		v, err := number.Float64()
+		if err != nil {
+			continue
+		}
+		// timestamps are stored in milliseconds
+		if spanreaderes.IsConvertedTimestamp(model.FilterKey(m.Field)) {
+			v = spanreaderes.MilliToNanoFloat64(v)
+		}
+		group.Metrics[m.Name()] = v
+	}
+	return group, true
+}
+
+// bucketKey returns the value of the group by tag of a terms bucket, booleans being keyed by 1 and 0.
+func bucketKey(bucket map[string]any) any {
+	switch bucket["key_as_string"] {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if number, ok := bucket["key"].(json.Number); ok {
+		if n, err := number.Int64(); err == nil {
+			return n
+		}
+		f, _ := number.Float64()
+		return f
+	}
+	return bucket["key"]
+}
@@ -25,4 +25,9 @@ import (
 type SearchController interface {
 	// Search spans in database
 	Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error)
+	// SearchTraces returns the traces having spans matching r by their most recent matching span, skipping offset
+	// traces, up to limit. The traces are returned without their spans.
+	SearchTraces(ctx context.Context, r spansquery.SearchTracesRequest, offset int, limit int) ([]*spansquery.TraceResult, error)
+	// Aggregate computes the aggregations of r, grouping the spans by groupByField, the indexed field of the group by tag
+	Aggregate(ctx context.Context, r spansquery.SearchRequest, groupByField string) (*spansquery.SearchResponse, error)
 }
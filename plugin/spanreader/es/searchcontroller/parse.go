@@ -34,6 +34,8 @@ func parseSpansResponse(body map[string]any, opts ...SpanParseOption) (*spansque
 	hits := body["hits"].(map[string]any)["hits"].([]any)
 
 	spans := []*internalspan.InternalSpan{}
+	// the same span may be returned by several clusters when searching across clusters with overlapping data
+	seenSpans := make(map[string]struct{}, len(hits))
 	for _, h := range hits {
 		hit := h.(map[string]any)["_source"].(map[string]any)
 		var s internalspan.InternalSpan
@@ -44,6 +46,12 @@ func parseSpansResponse(body map[string]any, opts ...SpanParseOption) (*spansque
 		if err != nil {
 			return nil, fmt.Errorf("Could not decode response hit from elasticsearch: %+v", err)
 		}
+		if spanKey := s.Span.TraceId + s.Span.SpanId; spanKey != "" {
+			if _, seen := seenSpans[spanKey]; seen {
+				continue
+			}
+			seenSpans[spanKey] = struct{}{}
+		}
 		spans = append(spans, &s)
 	}
 
@@ -91,5 +99,6 @@ func withMiliSecTimestampAsNanoSec() SpanParseOption {
 		}
 
 		s.ExternalFields.DurationNano = s.ExternalFields.DurationNano * 1000 * 1000
+		s.IngestionTimeUnixNano = s.IngestionTimeUnixNano * 1000 * 1000
 	}
 }
@@ -17,11 +17,16 @@
 package searchcontroller
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/tracing"
 	"github.com/teletrace/teletrace/plugin/spanreader/es/errors"
+	"github.com/teletrace/teletrace/plugin/spanreader/es/tagscontroller"
 	spanreaderes "github.com/teletrace/teletrace/plugin/spanreader/es/utils"
 
 	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
@@ -33,30 +38,42 @@ import (
 	"go.uber.org/zap"
 )
 
-const TieBreakerField = "span.spanId.keyword"
+const (
+	TieBreakerField = "span.spanId.keyword"
+
+	// defaultSearchSize is the page size of searches without a limit
+	defaultSearchSize = 50
+)
 
 type searchController struct {
-	client *elasticsearch.TypedClient
-	idx    string
+	rawClient *elasticsearch.Client
+	client    *elasticsearch.TypedClient
+	idx       string
 }
 
-func NewSearchController(logger *zap.Logger, client *elasticsearch.TypedClient, idx string) (*searchController, error) {
-	return &searchController{client: client, idx: idx}, nil
+func NewSearchController(logger *zap.Logger, rawClient *elasticsearch.Client, client *elasticsearch.TypedClient, idx string) (*searchController, error) {
+	return &searchController{rawClient: rawClient, client: client, idx: idx}, nil
 }
 
 func (sc *searchController) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
 	var err error
 
+	if r.Sample != nil {
+		return sc.sampleSearch(ctx, r)
+	}
+
+	_, span := spanreaderes.Tracer.Start(ctx, "elasticsearch.build_search_request")
 	req, err := buildSearchRequest(r)
+	tracing.EndSpan(span, err)
 	if err != nil {
-		return nil, fmt.Errorf("Could not build search request: %+v", err)
+		return nil, fmt.Errorf("%w: could not build search request: %v", spanreader.ErrInvalidQuery, err)
 	}
 
 	searchAPI := sc.client.API.Search()
 
 	res, err := searchAPI.Request(req).Index(sc.idx).Do(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("Could not search spans: %+v", err)
+		return nil, fmt.Errorf("Could not search spans: %w", err)
 	}
 
 	defer res.Body.Close()
@@ -66,10 +83,11 @@ func (sc *searchController) Search(ctx context.Context, r spansquery.SearchReque
 		switch err := err.(type) {
 		case *errors.ElasticSearchError:
 			if err.ErrorType == errors.IndexNotFoundError {
-				return &spansquery.SearchResponse{}, nil
+				return &spansquery.SearchResponse{Debug: debugInfo(r, req)}, nil
 			}
+			return nil, err
 		default:
-			return nil, fmt.Errorf("could not search spans: %+v", err)
+			return nil, fmt.Errorf("could not search spans: %w", err)
 		}
 	}
 
@@ -77,10 +95,20 @@ func (sc *searchController) Search(ctx context.Context, r spansquery.SearchReque
 	if err != nil {
 		return nil, fmt.Errorf("Could not parse response body to spans: %+v", err)
 	}
+	searchResp.Debug = debugInfo(r, req)
 
 	return searchResp, nil
 }
 
+// debugInfo returns the query DSL of req if r is a debug search, or nil otherwise.
+func debugInfo(r spansquery.SearchRequest, req *search.Request) *spansquery.DebugInfo {
+	if !r.Debug {
+		return nil
+	}
+	query, _ := json.Marshal(req)
+	return &spansquery.DebugInfo{Query: string(query)}
+}
+
 func buildSearchRequest(r spansquery.SearchRequest) (*search.Request, error) {
 	var err error
 
@@ -104,11 +132,98 @@ func buildSearchRequest(r spansquery.SearchRequest) (*search.Request, error) {
 		builder = builder.SearchAfter(sortResultsBuilder)
 	}
 
-	builder = builder.Size(50) // Where to get this number from?
+	size := defaultSearchSize
+	if r.Limit > 0 {
+		size = r.Limit
+	}
+	builder = builder.Size(size)
 
 	return builder.Build(), nil
 }
 
+// sampleSearch returns a random sample of the matching spans using elasticsearch random scoring,
+// along with the total number of matching spans.
+func (sc *searchController) sampleSearch(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	_, span := spanreaderes.Tracer.Start(ctx, "elasticsearch.build_sample_search_request")
+	body, err := buildSampleSearchBody(r)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not build sample search request: %v", spanreader.ErrInvalidQuery, err)
+	}
+
+	res, err := sc.rawClient.Search(
+		sc.rawClient.Search.WithContext(ctx),
+		sc.rawClient.Search.WithIndex(strings.Split(sc.idx, ",")...),
+		sc.rawClient.Search.WithBody(bytes.NewReader(body)),
+		sc.rawClient.Search.WithIgnoreUnavailable(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Could not sample spans: %w", err)
+	}
+
+	defer res.Body.Close()
+	if err := tagscontroller.SummarizeResponseError(res); err != nil {
+		return nil, err
+	}
+
+	var resBody map[string]any
+	decoder := json.NewDecoder(res.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&resBody); err != nil {
+		return nil, fmt.Errorf("failed to decode body: %v", err)
+	}
+
+	searchResp, err := parseSpansResponse(resBody, withMiliSecTimestampAsNanoSec())
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse response body to spans: %+v", err)
+	}
+	// sampled results are unordered, so they can't be paginated
+	searchResp.Metadata = nil
+	searchResp.Sample = &spansquery.SampleMetadata{EstimatedTotal: parseTotalHits(resBody)}
+	if r.Debug {
+		searchResp.Debug = &spansquery.DebugInfo{Query: string(body)}
+	}
+
+	return searchResp, nil
+}
+
+func buildSampleSearchBody(r spansquery.SearchRequest) ([]byte, error) {
+	req, err := buildSearchRequest(spansquery.SearchRequest{Timeframe: r.Timeframe, SearchFilters: r.SearchFilters})
+	if err != nil {
+		return nil, err
+	}
+
+	randomScore := map[string]any{}
+	if r.Sample.Seed != nil {
+		// a seeded random score must be based on a field, _seq_no is always available
+		randomScore["seed"] = *r.Sample.Seed
+		randomScore["field"] = "_seq_no"
+	}
+
+	return json.Marshal(map[string]any{
+		"size":             r.Sample.Size,
+		"track_total_hits": true,
+		"query": map[string]any{
+			"function_score": map[string]any{
+				"query":        req.Query,
+				"random_score": randomScore,
+				"boost_mode":   "replace",
+			},
+		},
+	})
+}
+
+func parseTotalHits(body map[string]any) uint64 {
+	hits, _ := body["hits"].(map[string]any)
+	total, _ := hits["total"].(map[string]any)
+	value, _ := total["value"].(json.Number)
+	count, err := value.Int64()
+	if err != nil || count < 0 {
+		return 0
+	}
+	return uint64(count)
+}
+
 func addSortField(fieldName spansquery.SortField, ascending bool, sorts []types.SortCombinations) []types.SortCombinations {
 	DIRECTION := map[bool]sortorder.SortOrder{true: sortorder.Asc, false: sortorder.Desc}
 
@@ -18,6 +18,7 @@ package searchcontroller
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 
@@ -105,6 +106,19 @@ func getSearchResponseMock() (map[string]any, error) {
 	return res, nil
 }
 
+func TestParseSpansResponseDeduplicatesCrossClusterHits(t *testing.T) {
+	res, err := getSearchResponseMock()
+	assert.Nil(t, err)
+
+	hits := res["hits"].(map[string]any)
+	hits["hits"] = append(hits["hits"].([]any), hits["hits"].([]any)[0])
+
+	spans, err := parseSpansResponse(res)
+
+	assert.Nil(t, err)
+	assert.Len(t, spans.Spans, 1)
+}
+
 func getSearchRequestMock(fs ...model.SearchFilter) (spansquery.SearchRequest, error) {
 	tf := model.Timeframe{
 		StartTime: uint64(time.Unix(0, 0).UnixNano()),
@@ -170,3 +184,150 @@ func TestBuildSearchRequest_WithNextToken(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, searchAfter[0], spanId)
 }
+
+func TestBuildSampleSearchBody(t *testing.T) {
+	searchReq, err := getSearchRequestMock()
+	assert.Nil(t, err)
+	seed := int64(42)
+	searchReq.Sample = &spansquery.Sample{Size: 100, Seed: &seed}
+
+	body, err := buildSampleSearchBody(searchReq)
+	assert.Nil(t, err)
+
+	var req map[string]any
+	assert.Nil(t, json.Unmarshal(body, &req))
+	assert.Equal(t, float64(100), req["size"])
+	assert.Equal(t, true, req["track_total_hits"])
+	assert.Nil(t, req["sort"])
+
+	functionScore := req["query"].(map[string]any)["function_score"].(map[string]any)
+	assert.NotNil(t, functionScore["query"])
+	assert.Equal(t, map[string]any{"seed": float64(42), "field": "_seq_no"}, functionScore["random_score"])
+}
+
+func TestBuildSearchTracesBody(t *testing.T) {
+	searchReq, err := getSearchRequestMock()
+	assert.Nil(t, err)
+
+	body, err := buildSearchTracesBody(spansquery.SearchTracesRequest{Timeframe: searchReq.Timeframe, SearchFilters: searchReq.SearchFilters}, 21)
+	assert.Nil(t, err)
+
+	var req map[string]any
+	assert.Nil(t, json.Unmarshal(body, &req))
+	assert.Equal(t, float64(0), req["size"])
+	assert.NotNil(t, req["query"])
+	terms := req["aggs"].(map[string]any)["traces"].(map[string]any)["terms"].(map[string]any)
+	assert.Equal(t, traceIdField, terms["field"])
+	assert.Equal(t, float64(21), terms["size"])
+}
+
+func TestParseTracesResponse(t *testing.T) {
+	var body map[string]any
+	decoder := json.NewDecoder(strings.NewReader(`{"aggregations": {"traces": {"buckets": [
+		{"key": "t1", "doc_count": 2, "latest": {"value": 1666585293167.0}},
+		{"key": "t2", "doc_count": 1, "latest": {"value": 1666585293000.0}}
+	]}}}`))
+	decoder.UseNumber()
+	assert.Nil(t, decoder.Decode(&body))
+
+	assert.Equal(t, []*spansquery.TraceResult{
+		{TraceId: "t1", LatestMatchTimeUnixNano: 1666585293167000000},
+		{TraceId: "t2", LatestMatchTimeUnixNano: 1666585293000000000},
+	}, parseTracesResponse(body))
+	assert.Empty(t, parseTracesResponse(map[string]any{}))
+}
+
+func TestBuildAggregateBody(t *testing.T) {
+	searchReq, err := getSearchRequestMock()
+	assert.Nil(t, err)
+	searchReq.Aggregations = &spansquery.Aggregations{
+		GroupBy: "span.name",
+		Limit:   5,
+		Metrics: []spansquery.Metric{
+			{Function: spansquery.AGGREGATION_COUNT},
+			{Function: spansquery.AGGREGATION_AVG, Field: "externalFields.durationNano"},
+			{Function: spansquery.AGGREGATION_P95, Field: "externalFields.durationNano"},
+		},
+	}
+
+	body, err := buildAggregateBody(searchReq, "span.name.keyword")
+	assert.Nil(t, err)
+
+	var req map[string]any
+	assert.Nil(t, json.Unmarshal(body, &req))
+	assert.Equal(t, float64(0), req["size"])
+	assert.NotNil(t, req["query"])
+	aggs := req["aggs"].(map[string]any)
+	groups := aggs["groups"].(map[string]any)
+	assert.Equal(t, map[string]any{"field": "span.name.keyword", "size": float64(5)}, groups["terms"])
+	assert.Equal(t, map[string]any{
+		"avg(externalFields.durationNano)": map[string]any{"avg": map[string]any{"field": "externalFields.durationNano"}},
+		"p95(externalFields.durationNano)": map[string]any{"percentiles": map[string]any{
+			"field": "externalFields.durationNano", "percents": []any{float64(95)},
+		}},
+	}, groups["aggs"])
+	assert.Equal(t, groups["aggs"], aggs["ungrouped"].(map[string]any)["aggs"])
+	assert.Nil(t, aggs["all"])
+
+	searchReq.Aggregations.GroupBy = ""
+	body, err = buildAggregateBody(searchReq, "")
+	assert.Nil(t, err)
+	assert.Nil(t, json.Unmarshal(body, &req))
+	aggs = req["aggs"].(map[string]any)
+	assert.NotNil(t, aggs["all"])
+	assert.Nil(t, aggs["groups"])
+}
+
+func TestParseAggregateResponse(t *testing.T) {
+	var body map[string]any
+	decoder := json.NewDecoder(strings.NewReader(`{"aggregations": {
+		"groups": {"buckets": [
+			{"key": "GET /cart", "doc_count": 2, "sum(span.attributes.retries)": {"value": 3.0},
+				"p99(span.startTimeUnixNano)": {"values": {"99.0": 1666585293167.0}}},
+			{"key": 1, "key_as_string": "true", "doc_count": 4, "sum(span.attributes.retries)": {"value": null},
+				"p99(span.startTimeUnixNano)": {"values": {"99.0": null}}}
+		]},
+		"ungrouped": {"doc_count": 3, "sum(span.attributes.retries)": {"value": 1.0},
+			"p99(span.startTimeUnixNano)": {"values": {"99.0": 1666585293000.0}}}
+	}}`))
+	decoder.UseNumber()
+	assert.Nil(t, decoder.Decode(&body))
+
+	aggregations := spansquery.Aggregations{GroupBy: "span.name", Limit: 2, Metrics: []spansquery.Metric{
+		{Function: spansquery.AGGREGATION_COUNT},
+		{Function: spansquery.AGGREGATION_SUM, Field: "span.attributes.retries"},
+		{Function: spansquery.AGGREGATION_P99, Field: "span.startTimeUnixNano"},
+	}}
+	assert.Equal(t, []spansquery.AggregationGroup{
+		{Value: true, Count: 4, Metrics: map[string]float64{"count": 4}},
+		{Value: nil, Count: 3, Metrics: map[string]float64{
+			"count": 3, "sum(span.attributes.retries)": 1, "p99(span.startTimeUnixNano)": 1666585293000000000,
+		}},
+	}, parseAggregateResponse(body, aggregations))
+	assert.Empty(t, parseAggregateResponse(map[string]any{}, aggregations))
+}
+
+func TestParseTotalHits(t *testing.T) {
+	var body map[string]any
+	decoder := json.NewDecoder(strings.NewReader(`{"hits": {"total": {"value": 123456, "relation": "eq"}, "hits": []}}`))
+	decoder.UseNumber()
+	assert.Nil(t, decoder.Decode(&body))
+
+	assert.Equal(t, uint64(123456), parseTotalHits(body))
+	assert.Equal(t, uint64(0), parseTotalHits(map[string]any{}))
+}
+
+func TestDebugInfo(t *testing.T) {
+	searchReq, err := getSearchRequestMock()
+	assert.Nil(t, err)
+	req, err := buildSearchRequest(searchReq)
+	assert.Nil(t, err)
+
+	assert.Nil(t, debugInfo(searchReq, req))
+
+	searchReq.Debug = true
+	info := debugInfo(searchReq, req)
+	assert.NotNil(t, info)
+	assert.Contains(t, info.Query, `"query"`)
+	assert.Contains(t, info.Query, `"sort"`)
+}
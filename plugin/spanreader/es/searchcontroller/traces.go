@@ -0,0 +1,116 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package searchcontroller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/tracing"
+	"github.com/teletrace/teletrace/plugin/spanreader/es/tagscontroller"
+	spanreaderes "github.com/teletrace/teletrace/plugin/spanreader/es/utils"
+
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+const traceIdField = "span.traceId.keyword"
+
+func (sc *searchController) SearchTraces(
+	ctx context.Context, r spansquery.SearchTracesRequest, offset int, limit int,
+) ([]*spansquery.TraceResult, error) {
+	_, span := spanreaderes.Tracer.Start(ctx, "elasticsearch.build_search_traces_request")
+	body, err := buildSearchTracesBody(r, offset+limit)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not build search traces request: %v", spanreader.ErrInvalidQuery, err)
+	}
+
+	res, err := sc.rawClient.Search(
+		sc.rawClient.Search.WithContext(ctx),
+		sc.rawClient.Search.WithIndex(strings.Split(sc.idx, ",")...),
+		sc.rawClient.Search.WithBody(bytes.NewReader(body)),
+		sc.rawClient.Search.WithIgnoreUnavailable(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Could not search traces: %w", err)
+	}
+
+	defer res.Body.Close()
+	if err := tagscontroller.SummarizeResponseError(res); err != nil {
+		return nil, err
+	}
+
+	var resBody map[string]any
+	decoder := json.NewDecoder(res.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&resBody); err != nil {
+		return nil, fmt.Errorf("failed to decode body: %v", err)
+	}
+
+	traces := parseTracesResponse(resBody)
+	if offset >= len(traces) {
+		return []*spansquery.TraceResult{}, nil
+	}
+	return traces[offset:], nil
+}
+
+// buildSearchTracesBody returns the body of the search of the first size traces having spans matching r, aggregated
+// by trace and ordered by their most recent matching span.
+func buildSearchTracesBody(r spansquery.SearchTracesRequest, size int) ([]byte, error) {
+	req, err := buildSearchRequest(spansquery.SearchRequest{Timeframe: r.Timeframe, SearchFilters: r.SearchFilters})
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]any{
+		"size":  0,
+		"query": req.Query,
+		"aggs": map[string]any{
+			"traces": map[string]any{
+				"terms": map[string]any{
+					"field": traceIdField,
+					"size":  size,
+					"order": map[string]any{"latest": "desc"},
+				},
+				"aggs": map[string]any{
+					"latest": map[string]any{"max": map[string]any{"field": "span.startTimeUnixNano"}},
+				},
+			},
+		},
+	})
+}
+
+func parseTracesResponse(body map[string]any) []*spansquery.TraceResult {
+	traces := []*spansquery.TraceResult{}
+	aggs, _ := body["aggregations"].(map[string]any)
+	agg, _ := aggs["traces"].(map[string]any)
+	buckets, _ := agg["buckets"].([]any)
+	for _, b := range buckets {
+		bucket, _ := b.(map[string]any)
+		traceId, _ := bucket["key"].(string)
+		latest, _ := bucket["latest"].(map[string]any)
+		value, _ := latest["value"].(json.Number)
+		// timestamps are stored in milliseconds
+		millis, _ := value.Float64()
+		traces = append(traces, &spansquery.TraceResult{TraceId: traceId, LatestMatchTimeUnixNano: uint64(millis) * 1e6})
+	}
+	return traces
+}
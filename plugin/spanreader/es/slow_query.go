@@ -0,0 +1,74 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package spanreaderes
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/slowquery"
+
+	"go.uber.org/zap"
+)
+
+// slowQueryTransport observes the requests made by span reader operations, logging the generated query of the slow ones.
+type slowQueryTransport struct {
+	next        http.RoundTripper
+	slowQueries *slowquery.Log
+}
+
+// newSlowQueryTransport wraps next with a slowQueryTransport, or returns next as is when the slow query log is disabled.
+func newSlowQueryTransport(logger *zap.Logger, threshold time.Duration, next http.RoundTripper) (http.RoundTripper, error) {
+	slowQueries, err := slowquery.NewLog(logger, threshold)
+	if err != nil || slowQueries == nil {
+		return next, err
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &slowQueryTransport{next: next, slowQueries: slowQueries}, nil
+}
+
+func (t *slowQueryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	operation, filters, ok := slowquery.OperationFromContext(req.Context())
+	if !ok {
+		// health checks, metadata and other requests made outside of span reader operations
+		return t.next.RoundTrip(req)
+	}
+
+	start := time.Now()
+	res, err := t.next.RoundTrip(req)
+	t.slowQueries.Observe(req.Context(), operation, req.Method+" "+req.URL.Path+" "+requestBody(req), filters, start)
+	return res, err
+}
+
+// requestBody returns a copy of the body of req, if the client kept one (it does for retrying requests).
+func requestBody(req *http.Request) string {
+	if req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return ""
+	}
+	return string(content)
+}
@@ -18,11 +18,16 @@ package spanreaderes
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 
+	"github.com/teletrace/teletrace/pkg/migrations"
 	"github.com/teletrace/teletrace/pkg/model"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
 	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
 	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/slowquery"
 	"github.com/teletrace/teletrace/pkg/spanreader"
 	"github.com/teletrace/teletrace/plugin/spanreader/es/metadatacontroller"
 	"github.com/teletrace/teletrace/plugin/spanreader/es/searchcontroller"
@@ -30,6 +35,7 @@ import (
 
 	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
 
+	"github.com/elastic/go-elasticsearch/v8"
 	"go.uber.org/zap"
 )
 
@@ -39,6 +45,7 @@ type spanReader struct {
 	cfg                ElasticConfig
 	logger             *zap.Logger
 	ctx                context.Context
+	rawClient          *elasticsearch.Client
 	searchController   searchcontroller.SearchController
 	tagsController     tagscontroller.TagsController
 	metadataController metadatacontroller.MetadataController
@@ -51,11 +58,87 @@ func (sr *spanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*
 	sr.convertFilterKeysToKeywords(r.SearchFilters)
 	sr.optimizeSort(r.Sort)
 
+	ctx, cancel := spanreader.WithQueryTimeout(ctx, sr.cfg.QueryTimeout)
+	defer cancel()
+	ctx = slowquery.WithOperation(ctx, "search", r.SearchFilters)
+	sort, err := sr.attributesSort(ctx, r.Sort)
+	if err != nil {
+		return nil, queryError(ctx, fmt.Errorf("Could not sort by attributes: %w", err))
+	}
+	r.Sort = sort
 	res, err := sr.searchController.Search(ctx, r)
 	if err != nil {
-		return nil, fmt.Errorf("Could not index document: %+v", err)
+		return nil, queryError(ctx, fmt.Errorf("Could not index document: %w", err))
+	}
+
+	return res, nil
+}
+
+// SearchTraces aggregates the matching spans by trace, and then searches the spans of the traces.
+func (sr *spanReader) SearchTraces(ctx context.Context, r spansquery.SearchTracesRequest) (*spansquery.SearchTracesResponse, error) {
+	offset, err := spanreader.TracesOffset(r.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	sr.convertFilterKeysToKeywords(r.SearchFilters)
+	limit := r.EffectiveLimit()
+
+	traces, err := sr.searchTraceIds(ctx, r, offset, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	res := &spansquery.SearchTracesResponse{Metadata: &spansquery.Metadata{}}
+	if len(traces) > limit {
+		traces = traces[:limit]
+		res.Metadata.NextToken = spanreader.TracesOffsetToken(offset + limit)
+	}
+	res.Traces = traces
+	if res.SpansTruncated, err = spanreader.AddTracesSpans(ctx, sr, traces); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (sr *spanReader) searchTraceIds(
+	ctx context.Context, r spansquery.SearchTracesRequest, offset int, limit int,
+) ([]*spansquery.TraceResult, error) {
+	ctx, cancel := spanreader.WithQueryTimeout(ctx, sr.cfg.QueryTimeout)
+	defer cancel()
+	ctx = slowquery.WithOperation(ctx, "search_traces", r.SearchFilters)
+	traces, err := sr.searchController.SearchTraces(ctx, r, offset, limit)
+	if err != nil {
+		return nil, queryError(ctx, fmt.Errorf("SearchTraces failed with error: %w", err))
 	}
+	return traces, nil
+}
 
+// Aggregate computes the aggregations with Elasticsearch aggregations, grouping by the 'keyword' field of string tags.
+// Bucketed aggregations are aggregated in memory, as the start times of the spans are indexed as numbers, which date
+// histograms can't bucket.
+func (sr *spanReader) Aggregate(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	if r.Aggregations.Interval != "" {
+		return spanreader.AggregateInSpans(ctx, sr, r)
+	}
+	sr.convertFilterKeysToKeywords(r.SearchFilters)
+	ctx, cancel := spanreader.WithQueryTimeout(ctx, sr.cfg.QueryTimeout)
+	defer cancel()
+	ctx = slowquery.WithOperation(ctx, "aggregate", r.SearchFilters)
+	groupByField := r.Aggregations.GroupBy
+	if groupByField != "" {
+		tagsMappings, err := sr.tagsController.GetTagsMappings(ctx, []string{groupByField})
+		if err != nil {
+			return nil, queryError(ctx, fmt.Errorf("Could not get the mapping of the group by tag: %w", err))
+		}
+		for _, mapping := range tagsMappings {
+			if mapping.Name == groupByField && mapping.Type == "Str" {
+				groupByField = fmt.Sprintf("%s.keyword", groupByField)
+			}
+		}
+	}
+	res, err := sr.searchController.Aggregate(ctx, r, groupByField)
+	if err != nil {
+		return nil, queryError(ctx, fmt.Errorf("Aggregate failed with error: %w", err))
+	}
 	return res, nil
 }
 
@@ -68,10 +151,50 @@ func (sr *spanReader) optimizeSort(s []spansquery.Sort) {
 	}
 }
 
+// attributesSort returns the sort with the string attributes sorted by their 'keyword' field, as text fields can't be
+// sorted by, and without the attributes not mapped in the index, which no span has.
+func (sr *spanReader) attributesSort(ctx context.Context, s []spansquery.Sort) ([]spansquery.Sort, error) {
+	var attributes []string
+	for _, sort := range s {
+		if sort.Field.IsAttribute() {
+			attributes = append(attributes, string(sort.Field))
+		}
+	}
+	if len(attributes) == 0 {
+		return s, nil
+	}
+	tagsMappings, err := sr.tagsController.GetTagsMappings(ctx, attributes)
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]string, len(tagsMappings))
+	for _, mapping := range tagsMappings {
+		types[mapping.Name] = mapping.Type
+	}
+
+	sorts := make([]spansquery.Sort, 0, len(s))
+	for _, sort := range s {
+		if sort.Field.IsAttribute() {
+			fieldType, ok := types[string(sort.Field)]
+			if !ok {
+				continue
+			}
+			if fieldType == "Str" {
+				sort.Field = spansquery.SortField(fmt.Sprintf("%s.keyword", sort.Field))
+			}
+		}
+		sorts = append(sorts, sort)
+	}
+	return sorts, nil
+}
+
 func (sr *spanReader) GetAvailableTags(ctx context.Context, r tagsquery.GetAvailableTagsRequest) (*tagsquery.GetAvailableTagsResponse, error) {
+	ctx, cancel := spanreader.WithQueryTimeout(ctx, sr.cfg.QueryTimeout)
+	defer cancel()
+	ctx = slowquery.WithOperation(ctx, "available_tags", nil)
 	res, err := sr.tagsController.GetAvailableTags(ctx, r)
 	if err != nil {
-		return nil, fmt.Errorf("GetAvailableTags failed with error: %+v", err)
+		return nil, queryError(ctx, fmt.Errorf("GetAvailableTags failed with error: %w", err))
 	}
 
 	return &res, nil
@@ -81,18 +204,48 @@ func (sr *spanReader) GetTagsValues(
 	ctx context.Context, r tagsquery.TagValuesRequest, tags []string,
 ) (map[string]*tagsquery.TagValuesResponse, error) {
 	sr.convertFilterKeysToKeywords(r.SearchFilters)
+	ctx, cancel := spanreader.WithQueryTimeout(ctx, sr.cfg.QueryTimeout)
+	defer cancel()
+	ctx = slowquery.WithOperation(ctx, "tag_values", r.SearchFilters)
 	res, err := sr.tagsController.GetTagsValues(ctx, r, tags)
 	if err != nil {
-		return nil, fmt.Errorf("GetTagsValues failed with error: %+v", err)
+		return nil, queryError(ctx, fmt.Errorf("GetTagsValues failed with error: %w", err))
 	}
 
 	return res, nil
 }
 
 func (sr *spanReader) GetTagsStatistics(ctx context.Context, r tagsquery.TagStatisticsRequest, tag string) (*tagsquery.TagStatisticsResponse, error) {
+	ctx, cancel := spanreader.WithQueryTimeout(ctx, sr.cfg.QueryTimeout)
+	defer cancel()
+	ctx = slowquery.WithOperation(ctx, "tag_statistics", r.SearchFilters)
 	res, err := sr.tagsController.GetTagsStatistics(ctx, r, tag)
 	if err != nil {
-		return nil, fmt.Errorf("GetTagsStatistics failed with error: %+v", err)
+		return nil, queryError(ctx, fmt.Errorf("GetTagsStatistics failed with error: %w", err))
+	}
+
+	return res, nil
+}
+
+func (sr *spanReader) GetTagsStatisticsBatch(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string,
+) (map[string]*tagsquery.TagStatisticsResponse, error) {
+	ctx, cancel := spanreader.WithQueryTimeout(ctx, sr.cfg.QueryTimeout)
+	defer cancel()
+	ctx = slowquery.WithOperation(ctx, "tag_statistics_batch", r.SearchFilters)
+	res, err := sr.tagsController.GetTagsStatisticsBatch(ctx, r, tags)
+	if err != nil {
+		return nil, queryError(ctx, fmt.Errorf("GetTagsStatisticsBatch failed with error: %w", err))
+	}
+
+	return res, nil
+}
+
+// SearchEvents searches the spans having the events, as events are indexed as part of their spans.
+func (sr *spanReader) SearchEvents(ctx context.Context, r eventsquery.SearchRequest) (*eventsquery.SearchResponse, error) {
+	res, err := spanreader.SearchEventsInSpans(ctx, sr, r)
+	if err != nil {
+		return nil, fmt.Errorf("SearchEvents failed with error: %w", err)
 	}
 
 	return res, nil
@@ -124,8 +277,16 @@ func (sr *spanReader) SetSystemId(ctx context.Context, r metadata.SetSystemIdReq
 	return res, nil
 }
 
+// Initialize applies the pending schema migrations of the span index.
 func (sr *spanReader) Initialize() error {
-	// This method may be implemented for other databases, not needed in ES.
+	store := migrationStore{client: sr.rawClient, migrated: sr.cfg.Index}
+	migrator, err := migrations.NewMigrator(sr.logger, "span index", store, sr.spanIndexMigrations(), migrations.NewDefaultConfig())
+	if err != nil {
+		return err
+	}
+	if err := migrator.Up(sr.ctx); err != nil {
+		return fmt.Errorf("failed to migrate span index: %w", err)
+	}
 	return nil
 }
 
@@ -142,12 +303,12 @@ func NewSpanReader(ctx context.Context, logger *zap.Logger, elasticSpansCfg Elas
 		return nil, fmt.Errorf(errMsg, err)
 	}
 
-	sc, err := searchcontroller.NewSearchController(logger, typedClient, elasticSpansCfg.Index)
+	sc, err := searchcontroller.NewSearchController(logger, rawClient, typedClient, elasticSpansCfg.SearchIndices())
 	if err != nil {
 		return nil, fmt.Errorf(errMsg, err)
 	}
 
-	tc, err := tagscontroller.NewTagsController(logger, rawClient, typedClient, elasticSpansCfg.Index)
+	tc, err := tagscontroller.NewTagsController(logger, rawClient, typedClient, elasticSpansCfg.SearchIndices())
 	if err != nil {
 		return nil, fmt.Errorf(errMsg, err)
 	}
@@ -165,8 +326,19 @@ func NewSpanReader(ctx context.Context, logger *zap.Logger, elasticSpansCfg Elas
 		cfg:                elasticSpansCfg,
 		logger:             logger,
 		ctx:                ctx,
+		rawClient:          rawClient,
 		searchController:   sc,
 		tagsController:     tc,
 		metadataController: mc,
 	}, nil
 }
+
+// queryError returns the error of a failed query wrapped by spanreader.ErrBackendUnavailable if the cluster couldn't be
+// reached, and by the query timeout or cancellation errors if the query context is done.
+func queryError(ctx context.Context, err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		err = fmt.Errorf("%w: %v", spanreader.ErrBackendUnavailable, err)
+	}
+	return spanreader.QueryContextError(ctx, err)
+}
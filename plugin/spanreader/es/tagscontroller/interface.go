@@ -29,6 +29,12 @@ type TagsController interface {
 	// Get the values and appearance count of all tags as specified by request.Tags
 	GetTagsValues(ctx context.Context, request tagsquery.TagValuesRequest, tags []string) (map[string]*tagsquery.TagValuesResponse, error)
 
+	// Get the types of the tags mapped in the index, omitting unmapped tags
+	GetTagsMappings(ctx context.Context, tags []string) ([]tagsquery.TagInfo, error)
+
 	// Get statistics for numeric tag values
 	GetTagsStatistics(ctx context.Context, req tagsquery.TagStatisticsRequest, tag string) (*tagsquery.TagStatisticsResponse, error)
+
+	// Get statistics for the numeric values of several tags in a single request
+	GetTagsStatisticsBatch(ctx context.Context, req tagsquery.TagStatisticsRequest, tags []string) (map[string]*tagsquery.TagStatisticsResponse, error)
 }
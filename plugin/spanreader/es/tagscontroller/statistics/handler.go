@@ -34,6 +34,22 @@ type StatisticHandler interface {
 	GetValue(aggs map[string]any) (float64, bool)
 }
 
+// AddCountAggregationContainerBuilder adds the aggregation of the number of values of the tag,
+// which the federated span reader weights the averages of its backends by.
+func AddCountAggregationContainerBuilder(tag string, aggs map[string]*types.AggregationContainerBuilder) {
+	aggs["count"] = types.NewAggregationContainerBuilder().ValueCount(types.NewValueCountAggregationBuilder().Field(types.Field(tag)))
+}
+
+// GetCount returns the number of values of the tag, 0 if the count aggregation is missing.
+func GetCount(aggs map[string]any) int64 {
+	count, ok := aggs["count"].(map[string]any)
+	if !ok {
+		return 0
+	}
+	value, _ := count["value"].(float64)
+	return int64(value)
+}
+
 type minHandler struct{}
 
 func (h *minHandler) AddAggregationContainerBuilder(tag string, aggs map[string]*types.AggregationContainerBuilder) {
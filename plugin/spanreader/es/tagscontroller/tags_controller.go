@@ -17,12 +17,15 @@
 package tagscontroller
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/tracing"
 	"github.com/teletrace/teletrace/plugin/spanreader/es/errors"
 	"github.com/teletrace/teletrace/plugin/spanreader/es/tagscontroller/statistics"
 
@@ -36,6 +39,9 @@ import (
 	"golang.org/x/exp/slices"
 )
 
+// defaultTagValuesSize is the number of values returned per tag for requests without a limit
+const defaultTagValuesSize = 100
+
 // Currently we use both raw and typed since fields mapping typed API has issue with querying the mapping of *
 type tagsController struct {
 	rawClient *elasticsearch.Client
@@ -84,10 +90,14 @@ func (r *tagsController) GetAvailableTags(
 				return tagsquery.GetAvailableTagsResponse{}, nil
 			}
 		default:
-			return result, fmt.Errorf("could not get available tags: %v", err)
+			return result, fmt.Errorf("could not get available tags: %w", err)
 		}
 	}
 
+	// the mapping APIs have no size, so the tags are limited here
+	if request.Limit > 0 && len(result.Tags) > request.Limit {
+		result.Tags = result.Tags[:request.Limit]
+	}
 	return result, nil
 }
 
@@ -103,8 +113,9 @@ func (r *tagsController) GetTagsValues(
 			if err.ErrorType == errors.IndexNotFoundError {
 				return nil, nil
 			}
+			return nil, err
 		default:
-			return nil, fmt.Errorf("could not get values for tags: %v", tags)
+			return nil, fmt.Errorf("could not get values for tags %v: %w", tags, err)
 		}
 	}
 
@@ -113,7 +124,15 @@ func (r *tagsController) GetTagsValues(
 		return map[string]*tagsquery.TagValuesResponse{}, err
 	}
 
-	return r.parseGetTagsValuesResponseBody(body)
+	result, err := r.parseGetTagsValuesResponseBody(body)
+	if err != nil {
+		return nil, err
+	}
+	// the values of the buckets are merged in maps, so they are sorted again
+	for _, values := range result {
+		tagsquery.SortValues(values.Values, request)
+	}
+	return result, nil
 }
 
 func (r *tagsController) GetTagsStatistics(
@@ -128,14 +147,16 @@ func (r *tagsController) performGetTagsStatisticsRequest(
 	tag string,
 	opts ...statistics.TagStatisticParseOption,
 ) (*tagsquery.TagStatisticsResponse, error) {
+	_, span := spanreaderes.Tracer.Start(ctx, "elasticsearch.build_tags_statistics_request")
 	req, err := buildTagsStatisticsRequest(request, tag)
+	tracing.EndSpan(span, err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build query: %s", err)
+		return nil, fmt.Errorf("%w: failed to build query: %v", spanreader.ErrInvalidQuery, err)
 	}
 
 	res, err := r.client.API.Search().Request(req).Index(r.idx).Do(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to perform search: %s", err)
+		return nil, fmt.Errorf("failed to perform search: %w", err)
 	}
 
 	var body map[string]any
@@ -146,6 +167,80 @@ func (r *tagsController) performGetTagsStatisticsRequest(
 	return r.parseTagStatisticsResponseBody(body, request, tag, opts)
 }
 
+// GetTagsStatisticsBatch searches the statistics of all the tags in a single multi search request,
+// one search per tag as in GetTagsStatistics.
+func (r *tagsController) GetTagsStatisticsBatch(
+	ctx context.Context, req tagsquery.TagStatisticsRequest, tags []string,
+) (map[string]*tagsquery.TagStatisticsResponse, error) {
+	_, span := spanreaderes.Tracer.Start(ctx, "elasticsearch.build_tags_statistics_batch_request")
+	reqBody, err := buildTagsStatisticsBatchBody(req, tags)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to build query: %v", spanreader.ErrInvalidQuery, err)
+	}
+
+	res, err := r.rawClient.Msearch(
+		bytes.NewReader(reqBody),
+		r.rawClient.Msearch.WithContext(ctx),
+		r.rawClient.Msearch.WithIndex(strings.Split(r.idx, ",")...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform multi search: %w", err)
+	}
+	defer res.Body.Close()
+	if err := SummarizeResponseError(res); err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Responses []map[string]any `json:"responses"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed parsing the response body: %s", err)
+	}
+	if len(body.Responses) != len(tags) {
+		return nil, fmt.Errorf("expected %d responses to the multi search, got %d", len(tags), len(body.Responses))
+	}
+
+	result := make(map[string]*tagsquery.TagStatisticsResponse, len(tags))
+	opts := []statistics.TagStatisticParseOption{statistics.WithMilliSecTimestampAsNanoSec()}
+	for i, tag := range tags {
+		tagRes := body.Responses[i]
+		if searchErr, ok := tagRes["error"]; ok {
+			return nil, fmt.Errorf("failed to search statistics of tag %s: %v", tag, searchErr)
+		}
+		if result[tag], err = r.parseTagStatisticsResponseBody(tagRes, req, tag, opts); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// buildTagsStatisticsBatchBody returns the newline delimited body of a multi search of the statistics of the tags,
+// each search being preceded by a header ignoring unavailable indices as the other tags searches do.
+func buildTagsStatisticsBatchBody(request tagsquery.TagStatisticsRequest, tags []string) ([]byte, error) {
+	header, err := json.Marshal(map[string]any{"ignore_unavailable": true})
+	if err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	for _, tag := range tags {
+		req, err := buildTagsStatisticsRequest(request, tag)
+		if err != nil {
+			return nil, err
+		}
+		line, err := json.Marshal(req)
+		if err != nil {
+			return nil, err
+		}
+		body.Write(header)
+		body.WriteByte('\n')
+		body.Write(line)
+		body.WriteByte('\n')
+	}
+	return body.Bytes(), nil
+}
+
 func (r *tagsController) parseTagStatisticsResponseBody(
 	body map[string]any, request tagsquery.TagStatisticsRequest, tag string, opts []statistics.TagStatisticParseOption,
 ) (*tagsquery.TagStatisticsResponse, error) {
@@ -160,6 +255,7 @@ func (r *tagsController) parseTagStatisticsResponseBody(
 				result.Statistics[ds] = v
 			}
 		}
+		result.Count = statistics.GetCount(aggregations)
 	}
 
 	for k := range result.Statistics {
@@ -182,6 +278,7 @@ func buildTagsStatisticsRequest(request tagsquery.TagStatisticsRequest, tag stri
 	builder.Size(0)
 
 	aggs := make(map[string]*types.AggregationContainerBuilder)
+	statistics.AddCountAggregationContainerBuilder(tag, aggs)
 
 	for _, d := range request.DesiredStatistics {
 		h := statistics.TagStatisticToHandler[d]
@@ -191,8 +288,22 @@ func buildTagsStatisticsRequest(request tagsquery.TagStatisticsRequest, tag stri
 	return builder.Aggregations(aggs).Build(), nil
 }
 
+// GetTagsMappings returns the types of the tags mapped in the index, and no tags if the index doesn't exist.
+func (r *tagsController) GetTagsMappings(ctx context.Context, tags []string) ([]tagsquery.TagInfo, error) {
+	tagsMappings, err := r.getTagsMappings(ctx, tags)
+	if err, ok := err.(*errors.ElasticSearchError); ok && err.ErrorType == errors.IndexNotFoundError {
+		return nil, nil
+	}
+	return tagsMappings, err
+}
+
 // Get elasticsearch mappings for specific tags
 func (r *tagsController) getTagsMappings(ctx context.Context, tags []string) ([]tagsquery.TagInfo, error) {
+	if isCrossClusterIndex(r.idx) {
+		// the field mapping api doesn't support remote clusters, field capabilities are used instead
+		return r.getTagsFieldCaps(ctx, tags)
+	}
+
 	var result []tagsquery.TagInfo
 	tagsMap := make(map[string]tagsquery.TagInfo)
 	res, err := r.rawClient.Indices.GetFieldMapping(
@@ -201,7 +312,7 @@ func (r *tagsController) getTagsMappings(ctx context.Context, tags []string) ([]
 		r.rawClient.Indices.GetFieldMapping.WithIndex(r.idx),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get field mapping: %v", err)
+		return nil, fmt.Errorf("failed to get field mapping: %w", err)
 	}
 
 	defer res.Body.Close()
@@ -257,7 +368,67 @@ func (r *tagsController) getTagsMappings(ctx context.Context, tags []string) ([]
 	return result, nil
 }
 
-func buildAggregations(builder *search.RequestBuilder, tagsMappings []tagsquery.TagInfo) {
+// Get elasticsearch field capabilities for specific tags, merged across all (local and remote) indices
+func (r *tagsController) getTagsFieldCaps(ctx context.Context, tags []string) ([]tagsquery.TagInfo, error) {
+	res, err := r.rawClient.FieldCaps(
+		r.rawClient.FieldCaps.WithContext(ctx),
+		r.rawClient.FieldCaps.WithIndex(strings.Split(r.idx, ",")...),
+		r.rawClient.FieldCaps.WithFields(tags...),
+		r.rawClient.FieldCaps.WithIgnoreUnavailable(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get field capabilities: %w", err)
+	}
+
+	defer res.Body.Close()
+	if err := SummarizeResponseError(res); err != nil {
+		return nil, err
+	}
+
+	var body map[string]any
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode body: %v", err)
+	}
+
+	return removeDuplicatedTextTags(parseFieldCapsResponseBody(body)), nil
+}
+
+// Field capabilities are grouped by field name and then by type,
+// a field may have several types when its mapping differs between indices.
+func parseFieldCapsResponseBody(body map[string]any) []tagsquery.TagInfo {
+	var result []tagsquery.TagInfo
+	fields, _ := body["fields"].(map[string]any)
+	for fieldName, v := range fields {
+		if strings.HasPrefix(fieldName, "_") {
+			// metadata fields (e.g. _id, _index) aren't tags
+			continue
+		}
+		fieldTypes, _ := v.(map[string]any)
+		var esTypes []string
+		for esType := range fieldTypes {
+			if _, ok := tagsValueTypeMap[esType]; ok {
+				esTypes = append(esTypes, esType)
+			}
+		}
+		if len(esTypes) == 0 {
+			continue
+		}
+		// prefer a deterministic type on mapping conflicts
+		slices.Sort(esTypes)
+		result = append(result, tagsquery.TagInfo{
+			Name: fieldName,
+			Type: tagsValueTypeMap[esTypes[0]].String(),
+		})
+	}
+	return result
+}
+
+// Cross-cluster index patterns are prefixed with the remote cluster name, e.g. "eu-west:teletrace-traces"
+func isCrossClusterIndex(idx string) bool {
+	return strings.Contains(idx, ":")
+}
+
+func buildAggregations(builder *search.RequestBuilder, tagsMappings []tagsquery.TagInfo, size int) {
 	aggs := make(map[string]*types.AggregationContainerBuilder, len(tagsMappings))
 	for _, mapping := range tagsMappings {
 		aggregationKey := mapping.Name
@@ -266,7 +437,7 @@ func buildAggregations(builder *search.RequestBuilder, tagsMappings []tagsquery.
 			aggregationField = fmt.Sprintf("%s.keyword", aggregationKey)
 		}
 		aggs[aggregationKey] = types.NewAggregationContainerBuilder()
-		aggs[aggregationKey].Terms(types.NewTermsAggregationBuilder().Field(types.Field(aggregationField)).Size(100))
+		aggs[aggregationKey].Terms(types.NewTermsAggregationBuilder().Field(types.Field(aggregationField)).Size(size))
 	}
 	builder.Aggregations(aggs)
 }
@@ -280,23 +451,80 @@ func buildTagsValuesRequest(request tagsquery.TagValuesRequest, tagsMappings []t
 		return nil, err
 	}
 	builder.Size(0)
-	buildAggregations(builder, tagsMappings)
+	size := defaultTagValuesSize
+	if request.Limit > 0 {
+		size = request.Limit
+	}
+	buildAggregations(builder, tagsMappings, size)
 	return builder.Build(), nil
 }
 
+// buildTagsValuesBody returns the body of the search of the values of the tags, the buckets of their terms
+// aggregations being ordered in the sort order of the request, which the typed API can't express.
+func buildTagsValuesBody(request tagsquery.TagValuesRequest, tagsMappings []tagsquery.TagInfo) ([]byte, error) {
+	req, err := buildTagsValuesRequest(request, tagsMappings)
+	if err != nil {
+		return nil, err
+	}
+	j, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var body map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(j))
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
+		return nil, err
+	}
+
+	sortField, ascending := request.Sort()
+	direction := map[bool]string{true: "asc", false: "desc"}[ascending]
+	aggregations, _ := body["aggregations"].(map[string]any)
+	for _, v := range aggregations {
+		aggregation, _ := v.(map[string]any)
+		terms, ok := aggregation["terms"].(map[string]any)
+		if !ok {
+			continue
+		}
+		switch sortField {
+		case tagsquery.SORT_BY_VALUE:
+			terms["order"] = map[string]any{"_key": direction}
+		case tagsquery.SORT_BY_LAST_SEEN:
+			terms["order"] = map[string]any{"lastSeen": direction}
+			aggregation["aggs"] = map[string]any{
+				"lastSeen": map[string]any{"max": map[string]any{"field": "span.startTimeUnixNano"}},
+			}
+		default:
+			terms["order"] = map[string]any{"_count": direction}
+		}
+	}
+	return json.Marshal(body)
+}
+
 // Perform search and return the response body
 func (r *tagsController) performGetTagsValuesRequest(
 	ctx context.Context,
 	request tagsquery.TagValuesRequest,
 	tagsMappings []tagsquery.TagInfo,
 ) (map[string]any, error) {
-	req, err := buildTagsValuesRequest(request, tagsMappings)
+	_, span := spanreaderes.Tracer.Start(ctx, "elasticsearch.build_tags_values_request")
+	reqBody, err := buildTagsValuesBody(request, tagsMappings)
+	tracing.EndSpan(span, err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build query: %s", err)
+		return nil, fmt.Errorf("%w: failed to build query: %v", spanreader.ErrInvalidQuery, err)
 	}
-	res, err := r.client.API.Search().Request(req).Index(r.idx).Do(ctx)
+	res, err := r.rawClient.Search(
+		r.rawClient.Search.WithContext(ctx),
+		r.rawClient.Search.WithIndex(strings.Split(r.idx, ",")...),
+		r.rawClient.Search.WithBody(bytes.NewReader(reqBody)),
+		r.rawClient.Search.WithIgnoreUnavailable(true),
+	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to perform search: %s", err)
+		return nil, fmt.Errorf("failed to perform search: %w", err)
+	}
+	defer res.Body.Close()
+	if err := SummarizeResponseError(res); err != nil {
+		return nil, err
 	}
 
 	var body map[string]any
@@ -329,14 +557,24 @@ func (r *tagsController) parseGetTagsValuesResponseBody(
 				bucket := v.(map[string]any)
 				value := bucket["key"]
 				count := int(bucket["doc_count"].(float64))
+				var lastSeen uint64
+				if agg, ok := bucket["lastSeen"].(map[string]any); ok {
+					// timestamps are stored in milliseconds
+					millis, _ := agg["value"].(float64)
+					lastSeen = uint64(millis) * 1e6
+				}
 
 				if info, found := tagValueInfos[tag][value]; !found {
 					tagValueInfos[tag][value] = tagsquery.TagValueInfo{
-						Value: value,
-						Count: count,
+						Value:            value,
+						Count:            count,
+						LastSeenUnixNano: lastSeen,
 					}
 				} else {
 					info.Count += count
+					if lastSeen > info.LastSeenUnixNano {
+						info.LastSeenUnixNano = lastSeen
+					}
 					tagValueInfos[tag][value] = info
 				}
 			}
@@ -348,8 +586,9 @@ func (r *tagsController) parseGetTagsValuesResponseBody(
 		var currentTagValues []tagsquery.TagValueInfo
 		for value, info := range valueInfoMap {
 			currentTagValues = append(currentTagValues, tagsquery.TagValueInfo{
-				Value: value,
-				Count: info.Count,
+				Value:            value,
+				Count:            info.Count,
+				LastSeenUnixNano: info.LastSeenUnixNano,
 			})
 		}
 		if currentTagValues != nil {
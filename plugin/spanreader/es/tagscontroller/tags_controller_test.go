@@ -201,6 +201,40 @@ func Test_RemoveDuplicatedTextTags_RemoveTextDuplicates(t *testing.T) {
 	assert.Contains(t, tagsNames, "span.attributes.http.method.not_keyword")
 }
 
+func Test_ParseFieldCapsResponseBody_MergesRemoteClusters(t *testing.T) {
+	responseContent := `
+	{
+		"indices": ["teletrace-traces", "eu-west:teletrace-traces"],
+		"fields": {
+			"_id": {
+				"_id": {"type": "_id", "searchable": true, "aggregatable": false}
+			},
+			"span.attributes.http.method": {
+				"text": {"type": "text", "searchable": true, "aggregatable": false}
+			},
+			"span.attributes.http.status_code": {
+				"long": {"type": "long", "searchable": true, "aggregatable": true, "indices": ["teletrace-traces"]},
+				"keyword": {"type": "keyword", "searchable": true, "aggregatable": true, "indices": ["eu-west:teletrace-traces"]}
+			},
+			"span.attributes": {
+				"object": {"type": "object", "searchable": false, "aggregatable": false}
+			}
+		}
+	}`
+	var body map[string]any
+	err := json.Unmarshal([]byte(responseContent), &body)
+	assert.NoError(t, err)
+
+	tags := parseFieldCapsResponseBody(body)
+
+	assert.ElementsMatch(t, []tagsquery.TagInfo{
+		{Name: "span.attributes.http.method", Type: pcommon.ValueTypeStr.String()},
+		{Name: "span.attributes.http.status_code", Type: pcommon.ValueTypeStr.String()},
+	}, tags)
+	assert.True(t, isCrossClusterIndex("teletrace-traces,eu-west:teletrace-traces"))
+	assert.False(t, isCrossClusterIndex("teletrace-traces"))
+}
+
 func Test_BuildTagsValuesRequest_sanity(t *testing.T) {
 	expectedJson := `{
  "aggregations": {
@@ -328,3 +362,74 @@ func Test_BuildTagsValuesRequest_BuildWithoutTimeframe(t *testing.T) {
 	assert.Nil(t, err)
 	assert.JSONEq(t, expectedJson, fmt.Sprintf("%+v", string(j)))
 }
+
+func Test_BuildTagsValuesRequest_BuildWithLimit(t *testing.T) {
+	tagsMapping := []tagsquery.TagInfo{
+		{
+			Name: "span.name",
+			Type: "Str",
+		},
+	}
+	request := tagsquery.TagValuesRequest{Limit: 10}
+	res, err := buildTagsValuesRequest(request, tagsMapping)
+	assert.Nil(t, err)
+	j, err := json.Marshal(res.Aggregations)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"span.name": {"terms": {"field": "span.name.keyword", "size": 10}}}`, string(j))
+}
+
+func Test_BuildTagsValuesBody_Sort(t *testing.T) {
+	tagsMapping := []tagsquery.TagInfo{{Name: "span.name", Type: "Str"}}
+	for _, test := range []struct {
+		request  tagsquery.TagValuesRequest
+		expected string
+	}{
+		{
+			request:  tagsquery.TagValuesRequest{Limit: 10},
+			expected: `{"span.name": {"terms": {"field": "span.name.keyword", "size": 10, "order": {"_count": "desc"}}}}`,
+		},
+		{
+			request:  tagsquery.TagValuesRequest{Limit: 10, SortBy: tagsquery.SORT_BY_VALUE},
+			expected: `{"span.name": {"terms": {"field": "span.name.keyword", "size": 10, "order": {"_key": "asc"}}}}`,
+		},
+		{
+			request: tagsquery.TagValuesRequest{Limit: 10, SortBy: tagsquery.SORT_BY_LAST_SEEN},
+			expected: `{"span.name": {
+				"terms": {"field": "span.name.keyword", "size": 10, "order": {"lastSeen": "desc"}},
+				"aggs": {"lastSeen": {"max": {"field": "span.startTimeUnixNano"}}}
+			}}`,
+		},
+	} {
+		body, err := buildTagsValuesBody(test.request, tagsMapping)
+		assert.Nil(t, err)
+		var req map[string]json.RawMessage
+		assert.Nil(t, json.Unmarshal(body, &req))
+		assert.JSONEq(t, test.expected, string(req["aggregations"]))
+	}
+}
+
+func Test_BuildTagsStatisticsBatchBody(t *testing.T) {
+	request := tagsquery.TagStatisticsRequest{DesiredStatistics: []tagsquery.TagStatistic{tagsquery.MIN}}
+	body, err := buildTagsStatisticsBatchBody(request, []string{"span.attributes.retries", "externalFields.durationNano"})
+	assert.Nil(t, err)
+
+	lines := bytes.Split(bytes.TrimSuffix(body, []byte("\n")), []byte("\n"))
+	assert.Len(t, lines, 4)
+	for i, field := range []string{"span.attributes.retries", "externalFields.durationNano"} {
+		assert.JSONEq(t, `{"ignore_unavailable": true}`, string(lines[2*i]))
+		var req map[string]json.RawMessage
+		assert.Nil(t, json.Unmarshal(lines[2*i+1], &req))
+		assert.JSONEq(t, fmt.Sprintf(`{"count": {"value_count": {"field": "%[1]s"}}, "min": {"min": {"field": "%[1]s"}}}`, field), string(req["aggregations"]))
+	}
+}
+
+func Test_ParseTagStatisticsResponseBody_Count(t *testing.T) {
+	body := map[string]any{"aggregations": map[string]any{
+		"count": map[string]any{"value": 4.0},
+		"avg":   map[string]any{"value": 2.5},
+	}}
+	request := tagsquery.TagStatisticsRequest{DesiredStatistics: []tagsquery.TagStatistic{tagsquery.AVG}}
+	res, err := (&tagsController{}).parseTagStatisticsResponseBody(body, request, "span.attributes.retries", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, &tagsquery.TagStatisticsResponse{Statistics: map[tagsquery.TagStatistic]float64{tagsquery.AVG: 2.5}, Count: 4}, res)
+}
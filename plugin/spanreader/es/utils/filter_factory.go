@@ -20,10 +20,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/teletrace/teletrace/pkg/model"
 	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
 
+	internalspanv1 "github.com/teletrace/teletrace/model/internalspan/v1"
+
 	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
 )
 
@@ -89,6 +92,10 @@ func BuildFilters(b *types.QueryContainerBuilder, fs []model.KeyValueFilter, opt
 			Builder: createRangeFilter,
 			Must:    true,
 		},
+		spansquery.OPERATOR_BINARY_PREFIX: {
+			Builder: createBinaryPrefixFilter,
+			Must:    true,
+		},
 	}
 
 	for _, f := range fs {
@@ -97,6 +104,7 @@ func BuildFilters(b *types.QueryContainerBuilder, fs []model.KeyValueFilter, opt
 		for _, opt := range opts {
 			opt(&f)
 		}
+		f.Value = spansquery.NormalizeFilterValue(f.Key, f.Value)
 
 		filter := m[string(f.Operator)]
 		qc, err := filter.Builder(f)
@@ -190,6 +198,40 @@ func createContainsFilter(f model.KeyValueFilter) (*types.QueryContainerBuilder,
 	return qc.Wildcard(m), nil
 }
 
+// Binary values are stored encoded, and the encoding of each binary attribute is kept under the binaryAttributes
+// next to its attributes (see internalspanv1.BinaryAttributes), so a binary prefix matches the attributes typed with
+// an encoding whose values start with the prefix of the bytes in that encoding.
+func createBinaryPrefixFilter(f model.KeyValueFilter) (*types.QueryContainerBuilder, error) {
+	prefix, err := spansquery.BinaryPrefix(f.Value)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse BINARY PREFIX filter value: %+v", err)
+	}
+	key := strings.TrimSuffix(string(f.Key), ".keyword")
+	i := strings.Index(key, "attributes.")
+	if i < 0 {
+		return nil, fmt.Errorf("BINARY PREFIX filter key is not an attribute: %s", f.Key)
+	}
+	encodingKey := fmt.Sprintf("%sbinaryAttributes.%s.keyword", key[:i], key[i+len("attributes."):])
+
+	qcSlice := []types.QueryContainer{}
+	for _, encoding := range []string{internalspanv1.BinaryEncodingBase64, internalspanv1.BinaryEncodingHex} {
+		valueQueries := []types.QueryContainer{}
+		for _, p := range internalspanv1.BinaryValuePrefixes(prefix, encoding) {
+			m := map[types.Field]*types.WildcardQueryBuilder{}
+			m[types.Field(key+".keyword")] = types.NewWildcardQueryBuilder().Value(p + "*")
+			valueQueries = append(valueQueries, types.NewQueryContainerBuilder().Wildcard(m).Build())
+		}
+		m := map[types.Field]*types.TermQueryBuilder{}
+		m[types.Field(encodingKey)] = types.NewTermQueryBuilder().Value(types.NewFieldValueBuilder().String(encoding))
+		qcSlice = append(qcSlice, types.NewQueryContainerBuilder().Bool(types.NewBoolQueryBuilder().Must([]types.QueryContainer{
+			types.NewQueryContainerBuilder().Term(m).Build(),
+			types.NewQueryContainerBuilder().Bool(types.NewBoolQueryBuilder().Should(valueQueries)).Build(),
+		})).Build())
+	}
+
+	return types.NewQueryContainerBuilder().Bool(types.NewBoolQueryBuilder().Should(qcSlice)), nil
+}
+
 func createExistsFilter(f model.KeyValueFilter) (*types.QueryContainerBuilder, error) {
 	qc := types.NewQueryContainerBuilder()
 
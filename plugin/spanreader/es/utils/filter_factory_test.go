@@ -155,3 +155,48 @@ func TestMultiMustNotFilters(t *testing.T) {
 	assert.Nil(t, err)
 	assert.JSONEq(t, expectedJson, string(queryJson))
 }
+
+func TestBinaryPrefixFilter(t *testing.T) {
+	expectedJson := `{
+	"bool": {
+		"must": [
+			{
+				"bool": {
+					"should": [
+						{
+							"bool": {
+								"must": [
+									{"term": {"span.binaryAttributes.payload.keyword": {"value": "base64"}}},
+									{"bool": {"should": [{"wildcard": {"span.attributes.payload.keyword": {"value": "yv66*"}}}]}}
+								]
+							}
+						},
+						{
+							"bool": {
+								"must": [
+									{"term": {"span.binaryAttributes.payload.keyword": {"value": "hex"}}},
+									{"bool": {"should": [{"wildcard": {"span.attributes.payload.keyword": {"value": "cafeba*"}}}]}}
+								]
+							}
+						}
+					]
+				}
+			}
+		]
+	}
+}`
+	query := types.NewQueryContainerBuilder()
+	kvFilters := []model.KeyValueFilter{
+		{
+			Key:      "span.attributes.payload.keyword",
+			Operator: "binary_prefix",
+			Value:    "yv66",
+		},
+	}
+
+	query, err := BuildFilters(query, kvFilters)
+	assert.Nil(t, err)
+	queryJson, err := json.Marshal(query.Build())
+	assert.Nil(t, err)
+	assert.JSONEq(t, expectedJson, string(queryJson))
+}
@@ -0,0 +1,43 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parquetspanreader
+
+import (
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/config"
+)
+
+type ParquetConfig struct {
+	// Directory is the directory of the hour partitions of span files written by the parquet exporter
+	Directory string
+	// MaxScannedSpans bounds the candidate spans read by each query, the most recent first
+	MaxScannedSpans int
+	// QueryTimeout interrupts queries running longer, 0 disables the timeout
+	QueryTimeout time.Duration
+	// SlowQueryThreshold logs and counts slower queries, 0 disables the slow query log
+	SlowQueryThreshold time.Duration
+}
+
+func NewParquetConfig(cfg config.Config) ParquetConfig {
+	return ParquetConfig{
+		Directory:          cfg.ParquetDirectory,
+		MaxScannedSpans:    cfg.ParquetMaxScannedSpans,
+		QueryTimeout:       time.Duration(cfg.StorageQueryTimeoutSeconds) * time.Second,
+		SlowQueryThreshold: time.Duration(cfg.StorageSlowQueryThresholdMilliseconds) * time.Millisecond,
+	}
+}
@@ -0,0 +1,51 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parquetspanreader
+
+import (
+	"encoding/json"
+	"strings"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+)
+
+// storedSpan is the JSON of a span in the span column of the span files, which keeps the events and links of the
+// span alongside it, as they aren't part of the span JSON.
+type storedSpan struct {
+	*internalspan.InternalSpan
+	Events []*internalspan.SpanEvent `json:"events"`
+	Links  []*internalspan.SpanLink  `json:"links"`
+}
+
+// decodeSpan decodes the span column of a span file. Numeric attribute values are decoded as json.Number,
+// keeping integers exact.
+func decodeSpan(data string) (*internalspan.InternalSpan, error) {
+	var stored storedSpan
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&stored); err != nil {
+		return nil, err
+	}
+	span := stored.InternalSpan
+	if span == nil {
+		span = &internalspan.InternalSpan{}
+	}
+	if span.Span != nil {
+		span.Span.Events, span.Span.Links = stored.Events, stored.Links
+	}
+	return span, nil
+}
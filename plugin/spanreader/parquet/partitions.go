@@ -0,0 +1,102 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parquetspanreader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The span files are written by the parquet exporter to hive partitions of the UTC hour the spans started in, e.g.
+// day=2023-01-02/hour=15, see teletrace-otelcol/exporter/parquetexporter/writer.go
+const (
+	dayPrefix  = "day="
+	hourPrefix = "hour="
+	dayLayout  = "2006-01-02"
+)
+
+// partition is an hour partition of the span files.
+type partition struct {
+	dir   string
+	start time.Time
+}
+
+// glob returns the pattern of the span files of the partition.
+func (p partition) glob() string {
+	return filepath.Join(p.dir, "*.parquet")
+}
+
+// listPartitions returns the partitions of dir holding span files which may have started within [start, end],
+// the oldest first. A missing directory has no partitions, as the exporter may not have written any spans yet.
+func listPartitions(dir string, start uint64, end uint64) ([]partition, error) {
+	days, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list span file partitions: %w", err)
+	}
+
+	var partitions []partition
+	for _, day := range days {
+		if !day.IsDir() || !strings.HasPrefix(day.Name(), dayPrefix) {
+			continue
+		}
+		dayStart, err := time.Parse(dayLayout, strings.TrimPrefix(day.Name(), dayPrefix))
+		if err != nil || !overlaps(dayStart, 24*time.Hour, start, end) {
+			continue
+		}
+		hours, err := os.ReadDir(filepath.Join(dir, day.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list span file partitions: %w", err)
+		}
+		for _, hour := range hours {
+			if !hour.IsDir() || !strings.HasPrefix(hour.Name(), hourPrefix) {
+				continue
+			}
+			h, err := strconv.Atoi(strings.TrimPrefix(hour.Name(), hourPrefix))
+			if err != nil {
+				continue
+			}
+			p := partition{dir: filepath.Join(dir, day.Name(), hour.Name()), start: dayStart.Add(time.Duration(h) * time.Hour)}
+			if !overlaps(p.start, time.Hour, start, end) {
+				continue
+			}
+			// reading a pattern matching no files fails, and partitions are empty while their first files are written
+			if files, _ := filepath.Glob(p.glob()); len(files) == 0 {
+				continue
+			}
+			partitions = append(partitions, p)
+		}
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].start.Before(partitions[j].start) })
+	return partitions, nil
+}
+
+// overlaps returns whether the period of width starting at periodStart overlaps [start, end].
+func overlaps(periodStart time.Time, width time.Duration, start uint64, end uint64) bool {
+	from := periodStart.UnixNano()
+	if from < 0 {
+		return false
+	}
+	return uint64(from) <= end && uint64(from+width.Nanoseconds()) > start
+}
@@ -0,0 +1,63 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parquetspanreader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListPartitions(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{
+		"day=2023-01-02/hour=23/a.parquet",
+		"day=2023-01-02/hour=3/a.parquet",
+		"day=2023-01-02/hour=4/a.parquet",
+		"day=2023-01-03/hour=0/a.parquet",
+		"day=2023-01-04/hour=0/a.parquet",
+		"day=2023-01-02/hour=5/a.tmp",
+		"staging/day=2023-01-02/hour=3/a.parquet",
+	} {
+		path := filepath.Join(dir, file)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, nil, 0o644))
+	}
+
+	hour := func(day int, hour int) time.Time { return time.Date(2023, 1, day, hour, 0, 0, 0, time.UTC) }
+	start := uint64(hour(2, 4).Add(30 * time.Minute).UnixNano())
+	end := uint64(hour(3, 0).UnixNano())
+
+	partitions, err := listPartitions(dir, start, end)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []partition{
+		{dir: filepath.Join(dir, "day=2023-01-02", "hour=4"), start: hour(2, 4)},
+		{dir: filepath.Join(dir, "day=2023-01-02", "hour=23"), start: hour(2, 23)},
+		{dir: filepath.Join(dir, "day=2023-01-03", "hour=0"), start: hour(3, 0)},
+	}, partitions)
+}
+
+func TestListPartitionsOfMissingDirectory(t *testing.T) {
+	partitions, err := listPartitions(filepath.Join(t.TempDir(), "missing"), 0, 10)
+
+	assert.NoError(t, err)
+	assert.Empty(t, partitions)
+}
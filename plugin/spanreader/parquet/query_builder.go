@@ -0,0 +1,220 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parquetspanreader
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader/inmemory"
+)
+
+// column is a column of the span files, which are written by the parquet exporter,
+// see teletrace-otelcol/exporter/parquetexporter/writer.go
+type column struct {
+	name string
+	// numeric columns hold unsigned integers, the others strings
+	numeric bool
+}
+
+// tagColumns maps the tags stored in their own columns of the span files to the columns.
+// The service name column only holds string service names, as required by the semantic conventions.
+var tagColumns = map[string]column{
+	"span.traceId":                          {name: "trace_id"},
+	"span.spanId":                           {name: "span_id"},
+	"span.parentSpanId":                     {name: "parent_span_id"},
+	"span.name":                             {name: "name"},
+	"span.kind":                             {name: "kind"},
+	"span.status.code":                      {name: "status_code"},
+	"resource.attributes.service.name":      {name: "service_name"},
+	"span.resource.attributes.service.name": {name: "service_name"},
+	"span.startTimeUnixNano":                {name: "start_time", numeric: true},
+	"span.endTimeUnixNano":                  {name: "end_time", numeric: true},
+	"externalFields.durationNano":           {name: "duration", numeric: true},
+	"span.duration":                         {name: "duration", numeric: true},
+	"ingestionTimeUnixNano":                 {name: "ingestion_time", numeric: true},
+}
+
+var rangeOperators = map[model.FilterOperator]string{
+	spansquery.OPERATOR_GT:  ">",
+	spansquery.OPERATOR_GTE: ">=",
+	spansquery.OPERATOR_LT:  "<",
+	spansquery.OPERATOR_LTE: "<=",
+}
+
+// tagColumn returns the column of a tag or a filter key, if it has one.
+func tagColumn(tag string) (column, bool) {
+	c, ok := tagColumns[strings.TrimSuffix(tag, ".keyword")]
+	return c, ok
+}
+
+// condition is a condition of the WHERE clause of a query of the span files.
+type condition struct {
+	sql  string
+	args []any
+}
+
+// pushDown returns the conditions of the filters which can be evaluated on the columns of the span files, matching the
+// spans the filters match in memory, and whether all of the filters were pushed down.
+func pushDown(filters []model.SearchFilter) ([]condition, bool) {
+	var conditions []condition
+	all := true
+	for _, f := range filters {
+		if f.KeyValueFilter == nil {
+			continue
+		}
+		c, ok := filterCondition(*f.KeyValueFilter)
+		if !ok {
+			all = false
+			continue
+		}
+		conditions = append(conditions, c)
+	}
+	return conditions, all
+}
+
+// filterCondition returns the condition of a filter on a column, comparing values as they're compared in memory.
+func filterCondition(f model.KeyValueFilter) (condition, bool) {
+	col, ok := tagColumn(string(f.Key))
+	if !ok {
+		return condition{}, false
+	}
+	value := spansquery.NormalizeFilterValue(f.Key, f.Value)
+	switch f.Operator {
+	case spansquery.OPERATOR_EQUALS, spansquery.OPERATOR_NOT_EQUALS:
+		arg, ok := col.arg(value)
+		if !ok {
+			return condition{}, false
+		}
+		if f.Operator == spansquery.OPERATOR_NOT_EQUALS {
+			// spans without the value don't equal it
+			return condition{sql: col.name + " IS DISTINCT FROM ?", args: []any{arg}}, true
+		}
+		return condition{sql: col.name + " = ?", args: []any{arg}}, true
+	case spansquery.OPERATOR_IN, spansquery.OPERATOR_NOT_IN:
+		values, _ := value.([]any)
+		if len(values) == 0 {
+			return condition{}, false
+		}
+		args := make([]any, 0, len(values))
+		for _, v := range values {
+			arg, ok := col.arg(v)
+			if !ok {
+				return condition{}, false
+			}
+			args = append(args, arg)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+		if f.Operator == spansquery.OPERATOR_NOT_IN {
+			return condition{sql: fmt.Sprintf("(%s IS NULL OR %s NOT IN (%s))", col.name, col.name, placeholders), args: args}, true
+		}
+		return condition{sql: fmt.Sprintf("%s IN (%s)", col.name, placeholders), args: args}, true
+	}
+	if operator, ok := rangeOperators[f.Operator]; ok && col.numeric {
+		if n, ok := inmemory.Number(value); ok {
+			return condition{sql: fmt.Sprintf("%s %s ?", col.name, operator), args: []any{n}}, true
+		}
+	}
+	return condition{}, false
+}
+
+// arg returns a filter value compared to the column: a number to a numeric column, or a string to the others.
+// Other values are compared by their string form in memory, so they aren't pushed down.
+func (c column) arg(value any) (any, bool) {
+	if c.numeric {
+		return inmemory.Number(value)
+	}
+	s, ok := value.(string)
+	return s, ok
+}
+
+// readSpanFiles returns the table function reading the span files of the partitions.
+func readSpanFiles(partitions []partition) string {
+	globs := make([]string, 0, len(partitions))
+	for _, p := range partitions {
+		globs = append(globs, quote(p.glob()))
+	}
+	return fmt.Sprintf("read_parquet([%s])", strings.Join(globs, ", "))
+}
+
+// quote returns s as a SQL string literal.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// where returns the WHERE clause matching the spans which started within [start, end] and match the conditions.
+func where(start uint64, end uint64, conditions []condition) (string, []any) {
+	clauses := []string{"start_time BETWEEN ? AND ?"}
+	args := []any{start, end}
+	for _, c := range conditions {
+		clauses = append(clauses, c.sql)
+		args = append(args, c.args...)
+	}
+	return " WHERE " + strings.Join(clauses, " AND "), args
+}
+
+// spansQuery returns the query of the spans of the partitions which started within [start, end] and match the
+// conditions, up to limit spans, the most recent first.
+func spansQuery(partitions []partition, start uint64, end uint64, conditions []condition, limit int) (string, []any) {
+	clause, args := where(start, end, conditions)
+	return fmt.Sprintf("SELECT span FROM %s%s ORDER BY start_time DESC LIMIT %d", readSpanFiles(partitions), clause, limit), args
+}
+
+// tagValuesQuery returns the query of the values of a column in the spans of the partitions which started within
+// [start, end] and match the conditions, with their number of spans, in the sort order of r and up to r.Limit values
+// unless it's 0.
+func tagValuesQuery(
+	col column, partitions []partition, start uint64, end uint64, conditions []condition, r tagsquery.TagValuesRequest,
+) (string, []any) {
+	notNull := condition{sql: col.name + " IS NOT NULL"}
+	clause, args := where(start, end, append(conditions[:len(conditions):len(conditions)], notNull))
+	sortField, ascending := r.Sort()
+	lastSeen := "NULL"
+	if sortField == tagsquery.SORT_BY_LAST_SEEN {
+		lastSeen = "max(start_time)"
+	}
+	order := "count"
+	switch sortField {
+	case tagsquery.SORT_BY_VALUE:
+		order = col.name
+	case tagsquery.SORT_BY_LAST_SEEN:
+		order = "last_seen"
+	}
+	direction := map[bool]string{true: "ASC", false: "DESC"}[ascending]
+	query := fmt.Sprintf("SELECT %[1]s, count(*) AS count, %[2]s AS last_seen FROM %[3]s%[4]s GROUP BY %[1]s ORDER BY %[5]s %[6]s, %[1]s",
+		col.name, lastSeen, readSpanFiles(partitions), clause, order, direction)
+	if r.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", r.Limit)
+	}
+	return query, args
+}
+
+// tagStatisticsQuery returns the query of the number, minimum, maximum, average and 99th percentile of numeric columns
+// in the spans of the partitions which started within [start, end] and match the conditions, five values per column.
+// The percentile is interpolated between the closest ranks, as in memory.
+func tagStatisticsQuery(cols []column, partitions []partition, start uint64, end uint64, conditions []condition) (string, []any) {
+	clause, args := where(start, end, conditions)
+	selects := make([]string, 0, len(cols))
+	for _, col := range cols {
+		selects = append(selects, fmt.Sprintf("count(%[1]s), min(%[1]s)::DOUBLE, max(%[1]s)::DOUBLE, avg(%[1]s)::DOUBLE, "+
+			"quantile_cont(%[1]s, 0.99)::DOUBLE", col.name))
+	}
+	return fmt.Sprintf("SELECT %s FROM %s%s", strings.Join(selects, ", "), readSpanFiles(partitions), clause), args
+}
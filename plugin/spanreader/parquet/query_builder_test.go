@@ -0,0 +1,105 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parquetspanreader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func filter(key string, operator string, value any) model.SearchFilter {
+	return model.SearchFilter{KeyValueFilter: &model.KeyValueFilter{
+		Key: model.FilterKey(key), Operator: model.FilterOperator(operator), Value: value,
+	}}
+}
+
+func TestPushDownFiltersOnColumns(t *testing.T) {
+	conditions, all := pushDown([]model.SearchFilter{
+		filter("span.kind", "in", []any{"SPAN_KIND_SERVER", 3.0}),
+		filter("resource.attributes.service.name.keyword", "equals", "cart"),
+		filter("span.status.code", "not_equals", "Error"),
+		filter("span.name", "not_in", []any{"GET /health"}),
+		filter("externalFields.durationNano", "gte", 1000.0),
+	})
+
+	assert.True(t, all)
+	assert.Equal(t, []condition{
+		{sql: "kind IN (?, ?)", args: []any{"Server", "Client"}},
+		{sql: "service_name = ?", args: []any{"cart"}},
+		{sql: "status_code IS DISTINCT FROM ?", args: []any{"Error"}},
+		{sql: "(name IS NULL OR name NOT IN (?))", args: []any{"GET /health"}},
+		{sql: "duration >= ?", args: []any{1000.0}},
+	}, conditions)
+}
+
+func TestPushDownSkipsFiltersComparedInMemory(t *testing.T) {
+	conditions, all := pushDown([]model.SearchFilter{
+		filter("span.attributes.http.url", "contains", "cart"),
+		filter("span.name", "contains", "cart"),
+		filter("span.name", "gt", 1.0),
+		filter("span.startTimeUnixNano", "equals", "10"),
+		filter("span.traceId", "in", []any{}),
+		filter("span.traceId", "equals", "a"),
+	})
+
+	assert.False(t, all)
+	assert.Equal(t, []condition{{sql: "trace_id = ?", args: []any{"a"}}}, conditions)
+}
+
+func TestSpansQuery(t *testing.T) {
+	partitions := []partition{
+		{dir: "spans/day=2023-01-02/hour=3"},
+		{dir: "o'neil/day=2023-01-02/hour=4"},
+	}
+	query, args := spansQuery(partitions, 10, 20, []condition{{sql: "kind = ?", args: []any{"Server"}}}, 100)
+
+	assert.Equal(t, "SELECT span FROM read_parquet(['spans/day=2023-01-02/hour=3/*.parquet', "+
+		"'o''neil/day=2023-01-02/hour=4/*.parquet']) WHERE start_time BETWEEN ? AND ? AND kind = ? "+
+		"ORDER BY start_time DESC LIMIT 100", query)
+	assert.Equal(t, []any{uint64(10), uint64(20), "Server"}, args)
+}
+
+func TestTagValuesQuery(t *testing.T) {
+	partitions := []partition{{dir: "spans/day=2023-01-02/hour=3", start: time.Unix(0, 0)}}
+	conditions := make([]condition, 1, 2)
+	conditions[0] = condition{sql: "kind = ?", args: []any{"Server"}}
+
+	query, args := tagValuesQuery(column{name: "name"}, partitions, 10, 20, conditions, tagsquery.TagValuesRequest{Limit: 5})
+
+	assert.Equal(t, "SELECT name, count(*) AS count, NULL AS last_seen FROM read_parquet(['spans/day=2023-01-02/hour=3/*.parquet']) "+
+		"WHERE start_time BETWEEN ? AND ? AND kind = ? AND name IS NOT NULL GROUP BY name ORDER BY count DESC, name LIMIT 5", query)
+	assert.Equal(t, []any{uint64(10), uint64(20), "Server"}, args)
+	assert.Len(t, conditions, 1)
+	assert.Empty(t, conditions[:2][1], "the conditions of the request must not be modified")
+}
+
+func TestTagStatisticsQuery(t *testing.T) {
+	partitions := []partition{{dir: "spans/day=2023-01-02/hour=3", start: time.Unix(0, 0)}}
+
+	query, args := tagStatisticsQuery([]column{{name: "duration"}, {name: "end_time"}}, partitions, 10, 20, nil)
+
+	assert.Equal(t, "SELECT count(duration), min(duration)::DOUBLE, max(duration)::DOUBLE, avg(duration)::DOUBLE, "+
+		"quantile_cont(duration, 0.99)::DOUBLE, count(end_time), min(end_time)::DOUBLE, max(end_time)::DOUBLE, "+
+		"avg(end_time)::DOUBLE, quantile_cont(end_time, 0.99)::DOUBLE FROM read_parquet(['spans/day=2023-01-02/hour=3/*.parquet']) "+
+		"WHERE start_time BETWEEN ? AND ?", query)
+	assert.Equal(t, []any{uint64(10), uint64(20)}, args)
+}
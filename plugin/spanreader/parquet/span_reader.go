@@ -0,0 +1,421 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parquetspanreader
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/pkg/model"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/slowquery"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/inmemory"
+	"github.com/teletrace/teletrace/pkg/tracing"
+
+	_ "github.com/marcboeker/go-duckdb"
+	"go.uber.org/zap"
+)
+
+// availableTagsPartitions is the number of latest partitions whose spans are read for their available tags
+const availableTagsPartitions = 24
+
+type spanReader struct {
+	cfg         ParquetConfig
+	logger      *zap.Logger
+	ctx         context.Context
+	db          *sql.DB
+	slowQueries *slowquery.Log
+}
+
+// candidates holds the candidate spans of a request, read by its query of the span files.
+type candidates struct {
+	spans []*internalspan.InternalSpan
+	query string
+	// partitions is the number of partitions read, and pushedDown the number of filters evaluated by the query
+	partitions int
+	pushedDown int
+	// truncated is set if the query had more than MaxScannedSpans candidates
+	truncated bool
+}
+
+func (sr *spanReader) Initialize() error {
+	return nil
+}
+
+// query runs a query of the span files, scanning each of its rows.
+func (sr *spanReader) query(
+	ctx context.Context, operation string, filters []model.SearchFilter, query string, args []any, scan func(*sql.Rows) error,
+) error {
+	ctx, cancel := spanreader.WithQueryTimeout(ctx, sr.cfg.QueryTimeout)
+	defer cancel()
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+	defer sr.slowQueries.Observe(ctx, operation, query, filters, time.Now())
+
+	rows, err := sr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return tracing.RecordError(span, spanreader.QueryContextError(ctx, err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return tracing.RecordError(span, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return tracing.RecordError(span, spanreader.QueryContextError(ctx, err))
+	}
+	return nil
+}
+
+// readSpans reads up to limit spans of the partitions which started within [start, end] and match the conditions,
+// the most recent first.
+func (sr *spanReader) readSpans(
+	ctx context.Context, operation string, filters []model.SearchFilter,
+	partitions []partition, start uint64, end uint64, conditions []condition, limit int,
+) ([]*internalspan.InternalSpan, string, error) {
+	if len(partitions) == 0 {
+		return nil, "", nil
+	}
+	query, args := spansQuery(partitions, start, end, conditions, limit)
+	var spans []*internalspan.InternalSpan
+	err := sr.query(ctx, operation, filters, query, args, func(rows *sql.Rows) error {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return fmt.Errorf("failed to scan span: %w", err)
+		}
+		span, err := decodeSpan(data)
+		if err != nil {
+			return fmt.Errorf("failed to decode span: %w", err)
+		}
+		spans = append(spans, span)
+		return nil
+	})
+	return spans, query, err
+}
+
+// readCandidates reads the candidate spans of the filters in the timeframe, evaluating the filters on the columns of
+// the span files where possible.
+func (sr *spanReader) readCandidates(
+	ctx context.Context, operation string, tf model.Timeframe, filters []model.SearchFilter,
+) (*candidates, error) {
+	start, end := timeRange(tf)
+	partitions, err := listPartitions(sr.cfg.Directory, start, end)
+	if err != nil {
+		return nil, err
+	}
+	conditions, _ := pushDown(filters)
+
+	result := &candidates{partitions: len(partitions), pushedDown: len(conditions)}
+	result.spans, result.query, err = sr.readSpans(ctx, operation, filters, partitions, start, end, conditions, sr.cfg.MaxScannedSpans+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.spans) > sr.cfg.MaxScannedSpans {
+		result.spans, result.truncated = result.spans[:sr.cfg.MaxScannedSpans], true
+		sr.logger.Debug("query has more candidate spans than read",
+			zap.String("operation", operation), zap.Int("maxScannedSpans", sr.cfg.MaxScannedSpans))
+	}
+	return result, nil
+}
+
+func (sr *spanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	c, err := sr.readCandidates(ctx, "search", r.Timeframe, r.SearchFilters)
+	if err != nil {
+		return nil, err
+	}
+	result, err := inmemory.Search(c.spans, r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", spanreader.ErrInvalidQuery, err)
+	}
+	if r.Debug {
+		queryPlan := []string{
+			fmt.Sprintf("read the span files of %d hour partitions", c.partitions),
+			fmt.Sprintf("evaluate %d filters on the span file columns", c.pushedDown),
+			fmt.Sprintf("filter %d candidate spans in memory", len(c.spans)),
+		}
+		if c.truncated {
+			queryPlan = append(queryPlan, fmt.Sprintf("stopped reading at %d candidate spans", sr.cfg.MaxScannedSpans))
+		}
+		result.Debug = &spansquery.DebugInfo{Query: c.query, QueryPlan: queryPlan}
+	}
+	return result, nil
+}
+
+// GetAvailableTags returns the tags of the spans of the latest partitions.
+func (sr *spanReader) GetAvailableTags(ctx context.Context, r tagsquery.GetAvailableTagsRequest) (*tagsquery.GetAvailableTagsResponse, error) {
+	partitions, err := listPartitions(sr.cfg.Directory, 0, math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+	if len(partitions) > availableTagsPartitions {
+		partitions = partitions[len(partitions)-availableTagsPartitions:]
+	}
+	spans, _, err := sr.readSpans(ctx, "available_tags", nil, partitions, 0, math.MaxInt64, nil, sr.cfg.MaxScannedSpans)
+	if err != nil {
+		return nil, err
+	}
+	return inmemory.AvailableTags(spans, r.Limit), nil
+}
+
+// GetTagsValues aggregates the values of the tags stored in their own columns in the query of the span files if all of
+// the filters are evaluated by it, and the values of any other tags in memory.
+func (sr *spanReader) GetTagsValues(ctx context.Context, r tagsquery.TagValuesRequest, tags []string) (map[string]*tagsquery.TagValuesResponse, error) {
+	tf := timeframe(r.Timeframe)
+	conditions, all := pushDown(r.SearchFilters)
+	result := make(map[string]*tagsquery.TagValuesResponse, len(tags))
+	var rest []string
+	for _, tag := range tags {
+		col, ok := tagColumn(tag)
+		if !ok || !all {
+			rest = append(rest, tag)
+			continue
+		}
+		values, err := sr.columnValues(ctx, col, tf, conditions, r)
+		if err != nil {
+			return nil, err
+		}
+		result[tag] = values
+	}
+	if len(rest) == 0 {
+		return result, nil
+	}
+
+	c, err := sr.readCandidates(ctx, "tag_values", tf, r.SearchFilters)
+	if err != nil {
+		return nil, err
+	}
+	for tag, values := range inmemory.TagsValues(c.spans, r, rest) {
+		result[tag] = values
+	}
+	return result, nil
+}
+
+// columnValues returns the values of a column in the spans matching the conditions, with their number of spans.
+func (sr *spanReader) columnValues(
+	ctx context.Context, col column, tf model.Timeframe, conditions []condition, r tagsquery.TagValuesRequest,
+) (*tagsquery.TagValuesResponse, error) {
+	start, end := timeRange(tf)
+	partitions, err := listPartitions(sr.cfg.Directory, start, end)
+	if err != nil || len(partitions) == 0 {
+		return &tagsquery.TagValuesResponse{}, err
+	}
+	query, args := tagValuesQuery(col, partitions, start, end, conditions, r)
+	res := &tagsquery.TagValuesResponse{}
+	err = sr.query(ctx, "tag_values", r.SearchFilters, query, args, func(rows *sql.Rows) error {
+		var value tagsquery.TagValueInfo
+		var lastSeen sql.NullInt64
+		if err := rows.Scan(&value.Value, &value.Count, &lastSeen); err != nil {
+			return fmt.Errorf("failed to scan tag value: %w", err)
+		}
+		value.LastSeenUnixNano = uint64(lastSeen.Int64)
+		res.Values = append(res.Values, value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetTagsStatistics aggregates the statistics of a tag stored in its own column in the query of the span files if all
+// of the filters are evaluated by it, or else in memory.
+func (sr *spanReader) GetTagsStatistics(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tag string,
+) (*tagsquery.TagStatisticsResponse, error) {
+	res, err := sr.GetTagsStatisticsBatch(ctx, r, []string{tag})
+	if err != nil {
+		return nil, err
+	}
+	return res[tag], nil
+}
+
+// GetTagsStatisticsBatch aggregates the statistics of all the tags stored in their own columns in a single query of the
+// span files if all of the filters are evaluated by it, and the statistics of any other tags in memory.
+func (sr *spanReader) GetTagsStatisticsBatch(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string,
+) (map[string]*tagsquery.TagStatisticsResponse, error) {
+	tf := timeframe(r.Timeframe)
+	conditions, all := pushDown(r.SearchFilters)
+	result := make(map[string]*tagsquery.TagStatisticsResponse, len(tags))
+	columnTags := make(map[string]string)
+	var cols []column
+	var rest []string
+	for _, tag := range tags {
+		col, ok := tagColumn(tag)
+		switch {
+		case !ok || !all:
+			rest = append(rest, tag)
+		case !col.numeric:
+			// as in memory, the statistics of a column without numeric values are omitted
+			result[tag] = &tagsquery.TagStatisticsResponse{Statistics: make(map[tagsquery.TagStatistic]float64)}
+		default:
+			columnTags[tag] = col.name
+			cols = append(cols, col)
+		}
+	}
+	if len(cols) > 0 {
+		statistics, err := sr.columnsStatistics(ctx, cols, tf, conditions, r)
+		if err != nil {
+			return nil, err
+		}
+		for tag, name := range columnTags {
+			result[tag] = statistics[name]
+		}
+	}
+	if len(rest) == 0 {
+		return result, nil
+	}
+
+	c, err := sr.readCandidates(ctx, "tag_statistics", tf, r.SearchFilters)
+	if err != nil {
+		return nil, err
+	}
+	for tag, statistics := range inmemory.TagsStatistics(c.spans, r, rest) {
+		result[tag] = statistics
+	}
+	return result, nil
+}
+
+// columnsStatistics returns the desired statistics of numeric columns in the spans matching the conditions by column name.
+// As in memory, the statistics of a column without values are omitted.
+func (sr *spanReader) columnsStatistics(
+	ctx context.Context, cols []column, tf model.Timeframe, conditions []condition, r tagsquery.TagStatisticsRequest,
+) (map[string]*tagsquery.TagStatisticsResponse, error) {
+	res := make(map[string]*tagsquery.TagStatisticsResponse, len(cols))
+	for _, col := range cols {
+		res[col.name] = &tagsquery.TagStatisticsResponse{Statistics: make(map[tagsquery.TagStatistic]float64)}
+	}
+	start, end := timeRange(tf)
+	partitions, err := listPartitions(sr.cfg.Directory, start, end)
+	if err != nil || len(partitions) == 0 {
+		return res, err
+	}
+
+	query, args := tagStatisticsQuery(cols, partitions, start, end, conditions)
+	counts := make([]int64, len(cols))
+	values := make([]sql.NullFloat64, 4*len(cols))
+	dest := make([]any, 0, 5*len(cols))
+	for i := range cols {
+		dest = append(dest, &counts[i], &values[4*i], &values[4*i+1], &values[4*i+2], &values[4*i+3])
+	}
+	err = sr.query(ctx, "tag_statistics", r.SearchFilters, query, args, func(rows *sql.Rows) error {
+		if err := rows.Scan(dest...); err != nil {
+			return fmt.Errorf("failed to scan tag statistics: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, col := range cols {
+		if counts[i] == 0 {
+			continue
+		}
+		res[col.name].Count = counts[i]
+		min, max, avg, p99 := values[4*i], values[4*i+1], values[4*i+2], values[4*i+3]
+		for _, s := range r.DesiredStatistics {
+			switch s {
+			case tagsquery.MIN:
+				res[col.name].Statistics[s] = min.Float64
+			case tagsquery.MAX:
+				res[col.name].Statistics[s] = max.Float64
+			case tagsquery.AVG:
+				res[col.name].Statistics[s] = avg.Float64
+			case tagsquery.P99:
+				res[col.name].Statistics[s] = p99.Float64
+			}
+		}
+	}
+	return res, nil
+}
+
+func (sr *spanReader) SearchEvents(ctx context.Context, r eventsquery.SearchRequest) (*eventsquery.SearchResponse, error) {
+	return spanreader.SearchEventsInSpans(ctx, sr, r)
+}
+
+// SearchTraces groups the most recent matching spans by trace in memory.
+func (sr *spanReader) SearchTraces(ctx context.Context, r spansquery.SearchTracesRequest) (*spansquery.SearchTracesResponse, error) {
+	return spanreader.SearchTracesInSpans(ctx, sr, r)
+}
+
+// Aggregate aggregates the matching spans in memory.
+func (sr *spanReader) Aggregate(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	return spanreader.AggregateInSpans(ctx, sr, r)
+}
+
+func (sr *spanReader) GetSystemId(ctx context.Context, r metadata.GetSystemIdRequest) (*metadata.GetSystemIdResponse, error) {
+	return nil, fmt.Errorf("Not implemented method")
+}
+
+func (sr *spanReader) SetSystemId(ctx context.Context, r metadata.SetSystemIdRequest) (*metadata.SetSystemIdResponse, error) {
+	return nil, fmt.Errorf("Not implemented method")
+}
+
+// Ping checks the DuckDB database querying the span files is open.
+func (sr *spanReader) Ping(ctx context.Context) error {
+	if err := sr.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping duckdb: %w", err)
+	}
+	return nil
+}
+
+// timeRange returns the start and end times of tf, whose end time is now when 0.
+func timeRange(tf model.Timeframe) (uint64, uint64) {
+	if tf.EndTime == 0 {
+		return tf.StartTime, uint64(time.Now().UnixNano())
+	}
+	return tf.StartTime, tf.EndTime
+}
+
+func timeframe(tf *model.Timeframe) model.Timeframe {
+	if tf == nil {
+		return model.Timeframe{}
+	}
+	return *tf
+}
+
+// NewParquetSpanReader returns a span reader querying the span files written by the parquet exporter through an
+// in-memory DuckDB database. The files are only read, so they can be copied elsewhere and queried there as well.
+func NewParquetSpanReader(ctx context.Context, logger *zap.Logger, cfg ParquetConfig) (spanreader.SpanReader, error) {
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		return nil, fmt.Errorf("cannot create a new span reader for parquet: %w", err)
+	}
+
+	slowQueries, err := slowquery.NewLog(logger, cfg.SlowQueryThreshold)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("cannot create a new span reader for parquet: %w", err)
+	}
+
+	return &spanReader{
+		cfg:         cfg,
+		logger:      logger,
+		ctx:         ctx,
+		db:          db,
+		slowQueries: slowQueries,
+	}, nil
+}
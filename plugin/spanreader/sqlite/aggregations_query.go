@@ -0,0 +1,194 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlitespanreader
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// aggregationAttributes are the queries of the span_id, value and type of the attributes with the key of their
+// argument of the spans or of their resources, by the prefix of the tags of the attributes
+var aggregationAttributes = map[string]string{
+	"span.attributes.": "SELECT span_id, value, type FROM span_attributes WHERE key = '%s'",
+	"resource.attributes.": "SELECT sra.span_id, ra.value, ra.type FROM span_resource_attributes AS sra " +
+		"JOIN resource_attributes AS ra ON ra.resource_id = sra.resource_attribute_id WHERE ra.key = '%s'",
+}
+
+// aggregationAttribute returns the query of the span_id, value and type of the attribute tag, and false if tag isn't
+// an attribute of the spans or their resources.
+func aggregationAttribute(tag string) (string, bool) {
+	for prefix, query := range aggregationAttributes {
+		if key := strings.TrimPrefix(tag, prefix); key != tag {
+			return fmt.Sprintf(query, strings.ReplaceAll(key, "'", "''")), true
+		}
+	}
+	return "", false
+}
+
+// aggregationColumn returns the column of the spans table of tag, and false if it isn't stored in the spans table.
+func aggregationColumn(tag string) (string, bool) {
+	column, ok := sqliteFieldsMap[tag]
+	return column, ok && strings.HasPrefix(column, "spans.")
+}
+
+// aggregationMetricValue returns the expression of the numeric value of tag of the span joined as spans, NULL for
+// other values, and false if the tag can't be aggregated in the query.
+func aggregationMetricValue(tag string) (string, bool) {
+	value, ok := aggregationColumn(tag)
+	if !ok {
+		query, ok := aggregationAttribute(tag)
+		if !ok {
+			return "", false
+		}
+		// array attributes and booleans, stored as integers, aren't numbers
+		value = fmt.Sprintf("(SELECT a.value FROM (%s) AS a WHERE a.span_id = spans.span_id AND a.type IN ('%s', '%s'))",
+			query, NumberType, DoubleType)
+	}
+	return fmt.Sprintf("(CASE WHEN typeof(%[1]s) IN ('integer', 'real') THEN %[1]s END)", value), true
+}
+
+// aggregationGroupedQuery returns the query of the matching spans of r with their values of the group by tag, as
+// span_id, group_value and group_type, the type being 'Bool' for booleans and NULL otherwise. A span having several
+// values, i.e. an array attribute, is selected once per distinct value, and a span without the tag with a NULL value.
+// Returns false if the group by tag can't be aggregated in the query.
+func aggregationGroupedQuery(r spansquery.SearchRequest) (string, bool, error) {
+	filters := createTimeframeFilters(r.Timeframe)
+	filters = append(filters, convertFiltersValues(r.SearchFilters)...)
+	subQueryBuilder := newSubQueryBuilder("spans")
+	if err := subQueryBuilder.addFiltersToSubQuery(filters); err != nil {
+		return "", false, fmt.Errorf("failed to add filters: %v", err)
+	}
+	subQuery, err := subQueryBuilder.buildSubQuery()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to build sub query: %v", err)
+	}
+
+	groupValue, groupType, groupJoin := "NULL", "NULL", ""
+	if groupBy := r.Aggregations.GroupBy; groupBy != "" {
+		if column, ok := aggregationColumn(groupBy); ok {
+			groupValue = column
+		} else if query, ok := aggregationAttribute(groupBy); ok {
+			// the elements of array attributes are grouped separately, as in the tag values
+			groupJoin = fmt.Sprintf(" LEFT JOIN (%s) AS g ON g.span_id = spans.span_id "+
+				"LEFT JOIN json_each(CASE WHEN g.type = '%s' THEN g.value ELSE '[]' END) AS ge", query, SliceType)
+			groupValue = fmt.Sprintf("CASE WHEN g.type = '%s' THEN ge.value ELSE g.value END", SliceType)
+			groupType = fmt.Sprintf("CASE WHEN g.type = '%[1]s' OR (g.type = '%[2]s' AND ge.type IN ('true', 'false')) THEN '%[1]s' END", BoolType, SliceType)
+		} else {
+			return "", false, nil
+		}
+	}
+
+	return fmt.Sprintf("SELECT DISTINCT spans.span_id AS span_id, %s AS group_value, %s AS group_type "+
+		"FROM (SELECT DISTINCT span_id FROM (%s)) AS matching JOIN spans ON spans.span_id = matching.span_id%s",
+		groupValue, groupType, subQuery, groupJoin), true, nil
+}
+
+// buildAggregationsQuery returns the query of the groups of r, the largest first up to the limit of r, as the group
+// value and type, the number of spans, and the value of each of the metrics which aren't percentiles, in the order
+// of r. Returns false if the tags of r can't be aggregated in the query.
+func buildAggregationsQuery(r spansquery.SearchRequest) (string, bool, error) {
+	grouped, ok, err := aggregationGroupedQuery(r)
+	if err != nil || !ok {
+		return "", ok, err
+	}
+	columns := []string{"grouped.group_value", "grouped.group_type", "COUNT(*)"}
+	for _, m := range r.Aggregations.Metrics {
+		switch m.Function {
+		case spansquery.AGGREGATION_COUNT:
+			continue
+		case spansquery.AGGREGATION_SUM, spansquery.AGGREGATION_AVG, spansquery.AGGREGATION_MIN, spansquery.AGGREGATION_MAX:
+		default:
+			// percentiles are queried by buildAggregationPercentilesQuery
+			if _, ok := aggregationMetricValue(m.Field); !ok {
+				return "", false, nil
+			}
+			continue
+		}
+		value, ok := aggregationMetricValue(m.Field)
+		if !ok {
+			return "", false, nil
+		}
+		columns = append(columns, fmt.Sprintf("%s(%s)", strings.ToUpper(string(m.Function)), value))
+	}
+	return fmt.Sprintf("WITH grouped AS (%s) SELECT %s FROM grouped JOIN spans ON spans.span_id = grouped.span_id "+
+		"GROUP BY grouped.group_value, grouped.group_type ORDER BY COUNT(*) DESC, grouped.group_value LIMIT %d",
+		grouped, strings.Join(columns, ", "), r.Aggregations.EffectiveLimit()), true, nil
+}
+
+// buildAggregationPercentilesQuery returns the query of the values of field in each group of r which the percentiles
+// are interpolated between, as the group value and type, the rank of the value in the group from 0, the number of
+// values of the group and the value.
+func buildAggregationPercentilesQuery(r spansquery.SearchRequest, field string, percentiles []float64) (string, error) {
+	grouped, _, err := aggregationGroupedQuery(r)
+	if err != nil {
+		return "", err
+	}
+	value, _ := aggregationMetricValue(field)
+	var ranks []string
+	for _, p := range percentiles {
+		lower := fmt.Sprintf("CAST(%s * (n - 1) AS INTEGER)", strconv.FormatFloat(p, 'f', -1, 64))
+		ranks = append(ranks, lower, lower+" + 1")
+	}
+	return fmt.Sprintf("WITH grouped AS (%s), "+
+		"field_values AS (SELECT grouped.group_value, grouped.group_type, %s AS value FROM grouped JOIN spans ON spans.span_id = grouped.span_id), "+
+		"ranked AS (SELECT group_value, group_type, value, "+
+		"ROW_NUMBER() OVER (PARTITION BY group_value, group_type ORDER BY value) - 1 AS rank, "+
+		"COUNT(*) OVER (PARTITION BY group_value, group_type) AS n FROM field_values WHERE value IS NOT NULL) "+
+		"SELECT group_value, group_type, rank, n, value FROM ranked WHERE rank IN (%s)",
+		grouped, value, strings.Join(ranks, ", ")), nil
+}
+
+// aggregationGroupKey returns the key of the group of a value and type of the group by tag, numbers being grouped by
+// their value whatever their type.
+func aggregationGroupKey(value any, valueType any) string {
+	switch v := value.(type) {
+	case int64:
+		return fmt.Sprintf("n:%s:%v", strconv.FormatFloat(float64(v), 'g', -1, 64), valueType)
+	case float64:
+		return fmt.Sprintf("n:%s:%v", strconv.FormatFloat(v, 'g', -1, 64), valueType)
+	case []byte:
+		return "s:" + string(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("s:%v", v)
+	}
+}
+
+// aggregationGroupValue returns the value of the group by tag of a group, by its type.
+func aggregationGroupValue(value any, valueType any) any {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	if valueType == BoolType {
+		return decodeTagValue(value, BoolType)
+	}
+	return value
+}
+
+// interpolatePercentile returns the p percentile of the values of a group by their rank, interpolating between the
+// closest ranks as the in memory aggregations do.
+func interpolatePercentile(values map[int64]float64, n int64, p float64) float64 {
+	rank := p * float64(n-1)
+	lower, upper := math.Floor(rank), math.Ceil(rank)
+	return values[int64(lower)] + (values[int64(upper)]-values[int64(lower)])*(rank-lower)
+}
@@ -0,0 +1,197 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package sqlitespanreader
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Backup copies the database at dbPath to a new database at backupPath with the SQLite online backup API.
+// The database is copied in a single step holding a read transaction, so the backup is a consistent snapshot,
+// while the writes of the exporter continue as the database is in WAL mode.
+func Backup(ctx context.Context, dbPath string, backupPath string) error {
+	if _, err := os.Stat(dbPath); err != nil {
+		return fmt.Errorf("could not find database: %w", err)
+	}
+	if _, err := os.Stat(backupPath); err == nil {
+		return fmt.Errorf("backup %s already exists", backupPath)
+	}
+	if err := copyDatabase(ctx, dbPath, backupPath); err != nil {
+		os.Remove(backupPath)
+		return fmt.Errorf("could not back up %s: %w", dbPath, err)
+	}
+	return nil
+}
+
+// Restore replaces the content of the database at dbPath with the backup at backupPath, after checking the integrity of
+// the backup. The database may be in use: its connections see the restored content once the restore completes, and
+// the writes committed during the restore are lost.
+func Restore(ctx context.Context, backupPath string, dbPath string) error {
+	if err := checkIntegrity(ctx, backupPath, true); err != nil {
+		return fmt.Errorf("could not restore %s: %w", backupPath, err)
+	}
+	if err := copyDatabase(ctx, backupPath, dbPath); err != nil {
+		return fmt.Errorf("could not restore %s: %w", backupPath, err)
+	}
+	return nil
+}
+
+// BackupShards backs up the database at the path of cfg and, when sharded, each of its shards to the files next to
+// backupPath named as the shards, e.g. backup.2023-01-02.db for embedded_spans.2023-01-02.db. The shards are backed up
+// one after the other, so the backup of a shard doesn't include the spans written to it after the previous ones.
+func BackupShards(ctx context.Context, cfg SqliteConfig, backupPath string) error {
+	if cfg.ShardBy == "" {
+		return Backup(ctx, cfg.Path, backupPath)
+	}
+	shards, err := listShards(cfg)
+	if err != nil {
+		return err
+	}
+	// the database at the path holds the metadata, such as saved queries, when it's stored with the spans
+	if _, err := os.Stat(cfg.Path); err == nil {
+		if err := Backup(ctx, cfg.Path, backupPath); err != nil {
+			return err
+		}
+	} else if len(shards) == 0 {
+		return fmt.Errorf("could not find database or shards of %s", cfg.Path)
+	}
+	for _, s := range shards {
+		if err := Backup(ctx, s.path, shardFile(backupPath, s.name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestoreShards restores the database at the path of cfg and, when sharded, each of its shards from the backups taken
+// by BackupShards. All the backups are checked before any of them is restored. Shards missing from the backup, such as
+// the days following it, are left as is.
+func RestoreShards(ctx context.Context, cfg SqliteConfig, backupPath string) error {
+	if cfg.ShardBy == "" {
+		return Restore(ctx, backupPath, cfg.Path)
+	}
+	backupCfg := cfg
+	backupCfg.Path = backupPath
+	shards, err := listShards(backupCfg)
+	if err != nil {
+		return err
+	}
+	restores := make(map[string]string, len(shards)+1)
+	if _, err := os.Stat(backupPath); err == nil {
+		restores[backupPath] = cfg.Path
+	}
+	for _, s := range shards {
+		restores[s.path] = shardFile(cfg.Path, s.name)
+	}
+	if len(restores) == 0 {
+		return fmt.Errorf("could not find backup or shard backups of %s", backupPath)
+	}
+	for backup := range restores {
+		// the database at the path of sharded spans holds only the metadata
+		if err := checkIntegrity(ctx, backup, backup != backupPath); err != nil {
+			return fmt.Errorf("could not restore %s: %w", backup, err)
+		}
+	}
+	for backup, dbPath := range restores {
+		if err := copyDatabase(ctx, backup, dbPath); err != nil {
+			return fmt.Errorf("could not restore %s: %w", backup, err)
+		}
+	}
+	return nil
+}
+
+// shardFile returns the path of the shard named name of the database at path, as written by the exporter.
+func shardFile(path string, name string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + name + ext
+}
+
+func copyDatabase(ctx context.Context, srcPath string, destPath string) error {
+	srcDb, err := sql.Open("sqlite3", "file:"+srcPath+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer srcDb.Close()
+	destDb, err := sql.Open("sqlite3", destPath)
+	if err != nil {
+		return err
+	}
+	defer destDb.Close()
+
+	srcConn, err := srcDb.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+	destConn, err := destDb.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+
+	return destConn.Raw(func(destDriverConn any) error {
+		return srcConn.Raw(func(srcDriverConn any) error {
+			backup, err := destDriverConn.(*sqlite3.SQLiteConn).Backup("main", srcDriverConn.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+			// a single step copies all the pages at once, as a backup copied in steps restarts on every write to the source
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+}
+
+// checkIntegrity checks that the database at path is a valid SQLite database, holding spans if spans is set.
+func checkIntegrity(ctx context.Context, path string, spans bool) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("could not find backup: %w", err)
+	}
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRowContext(ctx, "PRAGMA quick_check").Scan(&result); err != nil {
+		return fmt.Errorf("could not check backup integrity: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("backup is corrupted: %s", result)
+	}
+	if !spans {
+		return nil
+	}
+	var tables int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'spans'").Scan(&tables); err != nil {
+		return fmt.Errorf("could not check backup tables: %w", err)
+	}
+	if tables == 0 {
+		return fmt.Errorf("backup holds no spans table")
+	}
+	return nil
+}
@@ -16,14 +16,31 @@
 
 package sqlitespanreader
 
-import "github.com/teletrace/teletrace/pkg/config"
+import (
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/config"
+)
 
 type SqliteConfig struct {
 	Path string
+	// QueryTimeout interrupts statements running longer, 0 disables the timeout
+	QueryTimeout time.Duration
+	// SlowQueryThreshold logs and counts slower statements, 0 disables the slow query log
+	SlowQueryThreshold time.Duration
+	// ShardBy splits the spans across database files next to Path, by the day they started in or the hash of
+	// their trace id, see shards.go. Empty reads the spans from Path.
+	ShardBy string
+	// Shards is the number of trace id shards
+	Shards int
 }
 
 func NewSqliteConfig(cfg config.Config) SqliteConfig {
 	return SqliteConfig{
-		Path: cfg.SQLitePath,
+		Path:               cfg.SQLitePath,
+		QueryTimeout:       time.Duration(cfg.StorageQueryTimeoutSeconds) * time.Second,
+		SlowQueryThreshold: time.Duration(cfg.StorageSlowQueryThresholdMilliseconds) * time.Millisecond,
+		ShardBy:            cfg.SQLiteShardBy,
+		Shards:             cfg.SQLiteShards,
 	}
 }
@@ -0,0 +1,122 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package sqlitespanreader
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+const searchEventsQuery = "SELECT events.span_id, spans.trace_id, spans.name, events.name, events.time_unix_nano, " +
+	"(SELECT json_group_array(json_object('key', ea.key, 'value', ea.value, 'type', ea.type)) FROM event_attributes ea " +
+	"WHERE ea.event_id = events.id AND ea.key IS NOT NULL), " + // the event attributes as json
+	"(SELECT ra.value FROM span_resource_attributes sra JOIN resource_attributes ra ON ra.resource_id = sra.resource_attribute_id " +
+	"WHERE sra.span_id = events.span_id AND ra.key = 'service.name' LIMIT 1) " + // the service of the span owning the event
+	"FROM events JOIN spans ON spans.span_id = events.span_id "
+
+// buildSearchEventsQuery returns the query of the events matching r, and its arguments.
+// Empty events, stored for spans without events, are never matched.
+func buildSearchEventsQuery(r eventsquery.SearchRequest) (string, []any, error) {
+	conditions := []string{"events.name IS NOT NULL", "events.time_unix_nano >= ?"}
+	args := []any{r.Timeframe.StartTime}
+	if r.Timeframe.EndTime != 0 {
+		conditions = append(conditions, "events.time_unix_nano <= ?")
+		args = append(args, r.Timeframe.EndTime)
+	}
+	if r.Name != "" {
+		conditions = append(conditions, "events.name = ?")
+		args = append(args, r.Name)
+	}
+
+	keys := make([]string, 0, len(r.Attributes))
+	for key := range r.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM event_attributes ea WHERE ea.event_id = events.id AND ea.key = ? AND ea.value = ?)")
+		args = append(args, key, r.Attributes[key])
+	}
+
+	if len(r.SearchFilters) > 0 {
+		spansQuery, err := buildEventSpansQuery(r)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("events.span_id IN (SELECT span_id FROM (%s))", spansQuery))
+	}
+
+	query := searchEventsQuery + "WHERE " + strings.Join(conditions, " AND ") +
+		fmt.Sprintf(" ORDER BY events.time_unix_nano DESC LIMIT %d", r.EffectiveLimit())
+	return query, args, nil
+}
+
+// buildEventSpansQuery returns the query of the spans matching the request filters, which may own its events.
+func buildEventSpansQuery(r eventsquery.SearchRequest) (string, error) {
+	// spans owning events of the timeframe start before it ends, and filtering the spans table
+	// makes the sub query select spans whichever tables the request filters are on
+	endTime := r.Timeframe.EndTime
+	if endTime == 0 {
+		endTime = math.MaxInt64
+	}
+	filters := []model.SearchFilter{newSearchFilter("span.startTimeUnixNano", spansquery.OPERATOR_LTE, endTime)}
+	filters = append(filters, convertFiltersValues(r.SearchFilters)...)
+	subQueryBuilder := newSubQueryBuilder("spans")
+	if err := subQueryBuilder.addFiltersToSubQuery(filters); err != nil {
+		return "", fmt.Errorf("failed to add filters: %v", err)
+	}
+	subQuery, err := subQueryBuilder.buildSubQuery()
+	if err != nil {
+		return "", fmt.Errorf("failed to build sub query: %v", err)
+	}
+	return subQuery, nil
+}
+
+type sqliteEvent struct {
+	spanId       sql.NullString
+	traceId      sql.NullString
+	spanName     sql.NullString
+	name         sql.NullString
+	timeUnixNano sql.NullInt64
+	attributes   sql.NullString
+	serviceName  sql.NullString
+}
+
+func (se *sqliteEvent) toEvent() (eventsquery.Event, error) {
+	attributes := make(map[string]any)
+	if se.attributes.Valid {
+		var err error
+		if attributes, _, err = jsonToAttributesMap(se.attributes.String); err != nil {
+			return eventsquery.Event{}, err
+		}
+	}
+	return eventsquery.Event{
+		TraceId:      se.traceId.String,
+		SpanId:       se.spanId.String,
+		SpanName:     se.spanName.String,
+		ServiceName:  se.serviceName.String,
+		Name:         se.name.String,
+		TimeUnixNano: uint64(se.timeUnixNano.Int64),
+		Attributes:   attributes,
+	}, nil
+}
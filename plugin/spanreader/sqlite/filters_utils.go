@@ -23,13 +23,16 @@ import (
 	"github.com/teletrace/teletrace/pkg/model"
 
 	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+
+	internalspanv1 "github.com/teletrace/teletrace/model/internalspan/v1"
 )
 
 var sqliteFieldsMap = map[string]string{
 	"span.events.name":                    "events.name",
 	"span.events.droppedAttributesCount":  "events.dropped_attributes_count",
 	"span.events.spanId":                  "events.span_id",
-	"span.links.spanId":                   "links.span_id",
+	"span.links.traceId":                  "links.linked_trace_id",
+	"span.links.spanId":                   "links.linked_span_id",
 	"span.links.traceState":               "links.trace_state",
 	"span.links.droppedAttributesCount":   "links.dropped_attributes_count",
 	"scope.name":                          "scopes.name",
@@ -44,13 +47,15 @@ var sqliteFieldsMap = map[string]string{
 	"span.startTimeUnixNano":              "spans.start_time_unix_nano",
 	"span.endTimeUnixNano":                "spans.end_time_unix_nano",
 	"span.duration":                       "spans.duration",
-	"span.droppedAttributesCount":         "spans.dropped_attributes_count",
-	"span.status.message":                 "spans.status_message",
+	"span.childCount":                     "spans.child_count",
+	"span.droppedAttributesCount":         "spans.dropped_span_attributes_count",
+	"span.status.message":                 "spans.span_status_message",
 	"span.status.code":                    "spans.span_status_code",
 	"span.droppedResourceAttributesCount": "spans.dropped_resource_attributes_count",
 	"span.droppedEventsCount":             "spans.dropped_events_count",
 	"span.droppedLinksCount":              "spans.dropped_links_count",
 	"externalFields.durationNano":         "spans.duration",
+	"externalFields.childCount":           "spans.child_count",
 }
 
 var sqliteTableNameMap = map[string]string{
@@ -66,11 +71,30 @@ var sqliteTableNameMap = map[string]string{
 	"span":                     "spans",
 }
 
+// externalFieldsFiltersMap maps derived fields to the spans table columns they are stored in
+var externalFieldsFiltersMap = map[string]string{
+	"externalFields.childCount": "span.childCount",
+}
+
 var existenceCheckFiltersMap = map[model.FilterOperator]model.FilterOperator{
 	spansquery.OPERATOR_EXISTS:     spansquery.OPERATOR_EQUALS,
 	spansquery.OPERATOR_NOT_EXISTS: spansquery.OPERATOR_NOT_EQUALS,
 }
 
+// negatedFilterOperators maps the negative operators to the operators they negate
+var negatedFilterOperators = map[model.FilterOperator]model.FilterOperator{
+	spansquery.OPERATOR_NOT_EQUALS:   spansquery.OPERATOR_EQUALS,
+	spansquery.OPERATOR_NOT_IN:       spansquery.OPERATOR_IN,
+	spansquery.OPERATOR_NOT_CONTAINS: spansquery.OPERATOR_CONTAINS,
+}
+
+var rangeFilterOperators = map[model.FilterOperator]bool{
+	spansquery.OPERATOR_GT:  true,
+	spansquery.OPERATOR_GTE: true,
+	spansquery.OPERATOR_LT:  true,
+	spansquery.OPERATOR_LTE: true,
+}
+
 var tableJoinKeyMap = map[string]string{
 	"events_attributes":   "event_id",
 	"events":              "id",
@@ -83,6 +107,20 @@ var tableJoinKeyMap = map[string]string{
 	"spans":               "span_id",
 }
 
+// lastSeenFields maps the tables of tag values to the start time of the span of a row of the table, or of the most
+// recent span of rows shared by spans, to sort tag values by their most recent occurrence
+var lastSeenFields = map[string]string{
+	"spans":               "spans.start_time_unix_nano",
+	"span_attributes":     "(SELECT s.start_time_unix_nano FROM spans s WHERE s.span_id = span_attributes.span_id)",
+	"events":              "events.time_unix_nano",
+	"event_attributes":    "(SELECT e.time_unix_nano FROM events e WHERE e.id = event_attributes.event_id)",
+	"links":               "(SELECT s.start_time_unix_nano FROM spans s WHERE s.span_id = links.span_id)",
+	"link_attributes":     "(SELECT s.start_time_unix_nano FROM links l JOIN spans s ON s.span_id = l.span_id WHERE l.id = link_attributes.link_id)",
+	"resource_attributes": "(SELECT MAX(s.start_time_unix_nano) FROM span_resource_attributes sra JOIN spans s ON s.span_id = sra.span_id WHERE sra.resource_attribute_id = resource_attributes.resource_id)",
+	"scope_attributes":    "(SELECT MAX(s.start_time_unix_nano) FROM spans s WHERE s.instrumentation_scope_id = scope_attributes.scope_id)",
+	"scopes":              "(SELECT MAX(s.start_time_unix_nano) FROM spans s WHERE s.instrumentation_scope_id = scopes.id)",
+}
+
 // should be ordered, regular map is not option
 var filterTablesNames = []string{"span.attributes", "span.events", "span.event.attributes", "span.links", "span.link.attributes", "span.resource.attributes", "resource.attributes", "scope.attributes", "scope", "span"}
 
@@ -90,7 +128,11 @@ func convertFiltersValues(filters []model.SearchFilter) []model.SearchFilter {
 	var convertedFilters []model.SearchFilter
 	for _, filter := range filters {
 		filterKey := string(filter.KeyValueFilter.Key)
+		if mappedKey, ok := externalFieldsFiltersMap[filterKey]; ok {
+			filterKey = mappedKey
+		}
 		filterOperator := filter.KeyValueFilter.Operator
+		filterValue := spansquery.NormalizeFilterValue(filter.KeyValueFilter.Key, filter.KeyValueFilter.Value)
 		prepareSqliteFilter, err := newSqliteFilter(filterKey)
 		if err != nil {
 			continue
@@ -106,17 +148,22 @@ func convertFiltersValues(filters []model.SearchFilter) []model.SearchFilter {
 			filterKey = createDynamicTagValueField(prepareSqliteFilter.getTableKey())
 		}
 
-		values, ok := filter.KeyValueFilter.Value.([]interface{})
+		values, ok := filterValue.([]interface{})
 		if ok {
 			newFilterValue = convertSliceOfValuesToString(values)
-		} else if str, ok := filter.KeyValueFilter.Value.(string); ok {
-			if filterOperator == spansquery.OPERATOR_CONTAINS || filterOperator == spansquery.OPERATOR_NOT_CONTAINS {
+		} else if str, ok := filterValue.(string); ok {
+			if filterOperator == spansquery.OPERATOR_CONTAINS || filterOperator == spansquery.OPERATOR_NOT_CONTAINS ||
+				filterOperator == spansquery.OPERATOR_BINARY_PREFIX {
 				newFilterValue = str
 			} else {
 				newFilterValue = fmt.Sprintf("'%s'", str)
 			}
-		} else if value, ok := filter.KeyValueFilter.Value.(float64); ok {
+		} else if value, ok := filterValue.(float64); ok {
 			newFilterValue = fmt.Sprintf("%f", value)
+		} else if spansquery.IsNumber(filterValue) {
+			newFilterValue = fmt.Sprintf("%v", filterValue)
+		} else if value, ok := filterValue.(bool); ok {
+			newFilterValue = sqliteBool(value)
 		} else {
 			continue
 		}
@@ -131,6 +178,8 @@ func convertSliceOfValuesToString(values []interface{}) string {
 		switch value.(type) {
 		case string:
 			valuesStrSlice = append(valuesStrSlice, fmt.Sprintf("'%s'", value))
+		case bool:
+			valuesStrSlice = append(valuesStrSlice, sqliteBool(value.(bool)))
 		default:
 			valuesStrSlice = append(valuesStrSlice, fmt.Sprintf("%v", value))
 		}
@@ -138,6 +187,14 @@ func convertSliceOfValuesToString(values []interface{}) string {
 	return strings.Join(valuesStrSlice, ",")
 }
 
+// sqliteBool returns the literal of a boolean filter value, as booleans are stored as the integers 1 and 0.
+func sqliteBool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
 func removeTablePrefixFromDynamicTag(tag string) string {
 	for _, tableKey := range filterTablesNames {
 		if strings.HasPrefix(tag, tableKey) {
@@ -241,7 +298,57 @@ func covertFilterToSqliteQueryCondition(filter model.SearchFilter) string {
 		return fmt.Sprintf("%s IN (%s)", filterKey, value)
 	case spansquery.OPERATOR_NOT_IN:
 		return fmt.Sprintf("%s NOT IN (%s) OR %s IS NULL", filterKey, value, filterKey)
+	case spansquery.OPERATOR_BINARY_PREFIX:
+		// only attribute values are binary, see attributeValueCondition
+		return "FALSE"
 	default:
 		return ""
 	}
 }
+
+// attributeValueCondition returns the condition of a filter on the attribute values of a table.
+// Array and kvlist values are stored as JSON, and match when any of their (nested) values matches,
+// or with a negative operator when none of them matches.
+func attributeValueCondition(tableName string, operator model.FilterOperator, value model.FilterValue) string {
+	valueField := createDynamicTagValueField(tableName)
+	if operator == spansquery.OPERATOR_BINARY_PREFIX {
+		return fmt.Sprintf("(%s.type = '%s' AND %s)", tableName, BytesType, binaryPrefixCondition(valueField, value))
+	}
+	typeField := fmt.Sprintf("COALESCE(%s.type, '')", tableName)
+	nestedTypes := fmt.Sprintf("('%s', '%s')", SliceType, MapType)
+
+	scalarTypes := fmt.Sprintf("%s NOT IN %s", typeField, nestedTypes)
+	atomTypes := "json_tree.atom IS NOT NULL"
+	if rangeFilterOperators[operator] {
+		// range filters only compare numbers, as sqlite orders any number before any string instead of failing
+		scalarTypes = fmt.Sprintf("%s IN ('%s', '%s')", typeField, NumberType, DoubleType)
+		atomTypes = "json_tree.type IN ('integer', 'real')"
+	}
+
+	scalarCondition := covertFilterToSqliteQueryCondition(newSearchFilter(valueField, operator, value))
+	nestedOperator, negated := negatedFilterOperators[operator]
+	if !negated {
+		nestedOperator = operator
+	}
+	nestedCondition := fmt.Sprintf("EXISTS (SELECT 1 FROM json_tree(%s) WHERE %s AND (%s))",
+		valueField, atomTypes, covertFilterToSqliteQueryCondition(newSearchFilter("json_tree.atom", nestedOperator, value)))
+	if negated {
+		nestedCondition = "NOT " + nestedCondition
+	}
+	return fmt.Sprintf("((%s AND (%s)) OR (%s IN %s AND %s))",
+		scalarTypes, scalarCondition, typeField, nestedTypes, nestedCondition)
+}
+
+// Binary values are stored in base64 (see BytesType), so a binary prefix matches any of the base64 prefixes
+// of its bytes. GLOB is used since LIKE is case-insensitive.
+func binaryPrefixCondition(filterKey string, value model.FilterValue) string {
+	prefix, err := spansquery.BinaryPrefix(value)
+	if err != nil {
+		return "FALSE"
+	}
+	var conditions []string
+	for _, p := range internalspanv1.BinaryValuePrefixes(prefix, internalspanv1.BinaryEncodingBase64) {
+		conditions = append(conditions, fmt.Sprintf("%s GLOB '%s*'", filterKey, p))
+	}
+	return fmt.Sprintf("(%s)", strings.Join(conditions, " OR "))
+}
@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -57,6 +58,24 @@ func TestConvertSliceOfValuesToStringNotOnlyStrings(t *testing.T) {
 	assert.Equal(t, expectedString, convertSliceOfValuesToString(sliceToTest))
 }
 
+func TestConvertBoolValues(t *testing.T) {
+	assert.Equal(t, "1,0", convertSliceOfValuesToString([]interface{}{true, false}))
+
+	convertedFilters := convertFiltersValues([]model.SearchFilter{newSearchFilter("span.attributes.cached", spansquery.OPERATOR_EQUALS, false)})
+	assert.Len(t, convertedFilters, 1)
+	assert.Equal(t, "0", convertedFilters[0].KeyValueFilter.Value)
+}
+
+func TestConvertEnumValues(t *testing.T) {
+	convertedFilters := convertFiltersValues([]model.SearchFilter{
+		newSearchFilter("span.kind", spansquery.OPERATOR_EQUALS, "SPAN_KIND_SERVER"),
+		newSearchFilter("span.status.code", spansquery.OPERATOR_IN, []interface{}{"STATUS_CODE_ERROR", 1.0, "Unset"}),
+	})
+	assert.Len(t, convertedFilters, 2)
+	assert.Equal(t, "'Server'", convertedFilters[0].KeyValueFilter.Value)
+	assert.Equal(t, "'Error','Ok','Unset'", convertedFilters[1].KeyValueFilter.Value)
+}
+
 func TestConvertSliceOfValuesToStringEmptyInput(t *testing.T) {
 	assert.Equal(t, "", convertSliceOfValuesToString([]interface{}{}))
 }
@@ -112,3 +131,17 @@ func TestConvertFiltersValuesEmptyInput(t *testing.T) {
 	convertedFilters := convertFiltersValues(filters)
 	assert.Empty(t, convertedFilters)
 }
+
+func TestBinaryPrefixCondition(t *testing.T) {
+	filters := convertFiltersValues([]model.SearchFilter{
+		newSearchFilter("span.attributes.payload", spansquery.OPERATOR_BINARY_PREFIX, "yv4="),
+	})
+	assert.Len(t, filters, 1)
+
+	condition := attributeValueCondition("span_attributes", filters[0].KeyValueFilter.Operator, filters[0].KeyValueFilter.Value)
+	assert.Equal(t,
+		"(span_attributes.type = 'Bytes' AND (span_attributes.value GLOB 'yv4*' OR span_attributes.value GLOB 'yv5*' "+
+			"OR span_attributes.value GLOB 'yv6*' OR span_attributes.value GLOB 'yv7*'))",
+		condition)
+	assert.Equal(t, "FALSE", covertFilterToSqliteQueryCondition(filters[0]))
+}
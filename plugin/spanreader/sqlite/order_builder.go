@@ -28,6 +28,10 @@ type extractOrderResponse struct {
 	filter  *model.SearchFilter
 	sortTag string
 	sortBy  string
+	// attribute is set when sorting by an attribute, whose value is selected by sortTag and whose spans following the
+	// next token match condition
+	attribute bool
+	condition string
 }
 
 func (er *extractOrderResponse) getFilter() *model.SearchFilter {
@@ -42,6 +46,14 @@ func (er *extractOrderResponse) getSortBy() string {
 	return er.sortBy
 }
 
+func (er *extractOrderResponse) isAttribute() bool {
+	return er.attribute
+}
+
+func (er *extractOrderResponse) getCondition() string {
+	return er.condition
+}
+
 func extractNextToken(orders []spansquery.Sort, nextToken spansquery.ContinuationToken) (*extractOrderResponse, error) {
 	if len(orders) > 1 {
 		return nil, fmt.Errorf("expected a single sort field, but found: %v", len(orders))
@@ -51,6 +63,15 @@ func extractNextToken(orders []spansquery.Sort, nextToken spansquery.Continuatio
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse order: %v", err)
 	}
+	if sqliteOrder.expression != "" {
+		res := &extractOrderResponse{sortTag: sqliteOrder.expression, sortBy: sqliteOrder.getOrderBy(), attribute: true}
+		if nextToken != "" {
+			if res.condition, err = afterAttributeToken(sqliteOrder, nextToken); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	}
 	if (nextToken) != "" {
 		var filter model.SearchFilter
 		switch sqliteOrder.orderBy {
@@ -27,6 +27,7 @@ import (
 )
 
 const (
+	// LimitOfSpanRecords is the page size of searches without a limit
 	LimitOfSpanRecords = 200
 )
 
@@ -34,14 +35,31 @@ func buildSearchQuery(r spansquery.SearchRequest) (*searchQueryResponse, error)
 	var order string
 	var err error
 	var extractedNextToken *extractOrderResponse
+	sortValue, condition := "NULL", ""
 	searchQueryResponse := newSearchQueryResponse()
 	filters := createTimeframeFilters(r.Timeframe)
-	if r.Sort != nil {
+	limit := LimitOfSpanRecords
+	if r.Limit > 0 {
+		limit = r.Limit
+	}
+	if r.Sample != nil {
+		// sampled results are drawn randomly, and the sort and continuation token don't apply
+		order = " ORDER BY random() "
+		limit = r.Sample.Size
+	} else if r.Sort != nil {
 		extractedNextToken, err = extractNextToken(r.Sort, r.Metadata.NextToken)
 		if err != nil {
 			return nil, fmt.Errorf("failed to extract next token: %v", err)
 		}
 		order = fmt.Sprintf(" ORDER BY %s %s ", extractedNextToken.getSortTag(), extractedNextToken.getSortBy())
+		if extractedNextToken.isAttribute() {
+			// spans without the attribute are last like in the other plugins, and spans sharing an attribute value are
+			// ordered by their ID, so they can be paginated
+			order = fmt.Sprintf(" ORDER BY sort_value IS NULL, sort_value %[1]s, iq.span_id %[1]s ", extractedNextToken.getSortBy())
+			sortValue = extractedNextToken.getSortTag()
+			condition = extractedNextToken.getCondition()
+			searchQueryResponse.sort = "attribute"
+		}
 		orderFilter := extractedNextToken.getFilter()
 		if orderFilter != nil {
 			filters = append(filters, *orderFilter)
@@ -57,16 +75,40 @@ func buildSearchQuery(r spansquery.SearchRequest) (*searchQueryResponse, error)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build sub query: %v", err)
 	}
-	searchQueryResponse.query = getSearchQuery(subQuery, order)
+	searchQueryResponse.query = getSearchQuery(subQuery, sortValue, condition, order, limit, r)
+	if r.Sample != nil {
+		searchQueryResponse.countQuery = fmt.Sprintf("SELECT COUNT(DISTINCT span_id) FROM (%s)", subQuery)
+	}
 	return searchQueryResponse, nil
 }
 
-func getSearchQuery(subQuery string, orders string) string {
+// getSearchQuery returns the query of the spans of the sub query matching the condition if set, joining the tables of
+// the field groups included by r and selecting NULL for the others. The sort value of the spans is selected last.
+func getSearchQuery(subQuery string, sortValue string, condition string, orders string, limit int, r spansquery.SearchRequest) string {
 	spanIdentifiersQuery := fmt.Sprintf("WITH initial_query as (%s)", subQuery) // base query for search query
+	spanAttributesColumn := "span_attributes"
+	scopeColumns := "scopes.name, scopes.version, scopes.dropped_attributes_count, scope_attributes"
+	eventsColumn, linksColumn, resourcesColumn := "events", "links", "resources"
+	if !r.IncludesField(spansquery.FIELD_GROUP_SPAN_ATTRIBUTES) {
+		spanAttributesColumn = "NULL"
+	}
+	if !r.IncludesField(spansquery.FIELD_GROUP_SCOPE) {
+		scopeColumns = "NULL, NULL, NULL, NULL"
+	}
+	if !r.IncludesField(spansquery.FIELD_GROUP_SPAN_EVENTS) {
+		eventsColumn = "NULL"
+	}
+	if !r.IncludesField(spansquery.FIELD_GROUP_SPAN_LINKS) {
+		linksColumn = "NULL"
+	}
+	if !r.IncludesField(spansquery.FIELD_GROUP_RESOURCE_ATTRIBUTES) {
+		resourcesColumn = "NULL"
+	}
 	internalSpanParamsQuery := " SELECT iq.span_id, spans.trace_id, spans.trace_state, spans.parent_span_id, spans.name, spans.kind, spans.start_time_unix_nano, " +
 		"spans.end_time_unix_nano, spans.dropped_span_attributes_count, spans.span_status_message, spans.span_status_code, spans.dropped_resource_attributes_count, " +
-		"spans.dropped_events_count, spans.dropped_links_count, spans.duration, spans.ingestion_time_unix_nano, " +
-		"span_attributes ,scopes.name, scopes.version, scopes.dropped_attributes_count, scope_attributes, events, links, resources FROM initial_query AS iq " // query for internal span params
+		"spans.dropped_events_count, spans.dropped_links_count, spans.duration, spans.ingestion_time_unix_nano, spans.child_count, " +
+		spanAttributesColumn + " ," + scopeColumns + ", " + eventsColumn + ", " + linksColumn + ", " + resourcesColumn + ", " +
+		sortValue + " AS sort_value FROM initial_query AS iq " // query for internal span params
 	spanSchemaJoinQuery := " JOIN spans ON spans.span_id = iq.span_id " // join spans table for internal span params
 	resourceAttributesJoinQuery := " LEFT JOIN " +
 		"(SELECT span_resource_attributes.span_id, json_group_array(json_object('key', span_resource_attributes.key, 'value', span_resource_attributes.value, 'type', span_resource_attributes.type)) " + // join table with all span's resources as json
@@ -91,22 +133,41 @@ func getSearchQuery(subQuery string, orders string) string {
 		"(SELECT events.span_id, json_group_array(json_object('time_unix_nano', events.time_unix_nano, 'name', events.name, 'dropped_attributes_count', events.dropped_attributes_count, 'event_attributes', events.event_attributes)) " +
 		"AS events " +
 		"FROM " +
-		"(SELECT events.span_id, events.time_unix_nano, events.name, events.dropped_attributes_count, json_group_array(json_object('key', ea.key, 'value', ea.value)) " +
+		"(SELECT events.span_id, events.time_unix_nano, events.name, events.dropped_attributes_count, json_group_array(json_object('key', ea.key, 'value', ea.value, 'type', ea.type)) " +
 		"AS event_attributes FROM events " +
 		"JOIN event_attributes ea ON events.id = ea.event_id GROUP BY events.id) " + // join between events and event attributes for map between event and theirs attributes
 		"AS events GROUP BY events.span_id) " +
 		"AS events ON events.span_id = iq.span_id " // join between events and spans
 	LinksJoinQuery := " LEFT JOIN " +
-		"(SELECT links.span_id, json_group_array(json_object('trace_state', links.trace_state, 'name', links.dropped_attributes_count, 'link_attributes', links.link_attributes)) " +
+		"(SELECT links.span_id, json_group_array(json_object('trace_id', links.linked_trace_id, 'span_id', links.linked_span_id, 'trace_state', links.trace_state, 'dropped_attributes_count', links.dropped_attributes_count, 'link_attributes', links.link_attributes)) " +
 		"AS links FROM " +
-		"(SELECT links.span_id, links.trace_state, links.dropped_attributes_count,  json_group_array(json_object('key', la.key, 'value', la.value)) " +
+		"(SELECT links.span_id, links.linked_trace_id, links.linked_span_id, links.trace_state, links.dropped_attributes_count,  json_group_array(json_object('key', la.key, 'value', la.value, 'type', la.type)) " +
 		"AS link_attributes FROM links " +
 		"JOIN link_attributes la ON links.id = la.link_id GROUP BY links.id) " + // join between links and link attributes for map between link and theirs attributes
 		"AS links GROUP BY links.span_id) " +
 		"AS links ON links.span_id = iq.span_id " // join between links and spans
-	groupQuery := " GROUP BY iq.span_id "                      // group by span_id internal span params
-	limitQuery := fmt.Sprintf(" LIMIT %d", LimitOfSpanRecords) // set limit on number of records
-	return spanIdentifiersQuery + internalSpanParamsQuery + spanSchemaJoinQuery + resourceAttributesJoinQuery + spanAttributesJoinQuery + scopesJoinQuery + eventsJoinQuery + LinksJoinQuery + groupQuery + orders + limitQuery
+	groupQuery := " GROUP BY iq.span_id "         // group by span_id internal span params
+	limitQuery := fmt.Sprintf(" LIMIT %d", limit) // set limit on number of records
+	query := spanIdentifiersQuery + internalSpanParamsQuery + spanSchemaJoinQuery
+	if r.IncludesField(spansquery.FIELD_GROUP_RESOURCE_ATTRIBUTES) {
+		query += resourceAttributesJoinQuery
+	}
+	if r.IncludesField(spansquery.FIELD_GROUP_SPAN_ATTRIBUTES) {
+		query += spanAttributesJoinQuery
+	}
+	if r.IncludesField(spansquery.FIELD_GROUP_SCOPE) {
+		query += scopesJoinQuery
+	}
+	if r.IncludesField(spansquery.FIELD_GROUP_SPAN_EVENTS) {
+		query += eventsJoinQuery
+	}
+	if r.IncludesField(spansquery.FIELD_GROUP_SPAN_LINKS) {
+		query += LinksJoinQuery
+	}
+	if condition != "" {
+		query += " WHERE " + condition
+	}
+	return query + groupQuery + orders + limitQuery
 }
 
 func buildTagValuesQuery(r tagsquery.TagValuesRequest, tag string) (*tagValueQueryResponse, error) {
@@ -137,17 +198,41 @@ func buildTagValuesQuery(r tagsquery.TagValuesRequest, tag string) (*tagValueQue
 	tableKey := tableJoinKeyMap[mainTableName]
 	mainField := subQueryBuilder.getMainField()
 	mainCondition := subQueryBuilder.getMainCondition()
-	query := fmt.Sprintf("WITH subQuery AS (%s) SELECT %s, COUNT(*) FROM %s JOIN subQuery ON %s.%s = subQuery.%s %s GROUP BY %s", subQuery, mainField, mainTableName, mainTableName, tableKey, tableKey, mainCondition, mainField)
+	mainTypeField := subQueryBuilder.getMainTypeField()
+	mainJoin := subQueryBuilder.getMainJoin()
+	sortField, ascending := r.Sort()
+	lastSeenField := "NULL"
+	if sortField == tagsquery.SORT_BY_LAST_SEEN {
+		lastSeenField = fmt.Sprintf("MAX(%s)", lastSeenFields[mainTableName])
+	}
+	// values are grouped by type as well, as e.g. the boolean true and the integer 1 are both stored as 1
+	query := fmt.Sprintf("WITH subQuery AS (%s) SELECT %s, COUNT(*), %s, %s FROM %s JOIN subQuery ON %s.%s = subQuery.%s %s %s GROUP BY %s, %s", subQuery, mainField, mainTypeField, lastSeenField, mainTableName, mainTableName, tableKey, tableKey, mainJoin, mainCondition, mainField, mainTypeField)
+	orderField := "COUNT(*)"
+	switch sortField {
+	case tagsquery.SORT_BY_VALUE:
+		orderField = mainField
+	case tagsquery.SORT_BY_LAST_SEEN:
+		orderField = lastSeenField
+	}
+	direction := map[bool]string{true: "ASC", false: "DESC"}[ascending]
+	query += fmt.Sprintf(" ORDER BY %s %s", orderField, direction)
+	if r.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", r.Limit)
+	}
 	tagValueQueryResponse := newTagValueQueryResponse(query)
 	return tagValueQueryResponse, nil
 }
 
-func buildDynamicTagsQuery() string {
+func buildDynamicTagsQuery(limit int) string {
 	var queries []string
 	for tableKey, table := range sqliteTableNameMap {
 		if isDynamicTagsTable(table) && tableKey != "span.resource.attributes" {
 			queries = append(queries, fmt.Sprintf("SELECT DISTINCT '%s' as table_key, t.key as tag_name, t.type as tag_type FROM %s t", tableKey, table))
 		}
 	}
-	return strings.Join(queries, " UNION ALL ")
+	query := strings.Join(queries, " UNION ALL ")
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	return query
 }
@@ -0,0 +1,97 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlitespanreader
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSampledSearchQuery(t *testing.T) {
+	r := spansquery.SearchRequest{
+		Timeframe: model.Timeframe{StartTime: 1, EndTime: 2},
+		Sort:      []spansquery.Sort{{Field: "span.startTimeUnixNano", Ascending: false}},
+		Sample:    &spansquery.Sample{Size: 25},
+	}
+
+	searchQuery, err := buildSearchQuery(r)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(searchQuery.getQuery(), " ORDER BY random()  LIMIT 25"))
+	assert.True(t, strings.HasPrefix(searchQuery.getCountQuery(), "SELECT COUNT(DISTINCT span_id) FROM ("))
+}
+
+func TestBuildSearchQueryWithoutSample(t *testing.T) {
+	r := spansquery.SearchRequest{
+		Timeframe: model.Timeframe{StartTime: 1, EndTime: 2},
+	}
+
+	searchQuery, err := buildSearchQuery(r)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(searchQuery.getQuery(), fmt.Sprintf(" LIMIT %d", LimitOfSpanRecords)))
+	assert.Empty(t, searchQuery.getCountQuery())
+}
+
+func TestBuildQueriesWithLimit(t *testing.T) {
+	searchQuery, err := buildSearchQuery(spansquery.SearchRequest{Timeframe: model.Timeframe{StartTime: 1, EndTime: 2}, Limit: 30})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(searchQuery.getQuery(), " LIMIT 30"))
+
+	tagValuesQuery, err := buildTagValuesQuery(tagsquery.TagValuesRequest{Limit: 10}, "span.name")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(tagValuesQuery.getQuery(), " ORDER BY COUNT(*) DESC LIMIT 10"))
+
+	assert.True(t, strings.HasSuffix(buildDynamicTagsQuery(5), " LIMIT 5"))
+	assert.NotContains(t, buildDynamicTagsQuery(0), "LIMIT")
+}
+
+func TestBuildSearchQueryByChildCount(t *testing.T) {
+	r := spansquery.SearchRequest{
+		Timeframe: model.Timeframe{StartTime: 1, EndTime: 2},
+		SearchFilters: []model.SearchFilter{
+			newSearchFilter("externalFields.childCount", spansquery.OPERATOR_GTE, float64(10)),
+		},
+		Sort:     []spansquery.Sort{{Field: "externalFields.childCount", Ascending: false}},
+		Metadata: &spansquery.Metadata{},
+	}
+
+	searchQuery, err := buildSearchQuery(r)
+	assert.NoError(t, err)
+	assert.Contains(t, searchQuery.getQuery(), "spans.child_count >= 10.000000")
+	assert.Contains(t, searchQuery.getQuery(), "ORDER BY spans.child_count DESC")
+}
+
+func TestBuildSearchQueryWithFields(t *testing.T) {
+	r := spansquery.SearchRequest{
+		Timeframe: model.Timeframe{StartTime: 1, EndTime: 2},
+		Fields:    []spansquery.FieldGroup{spansquery.FIELD_GROUP_SPAN_ATTRIBUTES},
+	}
+
+	searchQuery, err := buildSearchQuery(r)
+	assert.NoError(t, err)
+	assert.Contains(t, searchQuery.getQuery(), "AS span_attributes ON iq.span_id = span_attributes.span_id")
+	assert.Contains(t, searchQuery.getQuery(), "span_attributes ,NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL AS sort_value FROM initial_query")
+	for _, join := range []string{"AS resource_attributes ON", "AS scopes ON", "AS events ON", "AS links ON"} {
+		assert.NotContains(t, searchQuery.getQuery(), join)
+	}
+}
@@ -17,8 +17,9 @@
 package sqlitespanreader
 
 type searchQueryResponse struct {
-	query string
-	sort  string
+	query      string
+	sort       string
+	countQuery string
 }
 
 func (sqr *searchQueryResponse) getQuery() string {
@@ -29,6 +30,10 @@ func (sqr *searchQueryResponse) getSort() string {
 	return sqr.sort
 }
 
+func (sqr *searchQueryResponse) getCountQuery() string {
+	return sqr.countQuery
+}
+
 func newSearchQueryResponse() *searchQueryResponse {
 	return &searchQueryResponse{
 		query: "",
@@ -0,0 +1,370 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlitespanreader
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/spanreader/federated"
+
+	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
+
+	"go.uber.org/zap"
+)
+
+// The shards are written by the sqlite exporter to database files next to the configured path, suffixed by the UTC day
+// their spans started in, e.g. embedded_spans.2023-01-02.db, or by the hash of their trace id, e.g.
+// embedded_spans.shard-3.db, see teletrace-otelcol/exporter/sqliteexporter/shards.go. The modules don't share code, so
+// TestShardNamesMatchExporter pins the names and hashes which the exporter's TestShardNamesMatchReader pins too.
+const (
+	ShardByDay     = "day"
+	ShardByTraceId = "trace_id"
+
+	shardDayLayout   = "2006-01-02"
+	traceShardPrefix = "shard-"
+)
+
+// shard is a database file of the spans.
+type shard struct {
+	name string
+	path string
+	// day is the day of the spans of a day shard
+	day time.Time
+	// index is the index of a trace id shard
+	index int
+}
+
+// traceShard returns the shard of a trace id, by the FNV-1a hash of its hex string.
+func traceShard(traceId string, shards int) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(strings.ToLower(traceId)))
+	return int(hash.Sum32() % uint32(shards))
+}
+
+// listShards returns the shards of cfg which exist, sorted by name. A missing shard has no spans, as the exporter
+// creates the shards on their first write.
+func listShards(cfg SqliteConfig) ([]shard, error) {
+	ext := filepath.Ext(cfg.Path)
+	base := strings.TrimSuffix(cfg.Path, ext)
+	files, err := filepath.Glob(base + ".*" + ext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shards: %w", err)
+	}
+
+	var shards []shard
+	for _, file := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(file, base+"."), ext)
+		s := shard{name: name, path: file}
+		switch cfg.ShardBy {
+		case ShardByDay:
+			if s.day, err = time.Parse(shardDayLayout, name); err != nil {
+				continue
+			}
+		case ShardByTraceId:
+			if !strings.HasPrefix(name, traceShardPrefix) {
+				continue
+			}
+			if s.index, err = strconv.Atoi(strings.TrimPrefix(name, traceShardPrefix)); err != nil || s.index >= cfg.Shards {
+				continue
+			}
+		}
+		shards = append(shards, s)
+	}
+	sort.Slice(shards, func(i, j int) bool { return shards[i].name < shards[j].name })
+	return shards, nil
+}
+
+// selectShards returns the shards which may hold the spans of tf, or of traceId if not empty.
+func selectShards(cfg SqliteConfig, shards []shard, tf *model.Timeframe, traceId string) []shard {
+	var selected []shard
+	for _, s := range shards {
+		switch cfg.ShardBy {
+		case ShardByDay:
+			if tf != nil && !overlaps(s.day, tf) {
+				continue
+			}
+		case ShardByTraceId:
+			if traceId != "" && s.index != traceShard(traceId, cfg.Shards) {
+				continue
+			}
+		}
+		selected = append(selected, s)
+	}
+	return selected
+}
+
+// overlaps returns whether the day starting at dayStart overlaps tf.
+func overlaps(dayStart time.Time, tf *model.Timeframe) bool {
+	from := dayStart.UnixNano()
+	if from < 0 {
+		return false
+	}
+	return (tf.EndTime == 0 || uint64(from) <= tf.EndTime) && uint64(from+(24*time.Hour).Nanoseconds()) > tf.StartTime
+}
+
+// filteredTraceId returns the trace id the filters match spans of, if they match a single one.
+func filteredTraceId(filters []model.SearchFilter) string {
+	for _, filter := range filters {
+		kv := filter.KeyValueFilter
+		if kv == nil || kv.Key != "span.traceId" || kv.Operator != spansquery.OPERATOR_EQUALS {
+			continue
+		}
+		if traceId, ok := kv.Value.(string); ok {
+			return traceId
+		}
+	}
+	return ""
+}
+
+// shardedSpanReader reads the spans of the shards of cfg, querying each of the shards which may hold the spans of a
+// request and merging their results as the federated span reader does, rather than attaching the shards to a single
+// connection, whose number SQLite limits. The shards are opened on their first query.
+type shardedSpanReader struct {
+	cfg    SqliteConfig
+	logger *zap.Logger
+	ctx    context.Context
+
+	mu      sync.Mutex
+	readers map[string]*spanReader
+}
+
+func newShardedSpanReader(ctx context.Context, logger *zap.Logger, cfg SqliteConfig) (*shardedSpanReader, error) {
+	switch cfg.ShardBy {
+	case ShardByDay:
+	case ShardByTraceId:
+		if cfg.Shards <= 0 {
+			return nil, fmt.Errorf("sharding by trace id requires a positive number of shards")
+		}
+	default:
+		return nil, fmt.Errorf("invalid sqlite sharding key %q, must be %s or %s", cfg.ShardBy, ShardByDay, ShardByTraceId)
+	}
+	return &shardedSpanReader{cfg: cfg, logger: logger, ctx: ctx, readers: make(map[string]*spanReader)}, nil
+}
+
+// shards returns a span reader of the shards which may hold the spans of tf, or of traceId if not empty.
+func (sr *shardedSpanReader) shards(tf *model.Timeframe, traceId string) (spanreader.SpanReader, error) {
+	shards, err := listShards(sr.cfg)
+	if err != nil {
+		return nil, err
+	}
+	selected := selectShards(sr.cfg, shards, tf, traceId)
+
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	sr.closeRemoved(shards)
+	backends := make([]federated.Backend, 0, len(selected))
+	for _, s := range selected {
+		reader, ok := sr.readers[s.path]
+		if !ok {
+			cfg := sr.cfg
+			cfg.Path = s.path
+			cfg.ShardBy = ""
+			if reader, err = newSpanReader(sr.ctx, sr.logger.With(zap.String("shard", s.name)), cfg); err != nil {
+				return nil, err
+			}
+			sr.readers[s.path] = reader
+		}
+		backends = append(backends, federated.Backend{Name: s.name, Reader: reader})
+	}
+	if len(backends) == 0 {
+		return emptySpanReader{}, nil
+	}
+	return federated.NewSpanReader(backends)
+}
+
+// closeRemoved closes the shards deleted by the retention of the exporter, so their files are released.
+func (sr *shardedSpanReader) closeRemoved(shards []shard) {
+	existing := make(map[string]bool, len(shards))
+	for _, s := range shards {
+		existing[s.path] = true
+	}
+	for path, reader := range sr.readers {
+		if existing[path] {
+			continue
+		}
+		if err := reader.client.db.Close(); err != nil {
+			sr.logger.Warn("Failed to close removed shard", zap.String("path", path), zap.Error(err))
+		}
+		delete(sr.readers, path)
+	}
+}
+
+func (sr *shardedSpanReader) Initialize() error {
+	return nil
+}
+
+func (sr *shardedSpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	shards, err := sr.shards(&r.Timeframe, filteredTraceId(r.SearchFilters))
+	if err != nil {
+		return nil, err
+	}
+	return shards.Search(ctx, r)
+}
+
+func (sr *shardedSpanReader) GetAvailableTags(ctx context.Context, r tagsquery.GetAvailableTagsRequest) (*tagsquery.GetAvailableTagsResponse, error) {
+	shards, err := sr.shards(nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return shards.GetAvailableTags(ctx, r)
+}
+
+func (sr *shardedSpanReader) GetTagsValues(ctx context.Context, r tagsquery.TagValuesRequest, tags []string) (map[string]*tagsquery.TagValuesResponse, error) {
+	shards, err := sr.shards(r.Timeframe, filteredTraceId(r.SearchFilters))
+	if err != nil {
+		return nil, err
+	}
+	return shards.GetTagsValues(ctx, r, tags)
+}
+
+func (sr *shardedSpanReader) GetTagsStatistics(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tag string,
+) (*tagsquery.TagStatisticsResponse, error) {
+	shards, err := sr.shards(r.Timeframe, filteredTraceId(r.SearchFilters))
+	if err != nil {
+		return nil, err
+	}
+	return shards.GetTagsStatistics(ctx, r, tag)
+}
+
+func (sr *shardedSpanReader) GetTagsStatisticsBatch(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string,
+) (map[string]*tagsquery.TagStatisticsResponse, error) {
+	shards, err := sr.shards(r.Timeframe, filteredTraceId(r.SearchFilters))
+	if err != nil {
+		return nil, err
+	}
+	return shards.GetTagsStatisticsBatch(ctx, r, tags)
+}
+
+func (sr *shardedSpanReader) SearchEvents(ctx context.Context, r eventsquery.SearchRequest) (*eventsquery.SearchResponse, error) {
+	shards, err := sr.shards(&r.Timeframe, filteredTraceId(r.SearchFilters))
+	if err != nil {
+		return nil, err
+	}
+	return shards.SearchEvents(ctx, r)
+}
+
+func (sr *shardedSpanReader) SearchTraces(ctx context.Context, r spansquery.SearchTracesRequest) (*spansquery.SearchTracesResponse, error) {
+	shards, err := sr.shards(&r.Timeframe, filteredTraceId(r.SearchFilters))
+	if err != nil {
+		return nil, err
+	}
+	return shards.SearchTraces(ctx, r)
+}
+
+func (sr *shardedSpanReader) Aggregate(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	shards, err := sr.shards(&r.Timeframe, filteredTraceId(r.SearchFilters))
+	if err != nil {
+		return nil, err
+	}
+	return shards.Aggregate(ctx, r)
+}
+
+func (sr *shardedSpanReader) GetSystemId(ctx context.Context, r metadata.GetSystemIdRequest) (*metadata.GetSystemIdResponse, error) {
+	return nil, fmt.Errorf("Not implemented method")
+}
+
+func (sr *shardedSpanReader) SetSystemId(ctx context.Context, r metadata.SetSystemIdRequest) (*metadata.SetSystemIdResponse, error) {
+	return nil, fmt.Errorf("Not implemented method")
+}
+
+// Ping pings all of the shards, none existing yet being healthy.
+func (sr *shardedSpanReader) Ping(ctx context.Context) error {
+	shards, err := sr.shards(nil, "")
+	if err != nil {
+		return err
+	}
+	return shards.Ping(ctx)
+}
+
+// emptySpanReader reads no spans, for requests which no shard may have spans of.
+type emptySpanReader struct{}
+
+func (emptySpanReader) Initialize() error {
+	return nil
+}
+
+func (emptySpanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	res := &spansquery.SearchResponse{Metadata: &spansquery.Metadata{}, Spans: make([]*internalspan.InternalSpan, 0)}
+	if r.Sample != nil {
+		res.Sample = &spansquery.SampleMetadata{}
+	}
+	return res, nil
+}
+
+func (emptySpanReader) GetAvailableTags(ctx context.Context, r tagsquery.GetAvailableTagsRequest) (*tagsquery.GetAvailableTagsResponse, error) {
+	return &tagsquery.GetAvailableTagsResponse{Tags: []tagsquery.TagInfo{}}, nil
+}
+
+func (emptySpanReader) GetTagsValues(ctx context.Context, r tagsquery.TagValuesRequest, tags []string) (map[string]*tagsquery.TagValuesResponse, error) {
+	return map[string]*tagsquery.TagValuesResponse{}, nil
+}
+
+func (emptySpanReader) GetTagsStatistics(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tag string,
+) (*tagsquery.TagStatisticsResponse, error) {
+	return &tagsquery.TagStatisticsResponse{Statistics: map[tagsquery.TagStatistic]float64{}}, nil
+}
+
+func (emptySpanReader) GetTagsStatisticsBatch(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string,
+) (map[string]*tagsquery.TagStatisticsResponse, error) {
+	return map[string]*tagsquery.TagStatisticsResponse{}, nil
+}
+
+func (emptySpanReader) SearchEvents(ctx context.Context, r eventsquery.SearchRequest) (*eventsquery.SearchResponse, error) {
+	return &eventsquery.SearchResponse{Events: make([]eventsquery.Event, 0)}, nil
+}
+
+func (emptySpanReader) SearchTraces(ctx context.Context, r spansquery.SearchTracesRequest) (*spansquery.SearchTracesResponse, error) {
+	return &spansquery.SearchTracesResponse{Metadata: &spansquery.Metadata{}, Traces: []*spansquery.TraceResult{}}, nil
+}
+
+func (emptySpanReader) Aggregate(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	return &spansquery.SearchResponse{
+		Metadata:     &spansquery.Metadata{},
+		Spans:        []*internalspan.InternalSpan{},
+		Aggregations: &spansquery.AggregationsResult{Groups: []spansquery.AggregationGroup{}},
+	}, nil
+}
+
+func (emptySpanReader) GetSystemId(ctx context.Context, r metadata.GetSystemIdRequest) (*metadata.GetSystemIdResponse, error) {
+	return nil, fmt.Errorf("Not implemented method")
+}
+
+func (emptySpanReader) SetSystemId(ctx context.Context, r metadata.SetSystemIdRequest) (*metadata.SetSystemIdResponse, error) {
+	return nil, fmt.Errorf("Not implemented method")
+}
+
+func (emptySpanReader) Ping(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,189 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlitespanreader
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func shardNames(shards []shard) []string {
+	var names []string
+	for _, s := range shards {
+		names = append(names, s.name)
+	}
+	return names
+}
+
+func TestListShards(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{
+		"spans.db", "spans.2023-01-03.db", "spans.2023-01-02.db", "spans.2023-01-02.db-wal",
+		"spans.shard-1.db", "spans.shard-9.db", "other.2023-01-02.db",
+	} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, file), nil, 0o644))
+	}
+	path := filepath.Join(dir, "spans.db")
+
+	shards, err := listShards(SqliteConfig{Path: path, ShardBy: ShardByDay})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2023-01-02", "2023-01-03"}, shardNames(shards))
+	assert.Equal(t, filepath.Join(dir, "spans.2023-01-02.db"), shards[0].path)
+
+	shards, err = listShards(SqliteConfig{Path: path, ShardBy: ShardByTraceId, Shards: 8})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"shard-1"}, shardNames(shards))
+}
+
+// TestShardNamesMatchExporter pins the shard files and trace id hashes of the sqlite exporter, whose
+// TestShardNamesMatchReader pins the same, see teletrace-otelcol/exporter/sqliteexporter/shards_test.go.
+func TestShardNamesMatchExporter(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"spans.2023-01-02.db", "spans.shard-5.db", "spans.shard-2.db"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, file), nil, 0o644))
+	}
+	path := filepath.Join(dir, "spans.db")
+
+	shards, err := listShards(SqliteConfig{Path: path, ShardBy: ShardByDay})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2023-01-02"}, shardNames(shards))
+
+	shards, err = listShards(SqliteConfig{Path: path, ShardBy: ShardByTraceId, Shards: 8})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"shard-2", "shard-5"}, shardNames(shards))
+	assert.Equal(t, 5, traceShard("0af7651916cd43dd8448eb211c80319c", 8))
+	assert.Equal(t, 2, traceShard("4bf92f3577b34da6a3ce929d0e0e4736", 8))
+}
+
+func TestBackupAndRestoreShards(t *testing.T) {
+	dir := t.TempDir()
+	cfg := SqliteConfig{Path: filepath.Join(dir, "spans.db"), ShardBy: ShardByDay}
+	for _, day := range []string{"1970-01-01", "1970-01-02"} {
+		db, err := sql.Open("sqlite3", filepath.Join(dir, "spans."+day+".db"))
+		assert.NoError(t, err)
+		_, err = db.Exec(eventsFixture)
+		assert.NoError(t, err)
+		assert.NoError(t, db.Close())
+	}
+	backupPath := filepath.Join(dir, "backup", "spans.db")
+	assert.NoError(t, os.Mkdir(filepath.Dir(backupPath), 0o755))
+
+	assert.NoError(t, BackupShards(context.Background(), cfg, backupPath))
+	for _, day := range []string{"1970-01-01", "1970-01-02"} {
+		assert.FileExists(t, filepath.Join(dir, "backup", "spans."+day+".db"))
+	}
+	assert.NoFileExists(t, backupPath)
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "spans.1970-01-02.db"))
+	assert.NoError(t, err)
+	_, err = db.Exec("DELETE FROM spans")
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	assert.NoError(t, RestoreShards(context.Background(), cfg, backupPath))
+	db, err = sql.Open("sqlite3", filepath.Join(dir, "spans.1970-01-02.db"))
+	assert.NoError(t, err)
+	defer db.Close()
+	var count int
+	assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM spans").Scan(&count))
+	assert.NotZero(t, count)
+}
+
+func TestSelectShards(t *testing.T) {
+	day := func(value string) time.Time {
+		d, _ := time.Parse(shardDayLayout, value)
+		return d
+	}
+	days := []shard{{name: "2023-01-02", day: day("2023-01-02")}, {name: "2023-01-03", day: day("2023-01-03")}}
+	cfg := SqliteConfig{ShardBy: ShardByDay}
+	assert.Equal(t, []string{"2023-01-02", "2023-01-03"}, shardNames(selectShards(cfg, days, nil, "")))
+	assert.Equal(t, []string{"2023-01-03"}, shardNames(selectShards(cfg, days, &model.Timeframe{
+		StartTime: uint64(day("2023-01-03").UnixNano()),
+	}, "")))
+	assert.Equal(t, []string{"2023-01-02"}, shardNames(selectShards(cfg, days, &model.Timeframe{
+		StartTime: uint64(day("2023-01-02").Add(time.Hour).UnixNano()),
+		EndTime:   uint64(day("2023-01-02").Add(2 * time.Hour).UnixNano()),
+	}, "")))
+
+	cfg = SqliteConfig{ShardBy: ShardByTraceId, Shards: 4}
+	traces := []shard{{name: "shard-0", index: 0}, {name: "shard-1", index: 1}, {name: "shard-2", index: 2}, {name: "shard-3", index: 3}}
+	assert.Len(t, selectShards(cfg, traces, nil, ""), 4)
+	selected := selectShards(cfg, traces, nil, "0af7651916cd43dd8448eb211c80319c")
+	assert.Len(t, selected, 1)
+	assert.Equal(t, traceShard("0AF7651916CD43DD8448EB211C80319C", 4), selected[0].index)
+}
+
+func TestFilteredTraceId(t *testing.T) {
+	assert.Equal(t, "t1", filteredTraceId([]model.SearchFilter{
+		{KeyValueFilter: &model.KeyValueFilter{Key: "span.name", Operator: spansquery.OPERATOR_EQUALS, Value: "GET /cart"}},
+		{KeyValueFilter: &model.KeyValueFilter{Key: "span.traceId", Operator: spansquery.OPERATOR_EQUALS, Value: "t1"}},
+	}))
+	assert.Empty(t, filteredTraceId([]model.SearchFilter{
+		{KeyValueFilter: &model.KeyValueFilter{Key: "span.traceId", Operator: spansquery.OPERATOR_NOT_EQUALS, Value: "t1"}},
+	}))
+}
+
+func TestShardedSearchEvents(t *testing.T) {
+	dir := t.TempDir()
+	for _, day := range []string{"1970-01-01", "1970-01-02"} {
+		db, err := sql.Open("sqlite3", filepath.Join(dir, "spans."+day+".db"))
+		assert.NoError(t, err)
+		_, err = db.Exec(eventsFixture)
+		assert.NoError(t, err)
+		assert.NoError(t, db.Close())
+	}
+	sr, err := NewSqliteSpanReader(context.Background(), zap.NewNop(), SqliteConfig{
+		Path: filepath.Join(dir, "spans.db"), ShardBy: ShardByDay,
+	})
+	assert.NoError(t, err)
+
+	// the events of the fixture are in the first day, so only its shard is queried
+	res, err := sr.SearchEvents(context.Background(), eventsquery.SearchRequest{Timeframe: model.Timeframe{EndTime: 1000}})
+	assert.NoError(t, err)
+	assert.Len(t, res.Events, 3)
+
+	res, err = sr.SearchEvents(context.Background(), eventsquery.SearchRequest{
+		Timeframe: model.Timeframe{EndTime: uint64(48 * time.Hour)},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, res.Events, 6)
+
+	res, err = sr.SearchEvents(context.Background(), eventsquery.SearchRequest{
+		Timeframe: model.Timeframe{StartTime: uint64(72 * time.Hour), EndTime: uint64(96 * time.Hour)},
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, res.Events)
+	assert.NoError(t, sr.Ping(context.Background()))
+}
+
+func TestNewShardedSpanReaderValidates(t *testing.T) {
+	_, err := NewSqliteSpanReader(context.Background(), zap.NewNop(), SqliteConfig{Path: "spans.db", ShardBy: "hour"})
+	assert.Error(t, err)
+	_, err = NewSqliteSpanReader(context.Background(), zap.NewNop(), SqliteConfig{Path: "spans.db", ShardBy: ShardByTraceId})
+	assert.Error(t, err)
+}
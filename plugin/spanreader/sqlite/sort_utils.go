@@ -17,6 +17,7 @@
 package sqlitespanreader
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -28,11 +29,14 @@ type sqliteOrder struct {
 	tableName string
 	tableKey  string
 	orderBy   string
+	// expression is the value of the sorted attribute of a span of the search query, empty for span fields
+	expression string
 }
 
 var orderSqliteFieldsMap = map[string]string{
 	"externalFields.durationNano": "span.duration",
 	"span.startTimeUnixNano":      "span.startTimeUnixNano",
+	"externalFields.childCount":   "span.childCount",
 }
 
 func newSqliteOrder(order spansquery.Sort) (sqliteOrder, error) {
@@ -46,14 +50,91 @@ func newSqliteOrder(order spansquery.Sort) (sqliteOrder, error) {
 			tableName := sqliteTableNameMap[tableKey]
 			so.tableName = tableName
 			so.tableKey = tableKey
-			so.tag = removeTablePrefixFromStaticTag(orderField)
 			so.orderBy = orderType(order)
+			if order.Field.IsAttribute() {
+				so.tag = removeTablePrefixFromDynamicTag(orderField)
+				expression, ok := attributeSortExpression(tableName, so.tag)
+				if !ok {
+					break
+				}
+				so.expression = expression
+				return so, nil
+			}
+			so.tag = removeTablePrefixFromStaticTag(orderField)
 			return so, nil
 		}
 	}
 	return so, fmt.Errorf("invalid order field: %s", order.Field)
 }
 
+// attributeSortExpression returns the value of an attribute of the span of the search query, which is NULL for spans
+// without the attribute and the first element of array attributes.
+func attributeSortExpression(tableName string, key string) (string, bool) {
+	var from string
+	switch tableName {
+	case "span_attributes":
+		from = "span_attributes a WHERE a.span_id = iq.span_id"
+	case "resource_attributes":
+		from = "span_resource_attributes sra JOIN resource_attributes a ON a.resource_id = sra.resource_attribute_id " +
+			"WHERE sra.span_id = iq.span_id"
+	case "scope_attributes":
+		from = "spans s JOIN scope_attributes a ON a.scope_id = s.instrumentation_scope_id WHERE s.span_id = iq.span_id"
+	default:
+		return "", false
+	}
+	return fmt.Sprintf("(SELECT CASE WHEN a.type = '%s' THEN json_extract(a.value, '$[0]') ELSE a.value END FROM %s AND a.key = %s LIMIT 1)",
+		SliceType, from, sqliteString(key)), true
+}
+
+// sqliteString returns s as an sqlite string literal.
+func sqliteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// attributeToken is the continuation token of a search sorted by an attribute: the sort value and ID of the last
+// span of the page, as spans may share a value or lack the attribute.
+type attributeToken struct {
+	Value  any    `json:"value"`
+	SpanId string `json:"spanId"`
+}
+
+func newAttributeToken(value any, spanId string) (spansquery.ContinuationToken, error) {
+	if b, ok := value.([]byte); ok {
+		value = string(b)
+	}
+	token, err := json.Marshal(attributeToken{Value: value, SpanId: spanId})
+	if err != nil {
+		return "", fmt.Errorf("failed to create next token: %v", err)
+	}
+	return spansquery.ContinuationToken(token), nil
+}
+
+// afterAttributeToken returns the condition of the spans following the continuation token in the order of the sort
+// expression and then the span ID, in which spans without the attribute come last.
+func afterAttributeToken(so sqliteOrder, token spansquery.ContinuationToken) (string, error) {
+	var t attributeToken
+	decoder := json.NewDecoder(strings.NewReader(string(token)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&t); err != nil {
+		return "", fmt.Errorf("invalid next token: %v", err)
+	}
+	operator := map[string]string{"ASC": ">", "DESC": "<"}[so.orderBy]
+	after := fmt.Sprintf("iq.span_id %s %s", operator, sqliteString(t.SpanId))
+	e := so.expression
+	var value string
+	switch v := t.Value.(type) {
+	case nil:
+		return fmt.Sprintf("(%s IS NULL AND %s)", e, after), nil
+	case string:
+		value = sqliteString(v)
+	case json.Number:
+		value = v.String()
+	default:
+		return "", fmt.Errorf("invalid next token value: %v", v)
+	}
+	return fmt.Sprintf("(%s %s %s OR (%s = %s AND %s) OR %s IS NULL)", e, operator, value, e, value, after, e), nil
+}
+
 func (so *sqliteOrder) getTableKey() string {
 	return so.tableKey
 }
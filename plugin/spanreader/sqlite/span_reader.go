@@ -18,11 +18,18 @@ package sqlitespanreader
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
 	"github.com/teletrace/teletrace/pkg/model/metadata/v1"
 	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/slowquery"
 	"github.com/teletrace/teletrace/pkg/spanreader"
+	"github.com/teletrace/teletrace/pkg/tracing"
 
 	internalspan "github.com/teletrace/teletrace/model/internalspan/v1"
 
@@ -32,10 +39,11 @@ import (
 )
 
 type spanReader struct {
-	cfg    SqliteConfig
-	logger *zap.Logger
-	ctx    context.Context
-	client *sqliteClient
+	cfg         SqliteConfig
+	logger      *zap.Logger
+	ctx         context.Context
+	client      *sqliteClient
+	slowQueries *slowquery.Log
 }
 
 func (sr *spanReader) Initialize() error {
@@ -45,23 +53,39 @@ func (sr *spanReader) Initialize() error {
 func (sr *spanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
 	var result spansquery.SearchResponse
 	result.Spans = make([]*internalspan.InternalSpan, 0) // can't be nil
+	_, buildSpan := tracer.Start(ctx, "sqlite.build_search_query")
 	searchQueryResponse, err := buildSearchQuery(r)
+	tracing.EndSpan(buildSpan, err)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", spanreader.ErrInvalidQuery, err)
+	}
+	ctx, cancel := spanreader.WithQueryTimeout(ctx, sr.cfg.QueryTimeout)
+	defer cancel()
+	ctx, span := startQuerySpan(ctx, searchQueryResponse.getQuery())
+	defer span.End()
+	defer sr.slowQueries.Observe(ctx, "search", searchQueryResponse.getQuery(), r.SearchFilters, time.Now())
+	if r.Debug {
+		queryPlan, err := sr.explainQueryPlan(ctx, searchQueryResponse.getQuery())
+		if err != nil {
+			return nil, tracing.RecordError(span, spanreader.QueryContextError(ctx, err))
+		}
+		result.Debug = &spansquery.DebugInfo{Query: searchQueryResponse.getQuery(), QueryPlan: queryPlan}
 	}
 	stmt, err := sr.client.db.PrepareContext(ctx, searchQueryResponse.getQuery())
 	if err != nil {
-		return nil, fmt.Errorf("failed to prepare query: %v", err)
+		return nil, tracing.RecordError(span, spanreader.QueryContextError(ctx, fmt.Errorf("failed to prepare query: %v", err)))
 	}
 	defer stmt.Close()
 	rows, err := stmt.QueryContext(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query spans: %v", err)
+		return nil, tracing.RecordError(span, spanreader.QueryContextError(ctx, fmt.Errorf("failed to query spans: %v", err)))
 	}
 	defer rows.Close()
 	var nextToken spansquery.ContinuationToken
+	var lastSortValue any
 	for rows.Next() {
 		sqliteSpan := newSqliteInternalSpan()
+		var sortValue any
 		err = rows.Scan(
 			&sqliteSpan.spanId,
 			&sqliteSpan.traceId,
@@ -79,6 +103,7 @@ func (sr *spanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*
 			&sqliteSpan.droppedLinksCount,
 			&sqliteSpan.durationNano,
 			&sqliteSpan.ingestionTimeUnixNano,
+			&sqliteSpan.childCount,
 			&sqliteSpan.spanAttributes,
 			&sqliteSpan.scopeName,
 			&sqliteSpan.scopeVersion,
@@ -87,24 +112,46 @@ func (sr *spanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*
 			&sqliteSpan.eventsAttributes,
 			&sqliteSpan.linksAttributes,
 			&sqliteSpan.resourceAttributes,
+			&sortValue,
 		)
+		// a span which can't be read is dropped rather than failing the search, and reported in the results
 		if err != nil {
 			sr.logger.Error("failed to get span value", zap.Error(err))
+			result.AddDroppedSpan(fmt.Sprintf("failed to read span: %v", err))
 			continue
 		}
 		internalSpan, err := sqliteSpan.toInternalSpan()
 		if err != nil {
 			sr.logger.Error("failed to convert span", zap.Error(err))
+			result.AddDroppedSpan(fmt.Sprintf("failed to convert span: %v", err))
 			continue
 		}
 		result.Spans = append(result.Spans, internalSpan)
+		lastSortValue = sortValue
 
 	}
+	// an interrupted statement ends the rows early, rather than failing the query
+	if err := rows.Err(); err != nil {
+		return nil, tracing.RecordError(span, spanreader.QueryContextError(ctx, fmt.Errorf("failed to read spans: %v", err)))
+	}
+	if r.Sample != nil {
+		var total uint64
+		if err := sr.client.db.QueryRowContext(ctx, searchQueryResponse.getCountQuery()).Scan(&total); err != nil {
+			return nil, tracing.RecordError(span, spanreader.QueryContextError(ctx, fmt.Errorf("failed to count spans: %v", err)))
+		}
+		result.Sample = &spansquery.SampleMetadata{EstimatedTotal: total}
+		// sampled results are unordered, so they can't be paginated
+		return &result, nil
+	}
 	if len(result.Spans) > 0 {
 		lastInternalSpanIndex := len(result.Spans) - 1
 		lastInternalSpan := result.Spans[lastInternalSpanIndex]
 		if lastInternalSpan != nil {
 			switch searchQueryResponse.getSort() {
+			case "attribute":
+				if nextToken, err = newAttributeToken(lastSortValue, lastInternalSpan.Span.SpanId); err != nil {
+					return nil, err
+				}
 			case "duration":
 				nextToken = spansquery.ContinuationToken(fmt.Sprintf("%d", lastInternalSpan.ExternalFields.DurationNano))
 			default:
@@ -118,6 +165,31 @@ func (sr *spanReader) Search(ctx context.Context, r spansquery.SearchRequest) (*
 	return &result, nil
 }
 
+// explainQueryPlan returns the steps of the plan sqlite chose for query, indented by their depth in the plan tree.
+func (sr *spanReader) explainQueryPlan(ctx context.Context, query string) ([]string, error) {
+	rows, err := sr.client.db.QueryContext(ctx, "EXPLAIN QUERY PLAN "+query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to explain query plan: %v", err)
+	}
+	defer rows.Close()
+
+	var plan []string
+	depths := make(map[int]int)
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+			return nil, fmt.Errorf("failed to read query plan: %v", err)
+		}
+		depths[id] = depths[parent] + 1
+		plan = append(plan, strings.Repeat("  ", depths[id]-1)+detail)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read query plan: %v", err)
+	}
+	return plan, nil
+}
+
 func (sr *spanReader) GetAvailableTags(ctx context.Context, r tagsquery.GetAvailableTagsRequest) (*tagsquery.GetAvailableTagsResponse, error) {
 	var tags tagsquery.GetAvailableTagsResponse
 	tag := tagsquery.TagInfo{}
@@ -126,16 +198,29 @@ func (sr *spanReader) GetAvailableTags(ctx context.Context, r tagsquery.GetAvail
 		tag.Type = fieldType
 		tags.Tags = append(tags.Tags, tag)
 	}
-	query := buildDynamicTagsQuery()
+	dynamicTagsLimit := 0
+	if r.Limit > 0 {
+		if len(tags.Tags) >= r.Limit {
+			tags.Tags = tags.Tags[:r.Limit]
+			return &tags, nil
+		}
+		dynamicTagsLimit = r.Limit - len(tags.Tags)
+	}
+	query := buildDynamicTagsQuery(dynamicTagsLimit)
 
+	ctx, cancel := spanreader.WithQueryTimeout(ctx, sr.cfg.QueryTimeout)
+	defer cancel()
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+	defer sr.slowQueries.Observe(ctx, "available_tags", query, nil, time.Now())
 	stmt, err := sr.client.db.PrepareContext(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to prepare query: %v", err)
+		return nil, tracing.RecordError(span, spanreader.QueryContextError(ctx, fmt.Errorf("failed to prepare query: %v", err)))
 	}
 	defer stmt.Close()
 	rows, err := stmt.QueryContext(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query tags: %v", err)
+		return nil, tracing.RecordError(span, spanreader.QueryContextError(ctx, fmt.Errorf("failed to query tags: %v", err)))
 	}
 	defer rows.Close()
 	for rows.Next() {
@@ -152,6 +237,9 @@ func (sr *spanReader) GetAvailableTags(ctx context.Context, r tagsquery.GetAvail
 		tag.Type = sqliteTag.getTagType()
 		tags.Tags = append(tags.Tags, tag)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, tracing.RecordError(span, spanreader.QueryContextError(ctx, fmt.Errorf("failed to read tags: %v", err)))
+	}
 	return &tags, nil
 }
 
@@ -159,6 +247,9 @@ func (sr *spanReader) GetTagsValues(ctx context.Context, r tagsquery.TagValuesRe
 	result := make(map[string]*tagsquery.TagValuesResponse)
 	for _, tag := range tags {
 		tagValueResponse, err := sr.GetTagValues(ctx, r, tag)
+		if errors.Is(err, spanreader.ErrQueryTimeout) || errors.Is(err, spanreader.ErrQueryCanceled) {
+			return nil, err
+		}
 		if err != nil {
 			sr.logger.Error("failed to get tag value", zap.Error(err))
 			continue
@@ -170,27 +261,36 @@ func (sr *spanReader) GetTagsValues(ctx context.Context, r tagsquery.TagValuesRe
 
 func (sr *spanReader) GetTagValues(ctx context.Context, r tagsquery.TagValuesRequest, tag string) (*tagsquery.TagValuesResponse, error) {
 	var currentTagValues []tagsquery.TagValueInfo
+	_, buildSpan := tracer.Start(ctx, "sqlite.build_tag_values_query")
 	tagValueQueryResponse, err := buildTagValuesQuery(r, tag)
+	tracing.EndSpan(buildSpan, err)
 	if err != nil {
 		sr.logger.Error("failed to build tag values query for: "+tag, zap.Error(err))
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", spanreader.ErrInvalidQuery, err)
 	}
+	ctx, cancel := spanreader.WithQueryTimeout(ctx, sr.cfg.QueryTimeout)
+	defer cancel()
+	ctx, span := startQuerySpan(ctx, tagValueQueryResponse.getQuery())
+	defer span.End()
+	defer sr.slowQueries.Observe(ctx, "tag_values", tagValueQueryResponse.getQuery(), r.SearchFilters, time.Now())
 	stmt, err := sr.client.db.PrepareContext(ctx, tagValueQueryResponse.getQuery())
 	if err != nil {
 		sr.logger.Error("failed to prepare query: "+tagValueQueryResponse.getQuery(), zap.Error(err))
-		return nil, err
+		return nil, tracing.RecordError(span, spanreader.QueryContextError(ctx, err))
 	}
 	defer stmt.Close()
 	rows, err := stmt.QueryContext(ctx)
 	if err != nil {
 		sr.logger.Error("failed to query tags values for: "+tag, zap.Error(err))
-		return nil, err
+		return nil, tracing.RecordError(span, spanreader.QueryContextError(ctx, err))
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var name any
 		var count int
-		err = rows.Scan(&name, &count)
+		var valueType sql.NullString
+		var lastSeen sql.NullInt64
+		err = rows.Scan(&name, &count, &valueType, &lastSeen)
 		if err != nil {
 			sr.logger.Error("failed to get tag value", zap.Error(err))
 			continue
@@ -199,16 +299,266 @@ func (sr *spanReader) GetTagValues(ctx context.Context, r tagsquery.TagValuesReq
 			continue
 		}
 		currentTagValues = append(currentTagValues, tagsquery.TagValueInfo{
-			Value: name,
-			Count: count,
+			Value:            decodeTagValue(name, valueType.String),
+			Count:            count,
+			LastSeenUnixNano: uint64(lastSeen.Int64),
 		})
 	}
+	if err := rows.Err(); err != nil {
+		return nil, tracing.RecordError(span, spanreader.QueryContextError(ctx, fmt.Errorf("failed to read tags values: %v", err)))
+	}
 
 	return &tagsquery.TagValuesResponse{
 		Values: currentTagValues,
 	}, nil
 }
 
+func (sr *spanReader) SearchEvents(ctx context.Context, r eventsquery.SearchRequest) (*eventsquery.SearchResponse, error) {
+	result := eventsquery.SearchResponse{Events: make([]eventsquery.Event, 0)}
+	query, args, err := buildSearchEventsQuery(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", spanreader.ErrInvalidQuery, err)
+	}
+	ctx, cancel := spanreader.WithQueryTimeout(ctx, sr.cfg.QueryTimeout)
+	defer cancel()
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+	defer sr.slowQueries.Observe(ctx, "search_events", query, r.SearchFilters, time.Now())
+	rows, err := sr.client.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, tracing.RecordError(span, spanreader.QueryContextError(ctx, fmt.Errorf("failed to query events: %v", err)))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var sqliteEvent sqliteEvent
+		err = rows.Scan(
+			&sqliteEvent.spanId,
+			&sqliteEvent.traceId,
+			&sqliteEvent.spanName,
+			&sqliteEvent.name,
+			&sqliteEvent.timeUnixNano,
+			&sqliteEvent.attributes,
+			&sqliteEvent.serviceName,
+		)
+		if err != nil {
+			sr.logger.Error("failed to get event value", zap.Error(err))
+			continue
+		}
+		event, err := sqliteEvent.toEvent()
+		if err != nil {
+			sr.logger.Error("failed to convert event", zap.Error(err))
+			continue
+		}
+		result.Events = append(result.Events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, tracing.RecordError(span, spanreader.QueryContextError(ctx, fmt.Errorf("failed to read events: %v", err)))
+	}
+	return &result, nil
+}
+
+// SearchTraces queries the traces of the matching spans, grouped by trace in the query, and then their spans.
+func (sr *spanReader) SearchTraces(ctx context.Context, r spansquery.SearchTracesRequest) (*spansquery.SearchTracesResponse, error) {
+	limit := r.EffectiveLimit()
+	traces, err := sr.matchingTraces(ctx, r, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	result := spansquery.SearchTracesResponse{Metadata: &spansquery.Metadata{}}
+	if len(traces) > limit {
+		traces = traces[:limit]
+		result.Metadata.NextToken = tracesToken(traces[limit-1])
+	}
+	result.Traces = traces
+	result.SpansTruncated, err = spanreader.AddTracesSpans(ctx, sr, traces)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// matchingTraces returns the traces having spans matching r, up to limit, without their spans.
+func (sr *spanReader) matchingTraces(ctx context.Context, r spansquery.SearchTracesRequest, limit int) ([]*spansquery.TraceResult, error) {
+	query, args, err := buildMatchingTracesQuery(r, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", spanreader.ErrInvalidQuery, err)
+	}
+	ctx, cancel := spanreader.WithQueryTimeout(ctx, sr.cfg.QueryTimeout)
+	defer cancel()
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+	defer sr.slowQueries.Observe(ctx, "search_traces", query, r.SearchFilters, time.Now())
+	rows, err := sr.client.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, tracing.RecordError(span, spanreader.QueryContextError(ctx, fmt.Errorf("failed to query traces: %v", err)))
+	}
+	defer rows.Close()
+	traces := make([]*spansquery.TraceResult, 0)
+	for rows.Next() {
+		var sqliteTrace sqliteTrace
+		if err := rows.Scan(&sqliteTrace.traceId, &sqliteTrace.latest); err != nil {
+			sr.logger.Error("failed to get trace value", zap.Error(err))
+			continue
+		}
+		traces = append(traces, sqliteTrace.toTraceResult())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, tracing.RecordError(span, spanreader.QueryContextError(ctx, fmt.Errorf("failed to read traces: %v", err)))
+	}
+	return traces, nil
+}
+
+// Aggregate groups the matching spans and computes the metrics of the groups in the query, and the percentiles with
+// a query per field of the values they're interpolated between. Groups by, or metrics of, tags other than the columns
+// of the spans table and the attributes of the spans and their resources are aggregated in memory, and so are
+// bucketed aggregations, as sqlite has no timezone database to align the buckets with the local hours and days.
+func (sr *spanReader) Aggregate(ctx context.Context, r spansquery.SearchRequest) (*spansquery.SearchResponse, error) {
+	if r.Aggregations.Interval != "" {
+		return spanreader.AggregateInSpans(ctx, sr, r)
+	}
+	query, ok, err := buildAggregationsQuery(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", spanreader.ErrInvalidQuery, err)
+	}
+	if !ok {
+		return spanreader.AggregateInSpans(ctx, sr, r)
+	}
+	ctx, cancel := spanreader.WithQueryTimeout(ctx, sr.cfg.QueryTimeout)
+	defer cancel()
+
+	keys, groups, err := sr.aggregationGroups(ctx, r, query)
+	if err != nil {
+		return nil, err
+	}
+	percentiles := make(map[string][]spansquery.Metric)
+	var fields []string
+	for _, m := range r.Aggregations.Metrics {
+		if _, ok := spansquery.AggregationPercentiles[m.Function]; ok {
+			if _, ok := percentiles[m.Field]; !ok {
+				fields = append(fields, m.Field)
+			}
+			percentiles[m.Field] = append(percentiles[m.Field], m)
+		}
+	}
+	for _, field := range fields {
+		if err := sr.aggregationPercentiles(ctx, r, field, percentiles[field], groups); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]spansquery.AggregationGroup, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *groups[key])
+	}
+	return &spansquery.SearchResponse{
+		Metadata:     &spansquery.Metadata{},
+		Spans:        make([]*internalspan.InternalSpan, 0),
+		Aggregations: &spansquery.AggregationsResult{Groups: result},
+	}, nil
+}
+
+// aggregationGroups runs the query of the groups of r, returning the keys of the groups in order and the groups by key.
+func (sr *spanReader) aggregationGroups(
+	ctx context.Context, r spansquery.SearchRequest, query string,
+) ([]string, map[string]*spansquery.AggregationGroup, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+	defer sr.slowQueries.Observe(ctx, "aggregate", query, r.SearchFilters, time.Now())
+	rows, err := sr.client.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, nil, tracing.RecordError(span, spanreader.QueryContextError(ctx, fmt.Errorf("failed to query aggregations: %v", err)))
+	}
+	defer rows.Close()
+
+	var keys []string
+	groups := make(map[string]*spansquery.AggregationGroup)
+	for rows.Next() {
+		var value, valueType any
+		var count uint64
+		dest := []any{&value, &valueType, &count}
+		var metrics []spansquery.Metric
+		for _, m := range r.Aggregations.Metrics {
+			switch m.Function {
+			case spansquery.AGGREGATION_SUM, spansquery.AGGREGATION_AVG, spansquery.AGGREGATION_MIN, spansquery.AGGREGATION_MAX:
+				metrics = append(metrics, m)
+				dest = append(dest, new(sql.NullFloat64))
+			}
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, nil, tracing.RecordError(span, fmt.Errorf("failed to scan aggregations: %v", err))
+		}
+		group := &spansquery.AggregationGroup{Value: aggregationGroupValue(value, valueType), Count: count, Metrics: make(map[string]float64)}
+		for _, m := range r.Aggregations.Metrics {
+			if m.Function == spansquery.AGGREGATION_COUNT {
+				group.Metrics[m.Name()] = float64(count)
+			}
+		}
+		for i, m := range metrics {
+			if v := dest[3+i].(*sql.NullFloat64); v.Valid {
+				group.Metrics[m.Name()] = v.Float64
+			}
+		}
+		key := aggregationGroupKey(value, valueType)
+		keys = append(keys, key)
+		groups[key] = group
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, tracing.RecordError(span, spanreader.QueryContextError(ctx, fmt.Errorf("failed to read aggregations: %v", err)))
+	}
+	return keys, groups, nil
+}
+
+// aggregationPercentiles runs the query of the percentile metrics of field of r, setting them in the groups.
+func (sr *spanReader) aggregationPercentiles(
+	ctx context.Context, r spansquery.SearchRequest, field string, metrics []spansquery.Metric, groups map[string]*spansquery.AggregationGroup,
+) error {
+	ps := make([]float64, 0, len(metrics))
+	for _, m := range metrics {
+		ps = append(ps, spansquery.AggregationPercentiles[m.Function])
+	}
+	query, err := buildAggregationPercentilesQuery(r, field, ps)
+	if err != nil {
+		return fmt.Errorf("%w: %v", spanreader.ErrInvalidQuery, err)
+	}
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+	defer sr.slowQueries.Observe(ctx, "aggregate_percentiles", query, r.SearchFilters, time.Now())
+	rows, err := sr.client.db.QueryContext(ctx, query)
+	if err != nil {
+		return tracing.RecordError(span, spanreader.QueryContextError(ctx, fmt.Errorf("failed to query percentiles: %v", err)))
+	}
+	defer rows.Close()
+
+	values := make(map[string]map[int64]float64)
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var value, valueType any
+		var rank, n int64
+		var fieldValue float64
+		if err := rows.Scan(&value, &valueType, &rank, &n, &fieldValue); err != nil {
+			return tracing.RecordError(span, fmt.Errorf("failed to scan percentiles: %v", err))
+		}
+		key := aggregationGroupKey(value, valueType)
+		if _, ok := groups[key]; !ok {
+			continue
+		}
+		if values[key] == nil {
+			values[key] = make(map[int64]float64)
+		}
+		values[key][rank] = fieldValue
+		counts[key] = n
+	}
+	if err := rows.Err(); err != nil {
+		return tracing.RecordError(span, spanreader.QueryContextError(ctx, fmt.Errorf("failed to read percentiles: %v", err)))
+	}
+	for key, groupValues := range values {
+		for _, m := range metrics {
+			groups[key].Metrics[m.Name()] = interpolatePercentile(groupValues, counts[key], spansquery.AggregationPercentiles[m.Function])
+		}
+	}
+	return nil
+}
+
 func (sr *spanReader) GetSystemId(ctx context.Context, r metadata.GetSystemIdRequest) (*metadata.GetSystemIdResponse, error) {
 	return nil, fmt.Errorf("Not implemented method")
 }
@@ -217,22 +567,52 @@ func (sr *spanReader) SetSystemId(ctx context.Context, r metadata.SetSystemIdReq
 	return nil, fmt.Errorf("Not implemented method")
 }
 
+// Ping runs a test query against the spans table, failing if the database can't be read or isn't migrated.
+func (sr *spanReader) Ping(ctx context.Context) error {
+	var one int
+	err := sr.client.db.QueryRowContext(ctx, "SELECT 1 FROM spans LIMIT 1").Scan(&one)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to query spans: %w", err)
+	}
+	return nil
+}
+
 func (sr *spanReader) GetTagsStatistics(
 	ctx context.Context, r tagsquery.TagStatisticsRequest, tag string,
 ) (*tagsquery.TagStatisticsResponse, error) {
 	return nil, fmt.Errorf("GetTagsStatistics is not yet implemented for sqlite plugin")
 }
 
+func (sr *spanReader) GetTagsStatisticsBatch(
+	ctx context.Context, r tagsquery.TagStatisticsRequest, tags []string,
+) (map[string]*tagsquery.TagStatisticsResponse, error) {
+	return nil, fmt.Errorf("GetTagsStatisticsBatch is not yet implemented for sqlite plugin")
+}
+
+// NewSqliteSpanReader returns a span reader of the database at the path of cfg, or of its shards if sharded.
 func NewSqliteSpanReader(ctx context.Context, logger *zap.Logger, cfg SqliteConfig) (spanreader.SpanReader, error) {
+	if cfg.ShardBy != "" {
+		return newShardedSpanReader(ctx, logger, cfg)
+	}
+	return newSpanReader(ctx, logger, cfg)
+}
+
+func newSpanReader(ctx context.Context, logger *zap.Logger, cfg SqliteConfig) (*spanReader, error) {
 	client, err := newSqliteClient(logger, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create a new span reader for sqlite: %w", err)
 	}
 
+	slowQueries, err := slowquery.NewLog(logger, cfg.SlowQueryThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create a new span reader for sqlite: %w", err)
+	}
+
 	return &spanReader{
-		cfg:    cfg,
-		logger: logger,
-		ctx:    ctx,
-		client: client,
+		cfg:         cfg,
+		logger:      logger,
+		ctx:         ctx,
+		client:      client,
+		slowQueries: slowQueries,
 	}, nil
 }
@@ -0,0 +1,481 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package sqlitespanreader
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/teletrace/teletrace/pkg/model"
+	eventsquery "github.com/teletrace/teletrace/pkg/model/eventsquery/v1"
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+	"github.com/teletrace/teletrace/pkg/model/tagsquery/v1"
+	"github.com/teletrace/teletrace/pkg/spanreader"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestExplainQueryPlan(t *testing.T) {
+	client, err := newSqliteClient(zap.NewNop(), SqliteConfig{Path: ":memory:"})
+	assert.NoError(t, err)
+	defer client.db.Close()
+	_, err = client.db.Exec("CREATE TABLE spans (span_id TEXT PRIMARY KEY, name TEXT)")
+	assert.NoError(t, err)
+	sr := &spanReader{client: client, logger: zap.NewNop()}
+
+	plan, err := sr.explainQueryPlan(context.Background(), "SELECT * FROM spans WHERE span_id = 'a' OR name IN (SELECT name FROM spans)")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, plan)
+	assert.Contains(t, plan[0], "spans")
+
+	_, err = sr.explainQueryPlan(context.Background(), "SELECT * FROM missing")
+	assert.Error(t, err)
+}
+
+const eventsFixture = `
+CREATE TABLE spans (span_id TEXT PRIMARY KEY, trace_id TEXT, name TEXT, start_time_unix_nano INTEGER, end_time_unix_nano INTEGER);
+CREATE TABLE resource_attributes (resource_id TEXT PRIMARY KEY, key TEXT, value BLOB, type TEXT);
+CREATE TABLE span_resource_attributes (id INTEGER PRIMARY KEY AUTOINCREMENT, span_id TEXT, resource_attribute_id TEXT);
+CREATE TABLE events (id INTEGER PRIMARY KEY AUTOINCREMENT, span_id TEXT, time_unix_nano INTEGER, name TEXT, dropped_attributes_count INTEGER);
+CREATE TABLE event_attributes (event_id INTEGER, key TEXT, value BLOB, type TEXT);
+INSERT INTO spans (span_id, trace_id, name, start_time_unix_nano, end_time_unix_nano) VALUES
+	('s1', 't1', 'GET /cart', 100, 200), ('s2', 't2', 'POST /pay', 300, 400), ('s3', 't3', 'GET /cart', 500, 600);
+INSERT INTO resource_attributes (resource_id, key, value, type) VALUES ('r1', 'service.name', 'cart', 'str');
+INSERT INTO span_resource_attributes (span_id, resource_attribute_id) VALUES ('s1', 'r1'), ('s3', 'r1');
+INSERT INTO events (id, span_id, time_unix_nano, name, dropped_attributes_count) VALUES
+	(1, 's1', 150, 'exception', 0), (2, 's2', 350, 'exception', 0), (3, 's3', 550, 'cache.miss', 0), (4, 's3', NULL, NULL, NULL);
+INSERT INTO event_attributes (event_id, key, value, type) VALUES
+	(1, 'exception.type', 'TimeoutError', 'str'), (1, 'retries', 3, 'int'), (2, 'exception.type', 'ValueError', 'str'), (4, NULL, NULL, NULL);
+`
+
+func TestSearchEvents(t *testing.T) {
+	client, err := newSqliteClient(zap.NewNop(), SqliteConfig{Path: ":memory:"})
+	assert.NoError(t, err)
+	defer client.db.Close()
+	client.db.SetMaxOpenConns(1) // every connection has its own in-memory database
+	_, err = client.db.Exec(eventsFixture)
+	assert.NoError(t, err)
+	sr := &spanReader{client: client, logger: zap.NewNop()}
+
+	res, err := sr.SearchEvents(context.Background(), eventsquery.SearchRequest{Timeframe: model.Timeframe{EndTime: 1000}})
+	assert.NoError(t, err)
+	assert.Len(t, res.Events, 3)
+	assert.Equal(t, "cache.miss", res.Events[0].Name)
+
+	res, err = sr.SearchEvents(context.Background(), eventsquery.SearchRequest{
+		Timeframe:  model.Timeframe{EndTime: 1000},
+		Name:       "exception",
+		Attributes: map[string]any{"exception.type": "TimeoutError", "retries": float64(3)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []eventsquery.Event{{
+		TraceId:      "t1",
+		SpanId:       "s1",
+		SpanName:     "GET /cart",
+		ServiceName:  "cart",
+		Name:         "exception",
+		TimeUnixNano: 150,
+		Attributes:   map[string]any{"exception.type": "TimeoutError", "retries": float64(3)},
+	}}, res.Events)
+
+	res, err = sr.SearchEvents(context.Background(), eventsquery.SearchRequest{
+		Timeframe: model.Timeframe{StartTime: 300, EndTime: 1000},
+		SearchFilters: []model.SearchFilter{{KeyValueFilter: &model.KeyValueFilter{
+			Key: "span.name", Operator: "equals", Value: "GET /cart",
+		}}},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, res.Events, 1)
+	assert.Equal(t, "s3", res.Events[0].SpanId)
+}
+
+func TestGetTagValuesSort(t *testing.T) {
+	client, err := newSqliteClient(zap.NewNop(), SqliteConfig{Path: ":memory:"})
+	assert.NoError(t, err)
+	defer client.db.Close()
+	client.db.SetMaxOpenConns(1)
+	_, err = client.db.Exec(eventsFixture)
+	assert.NoError(t, err)
+	sr := &spanReader{client: client, logger: zap.NewNop()}
+
+	for _, test := range []struct {
+		r        tagsquery.TagValuesRequest
+		expected []tagsquery.TagValueInfo
+	}{
+		{
+			r:        tagsquery.TagValuesRequest{},
+			expected: []tagsquery.TagValueInfo{{Value: "GET /cart", Count: 2}, {Value: "POST /pay", Count: 1}},
+		},
+		{
+			r:        tagsquery.TagValuesRequest{SortBy: tagsquery.SORT_BY_VALUE, SortOrder: tagsquery.SORT_ORDER_DESC},
+			expected: []tagsquery.TagValueInfo{{Value: "POST /pay", Count: 1}, {Value: "GET /cart", Count: 2}},
+		},
+		{
+			r: tagsquery.TagValuesRequest{SortBy: tagsquery.SORT_BY_LAST_SEEN, SortOrder: tagsquery.SORT_ORDER_ASC},
+			expected: []tagsquery.TagValueInfo{
+				{Value: "POST /pay", Count: 1, LastSeenUnixNano: 300}, {Value: "GET /cart", Count: 2, LastSeenUnixNano: 500},
+			},
+		},
+	} {
+		res, err := sr.GetTagValues(context.Background(), test.r, "span.name")
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, res.Values)
+	}
+}
+
+func TestMatchingTraces(t *testing.T) {
+	client, err := newSqliteClient(zap.NewNop(), SqliteConfig{Path: ":memory:"})
+	assert.NoError(t, err)
+	defer client.db.Close()
+	client.db.SetMaxOpenConns(1)
+	_, err = client.db.Exec(eventsFixture + "INSERT INTO spans VALUES ('s4', 't1', 'POST /pay', 700, 800);")
+	assert.NoError(t, err)
+	sr := &spanReader{client: client, logger: zap.NewNop()}
+
+	r := spansquery.SearchTracesRequest{
+		Timeframe: model.Timeframe{EndTime: 1000},
+		SearchFilters: []model.SearchFilter{{KeyValueFilter: &model.KeyValueFilter{
+			Key: "span.name", Operator: "equals", Value: "POST /pay",
+		}}},
+	}
+	traces, err := sr.matchingTraces(context.Background(), r, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []*spansquery.TraceResult{{TraceId: "t1", LatestMatchTimeUnixNano: 700}}, traces)
+
+	r.Metadata = &spansquery.Metadata{NextToken: tracesToken(traces[0])}
+	traces, err = sr.matchingTraces(context.Background(), r, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []*spansquery.TraceResult{{TraceId: "t2", LatestMatchTimeUnixNano: 300}}, traces)
+
+	r.Metadata = &spansquery.Metadata{NextToken: "invalid"}
+	_, err = sr.matchingTraces(context.Background(), r, 2)
+	assert.Error(t, err)
+}
+
+const attributeSortFixture = `
+CREATE TABLE spans (span_id TEXT PRIMARY KEY, trace_id TEXT, trace_state TEXT, parent_span_id TEXT, name TEXT, kind TEXT,
+	start_time_unix_nano INTEGER, end_time_unix_nano INTEGER, dropped_span_attributes_count INTEGER, span_status_message TEXT,
+	span_status_code TEXT, dropped_resource_attributes_count INTEGER, dropped_events_count INTEGER, dropped_links_count INTEGER,
+	duration INTEGER, ingestion_time_unix_nano INTEGER, child_count INTEGER, instrumentation_scope_id INTEGER);
+CREATE TABLE span_attributes (span_id TEXT, key TEXT, value BLOB, type TEXT);
+INSERT INTO spans (span_id, start_time_unix_nano, end_time_unix_nano) VALUES
+	('s1', 100, 200), ('s2', 300, 400), ('s3', 500, 600), ('s4', 700, 800);
+INSERT INTO span_attributes (span_id, key, value, type) VALUES
+	('s1', 'http.status_code', 200, 'Int'), ('s2', 'http.status_code', 500, 'Int'), ('s3', 'http.status_code', 200, 'Int');
+`
+
+func TestSearchSortedByAttribute(t *testing.T) {
+	client, err := newSqliteClient(zap.NewNop(), SqliteConfig{Path: ":memory:"})
+	assert.NoError(t, err)
+	defer client.db.Close()
+	client.db.SetMaxOpenConns(1)
+	_, err = client.db.Exec(attributeSortFixture)
+	assert.NoError(t, err)
+	sr := &spanReader{client: client, logger: zap.NewNop()}
+
+	for ascending, expected := range map[bool][]string{
+		false: {"s2", "s3", "s1", "s4"},
+		true:  {"s1", "s3", "s2", "s4"},
+	} {
+		r := spansquery.SearchRequest{
+			Timeframe: model.Timeframe{EndTime: 1000},
+			Sort:      []spansquery.Sort{{Field: "span.attributes.http.status_code", Ascending: ascending}},
+			Metadata:  &spansquery.Metadata{},
+			Limit:     2,
+			Fields:    []spansquery.FieldGroup{},
+		}
+		var spanIds []string
+		for {
+			res, err := sr.Search(context.Background(), r)
+			assert.NoError(t, err)
+			if len(res.Spans) == 0 {
+				break
+			}
+			for _, span := range res.Spans {
+				spanIds = append(spanIds, span.Span.SpanId)
+			}
+			r.Metadata = res.Metadata
+		}
+		assert.Equal(t, expected, spanIds)
+	}
+}
+
+func TestSearchReportsDroppedSpans(t *testing.T) {
+	client, err := newSqliteClient(zap.NewNop(), SqliteConfig{Path: ":memory:"})
+	assert.NoError(t, err)
+	defer client.db.Close()
+	client.db.SetMaxOpenConns(1)
+	_, err = client.db.Exec(attributeSortFixture)
+	assert.NoError(t, err)
+	// a duration which isn't a number fails to be read
+	_, err = client.db.Exec("UPDATE spans SET duration = 'slow' WHERE span_id = 's2'")
+	assert.NoError(t, err)
+	sr := &spanReader{client: client, logger: zap.NewNop()}
+
+	res, err := sr.Search(context.Background(), spansquery.SearchRequest{
+		Timeframe: model.Timeframe{EndTime: 1000},
+		Metadata:  &spansquery.Metadata{},
+		Fields:    []spansquery.FieldGroup{},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, res.Spans, 3)
+	assert.Equal(t, 1, res.Partial.DroppedSpans)
+	assert.Len(t, res.Partial.Reasons, 1)
+	assert.Contains(t, res.Partial.Reasons[0], "failed to read span")
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	dbPath, backupPath := filepath.Join(dir, "spans.db"), filepath.Join(dir, "backup.db")
+	db, err := sql.Open("sqlite3", dbPath+"?_journal_mode=WAL")
+	assert.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec("CREATE TABLE spans (span_id TEXT PRIMARY KEY); INSERT INTO spans VALUES ('s1')")
+	assert.NoError(t, err)
+	countSpans := func(db *sql.DB) int {
+		var count int
+		assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM spans").Scan(&count))
+		return count
+	}
+
+	assert.NoError(t, Backup(context.Background(), dbPath, backupPath))
+	assert.Error(t, Backup(context.Background(), dbPath, backupPath))
+	backupDb, err := sql.Open("sqlite3", backupPath)
+	assert.NoError(t, err)
+	defer backupDb.Close()
+	assert.Equal(t, 1, countSpans(backupDb))
+
+	_, err = db.Exec("INSERT INTO spans VALUES ('s2')")
+	assert.NoError(t, err)
+	assert.NoError(t, Restore(context.Background(), backupPath, dbPath))
+	assert.Equal(t, 1, countSpans(db))
+
+	otherPath := filepath.Join(dir, "other.db")
+	otherDb, err := sql.Open("sqlite3", otherPath)
+	assert.NoError(t, err)
+	defer otherDb.Close()
+	_, err = otherDb.Exec("CREATE TABLE settings (key TEXT)")
+	assert.NoError(t, err)
+	assert.Error(t, Restore(context.Background(), otherPath, dbPath))
+	assert.Error(t, Restore(context.Background(), filepath.Join(dir, "missing.db"), dbPath))
+}
+
+// searchSpanIds returns the IDs of the spans matching a filter.
+func searchSpanIds(t *testing.T, db *sql.DB, filter model.SearchFilter) []string {
+	filters := createTimeframeFilters(model.Timeframe{StartTime: 0, EndTime: 1000})
+	filters = append(filters, convertFiltersValues([]model.SearchFilter{filter})...)
+	sqb := newSubQueryBuilder("spans")
+	assert.NoError(t, sqb.addFiltersToSubQuery(filters))
+	query, err := sqb.buildSubQuery()
+	assert.NoError(t, err)
+	rows, err := db.Query(fmt.Sprintf("SELECT DISTINCT span_id FROM (%s) ORDER BY span_id", query))
+	assert.NoError(t, err)
+	defer rows.Close()
+	var spanIds []string
+	for rows.Next() {
+		var spanId string
+		assert.NoError(t, rows.Scan(&spanId))
+		spanIds = append(spanIds, spanId)
+	}
+	return spanIds
+}
+
+const nestedAttributesFixture = `
+CREATE TABLE spans (span_id TEXT PRIMARY KEY, start_time_unix_nano INTEGER, end_time_unix_nano INTEGER);
+CREATE TABLE span_attributes (span_id TEXT, key TEXT, value BLOB, type TEXT);
+INSERT INTO spans (span_id, start_time_unix_nano, end_time_unix_nano) VALUES ('s1', 100, 200), ('s2', 300, 400), ('s3', 500, 600);
+INSERT INTO span_attributes (span_id, key, value, type) VALUES
+	('s1', 'tags', '["a","b"]', 'Slice'), ('s2', 'tags', '["b","c"]', 'Slice'), ('s3', 'tags', 'd', 'Str'),
+	('s3', 'http.headers', '{"accept":"text/html"}', 'Map');
+`
+
+func TestNestedAttributes(t *testing.T) {
+	client, err := newSqliteClient(zap.NewNop(), SqliteConfig{Path: ":memory:"})
+	assert.NoError(t, err)
+	defer client.db.Close()
+	client.db.SetMaxOpenConns(1) // every connection has its own in-memory database
+	_, err = client.db.Exec(nestedAttributesFixture)
+	assert.NoError(t, err)
+	sr := &spanReader{client: client, logger: zap.NewNop()}
+
+	search := func(key string, operator model.FilterOperator, value model.FilterValue) []string {
+		return searchSpanIds(t, client.db, newSearchFilter(key, operator, value))
+	}
+
+	assert.Equal(t, []string{"s1", "s2"}, search("span.attributes.tags", spansquery.OPERATOR_EQUALS, "b"))
+	assert.Equal(t, []string{"s1", "s3"}, search("span.attributes.tags", spansquery.OPERATOR_IN, []any{"a", "d"}))
+	assert.Equal(t, []string{"s2", "s3"}, search("span.attributes.tags", spansquery.OPERATOR_NOT_EQUALS, "a"))
+	assert.Equal(t, []string{"s3"}, search("span.attributes.http.headers", spansquery.OPERATOR_EQUALS, "text/html"))
+
+	res, err := sr.GetTagValues(context.Background(), tagsquery.TagValuesRequest{}, "span.attributes.tags")
+	assert.NoError(t, err)
+	counts := map[any]int{}
+	for _, v := range res.Values {
+		counts[v.Value] = v.Count
+	}
+	assert.Equal(t, map[any]int{"a": 1, "b": 2, "c": 1, "d": 1}, counts)
+
+	attributes, _, err := jsonToAttributesMap(`[{"key": "tags", "value": "[\"a\",1]", "type": "Slice"}, {"key": "tag", "value": "[a]", "type": "Str"}]`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"tags": []any{"a", float64(1)}, "tag": "[a]"}, attributes)
+}
+
+const boolAttributesFixture = `
+CREATE TABLE spans (span_id TEXT PRIMARY KEY, start_time_unix_nano INTEGER, end_time_unix_nano INTEGER);
+CREATE TABLE span_attributes (span_id TEXT, key TEXT, value BLOB, type TEXT);
+INSERT INTO spans (span_id, start_time_unix_nano, end_time_unix_nano) VALUES ('s1', 100, 200), ('s2', 300, 400);
+INSERT INTO span_attributes (span_id, key, value, type) VALUES
+	('s1', 'cached', 1, 'Bool'), ('s2', 'cached', 0, 'Bool'), ('s1', 'retries', 2, 'Int'), ('s2', 'retries', 2, 'Int');
+`
+
+func TestBoolAttributes(t *testing.T) {
+	client, err := newSqliteClient(zap.NewNop(), SqliteConfig{Path: ":memory:"})
+	assert.NoError(t, err)
+	defer client.db.Close()
+	client.db.SetMaxOpenConns(1) // every connection has its own in-memory database
+	_, err = client.db.Exec(boolAttributesFixture)
+	assert.NoError(t, err)
+	sr := &spanReader{client: client, logger: zap.NewNop()}
+
+	res, err := sr.GetTagValues(context.Background(), tagsquery.TagValuesRequest{}, "span.attributes.cached")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []tagsquery.TagValueInfo{{Value: true, Count: 1}, {Value: false, Count: 1}}, res.Values)
+	res, err = sr.GetTagValues(context.Background(), tagsquery.TagValuesRequest{}, "span.attributes.retries")
+	assert.NoError(t, err)
+	assert.Equal(t, []tagsquery.TagValueInfo{{Value: int64(2), Count: 2}}, res.Values)
+
+	assert.Equal(t, []string{"s1"}, searchSpanIds(t, client.db, newSearchFilter("span.attributes.cached", spansquery.OPERATOR_EQUALS, true)))
+	assert.Equal(t, []string{"s2"}, searchSpanIds(t, client.db, newSearchFilter("span.attributes.cached", spansquery.OPERATOR_IN, []any{false})))
+
+	attributes, _, err := jsonToAttributesMap(`[{"key": "cached", "value": 1, "type": "Bool"}, {"key": "retries", "value": 1, "type": "Int"}]`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"cached": true, "retries": float64(1)}, attributes)
+}
+
+const numericAttributesFixture = `
+CREATE TABLE spans (span_id TEXT PRIMARY KEY, start_time_unix_nano INTEGER, end_time_unix_nano INTEGER);
+CREATE TABLE span_attributes (span_id TEXT, key TEXT, value BLOB, type TEXT);
+INSERT INTO spans (span_id, start_time_unix_nano, end_time_unix_nano) VALUES ('s1', 100, 200), ('s2', 300, 400), ('s3', 500, 600), ('s4', 700, 800);
+INSERT INTO span_attributes (span_id, key, value, type) VALUES
+	('s1', 'code', 503, 'Int'), ('s2', 'code', '600', 'Str'), ('s3', 'code', '[200,"x"]', 'Slice'), ('s4', 'code', '[100,501.5]', 'Slice');
+`
+
+func TestNumericRangeAttributes(t *testing.T) {
+	client, err := newSqliteClient(zap.NewNop(), SqliteConfig{Path: ":memory:"})
+	assert.NoError(t, err)
+	defer client.db.Close()
+	client.db.SetMaxOpenConns(1) // every connection has its own in-memory database
+	_, err = client.db.Exec(numericAttributesFixture)
+	assert.NoError(t, err)
+
+	// strings aren't compared to numbers, even in arrays
+	assert.Equal(t, []string{"s1", "s4"}, searchSpanIds(t, client.db, newSearchFilter("span.attributes.code", spansquery.OPERATOR_GTE, float64(500))))
+	assert.Equal(t, []string{"s3", "s4"}, searchSpanIds(t, client.db, newSearchFilter("span.attributes.code", spansquery.OPERATOR_LT, int64(300))))
+}
+
+const aggregationsFixture = `
+CREATE TABLE scopes (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, version TEXT, dropped_attributes_count INTEGER);
+CREATE TABLE scope_attributes (scope_id INTEGER, key TEXT, value BLOB, type TEXT);
+CREATE TABLE spans (span_id TEXT PRIMARY KEY, trace_id TEXT, trace_state TEXT, parent_span_id TEXT, name TEXT, kind TEXT,
+	start_time_unix_nano INTEGER, end_time_unix_nano INTEGER, dropped_span_attributes_count INTEGER, span_status_message TEXT,
+	span_status_code TEXT, dropped_resource_attributes_count INTEGER, dropped_events_count INTEGER, dropped_links_count INTEGER,
+	duration INTEGER, ingestion_time_unix_nano INTEGER, child_count INTEGER, instrumentation_scope_id INTEGER);
+CREATE TABLE span_attributes (span_id TEXT, key TEXT, value BLOB, type TEXT);
+CREATE TABLE resource_attributes (resource_id TEXT PRIMARY KEY, key TEXT, value BLOB, type TEXT);
+CREATE TABLE span_resource_attributes (id INTEGER PRIMARY KEY AUTOINCREMENT, span_id TEXT, resource_attribute_id TEXT);
+CREATE TABLE events (id INTEGER PRIMARY KEY AUTOINCREMENT, span_id TEXT, time_unix_nano INTEGER, name TEXT, dropped_attributes_count INTEGER);
+CREATE TABLE event_attributes (event_id INTEGER, key TEXT, value BLOB, type TEXT);
+CREATE TABLE links (id INTEGER PRIMARY KEY AUTOINCREMENT, span_id TEXT, linked_trace_id TEXT, linked_span_id TEXT,
+	trace_state TEXT, dropped_attributes_count INTEGER);
+CREATE TABLE link_attributes (link_id INTEGER, key TEXT, value BLOB, type TEXT);
+INSERT INTO scopes (id, name, version, dropped_attributes_count) VALUES (1, 'scope', '1', 0);
+INSERT INTO spans (span_id, trace_id, trace_state, parent_span_id, name, kind, start_time_unix_nano, end_time_unix_nano,
+	dropped_span_attributes_count, span_status_message, span_status_code, dropped_resource_attributes_count,
+	dropped_events_count, dropped_links_count, duration, ingestion_time_unix_nano, child_count, instrumentation_scope_id)
+VALUES
+	('s1', 't1', '', '', 'GET /cart', 'Server', 100, 110, 0, '', 'Unset', 0, 0, 0, 10, 0, 0, 1),
+	('s2', 't2', '', '', 'GET /cart', 'Server', 200, 220, 0, '', 'Unset', 0, 0, 0, 20, 0, 0, 1),
+	('s3', 't3', '', '', 'POST /pay', 'Server', 300, 330, 0, '', 'Unset', 0, 0, 0, 30, 0, 0, 1),
+	('s4', 't4', '', '', 'GET /cart', 'Server', 400, 440, 0, '', 'Unset', 0, 0, 0, 40, 0, 0, 1),
+	('s5', 't5', '', '', 'POST /pay', 'Server', 500, 550, 0, '', 'Unset', 0, 0, 0, 50, 0, 0, 1);
+INSERT INTO span_attributes (span_id, key, value, type) VALUES
+	('s1', 'http.status_code', 200, 'Int'), ('s2', 'http.status_code', 500, 'Int'), ('s3', 'http.status_code', 200, 'Int'),
+	('s4', 'http.status_code', 200, 'Int'), ('s1', 'cached', 1, 'Bool'), ('s2', 'cached', 0, 'Bool'),
+	('s2', 'retries', 1, 'Int'), ('s3', 'retries', 2.5, 'Double'), ('s4', 'retries', '3', 'Str'),
+	('s2', 'tags', '["a","b"]', 'Slice'), ('s3', 'tags', '["a","a"]', 'Slice');
+INSERT INTO resource_attributes (resource_id, key, value, type) VALUES ('r1', 'service.name', 'cart', 'Str');
+INSERT INTO span_resource_attributes (span_id, resource_attribute_id) VALUES ('s2', 'r1'), ('s3', 'r1');
+`
+
+func TestAggregateMatchesInMemory(t *testing.T) {
+	client, err := newSqliteClient(zap.NewNop(), SqliteConfig{Path: ":memory:"})
+	assert.NoError(t, err)
+	defer client.db.Close()
+	client.db.SetMaxOpenConns(1) // every connection has its own in-memory database
+	_, err = client.db.Exec(aggregationsFixture)
+	assert.NoError(t, err)
+	sr := &spanReader{client: client, logger: zap.NewNop()}
+
+	metrics := []spansquery.Metric{
+		{Function: spansquery.AGGREGATION_COUNT},
+		{Function: spansquery.AGGREGATION_AVG, Field: "externalFields.durationNano"},
+		{Function: spansquery.AGGREGATION_MAX, Field: "externalFields.durationNano"},
+		{Function: spansquery.AGGREGATION_P50, Field: "externalFields.durationNano"},
+		{Function: spansquery.AGGREGATION_P90, Field: "externalFields.durationNano"},
+		{Function: spansquery.AGGREGATION_SUM, Field: "span.attributes.retries"},
+		{Function: spansquery.AGGREGATION_P99, Field: "span.attributes.retries"},
+	}
+	for _, aggregations := range []spansquery.Aggregations{
+		{Metrics: metrics},
+		{Metrics: metrics, GroupBy: "span.name"},
+		{Metrics: metrics, GroupBy: "span.attributes.http.status_code"},
+		{Metrics: metrics, GroupBy: "span.attributes.cached"},
+		{Metrics: metrics, GroupBy: "span.attributes.tags"},
+		{Metrics: metrics, GroupBy: "resource.attributes.service.name"},
+		{Metrics: metrics, GroupBy: "span.attributes.http.status_code", Limit: 1},
+	} {
+		aggregations := aggregations
+		r := spansquery.SearchRequest{
+			Timeframe:     model.Timeframe{EndTime: 1000},
+			SearchFilters: []model.SearchFilter{newSearchFilter("span.startTimeUnixNano", spansquery.OPERATOR_GT, int64(100))},
+			Aggregations:  &aggregations,
+		}
+		_, ok, err := buildAggregationsQuery(r)
+		assert.NoError(t, err)
+		assert.True(t, ok, "%s is aggregated in the query", aggregations.GroupBy)
+		expected, err := spanreader.AggregateInSpans(context.Background(), sr, r)
+		assert.NoError(t, err)
+		res, err := sr.Aggregate(context.Background(), r)
+		assert.NoError(t, err)
+		// the numbers of attributes are float64 in memory, and int64 or float64 in the query
+		assert.ElementsMatch(t, encodeGroups(t, expected.Aggregations.Groups), encodeGroups(t, res.Aggregations.Groups), aggregations.GroupBy)
+	}
+}
+
+func encodeGroups(t *testing.T, groups []spansquery.AggregationGroup) []string {
+	encoded := make([]string, 0, len(groups))
+	for _, group := range groups {
+		b, err := json.Marshal(group)
+		assert.NoError(t, err)
+		encoded = append(encoded, string(b))
+	}
+	return encoded
+}
@@ -48,6 +48,7 @@ type sqliteSpan struct {
 	droppedLinksCount              sql.NullInt64
 	durationNano                   sql.NullInt64
 	ingestionTimeUnixNano          sql.NullInt64
+	childCount                     sql.NullInt64
 	scopeDroppedAttributesCount    sql.NullInt64
 }
 
@@ -153,6 +154,13 @@ func (sq *sqliteSpan) getInternalIngestionTimeUnixNano() uint64 {
 	return 0
 }
 
+func (sq *sqliteSpan) getInternalChildCount() uint64 {
+	if sq.childCount.Valid {
+		return uint64(sq.childCount.Int64)
+	}
+	return 0
+}
+
 func (sq *sqliteSpan) getInternalScopeDroppedAttributesCount() uint32 {
 	if sq.scopeDroppedAttributesCount.Valid {
 		return uint32(sq.scopeDroppedAttributesCount.Int64)
@@ -188,14 +196,6 @@ func (sq *sqliteSpan) getInternalScopeVersion() string {
 	return ""
 }
 
-func (sq *sqliteSpan) getInternalLinksTraceId() string {
-	return sq.getInternalTraceId()
-}
-
-func (sq *sqliteSpan) getInternalLinksSpanId() string {
-	return sq.getInternalSpanId()
-}
-
 func (sq *sqliteSpan) toInternalSpan() (*internalspan.InternalSpan, error) {
 	if !sq.spanId.Valid {
 		return nil, fmt.Errorf("spanId is empty")
@@ -205,9 +205,10 @@ func (sq *sqliteSpan) toInternalSpan() (*internalspan.InternalSpan, error) {
 	var events []*internalspan.SpanEvent
 	spanAttributes := make(map[string]interface{})
 	scopeAttributes := make(map[string]interface{})
+	var resourceBinaryAttributes, spanBinaryAttributes, scopeBinaryAttributes internalspan.BinaryAttributes
 	var links []*internalspan.SpanLink
 	if sq.resourceAttributes.Valid {
-		resourceAttributes, err = jsonToAttributesMap(sq.resourceAttributes.String)
+		resourceAttributes, resourceBinaryAttributes, err = jsonToAttributesMap(sq.resourceAttributes.String)
 		if err != nil {
 			return nil, err
 		}
@@ -220,20 +221,20 @@ func (sq *sqliteSpan) toInternalSpan() (*internalspan.InternalSpan, error) {
 	}
 
 	if sq.spanAttributes.Valid {
-		spanAttributes, err = jsonToAttributesMap(sq.spanAttributes.String)
+		spanAttributes, spanBinaryAttributes, err = jsonToAttributesMap(sq.spanAttributes.String)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	if sq.scopeAttributes.Valid {
-		scopeAttributes, err = jsonToAttributesMap(sq.scopeAttributes.String)
+		scopeAttributes, scopeBinaryAttributes, err = jsonToAttributesMap(sq.scopeAttributes.String)
 		if err != nil {
 			return nil, err
 		}
 	}
 	if sq.linksAttributes.Valid {
-		links, err = parseLinks(sq.linksAttributes.String, sq.getInternalLinksSpanId(), sq.getInternalLinksTraceId())
+		links, err = parseLinks(sq.linksAttributes.String)
 		if err != nil {
 			return nil, err
 		}
@@ -242,12 +243,14 @@ func (sq *sqliteSpan) toInternalSpan() (*internalspan.InternalSpan, error) {
 	return &internalspan.InternalSpan{
 		Resource: &internalspan.Resource{
 			Attributes:             resourceAttributes,
+			BinaryAttributes:       resourceBinaryAttributes,
 			DroppedAttributesCount: sq.getInternalResourceDroppedAttributesCount(),
 		},
 		Scope: &internalspan.InstrumentationScope{
 			Name:                   sq.getInternalScopeName(),
 			Version:                sq.getInternalScopeVersion(),
 			Attributes:             scopeAttributes,
+			BinaryAttributes:       scopeBinaryAttributes,
 			DroppedAttributesCount: sq.getInternalScopeDroppedAttributesCount(),
 		},
 		Span: &internalspan.Span{
@@ -260,6 +263,7 @@ func (sq *sqliteSpan) toInternalSpan() (*internalspan.InternalSpan, error) {
 			StartTimeUnixNano:      sq.getInternalStartTimeUnixNano(),
 			EndTimeUnixNano:        sq.getInternalEndTimeUnixNano(),
 			Attributes:             spanAttributes,
+			BinaryAttributes:       spanBinaryAttributes,
 			DroppedAttributesCount: sq.getInternalDroppedSpanAttributesCount(),
 			Events:                 events,
 			DroppedEventsCount:     sq.getInternalDroppedEventsCount(),
@@ -272,30 +276,56 @@ func (sq *sqliteSpan) toInternalSpan() (*internalspan.InternalSpan, error) {
 		},
 		ExternalFields: &internalspan.ExternalFields{
 			DurationNano: sq.getInternalDurationNano(),
+			ChildCount:   sq.getInternalChildCount(),
 		},
 		IngestionTimeUnixNano: sq.getInternalIngestionTimeUnixNano(),
 	}, nil
 }
 
-func jsonToAttributesMap(jsonString string) (map[string]interface{}, error) {
+// jsonToAttributesMap returns the attributes of a JSON array of attribute rows, and the encodings of the binary ones.
+func jsonToAttributesMap(jsonString string) (map[string]interface{}, internalspan.BinaryAttributes, error) {
 	attributesJson := make([]interface{}, 0)
 	attributes := make(map[string]interface{})
+	var binaryAttributes internalspan.BinaryAttributes
 	err := json.Unmarshal([]byte(jsonString), &attributesJson)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	for _, attr := range attributesJson {
 		attrMap := attr.(map[string]interface{})
 		key, ok := attrMap["key"].(string)
 		if !ok {
-			return nil, fmt.Errorf("jsonToAttributesMap: %v is not string", attrMap["key"])
+			return nil, nil, fmt.Errorf("jsonToAttributesMap: %v is not string", attrMap["key"])
+		}
+		attributes[key] = decodeAttributeValue(attrMap["value"], attrMap["type"])
+		if attrMap["type"] == BytesType {
+			if binaryAttributes == nil {
+				binaryAttributes = internalspan.BinaryAttributes{}
+			}
+			binaryAttributes[key] = internalspan.BinaryEncodingBase64
 		}
-		attributes[key] = attrMap["value"]
 	}
-	return attributes, nil
+	return attributes, binaryAttributes, nil
+}
+
+// decodeAttributeValue decodes the values of boolean attributes, which are stored as integers,
+// and of array and kvlist attributes, which are stored as JSON. Binary values are kept in base64.
+func decodeAttributeValue(value any, valueType any) any {
+	if number, ok := value.(float64); ok && valueType == BoolType {
+		return number != 0
+	}
+	encoded, ok := value.(string)
+	if !ok || (valueType != SliceType && valueType != MapType) {
+		return value
+	}
+	var decoded any
+	if err := json.Unmarshal([]byte(encoded), &decoded); err != nil {
+		return value
+	}
+	return decoded
 }
 
-func parseLinks(jsonString string, spanId string, traceId string) ([]*internalspan.SpanLink, error) {
+func parseLinks(jsonString string) ([]*internalspan.SpanLink, error) {
 	linksJson := make([]interface{}, 0)
 	links := make([]*internalspan.SpanLink, 0)
 	err := json.Unmarshal([]byte(jsonString), &linksJson)
@@ -315,11 +345,14 @@ func parseLinks(jsonString string, spanId string, traceId string) ([]*internalsp
 		if !ok {
 			continue
 		}
-		linkAttrJson, ok := linkMap["attributes"].(string)
+		// the linked span context isn't known for links written before it was stored
+		traceId, _ := linkMap["trace_id"].(string)
+		spanId, _ := linkMap["span_id"].(string)
+		linkAttrJson, ok := linkMap["link_attributes"].(string)
 		if !ok {
 			continue
 		}
-		linkAttr, err := jsonToAttributesMap(linkAttrJson)
+		linkAttr, linkBinaryAttr, err := jsonToAttributesMap(linkAttrJson)
 		if err != nil {
 			return nil, err
 		}
@@ -328,6 +361,7 @@ func parseLinks(jsonString string, spanId string, traceId string) ([]*internalsp
 			SpanId:                 spanId,
 			TraceState:             traceState,
 			Attributes:             linkAttr,
+			BinaryAttributes:       linkBinaryAttr,
 			DroppedAttributesCount: uint32(droppedAttributesCount),
 		}
 		links = append(links, &internalLink)
@@ -363,7 +397,7 @@ func parseEvents(jsonString string) ([]*internalspan.SpanEvent, error) {
 		if !ok {
 			continue
 		}
-		eventAttributes, err := jsonToAttributesMap(eventAttrJson)
+		eventAttributes, eventBinaryAttributes, err := jsonToAttributesMap(eventAttrJson)
 		if err != nil {
 			return nil, err
 		}
@@ -372,6 +406,7 @@ func parseEvents(jsonString string) ([]*internalspan.SpanEvent, error) {
 			Name:                   name,
 			DroppedAttributesCount: uint32(droppedAttributesCount),
 			Attributes:             eventAttributes,
+			BinaryAttributes:       eventBinaryAttributes,
 		}
 		internalEvents = append(internalEvents, &internalEvent)
 	}
@@ -27,7 +27,9 @@ type subQueryBuilder struct {
 	tableQueryMap    map[string][]string
 	mainTableName    string
 	mainField        string
+	mainTypeField    string
 	mainCondition    string
+	mainJoin         string
 	mainTableJoinKey string
 }
 
@@ -36,6 +38,7 @@ func newSubQueryBuilder(mainTableName string) *subQueryBuilder {
 		mainTableName:    mainTableName,
 		tableQueryMap:    make(map[string][]string),
 		mainField:        "*",
+		mainTypeField:    "NULL",
 		mainCondition:    "",
 		mainTableJoinKey: "*",
 	}
@@ -49,16 +52,27 @@ func (sqb *subQueryBuilder) getMainField() string {
 	return sqb.mainField
 }
 
+func (sqb *subQueryBuilder) getMainTypeField() string {
+	return sqb.mainTypeField
+}
+
 func (sqb *subQueryBuilder) getMainCondition() string {
 	return sqb.mainCondition
 }
 
+func (sqb *subQueryBuilder) getMainJoin() string {
+	return sqb.mainJoin
+}
+
 func (sqb *subQueryBuilder) initTagsQuery(prepareSqliteFilter *sqliteFilter, tag string) error {
 	var subQuery string
 	tableName := prepareSqliteFilter.getTableName()
 	if prepareSqliteFilter.isDynamicTable() {
 		subQuery = fmt.Sprintf("SELECT %s FROM %s WHERE %s = '%s'", getTableJoinKey(tableName), tableName, tableName+".key", prepareSqliteFilter.getTag())
-		sqb.mainField = createDynamicTagValueField(tableName)
+		// the values of array attributes are counted separately
+		sqb.mainField = fmt.Sprintf("CASE WHEN %s.type = '%s' THEN elements.value ELSE %s END", tableName, SliceType, createDynamicTagValueField(tableName))
+		sqb.mainTypeField = fmt.Sprintf("CASE WHEN %s.type = '%s' THEN elements.type ELSE %s.type END", tableName, SliceType, tableName)
+		sqb.mainJoin = fmt.Sprintf("LEFT JOIN json_each(CASE WHEN %s.type = '%s' THEN %s ELSE '[]' END) AS elements", tableName, SliceType, createDynamicTagValueField(tableName))
 		sqb.mainCondition = fmt.Sprintf("WHERE %s = '%s'", tableName+".key", prepareSqliteFilter.getTag())
 	} else {
 		mappedTag := sqliteFieldsMap[tag]
@@ -85,7 +99,11 @@ func (sqb *subQueryBuilder) addFiltersToSubQuery(filters []model.SearchFilter) e
 		var subQuery string
 		if sqliteFilter.isDynamicTable() {
 			key := fmt.Sprintf("%s.%s", sqliteFilter.getTableName(), sqliteFilter.getTag())
-			subQuery = fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s", getTableJoinKey(tableName), tableName, covertFilterToSqliteQueryCondition(newSearchFilter(key, filter.KeyValueFilter.Operator, filter.KeyValueFilter.Value)))
+			condition := covertFilterToSqliteQueryCondition(newSearchFilter(key, filter.KeyValueFilter.Operator, filter.KeyValueFilter.Value))
+			if sqliteFilter.getTag() == "value" {
+				condition = attributeValueCondition(tableName, filter.KeyValueFilter.Operator, filter.KeyValueFilter.Value)
+			}
+			subQuery = fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s", getTableJoinKey(tableName), tableName, condition)
 		} else {
 			key := sqliteFieldsMap[string(filter.KeyValueFilter.Key)]
 			subQuery = fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s", getTableJoinKey(tableName), tableName, covertFilterToSqliteQueryCondition(newSearchFilter(key, filter.KeyValueFilter.Operator, filter.KeyValueFilter.Value)))
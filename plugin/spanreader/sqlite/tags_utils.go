@@ -21,6 +21,14 @@ import "fmt"
 const (
 	TextType   = "Str"
 	NumberType = "Int"
+	DoubleType = "Double"
+	// BoolType is the type of boolean attributes, whose values are stored as the integers 1 and 0
+	BoolType = "Bool"
+	// SliceType and MapType are the types of array and kvlist attributes, whose values are stored as JSON
+	SliceType = "Slice"
+	MapType   = "Map"
+	// BytesType is the type of binary attributes, whose values are stored in base64
+	BytesType = "Bytes"
 )
 
 var staticTagTypeMap = map[string]string{
@@ -38,16 +46,17 @@ var staticTagTypeMap = map[string]string{
 	"span.traceState":                     TextType,
 	"span.parentSpanId":                   TextType,
 	"span.name":                           TextType,
-	"span.kind":                           NumberType,
+	"span.kind":                           TextType,
 	"span.startTimeUnixNano":              NumberType,
 	"span.endTimeUnixNano":                NumberType,
 	"span.droppedAttributesCount":         NumberType,
 	"span.status.message":                 TextType,
-	"span.status.code":                    NumberType,
+	"span.status.code":                    TextType,
 	"span.droppedResourceAttributesCount": NumberType,
 	"span.droppedEventsCount":             NumberType,
 	"span.droppedLinksCount":              NumberType,
 	"externalFields.durationNano":         NumberType,
+	"externalFields.childCount":           NumberType,
 }
 
 var tablesTypeMap = map[string]bool{
@@ -67,6 +76,20 @@ func isDynamicTagsTable(tableName string) bool {
 	return tablesTypeMap[tableName]
 }
 
+// decodeTagValue returns the value of a tag by its type, which is either an attribute type
+// or the JSON type of an array element.
+func decodeTagValue(value any, valueType string) any {
+	switch valueType {
+	case BoolType:
+		return value != int64(0)
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return value
+}
+
 func createDynamicTagValueField(table string) string {
 	return fmt.Sprintf("%s.value", table)
 }
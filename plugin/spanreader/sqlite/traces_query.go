@@ -0,0 +1,82 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlitespanreader
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	spansquery "github.com/teletrace/teletrace/pkg/model/spansquery/v1"
+)
+
+// buildMatchingTracesQuery returns the query of the traces having spans matching r, ordered by their most recent
+// matching span, and its arguments. The traces following the continuation token of r are queried, up to limit.
+func buildMatchingTracesQuery(r spansquery.SearchTracesRequest, limit int) (string, []any, error) {
+	filters := createTimeframeFilters(r.Timeframe)
+	filters = append(filters, convertFiltersValues(r.SearchFilters)...)
+	subQueryBuilder := newSubQueryBuilder("spans")
+	if err := subQueryBuilder.addFiltersToSubQuery(filters); err != nil {
+		return "", nil, fmt.Errorf("failed to add filters: %v", err)
+	}
+	subQuery, err := subQueryBuilder.buildSubQuery()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to build sub query: %v", err)
+	}
+
+	// the sub query selects the IDs of the matching spans, which are grouped by their trace
+	query := fmt.Sprintf("SELECT spans.trace_id, MAX(spans.start_time_unix_nano) AS latest FROM spans "+
+		"WHERE spans.span_id IN (SELECT span_id FROM (%s)) GROUP BY spans.trace_id", subQuery)
+	var args []any
+	if r.Metadata != nil && r.Metadata.NextToken != "" {
+		latest, traceId, err := parseTracesToken(r.Metadata.NextToken)
+		if err != nil {
+			return "", nil, err
+		}
+		query += " HAVING latest < ? OR (latest = ? AND trace_id < ?)"
+		args = append(args, latest, latest, traceId)
+	}
+	query += fmt.Sprintf(" ORDER BY latest DESC, spans.trace_id DESC LIMIT %d", limit)
+	return query, args, nil
+}
+
+// tracesToken returns the continuation token of the traces following trace, by their most recent matching span.
+func tracesToken(trace *spansquery.TraceResult) spansquery.ContinuationToken {
+	return spansquery.ContinuationToken(fmt.Sprintf("%d:%s", trace.LatestMatchTimeUnixNano, trace.TraceId))
+}
+
+func parseTracesToken(token spansquery.ContinuationToken) (int64, string, error) {
+	latest, traceId, ok := strings.Cut(string(token), ":")
+	n, err := strconv.ParseInt(latest, 10, 64)
+	if !ok || err != nil {
+		return 0, "", fmt.Errorf("invalid continuation token %q", token)
+	}
+	return n, traceId, nil
+}
+
+type sqliteTrace struct {
+	traceId sql.NullString
+	latest  sql.NullInt64
+}
+
+func (st *sqliteTrace) toTraceResult() *spansquery.TraceResult {
+	return &spansquery.TraceResult{
+		TraceId:                 st.traceId.String,
+		LatestMatchTimeUnixNano: uint64(st.latest.Int64),
+	}
+}
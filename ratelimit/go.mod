@@ -0,0 +1,3 @@
+module github.com/teletrace/teletrace/ratelimit
+
+go 1.19
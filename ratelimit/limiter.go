@@ -0,0 +1,175 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package ratelimit limits the rate of requests or items per key, e.g. per tenant or API key,
+// with a token bucket per key.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sweepInterval is the interval between removals of the buckets of idle keys
+const sweepInterval = time.Minute
+
+// Limit is a rate of items per second, allowing bursts of up to Burst items.
+type Limit struct {
+	Rate float64
+	// Burst defaults to the rate, rounded up
+	Burst int
+}
+
+// Unlimited returns whether the limit allows any rate.
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0
+}
+
+func (l Limit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Max(1, math.Ceil(l.Rate))
+}
+
+// ParseOverrides parses comma separated limits of keys, in key=rate or key=rate:burst form, e.g. "a=50:100,b=5".
+func ParseOverrides(s string) (map[string]Limit, error) {
+	overrides := map[string]Limit{}
+	for _, entry := range strings.Split(s, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid rate limit override %q, expected key=rate[:burst]", entry)
+		}
+		rateValue, burstValue, hasBurst := strings.Cut(value, ":")
+		rate, err := strconv.ParseFloat(rateValue, 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("invalid rate of rate limit override %q", entry)
+		}
+		limit := Limit{Rate: rate}
+		if hasBurst {
+			limit.Burst, err = strconv.Atoi(burstValue)
+			if err != nil || limit.Burst < 1 {
+				return nil, fmt.Errorf("invalid burst of rate limit override %q", entry)
+			}
+		}
+		overrides[strings.TrimSpace(key)] = limit
+	}
+	return overrides, nil
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// limits are the limits of a limiter, replaced as a whole by SetLimits.
+type limits struct {
+	defaultLimit Limit
+	overrides    map[string]Limit
+}
+
+// Limiter limits the rate of each key by a token bucket, refilled at the rate of the limit of the key.
+type Limiter struct {
+	limits atomic.Pointer[limits]
+	now    func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewLimiter returns a limiter applying the limit of each key in overrides, and defaultLimit to the other keys.
+func NewLimiter(defaultLimit Limit, overrides map[string]Limit) *Limiter {
+	l := &Limiter{
+		now:       time.Now,
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+	}
+	l.SetLimits(defaultLimit, overrides)
+	return l
+}
+
+// SetLimits replaces the limits of the limiter. The buckets of the keys are kept, and refilled at their new rate up
+// to their new burst.
+func (l *Limiter) SetLimits(defaultLimit Limit, overrides map[string]Limit) {
+	l.limits.Store(&limits{defaultLimit: defaultLimit, overrides: overrides})
+}
+
+// Limit returns the limit of key.
+func (l *Limiter) Limit(key string) Limit {
+	current := l.limits.Load()
+	if limit, ok := current.overrides[key]; ok {
+		return limit
+	}
+	return current.defaultLimit
+}
+
+// Overridden returns whether key has a limit of its own, rather than the default limit.
+func (l *Limiter) Overridden(key string) bool {
+	_, ok := l.limits.Load().overrides[key]
+	return ok
+}
+
+// Allow takes n tokens from the bucket of key, returning whether they were available, and if not, how long to wait
+// until they are. Batches larger than the burst are allowed once the bucket is full, delaying the following ones.
+func (l *Limiter) Allow(key string, n int) (bool, time.Duration) {
+	limit := l.Limit(key)
+	if limit.Unlimited() {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: limit.burst(), updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(limit.burst(), b.tokens+now.Sub(b.updated).Seconds()*limit.Rate)
+	b.updated = now
+
+	required := math.Min(float64(n), limit.burst())
+	if b.tokens < required {
+		wait := time.Duration((required - b.tokens) / limit.Rate * float64(time.Second))
+		return false, wait
+	}
+	b.tokens -= float64(n)
+	return true, 0
+}
+
+// sweep removes the buckets which were refilled since their last use, as new buckets start full anyway.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		limit := l.Limit(key)
+		if b.tokens+now.Sub(b.updated).Seconds()*limit.Rate >= limit.burst() {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestLimiter(defaultLimit Limit, overrides map[string]Limit) (*Limiter, *clock) {
+	c := &clock{now: time.Unix(0, 0)}
+	l := NewLimiter(defaultLimit, overrides)
+	l.now = func() time.Time { return c.now }
+	l.lastSweep = c.now
+	return l, c
+}
+
+func TestAllow(t *testing.T) {
+	l, c := newTestLimiter(Limit{Rate: 10, Burst: 20}, nil)
+
+	if ok, _ := l.Allow("a", 20); !ok {
+		t.Fatal("a full bucket allows a burst")
+	}
+	ok, wait := l.Allow("a", 5)
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("expected the empty bucket to reject with a 500ms wait, got %v %s", ok, wait)
+	}
+	if ok, _ := l.Allow("b", 1); !ok {
+		t.Fatal("each key has its own bucket")
+	}
+
+	c.advance(500 * time.Millisecond)
+	if ok, _ := l.Allow("a", 5); !ok {
+		t.Fatal("the bucket refills at the rate of the limit")
+	}
+}
+
+func TestAllowLargerThanBurst(t *testing.T) {
+	l, c := newTestLimiter(Limit{Rate: 10}, nil)
+
+	if ok, _ := l.Allow("a", 25); !ok {
+		t.Fatal("a batch larger than the burst is allowed by a full bucket")
+	}
+	ok, wait := l.Allow("a", 1)
+	if ok || wait != 1600*time.Millisecond {
+		t.Fatalf("expected the following batch to wait for the debt to be repaid, got %v %s", ok, wait)
+	}
+	c.advance(1600 * time.Millisecond)
+	if ok, _ := l.Allow("a", 1); !ok {
+		t.Fatal("expected the debt to be repaid")
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	overrides, err := ParseOverrides("premium=100:200, free=1 ,unlimited=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, _ := newTestLimiter(Limit{Rate: 10}, overrides)
+
+	if limit := l.Limit("premium"); limit != (Limit{Rate: 100, Burst: 200}) {
+		t.Fatalf("unexpected limit %v", limit)
+	}
+	l.Allow("free", 1)
+	if ok, _ := l.Allow("free", 1); ok {
+		t.Fatal("expected the override to apply")
+	}
+	if ok, _ := l.Allow("unlimited", 1000); !ok {
+		t.Fatal("a zero rate is unlimited")
+	}
+	if ok, _ := l.Allow("other", 10); !ok {
+		t.Fatal("expected the default limit to apply")
+	}
+	if !l.Overridden("free") || l.Overridden("other") {
+		t.Fatal("expected only keys with an override to be overridden")
+	}
+
+	for _, invalid := range []string{"premium", "=5", "a=fast", "a=5:0", "a=-1"} {
+		if _, err := ParseOverrides(invalid); err == nil {
+			t.Fatalf("expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestSetLimits(t *testing.T) {
+	l, c := newTestLimiter(Limit{}, nil)
+
+	if ok, _ := l.Allow("a", 100); !ok {
+		t.Fatal("expected no limit")
+	}
+	l.SetLimits(Limit{Rate: 1}, map[string]Limit{"b": {Rate: 2}})
+	l.Allow("a", 1)
+	if ok, _ := l.Allow("a", 1); ok {
+		t.Fatal("expected the new default limit to apply")
+	}
+	if !l.Overridden("b") {
+		t.Fatal("expected the new overrides to apply")
+	}
+
+	l.SetLimits(Limit{Rate: 10}, nil)
+	c.advance(100 * time.Millisecond)
+	if ok, _ := l.Allow("a", 1); !ok {
+		t.Fatal("expected the bucket to refill at the new rate")
+	}
+}
+
+func TestSweepIdleBuckets(t *testing.T) {
+	l, c := newTestLimiter(Limit{Rate: 10}, nil)
+	l.Allow("a", 10)
+	l.Allow("b", 1)
+
+	c.advance(sweepInterval)
+	l.Allow("c", 1)
+	if len(l.buckets) != 1 {
+		t.Fatalf("expected only the bucket of c to be kept, got %d buckets", len(l.buckets))
+	}
+}
@@ -0,0 +1,83 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package badgerexporter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	internalspanv1 "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/spanvalidation"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeahead"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writequeue"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeretry"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+)
+
+const (
+	typeStr          = "badger"
+	stability        = component.StabilityLevelInDevelopment
+	defaultDirectory = "embedded_spans_badger"
+	defaultTTL       = 7 * 24 * time.Hour
+)
+
+func NewFactory() component.ExporterFactory {
+	return component.NewExporterFactory(
+		typeStr,
+		createDefaultConfig,
+		component.WithTracesExporter(createTracesExporter, stability),
+	)
+}
+
+func createDefaultConfig() component.ExporterConfig {
+	return &Config{
+		ExporterSettings: config.NewExporterSettings(component.NewID(typeStr)),
+		Directory:        defaultDirectory,
+		TTL:              defaultTTL,
+		BinaryEncoding:   internalspanv1.BinaryEncodingBase64,
+		Retry:            writeretry.NewDefaultConfig(),
+		Queue:            writequeue.NewDefaultConfig(),
+		WriteAhead:       writeahead.NewDefaultConfig(),
+		Validation:       spanvalidation.NewDefaultConfig(),
+	}
+}
+
+func createTracesExporter(
+	ctx context.Context,
+	set component.ExporterCreateSettings,
+	cfg component.ExporterConfig,
+) (component.TracesExporter, error) {
+	exporter, err := newTracesExporter(set.Logger, cfg.(*Config))
+	if err != nil {
+		return nil, fmt.Errorf("could not create traces exporter: %w", err)
+	}
+
+	return exporterhelper.NewTracesExporter(
+		ctx, set, cfg,
+		exporter.pushTracesData,
+		exporterhelper.WithStart(exporter.Start),
+		exporterhelper.WithShutdown(exporter.Shutdown),
+		// Retries are handled by the exporter itself, see Config.Retry. The retries may outlast any
+		// request timeout, so the exporterhelper timeout is disabled as well.
+		exporterhelper.WithRetry(exporterhelper.RetrySettings{Enabled: false}),
+		exporterhelper.WithTimeout(exporterhelper.TimeoutSettings{Timeout: 0}),
+	)
+}
@@ -0,0 +1,82 @@
+module github.com/teletrace/teletrace/teletrace-otelcol/exporter/badgerexporter
+
+go 1.19
+
+require (
+	github.com/teletrace/teletrace/kvstore v0.0.0-00010101000000-000000000000
+	github.com/teletrace/teletrace/model v0.0.0-00010101000000-000000000000
+	github.com/teletrace/teletrace/teletrace-otelcol/internal/ingestionlag v0.0.0-00010101000000-000000000000
+	github.com/teletrace/teletrace/teletrace-otelcol/internal/modeltranslator v0.0.0-00010101000000-000000000000
+	github.com/teletrace/teletrace/teletrace-otelcol/internal/spanvalidation v0.0.0-00010101000000-000000000000
+	github.com/teletrace/teletrace/teletrace-otelcol/internal/writeahead v0.0.0-00010101000000-000000000000
+	github.com/teletrace/teletrace/teletrace-otelcol/internal/writequeue v0.0.0-00010101000000-000000000000
+	github.com/teletrace/teletrace/teletrace-otelcol/internal/writeretry v0.0.0-00010101000000-000000000000
+	go.opentelemetry.io/collector v0.64.1
+	go.opentelemetry.io/collector/pdata v0.66.0
+	go.uber.org/zap v1.23.0
+)
+
+require (
+	github.com/AndreasBriese/bbloom v0.0.0-20190825152654-46b345b51c96 // indirect
+	github.com/aws/aws-sdk-go-v2 v1.17.1 // indirect
+	github.com/aws/aws-sdk-go-v2/config v1.18.3 // indirect
+	github.com/aws/aws-sdk-go-v2/credentials v1.13.3 // indirect
+	github.com/aws/aws-sdk-go-v2/feature/ec2/imds v1.12.19 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.1.25 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.4.19 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/ini v1.3.26 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/presigned-url v1.9.19 // indirect
+	github.com/aws/aws-sdk-go-v2/service/sso v1.11.25 // indirect
+	github.com/aws/aws-sdk-go-v2/service/ssooidc v1.13.8 // indirect
+	github.com/aws/aws-sdk-go-v2/service/sts v1.17.5 // indirect
+	github.com/aws/smithy-go v1.13.4 // indirect
+	github.com/cenkalti/backoff/v4 v4.1.3 // indirect
+	github.com/cespare/xxhash v1.1.0 // indirect
+	github.com/dgraph-io/badger v1.6.2 // indirect
+	github.com/dgraph-io/ristretto v0.0.2 // indirect
+	github.com/dustin/go-humanize v1.0.0 // indirect
+	github.com/gogo/protobuf v1.3.2 // indirect
+	github.com/golang/protobuf v1.5.2 // indirect
+	github.com/json-iterator/go v1.1.12 // indirect
+	github.com/knadh/koanf v1.4.4 // indirect
+	github.com/mitchellh/copystructure v1.2.0 // indirect
+	github.com/mitchellh/mapstructure v1.5.0 // indirect
+	github.com/mitchellh/reflectwalk v1.0.2 // indirect
+	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
+	github.com/modern-go/reflect2 v1.0.2 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/teletrace/teletrace/blobstore v0.0.0-00010101000000-000000000000 // indirect
+	github.com/teletrace/teletrace/circuitbreaker v0.0.0-00010101000000-000000000000 // indirect
+	go.opencensus.io v0.24.0 // indirect
+	go.opentelemetry.io/otel v1.11.1 // indirect
+	go.opentelemetry.io/otel/metric v0.33.0 // indirect
+	go.opentelemetry.io/otel/trace v1.11.1 // indirect
+	go.uber.org/atomic v1.10.0 // indirect
+	go.uber.org/multierr v1.8.0 // indirect
+	golang.org/x/net v0.7.0 // indirect
+	golang.org/x/sys v0.5.0 // indirect
+	golang.org/x/text v0.7.0 // indirect
+	google.golang.org/genproto v0.0.0-20220314164441-57ef72a4c106 // indirect
+	google.golang.org/grpc v1.51.0 // indirect
+	google.golang.org/protobuf v1.28.1 // indirect
+)
+
+replace github.com/teletrace/teletrace/blobstore => ../../../blobstore
+
+replace github.com/teletrace/teletrace/circuitbreaker => ../../../circuitbreaker
+
+replace github.com/teletrace/teletrace/kvstore => ../../../kvstore
+
+replace github.com/teletrace/teletrace/model => ../../../model
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/internal/modeltranslator => ../../internal/modeltranslator
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/internal/ingestionlag => ../../internal/ingestionlag
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/internal/spanvalidation => ../../internal/spanvalidation
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/internal/writeahead => ../../internal/writeahead
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/internal/writequeue => ../../internal/writequeue
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/internal/writeretry => ../../internal/writeretry
@@ -0,0 +1,189 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package badgerexporter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/teletrace/teletrace/kvstore"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/ingestionlag"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/modeltranslator"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/spanvalidation"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeahead"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writequeue"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeretry"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+type badgerTracesExporter struct {
+	logger    *zap.Logger
+	cfg       *Config
+	writer    *writeretry.Writer
+	wal       *writeahead.Log
+	queue     *writequeue.Queue
+	validator *spanvalidation.Validator
+	lag       *ingestionlag.Recorder
+	store     *kvstore.Store
+}
+
+func newTracesExporter(logger *zap.Logger, cfg *Config) (*badgerTracesExporter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	writer, err := writeretry.NewWriter(logger, cfg.ID().String(), cfg.Retry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create retry writer: %+v", err)
+	}
+
+	wal, err := writeahead.NewLog(logger, cfg.ID().String(), cfg.WriteAhead)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create write-ahead log: %+v", err)
+	}
+
+	validator, err := spanvalidation.NewValidator(logger, cfg.ID().String(), cfg.Validation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create span validator: %+v", err)
+	}
+
+	lag, err := ingestionlag.NewRecorder(cfg.ID().String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ingestion lag recorder: %+v", err)
+	}
+
+	// the store is shared with the badger span reader of the process, if any
+	store, err := kvstore.Open(logger, cfg.Directory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open badger store: %+v", err)
+	}
+
+	exporter := &badgerTracesExporter{
+		logger:    logger,
+		cfg:       cfg,
+		writer:    writer,
+		wal:       wal,
+		validator: validator,
+		lag:       lag,
+		store:     store,
+	}
+
+	if cfg.Queue.Enabled {
+		exporter.queue, err = writequeue.NewQueue(logger, cfg.ID().String(), cfg.Queue, func(ctx context.Context, item interface{}) error {
+			batch := item.(*writequeue.TracesBatch)
+			if err := exporter.write(ctx, batch.Traces, batch.WALEntries...); err != nil {
+				// The batch was acknowledged, so it's kept in the write-ahead log to be replayed
+				exporter.wal.Retry(batch.WALEntries...)
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			store.Close()
+			return nil, fmt.Errorf("failed to create write queue: %+v", err)
+		}
+		exporter.queue.OnDrop(func(item interface{}) {
+			exporter.wal.Retry(item.(*writequeue.TracesBatch).WALEntries...)
+		})
+	}
+
+	return exporter, nil
+}
+
+// Start starts the write queue workers, and replays the batches left in the write-ahead log
+// and dead-lettered by previous runs in the background.
+func (e *badgerTracesExporter) Start(_ context.Context, _ component.Host) error {
+	if e.queue != nil {
+		e.queue.Start()
+	}
+
+	e.writer.StartReplay(e.wal,
+		func(ctx context.Context, td ptrace.Traces) error { return e.write(ctx, td) },
+		e.writeTraces,
+	)
+	return nil
+}
+
+func (e *badgerTracesExporter) Shutdown(ctx context.Context) error {
+	e.writer.StopReplay()
+	if e.queue != nil {
+		if err := e.queue.Stop(); err != nil {
+			e.logger.Warn("Failed to write queued batches", zap.Error(err))
+		}
+	}
+	if err := e.store.Close(); err != nil {
+		return fmt.Errorf("could not shut down badger exporter: %+v", err)
+	}
+	return nil
+}
+
+func (e *badgerTracesExporter) pushTracesData(ctx context.Context, td ptrace.Traces) error {
+	td = e.validator.Filter(ctx, td)
+	if td.SpanCount() == 0 {
+		return nil
+	}
+
+	walEntry, err := e.wal.Append(func() ([]byte, error) { return (&ptrace.ProtoMarshaler{}).MarshalTraces(td) })
+	if err != nil {
+		return err
+	}
+
+	if e.queue != nil {
+		err = e.queue.Enqueue(ctx, writequeue.NewTracesBatch(td, walEntry))
+	} else {
+		err = e.write(ctx, td, walEntry)
+	}
+	if err != nil {
+		// The batch isn't acknowledged, so the client sends it again
+		e.wal.Remove(walEntry)
+	}
+	return err
+}
+
+// write writes a batch, retrying on failure and dead-lettering it once retries are exhausted.
+// Its write-ahead log entries are removed once the batch is written or dead-lettered.
+func (e *badgerTracesExporter) write(ctx context.Context, td ptrace.Traces, walEntries ...string) error {
+	err := e.writer.Write(
+		ctx,
+		td.SpanCount(),
+		func(ctx context.Context) error { return e.writeTraces(ctx, td) },
+		func() ([]byte, error) { return (&ptrace.ProtoMarshaler{}).MarshalTraces(td) },
+	)
+	if err == nil {
+		e.wal.Remove(walEntries...)
+	}
+	return err
+}
+
+func (e *badgerTracesExporter) writeTraces(_ context.Context, td ptrace.Traces) error {
+	ingestionTime := time.Now()
+	internalSpans := modeltranslator.TranslateOTLPToInternalModel(
+		td,
+		modeltranslator.WithIngestionTime(ingestionTime),
+		modeltranslator.WithBinaryEncoding(e.cfg.BinaryEncoding),
+	)
+
+	if err := e.store.WriteSpans(internalSpans, e.cfg.TTL); err != nil {
+		return err
+	}
+	e.lag.Record(td, ingestionTime)
+	return nil
+}
@@ -0,0 +1,150 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cassandraexporter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	internalspanv1 "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/spanvalidation"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeahead"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writequeue"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeretry"
+
+	"github.com/gocql/gocql"
+	"go.opentelemetry.io/collector/config"
+)
+
+// Config defines configuration for Cassandra exporter.
+type Config struct {
+	config.ExporterSettings `mapstructure:",squash"`
+
+	// Hosts holds the addresses of the Cassandra (or ScyllaDB) nodes the exporter connects to
+	Hosts []string `mapstructure:"hosts"`
+
+	// Keyspace holds the tables of the spans and their indexes. Defaults to teletrace
+	Keyspace string `mapstructure:"keyspace"`
+
+	// Username is used to configure password authentication.
+	// If set, password must be set as well
+	Username string `mapstructure:"username"`
+
+	// Password is used to configure password authentication.
+	Password string `mapstructure:"password"`
+
+	// Consistency is the consistency level of the writes, e.g. ONE or LOCAL_QUORUM. Defaults to LOCAL_QUORUM
+	Consistency string `mapstructure:"consistency"`
+
+	// CreateSchema creates the keyspace and the tables on start if they don't exist. Defaults to true
+	CreateSchema bool `mapstructure:"create_schema"`
+
+	// ReplicationFactor is the replication factor of the keyspace created by CreateSchema. Defaults to 1
+	ReplicationFactor int `mapstructure:"replication_factor"`
+
+	// TTL expires the spans and their index entries after they are written, 0 keeps them forever.
+	// Defaults to 7 days
+	TTL time.Duration `mapstructure:"ttl"`
+
+	// BinaryEncoding is the encoding of binary attribute values, either base64 or hex.
+	// Defaults to base64
+	BinaryEncoding string `mapstructure:"binary_encoding"`
+
+	// Retry configures retries of failed writes, and the dead-letter directory for exhausted batches.
+	Retry writeretry.Config `mapstructure:"retry_on_failure"`
+
+	// Queue configures the queue decoupling the ingestion from the storage writes.
+	Queue writequeue.Config `mapstructure:"sending_queue"`
+
+	// WriteAhead configures the write-ahead log persisting batches before they are acknowledged.
+	WriteAhead writeahead.Config `mapstructure:"write_ahead_log"`
+
+	// Validation configures rejecting malformed spans before they are written.
+	Validation spanvalidation.Config `mapstructure:"validation"`
+}
+
+var (
+	errConfigNoHost      = errors.New("hosts must be specified")
+	errConfigEmptyHost   = errors.New("hosts must not include empty entries")
+	errConfigNoKeyspace  = errors.New("keyspace must be specified")
+	errConfigNoPassword  = errors.New("password must be set along with username")
+	errConfigNegativeTTL = errors.New("ttl cannot be negative")
+)
+
+// keyspaceRegexp matches the valid keyspace names, which are interpolated in the statements of the exporter
+var keyspaceRegexp = regexp.MustCompile(`^[a-zA-Z0-9_]{1,48}$`)
+
+// Validate validates the Cassandra exporter configuration.
+func (cfg *Config) Validate() error {
+	if len(cfg.Hosts) == 0 {
+		return errConfigNoHost
+	}
+
+	for _, host := range cfg.Hosts {
+		if host == "" {
+			return errConfigEmptyHost
+		}
+	}
+
+	if cfg.Keyspace == "" {
+		return errConfigNoKeyspace
+	}
+
+	if !keyspaceRegexp.MatchString(cfg.Keyspace) {
+		return fmt.Errorf("invalid keyspace %q, keyspaces are named by up to 48 alphanumeric or underscore characters", cfg.Keyspace)
+	}
+
+	if cfg.Username != "" && cfg.Password == "" {
+		return errConfigNoPassword
+	}
+
+	if _, err := gocql.ParseConsistencyWrapper(cfg.Consistency); err != nil {
+		return fmt.Errorf("invalid consistency: %w", err)
+	}
+
+	if cfg.CreateSchema && cfg.ReplicationFactor < 1 {
+		return fmt.Errorf("replication_factor must be at least 1, got %d", cfg.ReplicationFactor)
+	}
+
+	if cfg.TTL < 0 {
+		return errConfigNegativeTTL
+	}
+
+	if err := internalspanv1.ValidateBinaryEncoding(cfg.BinaryEncoding); err != nil {
+		return err
+	}
+
+	if err := cfg.Retry.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Queue.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.WriteAhead.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Validation.Validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cassandraexporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gocql/gocql"
+)
+
+// The tables are modeled after the Cassandra schema of Jaeger: spans are partitioned by their trace ID, so a trace
+// is read from a single partition, and searches read the candidate spans from index tables partitioned by the hour
+// bucket of their start time, and a shard spreading the spans of an hour over indexShards partitions.
+// The span reader of the cassandra storage plugin relies on this schema, the bucket size and the number of shards.
+const (
+	// bucketNano is the width of the time buckets of the index tables
+	bucketNano = int64(3600 * 1000 * 1000 * 1000)
+	// indexShards is the number of partitions of each bucket of the index tables
+	indexShards = 10
+)
+
+// keyspaceStatement creates the keyspace with the SimpleStrategy, keyspaces of multi-datacenter clusters
+// should be created beforehand with the NetworkTopologyStrategy.
+const keyspaceStatement = `CREATE KEYSPACE IF NOT EXISTS %s
+	WITH replication = {'class': 'SimpleStrategy', 'replication_factor': %d}`
+
+var tableStatements = []string{
+	// spans holds the spans as JSON, including their events and links
+	`CREATE TABLE IF NOT EXISTS %s.spans (
+		trace_id text,
+		span_id text,
+		start_time bigint,
+		span blob,
+		PRIMARY KEY (trace_id, span_id)
+	)`,
+	// span_time_index lists the spans by start time, for searches without an indexed filter
+	`CREATE TABLE IF NOT EXISTS %s.span_time_index (
+		bucket bigint,
+		shard int,
+		start_time bigint,
+		trace_id text,
+		span_id text,
+		PRIMARY KEY ((bucket, shard), start_time, trace_id, span_id)
+	) WITH CLUSTERING ORDER BY (start_time DESC, trace_id ASC, span_id ASC)`,
+	// tag_index lists the spans by the values of their indexed tags, for equals and in filters
+	`CREATE TABLE IF NOT EXISTS %s.tag_index (
+		tag_key text,
+		tag_value text,
+		bucket bigint,
+		shard int,
+		start_time bigint,
+		trace_id text,
+		span_id text,
+		PRIMARY KEY ((tag_key, tag_value, bucket, shard), start_time, trace_id, span_id)
+	) WITH CLUSTERING ORDER BY (start_time DESC, trace_id ASC, span_id ASC)`,
+	// duration_index lists the spans of a service by duration, for duration range filters
+	`CREATE TABLE IF NOT EXISTS %s.duration_index (
+		service_name text,
+		bucket bigint,
+		shard int,
+		duration bigint,
+		start_time bigint,
+		trace_id text,
+		span_id text,
+		PRIMARY KEY ((service_name, bucket, shard), duration, start_time, trace_id, span_id)
+	)`,
+	// tags lists the attribute tags of the written spans, with their types
+	`CREATE TABLE IF NOT EXISTS %s.tags (
+		tag_key text PRIMARY KEY,
+		tag_type text
+	)`,
+}
+
+// createSchema creates the keyspace and the tables if they don't exist.
+func createSchema(ctx context.Context, cfg *Config, cluster *gocql.ClusterConfig) error {
+	// the keyspace may not exist yet, so the schema is created by a session without one
+	schemaCluster := *cluster
+	schemaCluster.Keyspace = ""
+	session, err := schemaCluster.CreateSession()
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer session.Close()
+
+	if err := session.Query(fmt.Sprintf(keyspaceStatement, cfg.Keyspace, cfg.ReplicationFactor)).WithContext(ctx).Exec(); err != nil {
+		return fmt.Errorf("failed to create keyspace %s: %w", cfg.Keyspace, err)
+	}
+	for _, statement := range tableStatements {
+		if err := session.Query(fmt.Sprintf(statement, cfg.Keyspace)).WithContext(ctx).Exec(); err != nil {
+			return fmt.Errorf("failed to create table: %w", err)
+		}
+	}
+	return nil
+}
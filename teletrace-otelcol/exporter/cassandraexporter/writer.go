@@ -0,0 +1,278 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cassandraexporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+
+	internalspanv1 "github.com/teletrace/teletrace/model/internalspan/v1"
+)
+
+const (
+	// writeConcurrency is the number of statements of a batch executed concurrently
+	writeConcurrency = 32
+	// maxIndexedValueLength bounds the tag values written to the tag index, longer values aren't indexed
+	maxIndexedValueLength = 256
+)
+
+// storedSpan keeps the events and links of the span alongside it, as they aren't part of the span JSON.
+type storedSpan struct {
+	*internalspanv1.InternalSpan
+	Events []*internalspanv1.SpanEvent `json:"events"`
+	Links  []*internalspanv1.SpanLink  `json:"links"`
+}
+
+type statement struct {
+	query string
+	args  []any
+}
+
+// writeSpans writes the spans with their index entries, and adds their new attribute tags to the tags table.
+// Writes are upserts, so a span written again replaces the written span.
+func (e *cassandraTracesExporter) writeSpans(ctx context.Context, spans []*internalspanv1.InternalSpan) error {
+	ttl := int(e.cfg.TTL.Seconds())
+	var statements []statement
+	tags := make(map[string]string)
+	for _, span := range spans {
+		spanStatements, err := e.spanStatements(span, ttl)
+		if err != nil {
+			return err
+		}
+		statements = append(statements, spanStatements...)
+		for key, tagType := range attributeTags(span) {
+			if _, ok := e.tags.Load(key); !ok {
+				tags[key] = tagType
+			}
+		}
+	}
+	if err := e.exec(ctx, statements); err != nil {
+		return err
+	}
+
+	statements = statements[:0]
+	for key, tagType := range tags {
+		statements = append(statements, statement{
+			query: fmt.Sprintf("INSERT INTO %s.tags (tag_key, tag_type) VALUES (?, ?)", e.cfg.Keyspace),
+			args:  []any{key, tagType},
+		})
+	}
+	if err := e.exec(ctx, statements); err != nil {
+		return err
+	}
+	for key := range tags {
+		e.tags.Store(key, true)
+	}
+	return nil
+}
+
+// spanStatements returns the statements inserting span and its index entries, expiring after ttl seconds.
+func (e *cassandraTracesExporter) spanStatements(span *internalspanv1.InternalSpan, ttl int) ([]statement, error) {
+	data, err := json.Marshal(storedSpan{InternalSpan: span, Events: span.Span.Events, Links: span.Span.Links})
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode span with id %v: %w", span.Span.SpanId, err)
+	}
+
+	traceId, spanId := span.Span.TraceId, span.Span.SpanId
+	startTime := int64(span.Span.StartTimeUnixNano)
+	bucket, shard := startTime/bucketNano, shardOf(traceId)
+	keyspace := e.cfg.Keyspace
+
+	statements := []statement{
+		{
+			query: fmt.Sprintf("INSERT INTO %s.spans (trace_id, span_id, start_time, span) VALUES (?, ?, ?, ?) USING TTL ?", keyspace),
+			args:  []any{traceId, spanId, startTime, data, ttl},
+		},
+		{
+			query: fmt.Sprintf("INSERT INTO %s.span_time_index (bucket, shard, start_time, trace_id, span_id) VALUES (?, ?, ?, ?, ?) USING TTL ?", keyspace),
+			args:  []any{bucket, shard, startTime, traceId, spanId, ttl},
+		},
+	}
+	if service, ok := serviceName(span); ok && span.ExternalFields != nil {
+		statements = append(statements, statement{
+			query: fmt.Sprintf("INSERT INTO %s.duration_index (service_name, bucket, shard, duration, start_time, trace_id, span_id) VALUES (?, ?, ?, ?, ?, ?, ?) USING TTL ?", keyspace),
+			args:  []any{service, bucket, shard, int64(span.ExternalFields.DurationNano), startTime, traceId, spanId, ttl},
+		})
+	}
+	for key, values := range indexedTags(span) {
+		for value := range values {
+			statements = append(statements, statement{
+				query: fmt.Sprintf("INSERT INTO %s.tag_index (tag_key, tag_value, bucket, shard, start_time, trace_id, span_id) VALUES (?, ?, ?, ?, ?, ?, ?) USING TTL ?", keyspace),
+				args:  []any{key, value, bucket, shard, startTime, traceId, spanId, ttl},
+			})
+		}
+	}
+	return statements, nil
+}
+
+func serviceName(span *internalspanv1.InternalSpan) (string, bool) {
+	if span.Resource == nil {
+		return "", false
+	}
+	service, ok := span.Resource.Attributes["service.name"].(string)
+	return service, ok
+}
+
+// exec executes the statements concurrently, returning the first error.
+func (e *cassandraTracesExporter) exec(ctx context.Context, statements []statement) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	work := make(chan statement)
+	for i := 0; i < writeConcurrency && i < len(statements); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s := range work {
+				if err := e.session.Query(s.query, s.args...).WithContext(ctx).Exec(); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = fmt.Errorf("failed to write spans: %w", err)
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, s := range statements {
+		work <- s
+	}
+	close(work)
+	wg.Wait()
+	return firstErr
+}
+
+// shardOf returns the shard of the index entries of the spans of a trace.
+func shardOf(traceId string) int {
+	h := fnv.New32a()
+	h.Write([]byte(traceId))
+	return int(h.Sum32() % indexShards)
+}
+
+// indexedTags returns the values of the tags of span written to the tag index: the name, kind and status code of
+// the span, the names of its scope and events, and its scalar resource and span attributes, including the
+// scalar elements of their array attributes.
+func indexedTags(span *internalspanv1.InternalSpan) map[string]map[string]bool {
+	tags := make(map[string]map[string]bool)
+	add := func(key string, value any) {
+		v, ok := indexValue(value)
+		if !ok || len(v) > maxIndexedValueLength {
+			return
+		}
+		if tags[key] == nil {
+			tags[key] = make(map[string]bool)
+		}
+		tags[key][v] = true
+	}
+	addAttributes := func(prefix string, attributes internalspanv1.Attributes) {
+		for key, value := range attributes {
+			if elements, ok := value.([]any); ok {
+				for _, element := range elements {
+					add(prefix+key, element)
+				}
+				continue
+			}
+			add(prefix+key, value)
+		}
+	}
+
+	add("span.name", span.Span.Name)
+	add("span.kind", span.Span.Kind)
+	if span.Span.Status != nil {
+		add("span.status.code", span.Span.Status.Code)
+	}
+	if span.Scope != nil {
+		add("scope.name", span.Scope.Name)
+	}
+	for _, event := range span.Span.Events {
+		if event != nil {
+			add("span.events.name", event.Name)
+		}
+	}
+	if span.Resource != nil {
+		addAttributes("resource.attributes.", span.Resource.Attributes)
+	}
+	addAttributes("span.attributes.", span.Span.Attributes)
+	return tags
+}
+
+// indexValue returns the tag index value of a scalar value, numbers being written in their shortest decimal form.
+func indexValue(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case int:
+		return strconv.Itoa(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
+
+// attributeTags returns the tags of the attributes of span, with the OTLP type names of their values.
+func attributeTags(span *internalspanv1.InternalSpan) map[string]string {
+	tags := make(map[string]string)
+	add := func(prefix string, attributes internalspanv1.Attributes) {
+		for key, value := range attributes {
+			tags[prefix+key] = attributeType(value)
+		}
+	}
+	if span.Resource != nil {
+		add("resource.attributes.", span.Resource.Attributes)
+	}
+	if span.Scope != nil {
+		add("scope.attributes.", span.Scope.Attributes)
+	}
+	add("span.attributes.", span.Span.Attributes)
+	for _, event := range span.Span.Events {
+		if event != nil {
+			add("span.events.attributes.", event.Attributes)
+		}
+	}
+	for _, link := range span.Span.Links {
+		if link != nil {
+			add("span.links.attributes.", link.Attributes)
+		}
+	}
+	return tags
+}
+
+func attributeType(value any) string {
+	switch value.(type) {
+	case bool:
+		return "Bool"
+	case int, int64:
+		return "Int"
+	case float64:
+		return "Double"
+	case []any:
+		return "Slice"
+	case map[string]any:
+		return "Map"
+	default:
+		return "Str"
+	}
+}
@@ -0,0 +1,52 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package elasticsearchexporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/childcount"
+)
+
+// childCountSearch searches the children indexed by previous batches in index.
+func childCountSearch(c *elasticsearch.Client, index string) childcount.SearchFunc {
+	return func(ctx context.Context, body io.Reader) (io.ReadCloser, error) {
+		res, err := c.Search(
+			c.Search.WithContext(ctx),
+			c.Search.WithIndex(index),
+			c.Search.WithBody(body),
+			c.Search.WithIgnoreUnavailable(true),
+		)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode == http.StatusNotFound {
+			// Nothing was indexed yet
+			res.Body.Close()
+			return nil, nil
+		}
+		if res.IsError() {
+			defer res.Body.Close()
+			return nil, fmt.Errorf("child count query failed: %s", res.String())
+		}
+		return res.Body, nil
+	}
+}
@@ -19,9 +19,17 @@ package elasticsearchexporter
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/childcount"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/ingestionlag"
 	"github.com/teletrace/teletrace/teletrace-otelcol/internal/modeltranslator"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/spanvalidation"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeahead"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writequeue"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeretry"
 
+	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.uber.org/zap"
 
@@ -29,9 +37,15 @@ import (
 )
 
 type elasticsearchTracesExporter struct {
-	logger *zap.Logger
-	cfg    *Config
-	client *elasticsearch.Client
+	logger      *zap.Logger
+	cfg         *Config
+	writer      *writeretry.Writer
+	wal         *writeahead.Log
+	queue       *writequeue.Queue
+	validator   *spanvalidation.Validator
+	lag         *ingestionlag.Recorder
+	client      *elasticsearch.Client
+	childCounts *childcount.Lookup
 }
 
 func newTracesExporter(logger *zap.Logger, cfg *Config) (*elasticsearchTracesExporter, error) {
@@ -44,22 +58,130 @@ func newTracesExporter(logger *zap.Logger, cfg *Config) (*elasticsearchTracesExp
 		return nil, fmt.Errorf("failed to create client: %+v", err)
 	}
 
-	return &elasticsearchTracesExporter{
-		logger: logger,
-		cfg:    cfg,
-		client: esClient,
-	}, nil
+	writer, err := writeretry.NewWriter(logger, cfg.ID().String(), cfg.Retry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create retry writer: %+v", err)
+	}
+
+	wal, err := writeahead.NewLog(logger, cfg.ID().String(), cfg.WriteAhead)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create write-ahead log: %+v", err)
+	}
+
+	validator, err := spanvalidation.NewValidator(logger, cfg.ID().String(), cfg.Validation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create span validator: %+v", err)
+	}
+
+	lag, err := ingestionlag.NewRecorder(cfg.ID().String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ingestion lag recorder: %+v", err)
+	}
+
+	exporter := &elasticsearchTracesExporter{
+		logger:      logger,
+		cfg:         cfg,
+		writer:      writer,
+		wal:         wal,
+		validator:   validator,
+		lag:         lag,
+		client:      esClient,
+		childCounts: childcount.NewLookup(childCountSearch(esClient, cfg.Index), spanDocumentId),
+	}
+
+	if cfg.Queue.Enabled {
+		exporter.queue, err = writequeue.NewQueue(logger, cfg.ID().String(), cfg.Queue, func(ctx context.Context, item interface{}) error {
+			batch := item.(*writequeue.TracesBatch)
+			if err := exporter.write(ctx, batch.Traces, batch.WALEntries...); err != nil {
+				// The batch was acknowledged, so it's kept in the write-ahead log to be replayed
+				exporter.wal.Retry(batch.WALEntries...)
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create write queue: %+v", err)
+		}
+		exporter.queue.OnDrop(func(item interface{}) {
+			exporter.wal.Retry(item.(*writequeue.TracesBatch).WALEntries...)
+		})
+	}
+
+	return exporter, nil
+}
+
+// Start starts the write queue workers, and replays the batches left in the write-ahead log
+// and dead-lettered by previous runs in the background.
+func (e *elasticsearchTracesExporter) Start(_ context.Context, _ component.Host) error {
+	if e.queue != nil {
+		e.queue.Start()
+	}
+
+	e.writer.StartReplay(e.wal,
+		func(ctx context.Context, td ptrace.Traces) error { return e.write(ctx, td) },
+		e.writeTraces,
+	)
+	return nil
 }
 
 func (e *elasticsearchTracesExporter) Shutdown(ctx context.Context) error {
+	e.writer.StopReplay()
+	if e.queue != nil {
+		return e.queue.Stop()
+	}
 	return nil
 }
 
 func (e *elasticsearchTracesExporter) pushTracesData(ctx context.Context, td ptrace.Traces) error {
+	td = e.validator.Filter(ctx, td)
+	if td.SpanCount() == 0 {
+		return nil
+	}
+
+	walEntry, err := e.wal.Append(func() ([]byte, error) { return (&ptrace.ProtoMarshaler{}).MarshalTraces(td) })
+	if err != nil {
+		return err
+	}
+
+	if e.queue != nil {
+		err = e.queue.Enqueue(ctx, writequeue.NewTracesBatch(td, walEntry))
+	} else {
+		err = e.write(ctx, td, walEntry)
+	}
+	if err != nil {
+		// The batch isn't acknowledged, so the client sends it again
+		e.wal.Remove(walEntry)
+	}
+	return err
+}
+
+// write writes a batch, retrying on failure and dead-lettering it once retries are exhausted.
+// Its write-ahead log entries are removed once the batch is written or dead-lettered.
+func (e *elasticsearchTracesExporter) write(ctx context.Context, td ptrace.Traces, walEntries ...string) error {
+	err := e.writer.Write(
+		ctx,
+		td.SpanCount(),
+		func(ctx context.Context) error { return e.writeTraces(ctx, td) },
+		func() ([]byte, error) { return (&ptrace.ProtoMarshaler{}).MarshalTraces(td) },
+	)
+	if err == nil {
+		e.wal.Remove(walEntries...)
+	}
+	return err
+}
+
+func (e *elasticsearchTracesExporter) writeTraces(ctx context.Context, td ptrace.Traces) error {
+	ingestionTime := time.Now()
 	internalSpans := modeltranslator.TranslateOTLPToInternalModel(
 		td,
+		modeltranslator.WithIngestionTime(ingestionTime),
 		modeltranslator.WithMiliSec(),
+		modeltranslator.WithBinaryEncoding(e.cfg.BinaryEncoding),
 	)
 
-	return writeSpans(ctx, e.logger, e.client, e.cfg.Index, internalSpans...)
+	if err := writeSpans(ctx, e.logger, e.client, e.cfg.Index, e.childCounts, internalSpans...); err != nil {
+		return err
+	}
+	e.lag.Record(td, ingestionTime)
+	return nil
 }
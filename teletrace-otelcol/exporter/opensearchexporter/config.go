@@ -19,6 +19,12 @@ package opensearchexporter
 import (
 	"errors"
 
+	internalspanv1 "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/spanvalidation"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeahead"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writequeue"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeretry"
+
 	"go.opentelemetry.io/collector/config"
 )
 
@@ -38,6 +44,22 @@ type Config struct {
 
 	// Password is used to configure HTTP Basic Authentication.
 	Password string `mapstructure:"password"`
+
+	// BinaryEncoding is the encoding of binary attribute values, either base64 or hex.
+	// Defaults to base64
+	BinaryEncoding string `mapstructure:"binary_encoding"`
+
+	// Retry configures retries of failed bulk writes, and the dead-letter directory for exhausted batches.
+	Retry writeretry.Config `mapstructure:"retry_on_failure"`
+
+	// Queue configures the queue decoupling the ingestion from the storage writes.
+	Queue writequeue.Config `mapstructure:"sending_queue"`
+
+	// WriteAhead configures the write-ahead log persisting batches before they are acknowledged.
+	WriteAhead writeahead.Config `mapstructure:"write_ahead_log"`
+
+	// Validation configures rejecting malformed spans before they are written.
+	Validation spanvalidation.Config `mapstructure:"validation"`
 }
 
 var (
@@ -57,5 +79,25 @@ func (cfg *Config) Validate() error {
 		}
 	}
 
+	if err := internalspanv1.ValidateBinaryEncoding(cfg.BinaryEncoding); err != nil {
+		return err
+	}
+
+	if err := cfg.Retry.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Queue.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.WriteAhead.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Validation.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -20,6 +20,12 @@ import (
 	"context"
 	"fmt"
 
+	internalspanv1 "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/spanvalidation"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeahead"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writequeue"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeretry"
+
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config"
 	"go.opentelemetry.io/collector/exporter/exporterhelper"
@@ -43,6 +49,11 @@ func createDefaultConfig() component.ExporterConfig {
 	return &Config{
 		ExporterSettings: config.NewExporterSettings(component.NewID(typeStr)),
 		Index:            defaultIndex,
+		BinaryEncoding:   internalspanv1.BinaryEncodingBase64,
+		Retry:            writeretry.NewDefaultConfig(),
+		Queue:            writequeue.NewDefaultConfig(),
+		WriteAhead:       writeahead.NewDefaultConfig(),
+		Validation:       spanvalidation.NewDefaultConfig(),
 	}
 }
 
@@ -59,11 +70,11 @@ func createTracesExporter(
 	return exporterhelper.NewTracesExporter(
 		ctx, set, cfg,
 		exporter.pushTracesData,
+		exporterhelper.WithStart(exporter.Start),
 		exporterhelper.WithShutdown(exporter.Shutdown),
-		exporterhelper.WithRetry(exporterhelper.RetrySettings{
-			Enabled:        true,
-			MaxInterval:    3,  // TODO add to Teletrace config
-			MaxElapsedTime: 10, // TODO add to Teletrace config
-		}),
+		// Retries are handled by the exporter itself, see Config.Retry. The retries may outlast any
+		// request timeout, so the exporterhelper timeout is disabled as well.
+		exporterhelper.WithRetry(exporterhelper.RetrySettings{Enabled: false}),
+		exporterhelper.WithTimeout(exporterhelper.TimeoutSettings{Timeout: 0}),
 	)
 }
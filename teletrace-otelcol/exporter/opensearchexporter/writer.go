@@ -24,12 +24,19 @@ import (
 
 	"github.com/opensearch-project/opensearch-go"
 	internalspanv1 "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/childcount"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
 )
 
 var retryOnStatus = []int{500, 502, 503, 504, 429}
 
+// spanDocumentId identifies the document of a span by its trace id and span id, so a span that's written again,
+// e.g. when an SDK retries an export, overwrites its document instead of being duplicated.
+func spanDocumentId(span *internalspanv1.InternalSpan) string {
+	return span.Span.TraceId + "-" + span.Span.SpanId
+}
+
 type bulkResponse struct {
 	Errors bool `json:"errors"`
 	Items  []struct {
@@ -49,7 +56,7 @@ type bulkResponse struct {
 	} `json:"items"`
 }
 
-func writeSpans(ctx context.Context, logger *zap.Logger, c *opensearch.Client, index string, spans ...*internalspanv1.InternalSpan) error {
+func writeSpans(ctx context.Context, logger *zap.Logger, c *opensearch.Client, index string, childCounts *childcount.Lookup, spans ...*internalspanv1.InternalSpan) error {
 	var errs []error
 	var buf bytes.Buffer
 	var raw map[string]interface{}
@@ -57,9 +64,13 @@ func writeSpans(ctx context.Context, logger *zap.Logger, c *opensearch.Client, i
 
 	numItems := 0
 
+	if err := childCounts.AddIndexedChildCounts(ctx, spans); err != nil {
+		logger.Warn("Failed to count indexed children, child counts only include the batch", zap.Error(err))
+	}
+
 	for _, span := range spans {
 		numItems++
-		meta := []byte(fmt.Sprintf(`{ "index" : { "_id" : "%s" } }%s`, span.Span.SpanId, "\n"))
+		meta := []byte(fmt.Sprintf(`{ "index" : { "_id" : "%s" } }%s`, spanDocumentId(span), "\n"))
 		data, err := json.Marshal(span)
 		if err != nil {
 			errs = append(errs, (fmt.Errorf("Cannot encode span with id %v: %w", span.Span.SpanId, err)))
@@ -70,9 +81,10 @@ func writeSpans(ctx context.Context, logger *zap.Logger, c *opensearch.Client, i
 		buf.Write(meta)
 		buf.Write(data)
 	}
-	res, err := c.Bulk(bytes.NewReader(buf.Bytes()), c.Bulk.WithIndex(index))
+	res, err := c.Bulk(bytes.NewReader(buf.Bytes()), c.Bulk.WithContext(ctx), c.Bulk.WithIndex(index))
 	if err != nil { // handle errs from runtime
 		errs = append(errs, fmt.Errorf("Failure indexing %d spans: %w", numItems, err))
+		return multierr.Combine(errs...)
 	}
 	if res.IsError() { // handle errs from es
 		if err := json.NewDecoder(res.Body).Decode(&raw); err != nil {
@@ -89,7 +101,11 @@ func writeSpans(ctx context.Context, logger *zap.Logger, c *opensearch.Client, i
 		if err := json.NewDecoder(res.Body).Decode(&blk); err != nil {
 			errs = append(errs, fmt.Errorf("Failure to parse response body: %w", err))
 		} else {
+			overwritten := 0
 			for _, d := range blk.Items {
+				if d.Index.Result == "updated" {
+					overwritten++
+				}
 				if d.Index.Status > 201 {
 					errs = append(errs, fmt.Errorf("  Error: [%d]: %s: %s: %s: %s",
 						d.Index.Status,
@@ -100,6 +116,9 @@ func writeSpans(ctx context.Context, logger *zap.Logger, c *opensearch.Client, i
 					))
 				}
 			}
+			if overwritten > 0 {
+				logger.Debug("Overwrote spans that were already written", zap.Int("count", overwritten))
+			}
 		}
 	}
 
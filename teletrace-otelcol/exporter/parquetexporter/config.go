@@ -0,0 +1,92 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parquetexporter
+
+import (
+	"errors"
+
+	internalspanv1 "github.com/teletrace/teletrace/model/internalspan/v1"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/spanvalidation"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeahead"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writequeue"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeretry"
+
+	"go.opentelemetry.io/collector/config"
+)
+
+// Config defines configuration for Parquet exporter.
+type Config struct {
+	config.ExporterSettings `mapstructure:",squash"`
+
+	// Directory holds the hour partitions of span files, read by the parquet span reader.
+	// Defaults to embedded_spans_parquet
+	Directory string `mapstructure:"directory"`
+
+	// BinaryEncoding is the encoding of binary attribute values, either base64 or hex.
+	// Defaults to base64
+	BinaryEncoding string `mapstructure:"binary_encoding"`
+
+	// Retry configures retries of failed writes, and the dead-letter directory for exhausted batches.
+	Retry writeretry.Config `mapstructure:"retry_on_failure"`
+
+	// Queue configures the queue decoupling the ingestion from the storage writes.
+	Queue writequeue.Config `mapstructure:"sending_queue"`
+
+	// WriteAhead configures the write-ahead log persisting batches before they are acknowledged.
+	WriteAhead writeahead.Config `mapstructure:"write_ahead_log"`
+
+	// Validation configures rejecting malformed spans before they are written.
+	Validation spanvalidation.Config `mapstructure:"validation"`
+
+	// Retention configures deleting the partitions of aged spans.
+	Retention RetentionConfig `mapstructure:"retention"`
+}
+
+var errConfigNoDirectory = errors.New("directory must be specified")
+
+// Validate validates the Parquet exporter configuration.
+func (cfg *Config) Validate() error {
+	if cfg.Directory == "" {
+		return errConfigNoDirectory
+	}
+
+	if err := internalspanv1.ValidateBinaryEncoding(cfg.BinaryEncoding); err != nil {
+		return err
+	}
+
+	if err := cfg.Retry.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Queue.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.WriteAhead.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Validation.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Retention.Validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
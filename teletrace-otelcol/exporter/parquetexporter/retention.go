@@ -0,0 +1,131 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parquetexporter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RetentionConfig defines how long spans are kept.
+type RetentionConfig struct {
+	// MaxAge is the age, by start time, of the spans deleted by the retention. Retention is disabled if zero
+	MaxAge time.Duration `mapstructure:"max_age"`
+	// Interval is the interval between retention runs
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// NewDefaultRetentionConfig returns the default retention configuration, with retention disabled.
+func NewDefaultRetentionConfig() RetentionConfig {
+	return RetentionConfig{Interval: 10 * time.Minute}
+}
+
+// Enabled returns whether spans are deleted once aged.
+func (cfg *RetentionConfig) Enabled() bool {
+	return cfg.MaxAge > 0
+}
+
+// Validate validates the retention configuration.
+func (cfg *RetentionConfig) Validate() error {
+	if cfg.MaxAge < 0 {
+		return fmt.Errorf("retention max age must not be negative")
+	}
+	if !cfg.Enabled() {
+		return nil
+	}
+	if cfg.MaxAge < time.Hour {
+		return fmt.Errorf("retention max age must be at least %s", time.Hour)
+	}
+	if cfg.Interval <= 0 {
+		return fmt.Errorf("retention interval must be positive")
+	}
+	return nil
+}
+
+// startRetention applies the retention periodically in the background, until stopRetention is called.
+func (e *parquetTracesExporter) startRetention() {
+	ctx, cancel := context.WithCancel(context.Background())
+	e.stopRetention = cancel
+	e.retentionDone = make(chan struct{})
+
+	go func() {
+		defer close(e.retentionDone)
+		ticker := time.NewTicker(e.cfg.Retention.Interval)
+		defer ticker.Stop()
+		for {
+			if err := applyRetention(e.logger, e.cfg.Directory, time.Now().Add(-e.cfg.Retention.MaxAge)); err != nil {
+				e.logger.Error("Failed to apply retention", zap.Error(err))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// applyRetention deletes the hour partitions of dir whose spans all started before cutoff,
+// and the day partitions left empty.
+func applyRetention(logger *zap.Logger, dir string, cutoff time.Time) error {
+	days, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to list partitions: %w", err)
+	}
+	for _, day := range days {
+		if !day.IsDir() || !strings.HasPrefix(day.Name(), dayPrefix) {
+			continue
+		}
+		dayStart, err := time.Parse(dayLayout, strings.TrimPrefix(day.Name(), dayPrefix))
+		if err != nil || !dayStart.Before(cutoff) {
+			continue
+		}
+		dayDir := filepath.Join(dir, day.Name())
+		hours, err := os.ReadDir(dayDir)
+		if err != nil {
+			return fmt.Errorf("failed to list partitions: %w", err)
+		}
+		expired := 0
+		for _, hour := range hours {
+			h, err := strconv.Atoi(strings.TrimPrefix(hour.Name(), hourPrefix))
+			if err != nil || !hour.IsDir() || !strings.HasPrefix(hour.Name(), hourPrefix) {
+				continue
+			}
+			if dayStart.Add(time.Duration(h+1) * time.Hour).After(cutoff) {
+				continue
+			}
+			if err := os.RemoveAll(filepath.Join(dayDir, hour.Name())); err != nil {
+				return fmt.Errorf("failed to delete expired partition: %w", err)
+			}
+			logger.Info("Expired spans", zap.Time("partition", dayStart.Add(time.Duration(h)*time.Hour)))
+			expired++
+		}
+		if expired == len(hours) {
+			if err := os.Remove(dayDir); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to delete expired partition: %w", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parquetexporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/ingestionlag"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/modeltranslator"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/spanvalidation"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeahead"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writequeue"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeretry"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	_ "github.com/marcboeker/go-duckdb"
+)
+
+type parquetTracesExporter struct {
+	logger    *zap.Logger
+	cfg       *Config
+	writer    *writeretry.Writer
+	wal       *writeahead.Log
+	queue     *writequeue.Queue
+	validator *spanvalidation.Validator
+	lag       *ingestionlag.Recorder
+	db        *sql.DB
+
+	stopRetention context.CancelFunc
+	retentionDone chan struct{}
+}
+
+func newTracesExporter(logger *zap.Logger, cfg *Config) (*parquetTracesExporter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	writer, err := writeretry.NewWriter(logger, cfg.ID().String(), cfg.Retry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create retry writer: %+v", err)
+	}
+
+	wal, err := writeahead.NewLog(logger, cfg.ID().String(), cfg.WriteAhead)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create write-ahead log: %+v", err)
+	}
+
+	validator, err := spanvalidation.NewValidator(logger, cfg.ID().String(), cfg.Validation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create span validator: %+v", err)
+	}
+
+	lag, err := ingestionlag.NewRecorder(cfg.ID().String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ingestion lag recorder: %+v", err)
+	}
+
+	// span files left in staging by a previous run belong to batches which weren't acknowledged,
+	// so they're sent again or replayed from the write-ahead log
+	staging := filepath.Join(cfg.Directory, stagingDir)
+	if err := os.RemoveAll(staging); err != nil {
+		return nil, fmt.Errorf("failed to clean staging directory: %+v", err)
+	}
+	if err := os.MkdirAll(staging, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %+v", err)
+	}
+
+	// the in-memory database only holds the batches being copied to span files
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open duckdb: %+v", err)
+	}
+	if _, err := db.Exec(createBatchTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create batch table: %+v", err)
+	}
+
+	exporter := &parquetTracesExporter{
+		logger:    logger,
+		cfg:       cfg,
+		writer:    writer,
+		wal:       wal,
+		validator: validator,
+		lag:       lag,
+		db:        db,
+	}
+
+	if cfg.Queue.Enabled {
+		exporter.queue, err = writequeue.NewQueue(logger, cfg.ID().String(), cfg.Queue, func(ctx context.Context, item interface{}) error {
+			batch := item.(*writequeue.TracesBatch)
+			if err := exporter.write(ctx, batch.Traces, batch.WALEntries...); err != nil {
+				// The batch was acknowledged, so it's kept in the write-ahead log to be replayed
+				exporter.wal.Retry(batch.WALEntries...)
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to create write queue: %+v", err)
+		}
+		exporter.queue.OnDrop(func(item interface{}) {
+			exporter.wal.Retry(item.(*writequeue.TracesBatch).WALEntries...)
+		})
+	}
+
+	return exporter, nil
+}
+
+// Start starts the write queue workers and the retention, and replays the batches left in the write-ahead log
+// and dead-lettered by previous runs in the background.
+func (e *parquetTracesExporter) Start(_ context.Context, _ component.Host) error {
+	if e.queue != nil {
+		e.queue.Start()
+	}
+	if e.cfg.Retention.Enabled() {
+		e.startRetention()
+	}
+
+	e.writer.StartReplay(e.wal,
+		func(ctx context.Context, td ptrace.Traces) error { return e.write(ctx, td) },
+		e.writeTraces,
+	)
+	return nil
+}
+
+func (e *parquetTracesExporter) Shutdown(ctx context.Context) error {
+	e.writer.StopReplay()
+	if e.queue != nil {
+		if err := e.queue.Stop(); err != nil {
+			e.logger.Warn("Failed to write queued batches", zap.Error(err))
+		}
+	}
+	if e.stopRetention != nil {
+		e.stopRetention()
+		<-e.retentionDone
+	}
+	if err := e.db.Close(); err != nil {
+		return fmt.Errorf("could not shut down parquet exporter: %+v", err)
+	}
+	return nil
+}
+
+func (e *parquetTracesExporter) pushTracesData(ctx context.Context, td ptrace.Traces) error {
+	td = e.validator.Filter(ctx, td)
+	if td.SpanCount() == 0 {
+		return nil
+	}
+
+	walEntry, err := e.wal.Append(func() ([]byte, error) { return (&ptrace.ProtoMarshaler{}).MarshalTraces(td) })
+	if err != nil {
+		return err
+	}
+
+	if e.queue != nil {
+		err = e.queue.Enqueue(ctx, writequeue.NewTracesBatch(td, walEntry))
+	} else {
+		err = e.write(ctx, td, walEntry)
+	}
+	if err != nil {
+		// The batch isn't acknowledged, so the client sends it again
+		e.wal.Remove(walEntry)
+	}
+	return err
+}
+
+// write writes a batch, retrying on failure and dead-lettering it once retries are exhausted.
+// Its write-ahead log entries are removed once the batch is written or dead-lettered.
+func (e *parquetTracesExporter) write(ctx context.Context, td ptrace.Traces, walEntries ...string) error {
+	err := e.writer.Write(
+		ctx,
+		td.SpanCount(),
+		func(ctx context.Context) error { return e.writeTraces(ctx, td) },
+		func() ([]byte, error) { return (&ptrace.ProtoMarshaler{}).MarshalTraces(td) },
+	)
+	if err == nil {
+		e.wal.Remove(walEntries...)
+	}
+	return err
+}
+
+func (e *parquetTracesExporter) writeTraces(ctx context.Context, td ptrace.Traces) error {
+	ingestionTime := time.Now()
+	internalSpans := modeltranslator.TranslateOTLPToInternalModel(
+		td,
+		modeltranslator.WithIngestionTime(ingestionTime),
+		modeltranslator.WithBinaryEncoding(e.cfg.BinaryEncoding),
+	)
+	if err := e.writeSpans(ctx, internalSpans); err != nil {
+		return err
+	}
+	e.lag.Record(td, ingestionTime)
+	return nil
+}
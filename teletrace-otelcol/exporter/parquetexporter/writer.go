@@ -0,0 +1,180 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parquetexporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	internalspanv1 "github.com/teletrace/teletrace/model/internalspan/v1"
+)
+
+// The span files are partitioned by the UTC hour the spans started in, into hive partitions such as
+// day=2023-01-02/hour=15, which the parquet span reader prunes by the timeframe of its queries.
+const (
+	dayPrefix  = "day="
+	hourPrefix = "hour="
+	dayLayout  = "2006-01-02"
+	// stagingDir holds the span files while they're written, so the span reader only reads complete files
+	stagingDir = ".staging"
+)
+
+// createBatchTable creates the table of the in-memory DuckDB database holding the spans of a batch while they're
+// copied to span files. The fields filtered, grouped or aggregated by the most are stored in their own columns,
+// alongside the JSON of the whole span, and day and hour are the partition columns, stored in the directory names.
+const createBatchTable = `CREATE TABLE spans_batch (
+	trace_id VARCHAR NOT NULL,
+	span_id VARCHAR NOT NULL,
+	parent_span_id VARCHAR NOT NULL,
+	name VARCHAR NOT NULL,
+	kind VARCHAR NOT NULL,
+	status_code VARCHAR,
+	service_name VARCHAR,
+	start_time UBIGINT NOT NULL,
+	end_time UBIGINT NOT NULL,
+	duration UBIGINT,
+	ingestion_time UBIGINT NOT NULL,
+	span VARCHAR NOT NULL,
+	day VARCHAR NOT NULL,
+	hour INTEGER NOT NULL
+)`
+
+const insertSpan = "INSERT INTO spans_batch VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
+// storedSpan keeps the events and links of the span alongside it, as they aren't part of the span JSON.
+type storedSpan struct {
+	*internalspanv1.InternalSpan
+	Events []*internalspanv1.SpanEvent `json:"events"`
+	Links  []*internalspanv1.SpanLink  `json:"links"`
+}
+
+// writeSpans writes the spans to new span files in their partitions. Span files are only added, so a span written
+// again is stored twice.
+func (e *parquetTracesExporter) writeSpans(ctx context.Context, spans []*internalspanv1.InternalSpan) error {
+	staging, err := os.MkdirTemp(filepath.Join(e.cfg.Directory, stagingDir), "batch-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	if err := e.copySpans(ctx, spans, filepath.Join(staging, "spans")); err != nil {
+		return err
+	}
+	return publish(staging, e.cfg.Directory)
+}
+
+// copySpans writes the spans to span files in the partitions of dir, inserting them into the batch table
+// and copying them out in a transaction which is rolled back, leaving the table empty.
+// Transactions only see their own inserts, so batches are copied concurrently.
+func (e *parquetTracesExporter) copySpans(ctx context.Context, spans []*internalspanv1.InternalSpan, dir string) error {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, insertSpan)
+	if err != nil {
+		return fmt.Errorf("failed to prepare span insert: %w", err)
+	}
+	defer stmt.Close()
+	for _, span := range spans {
+		row, err := spanRow(span)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return fmt.Errorf("failed to insert span with id %v: %w", span.Span.SpanId, err)
+		}
+	}
+
+	// sorting the spans by start time lets the span reader skip the row groups outside of its timeframes
+	copySpans := fmt.Sprintf("COPY (SELECT * FROM spans_batch ORDER BY start_time) TO %s "+
+		"(FORMAT PARQUET, COMPRESSION ZSTD, PARTITION_BY (day, hour))", quote(dir))
+	if _, err := tx.ExecContext(ctx, copySpans); err != nil {
+		return fmt.Errorf("failed to write span files: %w", err)
+	}
+	return nil
+}
+
+// spanRow returns the values of the columns of span in the batch table.
+func spanRow(span *internalspanv1.InternalSpan) ([]any, error) {
+	data, err := json.Marshal(storedSpan{InternalSpan: span, Events: span.Span.Events, Links: span.Span.Links})
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode span with id %v: %w", span.Span.SpanId, err)
+	}
+
+	s := span.Span
+	var statusCode, service, duration any
+	if s.Status != nil {
+		statusCode = s.Status.Code
+	}
+	if name, ok := serviceName(span); ok {
+		service = name
+	}
+	if span.ExternalFields != nil {
+		duration = span.ExternalFields.DurationNano
+	}
+	start := time.Unix(0, int64(s.StartTimeUnixNano)).UTC()
+	return []any{
+		s.TraceId, s.SpanId, s.ParentSpanId, s.Name, s.Kind, statusCode, service,
+		s.StartTimeUnixNano, s.EndTimeUnixNano, duration, span.IngestionTimeUnixNano, string(data),
+		start.Format(dayLayout), start.Hour(),
+	}, nil
+}
+
+func serviceName(span *internalspanv1.InternalSpan) (string, bool) {
+	if span.Resource == nil {
+		return "", false
+	}
+	service, ok := span.Resource.Attributes["service.name"].(string)
+	return service, ok
+}
+
+// publish moves the span files of the staging directory of a batch to their partitions of dir, renaming them after
+// the batch so they don't replace the files of other batches.
+func publish(staging string, dir string) error {
+	batch := filepath.Base(staging)
+	return filepath.WalkDir(staging, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(filepath.Join(staging, "spans"), path)
+		if err != nil {
+			return err
+		}
+		partition := filepath.Join(dir, filepath.Dir(rel))
+		if err := os.MkdirAll(partition, 0o755); err != nil {
+			return fmt.Errorf("failed to create partition: %w", err)
+		}
+		if err := os.Rename(path, filepath.Join(partition, batch+"-"+d.Name())); err != nil {
+			return fmt.Errorf("failed to move span file to its partition: %w", err)
+		}
+		return nil
+	})
+}
+
+// quote returns s as a SQL string literal.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
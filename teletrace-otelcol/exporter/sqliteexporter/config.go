@@ -19,6 +19,12 @@ package sqliteexporter
 import (
 	"fmt"
 
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/replication"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/spanvalidation"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeahead"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writequeue"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeretry"
+
 	"go.opentelemetry.io/collector/config"
 )
 
@@ -28,6 +34,27 @@ type Config struct {
 
 	// Path is the sqlite instance full path.
 	Path string `mapstructure:"path"`
+
+	// Retry configures retries of failed writes, and the dead-letter directory for exhausted batches.
+	Retry writeretry.Config `mapstructure:"retry_on_failure"`
+
+	// Queue configures the queue decoupling the ingestion from the storage writes.
+	Queue writequeue.Config `mapstructure:"sending_queue"`
+
+	// WriteAhead configures the write-ahead log persisting batches before they are acknowledged.
+	WriteAhead writeahead.Config `mapstructure:"write_ahead_log"`
+
+	// Validation configures rejecting malformed spans before they are written.
+	Validation spanvalidation.Config `mapstructure:"validation"`
+
+	// Replication configures shipping committed batches to a peer Teletrace cluster, for cross-region redundancy.
+	Replication replication.Config `mapstructure:"replication"`
+
+	// Retention configures deleting aged spans, and rolling them up into hourly aggregates first.
+	Retention RetentionConfig `mapstructure:"retention"`
+
+	// Sharding configures splitting the spans across database files next to Path.
+	Sharding ShardingConfig `mapstructure:"sharding"`
 }
 
 // Validate validates the SQLite exporter configuration.
@@ -36,5 +63,33 @@ func (cfg *Config) Validate() error {
 		return fmt.Errorf("SQLite exporter requires a path, examples: '/database/my_spans.db'")
 	}
 
+	if err := cfg.Retry.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Queue.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.WriteAhead.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Validation.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Replication.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Retention.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Sharding.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -20,6 +20,12 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/replication"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/spanvalidation"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeahead"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writequeue"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeretry"
+
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config"
 	"go.opentelemetry.io/collector/exporter/exporterhelper"
@@ -42,6 +48,13 @@ func createDefaultConfig() component.ExporterConfig {
 	return &Config{
 		ExporterSettings: config.NewExporterSettings(component.NewID(typeStr)),
 		Path:             "teletrace_embedded.db",
+		Retry:            writeretry.NewDefaultConfig(),
+		Queue:            writequeue.NewDefaultConfig(),
+		WriteAhead:       writeahead.NewDefaultConfig(),
+		Validation:       spanvalidation.NewDefaultConfig(),
+		Replication:      replication.NewDefaultConfig(),
+		Retention:        NewDefaultRetentionConfig(),
+		Sharding:         NewDefaultShardingConfig(),
 	}
 }
 
@@ -58,6 +71,11 @@ func createTracesExporter(
 	return exporterhelper.NewTracesExporter(
 		ctx, set, cfg,
 		exporter.pushTracesData,
+		exporterhelper.WithStart(exporter.Start),
 		exporterhelper.WithShutdown(exporter.Shutdown),
+		// Retries are handled by the exporter itself, see Config.Retry. The retries may outlast any
+		// request timeout, so the exporterhelper timeout is disabled as well.
+		exporterhelper.WithRetry(exporterhelper.RetrySettings{Enabled: false}),
+		exporterhelper.WithTimeout(exporterhelper.TimeoutSettings{Timeout: 0}),
 	)
 }
@@ -61,6 +61,36 @@ func performInsert(tx *sql.Tx, query string, data ...any) (int64, error) {
 	return autoGeneratedId, nil
 }
 
+// writtenSpanTraceId returns the trace id of the written span with the given span id, if there's one.
+func writtenSpanTraceId(tx *sql.Tx, spanId string) (traceId string, exists bool, err error) {
+	err = tx.QueryRow("SELECT trace_id FROM spans WHERE span_id = ?", spanId).Scan(&traceId)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("could not query span: %v\n", err)
+	}
+	return traceId, true, nil
+}
+
+// deleteSpan deletes a written span along with its attributes, events and links, so it can be written again.
+func deleteSpan(tx *sql.Tx, spanId string) error {
+	for _, query := range []string{
+		"DELETE FROM span_attributes WHERE span_id = ?",
+		"DELETE FROM event_attributes WHERE event_id IN (SELECT id FROM events WHERE span_id = ?)",
+		"DELETE FROM events WHERE span_id = ?",
+		"DELETE FROM link_attributes WHERE link_id IN (SELECT id FROM links WHERE span_id = ?)",
+		"DELETE FROM links WHERE span_id = ?",
+		"DELETE FROM span_resource_attributes WHERE span_id = ?",
+		"DELETE FROM spans WHERE span_id = ?",
+	} {
+		if _, err := tx.Exec(query, spanId); err != nil {
+			return fmt.Errorf("could not delete span: %v\n", err)
+		}
+	}
+	return nil
+}
+
 func insertAttribute(tx *sql.Tx, attributeKind AttributeKind, id any, key string, value any, valueType string) error {
 	var table string
 	var idColumn string
@@ -95,7 +125,7 @@ func insertSpan(
 	scopeId int64,
 ) error {
 	duration := span.EndTimestamp() - span.StartTimestamp()
-	ingestionTimeUnixNano := uint64(time.Now().UTC().Nanosecond())
+	ingestionTimeUnixNano := uint64(time.Now().UnixNano())
 
 	_, err := performInsert(tx, `
 		INSERT INTO spans (
@@ -126,8 +156,8 @@ func insertSpan(
 
 func insertLink(tx *sql.Tx, link ptrace.SpanLink, spanId string) (int64, error) {
 	return performInsert(tx,
-		"INSERT INTO links (span_id, trace_state, dropped_attributes_count) VALUES (?, ?, ?)",
-		spanId, link.TraceState().AsRaw(), link.DroppedAttributesCount(),
+		"INSERT INTO links (span_id, linked_trace_id, linked_span_id, trace_state, dropped_attributes_count) VALUES (?, ?, ?, ?, ?)",
+		spanId, link.TraceID().HexString(), link.SpanID().HexString(), link.TraceState().AsRaw(), link.DroppedAttributesCount(),
 	)
 }
 
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS span_child_count_trigger;
+DROP INDEX IF EXISTS child_count_index;
+DROP INDEX IF EXISTS parent_span_id_index;
+ALTER TABLE spans DROP COLUMN child_count;
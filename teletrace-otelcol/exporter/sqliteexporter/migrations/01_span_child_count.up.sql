@@ -0,0 +1,20 @@
+ALTER TABLE spans ADD COLUMN child_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS parent_span_id_index
+ON spans (parent_span_id);
+
+CREATE INDEX IF NOT EXISTS child_count_index
+ON spans (child_count);
+
+UPDATE spans SET child_count = (SELECT COUNT(*) FROM spans AS children WHERE children.parent_span_id = spans.span_id);
+
+-- Children are usually written before their parent, so a new span counts its existing children,
+-- and increments the count of its parent if it was written already
+CREATE TRIGGER IF NOT EXISTS span_child_count_trigger
+AFTER INSERT ON spans
+BEGIN
+    UPDATE spans SET child_count = (SELECT COUNT(*) FROM spans AS children WHERE children.parent_span_id = NEW.span_id)
+    WHERE span_id = NEW.span_id;
+    UPDATE spans SET child_count = child_count + 1
+    WHERE span_id = NEW.parent_span_id AND NEW.parent_span_id != '';
+END;
@@ -0,0 +1 @@
+DROP TRIGGER IF EXISTS span_delete_child_count_trigger;
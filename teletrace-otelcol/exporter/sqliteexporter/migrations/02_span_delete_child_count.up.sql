@@ -0,0 +1,8 @@
+-- A span that's written again is deleted first, so the count of its parent is decremented
+-- before the span child count trigger increments it again
+CREATE TRIGGER IF NOT EXISTS span_delete_child_count_trigger
+AFTER DELETE ON spans
+BEGIN
+    UPDATE spans SET child_count = child_count - 1
+    WHERE span_id = OLD.parent_span_id AND OLD.parent_span_id != '';
+END;
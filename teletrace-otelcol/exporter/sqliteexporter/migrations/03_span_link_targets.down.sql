@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS linked_span_id_index;
+ALTER TABLE links DROP COLUMN linked_span_id;
+ALTER TABLE links DROP COLUMN linked_trace_id;
@@ -0,0 +1,6 @@
+-- The span context a link points to, so spans can be navigated through their links in both directions
+ALTER TABLE links ADD COLUMN linked_trace_id TEXT;
+ALTER TABLE links ADD COLUMN linked_span_id TEXT;
+
+CREATE INDEX IF NOT EXISTS linked_span_id_index
+ON links (linked_span_id);
@@ -0,0 +1,15 @@
+-- Hourly per-operation aggregates of the spans deleted by the retention, so long-term latency trends survive the raw spans
+CREATE TABLE IF NOT EXISTS span_rollups (
+    bucket_start_unix_nano INTEGER NOT NULL,
+    service_name TEXT NOT NULL,
+    span_name TEXT NOT NULL,
+    span_count INTEGER NOT NULL,
+    error_count INTEGER NOT NULL,
+    duration_sum INTEGER NOT NULL,
+    duration_min INTEGER NOT NULL,
+    duration_max INTEGER NOT NULL,
+    duration_p50 INTEGER NOT NULL,
+    duration_p95 INTEGER NOT NULL,
+    duration_p99 INTEGER NOT NULL,
+    PRIMARY KEY (bucket_start_unix_nano, service_name, span_name)
+) WITHOUT ROWID;
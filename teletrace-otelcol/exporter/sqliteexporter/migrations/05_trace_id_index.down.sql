@@ -0,0 +1 @@
+DROP INDEX IF EXISTS trace_id_index;
@@ -0,0 +1,3 @@
+-- Trace searches look the spans of the matching traces up by their trace ID
+CREATE INDEX IF NOT EXISTS trace_id_index
+ON spans (trace_id);
@@ -0,0 +1,278 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package sqliteexporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// rollupBucket is the time bucket of the span rollups, spans are expired one bucket at a time
+	rollupBucket    = time.Hour
+	errorStatusCode = "Error"
+)
+
+// RetentionConfig defines how long spans are kept, and whether they're rolled up before being deleted.
+type RetentionConfig struct {
+	// MaxAge is the age, by start time, of the spans deleted by the retention. Retention is disabled if zero
+	MaxAge time.Duration `mapstructure:"max_age"`
+	// Interval is the interval between retention runs
+	Interval time.Duration `mapstructure:"interval"`
+	// Rollup rolls the spans up into hourly per-operation aggregates before deleting them
+	Rollup bool `mapstructure:"rollup"`
+}
+
+// NewDefaultRetentionConfig returns the default retention configuration, with retention disabled.
+func NewDefaultRetentionConfig() RetentionConfig {
+	return RetentionConfig{
+		Interval: 10 * time.Minute,
+		Rollup:   true,
+	}
+}
+
+// Enabled returns whether spans are deleted once aged.
+func (cfg *RetentionConfig) Enabled() bool {
+	return cfg.MaxAge > 0
+}
+
+// Validate validates the retention configuration.
+func (cfg *RetentionConfig) Validate() error {
+	if cfg.MaxAge < 0 {
+		return fmt.Errorf("retention max age must not be negative")
+	}
+	if !cfg.Enabled() {
+		return nil
+	}
+	if cfg.MaxAge < rollupBucket {
+		return fmt.Errorf("retention max age must be at least %s", rollupBucket)
+	}
+	if cfg.Interval <= 0 {
+		return fmt.Errorf("retention interval must be positive")
+	}
+	return nil
+}
+
+type rollupKey struct {
+	serviceName string
+	spanName    string
+}
+
+type rollup struct {
+	errorCount int64
+	durations  []int64
+}
+
+// startRetention applies the retention periodically in the background, until stopRetention is called.
+func (exporter *sqliteTracesExporter) startRetention() {
+	ctx, cancel := context.WithCancel(context.Background())
+	exporter.stopRetention = cancel
+	exporter.retentionDone = make(chan struct{})
+
+	go func() {
+		defer close(exporter.retentionDone)
+		ticker := time.NewTicker(exporter.cfg.Retention.Interval)
+		defer ticker.Stop()
+		for {
+			if err := exporter.applyRetention(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				exporter.logger.Error("Failed to apply retention", zap.Error(err))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// applyRetention expires the spans of each database which started before the retention cutoff. The cutoff is truncated
+// to the bucket, so a bucket is only expired once all of its spans are aged. Without rollups, the day shards whose
+// whole day is aged are deleted at once.
+func (exporter *sqliteTracesExporter) applyRetention(ctx context.Context, now time.Time) error {
+	bucket := uint64(rollupBucket.Nanoseconds())
+	cutoff := uint64(now.Add(-exporter.cfg.Retention.MaxAge).UnixNano())
+	cutoff -= cutoff % bucket
+
+	paths, err := listPaths(exporter.cfg.Path, exporter.cfg.Sharding)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if day, ok := shardDay(exporter.cfg.Path, path); ok && !exporter.cfg.Retention.Rollup &&
+			uint64(day.Add(24*time.Hour).UnixNano()) <= cutoff {
+			if err := exporter.dbs.remove(path); err != nil {
+				return err
+			}
+			exporter.logger.Info("Expired shard", zap.String("path", path))
+			continue
+		}
+		db, err := exporter.dbs.get(path)
+		if err != nil {
+			return err
+		}
+		if err := exporter.applyDatabaseRetention(ctx, db, cutoff); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// applyDatabaseRetention expires the spans of db which started before cutoff, one bucket at a time, the oldest first.
+func (exporter *sqliteTracesExporter) applyDatabaseRetention(ctx context.Context, db *sql.DB, cutoff uint64) error {
+	bucket := uint64(rollupBucket.Nanoseconds())
+	for {
+		var oldest sql.NullInt64
+		if err := db.QueryRowContext(ctx, "SELECT MIN(start_time_unix_nano) FROM spans").Scan(&oldest); err != nil {
+			return fmt.Errorf("could not query oldest span: %w", err)
+		}
+		if !oldest.Valid || uint64(oldest.Int64) >= cutoff {
+			return nil
+		}
+
+		start := uint64(oldest.Int64) - uint64(oldest.Int64)%bucket
+		if err := exporter.expireBucket(ctx, db, start, start+bucket); err != nil {
+			return err
+		}
+	}
+}
+
+// expireBucket rolls up the spans which started within the bucket, if enabled, and deletes them
+// along with their attributes, events and links, in a single transaction.
+func (exporter *sqliteTracesExporter) expireBucket(ctx context.Context, db *sql.DB, start uint64, end uint64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if exporter.cfg.Retention.Rollup {
+		if err := rollupSpans(ctx, tx, start, end); err != nil {
+			return err
+		}
+	}
+
+	const bucketSpans = "SELECT span_id FROM spans WHERE start_time_unix_nano >= ? AND start_time_unix_nano < ?"
+	for _, query := range []string{
+		"DELETE FROM span_attributes WHERE span_id IN (" + bucketSpans + ")",
+		"DELETE FROM event_attributes WHERE event_id IN (SELECT id FROM events WHERE span_id IN (" + bucketSpans + "))",
+		"DELETE FROM events WHERE span_id IN (" + bucketSpans + ")",
+		"DELETE FROM link_attributes WHERE link_id IN (SELECT id FROM links WHERE span_id IN (" + bucketSpans + "))",
+		"DELETE FROM links WHERE span_id IN (" + bucketSpans + ")",
+		"DELETE FROM span_resource_attributes WHERE span_id IN (" + bucketSpans + ")",
+	} {
+		if _, err := tx.ExecContext(ctx, query, start, end); err != nil {
+			return fmt.Errorf("could not delete expired spans: %w", err)
+		}
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM spans WHERE start_time_unix_nano >= ? AND start_time_unix_nano < ?", start, end)
+	if err != nil {
+		return fmt.Errorf("could not delete expired spans: %w", err)
+	}
+	deleted, _ := res.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	exporter.logger.Info("Expired spans",
+		zap.Time("bucket", time.Unix(0, int64(start))), zap.Int64("spans", deleted))
+	return nil
+}
+
+// rollupSpans aggregates the spans which started within the bucket by service and span name into the span_rollups table.
+// Spans arriving late to a bucket which was rolled up already are merged into its rollups, where the percentiles are
+// approximated by their average weighted by the span counts.
+func rollupSpans(ctx context.Context, tx *sql.Tx, start uint64, end uint64) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			COALESCE((SELECT ra.value FROM span_resource_attributes sra JOIN resource_attributes ra ON ra.resource_id = sra.resource_attribute_id
+				WHERE sra.span_id = spans.span_id AND ra.key = 'service.name' LIMIT 1), ''),
+			COALESCE(spans.name, ''), spans.span_status_code, spans.duration
+		FROM spans WHERE start_time_unix_nano >= ? AND start_time_unix_nano < ?
+	`, start, end)
+	if err != nil {
+		return fmt.Errorf("could not query spans to roll up: %w", err)
+	}
+	defer rows.Close()
+
+	rollups := make(map[rollupKey]*rollup)
+	for rows.Next() {
+		var key rollupKey
+		var statusCode string
+		var duration int64
+		if err := rows.Scan(&key.serviceName, &key.spanName, &statusCode, &duration); err != nil {
+			return fmt.Errorf("could not scan span to roll up: %w", err)
+		}
+		r, ok := rollups[key]
+		if !ok {
+			r = &rollup{}
+			rollups[key] = r
+		}
+		if statusCode == errorStatusCode {
+			r.errorCount++
+		}
+		r.durations = append(r.durations, duration)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("could not read spans to roll up: %w", err)
+	}
+
+	for key, r := range rollups {
+		sort.Slice(r.durations, func(i, j int) bool { return r.durations[i] < r.durations[j] })
+		var sum int64
+		for _, d := range r.durations {
+			sum += d
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO span_rollups (
+				bucket_start_unix_nano, service_name, span_name, span_count, error_count,
+				duration_sum, duration_min, duration_max, duration_p50, duration_p95, duration_p99
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (bucket_start_unix_nano, service_name, span_name) DO UPDATE SET
+				duration_p50 = (duration_p50 * span_count + excluded.duration_p50 * excluded.span_count) / (span_count + excluded.span_count),
+				duration_p95 = (duration_p95 * span_count + excluded.duration_p95 * excluded.span_count) / (span_count + excluded.span_count),
+				duration_p99 = (duration_p99 * span_count + excluded.duration_p99 * excluded.span_count) / (span_count + excluded.span_count),
+				span_count = span_count + excluded.span_count,
+				error_count = error_count + excluded.error_count,
+				duration_sum = duration_sum + excluded.duration_sum,
+				duration_min = MIN(duration_min, excluded.duration_min),
+				duration_max = MAX(duration_max, excluded.duration_max)
+		`,
+			start, key.serviceName, key.spanName, len(r.durations), r.errorCount,
+			sum, r.durations[0], r.durations[len(r.durations)-1],
+			percentile(r.durations, 0.5), percentile(r.durations, 0.95), percentile(r.durations, 0.99),
+		)
+		if err != nil {
+			return fmt.Errorf("could not write span rollup: %w", err)
+		}
+	}
+	return nil
+}
+
+// percentile returns the nearest-rank percentile of sorted, non-empty values.
+func percentile(sorted []int64, p float64) int64 {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
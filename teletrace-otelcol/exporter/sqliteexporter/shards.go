@@ -0,0 +1,244 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqliteexporter
+
+import (
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// The shards are database files next to the configured path, suffixed by the UTC day their spans started in, e.g.
+// embedded_spans.2023-01-02.db, or by the hash of their trace id, e.g. embedded_spans.shard-3.db. The sqlite span
+// reader lists them the same way, see plugin/spanreader/sqlite/shards.go. TestShardNamesMatchReader pins the names and
+// hashes which the reader's TestShardNamesMatchExporter pins too, so a change to either breaks both.
+const (
+	ShardByDay     = "day"
+	ShardByTraceId = "trace_id"
+
+	shardDayLayout   = "2006-01-02"
+	traceShardPrefix = "shard-"
+)
+
+// ShardingConfig defines how the spans are split across database files, raising the write throughput and the size
+// ceiling of a single file.
+type ShardingConfig struct {
+	// By is the key of the shards, either day or trace_id. Sharding is disabled if empty
+	By string `mapstructure:"by"`
+	// Shards is the number of trace_id shards
+	Shards int `mapstructure:"shards"`
+}
+
+// NewDefaultShardingConfig returns the default sharding configuration, with sharding disabled.
+func NewDefaultShardingConfig() ShardingConfig {
+	return ShardingConfig{Shards: 8}
+}
+
+// Enabled returns whether the spans are split across database files.
+func (cfg *ShardingConfig) Enabled() bool {
+	return cfg.By != ""
+}
+
+// Validate validates the sharding configuration.
+func (cfg *ShardingConfig) Validate() error {
+	switch cfg.By {
+	case "", ShardByDay:
+		return nil
+	case ShardByTraceId:
+		if cfg.Shards <= 0 {
+			return fmt.Errorf("sharding by trace id requires a positive number of shards")
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid sharding key %q, must be %s or %s", cfg.By, ShardByDay, ShardByTraceId)
+	}
+}
+
+// shardPath returns the path of the shard of path with suffix.
+func shardPath(path string, suffix string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + suffix + ext
+}
+
+// traceShard returns the shard of a trace id, by the FNV-1a hash of its hex string.
+func traceShard(traceId string, shards int) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(traceId))
+	return int(hash.Sum32() % uint32(shards))
+}
+
+// spanPath returns the path of the database the span is written to.
+func spanPath(path string, cfg ShardingConfig, span ptrace.Span) string {
+	switch cfg.By {
+	case ShardByDay:
+		return shardPath(path, span.StartTimestamp().AsTime().UTC().Format(shardDayLayout))
+	case ShardByTraceId:
+		return shardPath(path, traceShardPrefix+strconv.Itoa(traceShard(span.TraceID().HexString(), cfg.Shards)))
+	default:
+		return path
+	}
+}
+
+// shardDay returns the day of the day shard at shard, if it is one.
+func shardDay(path string, shard string) (time.Time, bool) {
+	ext := filepath.Ext(path)
+	suffix := strings.TrimSuffix(strings.TrimPrefix(shard, strings.TrimSuffix(path, ext)+"."), ext)
+	day, err := time.Parse(shardDayLayout, suffix)
+	return day, err == nil
+}
+
+// listPaths returns the paths of the databases of the spans which exist, the path itself if sharding is disabled.
+func listPaths(path string, cfg ShardingConfig) ([]string, error) {
+	if !cfg.Enabled() {
+		return []string{path}, nil
+	}
+	ext := filepath.Ext(path)
+	files, err := filepath.Glob(strings.TrimSuffix(path, ext) + ".*" + ext)
+	if err != nil {
+		return nil, fmt.Errorf("could not list shards: %w", err)
+	}
+	var paths []string
+	for _, file := range files {
+		switch cfg.By {
+		case ShardByDay:
+			if _, ok := shardDay(path, file); ok {
+				paths = append(paths, file)
+			}
+		case ShardByTraceId:
+			for i := 0; i < cfg.Shards; i++ {
+				if file == shardPath(path, traceShardPrefix+strconv.Itoa(i)) {
+					paths = append(paths, file)
+				}
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// splitTraces splits the spans of traces by the path of their database, along with their resources and scopes.
+func splitTraces(traces ptrace.Traces, pathOf func(span ptrace.Span) string) map[string]ptrace.Traces {
+	split := make(map[string]ptrace.Traces)
+	resourceSpansSlice := traces.ResourceSpans()
+	for i := 0; i < resourceSpansSlice.Len(); i++ {
+		resourceSpans := resourceSpansSlice.At(i)
+		resources := make(map[string]ptrace.ResourceSpans)
+		scopeSpansSlice := resourceSpans.ScopeSpans()
+		for j := 0; j < scopeSpansSlice.Len(); j++ {
+			scopeSpans := scopeSpansSlice.At(j)
+			scopes := make(map[string]ptrace.ScopeSpans)
+			spanSlice := scopeSpans.Spans()
+			for k := 0; k < spanSlice.Len(); k++ {
+				span := spanSlice.At(k)
+				path := pathOf(span)
+				scope, ok := scopes[path]
+				if !ok {
+					resource, ok := resources[path]
+					if !ok {
+						pathTraces, ok := split[path]
+						if !ok {
+							pathTraces = ptrace.NewTraces()
+							split[path] = pathTraces
+						}
+						resource = pathTraces.ResourceSpans().AppendEmpty()
+						resourceSpans.Resource().CopyTo(resource.Resource())
+						resource.SetSchemaUrl(resourceSpans.SchemaUrl())
+						resources[path] = resource
+					}
+					scope = resource.ScopeSpans().AppendEmpty()
+					scopeSpans.Scope().CopyTo(scope.Scope())
+					scope.SetSchemaUrl(scopeSpans.SchemaUrl())
+					scopes[path] = scope
+				}
+				span.CopyTo(scope.Spans().AppendEmpty())
+			}
+		}
+	}
+	return split
+}
+
+// databases holds the open databases of the spans, each opened and migrated on its first use.
+type databases struct {
+	mu  sync.Mutex
+	dbs map[string]*sql.DB
+}
+
+func newDatabases() *databases {
+	return &databases{dbs: make(map[string]*sql.DB)}
+}
+
+// get returns the database at path, creating it if it doesn't exist.
+func (d *databases) get(path string) (*sql.DB, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if db, ok := d.dbs[path]; ok {
+		return db, nil
+	}
+	if err := migrateSchema(path); err != nil {
+		return nil, fmt.Errorf("could not migrate DB %s: %+v", path, err)
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open DB %s: %+v", path, err)
+	}
+	d.dbs[path] = db
+	return db, nil
+}
+
+// remove closes the database at path, if open, and deletes its files.
+func (d *databases) remove(path string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if db, ok := d.dbs[path]; ok {
+		delete(d.dbs, path)
+		if err := db.Close(); err != nil {
+			return fmt.Errorf("could not close DB %s: %w", path, err)
+		}
+	}
+	for _, file := range []string{path, path + "-wal", path + "-shm"} {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not delete DB %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// close closes all the open databases.
+func (d *databases) close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var errs []string
+	for path, db := range d.dbs {
+		if err := db.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", path, err))
+		}
+	}
+	d.dbs = make(map[string]*sql.DB)
+	if len(errs) > 0 {
+		return fmt.Errorf("could not close DBs: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqliteexporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// TestShardNamesMatchReader pins the shard files and trace id hashes of the sqlite span reader, whose
+// TestShardNamesMatchExporter pins the same, see plugin/spanreader/sqlite/shards_test.go.
+func TestShardNamesMatchReader(t *testing.T) {
+	span := ptrace.NewSpan()
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(time.Date(2023, 1, 2, 23, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "/data/spans.2023-01-02.db", spanPath("/data/spans.db", ShardingConfig{By: ShardByDay}, span))
+
+	cfg := ShardingConfig{By: ShardByTraceId, Shards: 8}
+	for traceId, path := range map[pcommon.TraceID]string{
+		{0x0a, 0xf7, 0x65, 0x19, 0x16, 0xcd, 0x43, 0xdd, 0x84, 0x48, 0xeb, 0x21, 0x1c, 0x80, 0x31, 0x9c}: "/data/spans.shard-5.db",
+		{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}: "/data/spans.shard-2.db",
+	} {
+		span.SetTraceID(traceId)
+		assert.Equal(t, path, spanPath("/data/spans.db", cfg, span))
+	}
+}
@@ -18,9 +18,16 @@ package sqliteexporter
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/ingestionlag"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/replication"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/spanvalidation"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeahead"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writequeue"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeretry"
+
+	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.uber.org/zap"
 
@@ -28,8 +35,19 @@ import (
 )
 
 type sqliteTracesExporter struct {
-	logger *zap.Logger
-	db     *sql.DB
+	logger    *zap.Logger
+	cfg       *Config
+	writer    *writeretry.Writer
+	wal       *writeahead.Log
+	queue     *writequeue.Queue
+	validator *spanvalidation.Validator
+	lag       *ingestionlag.Recorder
+	dbs       *databases
+
+	replicator *replication.Replicator
+
+	stopRetention context.CancelFunc
+	retentionDone chan struct{}
 }
 
 func newTracesExporter(logger *zap.Logger, cfg *Config) (*sqliteTracesExporter, error) {
@@ -37,31 +55,148 @@ func newTracesExporter(logger *zap.Logger, cfg *Config) (*sqliteTracesExporter,
 		return nil, err
 	}
 
-	dbName := cfg.Path
+	dbs := newDatabases()
+	if !cfg.Sharding.Enabled() {
+		// the shards are created on their first write, while an unsharded database is created upfront
+		if _, err := dbs.get(cfg.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	writer, err := writeretry.NewWriter(logger, cfg.ID().String(), cfg.Retry)
+	if err != nil {
+		return nil, fmt.Errorf("could not create retry writer: %+v", err)
+	}
 
-	if err := migrateSchema(dbName); err != nil {
-		return nil, fmt.Errorf("could not migrate DB: %+v", err)
+	wal, err := writeahead.NewLog(logger, cfg.ID().String(), cfg.WriteAhead)
+	if err != nil {
+		return nil, fmt.Errorf("could not create write-ahead log: %+v", err)
 	}
 
-	db, err := sql.Open("sqlite3", fmt.Sprintf("%s", dbName))
+	validator, err := spanvalidation.NewValidator(logger, cfg.ID().String(), cfg.Validation)
 	if err != nil {
-		return nil, fmt.Errorf("could not create sqlite exporter: %+v", err)
+		return nil, fmt.Errorf("could not create span validator: %+v", err)
 	}
 
-	return &sqliteTracesExporter{
-		logger: logger,
-		db:     db,
-	}, nil
+	lag, err := ingestionlag.NewRecorder(cfg.ID().String())
+	if err != nil {
+		return nil, fmt.Errorf("could not create ingestion lag recorder: %+v", err)
+	}
 
+	exporter := &sqliteTracesExporter{
+		logger:    logger,
+		cfg:       cfg,
+		writer:    writer,
+		wal:       wal,
+		validator: validator,
+		lag:       lag,
+		dbs:       dbs,
+	}
+
+	if cfg.Queue.Enabled {
+		exporter.queue, err = writequeue.NewQueue(logger, cfg.ID().String(), cfg.Queue, func(ctx context.Context, item interface{}) error {
+			batch := item.(*writequeue.TracesBatch)
+			if err := exporter.write(ctx, batch.Traces, batch.WALEntries...); err != nil {
+				// The batch was acknowledged, so it's kept in the write-ahead log to be replayed
+				exporter.wal.Retry(batch.WALEntries...)
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not create write queue: %+v", err)
+		}
+		exporter.queue.OnDrop(func(item interface{}) {
+			exporter.wal.Retry(item.(*writequeue.TracesBatch).WALEntries...)
+		})
+	}
+
+	if cfg.Replication.Enabled() {
+		exporter.replicator, err = replication.NewReplicator(logger, cfg.ID().String(), cfg.Replication)
+		if err != nil {
+			return nil, fmt.Errorf("could not create replicator: %+v", err)
+		}
+	}
+
+	return exporter, nil
+}
+
+// Start starts the write queue workers, the replicator and the retention, and replays the batches left in the
+// write-ahead log and dead-lettered by previous runs in the background.
+func (exporter *sqliteTracesExporter) Start(_ context.Context, _ component.Host) error {
+	if exporter.queue != nil {
+		exporter.queue.Start()
+	}
+	if exporter.replicator != nil {
+		exporter.replicator.Start()
+	}
+	if exporter.cfg.Retention.Enabled() {
+		exporter.startRetention()
+	}
+
+	exporter.writer.StartReplay(exporter.wal,
+		func(ctx context.Context, traces ptrace.Traces) error { return exporter.write(ctx, traces) },
+		exporter.writeTraces,
+	)
+	return nil
 }
 
 func (exporter *sqliteTracesExporter) Shutdown(ctx context.Context) error {
-	if err := exporter.db.Close(); err != nil {
+	exporter.writer.StopReplay()
+	if exporter.queue != nil {
+		if err := exporter.queue.Stop(); err != nil {
+			exporter.logger.Warn("Failed to write queued batches", zap.Error(err))
+		}
+	}
+	if exporter.replicator != nil {
+		if err := exporter.replicator.Stop(); err != nil {
+			exporter.logger.Warn("Failed to replicate queued batches", zap.Error(err))
+		}
+	}
+	if exporter.stopRetention != nil {
+		exporter.stopRetention()
+		<-exporter.retentionDone
+	}
+	if err := exporter.dbs.close(); err != nil {
 		return fmt.Errorf("could not shut down sqlite exporter: %+v", err)
 	}
 	return nil
 }
 
 func (exporter *sqliteTracesExporter) pushTracesData(ctx context.Context, traces ptrace.Traces) error {
-	return exporter.writeTraces(traces)
+	traces = exporter.validator.Filter(ctx, traces)
+	if traces.SpanCount() == 0 {
+		return nil
+	}
+
+	walEntry, err := exporter.wal.Append(func() ([]byte, error) { return (&ptrace.ProtoMarshaler{}).MarshalTraces(traces) })
+	if err != nil {
+		return err
+	}
+
+	if exporter.queue != nil {
+		err = exporter.queue.Enqueue(ctx, writequeue.NewTracesBatch(traces, walEntry))
+	} else {
+		err = exporter.write(ctx, traces, walEntry)
+	}
+	if err != nil {
+		// The batch isn't acknowledged, so the client sends it again
+		exporter.wal.Remove(walEntry)
+	}
+	return err
+}
+
+// write writes a batch, retrying on failure and dead-lettering it once retries are exhausted.
+// Its write-ahead log entries are removed once the batch is written or dead-lettered.
+func (exporter *sqliteTracesExporter) write(ctx context.Context, traces ptrace.Traces, walEntries ...string) error {
+	err := exporter.writer.Write(
+		ctx,
+		traces.SpanCount(),
+		func(ctx context.Context) error { return exporter.writeTraces(ctx, traces) },
+		func() ([]byte, error) { return (&ptrace.ProtoMarshaler{}).MarshalTraces(traces) },
+	)
+	if err == nil {
+		exporter.wal.Remove(walEntries...)
+	}
+	return err
 }
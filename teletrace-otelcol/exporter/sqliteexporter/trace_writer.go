@@ -17,18 +17,54 @@
 package sqliteexporter
 
 import (
+	"context"
 	"crypto/md5"
 	"database/sql"
 	"encoding/hex"
 	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	internalspanv1 "github.com/teletrace/teletrace/model/internalspan/v1"
 
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.uber.org/zap"
 )
 
-func (exporter *sqliteTracesExporter) writeTraces(traces ptrace.Traces) error {
-	tx, err := exporter.db.Begin()
+// writeTraces writes the spans to their databases. A batch spanning several shards is written in a transaction per
+// shard, so a failed write may leave some of its shards written, which the retried write replaces.
+func (exporter *sqliteTracesExporter) writeTraces(ctx context.Context, traces ptrace.Traces) error {
+	split := map[string]ptrace.Traces{exporter.cfg.Path: traces}
+	if exporter.cfg.Sharding.Enabled() {
+		split = splitTraces(traces, func(span ptrace.Span) string {
+			return spanPath(exporter.cfg.Path, exporter.cfg.Sharding, span)
+		})
+	}
+	for path, pathTraces := range split {
+		db, err := exporter.dbs.get(path)
+		if err != nil {
+			return err
+		}
+		if err := exporter.writeDatabaseTraces(ctx, db, pathTraces); err != nil {
+			return err
+		}
+	}
+	exporter.lag.Record(traces, time.Now())
+
+	if exporter.replicator != nil {
+		// The batch is committed locally, so a failure to replicate it must not fail the write
+		if err := exporter.replicator.Replicate(ctx, traces); err != nil {
+			exporter.logger.Warn("failed to queue batch for replication", zap.NamedError("reason", err))
+		}
+	}
+
+	return nil
+}
+
+func (exporter *sqliteTracesExporter) writeDatabaseTraces(ctx context.Context, db *sql.DB, traces ptrace.Traces) error {
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %+v\n", err)
 	}
@@ -163,6 +199,26 @@ func (exporter *sqliteTracesExporter) writeSpanLinks(
 func (exporter *sqliteTracesExporter) writeSpan(
 	tx *sql.Tx, span ptrace.Span, droppedResourceAttributesCount uint32, resourceAttributesIds map[string]string, scopeId int64) error {
 	spanId := span.SpanID().HexString()
+	// A span is identified by its trace id and span id, a span that's written again, e.g. when an SDK retries
+	// an export or a batch is replicated after it was committed, replaces the written span instead of duplicating it
+	writtenTraceId, exists, err := writtenSpanTraceId(tx, spanId)
+	if err != nil {
+		exporter.logger.Error("could not check if span exists", zap.NamedError("reason", err))
+		return err
+	}
+	if exists {
+		if traceId := span.TraceID().HexString(); writtenTraceId != traceId {
+			// span ids are the primary key of the spans table, so a colliding span of another trace can't be written
+			exporter.logger.Warn("skipping span whose id collides with a span of another trace",
+				zap.String("spanId", spanId), zap.String("traceId", traceId), zap.String("writtenTraceId", writtenTraceId))
+			return nil
+		}
+		if err := deleteSpan(tx, spanId); err != nil {
+			exporter.logger.Error("could not replace span", zap.NamedError("reason", err))
+			return err
+		}
+	}
+
 	if err := insertSpan(tx, span, spanId, droppedResourceAttributesCount, resourceAttributesIds, scopeId); err != nil {
 		exporter.logger.Error("could not insert span", zap.NamedError("reason", err))
 		return err
@@ -195,8 +251,16 @@ func (exporter *sqliteTracesExporter) writeAttributes(
 		value, _ := attributes.Get(key)
 
 		finalValue := value.AsRaw()
-		if value.Type() == pcommon.ValueTypeSlice { // Temporary handling for array values
+		switch value.Type() {
+		case pcommon.ValueTypeSlice, pcommon.ValueTypeMap:
+			// array and kvlist values are stored as JSON, which the span reader queries with the sqlite JSON functions
 			finalValue = value.AsString()
+		case pcommon.ValueTypeBytes:
+			// binary values are stored in base64, which the span reader decodes by their Bytes type,
+			// as the JSON functions of sqlite can't hold blobs
+			finalValue = internalspanv1.EncodeBinaryValue(value.Bytes().AsRaw(), internalspanv1.BinaryEncodingBase64)
+		case pcommon.ValueTypeStr:
+			finalValue = strings.ToValidUTF8(value.Str(), string(utf8.RuneError))
 		}
 
 		// In case attributeKind is a resource attribute, id is actually a map - so check and convert to get current resource id
@@ -0,0 +1,85 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package teletraceexporter
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writequeue"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeretry"
+
+	"go.opentelemetry.io/collector/config"
+)
+
+const (
+	compressionGzip = "gzip"
+	compressionNone = "none"
+)
+
+// Config defines configuration for Teletrace exporter.
+type Config struct {
+	config.ExporterSettings `mapstructure:",squash"`
+
+	// Endpoint is the OTLP/HTTP traces endpoint of the Teletrace collector, e.g. http://teletrace:4318/v1/traces.
+	Endpoint string `mapstructure:"endpoint"`
+
+	// Headers are added to the requests sent to Teletrace, e.g. for authentication.
+	Headers map[string]string `mapstructure:"headers"`
+
+	// Timeout is the timeout of a single request to Teletrace.
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// Compression is the compression of the request bodies, either gzip or none.
+	Compression string `mapstructure:"compression"`
+
+	// Retry configures retries of failed requests, and the dead-letter directory for exhausted batches.
+	Retry writeretry.Config `mapstructure:"retry_on_failure"`
+
+	// Queue configures the queue batching the spans sent to Teletrace.
+	Queue writequeue.Config `mapstructure:"sending_queue"`
+}
+
+// Validate validates the Teletrace exporter configuration.
+func (cfg *Config) Validate() error {
+	if cfg.Endpoint == "" {
+		return fmt.Errorf("Teletrace exporter requires an endpoint, examples: 'http://teletrace:4318/v1/traces'")
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid endpoint %q, expected an http or https URL", cfg.Endpoint)
+	}
+
+	if cfg.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+
+	if cfg.Compression != compressionGzip && cfg.Compression != compressionNone {
+		return fmt.Errorf("invalid compression %q, expected %s or %s", cfg.Compression, compressionGzip, compressionNone)
+	}
+
+	if err := cfg.Retry.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Queue.Validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
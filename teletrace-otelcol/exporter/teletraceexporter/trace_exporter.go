@@ -0,0 +1,239 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package teletraceexporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writequeue"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeretry"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+type teletraceTracesExporter struct {
+	logger *zap.Logger
+	cfg    *Config
+	client *http.Client
+	writer *writeretry.Writer
+	queue  *writequeue.Queue
+}
+
+func newTracesExporter(logger *zap.Logger, cfg *Config) (*teletraceTracesExporter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	writer, err := writeretry.NewWriter(logger, cfg.ID().String(), cfg.Retry)
+	if err != nil {
+		return nil, fmt.Errorf("could not create retry writer: %+v", err)
+	}
+
+	exporter := &teletraceTracesExporter{
+		logger: logger,
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		writer: writer,
+	}
+
+	if cfg.Queue.Enabled {
+		exporter.queue, err = writequeue.NewQueue(logger, cfg.ID().String(), cfg.Queue, func(ctx context.Context, item interface{}) error {
+			return exporter.write(ctx, item.(*queuedBatch).traces)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not create write queue: %+v", err)
+		}
+	}
+
+	return exporter, nil
+}
+
+// queuedBatch is a batch waiting in the write queue.
+// Queued batches are merged up to the queue's batch size, so they are sent in a single request.
+type queuedBatch struct {
+	traces    ptrace.Traces
+	spanCount int
+	merged    bool
+}
+
+// Size returns the number of spans in the batch.
+func (b *queuedBatch) Size() int {
+	return b.spanCount
+}
+
+// Merge appends the spans of other to the batch. The received batches are read-only,
+// so the batch is copied before its first merge.
+func (b *queuedBatch) Merge(other writequeue.Batch) {
+	o := other.(*queuedBatch)
+	if !b.merged {
+		traces := ptrace.NewTraces()
+		b.traces.CopyTo(traces)
+		b.traces = traces
+		b.merged = true
+	}
+	resourceSpans := o.traces.ResourceSpans()
+	for i := 0; i < resourceSpans.Len(); i++ {
+		resourceSpans.At(i).CopyTo(b.traces.ResourceSpans().AppendEmpty())
+	}
+	b.spanCount += o.spanCount
+}
+
+// Start starts the write queue workers, and resends the batches dead-lettered by previous runs in the background.
+func (exporter *teletraceTracesExporter) Start(_ context.Context, _ component.Host) error {
+	if exporter.queue != nil {
+		exporter.queue.Start()
+	}
+
+	go func() {
+		err := exporter.writer.Replay(context.Background(), exporter.send)
+		if err != nil {
+			exporter.logger.Error("Failed to replay dead-lettered batches", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+func (exporter *teletraceTracesExporter) Shutdown(_ context.Context) error {
+	if exporter.queue != nil {
+		if err := exporter.queue.Stop(); err != nil {
+			exporter.logger.Warn("Failed to send queued batches", zap.Error(err))
+		}
+	}
+	exporter.client.CloseIdleConnections()
+	return nil
+}
+
+func (exporter *teletraceTracesExporter) pushTracesData(ctx context.Context, traces ptrace.Traces) error {
+	if traces.SpanCount() == 0 {
+		return nil
+	}
+	if exporter.queue != nil {
+		return exporter.queue.Enqueue(ctx, &queuedBatch{traces: traces, spanCount: traces.SpanCount()})
+	}
+	err := exporter.write(ctx, traces)
+	var permanent *writeretry.PermanentError
+	if errors.As(err, &permanent) {
+		// The batch is rejected however many times it's sent, so the pipeline doesn't retry it either
+		return consumererror.NewPermanent(err)
+	}
+	return err
+}
+
+// write sends a batch, retrying on failure and dead-lettering it once retries are exhausted.
+func (exporter *teletraceTracesExporter) write(ctx context.Context, traces ptrace.Traces) error {
+	payload, err := (&ptrace.ProtoMarshaler{}).MarshalTraces(traces)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
+	}
+	return exporter.writer.Write(
+		ctx,
+		traces.SpanCount(),
+		func(ctx context.Context) error { return exporter.send(ctx, payload) },
+		func() ([]byte, error) { return payload, nil },
+	)
+}
+
+// send posts an OTLP protobuf payload to Teletrace. Since span writes are idempotent,
+// a payload that was written but not acknowledged can safely be sent again.
+// As in the OTLP/HTTP specification, only the 429, 502, 503 and 504 statuses are retried, honoring their
+// Retry-After header, while other statuses, e.g. 400 for a malformed payload, fail with a PermanentError.
+func (exporter *teletraceTracesExporter) send(ctx context.Context, payload []byte) error {
+	body, err := exporter.encode(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, exporter.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	if exporter.cfg.Compression == compressionGzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range exporter.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := exporter.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send batch to Teletrace: %w", err)
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return nil
+	}
+	err = fmt.Errorf("Teletrace responded with status %d", res.StatusCode)
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if retryAfter, ok := parseRetryAfter(res.Header.Get("Retry-After"), time.Now()); ok {
+			return &writeretry.ThrottledError{Err: err, RetryAfter: retryAfter}
+		}
+		return err
+	default:
+		return &writeretry.PermanentError{Err: err}
+	}
+}
+
+// parseRetryAfter parses the value of a Retry-After header, either a number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := date.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// encode compresses the payload with the configured compression.
+func (exporter *teletraceTracesExporter) encode(payload []byte) ([]byte, error) {
+	if exporter.cfg.Compression != compressionGzip {
+		return payload, nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(payload); err != nil {
+		return nil, fmt.Errorf("failed to compress batch: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress batch: %w", err)
+	}
+	return buf.Bytes(), nil
+}
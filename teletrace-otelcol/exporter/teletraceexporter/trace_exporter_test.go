@@ -0,0 +1,148 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package teletraceexporter
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeretry"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+func newTestExporter(t *testing.T, endpoint string) *teletraceTracesExporter {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Endpoint = endpoint
+	cfg.Queue.Enabled = false
+	cfg.Retry.InitialInterval = time.Millisecond
+	cfg.Retry.MaxInterval = time.Millisecond
+	exporter, err := newTracesExporter(zap.NewNop(), cfg)
+	assert.NoError(t, err)
+	return exporter
+}
+
+func newTestTraces() ptrace.Traces {
+	td := ptrace.NewTraces()
+	td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("checkout")
+	return td
+}
+
+func TestPushTracesRetriesUntilAccepted(t *testing.T) {
+	var received []ptrace.Traces
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zr, err := gzip.NewReader(r.Body)
+		assert.NoError(t, err)
+		body, err := io.ReadAll(zr)
+		assert.NoError(t, err)
+		td, err := (&ptrace.ProtoUnmarshaler{}).UnmarshalTraces(body)
+		assert.NoError(t, err)
+		received = append(received, td)
+		if len(received) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	exporter := newTestExporter(t, server.URL)
+
+	assert.NoError(t, exporter.pushTracesData(context.Background(), newTestTraces()))
+	assert.Len(t, received, 2)
+	assert.Equal(t, "checkout", received[1].ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Name())
+}
+
+func TestPushTracesDoesNotRetryRejectedBatches(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+	exporter := newTestExporter(t, server.URL)
+
+	err := exporter.pushTracesData(context.Background(), newTestTraces())
+	assert.True(t, consumererror.IsPermanent(err))
+	assert.Equal(t, 1, requests)
+}
+
+func TestSendClassifiesErrors(t *testing.T) {
+	for status, expected := range map[int]string{
+		http.StatusOK:                  "",
+		http.StatusAccepted:            "",
+		http.StatusBadRequest:          "permanent",
+		http.StatusUnauthorized:        "permanent",
+		http.StatusInternalServerError: "permanent",
+		http.StatusTooManyRequests:     "throttled",
+		http.StatusBadGateway:          "retryable",
+		http.StatusServiceUnavailable:  "throttled",
+		http.StatusGatewayTimeout:      "retryable",
+	} {
+		status := status
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+				w.Header().Set("Retry-After", "30")
+			}
+			w.WriteHeader(status)
+		}))
+		exporter := newTestExporter(t, server.URL)
+
+		err := exporter.send(context.Background(), []byte{})
+		var permanent *writeretry.PermanentError
+		var throttled *writeretry.ThrottledError
+		switch expected {
+		case "":
+			assert.NoError(t, err, "status %d", status)
+		case "permanent":
+			assert.True(t, errors.As(err, &permanent), "status %d", status)
+		case "throttled":
+			assert.True(t, errors.As(err, &throttled), "status %d", status)
+			assert.Equal(t, 30*time.Second, throttled.RetryAfter)
+		case "retryable":
+			assert.Error(t, err, "status %d", status)
+			assert.False(t, errors.As(err, &permanent), "status %d", status)
+			assert.False(t, errors.As(err, &throttled), "status %d", status)
+		}
+		server.Close()
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	for value, expected := range map[string]time.Duration{
+		"120":                           2 * time.Minute,
+		"0":                             0,
+		"Tue, 01 Nov 2022 12:00:30 GMT": 30 * time.Second,
+		"Tue, 01 Nov 2022 11:59:00 GMT": 0,
+	} {
+		retryAfter, ok := parseRetryAfter(value, now)
+		assert.True(t, ok, value)
+		assert.Equal(t, expected, retryAfter, value)
+	}
+	for _, value := range []string{"", "-1", "soon"} {
+		_, ok := parseRetryAfter(value, now)
+		assert.False(t, ok, value)
+	}
+}
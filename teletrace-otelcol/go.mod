@@ -5,9 +5,17 @@ go 1.19
 require (
 	github.com/open-telemetry/opentelemetry-collector-contrib/processor/attributesprocessor v0.64.0
 	github.com/open-telemetry/opentelemetry-collector-contrib/processor/transformprocessor v0.64.0
+	github.com/teletrace/teletrace/teletrace-otelcol/exporter/badgerexporter v0.0.0-00010101000000-000000000000
+	github.com/teletrace/teletrace/teletrace-otelcol/exporter/cassandraexporter v0.0.0-00010101000000-000000000000
 	github.com/teletrace/teletrace/teletrace-otelcol/exporter/elasticsearchexporter v0.0.0-00010101000000-000000000000
 	github.com/teletrace/teletrace/teletrace-otelcol/exporter/opensearchexporter v0.0.0-00010101000000-000000000000
+	github.com/teletrace/teletrace/teletrace-otelcol/exporter/parquetexporter v0.0.0-00010101000000-000000000000
 	github.com/teletrace/teletrace/teletrace-otelcol/exporter/sqliteexporter v0.0.0-00010101000000-000000000000
+	github.com/teletrace/teletrace/teletrace-otelcol/processor/geoipprocessor v0.0.0-00010101000000-000000000000
+	github.com/teletrace/teletrace/teletrace-otelcol/processor/k8smetadataprocessor v0.0.0-00010101000000-000000000000
+	github.com/teletrace/teletrace/teletrace-otelcol/processor/ratelimitprocessor v0.0.0-00010101000000-000000000000
+	github.com/teletrace/teletrace/teletrace-otelcol/processor/routetemplateprocessor v0.0.0-00010101000000-000000000000
+	github.com/teletrace/teletrace/teletrace-otelcol/processor/servicenameprocessor v0.0.0-00010101000000-000000000000
 	go.opentelemetry.io/collector v0.64.1
 	go.opentelemetry.io/collector/processor/batchprocessor v0.64.1
 	go.opentelemetry.io/collector/receiver/otlpreceiver v0.64.1
@@ -15,34 +23,67 @@ require (
 
 require (
 	contrib.go.opencensus.io/exporter/prometheus v0.4.2 // indirect
+	github.com/AndreasBriese/bbloom v0.0.0-20190825152654-46b345b51c96 // indirect
+	github.com/PuerkitoBio/purell v1.1.1 // indirect
+	github.com/PuerkitoBio/urlesc v0.0.0-20170810143723-de5bf2ad4578 // indirect
 	github.com/alecthomas/participle/v2 v2.0.0-beta.5 // indirect
 	github.com/antonmedv/expr v1.9.0 // indirect
+	github.com/aws/aws-sdk-go-v2 v1.17.1 // indirect
+	github.com/aws/aws-sdk-go-v2/config v1.18.3 // indirect
+	github.com/aws/aws-sdk-go-v2/credentials v1.13.3 // indirect
+	github.com/aws/aws-sdk-go-v2/feature/ec2/imds v1.12.19 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.1.25 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.4.19 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/ini v1.3.26 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/presigned-url v1.9.19 // indirect
+	github.com/aws/aws-sdk-go-v2/service/sso v1.11.25 // indirect
+	github.com/aws/aws-sdk-go-v2/service/ssooidc v1.13.8 // indirect
+	github.com/aws/aws-sdk-go-v2/service/sts v1.17.5 // indirect
+	github.com/aws/smithy-go v1.13.4 // indirect
 	github.com/beorn7/perks v1.0.1 // indirect
 	github.com/cenkalti/backoff/v4 v4.1.3 // indirect
+	github.com/cespare/xxhash v1.1.0 // indirect
 	github.com/cespare/xxhash/v2 v2.1.2 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/dgraph-io/badger v1.6.2 // indirect
+	github.com/dgraph-io/ristretto v0.0.2 // indirect
+	github.com/dustin/go-humanize v1.0.0 // indirect
 	github.com/elastic/elastic-transport-go/v8 v8.1.0 // indirect
 	github.com/elastic/go-elasticsearch/v8 v8.4.0 // indirect
+	github.com/emicklei/go-restful/v3 v3.8.0 // indirect
 	github.com/felixge/httpsnoop v1.0.3 // indirect
 	github.com/go-kit/log v0.2.1 // indirect
 	github.com/go-logfmt/logfmt v0.5.1 // indirect
 	github.com/go-logr/logr v1.2.3 // indirect
 	github.com/go-logr/stdr v1.2.2 // indirect
 	github.com/go-ole/go-ole v1.2.6 // indirect
+	github.com/go-openapi/jsonpointer v0.19.5 // indirect
+	github.com/go-openapi/jsonreference v0.19.5 // indirect
+	github.com/go-openapi/swag v0.19.14 // indirect
 	github.com/gobwas/glob v0.2.3 // indirect
+	github.com/gocql/gocql v1.6.0 // indirect
 	github.com/gogo/protobuf v1.3.2 // indirect
 	github.com/golang-migrate/migrate/v4 v4.15.2 // indirect
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/golang/snappy v0.0.4 // indirect
+	github.com/google/gnostic v0.5.7-v3refs // indirect
+	github.com/google/go-cmp v0.5.9 // indirect
+	github.com/google/gofuzz v1.2.0 // indirect
 	github.com/google/uuid v1.3.0 // indirect
+	github.com/hailocab/go-hostpool v0.0.0-20160125115350-e80d13ce29ed // indirect
 	github.com/hashicorp/errwrap v1.1.0 // indirect
 	github.com/hashicorp/go-multierror v1.1.1 // indirect
 	github.com/iancoleman/strcase v0.2.0 // indirect
+	github.com/imdario/mergo v0.3.12 // indirect
 	github.com/inconshreveable/mousetrap v1.0.1 // indirect
+	github.com/josharian/intern v1.0.0 // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
 	github.com/klauspost/compress v1.15.12 // indirect
 	github.com/knadh/koanf v1.4.4 // indirect
 	github.com/lufia/plan9stats v0.0.0-20211012122336-39d0f177ccd0 // indirect
+	github.com/mailru/easyjson v0.7.6 // indirect
+	github.com/marcboeker/go-duckdb v1.5.6 // indirect
 	github.com/mattn/go-sqlite3 v1.14.16 // indirect
 	github.com/matttproud/golang_protobuf_extensions v1.0.2-0.20181231171920-c182affec369 // indirect
 	github.com/mitchellh/copystructure v1.2.0 // indirect
@@ -51,9 +92,13 @@ require (
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/mostynb/go-grpc-compression v1.1.17 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
 	github.com/open-telemetry/opentelemetry-collector-contrib/internal/coreinternal v0.64.0 // indirect
 	github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl v0.64.0 // indirect
 	github.com/opensearch-project/opensearch-go v1.1.0 // indirect
+	github.com/oschwald/geoip2-golang v1.9.0 // indirect
+	github.com/oschwald/maxminddb-golang v1.12.0 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
 	github.com/power-devops/perfstat v0.0.0-20210106213030-5aafc221ea8c // indirect
 	github.com/prometheus/client_golang v1.13.1 // indirect
 	github.com/prometheus/client_model v0.3.0 // indirect
@@ -65,8 +110,19 @@ require (
 	github.com/spf13/cast v1.5.0 // indirect
 	github.com/spf13/cobra v1.6.1 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
+	github.com/teletrace/teletrace/blobstore v0.0.0-00010101000000-000000000000 // indirect
+	github.com/teletrace/teletrace/circuitbreaker v0.0.0-00010101000000-000000000000 // indirect
+	github.com/teletrace/teletrace/kvstore v0.0.0-00010101000000-000000000000 // indirect
 	github.com/teletrace/teletrace/model v0.0.0-00010101000000-000000000000 // indirect
+	github.com/teletrace/teletrace/ratelimit v0.0.0-00010101000000-000000000000 // indirect
+	github.com/teletrace/teletrace/teletrace-otelcol/internal/childcount v0.0.0-00010101000000-000000000000 // indirect
+	github.com/teletrace/teletrace/teletrace-otelcol/internal/ingestionlag v0.0.0-00010101000000-000000000000 // indirect
 	github.com/teletrace/teletrace/teletrace-otelcol/internal/modeltranslator v0.0.0-00010101000000-000000000000 // indirect
+	github.com/teletrace/teletrace/teletrace-otelcol/internal/replication v0.0.0-00010101000000-000000000000 // indirect
+	github.com/teletrace/teletrace/teletrace-otelcol/internal/spanvalidation v0.0.0-00010101000000-000000000000 // indirect
+	github.com/teletrace/teletrace/teletrace-otelcol/internal/writeahead v0.0.0-00010101000000-000000000000 // indirect
+	github.com/teletrace/teletrace/teletrace-otelcol/internal/writequeue v0.0.0-00010101000000-000000000000 // indirect
+	github.com/teletrace/teletrace/teletrace-otelcol/internal/writeretry v0.0.0-00010101000000-000000000000 // indirect
 	github.com/tklauser/go-sysconf v0.3.10 // indirect
 	github.com/tklauser/numcpus v0.4.0 // indirect
 	github.com/yusufpapurcu/wmi v1.2.2 // indirect
@@ -87,21 +143,73 @@ require (
 	go.uber.org/zap v1.23.0 // indirect
 	golang.org/x/exp v0.0.0-20220722155223-a9213eeb770e // indirect
 	golang.org/x/net v0.7.0 // indirect
-	golang.org/x/sys v0.5.0 // indirect
+	golang.org/x/oauth2 v0.0.0-20220223155221-ee480838109b // indirect
+	golang.org/x/sys v0.10.0 // indirect
+	golang.org/x/term v0.5.0 // indirect
 	golang.org/x/text v0.7.0 // indirect
+	golang.org/x/time v0.0.0-20220224211638-0e9765cccd65 // indirect
+	google.golang.org/appengine v1.6.7 // indirect
 	google.golang.org/genproto v0.0.0-20221027153422-115e99e71e1c // indirect
 	google.golang.org/grpc v1.51.0 // indirect
 	google.golang.org/protobuf v1.28.1 // indirect
+	gopkg.in/inf.v0 v0.9.1 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
+	k8s.io/api v0.25.4 // indirect
+	k8s.io/apimachinery v0.25.4 // indirect
+	k8s.io/client-go v0.25.4 // indirect
+	k8s.io/klog/v2 v2.70.1 // indirect
+	k8s.io/kube-openapi v0.0.0-20220803162953-67bda5d908f1 // indirect
+	k8s.io/utils v0.0.0-20220728103510-ee6ede2d64ed // indirect
+	sigs.k8s.io/json v0.0.0-20220713155537-f223a00ba0e2 // indirect
+	sigs.k8s.io/structured-merge-diff/v4 v4.2.3 // indirect
+	sigs.k8s.io/yaml v1.2.0 // indirect
 )
 
+replace github.com/teletrace/teletrace/blobstore => ../blobstore
+
+replace github.com/teletrace/teletrace/circuitbreaker => ../circuitbreaker
+
+replace github.com/teletrace/teletrace/kvstore => ../kvstore
+
 replace github.com/teletrace/teletrace/model => ../model
 
+replace github.com/teletrace/teletrace/ratelimit => ../ratelimit
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/exporter/badgerexporter => ./exporter/badgerexporter
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/exporter/cassandraexporter => ./exporter/cassandraexporter
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/exporter/parquetexporter => ./exporter/parquetexporter
+
 replace github.com/teletrace/teletrace/teletrace-otelcol/internal/modeltranslator => ./internal/modeltranslator
 
+replace github.com/teletrace/teletrace/teletrace-otelcol/internal/replication => ./internal/replication
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/internal/childcount => ./internal/childcount
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/internal/ingestionlag => ./internal/ingestionlag
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/internal/spanvalidation => ./internal/spanvalidation
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/internal/writeahead => ./internal/writeahead
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/internal/writequeue => ./internal/writequeue
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/internal/writeretry => ./internal/writeretry
+
 replace github.com/teletrace/teletrace/teletrace-otelcol/exporter/elasticsearchexporter => ./exporter/elasticsearchexporter
 
 replace github.com/teletrace/teletrace/teletrace-otelcol/exporter/opensearchexporter => ./exporter/opensearchexporter
 
 replace github.com/teletrace/teletrace/teletrace-otelcol/exporter/sqliteexporter => ./exporter/sqliteexporter
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/processor/geoipprocessor => ./processor/geoipprocessor
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/processor/k8smetadataprocessor => ./processor/k8smetadataprocessor
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/processor/ratelimitprocessor => ./processor/ratelimitprocessor
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/processor/routetemplateprocessor => ./processor/routetemplateprocessor
+
+replace github.com/teletrace/teletrace/teletrace-otelcol/processor/servicenameprocessor => ./processor/servicenameprocessor
@@ -0,0 +1,16 @@
+module github.com/teletrace/teletrace/teletrace-otelcol/internal/childcount
+
+go 1.19
+
+replace github.com/teletrace/teletrace/model => ../../../model
+
+require (
+	github.com/stretchr/testify v1.8.1
+	github.com/teletrace/teletrace/model v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,204 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package childcount
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	internalspanv1 "github.com/teletrace/teletrace/model/internalspan/v1"
+)
+
+// maxBatchSpans bounds the span ids of a search, below the default max_terms_count of Elasticsearch and OpenSearch
+const maxBatchSpans = 10000
+
+// SearchFunc runs a search of the span index, returning a nil body if the index doesn't exist yet.
+type SearchFunc func(ctx context.Context, body io.Reader) (io.ReadCloser, error)
+
+// DocumentIdFunc returns the id of the document of a span.
+type DocumentIdFunc func(span *internalspanv1.InternalSpan) string
+
+// Lookup adds the children indexed by previous batches to the child count of the spans of a batch.
+// Children are usually written before their parent, so this covers most of the children outside the batch.
+// Children of the spans in the batch that are written by later batches are not counted.
+//
+// A single search is run at a time, and the lookups of the batches written meanwhile are batched into the next one,
+// so concurrent writes don't each add a search.
+type Lookup struct {
+	search     SearchFunc
+	documentId DocumentIdFunc
+	// searching allows a single search at a time
+	searching chan struct{}
+
+	mu      sync.Mutex
+	pending *batch
+}
+
+// batch is the lookups of the spans of several written batches, searched at once.
+type batch struct {
+	spanIds  []string
+	requests [][]string // the document ids of the spans of each written batch
+	done     chan struct{}
+	// counts are the indexed children of each span id, by request, set once done
+	counts []map[string]uint64
+	err    error
+}
+
+// NewLookup creates a Lookup running its searches with search.
+func NewLookup(search SearchFunc, documentId DocumentIdFunc) *Lookup {
+	return &Lookup{search: search, documentId: documentId, searching: make(chan struct{}, 1)}
+}
+
+// AddIndexedChildCounts adds the children indexed by previous batches to the child count of spans.
+func (l *Lookup) AddIndexedChildCounts(ctx context.Context, spans []*internalspanv1.InternalSpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	b, request := l.enqueue(spans)
+
+	select {
+	case <-b.done:
+	case l.searching <- struct{}{}:
+		// Whoever gets to search first searches the whole batch, which stops accepting lookups
+		l.mu.Lock()
+		if l.pending == b {
+			l.pending = nil
+		}
+		l.mu.Unlock()
+		select {
+		case <-b.done:
+		default:
+			b.counts, b.err = l.run(ctx, b)
+			close(b.done)
+		}
+		<-l.searching
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if b.err != nil {
+		return b.err
+	}
+	for _, span := range spans {
+		span.ExternalFields.ChildCount += b.counts[request][span.Span.SpanId]
+	}
+	return nil
+}
+
+// enqueue adds the lookup of spans to the pending batch, and returns it with the index of the lookup in it.
+func (l *Lookup) enqueue(spans []*internalspanv1.InternalSpan) (*batch, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.pending == nil || len(l.pending.spanIds)+len(spans) > maxBatchSpans {
+		l.pending = &batch{done: make(chan struct{})}
+	}
+	documentIds := make([]string, 0, len(spans))
+	for _, span := range spans {
+		l.pending.spanIds = append(l.pending.spanIds, span.Span.SpanId)
+		documentIds = append(documentIds, l.documentId(span))
+	}
+	l.pending.requests = append(l.pending.requests, documentIds)
+	return l.pending, len(l.pending.requests) - 1
+}
+
+type childCountResponse struct {
+	Aggregations struct {
+		Parents struct {
+			Buckets []struct {
+				Key      string `json:"key"`
+				DocCount uint64 `json:"doc_count"`
+				Requests struct {
+					Buckets []struct {
+						DocCount uint64 `json:"doc_count"`
+					} `json:"buckets"`
+				} `json:"requests"`
+			} `json:"buckets"`
+		} `json:"parents"`
+	} `json:"aggregations"`
+}
+
+// run searches the indexed children of the spans of b, by request.
+func (l *Lookup) run(ctx context.Context, b *batch) ([]map[string]uint64, error) {
+	body, err := json.Marshal(buildQuery(b))
+	if err != nil {
+		return nil, err
+	}
+	res, err := l.search(ctx, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]map[string]uint64, len(b.requests))
+	for i := range counts {
+		counts[i] = make(map[string]uint64)
+	}
+	if res == nil {
+		// Nothing was indexed yet
+		return counts, nil
+	}
+	defer res.Close()
+
+	var parsed childCountResponse
+	if err := json.NewDecoder(res).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse child count response: %w", err)
+	}
+	for _, bucket := range parsed.Aggregations.Parents.Buckets {
+		if len(bucket.Requests.Buckets) != len(b.requests) {
+			return nil, fmt.Errorf("unexpected child count response of %d requests", len(bucket.Requests.Buckets))
+		}
+		for i, request := range bucket.Requests.Buckets {
+			counts[i][bucket.Key] = bucket.DocCount - request.DocCount
+		}
+	}
+	return counts, nil
+}
+
+// buildQuery counts the indexed children of the span ids of b, and those of them in each request.
+// Children in a request are already counted, and may have been indexed by a previous attempt of the request,
+// so they are subtracted from the count of its spans.
+func buildQuery(b *batch) map[string]any {
+	spanIds := make(map[string]bool, len(b.spanIds))
+	for _, spanId := range b.spanIds {
+		spanIds[spanId] = true
+	}
+
+	requests := make([]any, 0, len(b.requests))
+	for _, documentIds := range b.requests {
+		requests = append(requests, map[string]any{"ids": map[string]any{"values": documentIds}})
+	}
+
+	return map[string]any{
+		"size": 0,
+		"query": map[string]any{
+			"bool": map[string]any{
+				"filter": map[string]any{"terms": map[string]any{"span.parentSpanId.keyword": b.spanIds}},
+			},
+		},
+		"aggs": map[string]any{
+			"parents": map[string]any{
+				"terms": map[string]any{"field": "span.parentSpanId.keyword", "size": len(spanIds)},
+				"aggs": map[string]any{
+					"requests": map[string]any{"filters": map[string]any{"filters": requests}},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,200 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package childcount
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	internalspanv1 "github.com/teletrace/teletrace/model/internalspan/v1"
+)
+
+func newSpan(spanId string, parentSpanId string) *internalspanv1.InternalSpan {
+	return &internalspanv1.InternalSpan{
+		Span:           &internalspanv1.Span{TraceId: "trace", SpanId: spanId, ParentSpanId: parentSpanId},
+		ExternalFields: &internalspanv1.ExternalFields{},
+	}
+}
+
+func documentId(span *internalspanv1.InternalSpan) string {
+	return span.Span.TraceId + "-" + span.Span.SpanId
+}
+
+// fakeIndex answers child count searches from indexed spans
+type fakeIndex struct {
+	mu       sync.Mutex
+	spans    []*internalspanv1.InternalSpan
+	searches int
+	// block delays searches until closed
+	block chan struct{}
+}
+
+func (f *fakeIndex) search(_ context.Context, body io.Reader) (io.ReadCloser, error) {
+	if f.block != nil {
+		<-f.block
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.searches++
+
+	var query struct {
+		Aggs struct {
+			Parents struct {
+				Aggs struct {
+					Requests struct {
+						Filters struct {
+							Filters []struct {
+								Ids struct {
+									Values []string `json:"values"`
+								} `json:"ids"`
+							} `json:"filters"`
+						} `json:"filters"`
+					} `json:"requests"`
+				} `json:"aggs"`
+			} `json:"parents"`
+		} `json:"aggs"`
+	}
+	if err := json.NewDecoder(body).Decode(&query); err != nil {
+		return nil, err
+	}
+	filters := query.Aggs.Parents.Aggs.Requests.Filters.Filters
+
+	type requestBucket struct {
+		DocCount int `json:"doc_count"`
+	}
+	type parentBucket struct {
+		Key      string `json:"key"`
+		DocCount int    `json:"doc_count"`
+		Requests struct {
+			Buckets []requestBucket `json:"buckets"`
+		} `json:"requests"`
+	}
+	buckets := map[string]*parentBucket{}
+	var keys []string
+	for _, span := range f.spans {
+		parent := span.Span.ParentSpanId
+		if buckets[parent] == nil {
+			buckets[parent] = &parentBucket{Key: parent}
+			buckets[parent].Requests.Buckets = make([]requestBucket, len(filters))
+			keys = append(keys, parent)
+		}
+		buckets[parent].DocCount++
+		for i, filter := range filters {
+			for _, id := range filter.Ids.Values {
+				if id == documentId(span) {
+					buckets[parent].Requests.Buckets[i].DocCount++
+				}
+			}
+		}
+	}
+	var res struct {
+		Aggregations struct {
+			Parents struct {
+				Buckets []*parentBucket `json:"buckets"`
+			} `json:"parents"`
+		} `json:"aggregations"`
+	}
+	for _, key := range keys {
+		res.Aggregations.Parents.Buckets = append(res.Aggregations.Parents.Buckets, buckets[key])
+	}
+	data, err := json.Marshal(res)
+	return io.NopCloser(strings.NewReader(string(data))), err
+}
+
+func TestAddIndexedChildCounts(t *testing.T) {
+	index := &fakeIndex{spans: []*internalspanv1.InternalSpan{newSpan("b", "a"), newSpan("c", "a"), newSpan("d", "b")}}
+	lookup := NewLookup(index.search, documentId)
+	// c was indexed by a previous attempt of the batch, and is already counted in it
+	parent, child := newSpan("a", ""), newSpan("c", "a")
+	parent.ExternalFields.ChildCount = 1
+
+	err := lookup.AddIndexedChildCounts(context.Background(), []*internalspanv1.InternalSpan{parent, child})
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), parent.ExternalFields.ChildCount)
+	assert.Equal(t, uint64(0), child.ExternalFields.ChildCount)
+}
+
+func TestAddIndexedChildCountsWithoutIndex(t *testing.T) {
+	lookup := NewLookup(func(context.Context, io.Reader) (io.ReadCloser, error) { return nil, nil }, documentId)
+	span := newSpan("a", "")
+
+	assert.NoError(t, lookup.AddIndexedChildCounts(context.Background(), []*internalspanv1.InternalSpan{span}))
+	assert.Equal(t, uint64(0), span.ExternalFields.ChildCount)
+}
+
+func TestAddIndexedChildCountsFailure(t *testing.T) {
+	lookup := NewLookup(func(context.Context, io.Reader) (io.ReadCloser, error) { return nil, errors.New("unavailable") }, documentId)
+
+	assert.Error(t, lookup.AddIndexedChildCounts(context.Background(), []*internalspanv1.InternalSpan{newSpan("a", "")}))
+}
+
+func TestConcurrentLookupsAreBatched(t *testing.T) {
+	index := &fakeIndex{
+		spans: []*internalspanv1.InternalSpan{newSpan("b", "a"), newSpan("y", "x"), newSpan("z", "x")},
+		block: make(chan struct{}),
+	}
+	lookup := NewLookup(index.search, documentId)
+
+	// The first lookup blocks the search, so the next ones are batched into a single search
+	first := newSpan("first", "")
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, lookup.AddIndexedChildCounts(context.Background(), []*internalspanv1.InternalSpan{first}))
+	}()
+	for {
+		lookup.mu.Lock()
+		started := len(lookup.searching) == 1 && lookup.pending == nil
+		lookup.mu.Unlock()
+		if started {
+			break
+		}
+	}
+
+	spans := []*internalspanv1.InternalSpan{newSpan("a", ""), newSpan("x", ""), newSpan("z", "x")}
+	for _, span := range spans {
+		span := span
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, lookup.AddIndexedChildCounts(context.Background(), []*internalspanv1.InternalSpan{span}))
+		}()
+	}
+	for {
+		lookup.mu.Lock()
+		queued := lookup.pending != nil && len(lookup.pending.requests) == len(spans)
+		lookup.mu.Unlock()
+		if queued {
+			break
+		}
+	}
+	close(index.block)
+	wg.Wait()
+
+	assert.Equal(t, 2, index.searches)
+	assert.Equal(t, uint64(1), spans[0].ExternalFields.ChildCount)
+	assert.Equal(t, uint64(2), spans[1].ExternalFields.ChildCount, "children in other batches must be counted")
+	assert.Equal(t, uint64(0), spans[2].ExternalFields.ChildCount)
+}
@@ -0,0 +1,104 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package ingestionlag
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+const serviceNameAttribute = "service.name"
+
+var (
+	exporterNameKey = tag.MustNewKey("exporter")
+	serviceKey      = tag.MustNewKey("service")
+
+	ingestionLag = stats.Float64(
+		"teletrace_ingestion_lag",
+		"Time from the end of spans until they were written to the storage",
+		stats.UnitMilliseconds,
+	)
+
+	registerViewsOnce sync.Once
+	errRegisterViews  error
+)
+
+// Recorder records the ingestion lag of written spans with OpenCensus, which is exposed by the collector's own
+// telemetry.
+type Recorder struct {
+	ctx context.Context
+}
+
+// NewRecorder creates the ingestion lag recorder of the exporter name.
+func NewRecorder(name string) (*Recorder, error) {
+	registerViewsOnce.Do(func() {
+		errRegisterViews = view.Register(
+			&view.View{
+				Name:        ingestionLag.Name(),
+				Description: ingestionLag.Description(),
+				Measure:     ingestionLag,
+				TagKeys:     []tag.Key{exporterNameKey, serviceKey},
+				Aggregation: view.Distribution(100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000, 300000, 900000, 3600000),
+			},
+		)
+	})
+	if errRegisterViews != nil {
+		return nil, errRegisterViews
+	}
+
+	ctx, err := tag.New(context.Background(), tag.Insert(exporterNameKey, name))
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{ctx: ctx}, nil
+}
+
+// Record records the ingestion lag of the spans of td, written at ingestionTime, tagged with their service.
+// Spans ingested before their end due to clock skew have no lag.
+func (r *Recorder) Record(td ptrace.Traces, ingestionTime time.Time) {
+	ingestionTimeUnixNano := ingestionTime.UnixNano()
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		resourceSpans := td.ResourceSpans().At(i)
+		service := ""
+		if value, ok := resourceSpans.Resource().Attributes().Get(serviceNameAttribute); ok {
+			service = value.AsString()
+		}
+		mutators := []tag.Mutator{tag.Upsert(serviceKey, service)}
+
+		scopeSpans := resourceSpans.ScopeSpans()
+		for j := 0; j < scopeSpans.Len(); j++ {
+			spans := scopeSpans.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				_ = stats.RecordWithTags(r.ctx, mutators, ingestionLag.M(lagMillis(spans.At(k), ingestionTimeUnixNano)))
+			}
+		}
+	}
+}
+
+// lagMillis returns the time from the end of span until ingestionTimeUnixNano, in milliseconds.
+func lagMillis(span ptrace.Span, ingestionTimeUnixNano int64) float64 {
+	lag := ingestionTimeUnixNano - int64(span.EndTimestamp())
+	if lag < 0 {
+		return 0
+	}
+	return float64(lag) / float64(time.Millisecond)
+}
@@ -0,0 +1,73 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package ingestionlag
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func newTestTraces(service string, endTimes ...time.Time) ptrace.Traces {
+	traces := ptrace.NewTraces()
+	rs := traces.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", service)
+	spans := rs.ScopeSpans().AppendEmpty().Spans()
+	for _, end := range endTimes {
+		spans.AppendEmpty().SetEndTimestamp(pcommon.NewTimestampFromTime(end))
+	}
+	return traces
+}
+
+func retrieveLag(t *testing.T, exporter string, service string) *view.DistributionData {
+	rows, err := view.RetrieveData(ingestionLag.Name())
+	assert.NoError(t, err)
+	for _, row := range rows {
+		tags := make(map[tag.Key]string)
+		for _, tg := range row.Tags {
+			tags[tg.Key] = tg.Value
+		}
+		if tags[exporterNameKey] == exporter && tags[serviceKey] == service {
+			return row.Data.(*view.DistributionData)
+		}
+	}
+	return nil
+}
+
+func TestRecord(t *testing.T) {
+	recorder, err := NewRecorder("sqlite/record")
+	assert.NoError(t, err)
+	ingestionTime := time.Now()
+
+	recorder.Record(newTestTraces("checkout", ingestionTime.Add(-2*time.Second), ingestionTime.Add(-4*time.Second)), ingestionTime)
+	recorder.Record(newTestTraces("payments", ingestionTime.Add(time.Second)), ingestionTime)
+
+	checkout := retrieveLag(t, "sqlite/record", "checkout")
+	assert.NotNil(t, checkout)
+	assert.Equal(t, int64(2), checkout.Count)
+	assert.InDelta(t, 3000, checkout.Mean, 1)
+	assert.InDelta(t, 4000, checkout.Max, 1)
+
+	payments := retrieveLag(t, "sqlite/record", "payments")
+	assert.NotNil(t, payments)
+	assert.Equal(t, int64(1), payments.Count)
+	assert.Equal(t, float64(0), payments.Mean, "spans ingested before their end should have no lag")
+}
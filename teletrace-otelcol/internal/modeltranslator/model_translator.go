@@ -17,6 +17,8 @@
 package modeltranslator
 
 import (
+	"time"
+
 	internalspanv1 "github.com/teletrace/teletrace/model/internalspan/v1"
 
 	"go.opentelemetry.io/collector/pdata/ptrace"
@@ -54,13 +56,35 @@ func TranslateOTLPToInternalModel(td ptrace.Traces, opts ...TranslationOption) [
 				for _, opt := range opts {
 					opt(iSpan)
 				}
+				// binary values not encoded by WithBinaryEncoding are encoded with the default encoding
+				iSpan.NormalizeAttributes(internalspanv1.BinaryEncodingBase64)
 				internalSpans = append(internalSpans, iSpan)
 			}
 		}
 	}
+	countChildren(internalSpans)
 	return internalSpans
 }
 
+// countChildren sets the child count of each span to the number of its children in the batch.
+// Children written in other batches are counted by the exporters.
+func countChildren(internalSpans []*internalspanv1.InternalSpan) {
+	children := make(map[string]map[string]struct{})
+	for _, s := range internalSpans {
+		parentSpanId := s.Span.ParentSpanId
+		if parentSpanId == "" {
+			continue
+		}
+		if children[parentSpanId] == nil {
+			children[parentSpanId] = make(map[string]struct{})
+		}
+		children[parentSpanId][s.Span.SpanId] = struct{}{}
+	}
+	for _, s := range internalSpans {
+		s.ExternalFields.ChildCount = uint64(len(children[s.Span.SpanId]))
+	}
+}
+
 func getInternalSpanResource(resourceSpans ptrace.ResourceSpans) *internalspanv1.Resource {
 	resource := resourceSpans.Resource()
 	return &internalspanv1.Resource{
@@ -155,8 +179,24 @@ func WithMiliSec() TranslationOption {
 		s.Span.StartTimeUnixNano = uint64(s.Span.StartTimeUnixNano / (1000 * 1000))
 		s.Span.EndTimeUnixNano = uint64(s.Span.EndTimeUnixNano / (1000 * 1000))
 		s.ExternalFields.DurationNano = uint64(s.ExternalFields.DurationNano / (1000 * 1000))
+		s.IngestionTimeUnixNano = uint64(s.IngestionTimeUnixNano / (1000 * 1000))
 		for _, e := range s.Span.Events {
 			e.TimeUnixNano = uint64(e.TimeUnixNano / (1000 * 1000))
 		}
 	}
 }
+
+// WithIngestionTime sets the ingestion time of the spans, it must precede WithMiliSec.
+func WithIngestionTime(t time.Time) TranslationOption {
+	return func(s *internalspanv1.InternalSpan) {
+		s.IngestionTimeUnixNano = uint64(t.UnixNano())
+	}
+}
+
+// WithBinaryEncoding encodes binary attribute values with the given encoding
+// (see internalspanv1.EncodeBinaryValue) and records it in their BinaryAttributes, base64 is used by default.
+func WithBinaryEncoding(encoding string) TranslationOption {
+	return func(s *internalspanv1.InternalSpan) {
+		s.NormalizeAttributes(encoding)
+	}
+}
@@ -19,6 +19,7 @@ package modeltranslator
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	internalspanv1 "github.com/teletrace/teletrace/model/internalspan/v1"
 
@@ -45,6 +46,60 @@ func TestModelTranslatorWithMili(t *testing.T) {
 	assert.ElementsMatch(t, expectedInternalSpans, actualInternalSpans)
 }
 
+func TestModelTranslatorIngestionTime(t *testing.T) {
+	ingestionTime := time.Unix(1670000000, 123456789)
+
+	for _, s := range TranslateOTLPToInternalModel(createOTLPTraces(), WithIngestionTime(ingestionTime)) {
+		assert.Equal(t, uint64(1670000000123456789), s.IngestionTimeUnixNano)
+	}
+	for _, s := range TranslateOTLPToInternalModel(createOTLPTraces(), WithIngestionTime(ingestionTime), WithMiliSec()) {
+		assert.Equal(t, uint64(1670000000123), s.IngestionTimeUnixNano)
+	}
+}
+
+func TestModelTranslatorBinaryAttributes(t *testing.T) {
+	traces := ptrace.NewTraces()
+	resourceSpans := traces.ResourceSpans().AppendEmpty()
+	resourceSpans.Resource().Attributes().PutEmptyBytes("resource.binary").FromRaw([]byte{0xff, 0x00})
+	span := resourceSpans.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.Attributes().PutEmptyBytes("binary").FromRaw([]byte{0x01, 0x02})
+	span.Attributes().PutStr("invalid", "a\xffb")
+
+	internalSpans := TranslateOTLPToInternalModel(traces)
+	assert.Equal(t, "/wA=", internalSpans[0].Resource.Attributes["resource.binary"])
+	assert.Equal(t, internalspanv1.BinaryAttributes{"resource.binary": internalspanv1.BinaryEncodingBase64}, internalSpans[0].Resource.BinaryAttributes)
+	assert.Equal(t, "AQI=", internalSpans[0].Span.Attributes["binary"])
+	assert.Equal(t, internalspanv1.BinaryAttributes{"binary": internalspanv1.BinaryEncodingBase64}, internalSpans[0].Span.BinaryAttributes)
+	assert.Equal(t, "a\uFFFDb", internalSpans[0].Span.Attributes["invalid"])
+
+	internalSpans = TranslateOTLPToInternalModel(traces, WithBinaryEncoding(internalspanv1.BinaryEncodingHex))
+	assert.Equal(t, "0102", internalSpans[0].Span.Attributes["binary"])
+	assert.Equal(t, internalspanv1.BinaryAttributes{"binary": internalspanv1.BinaryEncodingHex}, internalSpans[0].Span.BinaryAttributes)
+}
+
+func TestModelTranslatorChildCount(t *testing.T) {
+	traces := ptrace.NewTraces()
+	spans := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	for i, parent := range []byte{0, 1, 1, 1, 2} {
+		span := spans.AppendEmpty()
+		span.SetSpanID(pcommon.SpanID([8]byte{byte(i + 1)}))
+		if parent != 0 {
+			span.SetParentSpanID(pcommon.SpanID([8]byte{parent}))
+		}
+	}
+	// A duplicate of an already counted child
+	spans.At(1).CopyTo(spans.AppendEmpty())
+
+	internalSpans := TranslateOTLPToInternalModel(traces)
+	childCounts := make(map[string]uint64)
+	for _, s := range internalSpans {
+		childCounts[s.Span.SpanId] = s.ExternalFields.ChildCount
+	}
+	assert.Equal(t, uint64(3), childCounts[pcommon.SpanID([8]byte{1}).HexString()])
+	assert.Equal(t, uint64(1), childCounts[pcommon.SpanID([8]byte{2}).HexString()])
+	assert.Equal(t, uint64(0), childCounts[pcommon.SpanID([8]byte{5}).HexString()])
+}
+
 func createOTLPTraces() ptrace.Traces {
 	td := ptrace.NewTraces()
 
@@ -0,0 +1,100 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package replication
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+var (
+	replicatorNameKey = tag.MustNewKey("replicator")
+
+	replicationLag = stats.Int64(
+		"teletrace_replication_lag",
+		"Time between a batch being committed locally and acknowledged by the peer",
+		stats.UnitMilliseconds,
+	)
+	replicatedBatches = stats.Int64(
+		"teletrace_replication_replicated_batches",
+		"Number of batches acknowledged by the peer",
+		stats.UnitDimensionless,
+	)
+	failedBatches = stats.Int64(
+		"teletrace_replication_failed_batches",
+		"Number of batches that failed to be replicated once retries were exhausted",
+		stats.UnitDimensionless,
+	)
+
+	registerViewsOnce sync.Once
+	errRegisterViews  error
+)
+
+// metrics records the replication metrics with OpenCensus, which are exposed by the collector's own telemetry.
+type metrics struct {
+	ctx context.Context
+}
+
+func newMetrics(name string) (*metrics, error) {
+	registerViewsOnce.Do(func() {
+		errRegisterViews = view.Register(
+			&view.View{
+				Name:        replicationLag.Name(),
+				Description: replicationLag.Description(),
+				Measure:     replicationLag,
+				TagKeys:     []tag.Key{replicatorNameKey},
+				Aggregation: view.LastValue(),
+			},
+			&view.View{
+				Name:        replicatedBatches.Name(),
+				Description: replicatedBatches.Description(),
+				Measure:     replicatedBatches,
+				TagKeys:     []tag.Key{replicatorNameKey},
+				Aggregation: view.Sum(),
+			},
+			&view.View{
+				Name:        failedBatches.Name(),
+				Description: failedBatches.Description(),
+				Measure:     failedBatches,
+				TagKeys:     []tag.Key{replicatorNameKey},
+				Aggregation: view.Sum(),
+			},
+		)
+	})
+	if errRegisterViews != nil {
+		return nil, errRegisterViews
+	}
+
+	ctx, err := tag.New(context.Background(), tag.Insert(replicatorNameKey, name))
+	if err != nil {
+		return nil, err
+	}
+	return &metrics{ctx: ctx}, nil
+}
+
+func (m *metrics) recordReplicated(lag time.Duration) {
+	stats.Record(m.ctx, replicationLag.M(lag.Milliseconds()), replicatedBatches.M(1))
+}
+
+func (m *metrics) recordFailed() {
+	stats.Record(m.ctx, failedBatches.M(1))
+}
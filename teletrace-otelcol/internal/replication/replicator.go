@@ -0,0 +1,220 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package replication
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writequeue"
+	"github.com/teletrace/teletrace/teletrace-otelcol/internal/writeretry"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+// OriginAttributeKey is the resource attribute holding the region a replicated batch was committed in.
+// Resources carrying it are not replicated again, so peers replicating to each other don't loop.
+const OriginAttributeKey = "teletrace.replication.origin"
+
+// Config defines the peer cluster committed batches are replicated to.
+type Config struct {
+	// Endpoint is the OTLP/HTTP traces endpoint of the peer cluster, e.g. https://teletrace.eu-west-1:4318/v1/traces.
+	// Replication is disabled if empty
+	Endpoint string `mapstructure:"endpoint"`
+	// Region identifies this cluster, and is stored on the replicated resources under OriginAttributeKey
+	Region string `mapstructure:"region"`
+	// Headers are added to the requests sent to the peer, e.g. for authentication
+	Headers map[string]string `mapstructure:"headers"`
+	// Timeout is the timeout of a single request to the peer
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Queue configures the queue of batches waiting to be replicated
+	Queue writequeue.Config `mapstructure:"sending_queue"`
+	// Retry configures retries of failed replications, and the dead-letter directory for exhausted batches
+	Retry writeretry.Config `mapstructure:"retry_on_failure"`
+}
+
+// NewDefaultConfig returns the default replication configuration, with replication disabled.
+// A lagging peer drops the oldest batches rather than slowing down the local ingestion.
+func NewDefaultConfig() Config {
+	queue := writequeue.NewDefaultConfig()
+	queue.OverflowPolicy = writequeue.OverflowPolicyDropOldest
+	return Config{
+		Timeout: 10 * time.Second,
+		Queue:   queue,
+		Retry:   writeretry.NewDefaultConfig(),
+	}
+}
+
+// Enabled returns whether a peer is configured.
+func (cfg *Config) Enabled() bool {
+	return cfg.Endpoint != ""
+}
+
+// Validate validates the replication configuration.
+func (cfg *Config) Validate() error {
+	if !cfg.Enabled() {
+		return nil
+	}
+	if cfg.Region == "" {
+		return fmt.Errorf("replication requires a region")
+	}
+	if cfg.Timeout <= 0 {
+		return fmt.Errorf("replication timeout must be positive")
+	}
+	// Replication is asynchronous by design, so the queue is always enabled
+	queue := cfg.Queue
+	queue.Enabled = true
+	if err := queue.Validate(); err != nil {
+		return err
+	}
+	return cfg.Retry.Validate()
+}
+
+// batch is a committed batch waiting to be replicated.
+type batch struct {
+	traces      ptrace.Traces
+	committedAt time.Time
+}
+
+// Replicator ships committed batches to a peer cluster.
+type Replicator struct {
+	logger  *zap.Logger
+	cfg     Config
+	client  *http.Client
+	queue   *writequeue.Queue
+	writer  *writeretry.Writer
+	metrics *metrics
+}
+
+// NewReplicator creates a Replicator, name identifies it in the exported metrics, e.g. the exporter name.
+func NewReplicator(logger *zap.Logger, name string, cfg Config) (*Replicator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg.Queue.Enabled = true
+	writer, err := writeretry.NewWriter(logger, "replication/"+name, cfg.Retry)
+	if err != nil {
+		return nil, err
+	}
+	m, err := newMetrics(name)
+	if err != nil {
+		return nil, err
+	}
+	r := &Replicator{
+		logger:  logger,
+		cfg:     cfg,
+		client:  &http.Client{Timeout: cfg.Timeout},
+		writer:  writer,
+		metrics: m,
+	}
+	r.queue, err = writequeue.NewQueue(logger, "replication/"+name, cfg.Queue, func(ctx context.Context, item interface{}) error {
+		return r.replicate(ctx, item.(batch))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Start starts replicating, and replays the batches dead-lettered by previous runs in the background.
+func (r *Replicator) Start() {
+	r.queue.Start()
+	go func() {
+		if err := r.writer.Replay(context.Background(), r.send); err != nil {
+			r.logger.Error("Failed to replay dead-lettered replication batches", zap.Error(err))
+		}
+	}()
+}
+
+// Stop stops accepting batches and waits for the queued ones to be replicated.
+func (r *Replicator) Stop() error {
+	return r.queue.Stop()
+}
+
+// Replicate queues a committed batch to be replicated. Resources that were replicated to this cluster
+// are skipped. The batch is copied, so the caller keeps ownership of traces.
+func (r *Replicator) Replicate(ctx context.Context, traces ptrace.Traces) error {
+	local := ptrace.NewTraces()
+	traces.CopyTo(local)
+	local.ResourceSpans().RemoveIf(func(rs ptrace.ResourceSpans) bool {
+		_, replicated := rs.Resource().Attributes().Get(OriginAttributeKey)
+		return replicated
+	})
+	if local.ResourceSpans().Len() == 0 {
+		return nil
+	}
+	for i := 0; i < local.ResourceSpans().Len(); i++ {
+		local.ResourceSpans().At(i).Resource().Attributes().PutStr(OriginAttributeKey, r.cfg.Region)
+	}
+	return r.queue.Enqueue(ctx, batch{traces: local, committedAt: time.Now()})
+}
+
+func (r *Replicator) replicate(ctx context.Context, b batch) error {
+	marshaler := &ptrace.ProtoMarshaler{}
+	payload, err := marshaler.MarshalTraces(b.traces)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
+	}
+	acknowledged := false
+	err = r.writer.Write(ctx, b.traces.SpanCount(),
+		func(ctx context.Context) error {
+			if err := r.send(ctx, payload); err != nil {
+				return err
+			}
+			acknowledged = true
+			return nil
+		},
+		func() ([]byte, error) { return payload, nil },
+	)
+	if !acknowledged {
+		// Either failed or dead-lettered, to be replayed on the next start
+		r.metrics.recordFailed()
+		return err
+	}
+	r.metrics.recordReplicated(time.Since(b.committedAt))
+	return nil
+}
+
+// send posts an OTLP protobuf payload to the peer. Since span writes are idempotent on the peer,
+// a payload that was applied but not acknowledged can safely be sent again.
+func (r *Replicator) send(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range r.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send batch to peer: %w", err)
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("peer responded with status %d", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package replication
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+type peer struct {
+	mu       sync.Mutex
+	received []ptrace.Traces
+	status   int
+}
+
+func (p *peer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.status != http.StatusOK {
+		w.WriteHeader(p.status)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	traces, err := (&ptrace.ProtoUnmarshaler{}).UnmarshalTraces(body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	p.received = append(p.received, traces)
+}
+
+func newTestTraces(origins ...string) ptrace.Traces {
+	traces := ptrace.NewTraces()
+	for _, origin := range origins {
+		rs := traces.ResourceSpans().AppendEmpty()
+		rs.Resource().Attributes().PutStr("service.name", "checkout")
+		if origin != "" {
+			rs.Resource().Attributes().PutStr(OriginAttributeKey, origin)
+		}
+		rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("GET /cart")
+	}
+	return traces
+}
+
+func newTestConfig(endpoint string) Config {
+	cfg := NewDefaultConfig()
+	cfg.Endpoint = endpoint
+	cfg.Region = "us-east-1"
+	cfg.Retry.InitialInterval = time.Millisecond
+	cfg.Retry.MaxInterval = time.Millisecond
+	cfg.Retry.MaxElapsedTime = 10 * time.Millisecond
+	return cfg
+}
+
+func TestReplicateTagsOriginAndSkipsReplicatedResources(t *testing.T) {
+	p := &peer{status: http.StatusOK}
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	r, err := NewReplicator(zap.NewNop(), "sqlite", newTestConfig(server.URL))
+	assert.NoError(t, err)
+	r.Start()
+
+	traces := newTestTraces("", "eu-west-1")
+	assert.NoError(t, r.Replicate(context.Background(), traces))
+	assert.NoError(t, r.Replicate(context.Background(), newTestTraces("eu-west-1")))
+	assert.NoError(t, r.Stop())
+
+	assert.Len(t, p.received, 1)
+	assert.Equal(t, 1, p.received[0].ResourceSpans().Len())
+	origin, ok := p.received[0].ResourceSpans().At(0).Resource().Attributes().Get(OriginAttributeKey)
+	assert.True(t, ok)
+	assert.Equal(t, "us-east-1", origin.Str())
+
+	// The caller's batch is left untouched
+	_, ok = traces.ResourceSpans().At(0).Resource().Attributes().Get(OriginAttributeKey)
+	assert.False(t, ok)
+	assert.Equal(t, 2, traces.ResourceSpans().Len())
+}
+
+func TestFailedReplicationsAreDeadLetteredAndReplayed(t *testing.T) {
+	p := &peer{status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	cfg := newTestConfig(server.URL)
+	cfg.Retry.DeadLetterDirectory = t.TempDir()
+	r, err := NewReplicator(zap.NewNop(), "sqlite", cfg)
+	assert.NoError(t, err)
+	r.Start()
+	assert.NoError(t, r.Replicate(context.Background(), newTestTraces("")))
+	assert.NoError(t, r.Stop())
+	assert.Len(t, p.received, 0)
+
+	p.mu.Lock()
+	p.status = http.StatusOK
+	p.mu.Unlock()
+
+	r, err = NewReplicator(zap.NewNop(), "sqlite", cfg)
+	assert.NoError(t, err)
+	assert.NoError(t, r.writer.Replay(context.Background(), r.send))
+	assert.Len(t, p.received, 1)
+}
+
+func TestValidate(t *testing.T) {
+	cfg := NewDefaultConfig()
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Endpoint = "http://localhost:4318/v1/traces"
+	assert.Error(t, cfg.Validate())
+
+	cfg.Region = "us-east-1"
+	assert.NoError(t, cfg.Validate())
+}
@@ -0,0 +1,93 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spanvalidation
+
+import (
+	"context"
+	"sync"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+var (
+	validatorNameKey = tag.MustNewKey("validator")
+	serviceKey       = tag.MustNewKey("service")
+	reasonKey        = tag.MustNewKey("reason")
+
+	rejectedSpans = stats.Int64(
+		"teletrace_rejected_spans",
+		"Number of malformed spans rejected before being written",
+		stats.UnitDimensionless,
+	)
+
+	truncatedAttributes = stats.Int64(
+		"teletrace_truncated_attributes",
+		"Number of too long attribute values truncated before being written",
+		stats.UnitDimensionless,
+	)
+
+	registerViewsOnce sync.Once
+	errRegisterViews  error
+)
+
+// metrics records the validation metrics with OpenCensus, which are exposed by the collector's own telemetry.
+type metrics struct {
+	ctx context.Context
+}
+
+func newMetrics(name string) (*metrics, error) {
+	registerViewsOnce.Do(func() {
+		errRegisterViews = view.Register(
+			&view.View{
+				Name:        rejectedSpans.Name(),
+				Description: rejectedSpans.Description(),
+				Measure:     rejectedSpans,
+				TagKeys:     []tag.Key{validatorNameKey, serviceKey, reasonKey},
+				Aggregation: view.Sum(),
+			},
+			&view.View{
+				Name:        truncatedAttributes.Name(),
+				Description: truncatedAttributes.Description(),
+				Measure:     truncatedAttributes,
+				TagKeys:     []tag.Key{validatorNameKey, serviceKey},
+				Aggregation: view.Sum(),
+			},
+		)
+	})
+	if errRegisterViews != nil {
+		return nil, errRegisterViews
+	}
+
+	ctx, err := tag.New(context.Background(), tag.Insert(validatorNameKey, name))
+	if err != nil {
+		return nil, err
+	}
+	return &metrics{ctx: ctx}, nil
+}
+
+func (m *metrics) recordRejected(service string, reason string) {
+	_ = stats.RecordWithTags(m.ctx,
+		[]tag.Mutator{tag.Upsert(serviceKey, service), tag.Upsert(reasonKey, reason)},
+		rejectedSpans.M(1),
+	)
+}
+
+func (m *metrics) recordTruncated(service string) {
+	_ = stats.RecordWithTags(m.ctx, []tag.Mutator{tag.Upsert(serviceKey, service)}, truncatedAttributes.M(1))
+}
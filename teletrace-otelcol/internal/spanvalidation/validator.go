@@ -0,0 +1,257 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spanvalidation
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/teletrace/teletrace/blobstore"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+// Rejection reasons, exposed as the reason tag of the rejected spans metric
+const (
+	ReasonInvalidTraceId        = "invalid_trace_id"
+	ReasonInvalidSpanId         = "invalid_span_id"
+	ReasonInvalidTimestamps     = "invalid_timestamps"
+	ReasonTooManyAttributes     = "too_many_attributes"
+	ReasonAttributeValueTooLong = "attribute_value_too_long"
+)
+
+const unknownService = "unknown_service"
+
+// Config defines which spans are rejected before they are written.
+type Config struct {
+	// Enabled enables rejecting malformed spans
+	Enabled bool `mapstructure:"enabled"`
+	// MaxAttributeCount is the maximum number of attributes of a span, unlimited if zero
+	MaxAttributeCount int `mapstructure:"max_attribute_count"`
+	// MaxAttributeValueLength is the maximum length in bytes of a string or binary attribute value, unlimited if zero
+	MaxAttributeValueLength int `mapstructure:"max_attribute_value_length"`
+	// Truncation truncates too long attribute values instead of rejecting their spans
+	Truncation TruncationConfig `mapstructure:"truncation"`
+}
+
+// TruncationConfig defines how too long attribute values are truncated.
+type TruncationConfig struct {
+	// Enabled truncates too long attribute values to MaxAttributeValueLength instead of rejecting their spans
+	Enabled bool `mapstructure:"enabled"`
+	// Sidecar stores the full values of truncated attributes, which are discarded if no store is configured
+	Sidecar blobstore.Config `mapstructure:"sidecar"`
+	// SidecarTimeout bounds storing a full value, so a slow store doesn't stall the write of its span
+	SidecarTimeout time.Duration `mapstructure:"sidecar_timeout"`
+}
+
+// NewDefaultConfig returns the default validation configuration.
+func NewDefaultConfig() Config {
+	return Config{
+		Enabled:                 true,
+		MaxAttributeCount:       1024,
+		MaxAttributeValueLength: 64 * 1024,
+		Truncation:              TruncationConfig{SidecarTimeout: 2 * time.Second},
+	}
+}
+
+// Validate validates the validation configuration.
+func (cfg *Config) Validate() error {
+	if cfg.MaxAttributeCount < 0 || cfg.MaxAttributeValueLength < 0 {
+		return fmt.Errorf("attribute limits must not be negative")
+	}
+	if cfg.Truncation.SidecarTimeout < 0 {
+		return fmt.Errorf("sidecar timeout must not be negative")
+	}
+	if err := cfg.Truncation.Sidecar.Validate(); err != nil {
+		return fmt.Errorf("invalid truncation sidecar: %w", err)
+	}
+	return nil
+}
+
+// Validator rejects malformed spans, such as spans with a zero trace ID, an end before their start,
+// or oversized attributes, so instrumentation bugs are counted instead of being written.
+type Validator struct {
+	logger  *zap.Logger
+	cfg     Config
+	metrics *metrics
+	sidecar blobstore.Store
+}
+
+// NewValidator creates a Validator, name identifies it in the exported metrics, e.g. the exporter name.
+func NewValidator(logger *zap.Logger, name string, cfg Config) (*Validator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	m, err := newMetrics(name)
+	if err != nil {
+		return nil, err
+	}
+	var sidecar blobstore.Store
+	if cfg.Truncation.Enabled && cfg.Truncation.Sidecar.Enabled() {
+		if sidecar, err = blobstore.NewStore(cfg.Truncation.Sidecar); err != nil {
+			return nil, err
+		}
+	}
+	return &Validator{logger: logger, cfg: cfg, metrics: m, sidecar: sidecar}, nil
+}
+
+// Filter returns traces without the malformed spans, and counts the rejected spans by reason and service.
+// If truncation is enabled, too long attribute values are truncated instead, and their full values are stored in the sidecar.
+// traces isn't modified, a copy is returned if any span is rejected or truncated.
+func (v *Validator) Filter(ctx context.Context, traces ptrace.Traces) ptrace.Traces {
+	if !v.cfg.Enabled || !v.hasInvalidSpans(traces) {
+		return traces
+	}
+
+	valid := ptrace.NewTraces()
+	traces.CopyTo(valid)
+	valid.ResourceSpans().RemoveIf(func(rs ptrace.ResourceSpans) bool {
+		service := serviceName(rs.Resource())
+		rs.ScopeSpans().RemoveIf(func(ss ptrace.ScopeSpans) bool {
+			ss.Spans().RemoveIf(func(span ptrace.Span) bool {
+				reason := v.validate(span)
+				if reason == "" {
+					return false
+				}
+				if reason == ReasonAttributeValueTooLong && v.cfg.Truncation.Enabled {
+					v.truncate(ctx, service, span)
+					return false
+				}
+				v.metrics.recordRejected(service, reason)
+				v.logger.Debug("Rejected malformed span",
+					zap.String("service", service), zap.String("reason", reason),
+					zap.String("traceId", span.TraceID().HexString()), zap.String("spanId", span.SpanID().HexString()))
+				return true
+			})
+			return ss.Spans().Len() == 0
+		})
+		return rs.ScopeSpans().Len() == 0
+	})
+	return valid
+}
+
+func (v *Validator) hasInvalidSpans(traces ptrace.Traces) bool {
+	for i := 0; i < traces.ResourceSpans().Len(); i++ {
+		scopeSpans := traces.ResourceSpans().At(i).ScopeSpans()
+		for j := 0; j < scopeSpans.Len(); j++ {
+			spans := scopeSpans.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				if v.validate(spans.At(k)) != "" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// validate returns the reason the span is rejected for, or an empty string if it's valid.
+func (v *Validator) validate(span ptrace.Span) string {
+	if span.TraceID().IsEmpty() {
+		return ReasonInvalidTraceId
+	}
+	if span.SpanID().IsEmpty() {
+		return ReasonInvalidSpanId
+	}
+	if span.EndTimestamp() < span.StartTimestamp() {
+		return ReasonInvalidTimestamps
+	}
+	if v.cfg.MaxAttributeCount > 0 && span.Attributes().Len() > v.cfg.MaxAttributeCount {
+		return ReasonTooManyAttributes
+	}
+	if v.cfg.MaxAttributeValueLength > 0 && v.hasTooLongValue(span.Attributes()) {
+		return ReasonAttributeValueTooLong
+	}
+	return ""
+}
+
+func (v *Validator) hasTooLongValue(attributes pcommon.Map) bool {
+	tooLong := false
+	attributes.Range(func(_ string, value pcommon.Value) bool {
+		switch value.Type() {
+		case pcommon.ValueTypeStr:
+			tooLong = len(value.Str()) > v.cfg.MaxAttributeValueLength
+		case pcommon.ValueTypeBytes:
+			tooLong = value.Bytes().Len() > v.cfg.MaxAttributeValueLength
+		}
+		return !tooLong
+	})
+	return tooLong
+}
+
+// truncate truncates the too long attribute values of span, and references their full values stored in the sidecar.
+// A value whose full value can't be stored within the sidecar timeout is still truncated, so the span is written anyway.
+func (v *Validator) truncate(ctx context.Context, service string, span ptrace.Span) {
+	full := make(map[string][]byte)
+	span.Attributes().Range(func(key string, value pcommon.Value) bool {
+		switch value.Type() {
+		case pcommon.ValueTypeStr:
+			if len(value.Str()) > v.cfg.MaxAttributeValueLength {
+				full[key] = []byte(value.Str())
+				value.SetStr(truncateString(value.Str(), v.cfg.MaxAttributeValueLength))
+			}
+		case pcommon.ValueTypeBytes:
+			if value.Bytes().Len() > v.cfg.MaxAttributeValueLength {
+				full[key] = value.Bytes().AsRaw()
+				value.SetEmptyBytes().FromRaw(full[key][:v.cfg.MaxAttributeValueLength])
+			}
+		}
+		return true
+	})
+
+	for key, value := range full {
+		v.metrics.recordTruncated(service)
+		if v.sidecar == nil {
+			continue
+		}
+		blobKey := blobstore.ContentKey(value)
+		if err := v.putSidecar(ctx, blobKey, value); err != nil {
+			v.logger.Warn("Failed to store the full value of a truncated attribute",
+				zap.String("attribute", key), zap.String("traceId", span.TraceID().HexString()),
+				zap.String("spanId", span.SpanID().HexString()), zap.Error(err))
+			continue
+		}
+		span.Attributes().PutStr(blobstore.ReferenceAttributePrefix+key, blobKey)
+	}
+}
+
+func (v *Validator) putSidecar(ctx context.Context, key string, value []byte) error {
+	if v.cfg.Truncation.SidecarTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, v.cfg.Truncation.SidecarTimeout)
+		defer cancel()
+	}
+	return v.sidecar.Put(ctx, key, value)
+}
+
+// truncateString truncates s to at most maxLength bytes, without splitting a multi-byte character.
+func truncateString(s string, maxLength int) string {
+	for maxLength > 0 && !utf8.RuneStart(s[maxLength]) {
+		maxLength--
+	}
+	return s[:maxLength]
+}
+
+func serviceName(resource pcommon.Resource) string {
+	if service, ok := resource.Attributes().Get("service.name"); ok && service.Str() != "" {
+		return service.Str()
+	}
+	return unknownService
+}
@@ -0,0 +1,263 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spanvalidation
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teletrace/teletrace/blobstore"
+	"go.opencensus.io/stats/view"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+var (
+	validTraceId = pcommon.TraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	validSpanId  = pcommon.SpanID([8]byte{1, 2, 3, 4, 5, 6, 7, 8})
+)
+
+func newTestTraces(service string) (ptrace.Traces, ptrace.SpanSlice) {
+	traces := ptrace.NewTraces()
+	rs := traces.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", service)
+	return traces, rs.ScopeSpans().AppendEmpty().Spans()
+}
+
+func appendSpan(spans ptrace.SpanSlice, name string) ptrace.Span {
+	span := spans.AppendEmpty()
+	span.SetName(name)
+	span.SetTraceID(validTraceId)
+	span.SetSpanID(validSpanId)
+	span.SetStartTimestamp(100)
+	span.SetEndTimestamp(200)
+	return span
+}
+
+func retrieveRejected(t *testing.T, service string, reason string) int64 {
+	rows, err := view.RetrieveData(rejectedSpans.Name())
+	assert.NoError(t, err)
+	for _, row := range rows {
+		matches := 0
+		for _, tag := range row.Tags {
+			if (tag.Key == serviceKey && tag.Value == service) || (tag.Key == reasonKey && tag.Value == reason) {
+				matches++
+			}
+		}
+		if matches == 2 {
+			return int64(row.Data.(*view.SumData).Value)
+		}
+	}
+	return 0
+}
+
+func TestFilterRejectsMalformedSpans(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.MaxAttributeCount = 2
+	cfg.MaxAttributeValueLength = 8
+	validator, err := NewValidator(zap.NewNop(), "test", cfg)
+	assert.NoError(t, err)
+
+	traces, spans := newTestTraces("checkout")
+	appendSpan(spans, "valid")
+	appendSpan(spans, "zero trace id").SetTraceID(pcommon.NewTraceIDEmpty())
+	appendSpan(spans, "zero span id").SetSpanID(pcommon.NewSpanIDEmpty())
+	appendSpan(spans, "ends before start").SetEndTimestamp(50)
+	tooManyAttributes := appendSpan(spans, "too many attributes")
+	tooManyAttributes.Attributes().PutStr("a", "1")
+	tooManyAttributes.Attributes().PutStr("b", "2")
+	tooManyAttributes.Attributes().PutStr("c", "3")
+	appendSpan(spans, "too long value").Attributes().PutStr("sql", strings.Repeat("x", 9))
+
+	reasons := []string{
+		ReasonInvalidTraceId, ReasonInvalidSpanId, ReasonInvalidTimestamps,
+		ReasonTooManyAttributes, ReasonAttributeValueTooLong,
+	}
+	before := make(map[string]int64)
+	for _, reason := range reasons {
+		before[reason] = retrieveRejected(t, "checkout", reason)
+	}
+
+	valid := validator.Filter(context.Background(), traces)
+
+	assert.Equal(t, 1, valid.SpanCount())
+	assert.Equal(t, "valid", valid.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Name())
+	assert.Equal(t, 6, traces.SpanCount(), "the original traces must not be modified")
+	for _, reason := range reasons {
+		assert.Equal(t, before[reason]+1, retrieveRejected(t, "checkout", reason), reason)
+	}
+}
+
+func TestFilterRemovesEmptyResources(t *testing.T) {
+	validator, err := NewValidator(zap.NewNop(), "test", NewDefaultConfig())
+	assert.NoError(t, err)
+
+	traces, spans := newTestTraces("checkout")
+	appendSpan(spans, "zero trace id").SetTraceID(pcommon.NewTraceIDEmpty())
+	_, otherSpans := newTestTraces("payments")
+	appendSpan(otherSpans, "valid").CopyTo(traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty())
+
+	valid := validator.Filter(context.Background(), traces)
+
+	assert.Equal(t, 1, valid.ResourceSpans().Len())
+	assert.Equal(t, 1, valid.SpanCount())
+}
+
+func TestFilterReturnsValidTracesAsIs(t *testing.T) {
+	validator, err := NewValidator(zap.NewNop(), "test", NewDefaultConfig())
+	assert.NoError(t, err)
+
+	traces, spans := newTestTraces("checkout")
+	appendSpan(spans, "valid")
+
+	assert.Equal(t, traces, validator.Filter(context.Background(), traces))
+}
+
+func TestFilterDisabled(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Enabled = false
+	validator, err := NewValidator(zap.NewNop(), "test", cfg)
+	assert.NoError(t, err)
+
+	traces, spans := newTestTraces("checkout")
+	appendSpan(spans, "zero trace id").SetTraceID(pcommon.NewTraceIDEmpty())
+
+	assert.Equal(t, 1, validator.Filter(context.Background(), traces).SpanCount())
+}
+
+func retrieveTruncated(t *testing.T, service string) int64 {
+	rows, err := view.RetrieveData(truncatedAttributes.Name())
+	assert.NoError(t, err)
+	for _, row := range rows {
+		for _, tag := range row.Tags {
+			if tag.Key == serviceKey && tag.Value == service {
+				return int64(row.Data.(*view.SumData).Value)
+			}
+		}
+	}
+	return 0
+}
+
+func TestFilterTruncatesTooLongValues(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.MaxAttributeValueLength = 8
+	cfg.Truncation.Enabled = true
+	cfg.Truncation.Sidecar = blobstore.Config{Type: blobstore.TypeDisk, Directory: t.TempDir()}
+	validator, err := NewValidator(zap.NewNop(), "test", cfg)
+	assert.NoError(t, err)
+
+	traces, spans := newTestTraces("inventory")
+	span := appendSpan(spans, "too long values")
+	span.Attributes().PutStr("db.statement", "SELECT * FROM items")
+	span.Attributes().PutStr("unicode", "abcdefgé!")
+	span.Attributes().PutEmptyBytes("payload").FromRaw([]byte("0123456789"))
+	span.Attributes().PutStr("short", "ok")
+	before := retrieveTruncated(t, "inventory")
+
+	valid := validator.Filter(context.Background(), traces)
+
+	assert.Equal(t, 1, valid.SpanCount())
+	attributes := valid.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes()
+	statement, _ := attributes.Get("db.statement")
+	assert.Equal(t, "SELECT *", statement.Str())
+	unicode, _ := attributes.Get("unicode")
+	assert.Equal(t, "abcdefg", unicode.Str(), "a multi-byte character must not be split")
+	payload, _ := attributes.Get("payload")
+	assert.Equal(t, []byte("01234567"), payload.Bytes().AsRaw())
+	_, ok := attributes.Get(blobstore.ReferenceAttributePrefix + "short")
+	assert.False(t, ok)
+	assert.Equal(t, before+3, retrieveTruncated(t, "inventory"))
+
+	reference, ok := attributes.Get(blobstore.ReferenceAttributePrefix + "db.statement")
+	assert.True(t, ok)
+	full, err := validator.sidecar.Get(context.Background(), reference.Str())
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM items", string(full))
+
+	reference, _ = attributes.Get(blobstore.ReferenceAttributePrefix + "payload")
+	full, err = validator.sidecar.Get(context.Background(), reference.Str())
+	assert.NoError(t, err)
+	assert.Equal(t, "0123456789", string(full))
+
+	original, _ := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes().Get("db.statement")
+	assert.Equal(t, "SELECT * FROM items", original.Str(), "the original traces must not be modified")
+}
+
+func TestFilterTruncatesWithoutSidecar(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.MaxAttributeValueLength = 4
+	cfg.Truncation.Enabled = true
+	validator, err := NewValidator(zap.NewNop(), "test", cfg)
+	assert.NoError(t, err)
+
+	traces, spans := newTestTraces("inventory")
+	appendSpan(spans, "too long value").Attributes().PutStr("http.url", "http://inventory/items")
+
+	attributes := validator.Filter(context.Background(), traces).ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes()
+
+	url, _ := attributes.Get("http.url")
+	assert.Equal(t, "http", url.Str())
+	assert.Equal(t, 1, attributes.Len())
+}
+
+// blockingStore blocks puts until their context is done
+type blockingStore struct{}
+
+func (blockingStore) Put(ctx context.Context, _ string, _ []byte) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (blockingStore) Get(context.Context, string) ([]byte, error) {
+	return nil, blobstore.ErrNotFound
+}
+
+func TestFilterBoundsSidecarPuts(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.MaxAttributeValueLength = 4
+	cfg.Truncation.Enabled = true
+	cfg.Truncation.SidecarTimeout = 10 * time.Millisecond
+	validator, err := NewValidator(zap.NewNop(), "test", cfg)
+	assert.NoError(t, err)
+	validator.sidecar = blockingStore{}
+
+	traces, spans := newTestTraces("inventory")
+	appendSpan(spans, "too long value").Attributes().PutStr("http.url", "http://inventory/items")
+
+	attributes := validator.Filter(context.Background(), traces).ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes()
+
+	url, _ := attributes.Get("http.url")
+	assert.Equal(t, "http", url.Str())
+	_, ok := attributes.Get(blobstore.ReferenceAttributePrefix + "http.url")
+	assert.False(t, ok, "a value which couldn't be stored in time must not be referenced")
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := NewDefaultConfig()
+	assert.NoError(t, cfg.Validate())
+
+	cfg.MaxAttributeValueLength = -1
+	assert.Error(t, cfg.Validate())
+
+	cfg = NewDefaultConfig()
+	cfg.Truncation.Sidecar.Type = blobstore.TypeS3
+	assert.Error(t, cfg.Validate())
+}
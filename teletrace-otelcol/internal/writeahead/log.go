@@ -0,0 +1,316 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package writeahead
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	batchFileExt  = ".batch"
+	tmpFilePrefix = ".tmp-"
+)
+
+// ErrFull is returned when appending a batch to a log which reached its maximum size.
+var ErrFull = errors.New("write-ahead log is full")
+
+// Config defines the write-ahead log persisting received batches before they are acknowledged.
+type Config struct {
+	// Enabled enables persisting batches to the write-ahead log before acknowledging them
+	Enabled bool `mapstructure:"enabled"`
+	// Directory is where the batches are persisted, it must not be shared with other exporters
+	Directory string `mapstructure:"directory"`
+	// MaxSizeMiB is the maximum size of the unwritten batches, new batches are rejected once it's reached
+	MaxSizeMiB int `mapstructure:"max_size_mib"`
+	// Sync flushes each batch to the disk before acknowledging it, so it survives a crash of the host
+	Sync bool `mapstructure:"sync"`
+}
+
+// NewDefaultConfig returns the default write-ahead log configuration, with the log disabled.
+func NewDefaultConfig() Config {
+	return Config{
+		MaxSizeMiB: 1024,
+		Sync:       true,
+	}
+}
+
+// Validate validates the write-ahead log configuration.
+func (cfg *Config) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Directory == "" {
+		return fmt.Errorf("write-ahead log directory must be specified")
+	}
+	if cfg.MaxSizeMiB < 1 {
+		return fmt.Errorf("write-ahead log max_size_mib must be greater than zero")
+	}
+	return nil
+}
+
+// Log persists batches as files in a directory until they are written to the storage, so batches which were
+// acknowledged but not written when the process stopped are replayed on the next start.
+// A disabled Log persists nothing, and its methods are no-ops.
+type Log struct {
+	logger  *zap.Logger
+	cfg     Config
+	metrics *metrics
+
+	mu      sync.Mutex
+	last    int64
+	sizes   map[string]int64
+	size    int64
+	pending []string
+}
+
+// NewLog creates a Log, creating its directory if needed. The batches found in the directory are kept
+// to be replayed by Replay. The name identifies the log in the exported metrics, e.g. the exporter name.
+func NewLog(logger *zap.Logger, name string, cfg Config) (*Log, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	l := &Log{logger: logger, cfg: cfg, sizes: map[string]int64{}}
+	if !cfg.Enabled {
+		return l, nil
+	}
+
+	m, err := newMetrics(name)
+	if err != nil {
+		return nil, err
+	}
+	l.metrics = m
+	if err := os.MkdirAll(cfg.Directory, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create write-ahead log directory: %w", err)
+	}
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	l.metrics.recordSize(l.size)
+	return l, nil
+}
+
+// load lists the batches persisted by previous runs, oldest first, and removes the partially written ones.
+func (l *Log) load() error {
+	entries, err := os.ReadDir(l.cfg.Directory)
+	if err != nil {
+		return fmt.Errorf("failed to list write-ahead log directory: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if strings.HasPrefix(e.Name(), tmpFilePrefix) {
+			if err := os.Remove(filepath.Join(l.cfg.Directory, e.Name())); err != nil {
+				return fmt.Errorf("failed to remove partial write-ahead log batch: %w", err)
+			}
+			continue
+		}
+		if !strings.HasSuffix(e.Name(), batchFileExt) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return fmt.Errorf("failed to read write-ahead log batch: %w", err)
+		}
+		l.sizes[e.Name()] = info.Size()
+		l.size += info.Size()
+		l.pending = append(l.pending, e.Name())
+	}
+	sort.Strings(l.pending)
+	return nil
+}
+
+// Append persists a batch and returns its entry name, to be passed to Remove once the batch is written.
+// The batch is marshaled only if the log is enabled, otherwise an empty entry name is returned.
+// It returns ErrFull if the unwritten batches reached the maximum size.
+func (l *Log) Append(marshal func() ([]byte, error)) (string, error) {
+	if !l.cfg.Enabled {
+		return "", nil
+	}
+	batch, err := marshal()
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal batch for the write-ahead log: %w", err)
+	}
+
+	l.mu.Lock()
+	if l.size+int64(len(batch)) > int64(l.cfg.MaxSizeMiB)<<20 {
+		l.mu.Unlock()
+		return "", ErrFull
+	}
+	// The size is reserved before writing, so concurrent appends don't exceed the maximum size
+	name := fmt.Sprintf("%020d%s", l.nextSequence(), batchFileExt)
+	l.sizes[name] = int64(len(batch))
+	l.size += int64(len(batch))
+	l.mu.Unlock()
+
+	if err := l.write(name, batch); err != nil {
+		l.release(name)
+		return "", fmt.Errorf("failed to persist batch to the write-ahead log: %w", err)
+	}
+	l.metrics.recordSize(l.Size())
+	return name, nil
+}
+
+// write writes a batch to a temporary file first, so a crash while writing doesn't leave a partial batch to be replayed.
+func (l *Log) write(name string, batch []byte) error {
+	tmp, err := os.CreateTemp(l.cfg.Directory, tmpFilePrefix+"*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(batch); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if l.cfg.Sync {
+		if err := tmp.Sync(); err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(l.cfg.Directory, name)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if l.cfg.Sync {
+		return syncDir(l.cfg.Directory)
+	}
+	return nil
+}
+
+// syncDir flushes the directory entries to the disk, so a renamed file survives a crash of the host.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// nextSequence returns the current unix time in nanoseconds, kept increasing so file names are unique and ordered.
+// It must be called with mu held.
+func (l *Log) nextSequence() int64 {
+	seq := time.Now().UnixNano()
+	if seq <= l.last {
+		seq = l.last + 1
+	}
+	l.last = seq
+	return seq
+}
+
+// Remove removes written batches from the log. Failures are logged, as a batch is only written again
+// when replayed, which the idempotent writes of the exporters tolerate.
+func (l *Log) Remove(names ...string) {
+	if !l.cfg.Enabled {
+		return
+	}
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if err := os.Remove(filepath.Join(l.cfg.Directory, name)); err != nil && !os.IsNotExist(err) {
+			l.logger.Warn("Failed to remove written batch from the write-ahead log", zap.String("file", name), zap.Error(err))
+			continue
+		}
+		l.release(name)
+	}
+	l.metrics.recordSize(l.Size())
+}
+
+func (l *Log) release(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.size -= l.sizes[name]
+	delete(l.sizes, name)
+}
+
+// Size returns the total size in bytes of the batches in the log.
+func (l *Log) Size() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.size
+}
+
+// Retry keeps batches whose write failed, or which were dropped before being written, to be replayed by the next
+// Replay, so they are written once the storage recovers instead of filling the log until the next start.
+func (l *Log) Retry(names ...string) {
+	if !l.cfg.Enabled {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requeue(names)
+}
+
+// requeue adds the batches still in the log to the pending ones, keeping them in the order they were appended.
+// It must be called with mu held.
+func (l *Log) requeue(names []string) {
+	queued := make(map[string]bool, len(l.pending))
+	for _, name := range l.pending {
+		queued[name] = true
+	}
+	for _, name := range names {
+		if _, ok := l.sizes[name]; !ok || queued[name] {
+			continue
+		}
+		queued[name] = true
+		l.pending = append(l.pending, name)
+	}
+	sort.Strings(l.pending)
+}
+
+// Replay writes the batches persisted by previous runs, or kept by Retry, in the order they were appended, removing
+// each one once written. It stops at the first batch that fails to be written, keeping it and the batches after it
+// for the next replay.
+func (l *Log) Replay(ctx context.Context, write func(ctx context.Context, batch []byte) error) error {
+	l.mu.Lock()
+	pending := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+
+	for i, name := range pending {
+		batch, err := os.ReadFile(filepath.Join(l.cfg.Directory, name))
+		if err == nil {
+			err = write(ctx, batch)
+		}
+		if err != nil {
+			l.mu.Lock()
+			l.requeue(pending[i:])
+			l.mu.Unlock()
+			return fmt.Errorf("failed to replay write-ahead log batch %s: %w", name, err)
+		}
+		l.Remove(name)
+		l.metrics.recordReplayed()
+		l.logger.Info("Replayed write-ahead log batch", zap.String("file", name))
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package writeahead
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newTestConfig(t *testing.T) Config {
+	cfg := NewDefaultConfig()
+	cfg.Enabled = true
+	cfg.Directory = t.TempDir()
+	return cfg
+}
+
+func marshal(batch string) func() ([]byte, error) {
+	return func() ([]byte, error) { return []byte(batch), nil }
+}
+
+func TestAppendPersistsUntilRemoved(t *testing.T) {
+	cfg := newTestConfig(t)
+	l, err := NewLog(zap.NewNop(), "test", cfg)
+	assert.NoError(t, err)
+
+	name, err := l.Append(marshal("batch"))
+	assert.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(cfg.Directory, name))
+	assert.NoError(t, err)
+	assert.Equal(t, "batch", string(content))
+	assert.Equal(t, int64(5), l.Size())
+
+	l.Remove(name)
+	_, err = os.Stat(filepath.Join(cfg.Directory, name))
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, int64(0), l.Size())
+}
+
+func TestReplayWritesBatchesOfPreviousRuns(t *testing.T) {
+	cfg := newTestConfig(t)
+	previous, err := NewLog(zap.NewNop(), "test", cfg)
+	assert.NoError(t, err)
+	for _, batch := range []string{"first", "second", "third"} {
+		_, err := previous.Append(marshal(batch))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(cfg.Directory, tmpFilePrefix+"partial"), []byte("par"), 0o600))
+
+	l, err := NewLog(zap.NewNop(), "test", cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(16), l.Size())
+	// Batches appended after the start aren't replayed
+	_, err = l.Append(marshal("new"))
+	assert.NoError(t, err)
+
+	var replayed []string
+	err = l.Replay(context.Background(), func(ctx context.Context, batch []byte) error {
+		replayed = append(replayed, string(batch))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first", "second", "third"}, replayed)
+	assert.Equal(t, int64(3), l.Size())
+
+	entries, err := os.ReadDir(cfg.Directory)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestReplayKeepsBatchesFromFirstFailure(t *testing.T) {
+	cfg := newTestConfig(t)
+	previous, err := NewLog(zap.NewNop(), "test", cfg)
+	assert.NoError(t, err)
+	for _, batch := range []string{"first", "second", "third"} {
+		_, err := previous.Append(marshal(batch))
+		assert.NoError(t, err)
+	}
+
+	l, err := NewLog(zap.NewNop(), "test", cfg)
+	assert.NoError(t, err)
+	var replayed []string
+	err = l.Replay(context.Background(), func(ctx context.Context, batch []byte) error {
+		if string(batch) == "second" {
+			return errors.New("connection refused")
+		}
+		replayed = append(replayed, string(batch))
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"first"}, replayed)
+
+	next, err := NewLog(zap.NewNop(), "test", cfg)
+	assert.NoError(t, err)
+	replayed = nil
+	err = next.Replay(context.Background(), func(ctx context.Context, batch []byte) error {
+		replayed = append(replayed, string(batch))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"second", "third"}, replayed)
+}
+
+func TestReplayWritesRetriedBatchesOnceTheStorageRecovers(t *testing.T) {
+	cfg := newTestConfig(t)
+	l, err := NewLog(zap.NewNop(), "test", cfg)
+	assert.NoError(t, err)
+
+	var names []string
+	for _, batch := range []string{"first", "second"} {
+		name, err := l.Append(marshal(batch))
+		assert.NoError(t, err)
+		names = append(names, name)
+	}
+	// the queued writes failed once retries were exhausted, or the batches were dropped
+	l.Retry(names[1], names[0], "")
+
+	available := false
+	var replayed []string
+	write := func(ctx context.Context, batch []byte) error {
+		if !available {
+			return errors.New("connection refused")
+		}
+		replayed = append(replayed, string(batch))
+		return nil
+	}
+	assert.Error(t, l.Replay(context.Background(), write))
+	assert.Empty(t, replayed)
+	assert.Equal(t, int64(11), l.Size())
+
+	available = true
+	assert.NoError(t, l.Replay(context.Background(), write))
+	assert.Equal(t, []string{"first", "second"}, replayed)
+	assert.Equal(t, int64(0), l.Size())
+	entries, err := os.ReadDir(cfg.Directory)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestRetryIgnoresRemovedBatches(t *testing.T) {
+	cfg := newTestConfig(t)
+	l, err := NewLog(zap.NewNop(), "test", cfg)
+	assert.NoError(t, err)
+	name, err := l.Append(marshal("batch"))
+	assert.NoError(t, err)
+	l.Remove(name)
+
+	l.Retry(name)
+	err = l.Replay(context.Background(), func(ctx context.Context, batch []byte) error {
+		t.Fatal("removed batch replayed")
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestAppendRejectsWhenFull(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.MaxSizeMiB = 1
+	l, err := NewLog(zap.NewNop(), "test", cfg)
+	assert.NoError(t, err)
+
+	half := func() ([]byte, error) { return make([]byte, 600<<10), nil }
+	name, err := l.Append(half)
+	assert.NoError(t, err)
+	_, err = l.Append(half)
+	assert.ErrorIs(t, err, ErrFull)
+
+	l.Remove(name)
+	_, err = l.Append(half)
+	assert.NoError(t, err)
+}
+
+func TestDisabledLogPersistsNothing(t *testing.T) {
+	l, err := NewLog(zap.NewNop(), "test", NewDefaultConfig())
+	assert.NoError(t, err)
+
+	name, err := l.Append(func() ([]byte, error) {
+		t.Fatal("batch marshaled by a disabled log")
+		return nil, nil
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, name)
+	l.Remove(name)
+	assert.NoError(t, l.Replay(context.Background(), nil))
+}
+
+func TestValidate(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Enabled = true
+	assert.Error(t, cfg.Validate())
+
+	cfg.Directory = t.TempDir()
+	assert.NoError(t, cfg.Validate())
+
+	cfg.MaxSizeMiB = 0
+	assert.Error(t, cfg.Validate())
+}
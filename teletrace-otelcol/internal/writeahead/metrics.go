@@ -0,0 +1,86 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package writeahead
+
+import (
+	"context"
+	"sync"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+var (
+	logNameKey = tag.MustNewKey("log")
+
+	logSize = stats.Int64(
+		"teletrace_write_ahead_log_size",
+		"Size of the batches persisted to the write-ahead log and not written to the storage yet",
+		stats.UnitBytes,
+	)
+	replayedBatches = stats.Int64(
+		"teletrace_write_ahead_log_replayed_batches",
+		"Number of batches replayed from the write-ahead log on start",
+		stats.UnitDimensionless,
+	)
+
+	registerViewsOnce sync.Once
+	errRegisterViews  error
+)
+
+// metrics records the write-ahead log metrics with OpenCensus, which are exposed by the collector's own telemetry.
+type metrics struct {
+	ctx context.Context
+}
+
+func newMetrics(name string) (*metrics, error) {
+	registerViewsOnce.Do(func() {
+		errRegisterViews = view.Register(
+			&view.View{
+				Name:        logSize.Name(),
+				Description: logSize.Description(),
+				Measure:     logSize,
+				TagKeys:     []tag.Key{logNameKey},
+				Aggregation: view.LastValue(),
+			},
+			&view.View{
+				Name:        replayedBatches.Name(),
+				Description: replayedBatches.Description(),
+				Measure:     replayedBatches,
+				TagKeys:     []tag.Key{logNameKey},
+				Aggregation: view.Sum(),
+			},
+		)
+	})
+	if errRegisterViews != nil {
+		return nil, errRegisterViews
+	}
+
+	ctx, err := tag.New(context.Background(), tag.Insert(logNameKey, name))
+	if err != nil {
+		return nil, err
+	}
+	return &metrics{ctx: ctx}, nil
+}
+
+func (m *metrics) recordSize(size int64) {
+	stats.Record(m.ctx, logSize.M(size))
+}
+
+func (m *metrics) recordReplayed() {
+	stats.Record(m.ctx, replayedBatches.M(1))
+}
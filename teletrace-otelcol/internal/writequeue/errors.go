@@ -0,0 +1,52 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writequeue
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// QueueFullError is returned when a batch is rejected because the queue is full.
+// It implements GRPCStatus, so the OTLP receiver responds with RESOURCE_EXHAUSTED and a retry delay,
+// which the OpenTelemetry SDKs and collectors treat as retryable.
+type QueueFullError struct {
+	// RetryDelay is the time the client is asked to wait before sending the batch again
+	RetryDelay time.Duration
+}
+
+func (e *QueueFullError) Error() string {
+	return fmt.Sprintf("write queue is full, retry in %s", e.RetryDelay)
+}
+
+// GRPCStatus returns the RESOURCE_EXHAUSTED status with a RetryInfo detail.
+func (e *QueueFullError) GRPCStatus() *status.Status {
+	st := status.New(codes.ResourceExhausted, e.Error())
+	if e.RetryDelay <= 0 {
+		return st
+	}
+	withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(e.RetryDelay)})
+	if err != nil {
+		return st
+	}
+	return withDetails
+}
@@ -0,0 +1,104 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writequeue
+
+import (
+	"context"
+	"sync"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+var (
+	queueNameKey = tag.MustNewKey("queue")
+
+	queueDepth = stats.Int64(
+		"teletrace_write_queue_depth",
+		"Number of batches waiting to be written to the storage",
+		stats.UnitDimensionless,
+	)
+	queueDropped = stats.Int64(
+		"teletrace_write_queue_dropped_batches",
+		"Number of batches dropped because the write queue was full",
+		stats.UnitDimensionless,
+	)
+
+	queueRejected = stats.Int64(
+		"teletrace_write_queue_rejected_batches",
+		"Number of batches rejected with a retryable error because the write queue was full",
+		stats.UnitDimensionless,
+	)
+
+	registerViewsOnce sync.Once
+	errRegisterViews  error
+)
+
+// metrics records the queue metrics with OpenCensus, which are exposed by the collector's own telemetry.
+type metrics struct {
+	ctx context.Context
+}
+
+func newMetrics(name string) (*metrics, error) {
+	registerViewsOnce.Do(func() {
+		errRegisterViews = view.Register(
+			&view.View{
+				Name:        queueDepth.Name(),
+				Description: queueDepth.Description(),
+				Measure:     queueDepth,
+				TagKeys:     []tag.Key{queueNameKey},
+				Aggregation: view.LastValue(),
+			},
+			&view.View{
+				Name:        queueDropped.Name(),
+				Description: queueDropped.Description(),
+				Measure:     queueDropped,
+				TagKeys:     []tag.Key{queueNameKey},
+				Aggregation: view.Sum(),
+			},
+			&view.View{
+				Name:        queueRejected.Name(),
+				Description: queueRejected.Description(),
+				Measure:     queueRejected,
+				TagKeys:     []tag.Key{queueNameKey},
+				Aggregation: view.Sum(),
+			},
+		)
+	})
+	if errRegisterViews != nil {
+		return nil, errRegisterViews
+	}
+
+	ctx, err := tag.New(context.Background(), tag.Insert(queueNameKey, name))
+	if err != nil {
+		return nil, err
+	}
+	return &metrics{ctx: ctx}, nil
+}
+
+func (m *metrics) recordDepth(depth int) {
+	stats.Record(m.ctx, queueDepth.M(int64(depth)))
+}
+
+func (m *metrics) recordDropped() {
+	stats.Record(m.ctx, queueDropped.M(1))
+}
+
+func (m *metrics) recordRejected() {
+	stats.Record(m.ctx, queueRejected.M(1))
+}
@@ -0,0 +1,328 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writequeue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// OverflowPolicyBlock blocks the ingestion until the queue has room for the batch
+	OverflowPolicyBlock = "block"
+	// OverflowPolicyDropOldest drops the oldest queued batch to make room for the new one
+	OverflowPolicyDropOldest = "drop_oldest"
+	// OverflowPolicyReject rejects the new batch with a retryable error, so the clients back off and send it again
+	OverflowPolicyReject = "reject"
+)
+
+// ErrStopped is returned when enqueuing a batch to a stopped queue.
+var ErrStopped = errors.New("write queue is stopped")
+
+// Config defines the queue decoupling the ingestion from the storage writes.
+type Config struct {
+	// Enabled enables queueing batches and writing them asynchronously
+	Enabled bool `mapstructure:"enabled"`
+	// QueueSize is the maximum number of batches kept in the queue
+	QueueSize int `mapstructure:"queue_size"`
+	// NumWorkers is the number of batches written concurrently
+	NumWorkers int `mapstructure:"num_workers"`
+	// OverflowPolicy is what happens when the queue is full, either block, reject or drop_oldest.
+	// reject only pushes back on the clients when no processor decouples the pipeline, see the README.
+	OverflowPolicy string `mapstructure:"overflow_policy"`
+	// RetryDelay is the time rejected clients are asked to wait before sending the batch again
+	RetryDelay time.Duration `mapstructure:"retry_delay"`
+	// ShutdownTimeout is the maximum time to wait for queued batches to be written on shutdown
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	// BatchSize is the maximum size of queued batches merged into a single write, e.g. the number of spans,
+	// merging is disabled if zero
+	BatchSize int `mapstructure:"batch_size"`
+	// FlushInterval is the maximum time a worker waits for queued batches to fill a write up to the batch size,
+	// zero merges only the batches already queued
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+}
+
+// NewDefaultConfig returns the default queue configuration.
+func NewDefaultConfig() Config {
+	return Config{
+		Enabled:         true,
+		QueueSize:       1000,
+		NumWorkers:      4,
+		OverflowPolicy:  OverflowPolicyBlock,
+		RetryDelay:      5 * time.Second,
+		ShutdownTimeout: 10 * time.Second,
+		BatchSize:       1000,
+	}
+}
+
+// Validate validates the queue configuration.
+func (cfg *Config) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.QueueSize < 1 {
+		return fmt.Errorf("queue_size must be greater than zero")
+	}
+	if cfg.NumWorkers < 1 {
+		return fmt.Errorf("num_workers must be greater than zero")
+	}
+	if cfg.BatchSize < 0 {
+		return fmt.Errorf("batch_size must not be negative")
+	}
+	if cfg.FlushInterval < 0 {
+		return fmt.Errorf("flush_interval must not be negative")
+	}
+	switch cfg.OverflowPolicy {
+	case OverflowPolicyReject:
+		if cfg.RetryDelay < 0 {
+			return fmt.Errorf("retry_delay must not be negative")
+		}
+	case OverflowPolicyBlock, OverflowPolicyDropOldest:
+	default:
+		return fmt.Errorf("invalid overflow_policy %q, expected %s, %s or %s",
+			cfg.OverflowPolicy, OverflowPolicyReject, OverflowPolicyBlock, OverflowPolicyDropOldest)
+	}
+	return nil
+}
+
+// Batch is implemented by queued items which can be merged, so they are written together.
+type Batch interface {
+	// Size returns the size of the batch, e.g. its number of spans
+	Size() int
+	// Merge appends other, a Batch of the same type, to the batch
+	Merge(other Batch)
+}
+
+// Queue is a bounded, in-memory FIFO queue of batches, written to the storage by a pool of workers.
+type Queue struct {
+	logger  *zap.Logger
+	cfg     Config
+	consume func(ctx context.Context, item interface{}) error
+	drop    func(item interface{})
+	metrics *metrics
+
+	// mu guards items from being closed while batches are enqueued
+	mu       sync.RWMutex
+	stopped  bool
+	items    chan interface{}
+	dropped  atomic.Int64
+	rejected atomic.Int64
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	workerWG sync.WaitGroup
+}
+
+// NewQueue creates a queue, consume is called by the workers for each queued batch.
+// The name identifies the queue in the exported metrics, e.g. the exporter name.
+func NewQueue(logger *zap.Logger, name string, cfg Config, consume func(ctx context.Context, item interface{}) error) (*Queue, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	m, err := newMetrics(name)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue{
+		logger:  logger,
+		cfg:     cfg,
+		consume: consume,
+		metrics: m,
+		items:   make(chan interface{}, cfg.QueueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+	}, nil
+}
+
+// Start starts the workers.
+func (q *Queue) Start() {
+	for i := 0; i < q.cfg.NumWorkers; i++ {
+		q.workerWG.Add(1)
+		go func() {
+			defer q.workerWG.Done()
+			q.work()
+		}()
+	}
+}
+
+// work consumes the queued batches until the queue is stopped. Batches are merged up to the batch size
+// before being consumed, if they implement Batch.
+func (q *Queue) work() {
+	var next interface{}
+	for {
+		item := next
+		next = nil
+		if item == nil {
+			var ok bool
+			if item, ok = <-q.items; !ok {
+				return
+			}
+		}
+		if batch, ok := item.(Batch); ok && q.cfg.BatchSize > 0 {
+			next = q.fill(batch)
+		}
+		q.metrics.recordDepth(len(q.items))
+		if err := q.consume(q.ctx, item); err != nil {
+			q.logger.Error("Failed to write queued batch", zap.Error(err))
+		}
+	}
+}
+
+// fill merges queued batches into batch until it reaches the batch size, waiting up to the flush interval for them.
+// It returns the dequeued item which didn't fit into the batch, to be consumed next, or nil.
+func (q *Queue) fill(batch Batch) interface{} {
+	var flush <-chan time.Time
+	if q.cfg.FlushInterval > 0 {
+		timer := time.NewTimer(q.cfg.FlushInterval)
+		defer timer.Stop()
+		flush = timer.C
+	}
+
+	for batch.Size() < q.cfg.BatchSize {
+		var item interface{}
+		var ok bool
+		if flush == nil {
+			select {
+			case item, ok = <-q.items:
+			default:
+				return nil
+			}
+		} else {
+			select {
+			case item, ok = <-q.items:
+			case <-flush:
+				return nil
+			}
+		}
+		if !ok {
+			return nil
+		}
+
+		other, isBatch := item.(Batch)
+		if !isBatch || batch.Size()+other.Size() > q.cfg.BatchSize {
+			return item
+		}
+		batch.Merge(other)
+	}
+	return nil
+}
+
+// Enqueue adds a batch to the queue. When the queue is full, it either rejects the batch with a QueueFullError,
+// blocks until there's room or ctx is done, or drops the oldest queued batch, according to the overflow policy.
+func (q *Queue) Enqueue(ctx context.Context, item interface{}) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.stopped {
+		return ErrStopped
+	}
+	defer func() { q.metrics.recordDepth(len(q.items)) }()
+
+	switch q.cfg.OverflowPolicy {
+	case OverflowPolicyReject:
+		select {
+		case q.items <- item:
+			return nil
+		default:
+			q.rejected.Add(1)
+			q.metrics.recordRejected()
+			return &QueueFullError{RetryDelay: q.cfg.RetryDelay}
+		}
+	case OverflowPolicyBlock:
+		select {
+		case q.items <- item:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	for {
+		select {
+		case q.items <- item:
+			return nil
+		default:
+		}
+		// The queue is full, drop the oldest batch unless a worker took it meanwhile
+		select {
+		case dropped := <-q.items:
+			q.dropped.Add(1)
+			q.metrics.recordDropped()
+			if q.drop != nil {
+				q.drop(dropped)
+			}
+		default:
+		}
+	}
+}
+
+// OnDrop sets a function called with each batch dropped by the drop_oldest overflow policy, e.g. to keep it in the
+// write-ahead log to be written later. It must be called before batches are enqueued.
+func (q *Queue) OnDrop(drop func(item interface{})) {
+	q.drop = drop
+}
+
+// Depth returns the number of queued batches.
+func (q *Queue) Depth() int {
+	return len(q.items)
+}
+
+// Dropped returns the number of batches dropped since the queue was created.
+func (q *Queue) Dropped() int64 {
+	return q.dropped.Load()
+}
+
+// Rejected returns the number of batches rejected since the queue was created.
+func (q *Queue) Rejected() int64 {
+	return q.rejected.Load()
+}
+
+// Stop stops accepting batches and waits for the queued ones to be written, up to the shutdown timeout.
+// Once the timeout is reached, the workers' context is canceled so the remaining writes fail.
+func (q *Queue) Stop() error {
+	q.mu.Lock()
+	if q.stopped {
+		q.mu.Unlock()
+		return nil
+	}
+	q.stopped = true
+	close(q.items)
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.workerWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-time.After(q.cfg.ShutdownTimeout):
+		remaining := len(q.items)
+		// The workers keep consuming the remaining batches with a canceled context, failing them fast
+		q.cancel()
+		return fmt.Errorf("timed out writing queued batches on shutdown, %d batches were not written", remaining)
+	}
+}
@@ -0,0 +1,267 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writequeue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats/view"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestConfig(policy string) Config {
+	cfg := NewDefaultConfig()
+	cfg.QueueSize = 2
+	cfg.NumWorkers = 1
+	cfg.OverflowPolicy = policy
+	cfg.ShutdownTimeout = time.Second
+	return cfg
+}
+
+func TestQueueWritesBatchesInOrder(t *testing.T) {
+	var mu sync.Mutex
+	var written []interface{}
+	q, err := NewQueue(zap.NewNop(), "test_order", newTestConfig(OverflowPolicyBlock), func(ctx context.Context, item interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		written = append(written, item)
+		return nil
+	})
+	assert.NoError(t, err)
+	q.Start()
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, q.Enqueue(context.Background(), i))
+	}
+	assert.NoError(t, q.Stop())
+
+	assert.Equal(t, []interface{}{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, written)
+	assert.ErrorIs(t, q.Enqueue(context.Background(), 10), ErrStopped)
+}
+
+func TestQueueDropsOldestWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	var written []interface{}
+	q, err := NewQueue(zap.NewNop(), "test_drop_oldest", newTestConfig(OverflowPolicyDropOldest), func(ctx context.Context, item interface{}) error {
+		<-release
+		written = append(written, item)
+		return nil
+	})
+	assert.NoError(t, err)
+	var dropped []interface{}
+	q.OnDrop(func(item interface{}) { dropped = append(dropped, item) })
+
+	droppedBefore := retrieveDropped(t, "test_drop_oldest")
+
+	// Workers aren't started, so the queue fills up
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, q.Enqueue(context.Background(), i))
+	}
+	assert.Equal(t, 2, q.Depth())
+	assert.Equal(t, int64(3), q.Dropped())
+	assert.Equal(t, []interface{}{0, 1, 2}, dropped)
+
+	q.Start()
+	close(release)
+	assert.NoError(t, q.Stop())
+	assert.Equal(t, []interface{}{3, 4}, written)
+
+	assert.Equal(t, droppedBefore+3, retrieveDropped(t, "test_drop_oldest"))
+}
+
+func retrieveDropped(t *testing.T, name string) float64 {
+	rows, err := view.RetrieveData(queueDropped.Name())
+	assert.NoError(t, err)
+	for _, row := range rows {
+		if row.Tags[0].Value == name {
+			return row.Data.(*view.SumData).Value
+		}
+	}
+	return 0
+}
+
+func TestQueueBlocksWhenFull(t *testing.T) {
+	q, err := NewQueue(zap.NewNop(), "test_block", newTestConfig(OverflowPolicyBlock), func(ctx context.Context, item interface{}) error {
+		return nil
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, q.Enqueue(context.Background(), 1))
+	assert.NoError(t, q.Enqueue(context.Background(), 2))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, q.Enqueue(ctx, 3), context.DeadlineExceeded)
+	assert.Equal(t, int64(0), q.Dropped())
+
+	q.Start()
+	assert.NoError(t, q.Enqueue(context.Background(), 3))
+	assert.NoError(t, q.Stop())
+}
+
+func TestQueueRejectsWhenFull(t *testing.T) {
+	q, err := NewQueue(zap.NewNop(), "test_reject", newTestConfig(OverflowPolicyReject), func(ctx context.Context, item interface{}) error {
+		return nil
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, q.Enqueue(context.Background(), 1))
+	assert.NoError(t, q.Enqueue(context.Background(), 2))
+
+	err = q.Enqueue(context.Background(), 3)
+	var queueFull *QueueFullError
+	assert.ErrorAs(t, err, &queueFull)
+	assert.Equal(t, int64(1), q.Rejected())
+	assert.Equal(t, 2, q.Depth())
+
+	st, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	assert.Len(t, st.Details(), 1)
+	retryInfo, ok := st.Details()[0].(*errdetails.RetryInfo)
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Second, retryInfo.RetryDelay.AsDuration())
+
+	q.Start()
+	assert.NoError(t, q.Stop())
+}
+
+func TestQueueStopTimesOut(t *testing.T) {
+	cfg := newTestConfig(OverflowPolicyBlock)
+	cfg.ShutdownTimeout = 10 * time.Millisecond
+	q, err := NewQueue(zap.NewNop(), "test_stop_timeout", cfg, func(ctx context.Context, item interface{}) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.NoError(t, err)
+	q.Start()
+
+	assert.NoError(t, q.Enqueue(context.Background(), 1))
+	assert.Error(t, q.Stop())
+}
+
+type testBatch struct {
+	items []int
+}
+
+func (b *testBatch) Size() int {
+	return len(b.items)
+}
+
+func (b *testBatch) Merge(other Batch) {
+	b.items = append(b.items, other.(*testBatch).items...)
+}
+
+func newTestBatchQueue(t *testing.T, name string, cfg Config) (*Queue, func() [][]int) {
+	var mu sync.Mutex
+	var written [][]int
+	q, err := NewQueue(zap.NewNop(), name, cfg, func(ctx context.Context, item interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		written = append(written, item.(*testBatch).items)
+		return nil
+	})
+	assert.NoError(t, err)
+	return q, func() [][]int {
+		mu.Lock()
+		defer mu.Unlock()
+		return written
+	}
+}
+
+func TestQueueMergesBatchesUpToBatchSize(t *testing.T) {
+	cfg := newTestConfig(OverflowPolicyReject)
+	cfg.QueueSize = 10
+	cfg.BatchSize = 5
+	q, written := newTestBatchQueue(t, "test_merge", cfg)
+
+	// The batches are queued before the worker starts, so they're merged without waiting
+	for _, batch := range []*testBatch{{items: []int{1, 2}}, {items: []int{3, 4}}, {items: []int{5, 6}}, {items: []int{7}}} {
+		assert.NoError(t, q.Enqueue(context.Background(), batch))
+	}
+	q.Start()
+	assert.NoError(t, q.Stop())
+
+	assert.Equal(t, [][]int{{1, 2, 3, 4}, {5, 6, 7}}, written())
+}
+
+func TestQueueWaitsFlushIntervalToFillBatch(t *testing.T) {
+	cfg := newTestConfig(OverflowPolicyReject)
+	cfg.BatchSize = 2
+	cfg.FlushInterval = time.Minute
+	q, written := newTestBatchQueue(t, "test_flush_interval", cfg)
+	q.Start()
+
+	assert.NoError(t, q.Enqueue(context.Background(), &testBatch{items: []int{1}}))
+	time.Sleep(10 * time.Millisecond)
+	assert.Empty(t, written())
+
+	// The batch is written once it reaches the batch size, before the flush interval
+	assert.NoError(t, q.Enqueue(context.Background(), &testBatch{items: []int{2}}))
+	assert.Eventually(t, func() bool { return len(written()) == 1 }, time.Second, time.Millisecond)
+	assert.NoError(t, q.Stop())
+
+	assert.Equal(t, [][]int{{1, 2}}, written())
+}
+
+func TestQueueFlushesPartialBatchOnStop(t *testing.T) {
+	cfg := newTestConfig(OverflowPolicyReject)
+	cfg.BatchSize = 10
+	cfg.FlushInterval = time.Minute
+	q, written := newTestBatchQueue(t, "test_flush_on_stop", cfg)
+	q.Start()
+
+	assert.NoError(t, q.Enqueue(context.Background(), &testBatch{items: []int{1}}))
+	assert.NoError(t, q.Stop())
+
+	assert.Equal(t, [][]int{{1}}, written())
+}
+
+func TestValidate(t *testing.T) {
+	cfg := NewDefaultConfig()
+	assert.NoError(t, cfg.Validate())
+
+	cfg.OverflowPolicy = "drop_newest"
+	assert.Error(t, cfg.Validate())
+
+	cfg = NewDefaultConfig()
+	cfg.OverflowPolicy = OverflowPolicyReject
+	cfg.RetryDelay = -time.Second
+	assert.Error(t, cfg.Validate())
+
+	cfg = NewDefaultConfig()
+	cfg.BatchSize = -1
+	assert.Error(t, cfg.Validate())
+
+	cfg = NewDefaultConfig()
+	cfg.FlushInterval = -time.Second
+	assert.Error(t, cfg.Validate())
+
+	cfg = NewDefaultConfig()
+	cfg.QueueSize = 0
+	assert.Error(t, cfg.Validate())
+
+	cfg.Enabled = false
+	assert.NoError(t, cfg.Validate())
+}
@@ -0,0 +1,59 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writequeue
+
+import "go.opentelemetry.io/collector/pdata/ptrace"
+
+// TracesBatch is a batch of spans waiting in the queue, with the write-ahead log entries of the received batches
+// it holds. Queued batches are merged up to the queue's batch size, so they are written in a single storage write.
+type TracesBatch struct {
+	// Traces are the spans of the batch
+	Traces ptrace.Traces
+	// WALEntries are the write-ahead log entries to remove once the batch is written
+	WALEntries []string
+
+	spanCount int
+	merged    bool
+}
+
+// NewTracesBatch creates a batch of the received traces, walEntry is their write-ahead log entry, empty if disabled.
+func NewTracesBatch(td ptrace.Traces, walEntry string) *TracesBatch {
+	return &TracesBatch{Traces: td, WALEntries: []string{walEntry}, spanCount: td.SpanCount()}
+}
+
+// Size returns the number of spans in the batch.
+func (b *TracesBatch) Size() int {
+	return b.spanCount
+}
+
+// Merge appends the spans of other to the batch. The received batches are read-only,
+// so the batch is copied before its first merge.
+func (b *TracesBatch) Merge(other Batch) {
+	o := other.(*TracesBatch)
+	if !b.merged {
+		td := ptrace.NewTraces()
+		b.Traces.CopyTo(td)
+		b.Traces = td
+		b.merged = true
+	}
+	resourceSpans := o.Traces.ResourceSpans()
+	for i := 0; i < resourceSpans.Len(); i++ {
+		resourceSpans.At(i).CopyTo(b.Traces.ResourceSpans().AppendEmpty())
+	}
+	b.spanCount += o.spanCount
+	b.WALEntries = append(b.WALEntries, o.WALEntries...)
+}
@@ -0,0 +1,46 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writequeue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func newTestTraces(spanNames ...string) ptrace.Traces {
+	td := ptrace.NewTraces()
+	spans := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	for _, name := range spanNames {
+		spans.AppendEmpty().SetName(name)
+	}
+	return td
+}
+
+func TestTracesBatchMergeCopiesReceivedTraces(t *testing.T) {
+	received := newTestTraces("a", "b")
+	batch := NewTracesBatch(received, "1.batch")
+	batch.Merge(NewTracesBatch(newTestTraces("c"), "2.batch"))
+	batch.Merge(NewTracesBatch(newTestTraces("d"), ""))
+
+	assert.Equal(t, 4, batch.Size())
+	assert.Equal(t, 4, batch.Traces.SpanCount())
+	assert.Equal(t, []string{"1.batch", "2.batch", ""}, batch.WALEntries)
+	// the received batch is read-only and must not be modified
+	assert.Equal(t, 2, received.SpanCount())
+}
@@ -0,0 +1,124 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writeretry
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const deadLetterFileExt = ".batch"
+
+// deadLetterQueue persists batches as files in a directory, named by the time they were persisted.
+type deadLetterQueue struct {
+	dir string
+
+	mu   sync.Mutex
+	last int64
+}
+
+func newDeadLetterQueue(dir string) (*deadLetterQueue, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create dead-letter directory: %w", err)
+	}
+	return &deadLetterQueue{dir: dir}, nil
+}
+
+// put persists a batch and returns its file name. The batch is written and synced to a temporary file first,
+// so a crash while writing doesn't leave a partial batch to be replayed, and the directory is synced once the
+// file is renamed, so the batch survives a crash once put returns, as the writer reports it as not lost.
+func (q *deadLetterQueue) put(batch []byte) (string, error) {
+	name := fmt.Sprintf("%020d%s", q.nextSequence(), deadLetterFileExt)
+
+	tmp, err := os.CreateTemp(q.dir, ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(batch); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(q.dir, name)); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := syncDir(q.dir); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// syncDir syncs a directory, persisting the files renamed into it.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// nextSequence returns the current unix time in nanoseconds, kept increasing so file names are unique and ordered.
+func (q *deadLetterQueue) nextSequence() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	seq := time.Now().UnixNano()
+	if seq <= q.last {
+		seq = q.last + 1
+	}
+	q.last = seq
+	return seq
+}
+
+// list returns the names of the persisted batches, oldest first.
+func (q *deadLetterQueue) list() ([]string, error) {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead-letter directory: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), deadLetterFileExt) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (q *deadLetterQueue) get(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(q.dir, name))
+}
+
+func (q *deadLetterQueue) remove(name string) error {
+	return os.Remove(filepath.Join(q.dir, name))
+}
@@ -0,0 +1,51 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writeretry
+
+import (
+	"fmt"
+	"time"
+)
+
+// PermanentError is returned by a write which fails however many times it's retried, e.g. since the storage
+// rejected the batch as malformed. Such a write is neither retried nor dead-lettered.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return fmt.Sprintf("permanent write error: %v", e.Err)
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// ThrottledError is returned by a write the storage asked to retry later, e.g. with a Retry-After header.
+// The write is retried after RetryAfter, unless the backoff interval is longer.
+type ThrottledError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("write throttled, retry in %s: %v", e.RetryAfter, e.Err)
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,113 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package writeretry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+var (
+	writerNameKey = tag.MustNewKey("writer")
+	resultKey     = tag.MustNewKey("result")
+
+	writtenSpans = stats.Int64(
+		"teletrace_written_spans",
+		"Number of spans written to the storage",
+		stats.UnitDimensionless,
+	)
+	writeLatency = stats.Float64(
+		"teletrace_write_latency",
+		"Latency of write attempts to the storage",
+		stats.UnitMilliseconds,
+	)
+	writeErrors = stats.Int64(
+		"teletrace_write_errors",
+		"Number of failed write attempts to the storage, including attempts that were retried",
+		stats.UnitDimensionless,
+	)
+
+	registerViewsOnce sync.Once
+	errRegisterViews  error
+)
+
+// metrics records the write metrics with OpenCensus, which are exposed by the collector's own telemetry.
+type metrics struct {
+	ctx context.Context
+}
+
+func newMetrics(name string) (*metrics, error) {
+	registerViewsOnce.Do(func() {
+		errRegisterViews = view.Register(
+			&view.View{
+				Name:        writtenSpans.Name(),
+				Description: writtenSpans.Description(),
+				Measure:     writtenSpans,
+				TagKeys:     []tag.Key{writerNameKey},
+				Aggregation: view.Sum(),
+			},
+			&view.View{
+				Name:        writeLatency.Name(),
+				Description: writeLatency.Description(),
+				Measure:     writeLatency,
+				TagKeys:     []tag.Key{writerNameKey, resultKey},
+				Aggregation: view.Distribution(5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000),
+			},
+			&view.View{
+				Name:        writeErrors.Name(),
+				Description: writeErrors.Description(),
+				Measure:     writeErrors,
+				TagKeys:     []tag.Key{writerNameKey},
+				Aggregation: view.Sum(),
+			},
+		)
+	})
+	if errRegisterViews != nil {
+		return nil, errRegisterViews
+	}
+
+	ctx, err := tag.New(context.Background(), tag.Insert(writerNameKey, name))
+	if err != nil {
+		return nil, err
+	}
+	return &metrics{ctx: ctx}, nil
+}
+
+func (m *metrics) recordAttempt(latency time.Duration, err error) {
+	result := resultSuccess
+	if err != nil {
+		result = resultFailure
+		stats.Record(m.ctx, writeErrors.M(1))
+	}
+	_ = stats.RecordWithTags(m.ctx,
+		[]tag.Mutator{tag.Upsert(resultKey, result)},
+		writeLatency.M(float64(latency)/float64(time.Millisecond)),
+	)
+}
+
+func (m *metrics) recordWritten(spanCount int) {
+	stats.Record(m.ctx, writtenSpans.M(int64(spanCount)))
+}
@@ -0,0 +1,91 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writeretry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+// Log is a log of batches to replay, e.g. the write-ahead log of an exporter.
+type Log interface {
+	Replay(ctx context.Context, write func(ctx context.Context, batch []byte) error) error
+}
+
+// StartReplay replays in the background the batches left in log by previous runs with write, and then
+// the batches dead-lettered by previous runs with writeDeadLettered. Unreadable batches are dropped.
+// writeDeadLettered should write without retries, so a failed batch is kept in the dead-letter directory.
+// The replay runs again every ReplayInterval until StopReplay is called, so a failed replay is retried once
+// the storage recovers, and batches kept in log after failed writes are written without waiting for a restart.
+func (w *Writer) StartReplay(log Log, write, writeDeadLettered func(ctx context.Context, td ptrace.Traces) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.stopReplay = cancel
+	w.replayDone = make(chan struct{})
+
+	go func() {
+		defer close(w.replayDone)
+		for {
+			w.replay(ctx, log, write, writeDeadLettered)
+			if w.cfg.ReplayInterval <= 0 {
+				return
+			}
+			if err := w.sleep(ctx, w.cfg.ReplayInterval); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// StopReplay stops the replay started by StartReplay, and waits for it to return.
+func (w *Writer) StopReplay() {
+	if w.stopReplay == nil {
+		return
+	}
+	w.stopReplay()
+	<-w.replayDone
+}
+
+// replay replays the batches of log and the dead-lettered batches, stopping at the first failure.
+func (w *Writer) replay(ctx context.Context, log Log, write, writeDeadLettered func(ctx context.Context, td ptrace.Traces) error) {
+	unmarshaler := &ptrace.ProtoUnmarshaler{}
+	err := log.Replay(ctx, func(ctx context.Context, batch []byte) error {
+		td, err := unmarshaler.UnmarshalTraces(batch)
+		if err != nil {
+			w.logger.Error("Dropping unreadable write-ahead log batch", zap.Error(err))
+			return nil
+		}
+		return write(ctx, td)
+	})
+	if err != nil {
+		w.logger.Error("Failed to replay write-ahead log batches", zap.Error(err))
+		return
+	}
+
+	err = w.Replay(ctx, func(ctx context.Context, batch []byte) error {
+		td, err := unmarshaler.UnmarshalTraces(batch)
+		if err != nil {
+			w.logger.Error("Dropping unreadable dead-lettered batch", zap.Error(err))
+			return nil
+		}
+		return writeDeadLettered(ctx, td)
+	})
+	if err != nil {
+		w.logger.Error("Failed to replay dead-lettered batches", zap.Error(err))
+	}
+}
@@ -0,0 +1,147 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writeretry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+type testLog struct {
+	batches [][]byte
+}
+
+func (l *testLog) Replay(ctx context.Context, write func(ctx context.Context, batch []byte) error) error {
+	for len(l.batches) > 0 {
+		if err := write(ctx, l.batches[0]); err != nil {
+			return err
+		}
+		l.batches = l.batches[1:]
+	}
+	return nil
+}
+
+func marshalTestTraces(t *testing.T, spanName string) []byte {
+	td := ptrace.NewTraces()
+	td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName(spanName)
+	batch, err := (&ptrace.ProtoMarshaler{}).MarshalTraces(td)
+	assert.NoError(t, err)
+	return batch
+}
+
+func spanName(td ptrace.Traces) string {
+	return td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Name()
+}
+
+func TestStartReplayWritesLoggedThenDeadLetteredBatches(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Enabled = false
+	cfg.DeadLetterDirectory = t.TempDir()
+	cfg.ReplayInterval = 0
+	w, _ := newTestWriter(t, cfg)
+
+	err := w.Write(context.Background(), 1,
+		func(ctx context.Context) error { return errWrite },
+		func() ([]byte, error) { return marshalTestTraces(t, "dead-lettered"), nil },
+	)
+	assert.NoError(t, err)
+	log := &testLog{batches: [][]byte{marshalTestTraces(t, "logged"), []byte("unreadable")}}
+
+	var written []string
+	write := func(ctx context.Context, td ptrace.Traces) error {
+		written = append(written, spanName(td))
+		return nil
+	}
+	w.StartReplay(log, write, write)
+	<-w.replayDone
+	w.StopReplay()
+
+	assert.Equal(t, []string{"logged", "dead-lettered"}, written)
+	assert.Empty(t, log.batches)
+	names, err := w.deadLetter.list()
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+// stopAfterWaits makes the replay of w stop once it waited n times.
+func stopAfterWaits(w *Writer, n int) {
+	sleep := w.sleep
+	waits := 0
+	w.sleep = func(ctx context.Context, d time.Duration) error {
+		if err := sleep(ctx, d); err != nil {
+			return err
+		}
+		waits++
+		if waits == n {
+			return context.Canceled
+		}
+		return nil
+	}
+}
+
+func TestStartReplayRetriesFailedReplays(t *testing.T) {
+	cfg := NewDefaultConfig()
+	w, waits := newTestWriter(t, cfg)
+	stopAfterWaits(w, 3)
+	log := &testLog{batches: [][]byte{marshalTestTraces(t, "first"), marshalTestTraces(t, "second")}}
+
+	attempts := 0
+	var written []string
+	write := func(ctx context.Context, td ptrace.Traces) error {
+		// the storage is unavailable for the first two replays
+		attempts++
+		if attempts <= 2 {
+			return errWrite
+		}
+		written = append(written, spanName(td))
+		return nil
+	}
+	w.StartReplay(log, write, write)
+	<-w.replayDone
+	w.StopReplay()
+
+	assert.Equal(t, []string{"first", "second"}, written)
+	assert.Equal(t, []time.Duration{cfg.ReplayInterval, cfg.ReplayInterval, cfg.ReplayInterval}, *waits)
+}
+
+func TestStartReplayWritesBatchesLoggedAfterStart(t *testing.T) {
+	cfg := NewDefaultConfig()
+	w, _ := newTestWriter(t, cfg)
+	stopAfterWaits(w, 2)
+	log := &testLog{}
+
+	var written []string
+	write := func(ctx context.Context, td ptrace.Traces) error {
+		written = append(written, spanName(td))
+		return nil
+	}
+	// a queued write fails after the first replay, and its batch is kept in the log
+	sleep := w.sleep
+	w.sleep = func(ctx context.Context, d time.Duration) error {
+		log.batches = append(log.batches, marshalTestTraces(t, "failed"))
+		return sleep(ctx, d)
+	}
+	w.StartReplay(log, write, write)
+	<-w.replayDone
+	w.StopReplay()
+
+	assert.Equal(t, []string{"failed"}, written)
+}
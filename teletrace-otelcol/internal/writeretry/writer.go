@@ -0,0 +1,265 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writeretry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/teletrace/teletrace/circuitbreaker"
+	"go.uber.org/zap"
+)
+
+// Config defines how failed writes are retried, and where batches are kept once retries are exhausted.
+type Config struct {
+	// Enabled enables retrying failed writes
+	Enabled bool `mapstructure:"enabled"`
+	// InitialInterval is the time to wait after the first failure, doubled after each following failure
+	InitialInterval time.Duration `mapstructure:"initial_interval"`
+	// MaxInterval is the upper bound of the time to wait between retries
+	MaxInterval time.Duration `mapstructure:"max_interval"`
+	// MaxElapsedTime is the maximum time spent retrying a batch
+	MaxElapsedTime time.Duration `mapstructure:"max_elapsed_time"`
+	// DeadLetterDirectory is where batches are persisted once retries are exhausted, disabled if empty.
+	// Dead-lettered batches are replayed when the exporter starts, and then every ReplayInterval.
+	DeadLetterDirectory string `mapstructure:"dead_letter_directory"`
+	// ReplayInterval is the time to wait between replays of the write-ahead log and dead-lettered batches,
+	// zero replays them only on start
+	ReplayInterval time.Duration `mapstructure:"replay_interval"`
+	// CircuitBreaker fast-fails writes while the storage keeps failing
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+}
+
+// CircuitBreakerConfig defines when writes fast-fail, see the circuitbreaker module.
+type CircuitBreakerConfig struct {
+	// Enabled enables the circuit breaker
+	Enabled bool `mapstructure:"enabled"`
+	// FailureThreshold is the number of consecutive failed write attempts that opens the circuit breaker
+	FailureThreshold int `mapstructure:"failure_threshold"`
+	// OpenDuration is how long writes fast-fail before a single trial write is let through
+	OpenDuration time.Duration `mapstructure:"open_duration"`
+}
+
+// NewDefaultConfig returns the default retry configuration, with the dead-letter directory disabled.
+func NewDefaultConfig() Config {
+	return Config{
+		Enabled:         true,
+		InitialInterval: time.Second,
+		MaxInterval:     30 * time.Second,
+		MaxElapsedTime:  5 * time.Minute,
+		ReplayInterval:  time.Minute,
+		CircuitBreaker: CircuitBreakerConfig{
+			FailureThreshold: 5,
+			OpenDuration:     30 * time.Second,
+		},
+	}
+}
+
+// Validate validates the retry configuration.
+func (cfg *Config) Validate() error {
+	if cfg.CircuitBreaker.Enabled && (cfg.CircuitBreaker.FailureThreshold <= 0 || cfg.CircuitBreaker.OpenDuration <= 0) {
+		return fmt.Errorf("circuit_breaker failure_threshold and open_duration must be positive")
+	}
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.InitialInterval <= 0 || cfg.MaxInterval <= 0 {
+		return fmt.Errorf("retry intervals must be positive")
+	}
+	if cfg.MaxInterval < cfg.InitialInterval {
+		return fmt.Errorf("retry max_interval must not be smaller than initial_interval")
+	}
+	if cfg.ReplayInterval < 0 {
+		return fmt.Errorf("retry replay_interval must not be negative")
+	}
+	return nil
+}
+
+// Writer retries failed writes with exponential backoff, and dead-letters the batches it gave up on.
+type Writer struct {
+	logger     *zap.Logger
+	cfg        Config
+	deadLetter *deadLetterQueue
+	breaker    *circuitbreaker.Breaker
+	sleep      func(ctx context.Context, d time.Duration) error
+	now        func() time.Time
+	metrics    *metrics
+
+	stopReplay context.CancelFunc
+	replayDone chan struct{}
+}
+
+// NewWriter creates a Writer, creating the dead-letter directory if configured.
+// The name identifies the writer in the exported metrics, e.g. the exporter name.
+func NewWriter(logger *zap.Logger, name string, cfg Config) (*Writer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	m, err := newMetrics(name)
+	if err != nil {
+		return nil, err
+	}
+	w := &Writer{logger: logger, cfg: cfg, sleep: sleep, now: time.Now, metrics: m}
+	if cfg.CircuitBreaker.Enabled {
+		w.breaker = circuitbreaker.New(logger, circuitbreaker.Config{
+			FailureThreshold: cfg.CircuitBreaker.FailureThreshold,
+			OpenDuration:     cfg.CircuitBreaker.OpenDuration,
+		}, isPermanent)
+	}
+	if cfg.DeadLetterDirectory != "" {
+		dlq, err := newDeadLetterQueue(cfg.DeadLetterDirectory)
+		if err != nil {
+			return nil, err
+		}
+		w.deadLetter = dlq
+	}
+	return w, nil
+}
+
+// Write calls write until it succeeds or retries are exhausted, spanCount is the number of spans in the batch.
+// Retries stop early if ctx is done, so ctx must not carry a deadline shorter than MaxElapsedTime,
+// the exporters disable the exporterhelper timeout for this reason.
+// Exhausted batches are marshaled and persisted to the dead-letter directory if configured, in which case
+// no error is returned since the batch isn't lost. A PermanentError is returned as is, without retries.
+// While the circuit breaker is open, attempts fail with circuitbreaker.ErrOpen without calling write,
+// and the batch is dead-lettered right away if configured, instead of waiting for the storage to recover.
+func (w *Writer) Write(
+	ctx context.Context, spanCount int, write func(ctx context.Context) error, marshal func() ([]byte, error),
+) error {
+	err := w.retry(ctx, write)
+	if err == nil {
+		w.metrics.recordWritten(spanCount)
+		return nil
+	}
+	if w.deadLetter == nil || isPermanent(err) {
+		return err
+	}
+
+	batch, marshalErr := marshal()
+	if marshalErr != nil {
+		return fmt.Errorf("failed to marshal batch for the dead-letter queue: %v, write error: %w", marshalErr, err)
+	}
+	name, dlqErr := w.deadLetter.put(batch)
+	if dlqErr != nil {
+		return fmt.Errorf("failed to persist batch to the dead-letter queue: %v, write error: %w", dlqErr, err)
+	}
+	w.logger.Warn("Write retries exhausted, batch moved to the dead-letter queue",
+		zap.String("file", name), zap.Error(err))
+	return nil
+}
+
+func (w *Writer) retry(ctx context.Context, write func(ctx context.Context) error) error {
+	start := w.now()
+	interval := w.cfg.InitialInterval
+	for {
+		err := w.attempt(ctx, func(ctx context.Context) error {
+			attemptStart := w.now()
+			err := write(ctx)
+			w.metrics.recordAttempt(w.now().Sub(attemptStart), err)
+			return err
+		})
+		if err == nil || !w.cfg.Enabled || isPermanent(err) {
+			return err
+		}
+		if errors.Is(err, circuitbreaker.ErrOpen) && w.deadLetter != nil {
+			return err
+		}
+
+		wait := jitter(interval)
+		var throttled *ThrottledError
+		if errors.As(err, &throttled) && throttled.RetryAfter > wait {
+			wait = throttled.RetryAfter
+		}
+		if w.cfg.MaxElapsedTime > 0 && w.now().Sub(start)+wait > w.cfg.MaxElapsedTime {
+			return err
+		}
+		w.logger.Debug("Write failed, retrying", zap.Duration("wait", wait), zap.Error(err))
+		if sleepErr := w.sleep(ctx, wait); sleepErr != nil {
+			return err
+		}
+
+		interval *= 2
+		if interval > w.cfg.MaxInterval {
+			interval = w.cfg.MaxInterval
+		}
+	}
+}
+
+// attempt calls write through the circuit breaker if enabled.
+func (w *Writer) attempt(ctx context.Context, write func(ctx context.Context) error) error {
+	if w.breaker == nil {
+		return write(ctx)
+	}
+	return w.breaker.Do(ctx, func() error { return write(ctx) })
+}
+
+// isPermanent returns whether err is a PermanentError, a rejected batch, which tells nothing about the storage health.
+func isPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// Replay writes the dead-lettered batches in the order they were persisted, removing each one once written.
+// It stops at the first batch that fails to be written, keeping it and the batches after it, except for batches
+// failing with a PermanentError, which are dropped. Batches are written through the circuit breaker if enabled,
+// so the replay stops without calling write while it's open.
+func (w *Writer) Replay(ctx context.Context, write func(ctx context.Context, batch []byte) error) error {
+	if w.deadLetter == nil {
+		return nil
+	}
+	names, err := w.deadLetter.list()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		batch, err := w.deadLetter.get(name)
+		if err != nil {
+			return err
+		}
+		err = w.attempt(ctx, func(ctx context.Context) error { return write(ctx, batch) })
+		if err != nil {
+			if !isPermanent(err) {
+				return fmt.Errorf("failed to replay dead-lettered batch %s: %w", name, err)
+			}
+			w.logger.Error("Dropping rejected dead-lettered batch", zap.String("file", name), zap.Error(err))
+		}
+		if err := w.deadLetter.remove(name); err != nil {
+			return err
+		}
+		w.logger.Info("Replayed dead-lettered batch", zap.String("file", name))
+	}
+	return nil
+}
+
+// jitter randomizes d by up to 50% in either direction, so retries of concurrent writers spread out.
+func jitter(d time.Duration) time.Duration {
+	return time.Duration(float64(d) * (0.5 + rand.Float64()))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,303 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writeretry
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teletrace/teletrace/circuitbreaker"
+	"go.opencensus.io/stats/view"
+	"go.uber.org/zap"
+)
+
+var errWrite = errors.New("connection refused")
+
+func newTestWriter(t *testing.T, cfg Config) (*Writer, *[]time.Duration) {
+	w, err := NewWriter(zap.NewNop(), "test", cfg)
+	assert.NoError(t, err)
+	// sleeping advances a fake clock, so elapsed time is measured without waiting
+	clock := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+	w.now = func() time.Time { return clock }
+	var waits []time.Duration
+	w.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		clock = clock.Add(d)
+		return ctx.Err()
+	}
+	return w, &waits
+}
+
+func retrieveSum(t *testing.T, name string) int64 {
+	rows, err := view.RetrieveData(name)
+	assert.NoError(t, err)
+	for _, row := range rows {
+		for _, tag := range row.Tags {
+			if tag.Key == writerNameKey && tag.Value == "test" {
+				return int64(row.Data.(*view.SumData).Value)
+			}
+		}
+	}
+	return 0
+}
+
+func TestWriteRetriesWithBackoff(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.MaxInterval = 4 * time.Second
+	cfg.MaxElapsedTime = 0
+	w, waits := newTestWriter(t, cfg)
+	writtenBefore := retrieveSum(t, writtenSpans.Name())
+	errorsBefore := retrieveSum(t, writeErrors.Name())
+
+	calls := 0
+	err := w.Write(context.Background(), 10, func(ctx context.Context) error {
+		calls++
+		if calls < 6 {
+			return errWrite
+		}
+		return nil
+	}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 6, calls)
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second, 4 * time.Second}
+	assert.Len(t, *waits, len(expected))
+	for i, wait := range *waits {
+		assert.GreaterOrEqual(t, wait, expected[i]/2)
+		assert.Less(t, wait, expected[i]*3/2)
+	}
+	assert.Equal(t, writtenBefore+10, retrieveSum(t, writtenSpans.Name()))
+	assert.Equal(t, errorsBefore+5, retrieveSum(t, writeErrors.Name()))
+}
+
+func TestWriteWithoutDeadLetterReturnsError(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Enabled = false
+	w, _ := newTestWriter(t, cfg)
+
+	err := w.Write(context.Background(), 10, func(ctx context.Context) error { return errWrite }, nil)
+	assert.ErrorIs(t, err, errWrite)
+}
+
+func TestWriteDoesNotRetryPermanentErrors(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.DeadLetterDirectory = t.TempDir()
+	w, waits := newTestWriter(t, cfg)
+
+	calls := 0
+	err := w.Write(context.Background(), 10, func(ctx context.Context) error {
+		calls++
+		return &PermanentError{Err: errWrite}
+	}, func() ([]byte, error) { return []byte("batch"), nil })
+
+	assert.ErrorIs(t, err, errWrite)
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, *waits)
+	entries, err := os.ReadDir(cfg.DeadLetterDirectory)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestWriteWaitsForThrottledRetries(t *testing.T) {
+	cfg := NewDefaultConfig()
+	w, waits := newTestWriter(t, cfg)
+
+	calls := 0
+	err := w.Write(context.Background(), 10, func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return &ThrottledError{Err: errWrite, RetryAfter: 20 * time.Second}
+		}
+		return nil
+	}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{20 * time.Second}, *waits)
+}
+
+func TestExhaustedBatchesAreDeadLetteredAndReplayed(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Enabled = false
+	cfg.DeadLetterDirectory = t.TempDir()
+	w, _ := newTestWriter(t, cfg)
+
+	for _, batch := range []string{"first", "second", "third"} {
+		batch := batch
+		err := w.Write(context.Background(), 10,
+			func(ctx context.Context) error { return errWrite },
+			func() ([]byte, error) { return []byte(batch), nil },
+		)
+		assert.NoError(t, err)
+	}
+
+	// replay stops at the first failure, keeping the remaining batches
+	var replayed []string
+	err := w.Replay(context.Background(), func(ctx context.Context, batch []byte) error {
+		if string(batch) == "second" && len(replayed) == 1 {
+			return errWrite
+		}
+		replayed = append(replayed, string(batch))
+		return nil
+	})
+	assert.ErrorIs(t, err, errWrite)
+	assert.Equal(t, []string{"first"}, replayed)
+
+	err = w.Replay(context.Background(), func(ctx context.Context, batch []byte) error {
+		replayed = append(replayed, string(batch))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first", "second", "third"}, replayed)
+
+	entries, err := os.ReadDir(cfg.DeadLetterDirectory)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestWriteStopsRetryingWhenContextIsDone(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.DeadLetterDirectory = t.TempDir()
+	w, _ := newTestWriter(t, cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	err := w.Write(ctx, 10,
+		func(ctx context.Context) error { calls++; return errWrite },
+		func() ([]byte, error) { return []byte("batch"), nil },
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	names, err := w.deadLetter.list()
+	assert.NoError(t, err)
+	assert.Len(t, names, 1)
+}
+
+func TestWriteKeepsRetryingPastTheExporterTimeout(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.DeadLetterDirectory = t.TempDir()
+	w, waits := newTestWriter(t, cfg)
+	start := w.now()
+
+	// the storage recovers after the 5s default timeout of exporterhelper
+	err := w.Write(context.Background(), 10,
+		func(ctx context.Context) error {
+			if w.now().Sub(start) < 8*time.Second {
+				return errWrite
+			}
+			return nil
+		},
+		func() ([]byte, error) { return []byte("batch"), nil },
+	)
+	assert.NoError(t, err)
+	assert.Greater(t, w.now().Sub(start), 5*time.Second)
+	assert.Greater(t, len(*waits), 1)
+
+	names, err := w.deadLetter.list()
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestWriteDeadLettersAfterMaxElapsedTime(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.MaxElapsedTime = time.Minute
+	cfg.DeadLetterDirectory = t.TempDir()
+	w, _ := newTestWriter(t, cfg)
+	start := w.now()
+
+	err := w.Write(context.Background(), 10,
+		func(ctx context.Context) error { return errWrite },
+		func() ([]byte, error) { return []byte("batch"), nil },
+	)
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, w.now().Sub(start), cfg.MaxElapsedTime)
+
+	names, err := w.deadLetter.list()
+	assert.NoError(t, err)
+	assert.Len(t, names, 1)
+}
+
+func TestOpenCircuitBreakerDeadLettersWithoutWriting(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.MaxElapsedTime = 0
+	cfg.DeadLetterDirectory = t.TempDir()
+	cfg.CircuitBreaker.Enabled = true
+	w, waits := newTestWriter(t, cfg)
+
+	calls := 0
+	write := func(ctx context.Context) error { calls++; return errWrite }
+	marshal := func() ([]byte, error) { return []byte("batch"), nil }
+
+	// the circuit breaker opens after FailureThreshold attempts, which dead-letters the batch
+	assert.NoError(t, w.Write(context.Background(), 10, write, marshal))
+	assert.Equal(t, cfg.CircuitBreaker.FailureThreshold, calls)
+	assert.Len(t, *waits, cfg.CircuitBreaker.FailureThreshold)
+
+	// while it's open, batches are dead-lettered without waiting nor calling the storage
+	assert.NoError(t, w.Write(context.Background(), 10, write, marshal))
+	assert.Equal(t, cfg.CircuitBreaker.FailureThreshold, calls)
+	assert.Len(t, *waits, cfg.CircuitBreaker.FailureThreshold)
+
+	err := w.Replay(context.Background(), func(ctx context.Context, batch []byte) error { calls++; return nil })
+	assert.ErrorIs(t, err, circuitbreaker.ErrOpen)
+	assert.Equal(t, cfg.CircuitBreaker.FailureThreshold, calls)
+
+	names, err := w.deadLetter.list()
+	assert.NoError(t, err)
+	assert.Len(t, names, 2)
+}
+
+func TestOpenCircuitBreakerWithoutDeadLetterKeepsRetrying(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.MaxElapsedTime = 0
+	cfg.CircuitBreaker.Enabled = true
+	cfg.CircuitBreaker.FailureThreshold = 1
+	cfg.CircuitBreaker.OpenDuration = time.Hour
+	w, waits := newTestWriter(t, cfg)
+	w.breaker.Now = w.now
+
+	// the storage is only called again once the circuit breaker lets a trial write through
+	calls := 0
+	err := w.Write(context.Background(), 10, func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return errWrite
+		}
+		return nil
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Greater(t, len(*waits), 1)
+}
+
+func TestValidate(t *testing.T) {
+	cfg := NewDefaultConfig()
+	assert.NoError(t, cfg.Validate())
+	cfg.MaxInterval = cfg.InitialInterval / 2
+	assert.Error(t, cfg.Validate())
+
+	cfg = NewDefaultConfig()
+	cfg.Enabled = false
+	cfg.CircuitBreaker.Enabled = true
+	cfg.CircuitBreaker.FailureThreshold = 0
+	assert.Error(t, cfg.Validate())
+}
@@ -23,8 +23,16 @@ import (
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/attributesprocessor"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/transformprocessor"
+	"github.com/teletrace/teletrace/teletrace-otelcol/exporter/badgerexporter"
+	"github.com/teletrace/teletrace/teletrace-otelcol/exporter/cassandraexporter"
 	"github.com/teletrace/teletrace/teletrace-otelcol/exporter/elasticsearchexporter"
+	"github.com/teletrace/teletrace/teletrace-otelcol/exporter/parquetexporter"
 	"github.com/teletrace/teletrace/teletrace-otelcol/exporter/sqliteexporter"
+	"github.com/teletrace/teletrace/teletrace-otelcol/processor/geoipprocessor"
+	"github.com/teletrace/teletrace/teletrace-otelcol/processor/k8smetadataprocessor"
+	"github.com/teletrace/teletrace/teletrace-otelcol/processor/ratelimitprocessor"
+	"github.com/teletrace/teletrace/teletrace-otelcol/processor/routetemplateprocessor"
+	"github.com/teletrace/teletrace/teletrace-otelcol/processor/servicenameprocessor"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/processor/batchprocessor"
 	"go.opentelemetry.io/collector/receiver/otlpreceiver"
@@ -42,14 +50,22 @@ func components() (component.Factories, error) {
 		batchprocessor.NewFactory(),
 		attributesprocessor.NewFactory(),
 		transformprocessor.NewFactory(),
+		geoipprocessor.NewFactory(),
+		k8smetadataprocessor.NewFactory(),
+		ratelimitprocessor.NewFactory(),
+		servicenameprocessor.NewFactory(),
+		routetemplateprocessor.NewFactory(),
 	)
 	if err != nil {
 		return component.Factories{}, fmt.Errorf("failed to make processor factory map: %w", err)
 	}
 
 	exporters, err := component.MakeExporterFactoryMap(
+		badgerexporter.NewFactory(),
+		cassandraexporter.NewFactory(),
 		elasticsearchexporter.NewFactory(),
 		opensearchexporter.NewFactory(),
+		parquetexporter.NewFactory(),
 		sqliteexporter.NewFactory(),
 	)
 	if err != nil {
@@ -0,0 +1,59 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package geoipprocessor
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/collector/config"
+)
+
+// Config defines configuration for the GeoIP processor.
+type Config struct {
+	config.ProcessorSettings `mapstructure:",squash"`
+
+	// CityDatabase is the path of a MaxMind GeoIP2 or GeoLite2 City database, used for the country and city attributes
+	CityDatabase string `mapstructure:"city_database"`
+
+	// ASNDatabase is the path of a MaxMind GeoIP2 or GeoLite2 ASN database, used for the autonomous system attributes
+	ASNDatabase string `mapstructure:"asn_database"`
+
+	// SourceAttributes are the span attributes holding the client IP, the first one present is used.
+	// Defaults to client.address
+	SourceAttributes []string `mapstructure:"source_attributes"`
+
+	// DropSourceAttribute removes all the source attributes once enriched, so the client IP isn't stored.
+	DropSourceAttribute bool `mapstructure:"drop_source_attribute"`
+}
+
+var (
+	errConfigNoDatabase         = errors.New("at least one of city_database and asn_database must be specified")
+	errConfigNoSourceAttributes = errors.New("source_attributes must not be empty")
+)
+
+// Validate validates the GeoIP processor configuration.
+func (cfg *Config) Validate() error {
+	if cfg.CityDatabase == "" && cfg.ASNDatabase == "" {
+		return errConfigNoDatabase
+	}
+
+	if len(cfg.SourceAttributes) == 0 {
+		return errConfigNoSourceAttributes
+	}
+
+	return nil
+}
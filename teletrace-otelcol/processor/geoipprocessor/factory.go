@@ -0,0 +1,66 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package geoipprocessor
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+const (
+	typeStr   = "geoip"
+	stability = component.StabilityLevelInDevelopment
+)
+
+func NewFactory() component.ProcessorFactory {
+	return component.NewProcessorFactory(
+		typeStr,
+		createDefaultConfig,
+		component.WithTracesProcessor(createTracesProcessor, stability),
+	)
+}
+
+func createDefaultConfig() component.ProcessorConfig {
+	return &Config{
+		ProcessorSettings: config.NewProcessorSettings(component.NewID(typeStr)),
+		SourceAttributes:  []string{"client.address"},
+	}
+}
+
+func createTracesProcessor(
+	ctx context.Context,
+	set component.ProcessorCreateSettings,
+	cfg component.ProcessorConfig,
+	nextConsumer consumer.Traces,
+) (component.TracesProcessor, error) {
+	processor, err := newGeoIPProcessor(set.Logger, cfg.(*Config))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create geoip processor: %w", err)
+	}
+
+	return processorhelper.NewTracesProcessor(
+		ctx, set, cfg, nextConsumer,
+		processor.processTraces,
+		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: true}),
+		processorhelper.WithShutdown(processor.Shutdown),
+	)
+}
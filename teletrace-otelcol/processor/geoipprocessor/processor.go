@@ -0,0 +1,176 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package geoipprocessor
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// Attributes added to the enriched spans
+const (
+	CountryISOCodeAttribute     = "client.geo.country.iso_code"
+	CountryNameAttribute        = "client.geo.country.name"
+	CityNameAttribute           = "client.geo.city.name"
+	ASNumberAttribute           = "client.as.number"
+	ASOrganizationNameAttribute = "client.as.organization.name"
+)
+
+// names are looked up in English, which every MaxMind database includes
+const namesLanguage = "en"
+
+// cityDatabase and asnDatabase are implemented by *geoip2.Reader, depending on the opened database type.
+type cityDatabase interface {
+	City(ip net.IP) (*geoip2.City, error)
+	Close() error
+}
+
+type asnDatabase interface {
+	ASN(ip net.IP) (*geoip2.ASN, error)
+	Close() error
+}
+
+type geoIPProcessor struct {
+	logger *zap.Logger
+	cfg    *Config
+	city   cityDatabase
+	asn    asnDatabase
+}
+
+func newGeoIPProcessor(logger *zap.Logger, cfg *Config) (*geoIPProcessor, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := &geoIPProcessor{logger: logger, cfg: cfg}
+	if cfg.CityDatabase != "" {
+		city, err := geoip2.Open(cfg.CityDatabase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open city database: %+v", err)
+		}
+		p.city = city
+	}
+	if cfg.ASNDatabase != "" {
+		asn, err := geoip2.Open(cfg.ASNDatabase)
+		if err != nil {
+			return nil, multierr.Append(fmt.Errorf("failed to open ASN database: %+v", err), p.Shutdown(context.Background()))
+		}
+		p.asn = asn
+	}
+	return p, nil
+}
+
+func (p *geoIPProcessor) Shutdown(_ context.Context) error {
+	var err error
+	if p.city != nil {
+		err = multierr.Append(err, p.city.Close())
+	}
+	if p.asn != nil {
+		err = multierr.Append(err, p.asn.Close())
+	}
+	return err
+}
+
+func (p *geoIPProcessor) processTraces(_ context.Context, td ptrace.Traces) (ptrace.Traces, error) {
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		scopeSpans := td.ResourceSpans().At(i).ScopeSpans()
+		for j := 0; j < scopeSpans.Len(); j++ {
+			spans := scopeSpans.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				p.enrich(spans.At(k).Attributes())
+			}
+		}
+	}
+	return td, nil
+}
+
+// enrich adds the geo and autonomous system attributes of the first client IP attribute present.
+// When DropSourceAttribute is set, every configured source attribute is removed, not only the enriched one,
+// since all of them hold the client IP.
+func (p *geoIPProcessor) enrich(attributes pcommon.Map) {
+	for _, key := range p.cfg.SourceAttributes {
+		value, ok := attributes.Get(key)
+		if !ok {
+			continue
+		}
+
+		if ip := parseIP(value.AsString()); ip != nil {
+			p.putCityAttributes(attributes, ip)
+			p.putASNAttributes(attributes, ip)
+		}
+		break
+	}
+
+	if p.cfg.DropSourceAttribute {
+		for _, key := range p.cfg.SourceAttributes {
+			attributes.Remove(key)
+		}
+	}
+}
+
+func (p *geoIPProcessor) putCityAttributes(attributes pcommon.Map, ip net.IP) {
+	if p.city == nil {
+		return
+	}
+	city, err := p.city.City(ip)
+	if err != nil {
+		p.logger.Debug("Failed to look up client IP city", zap.Error(err))
+		return
+	}
+	putNonEmptyStr(attributes, CountryISOCodeAttribute, city.Country.IsoCode)
+	putNonEmptyStr(attributes, CountryNameAttribute, city.Country.Names[namesLanguage])
+	putNonEmptyStr(attributes, CityNameAttribute, city.City.Names[namesLanguage])
+}
+
+func (p *geoIPProcessor) putASNAttributes(attributes pcommon.Map, ip net.IP) {
+	if p.asn == nil {
+		return
+	}
+	asn, err := p.asn.ASN(ip)
+	if err != nil {
+		p.logger.Debug("Failed to look up client IP autonomous system", zap.Error(err))
+		return
+	}
+	if asn.AutonomousSystemNumber != 0 {
+		attributes.PutInt(ASNumberAttribute, int64(asn.AutonomousSystemNumber))
+	}
+	putNonEmptyStr(attributes, ASOrganizationNameAttribute, asn.AutonomousSystemOrganization)
+}
+
+// parseIP parses an IP address, which may include a port.
+func parseIP(address string) net.IP {
+	if ip := net.ParseIP(address); ip != nil {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return net.ParseIP(host)
+	}
+	return nil
+}
+
+func putNonEmptyStr(attributes pcommon.Map, key string, value string) {
+	if value != "" {
+		attributes.PutStr(key, value)
+	}
+}
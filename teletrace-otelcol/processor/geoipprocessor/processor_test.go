@@ -0,0 +1,135 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package geoipprocessor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/oschwald/geoip2-golang"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+type fakeDatabase struct {
+	cities map[string]*geoip2.City
+	asns   map[string]*geoip2.ASN
+}
+
+func (db *fakeDatabase) City(ip net.IP) (*geoip2.City, error) {
+	if city, ok := db.cities[ip.String()]; ok {
+		return city, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (db *fakeDatabase) ASN(ip net.IP) (*geoip2.ASN, error) {
+	if asn, ok := db.asns[ip.String()]; ok {
+		return asn, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (db *fakeDatabase) Close() error {
+	return nil
+}
+
+func newTestProcessor(cfg *Config) *geoIPProcessor {
+	city := &geoip2.City{}
+	city.Country.IsoCode = "FR"
+	city.Country.Names = map[string]string{"en": "France", "fr": "France"}
+	city.City.Names = map[string]string{"en": "Paris"}
+	db := &fakeDatabase{
+		cities: map[string]*geoip2.City{"81.2.69.142": city},
+		asns: map[string]*geoip2.ASN{"81.2.69.142": {
+			AutonomousSystemNumber:       3215,
+			AutonomousSystemOrganization: "Orange",
+		}},
+	}
+	return &geoIPProcessor{logger: zap.NewNop(), cfg: cfg, city: db, asn: db}
+}
+
+func newTestTraces(attributes map[string]any) (ptrace.Traces, pcommon.Map) {
+	traces := ptrace.NewTraces()
+	span := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.Attributes().FromRaw(attributes)
+	return traces, span.Attributes()
+}
+
+func TestProcessTracesEnrichesClientAddress(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	p := newTestProcessor(cfg)
+	traces, attributes := newTestTraces(map[string]any{"client.address": "81.2.69.142"})
+
+	_, err := p.processTraces(context.Background(), traces)
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]any{
+		"client.address":              "81.2.69.142",
+		"client.geo.country.iso_code": "FR",
+		"client.geo.country.name":     "France",
+		"client.geo.city.name":        "Paris",
+		"client.as.number":            int64(3215),
+		"client.as.organization.name": "Orange",
+	}, attributes.AsRaw())
+}
+
+func TestProcessTracesDropsSourceAttribute(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.SourceAttributes = []string{"client.address", "net.sock.peer.addr"}
+	cfg.DropSourceAttribute = true
+	p := newTestProcessor(cfg)
+	traces, attributes := newTestTraces(map[string]any{
+		"client.address":     "81.2.69.142",
+		"net.sock.peer.addr": "81.2.69.142:52341",
+	})
+
+	_, err := p.processTraces(context.Background(), traces)
+	assert.NoError(t, err)
+
+	_, ok := attributes.Get("client.address")
+	assert.False(t, ok)
+	_, ok = attributes.Get("net.sock.peer.addr")
+	assert.False(t, ok)
+	country, _ := attributes.Get(CountryISOCodeAttribute)
+	assert.Equal(t, "FR", country.Str())
+}
+
+func TestProcessTracesUnknownAddress(t *testing.T) {
+	p := newTestProcessor(createDefaultConfig().(*Config))
+	traces, attributes := newTestTraces(map[string]any{"client.address": "10.0.0.1", "http.method": "GET"})
+
+	_, err := p.processTraces(context.Background(), traces)
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]any{"client.address": "10.0.0.1", "http.method": "GET"}, attributes.AsRaw())
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	assert.ErrorIs(t, cfg.Validate(), errConfigNoDatabase)
+
+	cfg.ASNDatabase = "GeoLite2-ASN.mmdb"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.SourceAttributes = nil
+	assert.ErrorIs(t, cfg.Validate(), errConfigNoSourceAttributes)
+}
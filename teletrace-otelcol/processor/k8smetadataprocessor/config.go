@@ -0,0 +1,78 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package k8smetadataprocessor
+
+import (
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/collector/config"
+)
+
+// The ways of authenticating to the Kubernetes API
+const (
+	// AuthTypeServiceAccount uses the service account of the pod the collector runs in
+	AuthTypeServiceAccount = "serviceAccount"
+	// AuthTypeKubeConfig uses the kubeconfig file of $KUBECONFIG or ~/.kube/config, e.g. when running outside the cluster
+	AuthTypeKubeConfig = "kubeConfig"
+)
+
+// Config defines configuration for the Kubernetes metadata processor.
+type Config struct {
+	config.ProcessorSettings `mapstructure:",squash"`
+
+	// AuthType is how the Kubernetes API is authenticated to, either serviceAccount or kubeConfig
+	AuthType string `mapstructure:"auth_type"`
+
+	// Node restricts the watched pods to the pods of a node, e.g. when the collector runs as a DaemonSet agent
+	Node string `mapstructure:"node"`
+
+	// Namespace restricts the watched pods to the pods of a namespace, all namespaces are watched if empty
+	Namespace string `mapstructure:"namespace"`
+
+	// PodIPAttributes are the resource attributes holding the pod IP, the first one present is used.
+	// Defaults to k8s.pod.ip
+	PodIPAttributes []string `mapstructure:"pod_ip_attributes"`
+
+	// UseConnectionIP resolves the pod by the IP of the connection the spans were received on, if the resource
+	// attributes don't identify it, for pods sending their spans directly to the collector
+	UseConnectionIP bool `mapstructure:"use_connection_ip"`
+
+	// PodLabels are the pod labels added as k8s.pod.labels.<label> attributes
+	PodLabels []string `mapstructure:"pod_labels"`
+
+	// ResyncPeriod is the period of the full resync of the cached pods
+	ResyncPeriod time.Duration `mapstructure:"resync_period"`
+}
+
+var (
+	errConfigAuthType     = errors.New("auth_type must be either serviceAccount or kubeConfig")
+	errConfigResyncPeriod = errors.New("resync_period must not be negative")
+)
+
+// Validate validates the Kubernetes metadata processor configuration.
+func (cfg *Config) Validate() error {
+	if cfg.AuthType != AuthTypeServiceAccount && cfg.AuthType != AuthTypeKubeConfig {
+		return errConfigAuthType
+	}
+
+	if cfg.ResyncPeriod < 0 {
+		return errConfigResyncPeriod
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package k8smetadataprocessor
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// newClient returns a Kubernetes API client authenticated as configured.
+func newClient(cfg *Config) (kubernetes.Interface, error) {
+	var restConfig *rest.Config
+	var err error
+	switch cfg.AuthType {
+	case AuthTypeServiceAccount:
+		restConfig, err = rest.InClusterConfig()
+	case AuthTypeKubeConfig:
+		restConfig, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{},
+		).ClientConfig()
+	default:
+		return nil, errConfigAuthType
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s Kubernetes config: %w", cfg.AuthType, err)
+	}
+	return kubernetes.NewForConfig(restConfig)
+}
+
+// newPodInformer returns an informer watching the pods of the configured node and namespace,
+// keeping them in the store.
+func newPodInformer(client kubernetes.Interface, cfg *Config, store *podStore) cache.SharedIndexInformer {
+	options := []informers.SharedInformerOption{informers.WithNamespace(cfg.Namespace)}
+	if cfg.Node != "" {
+		options = append(options, informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+			o.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", cfg.Node).String()
+		}))
+	}
+	informer := informers.NewSharedInformerFactoryWithOptions(client, cfg.ResyncPeriod, options...).Core().V1().Pods().Informer()
+
+	set := func(obj interface{}) {
+		if p, ok := obj.(*corev1.Pod); ok {
+			store.set(newPod(p, cfg.PodLabels))
+		}
+	}
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    set,
+		UpdateFunc: func(_, obj interface{}) { set(obj) },
+		DeleteFunc: func(obj interface{}) {
+			// the final state of pods deleted while the watch was disconnected is unknown
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if p, ok := obj.(*corev1.Pod); ok {
+				store.remove(&pod{uid: string(p.UID)})
+			}
+		},
+	})
+	return informer
+}
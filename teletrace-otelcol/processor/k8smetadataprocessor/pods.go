@@ -0,0 +1,175 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package k8smetadataprocessor
+
+import (
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Resource attributes identifying the pod of the enriched resources, per the OpenTelemetry semantic conventions,
+// which are also added to the enriched resources
+const (
+	PodIPAttribute           = "k8s.pod.ip"
+	PodUIDAttribute          = "k8s.pod.uid"
+	PodNameAttribute         = "k8s.pod.name"
+	NamespaceAttribute       = "k8s.namespace.name"
+	NodeAttribute            = "k8s.node.name"
+	DeploymentAttribute      = "k8s.deployment.name"
+	ReplicaSetAttribute      = "k8s.replicaset.name"
+	StatefulSetAttribute     = "k8s.statefulset.name"
+	DaemonSetAttribute       = "k8s.daemonset.name"
+	JobAttribute             = "k8s.job.name"
+	PodLabelsAttributePrefix = "k8s.pod.labels."
+)
+
+// podTemplateHashLabel is the label Deployments add to the pods of their ReplicaSets, the name of a ReplicaSet
+// being the name of its Deployment suffixed by the hash
+const podTemplateHashLabel = "pod-template-hash"
+
+// pod is a cached pod, with the resource attributes added to its resources.
+type pod struct {
+	uid        string
+	name       string
+	namespace  string
+	ip         string
+	attributes map[string]string
+}
+
+// newPod returns the cached pod of a Kubernetes pod, with the values of its labels.
+func newPod(p *corev1.Pod, labels []string) *pod {
+	result := &pod{
+		uid:       string(p.UID),
+		name:      p.Name,
+		namespace: p.Namespace,
+		attributes: map[string]string{
+			PodUIDAttribute:    string(p.UID),
+			PodNameAttribute:   p.Name,
+			NamespaceAttribute: p.Namespace,
+		},
+	}
+	// the IPs of host network pods are the node IP, shared by the other host network pods of the node
+	if !p.Spec.HostNetwork {
+		result.ip = p.Status.PodIP
+	}
+	putNonEmpty(result.attributes, PodIPAttribute, result.ip)
+	putNonEmpty(result.attributes, NodeAttribute, p.Spec.NodeName)
+
+	for _, owner := range p.OwnerReferences {
+		if owner.Controller == nil || !*owner.Controller {
+			continue
+		}
+		switch owner.Kind {
+		case "ReplicaSet":
+			result.attributes[ReplicaSetAttribute] = owner.Name
+			if hash := p.Labels[podTemplateHashLabel]; hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+				result.attributes[DeploymentAttribute] = strings.TrimSuffix(owner.Name, "-"+hash)
+			}
+		case "StatefulSet":
+			result.attributes[StatefulSetAttribute] = owner.Name
+		case "DaemonSet":
+			result.attributes[DaemonSetAttribute] = owner.Name
+		case "Job":
+			result.attributes[JobAttribute] = owner.Name
+		}
+	}
+
+	for _, label := range labels {
+		if value, ok := p.Labels[label]; ok {
+			result.attributes[PodLabelsAttributePrefix+label] = value
+		}
+	}
+	return result
+}
+
+func putNonEmpty(attributes map[string]string, key string, value string) {
+	if value != "" {
+		attributes[key] = value
+	}
+}
+
+// podStore indexes the cached pods by their UID, name and IP.
+type podStore struct {
+	mu     sync.RWMutex
+	byUID  map[string]*pod
+	byName map[string]*pod
+	byIP   map[string]*pod
+}
+
+func newPodStore() *podStore {
+	return &podStore{
+		byUID:  make(map[string]*pod),
+		byName: make(map[string]*pod),
+		byIP:   make(map[string]*pod),
+	}
+}
+
+// set adds a pod, or replaces the pod with its UID.
+func (s *podStore) set(p *pod) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if previous, ok := s.byUID[p.uid]; ok {
+		s.unindex(previous)
+	}
+	s.byUID[p.uid] = p
+	s.byName[p.namespace+"/"+p.name] = p
+	if p.ip != "" {
+		s.byIP[p.ip] = p
+	}
+}
+
+// remove removes the pod with the UID of p.
+func (s *podStore) remove(p *pod) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if previous, ok := s.byUID[p.uid]; ok {
+		s.unindex(previous)
+		delete(s.byUID, p.uid)
+	}
+}
+
+// unindex removes the name and IP of a pod from the indexes, unless they were reused by another pod since,
+// as pod IPs are reused once freed and the pods of StatefulSets are recreated with the same names.
+func (s *podStore) unindex(p *pod) {
+	key := p.namespace + "/" + p.name
+	if s.byName[key] == p {
+		delete(s.byName, key)
+	}
+	if p.ip != "" && s.byIP[p.ip] == p {
+		delete(s.byIP, p.ip)
+	}
+}
+
+func (s *podStore) getByUID(uid string) *pod {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.byUID[uid]
+}
+
+func (s *podStore) getByName(namespace string, name string) *pod {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.byName[namespace+"/"+name]
+}
+
+func (s *podStore) getByIP(ip string) *pod {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.byIP[ip]
+}
@@ -0,0 +1,130 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package k8smetadataprocessor
+
+import (
+	"context"
+	"net"
+
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+	"k8s.io/client-go/tools/cache"
+)
+
+type k8sMetadataProcessor struct {
+	logger   *zap.Logger
+	cfg      *Config
+	store    *podStore
+	informer cache.SharedIndexInformer
+	stop     chan struct{}
+}
+
+func newK8sMetadataProcessor(logger *zap.Logger, cfg *Config) (*k8sMetadataProcessor, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	store := newPodStore()
+	return &k8sMetadataProcessor{
+		logger:   logger,
+		cfg:      cfg,
+		store:    store,
+		informer: newPodInformer(client, cfg, store),
+		stop:     make(chan struct{}),
+	}, nil
+}
+
+// Start runs the pod informer in the background. Spans received before the pods are listed aren't enriched.
+func (p *k8sMetadataProcessor) Start(_ context.Context, _ component.Host) error {
+	go p.informer.Run(p.stop)
+	go func() {
+		if cache.WaitForCacheSync(p.stop, p.informer.HasSynced) {
+			p.logger.Info("Listed Kubernetes pods", zap.Int("pods", len(p.informer.GetStore().ListKeys())))
+		}
+	}()
+	return nil
+}
+
+func (p *k8sMetadataProcessor) Shutdown(_ context.Context) error {
+	close(p.stop)
+	return nil
+}
+
+func (p *k8sMetadataProcessor) processTraces(ctx context.Context, td ptrace.Traces) (ptrace.Traces, error) {
+	connectionIP := ""
+	if p.cfg.UseConnectionIP {
+		connectionIP = addressIP(client.FromContext(ctx).Addr)
+	}
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		attributes := td.ResourceSpans().At(i).Resource().Attributes()
+		if pod := p.lookup(attributes, connectionIP); pod != nil {
+			for key, value := range pod.attributes {
+				// attributes set by the SDK are kept
+				if _, ok := attributes.Get(key); !ok {
+					attributes.PutStr(key, value)
+				}
+			}
+		}
+	}
+	return td, nil
+}
+
+// lookup returns the pod of a resource, identified by its UID, its namespace and name, its IP attributes or,
+// if none are set, the IP of the connection it was received on.
+func (p *k8sMetadataProcessor) lookup(attributes pcommon.Map, connectionIP string) *pod {
+	if uid, ok := attributes.Get(PodUIDAttribute); ok {
+		if pod := p.store.getByUID(uid.AsString()); pod != nil {
+			return pod
+		}
+	}
+	name, hasName := attributes.Get(PodNameAttribute)
+	namespace, hasNamespace := attributes.Get(NamespaceAttribute)
+	if hasName && hasNamespace {
+		if pod := p.store.getByName(namespace.AsString(), name.AsString()); pod != nil {
+			return pod
+		}
+	}
+	for _, key := range p.cfg.PodIPAttributes {
+		if ip, ok := attributes.Get(key); ok {
+			return p.store.getByIP(ip.AsString())
+		}
+	}
+	if connectionIP != "" {
+		return p.store.getByIP(connectionIP)
+	}
+	return nil
+}
+
+// addressIP returns the IP of a connection address, or an empty string if it isn't an IP address.
+func addressIP(addr net.Addr) string {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP.String()
+	case *net.UDPAddr:
+		return a.IP.String()
+	case *net.IPAddr:
+		return a.IP.String()
+	}
+	return ""
+}
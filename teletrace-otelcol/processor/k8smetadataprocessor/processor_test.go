@@ -0,0 +1,163 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package k8smetadataprocessor
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newTestPod(uid string, name string, ip string, owner metav1.OwnerReference) *corev1.Pod {
+	controller := true
+	owner.Controller = &controller
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			UID:             types.UID(uid),
+			Name:            name,
+			Namespace:       "shop",
+			Labels:          map[string]string{"app.kubernetes.io/name": "checkout", podTemplateHashLabel: "7d9f8b6c5"},
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+		Spec:   corev1.PodSpec{NodeName: "node-1"},
+		Status: corev1.PodStatus{PodIP: ip},
+	}
+}
+
+func newTestProcessor(cfg *Config, pods ...*corev1.Pod) *k8sMetadataProcessor {
+	store := newPodStore()
+	for _, p := range pods {
+		store.set(newPod(p, cfg.PodLabels))
+	}
+	return &k8sMetadataProcessor{logger: zap.NewNop(), cfg: cfg, store: store}
+}
+
+func newTestTraces(attributes map[string]any) (ptrace.Traces, pcommon.Map) {
+	traces := ptrace.NewTraces()
+	resourceSpans := traces.ResourceSpans().AppendEmpty()
+	resourceSpans.Resource().Attributes().FromRaw(attributes)
+	resourceSpans.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	return traces, resourceSpans.Resource().Attributes()
+}
+
+func TestProcessTracesEnrichesByPodIP(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.PodLabels = []string{"app.kubernetes.io/name", "missing"}
+	p := newTestProcessor(cfg, newTestPod("uid-1", "checkout-7d9f8b6c5-x2x9z", "10.1.0.7", metav1.OwnerReference{
+		Kind: "ReplicaSet", Name: "checkout-7d9f8b6c5",
+	}))
+	traces, attributes := newTestTraces(map[string]any{"service.name": "checkout", "k8s.pod.ip": "10.1.0.7"})
+
+	_, err := p.processTraces(context.Background(), traces)
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]any{
+		"service.name":                          "checkout",
+		"k8s.pod.ip":                            "10.1.0.7",
+		"k8s.pod.uid":                           "uid-1",
+		"k8s.pod.name":                          "checkout-7d9f8b6c5-x2x9z",
+		"k8s.namespace.name":                    "shop",
+		"k8s.node.name":                         "node-1",
+		"k8s.replicaset.name":                   "checkout-7d9f8b6c5",
+		"k8s.deployment.name":                   "checkout",
+		"k8s.pod.labels.app.kubernetes.io/name": "checkout",
+	}, attributes.AsRaw())
+}
+
+func TestProcessTracesEnrichesByPodNameAndKeepsSetAttributes(t *testing.T) {
+	p := newTestProcessor(createDefaultConfig().(*Config), newTestPod("uid-1", "db-0", "10.1.0.8", metav1.OwnerReference{
+		Kind: "StatefulSet", Name: "db",
+	}))
+	traces, attributes := newTestTraces(map[string]any{
+		"k8s.pod.name": "db-0", "k8s.namespace.name": "shop", "k8s.node.name": "node-from-sdk",
+	})
+
+	_, err := p.processTraces(context.Background(), traces)
+	assert.NoError(t, err)
+
+	node, _ := attributes.Get(NodeAttribute)
+	assert.Equal(t, "node-from-sdk", node.Str())
+	statefulSet, _ := attributes.Get(StatefulSetAttribute)
+	assert.Equal(t, "db", statefulSet.Str())
+}
+
+func TestProcessTracesEnrichesByConnectionIP(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	p := newTestProcessor(cfg, newTestPod("uid-1", "agent-abcde", "10.1.0.9", metav1.OwnerReference{
+		Kind: "DaemonSet", Name: "agent",
+	}))
+	ctx := client.NewContext(context.Background(), client.Info{Addr: &net.TCPAddr{IP: net.ParseIP("10.1.0.9"), Port: 52341}})
+
+	traces, attributes := newTestTraces(map[string]any{"service.name": "agent"})
+	_, err := p.processTraces(ctx, traces)
+	assert.NoError(t, err)
+	daemonSet, _ := attributes.Get(DaemonSetAttribute)
+	assert.Equal(t, "agent", daemonSet.Str())
+
+	cfg.UseConnectionIP = false
+	traces, attributes = newTestTraces(map[string]any{"service.name": "agent"})
+	_, err = p.processTraces(ctx, traces)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"service.name": "agent"}, attributes.AsRaw())
+}
+
+func TestPodStoreKeepsReusedIPs(t *testing.T) {
+	store := newPodStore()
+	deleted := newPod(newTestPod("uid-1", "checkout-1", "10.1.0.7", metav1.OwnerReference{Kind: "Job", Name: "migrate"}), nil)
+	recreated := newPod(newTestPod("uid-2", "checkout-2", "10.1.0.7", metav1.OwnerReference{Kind: "Job", Name: "migrate"}), nil)
+	store.set(deleted)
+	store.set(recreated)
+
+	// the deletion of the previous pod of the IP is received after the new pod was added
+	store.remove(&pod{uid: "uid-1"})
+
+	assert.Equal(t, recreated, store.getByIP("10.1.0.7"))
+	assert.Nil(t, store.getByUID("uid-1"))
+	assert.Nil(t, store.getByName("shop", "checkout-1"))
+}
+
+func TestNewPodSkipsHostNetworkIP(t *testing.T) {
+	p := newTestPod("uid-1", "node-exporter-abcde", "192.168.1.10", metav1.OwnerReference{Kind: "DaemonSet", Name: "node-exporter"})
+	p.Spec.HostNetwork = true
+
+	cached := newPod(p, nil)
+
+	assert.Empty(t, cached.ip)
+	_, ok := cached.attributes[PodIPAttribute]
+	assert.False(t, ok)
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	assert.NoError(t, cfg.Validate())
+
+	cfg.AuthType = "token"
+	assert.ErrorIs(t, cfg.Validate(), errConfigAuthType)
+
+	cfg.AuthType = AuthTypeKubeConfig
+	cfg.ResyncPeriod = -1
+	assert.ErrorIs(t, cfg.Validate(), errConfigResyncPeriod)
+}
@@ -0,0 +1,69 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package ratelimitprocessor
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/collector/config"
+)
+
+// Config defines configuration for the rate limit processor.
+type Config struct {
+	config.ProcessorSettings `mapstructure:",squash"`
+
+	// SpansPerSecond is the rate of spans allowed to each tenant without an override, 0 is unlimited
+	SpansPerSecond float64 `mapstructure:"spans_per_second"`
+
+	// Burst is the number of spans a tenant may send at once above the rate, defaults to the rate
+	Burst int `mapstructure:"burst"`
+
+	// MetadataKey is the request metadata (a gRPC metadata or HTTP header) holding the tenant of the spans,
+	// requires the receiver's include_metadata option
+	MetadataKey string `mapstructure:"metadata_key"`
+
+	// Overrides are the limits of specific tenants
+	Overrides map[string]LimitConfig `mapstructure:"overrides"`
+}
+
+// LimitConfig is the limit of a tenant.
+type LimitConfig struct {
+	SpansPerSecond float64 `mapstructure:"spans_per_second"`
+	Burst          int     `mapstructure:"burst"`
+}
+
+var (
+	errConfigNegativeLimit = errors.New("spans_per_second and burst must not be negative")
+	errConfigNoMetadataKey = errors.New("metadata_key must not be empty")
+)
+
+// Validate validates the rate limit processor configuration.
+func (cfg *Config) Validate() error {
+	if cfg.SpansPerSecond < 0 || cfg.Burst < 0 {
+		return errConfigNegativeLimit
+	}
+	for _, limit := range cfg.Overrides {
+		if limit.SpansPerSecond < 0 || limit.Burst < 0 {
+			return errConfigNegativeLimit
+		}
+	}
+
+	if cfg.MetadataKey == "" {
+		return errConfigNoMetadataKey
+	}
+
+	return nil
+}
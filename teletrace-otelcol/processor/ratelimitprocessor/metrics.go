@@ -0,0 +1,66 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package ratelimitprocessor
+
+import (
+	"context"
+	"sync"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+var (
+	tenantKey = tag.MustNewKey("tenant")
+
+	rejectedSpans = stats.Int64(
+		"teletrace_rate_limited_spans",
+		"Number of spans rejected with a retryable error because their tenant exceeded its rate limit",
+		stats.UnitDimensionless,
+	)
+
+	registerViewsOnce sync.Once
+	errRegisterViews  error
+)
+
+// metrics records the rate limit metrics with OpenCensus, which are exposed by the collector's own telemetry.
+type metrics struct{}
+
+func newMetrics() (*metrics, error) {
+	registerViewsOnce.Do(func() {
+		errRegisterViews = view.Register(
+			&view.View{
+				Name:        rejectedSpans.Name(),
+				Description: rejectedSpans.Description(),
+				Measure:     rejectedSpans,
+				TagKeys:     []tag.Key{tenantKey},
+				Aggregation: view.Sum(),
+			},
+		)
+	})
+	if errRegisterViews != nil {
+		return nil, errRegisterViews
+	}
+	return &metrics{}, nil
+}
+
+func (m *metrics) recordRejected(tenant string, spanCount int) {
+	_ = stats.RecordWithTags(context.Background(),
+		[]tag.Mutator{tag.Upsert(tenantKey, tenant)},
+		rejectedSpans.M(int64(spanCount)),
+	)
+}
@@ -0,0 +1,115 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package ratelimitprocessor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/teletrace/teletrace/ratelimit"
+
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// defaultTenant tags the rejected spans of tenants without an override, to keep the metric's cardinality bounded
+const defaultTenant = "default"
+
+// RateLimitedError is returned when a batch is rejected because its tenant exceeded its rate limit.
+// It implements GRPCStatus, so the OTLP receiver responds with RESOURCE_EXHAUSTED and a retry delay,
+// which the OpenTelemetry SDKs and collectors treat as retryable.
+type RateLimitedError struct {
+	Tenant string
+	// RetryDelay is the time the client is asked to wait before sending the batch again
+	RetryDelay time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limit of tenant %q exceeded, retry in %s", e.Tenant, e.RetryDelay)
+}
+
+// GRPCStatus returns the RESOURCE_EXHAUSTED status with a RetryInfo detail.
+func (e *RateLimitedError) GRPCStatus() *status.Status {
+	st := status.New(codes.ResourceExhausted, e.Error())
+	withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(e.RetryDelay)})
+	if err != nil {
+		return st
+	}
+	return withDetails
+}
+
+type rateLimitProcessor struct {
+	logger  *zap.Logger
+	cfg     *Config
+	limiter *ratelimit.Limiter
+	metrics *metrics
+}
+
+func newRateLimitProcessor(logger *zap.Logger, cfg *Config) (*rateLimitProcessor, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	m, err := newMetrics()
+	if err != nil {
+		return nil, fmt.Errorf("failed to register metrics: %+v", err)
+	}
+
+	overrides := make(map[string]ratelimit.Limit, len(cfg.Overrides))
+	for tenant, limit := range cfg.Overrides {
+		overrides[tenant] = ratelimit.Limit{Rate: limit.SpansPerSecond, Burst: limit.Burst}
+	}
+	return &rateLimitProcessor{
+		logger:  logger,
+		cfg:     cfg,
+		limiter: ratelimit.NewLimiter(ratelimit.Limit{Rate: cfg.SpansPerSecond, Burst: cfg.Burst}, overrides),
+		metrics: m,
+	}, nil
+}
+
+// tenant returns the tenant of the request, empty for requests without one, which share the default limit.
+func (p *rateLimitProcessor) tenant(ctx context.Context) string {
+	values := client.FromContext(ctx).Metadata.Get(p.cfg.MetadataKey)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// processTraces passes the spans on if their tenant is within its limit, and rejects the whole batch otherwise.
+func (p *rateLimitProcessor) processTraces(ctx context.Context, td ptrace.Traces) (ptrace.Traces, error) {
+	tenant := p.tenant(ctx)
+	spanCount := td.SpanCount()
+	allowed, wait := p.limiter.Allow(tenant, spanCount)
+	if allowed {
+		return td, nil
+	}
+
+	metricTenant := defaultTenant
+	if p.limiter.Overridden(tenant) {
+		metricTenant = tenant
+	}
+	p.metrics.recordRejected(metricTenant, spanCount)
+	p.logger.Debug("Rejected spans exceeding the rate limit",
+		zap.String("tenant", tenant), zap.Int("spans", spanCount), zap.Duration("retry_delay", wait))
+	return td, &RateLimitedError{Tenant: tenant, RetryDelay: wait}
+}
@@ -0,0 +1,99 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package ratelimitprocessor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTraces(spanCount int) ptrace.Traces {
+	td := ptrace.NewTraces()
+	spans := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	for i := 0; i < spanCount; i++ {
+		spans.AppendEmpty().SetName("span")
+	}
+	return td
+}
+
+func tenantContext(tenant string) context.Context {
+	return client.NewContext(context.Background(), client.Info{
+		Metadata: client.NewMetadata(map[string][]string{"x-tenant-id": {tenant}}),
+	})
+}
+
+func TestProcessTraces(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.SpansPerSecond = 1
+	cfg.Burst = 10
+	cfg.Overrides = map[string]LimitConfig{"premium": {SpansPerSecond: 1000}}
+	p, err := newRateLimitProcessor(zap.NewNop(), cfg)
+	assert.NoError(t, err)
+
+	_, err = p.processTraces(tenantContext("a"), newTraces(10))
+	assert.NoError(t, err)
+	_, err = p.processTraces(tenantContext("a"), newTraces(1))
+	var rateLimited *RateLimitedError
+	assert.True(t, errors.As(err, &rateLimited))
+	assert.Equal(t, "a", rateLimited.Tenant)
+
+	// tenants are limited separately, and overrides apply to their tenants
+	_, err = p.processTraces(tenantContext("b"), newTraces(10))
+	assert.NoError(t, err)
+	_, err = p.processTraces(tenantContext("premium"), newTraces(500))
+	assert.NoError(t, err)
+	_, err = p.processTraces(tenantContext("premium"), newTraces(500))
+	assert.NoError(t, err)
+
+	// requests without a tenant share the default limit
+	_, err = p.processTraces(context.Background(), newTraces(10))
+	assert.NoError(t, err)
+	_, err = p.processTraces(context.Background(), newTraces(10))
+	assert.Error(t, err)
+}
+
+func TestRateLimitedErrorStatus(t *testing.T) {
+	var err error = &RateLimitedError{Tenant: "a", RetryDelay: 2 * time.Second}
+	st, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	assert.Len(t, st.Details(), 1)
+	retryInfo, ok := st.Details()[0].(*errdetails.RetryInfo)
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), retryInfo.RetryDelay.Seconds)
+}
+
+func TestValidate(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Overrides = map[string]LimitConfig{"a": {SpansPerSecond: -1}}
+	assert.Equal(t, errConfigNegativeLimit, cfg.Validate())
+
+	cfg = createDefaultConfig().(*Config)
+	cfg.MetadataKey = ""
+	assert.Equal(t, errConfigNoMetadataKey, cfg.Validate())
+}
@@ -0,0 +1,69 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package routetemplateprocessor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"go.opentelemetry.io/collector/config"
+)
+
+// Config defines configuration for the route template processor.
+type Config struct {
+	config.ProcessorSettings `mapstructure:",squash"`
+
+	// SpanNames templates the paths in span names, e.g. GET /users/123 becomes GET /users/{id}
+	SpanNames bool `mapstructure:"span_names"`
+
+	// Attributes are the span attributes holding paths to template, defaults to http.target
+	Attributes []string `mapstructure:"attributes"`
+
+	// Placeholder replaces the templated path segments, defaults to {id}
+	Placeholder string `mapstructure:"placeholder"`
+
+	// Patterns are regular expressions of path segments to template besides numbers, UUIDs and long hex strings,
+	// matched against whole segments
+	Patterns []string `mapstructure:"patterns"`
+
+	// DropQuery removes the query string of the templated attributes
+	DropQuery bool `mapstructure:"drop_query"`
+}
+
+var (
+	errConfigNothingToTemplate = errors.New("span_names must be enabled or attributes must not be empty")
+	errConfigNoPlaceholder     = errors.New("placeholder must not be empty")
+)
+
+// Validate validates the route template processor configuration.
+func (cfg *Config) Validate() error {
+	if !cfg.SpanNames && len(cfg.Attributes) == 0 {
+		return errConfigNothingToTemplate
+	}
+
+	if cfg.Placeholder == "" {
+		return errConfigNoPlaceholder
+	}
+
+	for _, pattern := range cfg.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package routetemplateprocessor
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// The path segments which are always templated: numbers, UUIDs and hex strings with a digit of at least
+// minHexLength characters, e.g. object IDs and hashes
+var (
+	numberPattern = regexp.MustCompile(`^[0-9]+$`)
+	uuidPattern   = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hexPattern    = regexp.MustCompile(`^[0-9a-fA-F]*[0-9][0-9a-fA-F]*$`)
+)
+
+// minHexLength is the minimal length of the hex strings templated, so short segments like "v1" or "b2b" aren't
+const minHexLength = 16
+
+type routeTemplateProcessor struct {
+	cfg      *Config
+	patterns []*regexp.Regexp
+}
+
+func newRouteTemplateProcessor(cfg *Config) (*routeTemplateProcessor, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := &routeTemplateProcessor{cfg: cfg}
+	for _, pattern := range cfg.Patterns {
+		// the patterns are matched against whole segments
+		p.patterns = append(p.patterns, regexp.MustCompile("^(?:"+pattern+")$"))
+	}
+	return p, nil
+}
+
+func (p *routeTemplateProcessor) processTraces(_ context.Context, td ptrace.Traces) (ptrace.Traces, error) {
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		scopeSpans := td.ResourceSpans().At(i).ScopeSpans()
+		for j := 0; j < scopeSpans.Len(); j++ {
+			spans := scopeSpans.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				p.templateSpan(spans.At(k))
+			}
+		}
+	}
+	return td, nil
+}
+
+func (p *routeTemplateProcessor) templateSpan(span ptrace.Span) {
+	if p.cfg.SpanNames {
+		if name := p.templateSpanName(span.Name()); name != span.Name() {
+			span.SetName(name)
+		}
+	}
+	p.templateAttributes(span.Attributes())
+}
+
+// templateSpanName templates the words of a span name which are paths, e.g. the route of GET /users/123.
+func (p *routeTemplateProcessor) templateSpanName(name string) string {
+	words := strings.Split(name, " ")
+	for i, word := range words {
+		if strings.HasPrefix(word, "/") {
+			words[i] = p.templatePath(word, false)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func (p *routeTemplateProcessor) templateAttributes(attributes pcommon.Map) {
+	for _, key := range p.cfg.Attributes {
+		value, ok := attributes.Get(key)
+		if !ok || value.Type() != pcommon.ValueTypeStr {
+			continue
+		}
+		if path := p.templatePath(value.Str(), p.cfg.DropQuery); path != value.Str() {
+			attributes.PutStr(key, path)
+		}
+	}
+}
+
+// templatePath replaces the segments of a path which are IDs with the placeholder, keeping its query string and
+// fragment unless dropQuery is set.
+func (p *routeTemplateProcessor) templatePath(target string, dropQuery bool) string {
+	path, query := target, ""
+	if i := strings.IndexAny(target, "?#"); i >= 0 {
+		path, query = target[:i], target[i:]
+	}
+	if dropQuery {
+		query = ""
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if p.isID(segment) {
+			segments[i] = p.cfg.Placeholder
+		}
+	}
+	return strings.Join(segments, "/") + query
+}
+
+// isID returns whether a path segment is an ID.
+func (p *routeTemplateProcessor) isID(segment string) bool {
+	if segment == "" {
+		return false
+	}
+	if numberPattern.MatchString(segment) || uuidPattern.MatchString(segment) {
+		return true
+	}
+	if len(segment) >= minHexLength && hexPattern.MatchString(segment) {
+		return true
+	}
+	for _, pattern := range p.patterns {
+		if pattern.MatchString(segment) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package routetemplateprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func newTestSpan(name string, attributes map[string]any) (ptrace.Traces, ptrace.Span) {
+	traces := ptrace.NewTraces()
+	span := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetName(name)
+	span.Attributes().FromRaw(attributes)
+	return traces, span
+}
+
+func process(t *testing.T, cfg *Config, traces ptrace.Traces) {
+	p, err := newRouteTemplateProcessor(cfg)
+	assert.NoError(t, err)
+	_, err = p.processTraces(context.Background(), traces)
+	assert.NoError(t, err)
+}
+
+func TestTemplatePath(t *testing.T) {
+	p, err := newRouteTemplateProcessor(createDefaultConfig().(*Config))
+	assert.NoError(t, err)
+
+	for path, expected := range map[string]string{
+		"/users/123":                                      "/users/{id}",
+		"/users/123/orders/456?expand=items":              "/users/{id}/orders/{id}?expand=items",
+		"/orders/0f8fad5b-d9cb-469f-a165-70867728950e":    "/orders/{id}",
+		"/blobs/5d41402abc4b2a76b9719d911017c592/content": "/blobs/{id}/content",
+		"/api/v1/b2b/cafe":                                "/api/v1/b2b/cafe",
+		"/users/":                                         "/users/",
+		"/":                                               "/",
+		"/users/me#profile":                               "/users/me#profile",
+		"/reports/2023/summary-5d41402abc4b2a76b9719d911017": "/reports/{id}/summary-5d41402abc4b2a76b9719d911017",
+	} {
+		assert.Equal(t, expected, p.templatePath(path, false), path)
+	}
+	assert.Equal(t, "/users/{id}", p.templatePath("/users/123?token=abc", true))
+}
+
+func TestProcessTracesTemplatesSpanNamesAndAttributes(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	traces, span := newTestSpan("GET /users/123", map[string]any{
+		"http.target": "/users/123?verbose=1",
+		"http.url":    "https://example.com/users/123",
+	})
+
+	process(t, cfg, traces)
+
+	assert.Equal(t, "GET /users/{id}", span.Name())
+	target, _ := span.Attributes().Get("http.target")
+	assert.Equal(t, "/users/{id}?verbose=1", target.Str())
+	url, _ := span.Attributes().Get("http.url")
+	assert.Equal(t, "https://example.com/users/123", url.Str(), "attributes which aren't configured shouldn't be templated")
+}
+
+func TestProcessTracesWithCustomPatternsAndPlaceholder(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.SpanNames = false
+	cfg.Attributes = []string{"url.path"}
+	cfg.Placeholder = ":id"
+	cfg.Patterns = []string{`[a-z]+-[0-9]+`, `ord_[A-Za-z0-9]+`}
+	cfg.DropQuery = true
+	traces, span := newTestSpan("GET /tickets/JIRA-1", map[string]any{"url.path": "/tickets/abc-12/orders/ord_9aZ?page=2"})
+
+	process(t, cfg, traces)
+
+	assert.Equal(t, "GET /tickets/JIRA-1", span.Name())
+	path, _ := span.Attributes().Get("url.path")
+	assert.Equal(t, "/tickets/:id/orders/:id", path.Str())
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Patterns = []string{"("}
+	assert.Error(t, cfg.Validate())
+
+	cfg.Patterns = nil
+	cfg.Placeholder = ""
+	assert.ErrorIs(t, cfg.Validate(), errConfigNoPlaceholder)
+
+	cfg.Placeholder = "{id}"
+	cfg.SpanNames = false
+	cfg.Attributes = nil
+	assert.ErrorIs(t, cfg.Validate(), errConfigNothingToTemplate)
+}
@@ -0,0 +1,64 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package servicenameprocessor
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/collector/config"
+)
+
+// Config defines configuration for the service name processor.
+type Config struct {
+	config.ProcessorSettings `mapstructure:",squash"`
+
+	// Lowercase converts service names to lowercase
+	Lowercase bool `mapstructure:"lowercase"`
+
+	// TrimSuffixes are removed from the end of service names, e.g. -legacy, the first matching one only
+	TrimSuffixes []string `mapstructure:"trim_suffixes"`
+
+	// Aliases maps service names, after the normalization above, to the name of the logical service they belong to
+	Aliases map[string]string `mapstructure:"aliases"`
+
+	// OriginalAttribute is the resource attribute keeping the original service name of renamed services, if set
+	OriginalAttribute string `mapstructure:"original_attribute"`
+}
+
+var (
+	errConfigNoRules          = errors.New("at least one of lowercase, trim_suffixes and aliases must be specified")
+	errConfigEmptyAlias       = errors.New("aliases must not map a service name to an empty name")
+	errConfigServiceAttribute = errors.New("original_attribute must not be service.name")
+)
+
+// Validate validates the service name processor configuration.
+func (cfg *Config) Validate() error {
+	if !cfg.Lowercase && len(cfg.TrimSuffixes) == 0 && len(cfg.Aliases) == 0 {
+		return errConfigNoRules
+	}
+
+	for _, name := range cfg.Aliases {
+		if name == "" {
+			return errConfigEmptyAlias
+		}
+	}
+
+	if cfg.OriginalAttribute == ServiceNameAttribute {
+		return errConfigServiceAttribute
+	}
+
+	return nil
+}
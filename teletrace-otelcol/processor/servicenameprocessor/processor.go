@@ -0,0 +1,92 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package servicenameprocessor
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// ServiceNameAttribute is the resource attribute holding the service name
+const ServiceNameAttribute = "service.name"
+
+type serviceNameProcessor struct {
+	cfg     *Config
+	aliases map[string]string
+}
+
+func newServiceNameProcessor(cfg *Config) (*serviceNameProcessor, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := &serviceNameProcessor{cfg: cfg, aliases: make(map[string]string, len(cfg.Aliases))}
+	// the aliases are matched after normalization, so they're normalized as well
+	for alias, name := range cfg.Aliases {
+		p.aliases[p.normalize(alias)] = name
+	}
+	return p, nil
+}
+
+func (p *serviceNameProcessor) processTraces(_ context.Context, td ptrace.Traces) (ptrace.Traces, error) {
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		p.rename(td.ResourceSpans().At(i).Resource().Attributes())
+	}
+	return td, nil
+}
+
+// rename replaces the service name of a resource with its normalized or aliased name.
+func (p *serviceNameProcessor) rename(attributes pcommon.Map) {
+	value, ok := attributes.Get(ServiceNameAttribute)
+	if !ok || value.Type() != pcommon.ValueTypeStr {
+		return
+	}
+
+	original := value.Str()
+	name := p.serviceName(original)
+	if name == original {
+		return
+	}
+	attributes.PutStr(ServiceNameAttribute, name)
+	if p.cfg.OriginalAttribute != "" {
+		attributes.PutStr(p.cfg.OriginalAttribute, original)
+	}
+}
+
+// serviceName returns the logical service name of a service name.
+func (p *serviceNameProcessor) serviceName(name string) string {
+	name = p.normalize(name)
+	if alias, ok := p.aliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+func (p *serviceNameProcessor) normalize(name string) string {
+	name = strings.TrimSpace(name)
+	if p.cfg.Lowercase {
+		name = strings.ToLower(name)
+	}
+	for _, suffix := range p.cfg.TrimSuffixes {
+		if trimmed := strings.TrimSuffix(name, suffix); trimmed != name && trimmed != "" {
+			return trimmed
+		}
+	}
+	return name
+}
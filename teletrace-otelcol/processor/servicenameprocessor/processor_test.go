@@ -0,0 +1,107 @@
+/**
+ * Copyright 2022 Cisco Systems, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package servicenameprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func newTestTraces(serviceNames ...string) ptrace.Traces {
+	traces := ptrace.NewTraces()
+	for _, name := range serviceNames {
+		traces.ResourceSpans().AppendEmpty().Resource().Attributes().PutStr(ServiceNameAttribute, name)
+	}
+	return traces
+}
+
+func resourceAttributes(traces ptrace.Traces, i int) pcommon.Map {
+	return traces.ResourceSpans().At(i).Resource().Attributes()
+}
+
+func process(t *testing.T, cfg *Config, traces ptrace.Traces) {
+	p, err := newServiceNameProcessor(cfg)
+	assert.NoError(t, err)
+	_, err = p.processTraces(context.Background(), traces)
+	assert.NoError(t, err)
+}
+
+func TestProcessTracesAliasesServiceNames(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Aliases = map[string]string{"checkout-legacy": "checkout", "billing": "payments"}
+	cfg.OriginalAttribute = "service.original_name"
+	traces := newTestTraces("checkout-legacy", "billing", "checkout", "frontend")
+
+	process(t, cfg, traces)
+
+	for i, expected := range []string{"checkout", "payments", "checkout", "frontend"} {
+		name, _ := resourceAttributes(traces, i).Get(ServiceNameAttribute)
+		assert.Equal(t, expected, name.Str())
+	}
+	original, ok := resourceAttributes(traces, 0).Get("service.original_name")
+	assert.True(t, ok)
+	assert.Equal(t, "checkout-legacy", original.Str())
+	_, ok = resourceAttributes(traces, 2).Get("service.original_name")
+	assert.False(t, ok, "services which aren't renamed shouldn't get the original name attribute")
+}
+
+func TestProcessTracesNormalizesServiceNames(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Lowercase = true
+	cfg.TrimSuffixes = []string{"-v2", "-legacy"}
+	cfg.Aliases = map[string]string{"Billing": "payments"}
+	traces := newTestTraces(" Checkout-V2", "checkout-legacy", "BILLING-legacy", "-legacy")
+
+	process(t, cfg, traces)
+
+	for i, expected := range []string{"checkout", "checkout", "payments", "-legacy"} {
+		name, _ := resourceAttributes(traces, i).Get(ServiceNameAttribute)
+		assert.Equal(t, expected, name.Str())
+	}
+}
+
+func TestProcessTracesIgnoresResourcesWithoutServiceName(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Lowercase = true
+	traces := ptrace.NewTraces()
+	traces.ResourceSpans().AppendEmpty().Resource().Attributes().PutInt(ServiceNameAttribute, 1)
+	traces.ResourceSpans().AppendEmpty()
+
+	process(t, cfg, traces)
+
+	name, _ := resourceAttributes(traces, 0).Get(ServiceNameAttribute)
+	assert.Equal(t, int64(1), name.Int())
+	assert.Equal(t, 0, resourceAttributes(traces, 1).Len())
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	assert.ErrorIs(t, cfg.Validate(), errConfigNoRules)
+
+	cfg.Aliases = map[string]string{"checkout-legacy": ""}
+	assert.ErrorIs(t, cfg.Validate(), errConfigEmptyAlias)
+
+	cfg.Aliases = map[string]string{"checkout-legacy": "checkout"}
+	cfg.OriginalAttribute = ServiceNameAttribute
+	assert.ErrorIs(t, cfg.Validate(), errConfigServiceAttribute)
+
+	cfg.OriginalAttribute = "service.original_name"
+	assert.NoError(t, cfg.Validate())
+}
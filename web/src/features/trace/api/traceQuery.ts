@@ -22,6 +22,7 @@ import { InternalSpan } from "@/types/span";
 interface TraceQueryResponse {
   spans: InternalSpan[];
   metadata: { nextToken: string } | null;
+  clockSkewAdjustments?: { spanId: string; adjustmentNano: number }[];
 }
 
 const fetchTrace = (traceId: string): Promise<TraceQueryResponse> => {
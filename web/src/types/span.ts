@@ -88,6 +88,7 @@ export type RawSpan = {
 
 export type ExternalFields = {
   durationNano: number;
+  childCount?: number;
 };
 
 export type InternalSpan = {